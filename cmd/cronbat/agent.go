@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/patrickspencer/cronbat/internal/agent"
+	"github.com/patrickspencer/cronbat/internal/placement"
+)
+
+// runAgent connects to a cronbat server and runs the jobs whose runs_on
+// selects this host, until interrupted.
+func runAgent(args []string) int {
+	fs := flag.NewFlagSet("agent", flag.ExitOnError)
+	server := fs.String("server", "http://localhost:8080", "cronbat server URL")
+	token := fs.String("token", "", "agent token (default $CRONBAT_AGENT_TOKEN)")
+	hostname := fs.String("hostname", "", "name to register as (default the host name)")
+	labels := fs.String("labels", "", "comma-separated key=value labels, e.g. role=db,zone=eu")
+	fs.Parse(args)
+
+	if *token == "" {
+		*token = os.Getenv("CRONBAT_AGENT_TOKEN")
+	}
+	if *token == "" {
+		fmt.Fprintln(os.Stderr, "error: --token or CRONBAT_AGENT_TOKEN is required")
+		return 1
+	}
+	if *hostname == "" {
+		h, err := os.Hostname()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		*hostname = h
+	}
+	parsed, err := parseAgentLabels(*labels)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	a := &agent.Agent{
+		Server:   *server,
+		Token:    *token,
+		Hostname: *hostname,
+		Labels:   parsed,
+		Version:  cronbatVersion(),
+	}
+	if err := a.Run(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func parseAgentLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if !ok || k == "" || v == "" {
+			return nil, fmt.Errorf("invalid label %q: want key=value", pair)
+		}
+		if k == placement.HostnameLabel {
+			return nil, fmt.Errorf("invalid label %q: use --hostname", pair)
+		}
+		labels[k] = v
+	}
+	return labels, nil
+}
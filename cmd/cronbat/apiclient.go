@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net/http"
+	"os"
+)
+
+// apiKeyEnv holds the API key CLI commands send to the daemon's API, needed
+// when the daemon sets auth.required.
+const apiKeyEnv = "CRONBAT_API_KEY"
+
+// apiClient is the HTTP client CLI commands call the daemon's API with.
+var apiClient = &http.Client{Transport: apiKeyTransport{}}
+
+// apiKeyTransport adds $CRONBAT_API_KEY as a bearer token to requests that
+// carry no credentials of their own.
+type apiKeyTransport struct{}
+
+func (apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if key := os.Getenv(apiKeyEnv); key != "" && req.Header.Get("Authorization") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
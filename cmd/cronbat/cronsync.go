@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -10,9 +11,14 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/crontab"
+	"github.com/patrickspencer/cronbat/internal/jobimport"
+	"github.com/patrickspencer/cronbat/internal/lint"
+	"github.com/patrickspencer/cronbat/internal/scheduler"
 )
 
 const (
@@ -23,7 +29,7 @@ const (
 
 func runCronSync(args []string) int {
 	if len(args) == 0 {
-		fmt.Fprintln(os.Stderr, "usage: cronbat cron-sync <install|import> [flags]")
+		fmt.Fprintln(os.Stderr, "usage: cronbat cron-sync <install|uninstall|status|preview|import> [flags]")
 		return 1
 	}
 
@@ -33,11 +39,17 @@ func runCronSync(args []string) int {
 	switch sub {
 	case "install":
 		return runCronSyncInstall(rest)
+	case "uninstall":
+		return runCronSyncUninstall(rest)
+	case "status":
+		return runCronSyncStatus(rest)
+	case "preview":
+		return runCronSyncPreview(rest)
 	case "import":
 		return runCronSyncImport(rest)
 	default:
 		fmt.Fprintf(os.Stderr, "unknown cron-sync subcommand: %s\n", sub)
-		fmt.Fprintln(os.Stderr, "usage: cronbat cron-sync <install|import> [flags]")
+		fmt.Fprintln(os.Stderr, "usage: cronbat cron-sync <install|uninstall|status|preview|import> [flags]")
 		return 1
 	}
 }
@@ -47,6 +59,9 @@ func runCronSyncInstall(args []string) int {
 	apiURL := fs.String("api", "", "API URL (if set, reads jobs from API)")
 	configPath := fs.String("config", "cronbat.yaml", "path to config file (for direct file access)")
 	dryRun := fs.Bool("dry-run", false, "preview only, don't modify crontab")
+	names := fs.String("name", "", "comma-separated job names to install, keeping other installed jobs (default all)")
+	tag := fs.String("tag", "", "only install jobs with this tag, keeping other installed jobs")
+	target := addCronTargetFlags(fs)
 	fs.Parse(args)
 
 	jobs, err := loadJobsForSync(*apiURL, *configPath)
@@ -54,40 +69,60 @@ func runCronSyncInstall(args []string) int {
 		fmt.Fprintf(os.Stderr, "error loading jobs: %v\n", err)
 		return 1
 	}
+	selective := *names != "" || *tag != ""
+	jobs = config.FilterJobs(jobs, splitNames(*names), *tag)
 
 	if len(jobs) == 0 {
 		fmt.Println("no jobs to install")
 		return 0
 	}
 
-	// Resolve cronbat binary path.
-	cronbatBin, err := os.Executable()
-	if err != nil {
-		cronbatBin = "cronbat"
-	}
+	cronbatBin, absConfig := cronSyncPaths(*configPath)
 
-	// Resolve absolute config path for crontab entries.
-	absConfig, err := filepath.Abs(*configPath)
-	if err != nil {
-		absConfig = *configPath
-	}
-
-	// Build managed crontab entries.
-	var managed strings.Builder
-	managed.WriteString(cronbatBeginMarker + "\n")
+	// Build managed crontab entries. Commands cron cannot represent fail
+	// the whole install rather than silently dropping the job.
+	var lines []string
+	var unrepresentable int
+	selected := make(map[string]bool, len(jobs))
+	byJob := make(map[string]string, len(jobs))
 	for _, j := range jobs {
+		selected[j.Name] = true
 		if !j.IsEnabled() {
 			continue
 		}
-		line := fmt.Sprintf("%s %s wrap --name %s --config %s -- %s  %s",
-			j.Schedule, cronbatBin, j.Name, absConfig, j.Command, cronbatTag)
-		managed.WriteString(line + "\n")
+		line, err := target.line(j, cronbatBin, absConfig)
+		if errors.Is(err, errUnrepresentable) {
+			fmt.Fprintf(os.Stderr, "error: job %s: %v\n", j.Name, err)
+			unrepresentable++
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: skipping job %s: %v\n", j.Name, err)
+			continue
+		}
+		lines = append(lines, line)
+		byJob[j.Name] = line
+	}
+	if unrepresentable > 0 {
+		fmt.Fprintf(os.Stderr, "error: %d job(s) cannot be installed into crontab; crontab not modified\n", unrepresentable)
+		return 1
 	}
-	managed.WriteString(cronbatEndMarker + "\n")
+	installed := len(lines)
 
-	if *dryRun {
-		fmt.Println("--- dry run: would install the following crontab section ---")
-		fmt.Print(managed.String())
+	// Selected jobs that are not installed lose their drop-ins; without a
+	// selection, so do all other jobs.
+	if target.system {
+		if *dryRun {
+			fmt.Println("--- dry run: would write the following drop-ins ---")
+		}
+		written, removed, err := syncDropIns(target.dir, byJob, selected, !selective, *dryRun)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error writing drop-ins: %v\n", err)
+			return 1
+		}
+		if !*dryRun {
+			fmt.Printf("installed %d job(s) into %s, removed %d drop-in(s)\n", written, target.dir, removed)
+		}
 		return 0
 	}
 
@@ -98,70 +133,415 @@ func runCronSyncInstall(args []string) int {
 		return 1
 	}
 
+	// A selective install replaces only the selected jobs' entries.
+	if selective {
+		var kept []string
+		for _, line := range managedLines(existing) {
+			if name, ok := managedJobName(line); !ok || !selected[name] {
+				kept = append(kept, line)
+			}
+		}
+		lines = append(kept, lines...)
+	}
+	managed := managedBlock(lines)
+
+	if *dryRun {
+		fmt.Println("--- dry run: would install the following crontab section ---")
+		fmt.Print(managed)
+		return 0
+	}
+
 	// Merge: replace managed section, preserve everything else.
-	merged := mergeCrontab(existing, managed.String())
+	merged := mergeCrontab(existing, managed)
 
 	if err := writeCrontab(merged); err != nil {
 		fmt.Fprintf(os.Stderr, "error writing crontab: %v\n", err)
 		return 1
 	}
 
-	fmt.Printf("installed %d job(s) into crontab\n", len(jobs))
+	fmt.Printf("installed %d job(s) into crontab\n", installed)
 	return 0
 }
 
-func runCronSyncImport(args []string) int {
-	fs := flag.NewFlagSet("cron-sync import", flag.ExitOnError)
-	apiURL := fs.String("api", "", "API URL (if set, creates jobs via API)")
+func runCronSyncUninstall(args []string) int {
+	fs := flag.NewFlagSet("cron-sync uninstall", flag.ExitOnError)
+	apiURL := fs.String("api", "", "API URL (if set, reads jobs from API for --tag)")
 	configPath := fs.String("config", "cronbat.yaml", "path to config file (for direct file access)")
-	dryRun := fs.Bool("dry-run", false, "preview only, don't create jobs")
-	prefix := fs.String("prefix", "cron-", "name prefix for imported jobs")
+	dryRun := fs.Bool("dry-run", false, "preview only, don't modify crontab")
+	names := fs.String("name", "", "comma-separated job names to remove (default: the whole managed section)")
+	tag := fs.String("tag", "", "remove the jobs with this tag")
+	target := addCronTargetFlags(fs)
 	fs.Parse(args)
 
-	crontab, err := readCrontab()
+	if target.system {
+		return uninstallDropIns(target.dir, *apiURL, *configPath, *names, *tag, *dryRun)
+	}
+
+	existing, err := readCrontab()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error reading crontab: %v\n", err)
 		return 1
 	}
+	if !strings.Contains(existing, cronbatBeginMarker) {
+		fmt.Println("no cronbat managed section in crontab")
+		return 0
+	}
+
+	targets, err := uninstallTargets(*apiURL, *configPath, *names, *tag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading jobs: %v\n", err)
+		return 1
+	}
 
-	entries := parseCronbatEntries(crontab, *prefix)
-	if len(entries) == 0 {
-		fmt.Println("no #cronbat tagged entries found in crontab")
+	var kept, removed []string
+	for _, line := range managedLines(existing) {
+		name, ok := managedJobName(line)
+		if (*names == "" && *tag == "") || (ok && targets[name]) {
+			removed = append(removed, line)
+		} else {
+			kept = append(kept, line)
+		}
+	}
+	if len(removed) == 0 {
+		fmt.Println("no matching jobs installed in crontab")
 		return 0
 	}
 
+	// Removing the last entry removes the managed section.
+	managed := ""
+	if len(kept) > 0 {
+		managed = managedBlock(kept)
+	}
+
+	if *dryRun {
+		fmt.Println("--- dry run: would remove the following crontab entries ---")
+		for _, line := range removed {
+			fmt.Println(line)
+		}
+		return 0
+	}
+
+	if err := writeCrontab(mergeCrontab(existing, managed)); err != nil {
+		fmt.Fprintf(os.Stderr, "error writing crontab: %v\n", err)
+		return 1
+	}
+	fmt.Printf("removed %d job(s) from crontab\n", len(removed))
+	return 0
+}
+
+// uninstallTargets returns the jobs to uninstall. Names need no job store,
+// so entries of deleted jobs can be removed.
+func uninstallTargets(apiURL, configPath, names, tag string) (map[string]bool, error) {
+	targets := make(map[string]bool)
+	for _, n := range splitNames(names) {
+		targets[n] = true
+	}
+	if tag != "" {
+		jobs, err := loadJobsForSync(apiURL, configPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, j := range config.FilterJobs(jobs, nil, tag) {
+			targets[j.Name] = true
+		}
+	}
+	return targets, nil
+}
+
+func uninstallDropIns(dir, apiURL, configPath, names, tag string, dryRun bool) int {
+	targets, err := uninstallTargets(apiURL, configPath, names, tag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading jobs: %v\n", err)
+		return 1
+	}
+	if dryRun {
+		fmt.Println("--- dry run: would remove the following drop-ins ---")
+	}
+	_, removed, err := syncDropIns(dir, nil, targets, names == "" && tag == "", dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error removing drop-ins: %v\n", err)
+		return 1
+	}
+	if removed == 0 {
+		fmt.Println("no matching cronbat drop-ins in " + dir)
+	} else if !dryRun {
+		fmt.Printf("removed %d drop-in(s) from %s\n", removed, dir)
+	}
+	return 0
+}
+
+// runCronSyncStatus compares the managed crontab section with the job
+// store. It exits 1 if they differ, so it can run as a drift check.
+func runCronSyncStatus(args []string) int {
+	fs := flag.NewFlagSet("cron-sync status", flag.ExitOnError)
+	apiURL := fs.String("api", "", "API URL (if set, reads jobs from API)")
+	configPath := fs.String("config", "cronbat.yaml", "path to config file (for direct file access)")
+	names := fs.String("name", "", "comma-separated job names to check (default all)")
+	tag := fs.String("tag", "", "only check jobs with this tag")
+	target := addCronTargetFlags(fs)
+	fs.Parse(args)
+
+	jobs, err := loadJobsForSync(*apiURL, *configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading jobs: %v\n", err)
+		return 1
+	}
+	installed, err := target.installed()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading installed entries: %v\n", err)
+		return 1
+	}
+
+	cronbatBin, absConfig := cronSyncPaths(*configPath)
+	known := make(map[string]bool, len(jobs))
+	for _, j := range jobs {
+		known[j.Name] = true
+	}
+
+	drift := false
+	for _, j := range config.FilterJobs(jobs, splitNames(*names), *tag) {
+		have, isInstalled := installed[j.Name]
+		want, err := target.line(j, cronbatBin, absConfig)
+		switch {
+		case errors.Is(err, errUnrepresentable):
+			fmt.Printf("%-10s %s: %v\n", "error", j.Name, err)
+			drift = true
+		case err == nil && j.IsEnabled() && !isInstalled:
+			fmt.Printf("%-10s %s\n", "missing", j.Name)
+			drift = true
+		case err == nil && j.IsEnabled() && have != want:
+			fmt.Printf("%-10s %s\n  - %s\n  + %s\n", "changed", j.Name, have, want)
+			drift = true
+		case err == nil && j.IsEnabled():
+			fmt.Printf("%-10s %s\n", "ok", j.Name)
+		case isInstalled:
+			// Disabled, one-shot, matrix, or no valid cron schedule.
+			fmt.Printf("%-10s %s\n", "extra", j.Name)
+			drift = true
+		}
+	}
+
+	// Entries of jobs that no longer exist.
+	filtered := *names != "" || *tag != ""
+	wanted := make(map[string]bool)
+	for _, n := range splitNames(*names) {
+		wanted[n] = true
+	}
+	var stale []string
+	for name := range installed {
+		if !known[name] && (!filtered || wanted[name]) {
+			stale = append(stale, name)
+		}
+	}
+	sort.Strings(stale)
+	for _, name := range stale {
+		fmt.Printf("%-10s %s\n", "stale", name)
+		drift = true
+	}
+
+	if drift {
+		return 1
+	}
+	return 0
+}
+
+func runCronSyncPreview(args []string) int {
+	fs := flag.NewFlagSet("cron-sync preview", flag.ExitOnError)
+	apiURL := fs.String("api", "", "API URL (if set, reads jobs from API)")
+	configPath := fs.String("config", "cronbat.yaml", "path to config file (for direct file access)")
+	names := fs.String("name", "", "comma-separated job names to preview (default all)")
+	tag := fs.String("tag", "", "only preview jobs with this tag")
+	target := addCronTargetFlags(fs)
+	fs.Parse(args)
+
+	jobs, err := loadJobsForSync(*apiURL, *configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading jobs: %v\n", err)
+		return 1
+	}
+	jobs = config.FilterJobs(jobs, splitNames(*names), *tag)
+	if len(jobs) == 0 {
+		fmt.Fprintln(os.Stderr, "error: no matching jobs")
+		return 1
+	}
+	cronbatBin, absConfig := cronSyncPaths(*configPath)
+
+	code := 0
+	for _, j := range jobs {
+		line, err := target.line(j, cronbatBin, absConfig)
+		switch {
+		case errors.Is(err, errUnrepresentable):
+			fmt.Printf("# %s: error: %v\n", j.Name, err)
+			code = 1
+		case err != nil:
+			fmt.Printf("# %s: skipped: %v\n", j.Name, err)
+		case !j.IsEnabled():
+			fmt.Printf("# %s: skipped: disabled\n", j.Name)
+		default:
+			fmt.Printf("# %s\n%s\n", j.Name, line)
+		}
+	}
+	return code
+}
+
+func runCronSyncImport(args []string) int {
+	fs := flag.NewFlagSet("cron-sync import", flag.ExitOnError)
+	apiURL := fs.String("api", "", "API URL (if set, creates jobs via API)")
+	configPath := fs.String("config", "cronbat.yaml", "path to config file (for direct file access)")
+	dryRun := fs.Bool("dry-run", false, "preview only, don't create jobs")
+	prefix := fs.String("prefix", "cron-", "name prefix for imported jobs")
+	from := fs.String("from", "crontab", "source: crontab, kubernetes (CronJob manifests), or compose (docker-compose file)")
+	file := fs.String("file", "", "manifest or compose file for --from kubernetes|compose (- for stdin)")
+	fs.Parse(args)
+
+	var jobs []*config.Job
+	switch *from {
+	case "crontab":
+		content, err := readCrontab()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading crontab: %v\n", err)
+			return 1
+		}
+		for _, e := range parseCronbatEntries(content, *prefix) {
+			jobs = append(jobs, &config.Job{Name: e.Name, Schedule: e.Schedule, Command: e.Command})
+		}
+		if len(jobs) == 0 {
+			fmt.Println("no #cronbat tagged entries found in crontab")
+			return 0
+		}
+	case "kubernetes", "compose":
+		var err error
+		if jobs, err = importJobsFromFile(*from, *file); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		if len(jobs) == 0 {
+			fmt.Printf("no jobs found in %s\n", *file)
+			return 0
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "error: unknown --from %q (want crontab, kubernetes, or compose)\n", *from)
+		return 1
+	}
+
 	if *dryRun {
 		fmt.Println("--- dry run: would import the following jobs ---")
-		for _, e := range entries {
-			fmt.Printf("  name: %s, schedule: %s, command: %s\n", e.Name, e.Schedule, e.Command)
+		for _, j := range jobs {
+			fmt.Printf("  name: %s, schedule: %s, command: %s\n", j.Name, j.Schedule, j.Command)
 		}
 		return 0
 	}
 
-	for _, e := range entries {
+	for _, j := range jobs {
 		if *apiURL != "" {
-			if err := createJobViaAPI(*apiURL, e); err != nil {
-				fmt.Fprintf(os.Stderr, "error creating job %q via API: %v\n", e.Name, err)
+			if err := createJobViaAPI(*apiURL, j); err != nil {
+				fmt.Fprintf(os.Stderr, "error creating job %q via API: %v\n", j.Name, err)
 				continue
 			}
 		} else {
-			if err := createJobViaFile(*configPath, e); err != nil {
-				fmt.Fprintf(os.Stderr, "error creating job %q: %v\n", e.Name, err)
+			if err := createJobViaFile(*configPath, j); err != nil {
+				fmt.Fprintf(os.Stderr, "error creating job %q: %v\n", j.Name, err)
 				continue
 			}
 		}
-		fmt.Printf("imported job %q\n", e.Name)
+		fmt.Printf("imported job %q\n", j.Name)
 	}
 
 	return 0
 }
 
+// importJobsFromFile converts the Kubernetes manifests or docker-compose
+// file at path, printing what could not be carried over.
+func importJobsFromFile(from, path string) ([]*config.Job, error) {
+	if path == "" {
+		return nil, fmt.Errorf("--file is required with --from %s", from)
+	}
+	// Compose commands refer to the compose file, so it needs a path.
+	if from == "compose" && path == "-" {
+		return nil, errors.New("--from compose needs the compose file's path, not stdin")
+	}
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []*config.Job
+	var warnings []string
+	if from == "kubernetes" {
+		jobs, warnings, err = jobimport.Kubernetes(data)
+	} else {
+		composePath := path
+		if abs, err := filepath.Abs(path); err == nil {
+			composePath = abs
+		}
+		jobs, warnings, err = jobimport.Compose(data, composePath)
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+	}
+	return jobs, err
+}
+
 type importedJob struct {
 	Name     string
 	Schedule string
 	Command  string
 }
 
+// errUnrepresentable marks jobs whose wrap invocation cannot be written as
+// a crontab line.
+var errUnrepresentable = errors.New("command cannot be represented in a crontab line")
+
+// cronSyncPaths returns the cronbat binary and absolute config path that
+// crontab entries invoke.
+func cronSyncPaths(configPath string) (bin, absConfig string) {
+	bin, err := os.Executable()
+	if err != nil {
+		bin = "cronbat"
+	}
+	absConfig, err = filepath.Abs(configPath)
+	if err != nil {
+		absConfig = configPath
+	}
+	return bin, absConfig
+}
+
+// crontabLine returns the crontab entry that runs j through "cronbat wrap".
+// The job's command is passed as one quoted argument, so wrap runs it
+// with sh -c exactly as the daemon would. A non-empty user adds the user
+// column of system crontabs.
+func crontabLine(j *config.Job, cronbatBin, configPath, user string) (string, error) {
+	if j.IsOneShot() {
+		return "", errors.New("cron has no one-shot schedules")
+	}
+	if len(j.Matrix) > 0 {
+		return "", errors.New("cron cannot fan a matrix job out into instances")
+	}
+	schedule, err := scheduler.Normalize(j.Schedule)
+	if err != nil {
+		return "", err
+	}
+	// The trailing newline of a YAML block scalar changes nothing for sh.
+	cmd := strings.TrimRight(j.Command, "\n")
+	command, err := crontab.Command(cronbatBin, "wrap", "--name", j.Name, "--config", configPath, "--", cmd)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errUnrepresentable, err)
+	}
+	if user != "" {
+		if strings.ContainsAny(user, " \t\n\r") {
+			return "", fmt.Errorf("%w: invalid user %q", errUnrepresentable, user)
+		}
+		schedule += " " + user
+	}
+	return fmt.Sprintf("%s %s  %s", schedule, command, cronbatTag), nil
+}
+
 func loadJobsForSync(apiURL, configPath string) ([]*config.Job, error) {
 	if apiURL != "" {
 		return loadJobsFromAPI(apiURL)
@@ -171,7 +551,7 @@ func loadJobsForSync(apiURL, configPath string) ([]*config.Job, error) {
 
 func loadJobsFromAPI(apiURL string) ([]*config.Job, error) {
 	apiURL = strings.TrimRight(apiURL, "/")
-	resp, err := http.Get(apiURL + "/api/v1/jobs")
+	resp, err := apiClient.Get(apiURL + "/api/v1/jobs")
 	if err != nil {
 		return nil, err
 	}
@@ -200,7 +580,14 @@ func loadJobsFromConfig(configPath string) ([]*config.Job, error) {
 	if err != nil {
 		return nil, err
 	}
-	return config.LoadJobs(cfg.JobsDir)
+	jobs, loadErrors, err := config.LoadJobsReport(cfg.JobsDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, le := range loadErrors {
+		fmt.Fprintf(os.Stderr, "warning: skipping job file %s: %s\n", le.Path, le.Error)
+	}
+	return jobs, nil
 }
 
 func readCrontab() (string, error) {
@@ -223,6 +610,55 @@ func writeCrontab(content string) error {
 	return cmd.Run()
 }
 
+// managedBlock returns the managed crontab section holding lines.
+func managedBlock(lines []string) string {
+	var b strings.Builder
+	b.WriteString(cronbatBeginMarker + "\n")
+	for _, line := range lines {
+		b.WriteString(line + "\n")
+	}
+	b.WriteString(cronbatEndMarker + "\n")
+	return b.String()
+}
+
+// managedLines returns the non-empty lines of the managed section of a
+// crontab.
+func managedLines(content string) []string {
+	var lines []string
+	inManaged := false
+	for _, line := range strings.Split(content, "\n") {
+		switch strings.TrimSpace(line) {
+		case cronbatBeginMarker:
+			inManaged = true
+		case cronbatEndMarker:
+			inManaged = false
+		case "":
+		default:
+			if inManaged {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return lines
+}
+
+// managedJobName returns the job a managed crontab entry wraps.
+func managedJobName(line string) (string, bool) {
+	_, name, ok := parseWrapCommand(line)
+	return name, ok
+}
+
+// splitNames splits a comma-separated --name flag.
+func splitNames(names string) []string {
+	var out []string
+	for _, n := range strings.Split(names, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
 func mergeCrontab(existing, managed string) string {
 	lines := strings.Split(existing, "\n")
 
@@ -272,11 +708,11 @@ func mergeCrontab(existing, managed string) string {
 	return s
 }
 
-func parseCronbatEntries(crontab, prefix string) []importedJob {
+func parseCronbatEntries(content, prefix string) []importedJob {
 	var entries []importedJob
 	seen := make(map[string]bool)
 
-	for _, line := range strings.Split(crontab, "\n") {
+	for _, line := range strings.Split(content, "\n") {
 		line = strings.TrimSpace(line)
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
@@ -289,14 +725,20 @@ func parseCronbatEntries(crontab, prefix string) []importedJob {
 		line = strings.Replace(line, cronbatTag, "", 1)
 		line = strings.TrimSpace(line)
 
-		// Parse cron schedule (first 5 fields) and command.
+		// Parse cron schedule (first 5 fields) and command, keeping the
+		// command's spacing and quoting as written.
 		parts := strings.Fields(line)
 		if len(parts) < 6 {
 			continue
 		}
 
 		schedule := strings.Join(parts[:5], " ")
-		command := strings.Join(parts[5:], " ")
+		command := line
+		for i := 0; i < 5; i++ {
+			command = strings.TrimLeft(command, " \t")
+			command = command[strings.IndexAny(command, " \t"):]
+		}
+		command = strings.TrimSpace(command)
 
 		// If the command is a cronbat wrap invocation, extract the original command.
 		if wrapCmd, jobName, ok := parseWrapCommand(command); ok {
@@ -312,6 +754,12 @@ func parseCronbatEntries(crontab, prefix string) []importedJob {
 			continue
 		}
 
+		command, input := crontab.Unescape(command)
+		if input {
+			fmt.Fprintf(os.Stderr, "warning: skipping crontab entry %q: standard input after %% is not supported\n", line)
+			continue
+		}
+
 		// Generate a name from prefix + sanitized command.
 		name := generateJobName(prefix, command)
 		if seen[name] {
@@ -338,7 +786,10 @@ func parseWrapCommand(command string) (wrappedCmd, jobName string, ok bool) {
 		return "", "", false
 	}
 
-	parts := strings.Fields(command)
+	parts, err := crontab.Split(command)
+	if err != nil {
+		return "", "", false
+	}
 	var name string
 	var dashDashIdx int = -1
 
@@ -356,6 +807,7 @@ func parseWrapCommand(command string) (wrappedCmd, jobName string, ok bool) {
 		return "", "", false
 	}
 
+	// wrap joins its arguments the same way.
 	return strings.Join(parts[dashDashIdx+1:], " "), name, true
 }
 
@@ -387,15 +839,10 @@ func generateJobName(prefix, command string) string {
 	return prefix + name
 }
 
-func createJobViaAPI(apiURL string, entry importedJob) error {
+func createJobViaAPI(apiURL string, job *config.Job) error {
 	apiURL = strings.TrimRight(apiURL, "/")
-	payload := map[string]any{
-		"name":     entry.Name,
-		"schedule": entry.Schedule,
-		"command":  entry.Command,
-	}
-	body, _ := json.Marshal(payload)
-	resp, err := http.Post(apiURL+"/api/v1/jobs", "application/json", bytes.NewReader(body))
+	body, _ := json.Marshal(job)
+	resp, err := apiClient.Post(apiURL+"/api/v1/jobs", "application/json", bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
@@ -407,22 +854,33 @@ func createJobViaAPI(apiURL string, entry importedJob) error {
 	return nil
 }
 
-func createJobViaFile(configPath string, entry importedJob) error {
+func createJobViaFile(configPath string, job *config.Job) error {
 	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
 		return err
 	}
-
-	if err := os.MkdirAll(cfg.JobsDir, 0755); err != nil {
+	// The daemon would refuse to schedule a job breaking an error-level
+	// lint rule, so do not write it.
+	defaultTimeout, err := cfg.Defaults.ParseTimeout()
+	if err != nil {
+		return fmt.Errorf("invalid defaults.timeout: %w", err)
+	}
+	linter, err := lint.New(cfg.Lint, defaultTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid lint: %w", err)
+	}
+	violations := linter.Check(job)
+	if err := lint.Err(violations); err != nil {
 		return err
 	}
+	for _, w := range lint.Warnings(violations) {
+		fmt.Fprintf(os.Stderr, "warning: job %q breaks lint rule %s\n", job.Name, w)
+	}
 
-	job := &config.Job{
-		Name:     entry.Name,
-		Schedule: entry.Schedule,
-		Command:  entry.Command,
+	if err := os.MkdirAll(cfg.JobsDir, 0755); err != nil {
+		return err
 	}
 
-	path := filepath.Join(cfg.JobsDir, entry.Name+".yaml")
+	path := filepath.Join(cfg.JobsDir, job.Name+".yaml")
 	return config.SaveJob(path, job)
 }
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+)
+
+const (
+	defaultCronDir = "/etc/cron.d"
+	dropInPrefix   = "cronbat-"
+	dropInHeader   = "# Managed by cronbat cron-sync --system; changes are overwritten."
+)
+
+// cronTarget is where cron-sync keeps its entries: the managed section of
+// the user's crontab, or with --system one drop-in file per job in
+// /etc/cron.d, whose entries carry the user to run as.
+type cronTarget struct {
+	system bool
+	dir    string
+	user   string
+}
+
+func addCronTargetFlags(fs *flag.FlagSet) *cronTarget {
+	t := &cronTarget{}
+	fs.BoolVar(&t.system, "system", false, "use drop-in files in the system cron directory instead of the user crontab")
+	fs.StringVar(&t.dir, "cron-dir", defaultCronDir, "system cron directory for --system")
+	fs.StringVar(&t.user, "user", "root", "user to run jobs without a user field as, for --system")
+	return t
+}
+
+// line returns the entry of j for this target.
+func (t *cronTarget) line(j *config.Job, cronbatBin, configPath string) (string, error) {
+	user := ""
+	if t.system {
+		user = j.User
+		if user == "" {
+			user = t.user
+		}
+	}
+	return crontabLine(j, cronbatBin, configPath, user)
+}
+
+// installed returns the installed entries by job name.
+func (t *cronTarget) installed() (map[string]string, error) {
+	entries := make(map[string]string)
+	if t.system {
+		dropIns, err := readDropIns(t.dir)
+		if err != nil {
+			return nil, err
+		}
+		for name, d := range dropIns {
+			entries[name] = d.line
+		}
+		return entries, nil
+	}
+	existing, err := readCrontab()
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range managedLines(existing) {
+		if name, ok := managedJobName(line); ok {
+			entries[name] = line
+		}
+	}
+	return entries, nil
+}
+
+// dropIn is a cronbat drop-in file in the system cron directory.
+type dropIn struct {
+	path string
+	line string
+}
+
+// dropInPath returns the drop-in file of a job. cron skips files in
+// cron.d whose names contain dots, so dots become underscores.
+func dropInPath(dir, jobName string) string {
+	return filepath.Join(dir, dropInPrefix+strings.ReplaceAll(jobName, ".", "_"))
+}
+
+// readDropIns returns the drop-ins in dir by job name. Files without the
+// managed header are not cronbat's and are left alone.
+func readDropIns(dir string) (map[string]dropIn, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, dropInPrefix+"*"))
+	if err != nil {
+		return nil, err
+	}
+	dropIns := make(map[string]dropIn)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		content := string(data)
+		if !strings.HasPrefix(content, dropInHeader+"\n") {
+			continue
+		}
+		for _, line := range strings.Split(content, "\n") {
+			if name, ok := managedJobName(line); ok && strings.Contains(line, cronbatTag) {
+				dropIns[name] = dropIn{path: path, line: line}
+				break
+			}
+		}
+	}
+	return dropIns, nil
+}
+
+// writeDropIn writes the drop-in of a job. cron ignores drop-ins that are
+// writable by group or others or, in most implementations, not owned by
+// root, so the file gets mode 0644 and, when running as root, root
+// ownership. The file is renamed into place so cron never reads half of
+// it.
+func writeDropIn(dir, jobName, line string) error {
+	tmp, err := os.CreateTemp(dir, ".cronbat-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	// cron.d entries need the environment cron gives user crontabs.
+	content := fmt.Sprintf("%s\nSHELL=/bin/sh\nPATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin\n%s\n", dropInHeader, line)
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	if os.Geteuid() == 0 {
+		if err := os.Chown(tmp.Name(), 0, 0); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), dropInPath(dir, jobName))
+}
+
+// syncDropIns writes the drop-ins of lines (by job name) and removes the
+// drop-ins of the other jobs in remove, or, with removeStale, of every
+// job not in lines.
+func syncDropIns(dir string, lines map[string]string, remove map[string]bool, removeStale, dryRun bool) (written, removed int, err error) {
+	existing, err := readDropIns(dir)
+	if err != nil {
+		return 0, 0, err
+	}
+	if os.Geteuid() != 0 && !dryRun {
+		fmt.Fprintln(os.Stderr, "warning: not running as root; cron may ignore drop-ins not owned by root")
+	}
+
+	names := make([]string, 0, len(lines))
+	for name := range lines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	paths := make(map[string]string, len(names))
+	for _, name := range names {
+		path := dropInPath(dir, name)
+		if other, ok := paths[path]; ok {
+			return 0, 0, fmt.Errorf("jobs %s and %s would share drop-in %s", other, name, path)
+		}
+		paths[path] = name
+	}
+	for _, name := range names {
+		if dryRun {
+			fmt.Printf("%s:\n  %s\n", dropInPath(dir, name), lines[name])
+		} else if err := writeDropIn(dir, name, lines[name]); err != nil {
+			return written, removed, fmt.Errorf("job %s: %w", name, err)
+		}
+		written++
+	}
+
+	var stale []string
+	for name := range existing {
+		_, replaced := paths[existing[name].path]
+		if _, ok := lines[name]; !ok && !replaced && (removeStale || remove[name]) {
+			stale = append(stale, name)
+		}
+	}
+	sort.Strings(stale)
+	for _, name := range stale {
+		path := existing[name].path
+		if dryRun {
+			fmt.Printf("%s: remove\n", path)
+		} else if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return written, removed, fmt.Errorf("job %s: %w", name, err)
+		}
+		removed++
+	}
+	return written, removed, nil
+}
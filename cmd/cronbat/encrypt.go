@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/secrets"
+)
+
+// runEncrypt prints a value encrypted for a job's env, or with
+// --generate-key a new master key.
+func runEncrypt(args []string) int {
+	fs := flag.NewFlagSet("encrypt", flag.ExitOnError)
+	configPath := fs.String("config", "cronbat.yaml", "path to config file with the secrets key")
+	generateKey := fs.Bool("generate-key", false, "print a new master key instead of encrypting")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: cronbat encrypt [--config cronbat.yaml] [VALUE]")
+		fmt.Fprintln(os.Stderr, "       cronbat encrypt --generate-key")
+		fmt.Fprintln(os.Stderr, "Without VALUE, the value is read from stdin. Put the output in a job's env.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *generateKey {
+		key, err := secrets.GenerateKey()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		fmt.Println(key)
+		return 0
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	key, err := encryptKey(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	var value string
+	if fs.NArg() == 1 {
+		value = fs.Arg(0)
+	} else {
+		// Reading stdin keeps the value out of shell history.
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		value = strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+	}
+
+	encrypted, err := secrets.Encrypt(key, value)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	fmt.Println(encrypted)
+	return 0
+}
+
+// encryptKey loads the master key the daemon would use. A missing config
+// file leaves only the environment variable.
+func encryptKey(configPath string) ([]byte, error) {
+	var sc config.SecretsConfig
+	cfg, err := config.LoadConfig(configPath)
+	switch {
+	case err == nil:
+		sc = cfg.Secrets
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	key, err := secrets.LoadKey(sc)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, secrets.ErrNoKey
+	}
+	return key, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+)
+
+// runExport writes job definitions as YAML, JSON, or a tar of job files.
+// With --api it asks the running daemon; otherwise it reads jobs_dir.
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	apiURL := fs.String("api", "", "API URL (if set, exports the running daemon's jobs)")
+	configPath := fs.String("config", "cronbat.yaml", "path to config file (for direct file access)")
+	names := fs.String("name", "", "comma-separated job names to export (default all)")
+	tag := fs.String("tag", "", "only export jobs with this tag")
+	format := fs.String("format", config.FormatYAML, "output format: yaml, json, or tar")
+	outPath := fs.String("out", "", "file to write (default stdout)")
+	fs.Parse(args)
+
+	*format = strings.ToLower(*format)
+	if config.ExportContentType(*format) == "" {
+		fmt.Fprintf(os.Stderr, "error: invalid format %q (want yaml, json, or tar)\n", *format)
+		return 1
+	}
+
+	out := io.Writer(os.Stdout)
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+
+	var err error
+	if *apiURL != "" {
+		err = exportFromAPI(out, *apiURL, *names, *tag, *format)
+	} else {
+		err = exportFromDir(out, *configPath, *names, *tag, *format)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func exportFromDir(out io.Writer, configPath, names, tag, format string) error {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	jobs, loadErrors, err := config.LoadJobsReport(cfg.JobsDir)
+	if err != nil {
+		return fmt.Errorf("loading jobs: %w", err)
+	}
+	for _, le := range loadErrors {
+		fmt.Fprintf(os.Stderr, "warning: skipping %s: %s\n", le.Path, le.Error)
+	}
+	var wanted []string
+	for _, n := range strings.Split(names, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			wanted = append(wanted, n)
+		}
+	}
+	return config.WriteExport(out, config.FilterJobs(jobs, wanted, tag), format, time.Now())
+}
+
+func exportFromAPI(out io.Writer, apiURL, names, tag, format string) error {
+	q := url.Values{"format": {format}}
+	if names != "" {
+		q.Set("name", names)
+	}
+	if tag != "" {
+		q.Set("tag", tag)
+	}
+	resp, err := apiClient.Get(strings.TrimRight(apiURL, "/") + "/api/v1/jobs/export?" + q.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	_, err = io.Copy(out, resp.Body)
+	return err
+}
@@ -2,26 +2,56 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/patrickspencer/cronbat/internal/agent"
+	"github.com/patrickspencer/cronbat/internal/approval"
+	"github.com/patrickspencer/cronbat/internal/backfill"
+	"github.com/patrickspencer/cronbat/internal/batch"
+	"github.com/patrickspencer/cronbat/internal/bus"
+	"github.com/patrickspencer/cronbat/internal/cmdpolicy"
 	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/eventsink"
+	"github.com/patrickspencer/cronbat/internal/gitrev"
+	"github.com/patrickspencer/cronbat/internal/lint"
+	"github.com/patrickspencer/cronbat/internal/loadguard"
+	"github.com/patrickspencer/cronbat/internal/logship"
+	"github.com/patrickspencer/cronbat/internal/notify"
+	"github.com/patrickspencer/cronbat/internal/oidc"
+	"github.com/patrickspencer/cronbat/internal/placement"
+	"github.com/patrickspencer/cronbat/internal/predict"
 	"github.com/patrickspencer/cronbat/internal/realtime"
 	"github.com/patrickspencer/cronbat/internal/runlog"
 	"github.com/patrickspencer/cronbat/internal/runner"
+	"github.com/patrickspencer/cronbat/internal/runqueue"
 	"github.com/patrickspencer/cronbat/internal/scheduler"
+	"github.com/patrickspencer/cronbat/internal/secrets"
+	"github.com/patrickspencer/cronbat/internal/slo"
+	"github.com/patrickspencer/cronbat/internal/spool"
 	"github.com/patrickspencer/cronbat/internal/store"
+	"github.com/patrickspencer/cronbat/internal/supervisor"
+	"github.com/patrickspencer/cronbat/internal/tracing"
+	"github.com/patrickspencer/cronbat/internal/trigger"
+	"github.com/patrickspencer/cronbat/internal/updatecheck"
 	"github.com/patrickspencer/cronbat/internal/web"
+	"github.com/patrickspencer/cronbat/internal/web/api"
 	"github.com/patrickspencer/cronbat/pkg/plugin"
 )
 
@@ -35,10 +65,27 @@ func main() {
 			os.Exit(runCronSync(os.Args[2:]))
 		case "watchdog":
 			os.Exit(runWatchdog(os.Args[2:]))
+		case "store":
+			os.Exit(runStore(os.Args[2:]))
+		case "report":
+			os.Exit(runReport(os.Args[2:]))
+		case "migrate":
+			os.Exit(runMigrate(os.Args[2:]))
+		case "verify-logs":
+			os.Exit(runVerifyLogs(os.Args[2:]))
+		case "export":
+			os.Exit(runExport(os.Args[2:]))
+		case "agent":
+			os.Exit(runAgent(os.Args[2:]))
+		case "encrypt":
+			os.Exit(runEncrypt(os.Args[2:]))
+		case "replica":
+			os.Exit(runReplica(os.Args[2:]))
 		}
 	}
 
 	configPath := flag.String("config", "cronbat.yaml", "path to configuration file")
+	startPaused := flag.Bool("paused", false, "pause the scheduler on startup; it stays paused until resumed through the API")
 	flag.Parse()
 
 	cfg, err := config.LoadConfig(*configPath)
@@ -55,32 +102,162 @@ func main() {
 		log.Fatalf("failed to create jobs directory %s: %v", cfg.JobsDir, err)
 	}
 
+	// Start serving liveness/readiness before the slow startup steps so
+	// orchestrators can tell "starting" from "dead".
+	readiness := api.NewReadiness("store", "jobs", "scheduler", "api")
+	srv := web.NewServer(cfg.Listen, readiness, httpTimeouts(cfg.HTTP))
+	go func() {
+		if err := srv.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("http server error: %v", err)
+		}
+	}()
+
 	// Open SQLite store.
 	dbPath := filepath.Join(cfg.DataDir, "cronbat.db")
-	st, err := store.NewSQLiteStore(dbPath)
+	st, err := openSQLiteStore(cfg)
 	if err != nil {
 		log.Fatalf("failed to open store: %v", err)
 	}
 	defer st.Close()
 	log.Printf("store opened at %s", dbPath)
+	flushInterval, err := cfg.Store.ParseFlushInterval()
+	if err != nil {
+		log.Fatalf("invalid store.flush_interval %q: %v", cfg.Store.FlushInterval, err)
+	}
+	if flushInterval > 0 {
+		st.StartWriteBuffer(flushInterval, cfg.Store.FlushMaxBatch)
+		log.Printf("buffering run writes: flush_interval=%s", flushInterval)
+	}
+	tailFlushInterval, err := cfg.Store.ParseTailFlushInterval()
+	if err != nil {
+		log.Fatalf("invalid store.tail_flush_interval %q: %v", cfg.Store.TailFlushInterval, err)
+	}
+	heartbeatInterval, err := cfg.Store.ParseHeartbeatInterval()
+	if err != nil {
+		log.Fatalf("invalid store.heartbeat_interval %q: %v", cfg.Store.HeartbeatInterval, err)
+	}
+	checkpointInterval, err := cfg.Store.ParseCheckpointInterval()
+	if err != nil {
+		log.Fatalf("invalid store.checkpoint_interval %q: %v", cfg.Store.CheckpointInterval, err)
+	}
+	readiness.MarkDone("store")
+
+	commandPolicy, err := cmdpolicy.New(cfg.CommandPolicy)
+	if err != nil {
+		log.Fatalf("invalid command_policy: %v", err)
+	}
+
+	defaultTimeout, err := cfg.Defaults.ParseTimeout()
+	if err != nil {
+		log.Fatalf("invalid defaults.timeout %q: %v", cfg.Defaults.Timeout, err)
+	}
+	linter, err := lint.New(cfg.Lint, defaultTimeout)
+	if err != nil {
+		log.Fatalf("invalid lint: %v", err)
+	}
+	secretsKey, err := secrets.LoadKey(cfg.Secrets)
+	if err != nil {
+		log.Fatalf("invalid secrets: %v", err)
+	}
+	if err := cfg.Defaults.AutoDisable.Validate(); err != nil {
+		log.Fatalf("invalid defaults.auto_disable: %v", err)
+	}
+	if err := cfg.LoadGuard.Validate(); err != nil {
+		log.Fatalf("invalid load_guard: %v", err)
+	}
+	if cfg.Defaults.TailBytes > cfg.Store.MaxTailBytes {
+		log.Fatalf("defaults.tail_bytes %d exceeds store.max_tail_bytes %d", cfg.Defaults.TailBytes, cfg.Store.MaxTailBytes)
+	}
+	if cfg.Auth.Required && len(cfg.APIKeys) == 0 && !cfg.Auth.OIDC.IsEnabled() {
+		log.Fatalf("auth.required needs api_keys or auth.oidc to sign in with")
+	}
+	for _, k := range cfg.APIKeys {
+		if k.Role != "" && !api.ValidRole(k.Role) {
+			log.Fatalf("api_keys %s: unknown role %q (want admin, operator, or viewer)", k.Name, k.Role)
+		}
+	}
+	var oidcProvider *oidc.Provider
+	if cfg.Auth.OIDC.IsEnabled() {
+		oc := cfg.Auth.OIDC
+		for group, role := range oc.Roles {
+			if !api.ValidRole(role) {
+				log.Fatalf("auth.oidc.roles %s: unknown role %q (want admin, operator, or viewer)", group, role)
+			}
+		}
+		if oc.DefaultRole != "" && !api.ValidRole(oc.DefaultRole) {
+			log.Fatalf("auth.oidc.default_role: unknown role %q", oc.DefaultRole)
+		}
+		if oc.ClientSecret, err = secrets.Decrypt(secretsKey, oc.ClientSecret); err != nil {
+			log.Fatalf("auth.oidc.client_secret: %v", err)
+		}
+		if oidcProvider, err = oidc.New(oc); err != nil {
+			log.Fatalf("invalid auth.oidc: %v", err)
+		}
+	}
+	sessionTTL := api.DefaultSessionTTL
+	if cfg.Auth.SessionTTL != "" {
+		sessionTTL, err = time.ParseDuration(cfg.Auth.SessionTTL)
+		if err != nil || sessionTTL <= 0 {
+			log.Fatalf("invalid auth.session_ttl %q", cfg.Auth.SessionTTL)
+		}
+	}
 
 	// Load jobs.
-	jobs, err := config.LoadJobs(cfg.JobsDir)
+	jobs, jobLoadErrors, err := config.LoadJobsReport(cfg.JobsDir)
 	if err != nil {
 		log.Fatalf("failed to load jobs from %s: %v", cfg.JobsDir, err)
 	}
+	for i, le := range jobLoadErrors {
+		log.Printf("ERROR: skipping job file %s: %s", le.Path, le.Error)
+		if !cfg.QuarantineInvalidJobs {
+			continue
+		}
+		dst, err := config.QuarantineJobFile(le.Path)
+		if err != nil {
+			log.Printf("ERROR: quarantining job file %s: %v", le.Path, err)
+			continue
+		}
+		jobLoadErrors[i].QuarantinedPath = dst
+		log.Printf("quarantined job file %s to %s", le.Path, dst)
+	}
 	log.Printf("loaded %d job(s)", len(jobs))
+	readiness.MarkDone("jobs")
 
 	// Build job lookup map protected by mutex for runtime job management.
 	var jobsMu sync.RWMutex
+	// jobsVersion counts changes to jobMap and job states, for the jobs
+	// list's ETag.
+	var jobsVersion atomic.Uint64
 	jobMap := make(map[string]*config.Job, len(jobs))
 	jobStateMap := make(map[string]string, len(jobs))
+	// enabledAt is when each job was last enabled through the API; failures
+	// before it do not count toward auto_disable.
+	enabledAt := make(map[string]time.Time)
+	// snoozeUntil is when each snoozed (paused with a deadline) job resumes.
+	snoozeUntil := make(map[string]time.Time)
+	savedStates, err := st.ListJobStates(context.Background())
+	if err != nil {
+		log.Printf("ERROR: failed to load saved job states: %v", err)
+	}
 	for _, j := range jobs {
 		jobMap[j.Name] = j
 		if j.IsEnabled() {
 			jobStateMap[j.Name] = "started"
-		} else {
-			jobStateMap[j.Name] = "stopped"
+			continue
+		}
+		jobStateMap[j.Name] = "stopped"
+		// A job file enabled by hand wins over the saved state; otherwise a
+		// paused job stays paused across restarts.
+		if saved := savedStates[j.Name]; saved != nil {
+			if saved.State == "paused" {
+				jobStateMap[j.Name] = "paused"
+				if saved.SnoozeUntil != nil {
+					snoozeUntil[j.Name] = *saved.SnoozeUntil
+				}
+			}
+			if j.DisabledReason == "" {
+				j.DisabledReason = saved.DisabledReason
+			}
 		}
 	}
 
@@ -110,6 +287,40 @@ func main() {
 		return result
 	}
 
+	getJobLoadErrors := func() []config.LoadError {
+		jobsMu.RLock()
+		defer jobsMu.RUnlock()
+		result := make([]config.LoadError, len(jobLoadErrors))
+		copy(result, jobLoadErrors)
+		return result
+	}
+
+	// persistJobStateLocked saves the runtime state of the named jobs, or
+	// forgets it for jobs that no longer exist. Callers hold jobsMu.
+	persistJobStateLocked := func(names ...string) {
+		jobsVersion.Add(1)
+		ctx := context.Background()
+		for _, name := range names {
+			j, ok := jobMap[name]
+			if !ok {
+				delete(snoozeUntil, name)
+				if err := st.DeleteJobState(ctx, name); err != nil {
+					log.Printf("ERROR: failed to forget state of job %q: %v", name, err)
+				}
+				continue
+			}
+			js := &store.JobState{JobName: name, State: jobStateMap[name], DisabledReason: j.DisabledReason}
+			if js.State != "paused" {
+				delete(snoozeUntil, name)
+			} else if t, ok := snoozeUntil[name]; ok {
+				js.SnoozeUntil = &t
+			}
+			if err := st.SaveJobState(ctx, js); err != nil {
+				log.Printf("ERROR: failed to save state of job %q: %v", name, err)
+			}
+		}
+	}
+
 	getJobState := func(name string) string {
 		jobsMu.RLock()
 		defer jobsMu.RUnlock()
@@ -128,12 +339,7 @@ func main() {
 	}
 
 	getConfigSnapshot := func() *config.Config {
-		cp := *cfg
-		if cfg.RunLogs.Enabled != nil {
-			v := *cfg.RunLogs.Enabled
-			cp.RunLogs.Enabled = &v
-		}
-		return &cp
+		return cfg.Redacted()
 	}
 
 	events := realtime.NewBroker()
@@ -144,6 +350,39 @@ func main() {
 		cfg.RunLogs.MaxTotalMB*1024*1024,
 	)
 
+	runLogManager.SetRetentionProvider(func() map[string]runlog.JobRetention {
+		jobsMu.RLock()
+		defer jobsMu.RUnlock()
+		policies := make(map[string]runlog.JobRetention)
+		for name, j := range jobMap {
+			if j.LogRetention == nil {
+				continue
+			}
+			policies[name] = runlog.JobRetention{
+				MaxRuns:       j.LogRetention.MaxRuns,
+				MaxBytes:      j.LogRetention.MaxMB * 1024 * 1024,
+				RetentionDays: j.LogRetention.RetentionDays,
+			}
+		}
+		return policies
+	})
+
+	runLogManager.SetPinnedProvider(func() (map[string]bool, error) {
+		return st.PinnedRunIDs(context.Background())
+	})
+
+	if ac := cfg.RunLogs.Archive; cfg.RunLogs.IsEnabled() && ac.Enabled {
+		archive, err := newRunLogArchive(ac)
+		if err != nil {
+			log.Fatalf("invalid run_logs.archive config: %v", err)
+		}
+		runLogManager.SetArchive(archive, ac.AfterDays)
+		log.Printf("run log archival enabled: after_days=%d location=%s", ac.AfterDays, archive.Location(""))
+		if ac.AfterDays >= cfg.RunLogs.RetentionDays {
+			log.Printf("WARN: run_logs.archive.after_days (%d) is not below run_logs.retention_days (%d); logs will be deleted before they are archived", ac.AfterDays, cfg.RunLogs.RetentionDays)
+		}
+	}
+
 	if cfg.RunLogs.IsEnabled() {
 		if err := os.MkdirAll(runLogManager.BaseDir(), 0755); err != nil {
 			log.Fatalf("failed to create run logs directory %s: %v", runLogManager.BaseDir(), err)
@@ -164,199 +403,1451 @@ func main() {
 
 	r := runner.NewRunner()
 
-	// executeJob runs a job and records the result in the store.
-	executeJob := func(jobName string, trigger string) {
+	// agents runs jobs with runs_on on remote hosts; nil while agent
+	// connections are off.
+	var agents *agent.Hub
+	if cfg.Agents.Token != "" {
+		agents = agent.NewHub(cfg.Agents.Token)
+		log.Printf("agent connections enabled at %s", agent.ConnectPath)
+	}
+
+	// localRuns counts runs in progress on this host, for placement.
+	var localRuns atomic.Int64
+	// placeRun picks the host for a runs_on job: this one or an agent.
+	placeRun := func(selector map[string]string) (placement.Host, bool) {
+		hosts := []placement.Host{{
+			ID:       "local",
+			Hostname: cfg.Host.Name,
+			Labels:   cfg.Host.Labels,
+			Running:  int(localRuns.Load()),
+			Local:    true,
+		}}
+		if agents != nil {
+			for _, info := range agents.List() {
+				hosts = append(hosts, placement.Host{
+					ID:       info.ID,
+					Hostname: info.Hostname,
+					Labels:   info.Labels,
+					Running:  info.Running,
+				})
+			}
+		}
+		return placement.Place(selector, hosts)
+	}
+
+	// submitRun submits a run to the run queue; assigned once the queue exists.
+	var submitRun func(item runqueue.Item)
+	// autoDisableJob is set once the job management closures are defined.
+	var autoDisableJob func(name, reason string) error
+	// archiveOneShotJob archives an auto_archive one-shot job after its
+	// run; set once archiveJob is defined.
+	var archiveOneShotJob func(name string)
+	// deduper remembers the latest manual or bus run of each job with a
+	// dedupe_window.
+	deduper := &runqueue.Deduper{}
+	// enqueueRun queues a manual or bus run and returns its ID. Within the
+	// job's dedupe_window of the previous trigger it queues nothing and
+	// returns that run's ID with deduped set. A correlation ID is recorded
+	// on the run and passed to it as CRONBAT_CORRELATION_ID.
+	enqueueRun := func(jobName, trigger, triggeredBy, correlationID string, tc *store.TriggerContext) (runID string, deduped bool) {
 		jobsMu.RLock()
-		j, ok := jobMap[jobName]
-		if ok {
-			j = cloneJob(j)
+		var window time.Duration
+		var prefix string
+		if j, ok := jobMap[jobName]; ok {
+			window, _ = j.ParseDedupeWindow()
+			prefix = j.RunIDPrefix
 		}
 		jobsMu.RUnlock()
-		if !ok {
-			log.Printf("WARN: job %q not found for execution", jobName)
-			return
-		}
-		if !j.IsEnabled() {
-			log.Printf("DEBUG: skipping disabled job %q", jobName)
-			return
+
+		item := runqueue.Item{JobName: jobName, Trigger: trigger, TriggeredBy: triggeredBy, TriggerContext: tc, CorrelationID: correlationID, RunID: store.NewPrefixedRunID(prefix)}
+		if correlationID != "" {
+			item.Env = map[string]string{"CRONBAT_CORRELATION_ID": correlationID}
 		}
+		return deduper.Submit(item, window, submitRun)
+	}
 
-		timeout, err := j.ParseTimeout()
+	// jobsCommit returns the git HEAD of the jobs directory, or "" when it
+	// is not in a repository.
+	jobsCommit := func() string {
+		commit, err := gitrev.Head(cfg.JobsDir)
 		if err != nil {
-			log.Printf("ERROR: invalid timeout for job %q: %v", jobName, err)
-			return
+			return ""
 		}
+		return commit
+	}
 
-		jctx := plugin.JobContext{
-			JobName:  j.Name,
-			Schedule: j.Schedule,
-			Trigger:  trigger,
-			Env:      j.Env,
-			Metadata: j.Metadata,
+	// recordSkippedRun stores a run that never started, e.g. because the
+	// load guard deferred or skipped it, and returns its ID. An empty runID
+	// gets a new one.
+	recordSkippedRun := func(j *config.Job, item runqueue.Item, runID, status, reason string) string {
+		if runID == "" {
+			runID = store.NewPrefixedRunID(j.RunIDPrefix)
 		}
-
-		log.Printf("executing job %q (trigger=%s)", jobName, trigger)
-		startedAt := time.Now().UTC()
-		runID := store.NewRunID()
-
+		now := time.Now().UTC()
 		run := &store.Run{
-			ID:        runID,
-			JobName:   jobName,
-			Status:    "running",
-			StartedAt: startedAt,
-			Trigger:   trigger,
+			ID:             runID,
+			JobName:        j.Name,
+			Status:         status,
+			StartedAt:      now,
+			FinishedAt:     &now,
+			Trigger:        item.Trigger,
+			ErrorMsg:       reason,
+			JobsCommit:     jobsCommit(),
+			TriggeredBy:    item.TriggeredBy,
+			TriggerContext: item.TriggerContext,
+			CorrelationID:  item.CorrelationID,
+			ParentRunID:    item.ParentRunID,
+			Matrix:         item.Matrix,
 		}
 		if err := st.RecordRun(context.Background(), run); err != nil {
-			log.Printf("ERROR: failed to record run start: %v", err)
+			log.Printf("ERROR: failed to record %s run: %v", status, err)
 		}
 		events.Publish(realtime.Event{
-			Type:    "run.started",
-			JobName: jobName,
-			RunID:   runID,
-			Status:  "running",
-			Trigger: trigger,
+			Type:    "run.completed",
+			JobName: j.Name,
+			RunID:   run.ID,
+			Status:  status,
+			Trigger: item.Trigger,
 		})
+		return run.ID
+	}
 
-		var runOpts runner.RunOptions
-		var fileWriters *runlog.RunWriters
-		if cfg.RunLogs.IsEnabled() {
-			writers, err := runLogManager.OpenRunWriters(jobName, runID)
-			if err != nil {
-				log.Printf("WARN: failed to open persistent log files for run %s: %v", runID, err)
-			} else {
-				fileWriters = writers
-				runOpts.ExtraStdout = fileWriters.Stdout
-				runOpts.ExtraStderr = fileWriters.Stderr
-			}
+	// reportRun records a run executed outside the daemon, reported through
+	// the API or left in the spool by "cronbat wrap". It may only create a
+	// run or update one that was itself reported for the same job.
+	reportRun := func(ctx context.Context, rep *spool.Report) error {
+		if err := st.RecordReportedRun(ctx, rep.Run()); err != nil {
+			return err
 		}
+		events.Publish(realtime.Event{
+			Type:    "run.completed",
+			JobName: rep.JobName,
+			RunID:   rep.ID,
+			Status:  rep.Status,
+			Trigger: rep.Trigger,
+		})
+		return nil
+	}
 
-		runOpts.WorkDir = j.WorkingDir
-		result := r.Run(context.Background(), j.Command, jctx, timeout, &runOpts)
+	var guardMu sync.Mutex
+	loadDeferrals := make(map[string]int)
 
-		if fileWriters != nil {
-			closeErr := fileWriters.Close()
-			result.StdoutLogPath = fileWriters.StdoutPath
-			result.StderrLogPath = fileWriters.StderrPath
-			result.StdoutLogBytes = fileWriters.Stdout.WrittenBytes()
-			result.StderrLogBytes = fileWriters.Stderr.WrittenBytes()
-			result.StdoutTruncated = fileWriters.Stdout.Truncated()
-			result.StderrTruncated = fileWriters.Stderr.Truncated()
-			if closeErr != nil {
-				result.LogStorageWarning = closeErr.Error()
-			}
+	// checkLoadGuard reports whether the job may start now. When the host is
+	// over a threshold it records a deferred (resubmitted with backoff) or
+	// skipped run.
+	checkLoadGuard := func(j *config.Job, item runqueue.Item) bool {
+		guard := cfg.LoadGuard.Merge(j.LoadGuard)
+		reason, err := loadguard.Check(guard)
+		if err != nil {
+			log.Printf("WARN: load guard check failed for job %q: %v", j.Name, err)
+			return true
 		}
 
-		finishedAt := time.Now().UTC()
-		status := "success"
-		if result.ExitCode != 0 || result.Error != "" {
-			status = "failure"
+		guardMu.Lock()
+		defer guardMu.Unlock()
+		if reason == "" {
+			delete(loadDeferrals, j.Name)
+			return true
 		}
 
-		run.Status = status
-		run.ExitCode = result.ExitCode
-		run.FinishedAt = &finishedAt
-		run.DurationMs = result.DurationMs
-		run.StdoutTail = result.Stdout
-		run.StderrTail = result.Stderr
-		run.ErrorMsg = result.Error
-
-		if err := st.RecordRun(context.Background(), run); err != nil {
-			log.Printf("ERROR: failed to record run result: %v", err)
+		attempt := loadDeferrals[j.Name] + 1
+		if guard.Action == "skip" || attempt > guard.MaxRetries {
+			delete(loadDeferrals, j.Name)
+			log.Printf("WARN: skipping job %q under host pressure: %s", j.Name, reason)
+			runID := recordSkippedRun(j, item, item.RunID, "skipped:load", reason)
+			if item.Done != nil {
+				item.Done(runID, "skipped:load")
+			}
+			return false
 		}
-		events.Publish(realtime.Event{
-			Type:    "run.completed",
-			JobName: jobName,
-			RunID:   runID,
-			Status:  status,
-			Trigger: trigger,
-		})
+		loadDeferrals[j.Name] = attempt
 
-		log.Printf("job %q completed: status=%s duration=%dms", jobName, status, result.DurationMs)
+		// retry_after is validated on load; Backoff falls back to a
+		// minute when it is unset.
+		base, _ := time.ParseDuration(guard.RetryAfter)
+		delay := loadguard.Backoff(base, attempt)
+		log.Printf("WARN: deferring job %q for %s under host pressure: %s", j.Name, delay, reason)
+		recordSkippedRun(j, item, "", "deferred:load", fmt.Sprintf("%s; retry %d/%d in %s", reason, attempt, guard.MaxRetries, delay))
+		time.AfterFunc(delay, func() {
+			submitRun(item)
+		})
+		return false
 	}
 
-	// Set up scheduler.
-	sched := scheduler.NewScheduler(func(jobName string) {
-		executeJob(jobName, "schedule")
-	})
-	applyScheduleLocked := func(j *config.Job) error {
-		sched.RemoveJob(j.Name)
-		if !j.IsEnabled() {
-			return nil
+	// Runs are traced when tracing is configured; a nil tracer records
+	// nothing.
+	var tracer *tracing.Tracer
+	if cfg.Tracing.IsEnabled() {
+		tracer, err = tracing.New(cfg.Tracing)
+		if err != nil {
+			log.Fatalf("invalid tracing: %v", err)
 		}
-		schedule, err := scheduler.ParseSchedule(j.Schedule)
+	}
+
+	// Finished runs' logs are shipped when log_shipping is configured; a
+	// nil shipper ships nothing.
+	var shipper *logship.Shipper
+	if cfg.LogShipping.IsEnabled() {
+		shipper, err = logship.New(cfg.LogShipping)
 		if err != nil {
-			return err
+			log.Fatalf("invalid log_shipping: %v", err)
 		}
-		sched.AddJob(j.Name, schedule)
-		return nil
 	}
 
-	isSafeJobName := func(name string) bool {
-		if name == "" {
-			return false
+	// notifyRun posts a finished run to the job's matching notify_urls in
+	// the background. Failures are logged without the URL, which often
+	// embeds a token.
+	notifyClient := &http.Client{Timeout: notify.Timeout}
+	// updateIncident opens an incident once a job has failed n.Failures
+	// runs in a row and resolves it on the next success. Open incidents are
+	// stored, so a restart neither opens a duplicate nor forgets to resolve.
+	var incidentMu sync.Mutex
+	updateIncident := func(i int, n config.NotifyConfig, p notify.Payload) {
+		incidentMu.Lock()
+		defer incidentMu.Unlock()
+		ctx := context.Background()
+		target := notify.IncidentTarget(n)
+		inc, err := st.GetIncident(ctx, p.Job, target)
+		if err != nil {
+			log.Printf("ERROR: failed to look up incident of job %q: %v", p.Job, err)
+			return
 		}
-		for _, ch := range name {
-			isLower := ch >= 'a' && ch <= 'z'
-			isUpper := ch >= 'A' && ch <= 'Z'
-			isDigit := ch >= '0' && ch <= '9'
-			if isLower || isUpper || isDigit || ch == '-' || ch == '_' || ch == '.' {
-				continue
+		switch {
+		case (p.Status == "success" || p.Status == "warning") && inc != nil:
+			if err := notify.Resolve(ctx, notifyClient, n, inc.DedupKey, p); err != nil {
+				log.Printf("WARN: notify_urls[%d] of job %q failed to resolve incident %s: %v", i, p.Job, inc.DedupKey, err)
+				return
 			}
-			return false
+			if err := st.CloseIncident(ctx, p.Job, target); err != nil {
+				log.Printf("ERROR: failed to record resolved incident of job %q: %v", p.Job, err)
+			}
+			log.Printf("job %q succeeded; resolved %s incident %s", p.Job, n.Type, inc.DedupKey)
+		case p.Status == "failure" && inc == nil:
+			runs, err := st.ListRuns(ctx, store.ListOpts{JobName: p.Job, Limit: n.FailureThreshold() + 20})
+			if err != nil {
+				log.Printf("ERROR: failed to list runs of job %q: %v", p.Job, err)
+				return
+			}
+			failures := 0
+			for _, r := range runs {
+				if r.Status == "success" || r.Status == "warning" {
+					break
+				}
+				if r.Status == "failure" {
+					failures++
+				}
+			}
+			if failures < n.FailureThreshold() {
+				return
+			}
+			key := notify.DedupKey(p)
+			if err := notify.Trigger(ctx, notifyClient, n, key, p); err != nil {
+				log.Printf("WARN: notify_urls[%d] of job %q failed to open an incident for run %s: %v", i, p.Job, p.RunID, err)
+				return
+			}
+			if err := st.OpenIncident(ctx, &store.Incident{JobName: p.Job, Target: target, DedupKey: key, RunID: p.RunID}); err != nil {
+				log.Printf("ERROR: failed to record incident of job %q: %v", p.Job, err)
+			}
+			log.Printf("ERROR: job %q failed %d runs in a row; opened %s incident %s", p.Job, failures, n.Type, key)
 		}
-		return true
 	}
-
-	validateJob := func(j *config.Job) error {
-		j.Name = strings.TrimSpace(j.Name)
-		j.Schedule = strings.TrimSpace(j.Schedule)
-		j.Command = strings.TrimSpace(j.Command)
-		j.WorkingDir = strings.TrimSpace(j.WorkingDir)
-		j.Executor = strings.TrimSpace(j.Executor)
-		j.Timeout = strings.TrimSpace(j.Timeout)
-
-		if j.Name == "" {
-			return errors.New("job name is required")
-		}
-		if !isSafeJobName(j.Name) {
-			return errors.New("invalid job name: use only letters, numbers, '.', '-', '_'")
+	notifyRun := func(j *config.Job, run *store.Run, parent *tracing.Span) {
+		p := notify.Payload{
+			Job:            run.JobName,
+			RunID:          run.ID,
+			Status:         run.Status,
+			Trigger:        run.Trigger,
+			TriggeredBy:    run.TriggeredBy,
+			ExitCode:       run.ExitCode,
+			DurationMs:     run.DurationMs,
+			StartedAt:      run.StartedAt,
+			Error:          run.ErrorMsg,
+			StdoutTail:     run.StdoutTail,
+			StderrTail:     run.StderrTail,
+			TriggerContext: run.TriggerContext,
+			CorrelationID:  run.CorrelationID,
+		}
+		if run.FinishedAt != nil {
+			p.FinishedAt = *run.FinishedAt
+		}
+		// The notify span ends once every notification is sent.
+		var wg sync.WaitGroup
+		for i, n := range j.NotifyURLs {
+			if n.IsIncident() {
+				wg.Add(1)
+				go func(i int, n config.NotifyConfig) {
+					defer wg.Done()
+					updateIncident(i, n, p)
+				}(i, n)
+				continue
+			}
+			if !n.Matches(run.Status) {
+				continue
+			}
+			wg.Add(1)
+			go func(i int, n config.NotifyConfig) {
+				defer wg.Done()
+				if err := notify.Send(context.Background(), notifyClient, n, p); err != nil {
+					log.Printf("WARN: notify_urls[%d] of job %q failed for run %s: %v", i, j.Name, run.ID, err)
+				}
+			}(i, n)
 		}
-		if j.Schedule == "" {
-			return errors.New("job schedule is required")
+		if len(j.NotifyURLs) > 0 {
+			span := parent.Child("notify", time.Now())
+			go func() {
+				wg.Wait()
+				span.End()
+			}()
 		}
-		if j.Command == "" {
-			return errors.New("job command is required")
+	}
+
+	// evaluateSLO reports a job's compliance with its slo block, or nil if
+	// it has none. A job that never succeeded is measured against
+	// max_interval from the later of the window start, daemon start, and
+	// its last enable.
+	daemonStartedAt := time.Now().UTC()
+	evaluateSLO := func(ctx context.Context, j *config.Job) (*slo.Report, error) {
+		obj, ok, err := slo.FromConfig(j.SLO)
+		if err != nil || !ok {
+			return nil, err
+		}
+		now := time.Now().UTC()
+		since := now.Add(-obj.Window)
+		outcomes, err := st.GetJobOutcomes(ctx, j.Name, since)
+		if err != nil {
+			return nil, err
 		}
-		if j.Executor == "" {
-			j.Executor = "shell"
+		tracked := since
+		if daemonStartedAt.After(tracked) {
+			tracked = daemonStartedAt
 		}
-		if _, err := j.ParseTimeout(); err != nil {
-			return fmt.Errorf("invalid timeout: %w", err)
+		jobsMu.RLock()
+		if t, ok := enabledAt[j.Name]; ok && t.After(tracked) {
+			tracked = t
 		}
-		return nil
+		jobsMu.RUnlock()
+		report := slo.Evaluate(j.Name, obj, slo.History{
+			Successes:   outcomes.Successes,
+			Failures:    outcomes.Failures,
+			LastSuccess: outcomes.LastSuccess,
+			Since:       tracked,
+		}, now)
+		return &report, nil
 	}
 
-	saveJobLocked := func(j *config.Job) error {
-		return config.SaveJob(jobFilePath(j), j)
-	}
+	// checkSLO alerts when a job starts or stops violating its SLO. Disabled
+	// jobs are not alerted on.
+	var sloMu sync.Mutex
+	sloViolations := make(map[string]map[string]bool)
+	checkSLO := func(j *config.Job) {
+		var active map[string]bool
+		if j.IsEnabled() {
+			report, err := evaluateSLO(context.Background(), j)
+			if err != nil {
+				log.Printf("ERROR: failed to evaluate SLO of job %q: %v", j.Name, err)
+				return
+			}
+			if report != nil {
+				active = make(map[string]bool, len(report.Violations))
+				for _, v := range report.Violations {
+					active[v] = true
+				}
+			}
+		}
 
-	for _, j := range jobs {
-		if err := applyScheduleLocked(j); err != nil {
-			log.Printf("ERROR: invalid schedule for job %q (%s), skipping: %v", j.Name, j.Schedule, err)
-			continue
+		sloMu.Lock()
+		prev := sloViolations[j.Name]
+		if len(active) == 0 {
+			delete(sloViolations, j.Name)
+		} else {
+			sloViolations[j.Name] = active
 		}
-		if next, ok := sched.NextRunTime(j.Name); ok {
-			log.Printf("scheduled job %q, next run at %s", j.Name, next.Format(time.RFC3339))
+		sloMu.Unlock()
+
+		for v := range active {
+			if prev[v] {
+				continue
+			}
+			log.Printf("ERROR: job %q is violating its SLO: %s", j.Name, v)
+			events.Publish(realtime.Event{Type: "slo.violated", JobName: j.Name, Action: v})
+		}
+		for v := range prev {
+			if active[v] {
+				continue
+			}
+			log.Printf("job %q recovered from SLO violation: %s", j.Name, v)
+			events.Publish(realtime.Event{Type: "slo.recovered", JobName: j.Name, Action: v})
 		}
 	}
-	sched.Start()
 
-	cleanupCtx, cleanupCancel := context.WithCancel(context.Background())
-	cleanupEvery, err := time.ParseDuration(cfg.RunLogs.CleanupInterval)
-	if err != nil || cleanupEvery <= 0 {
-		cleanupEvery = time.Hour
-	}
-	if cfg.RunLogs.IsEnabled() {
+	// checkAutoDisable disables a job whose failures within its auto_disable
+	// window reached the limit. Only an explicit enable turns it back on.
+	checkAutoDisable := func(j *config.Job, runID string) {
+		policy := j.EffectiveAutoDisable(cfg.Defaults.AutoDisable)
+		if !policy.Active() {
+			return
+		}
+		within, err := policy.ParseWithin()
+		if err != nil {
+			return
+		}
+		since := time.Now().UTC().Add(-within)
+		jobsMu.RLock()
+		if t, ok := enabledAt[j.Name]; ok && t.After(since) {
+			since = t
+		}
+		jobsMu.RUnlock()
+		failures, err := st.CountRunsSince(context.Background(), j.Name, "failure", since)
+		if err != nil {
+			log.Printf("ERROR: failed to count failures of job %q: %v", j.Name, err)
+			return
+		}
+		if failures < policy.Failures {
+			return
+		}
+
+		reason := fmt.Sprintf("auto-disabled after %d failures within %s (last run %s)", failures, policy.Within, runID)
+		if err := autoDisableJob(j.Name, reason); err != nil {
+			log.Printf("ERROR: failed to auto-disable job %q: %v", j.Name, err)
+			return
+		}
+		log.Printf("ERROR: job %q %s; enable it to resume scheduling", j.Name, reason)
+		if err := st.RecordAudit(context.Background(), &store.AuditEntry{
+			Actor:   "cronbat",
+			Action:  "auto_disable",
+			JobName: j.Name,
+			Detail:  reason,
+		}); err != nil {
+			log.Printf("ERROR: failed to record audit entry: %v", err)
+		}
+		events.Publish(realtime.Event{
+			Type:    "job.auto_disabled",
+			JobName: j.Name,
+			RunID:   runID,
+			Status:  "disabled",
+		})
+	}
+
+	// applyPinnedVersion switches j to its pinned last known good
+	// definition, if any, and returns the version that will run. A pinned
+	// job runs that definition until unpinned.
+	applyPinnedVersion := func(ctx context.Context, j *config.Job) (version string, pinned bool) {
+		version = j.Version()
+		snap, err := st.GetLastGoodJob(ctx, j.Name)
+		if err != nil {
+			log.Printf("ERROR: failed to read last good version of job %q: %v", j.Name, err)
+			return version, false
+		}
+		if snap == nil || !snap.Pinned {
+			return version, false
+		}
+		pj, err := config.ParseJobYAML([]byte(snap.Definition))
+		if err != nil {
+			log.Printf("ERROR: pinned version %s of job %q is unreadable, running current version: %v", snap.Version, j.Name, err)
+			return version, false
+		}
+		j.ApplyPinned(pj)
+		return snap.Version, true
+	}
+
+	// executeJob runs a job and records the result in the store.
+	// activeRuns holds the runs executing now by run ID, with the process
+	// ID once a local run's process starts.
+	var activeMu sync.Mutex
+	activeRuns := make(map[string]*api.ActiveRun)
+	listActiveRuns := func() []api.ActiveRun {
+		activeMu.Lock()
+		defer activeMu.Unlock()
+		out := make([]api.ActiveRun, 0, len(activeRuns))
+		for _, ar := range activeRuns {
+			out = append(out, *ar)
+		}
+		return out
+	}
+
+	// runPreCheck runs a job's pre_check as the job itself would run, on
+	// the agent when the run was placed on one. It returns why the run
+	// should be skipped, or "" when the check passed.
+	runPreCheck := func(ctx context.Context, j *config.Job, jctx plugin.JobContext, remote *agent.Remote) string {
+		timeout, _ := j.ParsePreCheckTimeout()
+		checkRunner := r
+		if remote != nil {
+			rem := *remote
+			rem.Env = runner.JobEnv(jctx)
+			checkRunner = &runner.Runner{Executor: &rem}
+		}
+		result := checkRunner.Run(ctx, j.PreCheck, jctx, timeout, &runner.RunOptions{
+			WorkDir:    j.WorkingDir,
+			User:       j.User,
+			Group:      j.Group,
+			Sandbox:    sandboxOptions(j.Sandbox),
+			Shell:      j.Shell,
+			LoginShell: j.LoginShell,
+			TailBytes:  1024,
+		})
+		if result.ExitCode == 0 && result.Error == "" {
+			return ""
+		}
+		reason := fmt.Sprintf("pre_check exited %d", result.ExitCode)
+		if result.ExitCode <= 0 {
+			reason = "pre_check failed: " + result.Error
+		}
+		if line := lastLine(result.Stderr); line != "" {
+			reason += ": " + line
+		} else if line := lastLine(result.Stdout); line != "" {
+			reason += ": " + line
+		}
+		return reason
+	}
+
+	// runMatrix records the parent run of a matrix job and queues one
+	// instance per combination of its values. The parent finishes, and
+	// notifies, once every instance has; it succeeds only if all of them
+	// did.
+	runMatrix := func(j *config.Job, item runqueue.Item, version string, pinned bool, done func(runID, status string)) {
+		startedAt := time.Now().UTC()
+		runID := item.RunID
+		if runID == "" {
+			runID = store.NewPrefixedRunID(j.RunIDPrefix)
+		}
+		run := &store.Run{
+			ID:             runID,
+			JobName:        j.Name,
+			Status:         "running",
+			StartedAt:      startedAt,
+			Trigger:        item.Trigger,
+			JobsCommit:     jobsCommit(),
+			JobVersion:     version,
+			Pinned:         pinned,
+			TriggeredBy:    item.TriggeredBy,
+			TriggerContext: item.TriggerContext,
+			CorrelationID:  item.CorrelationID,
+		}
+		if !item.ScheduledAt.IsZero() {
+			scheduledAt := item.ScheduledAt
+			run.ScheduledAt = &scheduledAt
+			run.DriftMs = item.EnqueuedAt.Sub(scheduledAt).Milliseconds()
+		}
+		if err := st.RecordRun(context.Background(), run); err != nil {
+			log.Printf("ERROR: failed to record run start: %v", err)
+		}
+		events.Publish(realtime.Event{
+			Type:    "run.started",
+			JobName: j.Name,
+			RunID:   runID,
+			Status:  "running",
+			Trigger: item.Trigger,
+		})
+
+		instances := j.MatrixInstances()
+		log.Printf("executing job %q (trigger=%s) as %d matrix instances of run %s", j.Name, item.Trigger, len(instances), runID)
+		var mu sync.Mutex
+		remaining, failed, warned := len(instances), 0, 0
+		finish := func(_, status string) {
+			mu.Lock()
+			remaining--
+			switch status {
+			case "success", "skipped:condition":
+			case "warning":
+				warned++
+			default:
+				failed++
+			}
+			last := remaining == 0
+			mu.Unlock()
+			if !last {
+				return
+			}
+
+			finishedAt := time.Now().UTC()
+			run.Status = "success"
+			switch {
+			case failed > 0:
+				run.Status = "failure"
+				run.ErrorMsg = fmt.Sprintf("%d of %d matrix instances did not succeed", failed, len(instances))
+			case warned > 0:
+				run.Status = "warning"
+			}
+			run.FinishedAt = &finishedAt
+			run.DurationMs = finishedAt.Sub(startedAt).Milliseconds()
+			if err := st.RecordRun(context.Background(), run); err != nil {
+				log.Printf("ERROR: failed to record run result: %v", err)
+			}
+			events.Publish(realtime.Event{
+				Type:    "run.completed",
+				JobName: j.Name,
+				RunID:   runID,
+				Status:  run.Status,
+				Trigger: item.Trigger,
+			})
+			notifyRun(j, run, nil)
+			if run.Status == "failure" {
+				checkAutoDisable(j, runID)
+			}
+			if j.SLO != nil {
+				checkSLO(j)
+			}
+			log.Printf("job %q completed: status=%s duration=%dms", j.Name, run.Status, run.DurationMs)
+			done(runID, run.Status)
+			if j.AutoArchive && j.IsOneShot() && item.Trigger == "schedule" {
+				archiveOneShotJob(j.Name)
+			}
+		}
+		for _, values := range instances {
+			env := make(map[string]string, len(item.Env)+len(values))
+			for k, v := range item.Env {
+				env[k] = v
+			}
+			for k, v := range config.MatrixEnv(values) {
+				env[k] = v
+			}
+			submitRun(runqueue.Item{
+				JobName:        j.Name,
+				Trigger:        item.Trigger,
+				EnqueuedAt:     time.Now().UTC(),
+				ScheduledAt:    item.ScheduledAt,
+				Env:            env,
+				TriggeredBy:    item.TriggeredBy,
+				TriggerContext: item.TriggerContext,
+				CorrelationID:  item.CorrelationID,
+				ParentRunID:    runID,
+				Matrix:         values,
+				Done:           finish,
+			})
+		}
+	}
+
+	executeJob := func(ctx context.Context, item runqueue.Item) {
+		jobName, trigger := item.JobName, item.Trigger
+		done := func(runID, status string) {
+			if item.Done != nil {
+				item.Done(runID, status)
+			}
+		}
+
+		jobsMu.RLock()
+		j, ok := jobMap[jobName]
+		if ok {
+			j = cloneJob(j)
+		}
+		jobsMu.RUnlock()
+		if !ok {
+			log.Printf("WARN: job %q not found for execution", jobName)
+			done("", "skipped")
+			return
+		}
+		if !j.IsEnabled() {
+			log.Printf("DEBUG: skipping disabled job %q", jobName)
+			done("", "skipped")
+			return
+		}
+		// Service jobs only run under the supervisor.
+		isService := j.IsService()
+		if isService && trigger != supervisor.TriggerStart && trigger != supervisor.TriggerRestart {
+			log.Printf("WARN: skipping %s run of service job %q", trigger, jobName)
+			done("", "skipped")
+			return
+		}
+
+		version, pinned := applyPinnedVersion(context.Background(), j)
+		if len(j.Matrix) > 0 && item.ParentRunID == "" {
+			runMatrix(j, item, version, pinned, done)
+			return
+		}
+		// The parent run of a matrix instance notifies and archives for it.
+		instance := item.ParentRunID != ""
+
+		timeout, err := j.EffectiveTimeout(defaultTimeout)
+		if err != nil {
+			log.Printf("ERROR: invalid timeout for job %q: %v", jobName, err)
+			done("", "skipped")
+			return
+		}
+		if isService {
+			// Services run until they exit or are stopped.
+			timeout = 0
+		} else if !checkLoadGuard(j, item) {
+			return
+		}
+
+		runHost := cfg.Host.Name
+		var remote *agent.Remote
+		var placeErr error
+		if len(j.RunsOn) > 0 {
+			host, ok := placeRun(j.RunsOn)
+			switch {
+			case !ok && agents == nil:
+				// Another daemon sharing the store may match.
+				log.Printf("job %q runs_on %s does not match this host, skipping", jobName, placement.FormatSelector(j.RunsOn))
+				done("", "skipped")
+				return
+			case !ok:
+				runHost = ""
+				placeErr = fmt.Errorf("no connected agent matches runs_on %s", placement.FormatSelector(j.RunsOn))
+			case !host.Local:
+				runHost = host.Hostname
+				remote, placeErr = agents.Remote(host.ID)
+			}
+		}
+
+		env := j.Env
+		if len(item.Env) > 0 {
+			env = make(map[string]string, len(j.Env)+len(item.Env))
+			for k, v := range j.Env {
+				env[k] = v
+			}
+			for k, v := range item.Env {
+				env[k] = v
+			}
+		}
+		// Encrypted values are decrypted only for the run itself.
+		env, secretNames, envErr := secrets.DecryptEnv(secretsKey, env)
+		jctx := plugin.JobContext{
+			JobName:  j.Name,
+			Schedule: j.Schedule,
+			Trigger:  trigger,
+			Env:      env,
+			Metadata: j.Metadata,
+		}
+		if j.PreCheck != "" && envErr == nil && placeErr == nil {
+			if reason := runPreCheck(ctx, j, jctx, remote); reason != "" {
+				log.Printf("job %q skipped: %s", jobName, reason)
+				runID := recordSkippedRun(j, item, item.RunID, "skipped:condition", reason)
+				done(runID, "skipped:condition")
+				return
+			}
+		}
+
+		if pinned {
+			log.Printf("executing job %q (trigger=%s, pinned version %s)", jobName, trigger, version)
+		} else {
+			log.Printf("executing job %q (trigger=%s)", jobName, trigger)
+		}
+		startedAt := time.Now().UTC()
+		runID := item.RunID
+		if runID == "" {
+			runID = store.NewPrefixedRunID(j.RunIDPrefix)
+		}
+
+		// The run's trace starts when its schedule fired, with a span for
+		// each step: the fire, the queue wait, the process, the bookkeeping
+		// after it, and notifications.
+		traceStart := startedAt
+		if !item.EnqueuedAt.IsZero() {
+			traceStart = item.EnqueuedAt
+		}
+		if !item.ScheduledAt.IsZero() {
+			traceStart = item.ScheduledAt
+		}
+		span := tracer.Start("run "+jobName, traceStart)
+		span.SetAttr("cronbat.job", jobName)
+		span.SetAttr("cronbat.run_id", runID)
+		span.SetAttr("cronbat.trigger", trigger)
+		span.SetAttr("host.name", runHost)
+		if !item.ScheduledAt.IsZero() && !item.EnqueuedAt.IsZero() {
+			span.Child("schedule.fire", item.ScheduledAt).EndAt(item.EnqueuedAt)
+		}
+		if !item.EnqueuedAt.IsZero() {
+			span.Child("queue.wait", item.EnqueuedAt).EndAt(startedAt)
+		}
+
+		run := &store.Run{
+			ID:             runID,
+			JobName:        jobName,
+			Status:         "running",
+			StartedAt:      startedAt,
+			Trigger:        trigger,
+			JobsCommit:     jobsCommit(),
+			JobVersion:     version,
+			Pinned:         pinned,
+			TriggeredBy:    item.TriggeredBy,
+			Host:           runHost,
+			TraceID:        span.TraceID(),
+			TriggerContext: item.TriggerContext,
+			CorrelationID:  item.CorrelationID,
+			ParentRunID:    item.ParentRunID,
+			Matrix:         item.Matrix,
+		}
+		if !item.ScheduledAt.IsZero() {
+			scheduledAt := item.ScheduledAt
+			run.ScheduledAt = &scheduledAt
+			run.DriftMs = item.EnqueuedAt.Sub(scheduledAt).Milliseconds()
+		}
+		if err := st.RecordRun(context.Background(), run); err != nil {
+			log.Printf("ERROR: failed to record run start: %v", err)
+		}
+		events.Publish(realtime.Event{
+			Type:    "run.started",
+			JobName: jobName,
+			RunID:   runID,
+			Status:  "running",
+			Trigger: trigger,
+		})
+
+		active := &api.ActiveRun{
+			RunID:     runID,
+			JobName:   jobName,
+			Trigger:   trigger,
+			Priority:  item.Priority,
+			Host:      runHost,
+			StartedAt: startedAt,
+		}
+		activeMu.Lock()
+		activeRuns[runID] = active
+		activeMu.Unlock()
+		defer func() {
+			activeMu.Lock()
+			delete(activeRuns, runID)
+			activeMu.Unlock()
+		}()
+
+		if heartbeatInterval > 0 {
+			defer startHeartbeat(st, runID, heartbeatInterval)()
+		}
+
+		// Warn, without stopping the run, once it outlives warn_after.
+		if warnAfter, _ := j.ParseWarnAfter(); warnAfter > 0 {
+			slowTimer := time.AfterFunc(warnAfter, func() {
+				log.Printf("WARN: job %q run %s still running after %s", jobName, runID, warnAfter)
+				events.Publish(realtime.Event{
+					Type:    "run.slow",
+					JobName: jobName,
+					RunID:   runID,
+					Status:  "running",
+					Trigger: trigger,
+				})
+			})
+			defer slowTimer.Stop()
+		}
+
+		// Warn when the typical duration says this run will still be going
+		// at the job's next fire.
+		if durations, err := st.RecentDurations(context.Background(), jobName, predict.Samples); err != nil {
+			log.Printf("ERROR: failed to get run durations of job %q: %v", jobName, err)
+		} else if len(durations) >= predict.MinSamples {
+			if sched, err := scheduler.ParseSchedule(j.Schedule); err == nil {
+				typical := time.Duration(predict.Percentile(durations, 50)) * time.Millisecond
+				if e := predict.Run(sched, startedAt, typical); e.Overrun {
+					log.Printf("WARN: job %q run %s is expected to finish at %s, after its next fire at %s",
+						jobName, runID, e.ExpectedFinish.Format(time.RFC3339), e.WindowEnd.Format(time.RFC3339))
+					events.Publish(realtime.Event{
+						Type:    "run.overrun_predicted",
+						JobName: jobName,
+						RunID:   runID,
+						Status:  "running",
+						Trigger: trigger,
+					})
+				}
+			}
+		}
+
+		runOpts := runner.RunOptions{
+			DiscardStdout: !j.CapturesStdout(),
+			DiscardStderr: !j.CapturesStderr(),
+			MergeStderr:   j.MergesStderr(),
+			TailBytes:     j.EffectiveTailBytes(cfg.Defaults.TailBytes, cfg.Store.MaxTailBytes),
+		}
+		if j.Output != nil {
+			filters := runlog.FilterOptions{StripANSI: j.Output.StripANSI, NormalizeCRLF: j.Output.NormalizeCRLF}
+			if filters.Active() {
+				runOpts.WrapOutput = filters.Wrap
+			}
+		}
+		keepStdout := !runOpts.DiscardStdout
+		keepStderr := !runOpts.DiscardStderr && !runOpts.MergeStderr
+		var fileWriters *runlog.RunWriters
+		if cfg.RunLogs.IsEnabled() && (keepStdout || keepStderr) {
+			writers, err := runLogManager.OpenStreamWriters(jobName, runID, keepStdout, keepStderr)
+			if err != nil {
+				log.Printf("WARN: failed to open persistent log files for run %s: %v", runID, err)
+			} else {
+				fileWriters = writers
+				if fileWriters.Stdout != nil {
+					runOpts.ExtraStdout = fileWriters.Stdout
+				}
+				if fileWriters.Stderr != nil {
+					runOpts.ExtraStderr = fileWriters.Stderr
+				}
+			}
+		}
+		var systemLog *runlog.SystemLog
+		if j.Output != nil && j.Output.SystemLog != "" && (keepStdout || keepStderr) {
+			sl, err := runlog.OpenSystemLog(j.Output.SystemLog, jobName, runID)
+			if err != nil {
+				log.Printf("WARN: failed to open %s for run %s: %v", j.Output.SystemLog, runID, err)
+			} else {
+				systemLog = sl
+				if keepStdout {
+					runOpts.ExtraStdout = teeWriter(runOpts.ExtraStdout, sl.Stdout())
+				}
+				if keepStderr {
+					runOpts.ExtraStderr = teeWriter(runOpts.ExtraStderr, sl.Stderr())
+				}
+			}
+		}
+
+		runOpts.WorkDir = j.WorkingDir
+		runOpts.User = j.User
+		runOpts.Group = j.Group
+		runOpts.Sandbox = sandboxOptions(j.Sandbox)
+		runOpts.Shell = j.Shell
+		runOpts.LoginShell = j.LoginShell
+		runOpts.MaxOutputBytes = j.MaxOutputBytes
+		runOpts.KillOnOutputLimit = j.KillOnOutputLimit
+		runOpts.OnStart = func(pid int) {
+			activeMu.Lock()
+			active.PID = pid
+			activeMu.Unlock()
+		}
+		if tailFlushInterval > 0 {
+			runOpts.TailInterval = tailFlushInterval
+			runOpts.OnTail = func(stdout, stderr string) {
+				if err := st.UpdateRunTails(context.Background(), runID, stdout, stderr); err != nil {
+					log.Printf("WARN: failed to save output tails of run %s: %v", runID, err)
+				}
+			}
+		}
+		// The job joins the run's trace under the process span.
+		processSpan := span.Child("process", time.Now())
+		if tp := processSpan.Traceparent(); tp != "" {
+			traced := make(map[string]string, len(env)+1)
+			for k, v := range env {
+				traced[k] = v
+			}
+			traced[tracing.TraceparentEnv] = tp
+			env = traced
+			jctx.Env = env
+		}
+		rc := buildRunContext(runID, j, jctx, timeout, version)
+		rc.Hostname = runHost
+		jobRunner := r
+		var outputPath string
+		switch {
+		case envErr != nil:
+			jobRunner = &runner.Runner{Executor: unavailableExecutor{envErr}}
+		case placeErr != nil:
+			jobRunner = &runner.Runner{Executor: unavailableExecutor{placeErr}}
+		case remote != nil:
+			remote.Env = runner.JobEnv(jctx)
+			jobRunner = &runner.Runner{Executor: remote}
+			log.Printf("job %q run %s placed on agent %s", jobName, runID, runHost)
+			rc.Env = runner.RedactEnv(envSlice(remote.Env))
+			rc.WorkingDir = j.WorkingDir
+		default:
+			localRuns.Add(1)
+			defer localRuns.Add(-1)
+			// Remote runs write outputs on the agent's host and have none.
+			if path, err := runner.NewOutputFile(j.User, j.Group); err != nil {
+				log.Printf("WARN: failed to create output file for run %s: %v", runID, err)
+			} else {
+				outputPath = path
+				jctx.Env = make(map[string]string, len(env)+1)
+				for k, v := range env {
+					jctx.Env[k] = v
+				}
+				jctx.Env[runner.OutputEnv] = path
+				if sb := runOpts.Sandbox; sb != nil && sb.ReadOnly {
+					sb.WritablePaths = append(append([]string(nil), sb.WritablePaths...), path)
+				}
+			}
+		}
+		for _, name := range secretNames {
+			if _, ok := rc.Env[name]; ok {
+				rc.Env[name] = runner.RedactedValue
+			}
+		}
+		if err := st.SaveRunContext(context.Background(), rc); err != nil {
+			log.Printf("ERROR: failed to record context of run %s: %v", runID, err)
+		}
+		result := jobRunner.Run(ctx, j.Command, jctx, timeout, &runOpts)
+		if result.OutputLimited && !j.KillOnOutputLimit {
+			log.Printf("WARN: run %s of job %s exceeded max_output_bytes (%d); further output was dropped", runID, j.Name, j.MaxOutputBytes)
+		}
+		if systemLog != nil {
+			if err := systemLog.Close(); err != nil {
+				log.Printf("WARN: failed to close %s for run %s: %v", j.Output.SystemLog, runID, err)
+			}
+		}
+		if outputPath != "" {
+			outputs, err := runner.ReadOutputs(outputPath)
+			if err != nil {
+				log.Printf("WARN: failed to read outputs of run %s: %v", runID, err)
+			}
+			run.Outputs = outputs
+		}
+
+		if fileWriters != nil {
+			closeErr := fileWriters.Close()
+			result.StdoutLogPath = fileWriters.StdoutPath
+			result.StderrLogPath = fileWriters.StderrPath
+			if fileWriters.Stdout != nil {
+				result.StdoutLogBytes = fileWriters.Stdout.WrittenBytes()
+				result.StdoutTruncated = fileWriters.Stdout.Truncated()
+				run.StdoutSHA256 = fileWriters.Stdout.Checksum()
+			}
+			if fileWriters.Stderr != nil {
+				result.StderrLogBytes = fileWriters.Stderr.WrittenBytes()
+				result.StderrTruncated = fileWriters.Stderr.Truncated()
+				run.StderrSHA256 = fileWriters.Stderr.Checksum()
+			}
+			if closeErr != nil {
+				result.LogStorageWarning = closeErr.Error()
+			}
+		}
+
+		finishedAt := time.Now().UTC()
+		status := "failure"
+		if result.Exited {
+			status = j.ExitStatus(result.ExitCode)
+		}
+		if errors.Is(context.Cause(ctx), runqueue.ErrPreempted) {
+			status = "preempted"
+			result.Error = runqueue.ErrPreempted.Error()
+		}
+		if errors.Is(context.Cause(ctx), supervisor.ErrStopped) {
+			status = "stopped"
+			result.Error = supervisor.ErrStopped.Error()
+		}
+
+		processSpan.SetAttr("process.exit_code", result.ExitCode)
+		if status == "failure" {
+			msg := result.Error
+			if msg == "" {
+				msg = fmt.Sprintf("exit code %d", result.ExitCode)
+			}
+			processSpan.SetError(msg)
+			span.SetError(msg)
+		}
+		processSpan.EndAt(finishedAt)
+		hooksSpan := span.Child("hooks", finishedAt)
+		span.SetAttr("cronbat.status", status)
+		defer span.End()
+
+		run.Status = status
+		run.ExitCode = result.ExitCode
+		run.FinishedAt = &finishedAt
+		run.DurationMs = result.DurationMs
+		run.StdoutTail = result.Stdout
+		run.StderrTail = result.Stderr
+		run.ErrorMsg = result.Error
+
+		if err := st.RecordRun(context.Background(), run); err != nil {
+			log.Printf("ERROR: failed to record run result: %v", err)
+		}
+		shipper.Ship(logship.Run{
+			JobName:    jobName,
+			RunID:      runID,
+			Status:     status,
+			StartedAt:  run.StartedAt,
+			StdoutPath: result.StdoutLogPath,
+			StderrPath: result.StderrLogPath,
+			StdoutTail: result.Stdout,
+			StderrTail: result.Stderr,
+		})
+		if status == "success" && !pinned {
+			if def, err := config.MarshalJobYAML(j); err != nil {
+				log.Printf("ERROR: failed to snapshot job %q: %v", jobName, err)
+			} else if err := st.SaveLastGoodJob(context.Background(), &store.JobSnapshot{
+				JobName:    jobName,
+				Version:    version,
+				Definition: string(def),
+				RunID:      runID,
+				RecordedAt: finishedAt,
+			}); err != nil {
+				log.Printf("ERROR: failed to record last good version of job %q: %v", jobName, err)
+			}
+		}
+		events.Publish(realtime.Event{
+			Type:    "run.completed",
+			JobName: jobName,
+			RunID:   runID,
+			Status:  status,
+			Trigger: trigger,
+		})
+		if !instance {
+			notifyRun(j, run, span)
+			if status == "failure" {
+				checkAutoDisable(j, runID)
+			}
+			if j.SLO != nil {
+				checkSLO(j)
+			}
+		}
+		hooksSpan.End()
+
+		log.Printf("job %q completed: status=%s duration=%dms", jobName, status, result.DurationMs)
+		if status != "preempted" {
+			done(runID, status)
+		}
+		if j.AutoArchive && j.IsOneShot() && trigger == "schedule" && status != "preempted" && !instance {
+			archiveOneShotJob(jobName)
+		}
+	}
+
+	// Runs wait here for a free slot when max_concurrent_runs is reached.
+	queue := runqueue.New(cfg.MaxConcurrentRuns, executeJob)
+	submitRun = func(item runqueue.Item) {
+		jobsMu.RLock()
+		if j, ok := jobMap[item.JobName]; ok {
+			item.Priority = j.Priority
+			item.Preempt = j.Preempt
+		}
+		jobsMu.RUnlock()
+		queue.Submit(item)
+	}
+	listQueuedRuns := func() []api.QueuedRun {
+		pending := queue.Pending()
+		out := make([]api.QueuedRun, len(pending))
+		for i, it := range pending {
+			out[i] = api.QueuedRun{
+				JobName:     it.JobName,
+				Trigger:     it.Trigger,
+				Priority:    it.Priority,
+				RunID:       it.RunID,
+				TriggeredBy: it.TriggeredBy,
+				EnqueuedAt:  it.EnqueuedAt,
+			}
+		}
+		return out
+	}
+
+	// Set up scheduler.
+	sched := scheduler.NewScheduler(func(jobName string, scheduledAt time.Time) {
+		submitRun(runqueue.Item{
+			JobName:     jobName,
+			Trigger:     "schedule",
+			EnqueuedAt:  time.Now().UTC(),
+			ScheduledAt: scheduledAt.UTC(),
+		})
+	})
+	sched.OnDormant(func(jobName string) {
+		log.Printf("WARN: schedule for job %q has no future occurrence; job is dormant", jobName)
+		events.Publish(realtime.Event{
+			Type:    "job.dormant",
+			JobName: jobName,
+			Action:  "dormant",
+		})
+	})
+	sched.OnClockJump(func(delta time.Duration) {
+		log.Printf("WARN: wall clock jumped by %s; re-evaluating schedules", delta.Round(time.Second))
+	})
+	// Service jobs are kept running by the supervisor instead of being
+	// scheduled. Their runs bypass the run queue.
+	services := supervisor.New(func(ctx context.Context, jobName, trigger string) {
+		executeJob(ctx, runqueue.Item{JobName: jobName, Trigger: trigger, EnqueuedAt: time.Now().UTC()})
+	})
+	// unscheduleLocked stops a job's schedule or service.
+	unscheduleLocked := func(name string) {
+		sched.RemoveJob(name)
+		services.Stop(name)
+	}
+	applyScheduleLocked := func(j *config.Job) error {
+		unscheduleLocked(j.Name)
+		if !j.IsEnabled() {
+			return nil
+		}
+		if j.IsService() {
+			initial, maxDelay, err := j.ParseServiceBackoff()
+			if err != nil {
+				return err
+			}
+			// A run that stayed up for a minute resets the backoff.
+			services.Start(j.Name, supervisor.Backoff{Initial: initial, Max: maxDelay, ResetAfter: time.Minute})
+			return nil
+		}
+		policy, err := scheduler.ParseDSTPolicy(j.DSTPolicy)
+		if err != nil {
+			return err
+		}
+		schedule, err := scheduler.ParseScheduleWithDST(j.Schedule, policy)
+		if err != nil {
+			return err
+		}
+		// A one-shot job whose time has passed has nothing left to schedule.
+		if at, ok := schedule.(scheduler.AtSchedule); ok && !at.At.After(time.Now()) {
+			return nil
+		}
+		sched.AddJob(j.Name, schedule)
+		return nil
+	}
+
+	isSafeJobName := func(name string) bool {
+		if name == "" {
+			return false
+		}
+		for _, ch := range name {
+			isLower := ch >= 'a' && ch <= 'z'
+			isUpper := ch >= 'A' && ch <= 'Z'
+			isDigit := ch >= '0' && ch <= '9'
+			if isLower || isUpper || isDigit || ch == '-' || ch == '_' || ch == '.' {
+				continue
+			}
+			return false
+		}
+		return true
+	}
+
+	validateJob := func(j *config.Job) error {
+		j.Name = strings.TrimSpace(j.Name)
+		j.Schedule = strings.TrimSpace(j.Schedule)
+		j.Command = strings.TrimSpace(j.Command)
+		j.WorkingDir = strings.TrimSpace(j.WorkingDir)
+		j.Executor = strings.TrimSpace(j.Executor)
+		j.Timeout = strings.TrimSpace(j.Timeout)
+		j.DSTPolicy = strings.TrimSpace(j.DSTPolicy)
+		j.User = strings.TrimSpace(j.User)
+		j.Group = strings.TrimSpace(j.Group)
+		j.Shell = strings.TrimSpace(j.Shell)
+
+		if j.Name != "" && !isSafeJobName(j.Name) {
+			return errors.New("invalid job name: use only letters, numbers, '.', '-', '_'")
+		}
+		// Job.Validate covers the checks shared with jobs loaded from disk.
+		if err := j.Validate(); err != nil {
+			return err
+		}
+		if _, err := scheduler.ParseDSTPolicy(j.DSTPolicy); err != nil {
+			return err
+		}
+		if err := commandPolicy.CheckJob(j); err != nil {
+			return err
+		}
+		if err := lint.Err(linter.Check(j)); err != nil {
+			return err
+		}
+		if _, _, err := secrets.DecryptEnv(secretsKey, j.Env); err != nil {
+			return fmt.Errorf("invalid %w", err)
+		}
+		if j.Executor == "" {
+			j.Executor = "shell"
+		}
+		// Remote runs switch user and sandbox on the agent's host.
+		if len(j.RunsOn) == 0 {
+			if err := runner.ValidateRunAs(j.User, j.Group); err != nil {
+				return err
+			}
+			if err := runner.ValidateSandbox(sandboxOptions(j.Sandbox), j.User, j.Group); err != nil {
+				return err
+			}
+		}
+		if lr := j.LogRetention; lr != nil && (lr.MaxRuns < 0 || lr.MaxMB < 0 || lr.RetentionDays < 0) {
+			return errors.New("invalid log_retention: values must not be negative")
+		}
+		if err := runner.ValidateShell(j.Shell, j.LoginShell); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	// validateOneShotTime rejects a one-shot schedule whose time has
+	// already passed, unless current (the job being updated, or nil) has
+	// the same schedule and so may legitimately have run already.
+	validateOneShotTime := func(j, current *config.Job) error {
+		if !j.IsOneShot() || (current != nil && current.Schedule == j.Schedule) {
+			return nil
+		}
+		at, err := scheduler.ParseAt(j.Schedule)
+		if err != nil {
+			return fmt.Errorf("invalid schedule: %w", err)
+		}
+		if !at.At.After(time.Now()) {
+			return fmt.Errorf("invalid schedule: %s is in the past", at.At.Format(time.RFC3339))
+		}
+		return nil
+	}
+
+	saveJobLocked := func(j *config.Job) error {
+		return config.SaveJob(jobFilePath(j), j)
+	}
+
+	for _, j := range jobs {
+		for _, w := range j.Lint() {
+			log.Printf("WARN: job %q: %s", j.Name, w)
+		}
+		violations := linter.Check(j)
+		for _, w := range lint.Warnings(violations) {
+			log.Printf("WARN: job %q breaks lint rule %s", j.Name, w)
+		}
+		if err := lint.Err(violations); err != nil {
+			log.Printf("ERROR: job %q is not scheduled: %v", j.Name, err)
+			continue
+		}
+		if len(j.RunsOn) == 0 {
+			if err := runner.ValidateRunAs(j.User, j.Group); err != nil {
+				log.Printf("ERROR: job %q cannot switch to user=%q group=%q, runs will fail: %v", j.Name, j.User, j.Group, err)
+			}
+			if err := runner.ValidateSandbox(sandboxOptions(j.Sandbox), j.User, j.Group); err != nil {
+				log.Printf("ERROR: job %q sandbox cannot be applied, runs will fail: %v", j.Name, err)
+			}
+		}
+		if err := runner.ValidateShell(j.Shell, j.LoginShell); err != nil {
+			log.Printf("ERROR: job %q shell %q is not usable, runs will fail: %v", j.Name, j.Shell, err)
+		}
+		if _, _, err := secrets.DecryptEnv(secretsKey, j.Env); err != nil {
+			log.Printf("ERROR: job %q %v, runs will fail", j.Name, err)
+		}
+		if err := applyScheduleLocked(j); err != nil {
+			log.Printf("ERROR: invalid schedule for job %q (%s), skipping: %v", j.Name, j.Schedule, err)
+			continue
+		}
+		if next, ok := sched.NextRunTime(j.Name); ok {
+			log.Printf("scheduled job %q, next run at %s", j.Name, next.Format(time.RFC3339))
+		}
+	}
+	// catchUpOneShots runs one-shot jobs whose time passed while cronbat was
+	// down or the scheduler was paused, unless their scheduled run already
+	// happened.
+	catchUpOneShots := func() {
+		jobsMu.RLock()
+		var oneShots []*config.Job
+		for _, j := range jobMap {
+			if j.IsOneShot() && j.IsEnabled() {
+				oneShots = append(oneShots, j)
+			}
+		}
+		jobsMu.RUnlock()
+		for _, j := range oneShots {
+			at, err := scheduler.ParseAt(j.Schedule)
+			if err != nil || at.At.After(time.Now()) {
+				continue
+			}
+			runs, err := st.ListRuns(context.Background(), store.ListOpts{JobName: j.Name, Since: at.At})
+			if err != nil {
+				log.Printf("ERROR: failed to check runs of one-shot job %q: %v", j.Name, err)
+				continue
+			}
+			ran := false
+			for _, r := range runs {
+				ran = ran || r.Trigger == "schedule"
+			}
+			if ran {
+				continue
+			}
+			log.Printf("one-shot job %q missed its run at %s; running now", j.Name, at.At.Format(time.RFC3339))
+			submitRun(runqueue.Item{
+				JobName:     j.Name,
+				Trigger:     "schedule",
+				EnqueuedAt:  time.Now().UTC(),
+				ScheduledAt: at.At.UTC(),
+			})
+		}
+	}
+
+	// The scheduler stays paused across restarts until resumed; --paused
+	// pauses it before anything fires.
+	schedState, err := st.GetSchedulerState(context.Background())
+	if err != nil {
+		log.Fatalf("failed to load scheduler state: %v", err)
+	}
+	if *startPaused && !schedState.Paused {
+		schedState = &store.SchedulerState{Paused: true, Reason: "started with --paused", UpdatedBy: "cronbat"}
+		if err := st.SaveSchedulerState(context.Background(), schedState); err != nil {
+			log.Fatalf("failed to save scheduler state: %v", err)
+		}
+	}
+	if schedState.Paused {
+		sched.Pause()
+		log.Printf("WARN: scheduler is paused (%s); scheduled runs will not fire until it is resumed", schedState.Reason)
+	} else {
+		catchUpOneShots()
+	}
+	sched.Start()
+	readiness.MarkDone("scheduler")
+
+	// setSchedulerPaused pauses or resumes every schedule. Manual, bus, and
+	// backfill runs are unaffected.
+	var schedStateMu sync.Mutex
+	setSchedulerPaused := func(paused bool, reason, actor string) (*store.SchedulerState, error) {
+		schedStateMu.Lock()
+		defer schedStateMu.Unlock()
+		state := &store.SchedulerState{Paused: paused, Reason: reason, UpdatedBy: actor}
+		if err := st.SaveSchedulerState(context.Background(), state); err != nil {
+			return nil, err
+		}
+		if paused {
+			sched.Pause()
+			log.Printf("WARN: scheduler paused by %s: %s", actor, reason)
+		} else {
+			sched.Resume()
+			log.Printf("scheduler resumed by %s", actor)
+			catchUpOneShots()
+		}
+		return state, nil
+	}
+	getSchedulerState := func(ctx context.Context) (*store.SchedulerState, error) {
+		return st.GetSchedulerState(ctx)
+	}
+
+	backfills := backfill.NewManager(st, func(jobName string, env map[string]string, done func(runID, status string)) {
+		submitRun(runqueue.Item{JobName: jobName, Trigger: "backfill", Env: env, Done: done})
+	})
+	backfills.OnProgress = func(b store.Backfill, w *store.BackfillWindow) {
+		evt := realtime.Event{
+			Type:       "backfill.completed",
+			JobName:    b.JobName,
+			Status:     b.Status,
+			BackfillID: b.ID,
+		}
+		if w != nil {
+			evt.Type = "backfill.progress"
+			evt.RunID = w.RunID
+			evt.Status = w.Status
+		}
+		events.Publish(evt)
+	}
+	if err := backfills.Resume(context.Background()); err != nil {
+		log.Printf("ERROR: failed to resume backfills: %v", err)
+	}
+
+	createBackfill := func(ctx context.Context, jobName string, from, to time.Time, interval string, parallelism int) (*store.Backfill, error) {
+		jobsMu.RLock()
+		_, ok := jobMap[jobName]
+		jobsMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("job not found: %s", jobName)
+		}
+		b, err := backfills.Create(ctx, jobName, from, to, interval, parallelism)
+		if err == nil {
+			log.Printf("started backfill %s for job %q: %s to %s every %s", b.ID, jobName, b.From.Format(time.RFC3339), b.To.Format(time.RFC3339), b.Interval)
+		}
+		return b, err
+	}
+
+	getBackfill := func(ctx context.Context, id string) (*store.Backfill, []store.BackfillWindow, error) {
+		b, err := st.GetBackfill(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if b == nil {
+			return nil, nil, fmt.Errorf("backfill not found: %s", id)
+		}
+		windows, err := st.ListBackfillWindows(ctx, id)
+		return b, windows, err
+	}
+
+	listBackfills := func(ctx context.Context, jobName string) ([]*store.Backfill, error) {
+		return st.ListBackfills(ctx, jobName, "")
+	}
+
+	// Bulk runs triggered through POST /api/v1/jobs/run.
+	batches := batch.NewManager(func(jobName string, env map[string]string, done func(runID, status string, outputs map[string]string)) {
+		submitRun(runqueue.Item{JobName: jobName, Trigger: "batch", Env: env, Done: func(runID, status string) {
+			var outputs map[string]string
+			if runID != "" {
+				if run, err := st.GetRun(context.Background(), runID); err == nil && run != nil {
+					outputs = run.Outputs
+				}
+			}
+			done(runID, status, outputs)
+		}})
+	})
+
+	cleanupCtx, cleanupCancel := context.WithCancel(context.Background())
+	cleanupEvery, err := time.ParseDuration(cfg.RunLogs.CleanupInterval)
+	if err != nil || cleanupEvery <= 0 {
+		cleanupEvery = time.Hour
+	}
+	if cfg.RunLogs.IsEnabled() {
 		go func() {
 			ticker := time.NewTicker(cleanupEvery)
 			defer ticker.Stop()
@@ -364,24 +1855,285 @@ func main() {
 				select {
 				case <-cleanupCtx.Done():
 					return
-				case <-ticker.C:
-					if err := runLogManager.Cleanup(); err != nil {
-						log.Printf("WARN: run log cleanup failed: %v", err)
-					}
+				case <-ticker.C:
+					if err := runLogManager.Cleanup(); err != nil {
+						log.Printf("WARN: run log cleanup failed: %v", err)
+					}
+				}
+			}
+		}()
+	}
+
+	// Record runs "cronbat wrap" spooled while the database was locked.
+	spoolDir := filepath.Join(cfg.DataDir, spool.DirName)
+	drainSpool := func() {
+		n, err := spool.Drain(spoolDir, func(rep *spool.Report) error {
+			err := reportRun(cleanupCtx, rep)
+			if errors.Is(err, store.ErrRunConflict) {
+				// Retrying can never succeed; drop the report.
+				log.Printf("WARN: dropping spooled report of run %s: %v", rep.ID, err)
+				return nil
+			}
+			return err
+		})
+		if n > 0 {
+			log.Printf("recorded %d spooled runs", n)
+		}
+		if err != nil {
+			log.Printf("WARN: failed to record spooled runs: %v", err)
+		}
+	}
+	go func() {
+		drainSpool()
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-cleanupCtx.Done():
+				return
+			case <-ticker.C:
+				drainSpool()
+			}
+		}
+	}()
+
+	// Long-lived readers and "cronbat wrap" writers keep the WAL from
+	// being reset on its own, so it is truncated periodically.
+	if checkpointInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(checkpointInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-cleanupCtx.Done():
+					return
+				case <-ticker.C:
+					if err := st.Checkpoint(cleanupCtx); err != nil && cleanupCtx.Err() == nil {
+						log.Printf("WARN: WAL checkpoint failed: %v", err)
+					}
+				}
+			}
+		}()
+	}
+
+	// max_interval breaches happen without runs, so SLOs are also
+	// re-evaluated periodically.
+	go func() {
+		ticker := time.NewTicker(slo.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-cleanupCtx.Done():
+				return
+			case <-ticker.C:
+				jobsMu.RLock()
+				var tracked []*config.Job
+				for _, j := range jobMap {
+					if j.SLO != nil {
+						tracked = append(tracked, j)
+					}
+				}
+				jobsMu.RUnlock()
+				for _, j := range tracked {
+					checkSLO(j)
+				}
+			}
+		}
+	}()
+
+	triggerRun := func(jobName, triggeredBy, correlationID string, tc *store.TriggerContext) (string, bool) {
+		return enqueueRun(jobName, "manual", triggeredBy, correlationID, tc)
+	}
+
+	for i, wh := range cfg.EventWebhooks {
+		name := fmt.Sprintf("event_webhooks[%d]", i)
+		sink, err := eventsink.New(name, wh)
+		if err != nil {
+			log.Fatalf("invalid %s: %v", name, err)
+		}
+		sinkEvents, unsubscribe := events.Subscribe()
+		go func() {
+			<-cleanupCtx.Done()
+			unsubscribe()
+		}()
+		go sink.Run(cleanupCtx, sinkEvents)
+		log.Printf("posting events to %s", name)
+	}
+	if tracer != nil {
+		go tracer.Run(cleanupCtx)
+		log.Println("exporting run traces over OTLP")
+	}
+	if shipper != nil {
+		go shipper.Run(cleanupCtx)
+		log.Println("shipping run logs")
+	}
+	// A nil checker has no status, so the API reports no update info.
+	var updates *updatecheck.Checker
+	if cfg.UpdateCheck.Enabled {
+		updates, err = updatecheck.New(cfg.UpdateCheck.Repo, cronbatVersion())
+		if err != nil {
+			log.Fatalf("invalid update_check: %v", err)
+		}
+		go updates.Run(cleanupCtx)
+	}
+
+	// triggers lists the sources that fire jobs from outside the schedule.
+	triggers := trigger.NewRegistry()
+
+	// The message bus gets a copy of selected events and, with a
+	// trigger_subject, can fire jobs.
+	if cfg.Bus.URL != "" {
+		msgBus, err := bus.Open(cfg.Bus.URL)
+		if err != nil {
+			log.Fatalf("invalid bus config: %v", err)
+		}
+		publishTypes := make(map[string]bool, len(cfg.Bus.Events))
+		for _, t := range cfg.Bus.Events {
+			publishTypes[t] = true
+		}
+		busEvents, unsubscribe := events.Subscribe()
+		go func() {
+			<-cleanupCtx.Done()
+			unsubscribe()
+		}()
+		go func() {
+			defer msgBus.Close()
+			for evt := range busEvents {
+				if !publishTypes[evt.Type] {
+					continue
+				}
+				data, err := json.Marshal(evt)
+				if err != nil {
+					continue
+				}
+				if err := msgBus.Publish(cleanupCtx, cfg.Bus.EventsSubject+"."+evt.Type, data); err != nil {
+					log.Printf("WARN: failed to publish %s event to bus: %v", evt.Type, err)
+				}
+			}
+		}()
+
+		if cfg.Bus.TriggerSubject != "" {
+			busTrigger := triggers.Register("bus", "bus", cfg.Bus.TriggerSubject)
+			handleTrigger := func(data []byte) {
+				var msg bus.TriggerMessage
+				if err := json.Unmarshal(data, &msg); err != nil || msg.Job == "" {
+					log.Printf("WARN: ignoring invalid bus trigger message %q", data)
+					return
+				}
+				if !busTrigger.Enabled() {
+					log.Printf("WARN: bus trigger for job %q ignored: trigger disabled", msg.Job)
+					return
+				}
+				jobsMu.RLock()
+				j, ok := jobMap[msg.Job]
+				gated := ok && j.RequireApproval
+				jobsMu.RUnlock()
+				switch {
+				case !ok:
+					log.Printf("WARN: bus trigger for unknown job %q ignored", msg.Job)
+					return
+				case gated:
+					log.Printf("WARN: bus trigger for job %q ignored: job requires approval", msg.Job)
+					return
+				}
+				triggeredBy := "bus"
+				if msg.TriggeredBy != "" {
+					triggeredBy = "bus: " + msg.TriggeredBy
+				}
+				tc := &store.TriggerContext{Subject: cfg.Bus.TriggerSubject}
+				tc.SetBody(data)
+				runID, deduped := enqueueRun(msg.Job, "bus", triggeredBy, msg.CorrelationID, tc)
+				busTrigger.Fired(msg.Job, time.Now())
+				if deduped {
+					log.Printf("bus trigger for job %q folded into run %s (dedupe_window)", msg.Job, runID)
+					return
+				}
+				if err := st.RecordAudit(context.Background(), &store.AuditEntry{
+					Actor:   triggeredBy,
+					Action:  "run",
+					JobName: msg.Job,
+				}); err != nil {
+					log.Printf("ERROR: failed to record audit entry: %v", err)
 				}
 			}
-		}()
+			go msgBus.Subscribe(cleanupCtx, cfg.Bus.TriggerSubject, handleTrigger, func(err error) {
+				log.Printf("WARN: bus subscription to %s failed: %v", cfg.Bus.TriggerSubject, err)
+			})
+		}
+		if u, err := url.Parse(cfg.Bus.URL); err == nil {
+			log.Printf("message bus enabled at %s", u.Redacted())
+		}
 	}
 
-	triggerRun := func(jobName string) {
-		executeJob(jobName, "manual")
+	// dryRunJob resolves what a manual run of a job would execute.
+	dryRunJob := func(ctx context.Context, name string) (*api.DryRun, error) {
+		jobsMu.RLock()
+		j, ok := jobMap[name]
+		if ok {
+			j = cloneJob(j)
+		}
+		jobsMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("job not found: %s", name)
+		}
+
+		version, pinned := applyPinnedVersion(ctx, j)
+		timeout, err := j.EffectiveTimeout(defaultTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+		argv, err := runner.ShellCommand(j.Shell, j.LoginShell, j.Command)
+		if err != nil {
+			return nil, err
+		}
+		shell := j.Shell
+		if shell == "" {
+			shell = runner.DefaultShell
+		}
+		env := make(map[string]string, len(j.Env)+2)
+		for k, v := range j.Env {
+			env[k] = v
+		}
+		env["CRONBAT_JOB_NAME"] = j.Name
+		env["CRONBAT_TRIGGER"] = "manual"
+
+		plan := &api.DryRun{
+			Job:        j.Name,
+			Version:    version,
+			Pinned:     pinned,
+			Enabled:    j.IsEnabled(),
+			Command:    j.Command,
+			Argv:       argv,
+			Shell:      shell,
+			LoginShell: j.LoginShell,
+			Executor:   j.Executor,
+			WorkingDir: j.WorkingDir,
+			Env:        env,
+			User:       j.User,
+			Group:      j.Group,
+			Sandbox:    j.Sandbox,
+		}
+		if timeout > 0 {
+			plan.Timeout = timeout.String()
+		}
+		return plan, nil
 	}
 
+	// Manual runs of jobs with require_approval wait here until someone
+	// other than the requester approves them.
+	approvals := approval.NewManager(func(req *approval.Request) {
+		// An approved run was asked for explicitly; it is not deduplicated.
+		submitRun(runqueue.Item{JobName: req.JobName, Trigger: "manual", TriggeredBy: req.RequestedBy + "; approved by " + req.DecidedBy})
+	})
+
 	createJob := func(newJob config.Job) error {
 		candidate := &newJob
 		if err := validateJob(candidate); err != nil {
 			return err
 		}
+		if err := validateOneShotTime(candidate, nil); err != nil {
+			return err
+		}
 
 		jobsMu.Lock()
 		defer jobsMu.Unlock()
@@ -392,11 +2144,11 @@ func main() {
 
 		candidate.FilePath = filepath.Join(cfg.JobsDir, candidate.Name+".yaml")
 		if err := applyScheduleLocked(candidate); err != nil {
-			sched.RemoveJob(candidate.Name)
+			unscheduleLocked(candidate.Name)
 			return err
 		}
 		if err := config.SaveJob(candidate.FilePath, candidate); err != nil {
-			sched.RemoveJob(candidate.Name)
+			unscheduleLocked(candidate.Name)
 			return err
 		}
 
@@ -406,10 +2158,13 @@ func main() {
 		} else {
 			jobStateMap[candidate.Name] = "stopped"
 		}
+		persistJobStateLocked(candidate.Name)
 		return nil
 	}
 
-	setJobEnabled := func(name string, enabled bool) error {
+	// setJobEnabled enables or disables a job. reason and actor are recorded
+	// when disabling and cleared when enabling.
+	setJobEnabled := func(name string, enabled bool, reason, actor string) error {
 		jobsMu.Lock()
 		defer jobsMu.Unlock()
 
@@ -422,9 +2177,12 @@ func main() {
 		if enabled {
 			t := true
 			j.Enabled = &t
+			j.DisabledReason, j.DisabledBy, j.DisabledAt = "", "", nil
 		} else {
 			f := false
+			now := time.Now().UTC()
 			j.Enabled = &f
+			j.DisabledReason, j.DisabledBy, j.DisabledAt = reason, actor, &now
 		}
 
 		if err := applyScheduleLocked(j); err != nil {
@@ -439,22 +2197,27 @@ func main() {
 		}
 		if enabled {
 			jobStateMap[name] = "started"
+			enabledAt[name] = time.Now().UTC()
 		} else {
 			jobStateMap[name] = "stopped"
 		}
+		persistJobStateLocked(name)
 		return nil
 	}
+	autoDisableJob = func(name, reason string) error {
+		return setJobEnabled(name, false, reason, "cronbat")
+	}
 
 	enableJob := func(name string) error {
-		return setJobEnabled(name, true)
+		return setJobEnabled(name, true, "", "")
 	}
 
-	disableJob := func(name string) error {
-		return setJobEnabled(name, false)
+	disableJob := func(name, reason, actor string) error {
+		return setJobEnabled(name, false, reason, actor)
 	}
 
 	startJob := func(name string) error {
-		if err := setJobEnabled(name, true); err != nil {
+		if err := setJobEnabled(name, true, "", ""); err != nil {
 			return err
 		}
 		jobsMu.Lock()
@@ -463,8 +2226,8 @@ func main() {
 		return nil
 	}
 
-	stopJob := func(name string) error {
-		if err := setJobEnabled(name, false); err != nil {
+	stopJob := func(name, reason, actor string) error {
+		if err := setJobEnabled(name, false, reason, actor); err != nil {
 			return err
 		}
 		jobsMu.Lock()
@@ -473,16 +2236,76 @@ func main() {
 		return nil
 	}
 
-	pauseJob := func(name string) error {
-		if err := setJobEnabled(name, false); err != nil {
+	// pauseJob disables a job until it is enabled again or, with a non-zero
+	// until, snoozes it: the job resumes on its own at that time.
+	pauseJob := func(name, reason, actor string, until time.Time) error {
+		if err := setJobEnabled(name, false, reason, actor); err != nil {
 			return err
 		}
 		jobsMu.Lock()
+		defer jobsMu.Unlock()
 		jobStateMap[name] = "paused"
-		jobsMu.Unlock()
+		if !until.IsZero() {
+			snoozeUntil[name] = until.UTC()
+		}
+		persistJobStateLocked(name)
 		return nil
 	}
 
+	getSnoozeUntil := func(name string) (time.Time, bool) {
+		jobsMu.RLock()
+		defer jobsMu.RUnlock()
+		until, ok := snoozeUntil[name]
+		return until, ok
+	}
+
+	// Snoozed jobs resume once their deadline passes, including deadlines
+	// that passed while cronbat was down.
+	resumeSnoozedJobs := func() {
+		now := time.Now()
+		jobsMu.RLock()
+		var due []string
+		for name, until := range snoozeUntil {
+			if !now.Before(until) && jobStateMap[name] == "paused" {
+				due = append(due, name)
+			}
+		}
+		jobsMu.RUnlock()
+		for _, name := range due {
+			if err := setJobEnabled(name, true, "", ""); err != nil {
+				log.Printf("ERROR: failed to resume snoozed job %q: %v", name, err)
+				continue
+			}
+			log.Printf("job %q snooze ended; scheduling resumed", name)
+			if err := st.RecordAudit(context.Background(), &store.AuditEntry{
+				Actor:   "cronbat",
+				Action:  "resume",
+				JobName: name,
+				Detail:  "snooze ended",
+			}); err != nil {
+				log.Printf("ERROR: failed to record audit entry: %v", err)
+			}
+			events.Publish(realtime.Event{
+				Type:    "job.changed",
+				JobName: name,
+				Action:  "resume",
+			})
+		}
+	}
+	go func() {
+		resumeSnoozedJobs()
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-cleanupCtx.Done():
+				return
+			case <-ticker.C:
+				resumeSnoozedJobs()
+			}
+		}
+	}()
+
 	archiveJob := func(name string) error {
 		jobsMu.Lock()
 		defer jobsMu.Unlock()
@@ -492,7 +2315,7 @@ func main() {
 			return fmt.Errorf("job not found: %s", name)
 		}
 
-		sched.RemoveJob(name)
+		unscheduleLocked(name)
 
 		archiveDir := filepath.Join(cfg.JobsDir, "archive")
 		if err := os.MkdirAll(archiveDir, 0755); err != nil {
@@ -500,7 +2323,7 @@ func main() {
 		}
 
 		srcPath := jobFilePath(j)
-		archiveName := fmt.Sprintf("%s-%s.yaml", j.Name, time.Now().UTC().Format("20060102T150405Z"))
+		archiveName := fmt.Sprintf("%s-%s%s", j.Name, time.Now().UTC().Format("20060102T150405Z"), filepath.Ext(srcPath))
 		dstPath := filepath.Join(archiveDir, archiveName)
 
 		if err := os.Rename(srcPath, dstPath); err != nil {
@@ -517,8 +2340,29 @@ func main() {
 
 		delete(jobMap, name)
 		delete(jobStateMap, name)
+		persistJobStateLocked(name)
 		return nil
 	}
+	archiveOneShotJob = func(name string) {
+		if err := archiveJob(name); err != nil {
+			log.Printf("ERROR: failed to archive one-shot job %q: %v", name, err)
+			return
+		}
+		log.Printf("one-shot job %q finished; archived", name)
+		if err := st.RecordAudit(context.Background(), &store.AuditEntry{
+			Actor:   "cronbat",
+			Action:  "archive",
+			JobName: name,
+			Detail:  "one-shot run finished",
+		}); err != nil {
+			log.Printf("ERROR: failed to record audit entry: %v", err)
+		}
+		events.Publish(realtime.Event{
+			Type:    "job.changed",
+			JobName: name,
+			Action:  "archive",
+		})
+	}
 
 	deleteJob := func(name string) error {
 		jobsMu.Lock()
@@ -536,10 +2380,63 @@ func main() {
 
 		delete(jobMap, name)
 		delete(jobStateMap, name)
-		sched.RemoveJob(name)
+		persistJobStateLocked(name)
+		unscheduleLocked(name)
+		if err := st.DeleteLastGoodJob(context.Background(), name); err != nil {
+			log.Printf("ERROR: failed to forget last good version of job %q: %v", name, err)
+		}
+		return nil
+	}
+
+	// pinRunLogs pins or unpins a run's log files against retention.
+	pinRunLogs := func(ctx context.Context, id string, pinned bool) (*store.Run, error) {
+		found, err := st.SetRunLogsPinned(ctx, id, pinned)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("run not found: %s", id)
+		}
+		return st.GetRun(ctx, id)
+	}
+
+	deleteAnnotation := func(ctx context.Context, jobName string, id int64) error {
+		found, err := st.DeleteAnnotation(ctx, jobName, id)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("annotation not found: %d", id)
+		}
 		return nil
 	}
 
+	// setJobPinned pins or unpins a job to its last known good version.
+	setJobPinned := func(ctx context.Context, name string, pinned bool) (*store.JobSnapshot, error) {
+		jobsMu.RLock()
+		_, ok := jobMap[name]
+		jobsMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("job not found: %s", name)
+		}
+		found, err := st.SetJobPinned(ctx, name, pinned)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("job %s has no successful run to pin", name)
+		}
+		snap, err := st.GetLastGoodJob(ctx, name)
+		if err == nil && snap != nil {
+			if pinned {
+				log.Printf("job %q pinned to last good version %s", name, snap.Version)
+			} else {
+				log.Printf("job %q unpinned", name)
+			}
+		}
+		return snap, err
+	}
+
 	getJobYAML := func(name string) (string, error) {
 		jobsMu.RLock()
 		j, ok := jobMap[name]
@@ -551,15 +2448,19 @@ func main() {
 		path := jobFilePath(snapshot)
 		jobsMu.RUnlock()
 
-		data, err := os.ReadFile(path)
-		if err == nil {
-			return string(data), nil
-		}
-		if !errors.Is(err, os.ErrNotExist) {
-			return "", err
+		// JSON and TOML job files are shown as YAML; saving from the YAML
+		// editor writes the file back in its original format.
+		if config.JobFileFormat(path) == config.FormatYAML {
+			data, err := os.ReadFile(path)
+			if err == nil {
+				return string(data), nil
+			}
+			if !errors.Is(err, os.ErrNotExist) {
+				return "", err
+			}
 		}
 
-		// Fallback for jobs that exist in memory but have no file on disk.
+		// Fallback for other formats and for jobs that exist in memory but have no file on disk.
 		raw, err := config.MarshalJobYAML(snapshot)
 		if err != nil {
 			return "", err
@@ -574,6 +2475,28 @@ func main() {
 		return runLogManager.ReadRunLogs(jobName, runID)
 	}
 
+	purgeJobLogs := func(name string) (int, int64, error) {
+		if !cfg.RunLogs.IsEnabled() {
+			return 0, 0, nil
+		}
+		files, bytes, err := runLogManager.PurgeJob(name)
+		if err == nil {
+			log.Printf("purged %d log file(s) (%d bytes) for job %q", files, bytes, name)
+		}
+		return files, bytes, err
+	}
+
+	readRunLogRange := func(jobName, runID, stream string, offset, limit int64) (*runlog.LogRange, error) {
+		if !cfg.RunLogs.IsEnabled() {
+			return nil, os.ErrNotExist
+		}
+		return runLogManager.ReadRange(jobName, runID, stream, offset, limit)
+	}
+
+	verifyRunLogs := func(run *store.Run) []runlog.LogCheck {
+		return runLogManager.Verify(run.JobName, run.ID, run.StdoutSHA256, run.StderrSHA256)
+	}
+
 	updateJobYAML := func(name string, data string) (string, error) {
 		parsed, err := config.ParseJobYAML([]byte(data))
 		if err != nil {
@@ -597,6 +2520,9 @@ func main() {
 		if !ok {
 			return "", fmt.Errorf("job not found: %s", name)
 		}
+		if err := validateOneShotTime(parsed, current); err != nil {
+			return "", err
+		}
 
 		newName := parsed.Name
 		if newName != name {
@@ -608,7 +2534,7 @@ func main() {
 		old := cloneJob(current)
 		oldState, hadOldState := jobStateMap[name]
 		oldPath := jobFilePath(current)
-		newPath := filepath.Join(cfg.JobsDir, newName+".yaml")
+		newPath := filepath.Join(cfg.JobsDir, newName+filepath.Ext(oldPath))
 		parsed.FilePath = newPath
 
 		nextState := oldState
@@ -629,7 +2555,7 @@ func main() {
 		jobStateMap[newName] = nextState
 
 		// Refresh schedule with potential new name/schedule.
-		sched.RemoveJob(name)
+		unscheduleLocked(name)
 		if err := applyScheduleLocked(current); err != nil {
 			if newName != name {
 				delete(jobMap, newName)
@@ -647,8 +2573,8 @@ func main() {
 		}
 
 		restore := func() {
-			sched.RemoveJob(name)
-			sched.RemoveJob(newName)
+			unscheduleLocked(name)
+			unscheduleLocked(newName)
 			if newName != name {
 				delete(jobMap, newName)
 				jobMap[name] = current
@@ -675,6 +2601,13 @@ func main() {
 				return "", err
 			}
 		}
+		if newName != name {
+			if t, ok := snoozeUntil[name]; ok {
+				snoozeUntil[newName] = t
+			}
+			persistJobStateLocked(name)
+		}
+		persistJobStateLocked(newName)
 		return newName, nil
 	}
 
@@ -687,29 +2620,15 @@ func main() {
 			return fmt.Errorf("job not found: %s", name)
 		}
 
-		candidate := cloneJob(current)
+		candidate := current.MergeSettings(updated)
 		candidate.Name = name
-		candidate.Schedule = strings.TrimSpace(updated.Schedule)
-		candidate.Command = strings.TrimSpace(updated.Command)
-		candidate.WorkingDir = strings.TrimSpace(updated.WorkingDir)
-		candidate.Executor = strings.TrimSpace(updated.Executor)
-		if candidate.Executor == "" {
-			candidate.Executor = "shell"
-		}
-		candidate.Timeout = strings.TrimSpace(updated.Timeout)
-		candidate.Env = updated.Env
-		candidate.OnSuccess = updated.OnSuccess
-		candidate.OnFailure = updated.OnFailure
-		candidate.Metadata = updated.Metadata
-		candidate.Analyze = updated.Analyze
-		if updated.Enabled != nil {
-			v := *updated.Enabled
-			candidate.Enabled = &v
-		}
 
 		if err := validateJob(candidate); err != nil {
 			return err
 		}
+		if err := validateOneShotTime(candidate, current); err != nil {
+			return err
+		}
 
 		old := cloneJob(current)
 		oldState, hadOldState := jobStateMap[name]
@@ -741,43 +2660,378 @@ func main() {
 		} else if jobStateMap[name] == "" || jobStateMap[name] == "started" {
 			jobStateMap[name] = "stopped"
 		}
+		persistJobStateLocked(name)
 		return nil
 	}
 
-	// Set up HTTP server.
-	srv := web.NewServer(
-		cfg.Listen,
-		st,
-		events,
-		getConfigSnapshot,
-		getJobs,
-		getJobState,
-		createJob,
-		readRunLogs,
-		triggerRun,
-		sched.NextRunTime,
-		enableJob,
-		disableJob,
-		startJob,
-		stopJob,
-		pauseJob,
-		archiveJob,
-		deleteJob,
-		getJobYAML,
-		updateJobYAML,
-		updateJobSettings,
-	)
+	// importJobsLocked applies an import as one change. Every job is
+	// validated and its schedule parsed before anything is written; if a
+	// file cannot be written or a job cannot be scheduled, files, jobs, and
+	// schedules are put back as they were before the import. Callers hold
+	// jobsMu.
+	importJobsLocked := func(creates, updates []config.Job, deletes []string) error {
+		type change struct {
+			name     string
+			old      *config.Job // nil for creates
+			oldState string
+			next     *config.Job // nil for deletes
+		}
+		var changes []change
+		var files []config.JobFileChange
+
+		for i := range creates {
+			candidate := creates[i]
+			if err := validateJob(&candidate); err != nil {
+				return fmt.Errorf("job %s: %w", candidate.Name, err)
+			}
+			if err := validateOneShotTime(&candidate, nil); err != nil {
+				return fmt.Errorf("job %s: %w", candidate.Name, err)
+			}
+			if _, exists := jobMap[candidate.Name]; exists {
+				return fmt.Errorf("job already exists: %s", candidate.Name)
+			}
+			candidate.FilePath = filepath.Join(cfg.JobsDir, candidate.Name+".yaml")
+			changes = append(changes, change{name: candidate.Name, next: &candidate})
+		}
+		for _, updated := range updates {
+			current, ok := jobMap[updated.Name]
+			if !ok {
+				return fmt.Errorf("job not found: %s", updated.Name)
+			}
+			candidate := current.MergeSettings(updated)
+			candidate.Name = updated.Name
+			if err := validateJob(candidate); err != nil {
+				return fmt.Errorf("job %s: %w", candidate.Name, err)
+			}
+			if err := validateOneShotTime(candidate, current); err != nil {
+				return fmt.Errorf("job %s: %w", candidate.Name, err)
+			}
+			candidate.FilePath = jobFilePath(current)
+			changes = append(changes, change{name: candidate.Name, old: cloneJob(current), oldState: jobStateMap[candidate.Name], next: candidate})
+		}
+		for _, name := range deletes {
+			current, ok := jobMap[name]
+			if !ok {
+				return fmt.Errorf("job not found: %s", name)
+			}
+			changes = append(changes, change{name: name, old: cloneJob(current), oldState: jobStateMap[name]})
+		}
+		for _, c := range changes {
+			if c.next == nil {
+				files = append(files, config.JobFileChange{Path: jobFilePath(c.old)})
+				continue
+			}
+			if !c.next.IsService() {
+				policy, err := scheduler.ParseDSTPolicy(c.next.DSTPolicy)
+				if err != nil {
+					return fmt.Errorf("job %s: %w", c.name, err)
+				}
+				if _, err := scheduler.ParseScheduleWithDST(c.next.Schedule, policy); err != nil {
+					return fmt.Errorf("job %s: invalid schedule: %w", c.name, err)
+				}
+			}
+			files = append(files, config.JobFileChange{Path: c.next.FilePath, Job: c.next})
+		}
+
+		undoFiles, err := config.SaveJobs(files)
+		if err != nil {
+			return err
+		}
+
+		// setJob makes j (nil to remove) the live definition of name.
+		setJob := func(name string, j *config.Job, state string) error {
+			if j == nil {
+				delete(jobMap, name)
+				delete(jobStateMap, name)
+				unscheduleLocked(name)
+				return nil
+			}
+			jobMap[name] = j
+			switch {
+			case state != "":
+				jobStateMap[name] = state
+			case j.IsEnabled():
+				jobStateMap[name] = "started"
+			default:
+				jobStateMap[name] = "stopped"
+			}
+			return applyScheduleLocked(j)
+		}
+		for i, c := range changes {
+			state := ""
+			if c.next != nil && !c.next.IsEnabled() && c.oldState != "started" {
+				state = c.oldState
+			}
+			if err := setJob(c.name, c.next, state); err != nil {
+				for _, c := range changes[:i+1] {
+					_ = setJob(c.name, c.old, c.oldState)
+				}
+				if undoErr := undoFiles(); undoErr != nil {
+					log.Printf("ERROR: failed to restore job files after failed import: %v", undoErr)
+				}
+				return fmt.Errorf("job %s: %w", c.name, err)
+			}
+		}
+		for _, c := range changes {
+			persistJobStateLocked(c.name)
+		}
+
+		for _, name := range deletes {
+			if err := st.DeleteLastGoodJob(context.Background(), name); err != nil {
+				log.Printf("ERROR: failed to forget last good version of job %q: %v", name, err)
+			}
+		}
+		return nil
+	}
+
+	importJobs := func(creates, updates []config.Job, deletes []string) error {
+		jobsMu.Lock()
+		defer jobsMu.Unlock()
+		return importJobsLocked(creates, updates, deletes)
+	}
+
+	// updateJobs applies update to a copy of each named job and saves the
+	// changed ones as one import. It holds jobsMu throughout, so an edit
+	// made meanwhile through another endpoint is never reverted.
+	updateJobs := func(names []string, update func(j *config.Job) error) error {
+		jobsMu.Lock()
+		defer jobsMu.Unlock()
+
+		var updates []config.Job
+		for _, name := range names {
+			current, ok := jobMap[name]
+			if !ok {
+				return fmt.Errorf("job not found: %s", name)
+			}
+			candidate := cloneJob(current)
+			if err := update(candidate); err != nil {
+				return err
+			}
+			if reflect.DeepEqual(candidate, current) {
+				continue
+			}
+			updates = append(updates, *candidate)
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return importJobsLocked(nil, updates, nil)
+	}
+
+	// jobState reports "dormant" for started jobs the scheduler dropped
+	// because their schedule never fires again.
+	jobState := func(name string) string {
+		state := getJobState(name)
+		if state == "started" && sched.IsDormant(name) {
+			return "dormant"
+		}
+		return state
+	}
+
+	// deepHealth backs GET /api/v1/health?deep=1. The store and the
+	// scheduler are critical; the others only report.
+	deepHealth := func(ctx context.Context) []api.HealthCheck {
+		storeCheck := api.HealthCheck{Name: "store", OK: true, Critical: true, Details: map[string]any{
+			"wal_bytes": st.WALBytes(),
+		}}
+		checkCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if err := st.CheckWritable(checkCtx); err != nil {
+			storeCheck.OK, storeCheck.Error = false, "database not writable: "+err.Error()
+		}
+
+		schedCheck := api.HealthCheck{Name: "scheduler", OK: sched.Alive(), Critical: true, Details: map[string]any{
+			"jobs":   sched.Len(),
+			"paused": sched.Paused(),
+		}}
+		if !schedCheck.OK {
+			schedCheck.Error = "scheduler loop is not running"
+		}
+		if name, at, ok := sched.Next(); ok {
+			schedCheck.Details["next_job"] = name
+			schedCheck.Details["next_fire"] = at.UTC()
+		}
+
+		checks := []api.HealthCheck{storeCheck, schedCheck}
+		if cfg.RunLogs.IsEnabled() {
+			logCheck := api.HealthCheck{Name: "run_logs", OK: true, Details: map[string]any{
+				"dir":             runLogManager.BaseDir(),
+				"max_total_bytes": runLogManager.MaxTotalBytes(),
+			}}
+			if err := runLogManager.CheckWritable(); err != nil {
+				logCheck.OK, logCheck.Error = false, "log directory not writable: "+err.Error()
+			}
+			if used, err := runLogManager.Usage(); err != nil {
+				logCheck.OK, logCheck.Error = false, "measure log usage: "+err.Error()
+			} else {
+				logCheck.Details["used_bytes"] = used
+				if quota := runLogManager.MaxTotalBytes(); quota > 0 {
+					logCheck.Details["used_ratio"] = float64(used) / float64(quota)
+				}
+			}
+			checks = append(checks, logCheck)
+		}
+		checks = append(checks, api.HealthCheck{Name: "events", OK: true, Details: map[string]any{
+			"subscribers": events.Subscribers(),
+			"latest_id":   events.LatestID(),
+		}})
+		return checks
+	}
+
+	storageUsage := func(ctx context.Context) (*api.StorageUsage, error) {
+		stats, err := st.Stats(ctx)
+		if err != nil {
+			return nil, err
+		}
+		counts, err := st.RunCountsByJob(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, j := range getJobs() {
+			if _, ok := counts[j.Name]; !ok {
+				counts[j.Name] = 0
+			}
+		}
+		usage := &api.StorageUsage{Database: stats, Jobs: make([]api.JobStorage, 0, len(counts))}
+		if cfg.RunLogs.IsEnabled() {
+			used, err := runLogManager.Usage()
+			if err != nil {
+				return nil, fmt.Errorf("measure run logs: %w", err)
+			}
+			usage.RunLogs = &api.RunLogUsage{
+				Dir:           runLogManager.BaseDir(),
+				UsedBytes:     used,
+				MaxTotalBytes: runLogManager.MaxTotalBytes(),
+			}
+		}
+		for name, n := range counts {
+			js := api.JobStorage{Job: name, Runs: n}
+			if usage.RunLogs != nil {
+				if js.LogBytes, err = runLogManager.JobUsage(name); err != nil {
+					return nil, fmt.Errorf("measure run logs of %s: %w", name, err)
+				}
+			}
+			usage.Jobs = append(usage.Jobs, js)
+		}
+		sort.Slice(usage.Jobs, func(i, k int) bool { return usage.Jobs[i].Job < usage.Jobs[k].Job })
+		return usage, nil
+	}
+
+	var cleanupRunLogs func() (*api.LogCleanupResult, error)
+	if cfg.RunLogs.IsEnabled() {
+		cleanupRunLogs = func() (*api.LogCleanupResult, error) {
+			start := time.Now()
+			before, err := runLogManager.Usage()
+			if err != nil {
+				return nil, err
+			}
+			if err := runLogManager.Cleanup(); err != nil {
+				return nil, err
+			}
+			after, err := runLogManager.Usage()
+			if err != nil {
+				return nil, err
+			}
+			return &api.LogCleanupResult{
+				BeforeBytes: before,
+				AfterBytes:  after,
+				FreedBytes:  before - after,
+				DurationMs:  time.Since(start).Milliseconds(),
+			}, nil
+		}
+	}
+
+	cronbatBin, absConfig := cronSyncPaths(*configPath)
+	crontabLineFor := func(j *config.Job) (string, error) {
+		return crontabLine(j, cronbatBin, absConfig, "")
+	}
+
+	// Mount the full API and UI on the already-listening server.
+	srv.Mount(&api.API{
+		Store:              st,
+		Events:             events,
+		GetConfig:          getConfigSnapshot,
+		Jobs:               getJobs,
+		JobState:           jobState,
+		JobsVersion:        jobsVersion.Load,
+		SchedulerState:     getSchedulerState,
+		SetSchedulerPaused: setSchedulerPaused,
+		HungAfter:          3 * heartbeatInterval,
+		CreateJob:          createJob,
+		ReadRunLogs:        readRunLogs,
+		ReadRunLogRange:    readRunLogRange,
+		JobLoadErrors:      getJobLoadErrors,
+		PurgeJobLogs:       purgeJobLogs,
+		TriggerRun:         triggerRun,
+		APIKeys:            cfg.APIKeys,
+		RecordAudit:        st.RecordAudit,
+		ListAudit:          st.ListAudit,
+		NextRunTime:        sched.NextRunTime,
+		Schedules:          sched.Schedules,
+		Build:              buildInfo(),
+		UpdateStatus:       updates.Status,
+		Triggers:           triggers.List,
+		SetTriggerEnabled:  triggers.SetEnabled,
+		EnableJob:          enableJob,
+		DisableJob:         disableJob,
+		StartJob:           startJob,
+		StopJob:            stopJob,
+		PauseJob:           pauseJob,
+		SnoozeUntil:        getSnoozeUntil,
+		ArchiveJob:         archiveJob,
+		DeleteJob:          deleteJob,
+		GetJobYAML:         getJobYAML,
+		UpdateJobYAML:      updateJobYAML,
+		UpdateJobSettings:  updateJobSettings,
+		Readiness:          readiness,
+		StoreStats:         st.Stats,
+		CompactStore:       st.Compact,
+		CreateBackfill:     createBackfill,
+		ListBackfills:      listBackfills,
+		GetBackfill:        getBackfill,
+		CancelBackfill:     backfills.Cancel,
+		LastGoodJob:        st.GetLastGoodJob,
+		SetJobPinned:       setJobPinned,
+		PinRunLogs:         pinRunLogs,
+		VerifyRunLogs:      verifyRunLogs,
+		EvaluateSLO:        evaluateSLO,
+		ImportJobs:         importJobs,
+		UpdateJobs:         updateJobs,
+		CheckJob:           commandPolicy.CheckJob,
+		ListAnnotations:    st.ListAnnotations,
+		GetRunContext:      st.GetRunContext,
+		AddAnnotation:      st.AddAnnotation,
+		DeleteAnnotation:   deleteAnnotation,
+		CreateBatch:        batches.Create,
+		GetBatch:           batches.Get,
+		DryRunJob:          dryRunJob,
+		ServiceStatus:      services.Status,
+		RequestApproval:    approvals.Create,
+		ListApprovals:      approvals.List,
+		GetApproval:        approvals.Get,
+		DecideApproval:     approvals.Decide,
+		Agents:             agents,
+		ReportRun:          reportRun,
+		DeepHealth:         deepHealth,
+		Storage:            storageUsage,
+		CleanupRunLogs:     cleanupRunLogs,
+		CrontabLine:        crontabLineFor,
+		LintJob:            linter.Check,
+		Sessions:           api.NewSessions(sessionTTL),
+		RequireAuth:        cfg.Auth.Required,
+		SecureCookie:       cfg.Auth.SecureCookie,
+		OIDC:               oidcProvider,
+		OIDCRoles:          cfg.Auth.OIDC.Roles,
+		OIDCDefaultRole:    cfg.Auth.OIDC.DefaultRole,
+		ActiveRuns:         listActiveRuns,
+		QueuedRuns:         listQueuedRuns,
+	})
+	readiness.MarkDone("api")
 
 	// Graceful shutdown.
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
-	go func() {
-		if err := srv.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Fatalf("http server error: %v", err)
-		}
-	}()
-
 	log.Printf("cronbat started, listening on %s", cfg.Listen)
 
 	<-sigCh
@@ -785,12 +3039,147 @@ func main() {
 
 	cleanupCancel()
 	sched.Stop()
+	services.StopAll()
+	queue.Stop()
 
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), httpDuration(cfg.HTTP.ShutdownTimeout, 10*time.Second))
 	defer cancel()
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Printf("ERROR: http server shutdown error: %v", err)
 	}
+	if err := tracer.Flush(shutdownCtx); err != nil {
+		log.Printf("WARN: failed to export spans: %v", err)
+	}
+	if agents != nil {
+		agents.Close()
+	}
 
 	log.Println("cronbat stopped")
 }
+
+func sandboxOptions(sb *config.SandboxConfig) *runner.SandboxOptions {
+	if sb == nil {
+		return nil
+	}
+	return &runner.SandboxOptions{
+		ReadOnly:      sb.ReadOnly,
+		WritablePaths: sb.WritablePaths,
+		NoNetwork:     sb.NoNetwork,
+	}
+}
+
+// buildRunContext records what a run is about to execute. Environment
+// values that look like secrets are redacted, in the job definition too.
+func buildRunContext(runID string, j *config.Job, jctx plugin.JobContext, timeout time.Duration, jobVersion string) *store.RunContext {
+	rc := &store.RunContext{
+		RunID:          runID,
+		Command:        j.Command,
+		Shell:          j.Shell,
+		LoginShell:     j.LoginShell,
+		WorkingDir:     j.WorkingDir,
+		Executor:       j.Executor,
+		User:           j.User,
+		Group:          j.Group,
+		Env:            runner.RedactEnv(runner.BuildEnv(nil, jctx)),
+		CronbatVersion: cronbatVersion(),
+		JobVersion:     jobVersion,
+	}
+	if rc.Shell == "" {
+		rc.Shell = runner.DefaultShell
+	}
+	if timeout > 0 {
+		rc.Timeout = timeout.String()
+	}
+	if argv, err := runner.ShellCommand(j.Shell, j.LoginShell, j.Command); err == nil {
+		rc.Argv = argv
+	}
+	if rc.WorkingDir == "" {
+		rc.WorkingDir, _ = os.Getwd()
+	}
+	rc.Hostname, _ = os.Hostname()
+
+	def := *j
+	if len(j.Env) > 0 {
+		def.Env = runner.RedactEnv(envSlice(j.Env))
+	}
+	if data, err := config.MarshalJobYAML(&def); err == nil {
+		rc.Definition = string(data)
+	}
+	return rc
+}
+
+// startHeartbeat marks run runID alive now and every interval until the
+// returned stop func is called.
+func startHeartbeat(st *store.SQLiteStore, runID string, interval time.Duration) (stop func()) {
+	beat := func() {
+		if err := st.Heartbeat(context.Background(), runID, time.Now().UTC()); err != nil {
+			log.Printf("WARN: failed to record heartbeat of run %s: %v", runID, err)
+		}
+	}
+	beat()
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				beat()
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func envSlice(env map[string]string) []string {
+	out := make([]string, 0, len(env))
+	for k, v := range env {
+		out = append(out, k+"="+v)
+	}
+	return out
+}
+
+// unavailableExecutor fails every run with err, e.g. when no agent can
+// take a runs_on job.
+type unavailableExecutor struct{ err error }
+
+func (e unavailableExecutor) Run(context.Context, *runner.Spec) error { return e.err }
+
+// lastLine returns the last non-blank line of s, trimmed.
+func lastLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		s = strings.TrimSpace(s[i+1:])
+	}
+	return s
+}
+
+// teeWriter adds w to an optional existing extra output writer.
+func teeWriter(existing io.Writer, w io.Writer) io.Writer {
+	if existing == nil {
+		return w
+	}
+	return io.MultiWriter(existing, w)
+}
+
+// httpDuration parses an http timeout, falling back on invalid values.
+func httpDuration(value string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		log.Printf("WARN: invalid http timeout %q, using %s", value, fallback)
+		return fallback
+	}
+	return d
+}
+
+// httpTimeouts returns the server timeouts of the http section.
+func httpTimeouts(c config.HTTPConfig) web.Timeouts {
+	return web.Timeouts{
+		ReadHeader: httpDuration(c.ReadHeaderTimeout, 10*time.Second),
+		Read:       httpDuration(c.ReadTimeout, time.Minute),
+		Write:      httpDuration(c.WriteTimeout, time.Minute),
+		Idle:       httpDuration(c.IdleTimeout, 2*time.Minute),
+	}
+}
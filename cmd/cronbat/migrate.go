@@ -0,0 +1,145 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/store"
+)
+
+const migrateUsage = "usage: cronbat migrate <status|up|down> [flags]"
+
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 1
+	}
+
+	sub := args[0]
+	rest := args[1:]
+
+	switch sub {
+	case "status":
+		return runMigrateStatus(rest)
+	case "up":
+		return runMigrateUp(rest)
+	case "down":
+		return runMigrateDown(rest)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate subcommand: %s\n", sub)
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 1
+	}
+}
+
+func runMigrateStatus(args []string) int {
+	fs := flag.NewFlagSet("migrate status", flag.ExitOnError)
+	configPath := fs.String("config", "cronbat.yaml", "path to config file")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	fs.Parse(args)
+
+	db, err := openMigrateDB(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error opening database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	states, err := store.MigrationStatus(db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading migrations: %v\n", err)
+		return 1
+	}
+	if *asJSON {
+		return printJSON(states)
+	}
+
+	pending := 0
+	for _, s := range states {
+		status := "pending"
+		if s.Applied {
+			status = "applied " + s.AppliedAt.Format(time.RFC3339)
+		} else {
+			pending++
+		}
+		fmt.Printf("  %04d  %-24s %s\n", s.Version, s.Name, status)
+	}
+	fmt.Printf("%d migration(s), %d pending\n", len(states), pending)
+	return 0
+}
+
+func runMigrateUp(args []string) int {
+	fs := flag.NewFlagSet("migrate up", flag.ExitOnError)
+	configPath := fs.String("config", "cronbat.yaml", "path to config file")
+	to := fs.Int("to", 0, "stop after this version (0 applies all)")
+	fs.Parse(args)
+
+	db, err := openMigrateDB(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error opening database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	applied, err := store.MigrateUp(db, *to)
+	for _, m := range applied {
+		fmt.Printf("  applied %04d_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	if len(applied) == 0 {
+		fmt.Println("schema is up to date")
+	}
+	return 0
+}
+
+func runMigrateDown(args []string) int {
+	fs := flag.NewFlagSet("migrate down", flag.ExitOnError)
+	configPath := fs.String("config", "cronbat.yaml", "path to config file")
+	to := fs.Int("to", -1, "revert migrations newer than this version (required; 0 reverts all and drops all data)")
+	fs.Parse(args)
+
+	if *to < 0 {
+		fmt.Fprintln(os.Stderr, "error: --to is required for migrate down")
+		return 1
+	}
+
+	db, err := openMigrateDB(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error opening database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	reverted, err := store.MigrateDown(db, *to)
+	for _, m := range reverted {
+		fmt.Printf("  reverted %04d_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	if len(reverted) == 0 {
+		fmt.Println("nothing to revert")
+	}
+	return 0
+}
+
+// openMigrateDB opens the daemon's database without applying migrations.
+func openMigrateDB(configPath string) (*sql.DB, error) {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	if err := os.MkdirAll(cfg.DataDir, 0755); err != nil {
+		return nil, err
+	}
+	return store.OpenDB(filepath.Join(cfg.DataDir, "cronbat.db"))
+}
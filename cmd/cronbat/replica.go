@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/realtime"
+	"github.com/patrickspencer/cronbat/internal/replica"
+	"github.com/patrickspencer/cronbat/internal/runlog"
+	"github.com/patrickspencer/cronbat/internal/store"
+	"github.com/patrickspencer/cronbat/internal/web"
+	"github.com/patrickspencer/cronbat/internal/web/api"
+)
+
+// runReplica serves the UI and the read-only API over another instance's
+// database and jobs directory. It schedules nothing and refuses every
+// change; run events come from polling the database.
+func runReplica(args []string) int {
+	fs := flag.NewFlagSet("replica", flag.ExitOnError)
+	configPath := fs.String("config", "cronbat.yaml", "path to config file")
+	listen := fs.String("listen", "", "address to serve on (default: listen from the config)")
+	dbPath := fs.String("db", "", "database to read (default: cronbat.db in data_dir); may be a copy")
+	poll := fs.Duration("poll", replica.DefaultPollInterval, "how often to re-read jobs and runs")
+	fs.Parse(args)
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading config: %v\n", err)
+		return 1
+	}
+	if *listen == "" {
+		*listen = cfg.Listen
+	}
+	if *dbPath == "" {
+		*dbPath = filepath.Join(cfg.DataDir, "cronbat.db")
+	}
+	if *poll <= 0 {
+		fmt.Fprintln(os.Stderr, "error: --poll must be positive")
+		return 1
+	}
+	busyTimeout, err := cfg.Store.ParseBusyTimeout()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid store.busy_timeout %q: %v\n", cfg.Store.BusyTimeout, err)
+		return 1
+	}
+	heartbeatInterval, err := cfg.Store.ParseHeartbeatInterval()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid store.heartbeat_interval %q: %v\n", cfg.Store.HeartbeatInterval, err)
+		return 1
+	}
+	if cfg.Auth.Required && len(cfg.APIKeys) == 0 {
+		fmt.Fprintln(os.Stderr, "error: auth.required needs api_keys to sign in to a replica")
+		return 1
+	}
+	sessionTTL := api.DefaultSessionTTL
+	if cfg.Auth.SessionTTL != "" {
+		sessionTTL, err = time.ParseDuration(cfg.Auth.SessionTTL)
+		if err != nil || sessionTTL <= 0 {
+			fmt.Fprintf(os.Stderr, "invalid auth.session_ttl %q\n", cfg.Auth.SessionTTL)
+			return 1
+		}
+	}
+
+	st, err := store.OpenSQLiteStore(*dbPath, store.Options{BusyTimeout: busyTimeout, MaxOpenConns: cfg.Store.MaxOpenConns, ReadOnly: true})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error opening store: %v\n", err)
+		return 1
+	}
+	defer st.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	jobs := replica.NewJobs(cfg.JobsDir, st.ListJobStates)
+	if err := jobs.Reload(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "error loading jobs: %v\n", err)
+		return 1
+	}
+	events := realtime.NewBroker()
+	poller := replica.NewPoller(st, events.Publish)
+	if err := poller.Poll(ctx, time.Now()); err != nil {
+		fmt.Fprintf(os.Stderr, "error reading runs: %v\n", err)
+		return 1
+	}
+	go func() {
+		ticker := time.NewTicker(*poll)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := jobs.Reload(ctx); err != nil {
+					log.Printf("WARN: failed to reload jobs: %v", err)
+				}
+				if err := poller.Poll(ctx, time.Now()); err != nil {
+					log.Printf("WARN: failed to poll runs: %v", err)
+				}
+			}
+		}
+	}()
+
+	runLogManager := runlog.NewManager(cfg.RunLogs.Dir, cfg.RunLogs.MaxBytesPerStream, cfg.RunLogs.RetentionDays, cfg.RunLogs.MaxTotalMB*1024*1024)
+	readRunLogs := func(jobName, runID string) (string, string, string, string, error) {
+		if !cfg.RunLogs.IsEnabled() {
+			return "", "", "", "", os.ErrNotExist
+		}
+		return runLogManager.ReadRunLogs(jobName, runID)
+	}
+	readRunLogRange := func(jobName, runID, stream string, offset, limit int64) (*runlog.LogRange, error) {
+		if !cfg.RunLogs.IsEnabled() {
+			return nil, os.ErrNotExist
+		}
+		return runLogManager.ReadRange(jobName, runID, stream, offset, limit)
+	}
+	getBackfill := func(ctx context.Context, id string) (*store.Backfill, []store.BackfillWindow, error) {
+		b, err := st.GetBackfill(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if b == nil {
+			return nil, nil, fmt.Errorf("backfill not found: %s", id)
+		}
+		windows, err := st.ListBackfillWindows(ctx, id)
+		return b, windows, err
+	}
+	listBackfills := func(ctx context.Context, jobName string) ([]*store.Backfill, error) {
+		return st.ListBackfills(ctx, jobName, "")
+	}
+
+	srv := web.NewServer(*listen, api.NewReadiness(), httpTimeouts(cfg.HTTP))
+	srv.Mount(&api.API{
+		Store:           st,
+		Events:          events,
+		GetConfig:       func() *config.Config { return cfg.Redacted() },
+		Jobs:            jobs.List,
+		JobLoadErrors:   jobs.LoadErrors,
+		JobState:        jobs.State,
+		JobsVersion:     jobs.Version,
+		SnoozeUntil:     jobs.SnoozeUntil,
+		NextRunTime:     jobs.NextRunTime,
+		Schedules:       jobs.Schedules,
+		SchedulerState:  st.GetSchedulerState,
+		HungAfter:       3 * heartbeatInterval,
+		ReadRunLogs:     readRunLogs,
+		ReadRunLogRange: readRunLogRange,
+		ListAudit:       st.ListAudit,
+		ListBackfills:   listBackfills,
+		GetBackfill:     getBackfill,
+		LastGoodJob:     st.GetLastGoodJob,
+		ListAnnotations: st.ListAnnotations,
+		GetRunContext:   st.GetRunContext,
+		StoreStats:      st.Stats,
+		APIKeys:         cfg.APIKeys,
+		Sessions:        api.NewSessions(sessionTTL),
+		RequireAuth:     cfg.Auth.Required,
+		SecureCookie:    cfg.Auth.SecureCookie,
+		Build:           buildInfo(),
+		ReadOnly:        true,
+	})
+	go func() {
+		if err := srv.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("http server error: %v", err)
+		}
+	}()
+	log.Printf("cronbat replica of %s started, listening on %s", *dbPath, *listen)
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	<-sigCh
+	cancel()
+	shutdownCtx, stop := context.WithTimeout(context.Background(), httpDuration(cfg.HTTP.ShutdownTimeout, 10*time.Second))
+	defer stop()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("ERROR: http server shutdown error: %v", err)
+	}
+	return 0
+}
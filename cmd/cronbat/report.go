@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/store"
+)
+
+const reportUsage = "usage: cronbat report export --out DIR [flags]"
+
+func runReport(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, reportUsage)
+		return 1
+	}
+
+	switch args[0] {
+	case "export":
+		return runReportExport(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown report subcommand: %s\n", args[0])
+		fmt.Fprintln(os.Stderr, reportUsage)
+		return 1
+	}
+}
+
+// reportSnapshot is written as report.json and rendered into index.html.
+type reportSnapshot struct {
+	GeneratedAt time.Time   `json:"generated_at"`
+	Days        int         `json:"days"`
+	Jobs        []reportJob `json:"jobs"`
+	Daily       []reportDay `json:"daily"`
+	RecentRuns  []reportRun `json:"recent_runs"`
+}
+
+type reportJob struct {
+	Name          string     `json:"name"`
+	Schedule      string     `json:"schedule"`
+	Enabled       bool       `json:"enabled"`
+	TotalRuns     int        `json:"total_runs"`
+	Successes     int        `json:"successes"`
+	Failures      int        `json:"failures"`
+	Warnings      int        `json:"warnings"`
+	SuccessRate   float64    `json:"success_rate"`
+	AvgDurationMs float64    `json:"avg_duration_ms"`
+	LastRun       *time.Time `json:"last_run,omitempty"`
+	LastStatus    string     `json:"last_status,omitempty"`
+}
+
+type reportDay struct {
+	Day     string `json:"day"`
+	Success int    `json:"success"`
+	Warning int    `json:"warning"`
+	Failure int    `json:"failure"`
+	Other   int    `json:"other"`
+}
+
+type reportRun struct {
+	ID         string    `json:"id"`
+	JobName    string    `json:"job_name"`
+	Status     string    `json:"status"`
+	Trigger    string    `json:"trigger"`
+	ExitCode   int       `json:"exit_code"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+func runReportExport(args []string) int {
+	fs := flag.NewFlagSet("report export", flag.ExitOnError)
+	configPath := fs.String("config", "cronbat.yaml", "path to config file")
+	outDir := fs.String("out", "", "directory to write index.html and report.json to")
+	days := fs.Int("days", 14, "days of run history to chart")
+	runs := fs.Int("runs", 100, "number of recent runs to list")
+	fs.Parse(args)
+
+	if *outDir == "" {
+		fmt.Fprintln(os.Stderr, reportUsage)
+		return 1
+	}
+	if *days <= 0 || *runs <= 0 {
+		fmt.Fprintln(os.Stderr, "error: --days and --runs must be positive")
+		return 1
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading config: %v\n", err)
+		return 1
+	}
+	jobs, _, err := config.LoadJobsReport(cfg.JobsDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading jobs: %v\n", err)
+		return 1
+	}
+	st, err := openSQLiteStore(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error opening store: %v\n", err)
+		return 1
+	}
+	defer st.Close()
+
+	snap, err := buildReport(context.Background(), st, jobs, *days, *runs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error building report: %v\n", err)
+		return 1
+	}
+	if err := writeReport(*outDir, snap); err != nil {
+		fmt.Fprintf(os.Stderr, "error writing report: %v\n", err)
+		return 1
+	}
+	fmt.Printf("wrote report for %d job(s) to %s\n", len(snap.Jobs), *outDir)
+	return 0
+}
+
+func buildReport(ctx context.Context, st *store.SQLiteStore, jobs []*config.Job, days, runs int) (*reportSnapshot, error) {
+	now := time.Now().UTC()
+	snap := &reportSnapshot{GeneratedAt: now, Days: days}
+
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].Name < jobs[k].Name })
+	names := make([]string, len(jobs))
+	for i, j := range jobs {
+		names[i] = j.Name
+	}
+	latest, err := st.GetLatestRuns(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+	for _, j := range jobs {
+		stats, err := st.GetJobStats(ctx, j.Name)
+		if err != nil {
+			return nil, err
+		}
+		rj := reportJob{
+			Name:          j.Name,
+			Schedule:      j.Schedule,
+			Enabled:       j.IsEnabled(),
+			TotalRuns:     stats.TotalRuns,
+			Successes:     stats.Successes,
+			Failures:      stats.Failures,
+			Warnings:      stats.Warnings,
+			AvgDurationMs: stats.AvgDurationMs,
+			LastRun:       stats.LastRun,
+		}
+		if stats.TotalRuns > 0 {
+			rj.SuccessRate = float64(stats.Successes) / float64(stats.TotalRuns)
+		}
+		if run := latest[j.Name]; run != nil {
+			rj.LastStatus = run.Status
+		}
+		snap.Jobs = append(snap.Jobs, rj)
+	}
+
+	// One entry per day, including days without runs.
+	start := now.Truncate(24*time.Hour).AddDate(0, 0, -(days - 1))
+	counts, err := st.DailyRunCounts(ctx, start)
+	if err != nil {
+		return nil, err
+	}
+	byDay := make(map[string]*reportDay, days)
+	for i := 0; i < days; i++ {
+		day := start.AddDate(0, 0, i).Format("2006-01-02")
+		snap.Daily = append(snap.Daily, reportDay{Day: day})
+	}
+	for i := range snap.Daily {
+		byDay[snap.Daily[i].Day] = &snap.Daily[i]
+	}
+	for _, c := range counts {
+		d, ok := byDay[c.Day]
+		if !ok {
+			continue
+		}
+		switch c.Status {
+		case "success":
+			d.Success += c.Count
+		case "warning":
+			d.Warning += c.Count
+		case "failure":
+			d.Failure += c.Count
+		default:
+			d.Other += c.Count
+		}
+	}
+
+	recent, err := st.ListRuns(ctx, store.ListOpts{Limit: runs})
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range recent {
+		snap.RecentRuns = append(snap.RecentRuns, reportRun{
+			ID:         r.ID,
+			JobName:    r.JobName,
+			Status:     r.Status,
+			Trigger:    r.Trigger,
+			ExitCode:   r.ExitCode,
+			StartedAt:  r.StartedAt,
+			DurationMs: r.DurationMs,
+		})
+	}
+	return snap, nil
+}
+
+func writeReport(dir string, snap *reportSnapshot) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "report.json"), append(data, '\n'), 0644); err != nil {
+		return err
+	}
+
+	f, err := os.Create(filepath.Join(dir, "index.html"))
+	if err != nil {
+		return err
+	}
+	if err := reportTemplate.Execute(f, reportView{reportSnapshot: snap, Bars: chartBars(snap.Daily)}); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// reportBar is one day of the stacked run chart, in SVG units.
+type reportBar struct {
+	X, Width           int
+	SuccessY, SuccessH float64
+	WarningY, WarningH float64
+	FailureY, FailureH float64
+	OtherY, OtherH     float64
+	Label, Title       string
+}
+
+type reportView struct {
+	*reportSnapshot
+	Bars []reportBar
+}
+
+const (
+	chartHeight = 120.0
+	barWidth    = 18
+	barGap      = 6
+)
+
+func chartBars(days []reportDay) []reportBar {
+	max := 0
+	for _, d := range days {
+		if total := d.Success + d.Warning + d.Failure + d.Other; total > max {
+			max = total
+		}
+	}
+	scale := 0.0
+	if max > 0 {
+		scale = chartHeight / float64(max)
+	}
+
+	bars := make([]reportBar, 0, len(days))
+	for i, d := range days {
+		b := reportBar{
+			X:     i * (barWidth + barGap),
+			Width: barWidth,
+			Label: d.Day[5:],
+			Title: fmt.Sprintf("%s: %d success, %d warning, %d failure, %d other", d.Day, d.Success, d.Warning, d.Failure, d.Other),
+		}
+		b.SuccessH = float64(d.Success) * scale
+		b.WarningH = float64(d.Warning) * scale
+		b.FailureH = float64(d.Failure) * scale
+		b.OtherH = float64(d.Other) * scale
+		b.SuccessY = chartHeight - b.SuccessH
+		b.WarningY = b.SuccessY - b.WarningH
+		b.FailureY = b.WarningY - b.FailureH
+		b.OtherY = b.FailureY - b.OtherH
+		bars = append(bars, b)
+	}
+	return bars
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"percent": func(f float64) string { return fmt.Sprintf("%.0f%%", f*100) },
+	"ms": func(ms float64) string {
+		return (time.Duration(ms) * time.Millisecond).Round(time.Millisecond).String()
+	},
+	"when":       func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04:05 UTC") },
+	"chartWidth": func(n int) int { return n*(barWidth+barGap) - barGap },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>cronbat report</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem; color: #1f2328; }
+h1 { margin-bottom: 0.25rem; }
+.muted { color: #656d76; }
+table { border-collapse: collapse; margin: 1rem 0 2rem; width: 100%; }
+th, td { text-align: left; padding: 0.35rem 0.75rem; border-bottom: 1px solid #d0d7de; }
+.success { color: #1a7f37; }
+.warning { color: #9a6700; }
+.failure { color: #cf222e; }
+svg text { font-size: 9px; fill: #656d76; }
+</style>
+</head>
+<body>
+<h1>cronbat report</h1>
+<p class="muted">Generated {{when .GeneratedAt}}</p>
+
+<h2>Runs per day (last {{.Days}} days)</h2>
+<svg width="{{chartWidth (len .Bars)}}" height="140" role="img" aria-label="runs per day">
+{{- range .Bars}}
+<g><title>{{.Title}}</title>
+<rect x="{{.X}}" y="{{.SuccessY}}" width="{{.Width}}" height="{{.SuccessH}}" fill="#2da44e"/>
+<rect x="{{.X}}" y="{{.WarningY}}" width="{{.Width}}" height="{{.WarningH}}" fill="#bf8700"/>
+<rect x="{{.X}}" y="{{.FailureY}}" width="{{.Width}}" height="{{.FailureH}}" fill="#cf222e"/>
+<rect x="{{.X}}" y="{{.OtherY}}" width="{{.Width}}" height="{{.OtherH}}" fill="#8c959f"/>
+<text x="{{.X}}" y="135">{{.Label}}</text></g>
+{{- end}}
+</svg>
+
+<h2>Jobs</h2>
+<table>
+<tr><th>Job</th><th>Schedule</th><th>Enabled</th><th>Runs</th><th>Warnings</th><th>Success rate</th><th>Avg duration</th><th>Last run</th></tr>
+{{- range .Jobs}}
+<tr>
+<td>{{.Name}}</td><td><code>{{.Schedule}}</code></td><td>{{if .Enabled}}yes{{else}}no{{end}}</td>
+<td>{{.TotalRuns}}</td><td>{{.Warnings}}</td><td>{{if .TotalRuns}}{{percent .SuccessRate}}{{else}}-{{end}}</td>
+<td>{{if .TotalRuns}}{{ms .AvgDurationMs}}{{else}}-{{end}}</td>
+<td>{{if .LastRun}}{{when .LastRun}} <span class="{{.LastStatus}}">{{.LastStatus}}</span>{{else}}-{{end}}</td>
+</tr>
+{{- end}}
+</table>
+
+<h2>Recent runs</h2>
+<table>
+<tr><th>Started</th><th>Job</th><th>Status</th><th>Exit</th><th>Duration</th><th>Trigger</th></tr>
+{{- range .RecentRuns}}
+<tr>
+<td>{{when .StartedAt}}</td><td>{{.JobName}}</td><td class="{{.Status}}">{{.Status}}</td>
+<td>{{.ExitCode}}</td><td>{{.DurationMs}} ms</td><td>{{.Trigger}}</td>
+</tr>
+{{- end}}
+</table>
+<p class="muted">Raw data: <a href="report.json">report.json</a></p>
+</body>
+</html>
+`))
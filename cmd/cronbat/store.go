@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/store"
+)
+
+const storeUsage = "usage: cronbat store <stats|compact> [flags]"
+
+func runStore(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, storeUsage)
+		return 1
+	}
+
+	sub := args[0]
+	rest := args[1:]
+
+	switch sub {
+	case "stats":
+		return runStoreStats(rest)
+	case "compact":
+		return runStoreCompact(rest)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown store subcommand: %s\n", sub)
+		fmt.Fprintln(os.Stderr, storeUsage)
+		return 1
+	}
+}
+
+func runStoreStats(args []string) int {
+	fs := flag.NewFlagSet("store stats", flag.ExitOnError)
+	apiURL := fs.String("api", "", "API URL (if set, asks the running daemon)")
+	configPath := fs.String("config", "cronbat.yaml", "path to config file (for direct DB access)")
+	asJSON := fs.Bool("json", false, "print JSON instead of a summary")
+	fs.Parse(args)
+
+	var stats store.DBStats
+	if *apiURL != "" {
+		if err := storeAPIRequest(http.MethodGet, *apiURL, "/api/v1/store/stats", &stats); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+	} else {
+		st, err := openStoreDirect(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error opening store: %v\n", err)
+			return 1
+		}
+		defer st.Close()
+
+		s, err := st.Stats(context.Background())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading stats: %v\n", err)
+			return 1
+		}
+		stats = *s
+	}
+
+	if *asJSON {
+		return printJSON(stats)
+	}
+	printDBStats(&stats)
+	return 0
+}
+
+func runStoreCompact(args []string) int {
+	fs := flag.NewFlagSet("store compact", flag.ExitOnError)
+	apiURL := fs.String("api", "", "API URL (if set, the running daemon compacts its own DB)")
+	configPath := fs.String("config", "cronbat.yaml", "path to config file (for direct DB access)")
+	asJSON := fs.Bool("json", false, "print JSON instead of a summary")
+	fs.Parse(args)
+
+	var result store.CompactResult
+	if *apiURL != "" {
+		if err := storeAPIRequest(http.MethodPost, *apiURL, "/api/v1/store/compact", &result); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+	} else {
+		st, err := openStoreDirect(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error opening store: %v\n", err)
+			return 1
+		}
+		defer st.Close()
+
+		r, err := st.Compact(context.Background())
+		if err != nil {
+			if r != nil && r.Integrity != "" {
+				fmt.Fprintf(os.Stderr, "integrity: %s\n", r.Integrity)
+			}
+			fmt.Fprintf(os.Stderr, "error compacting store: %v\n", err)
+			return 1
+		}
+		result = *r
+	}
+
+	if *asJSON {
+		return printJSON(result)
+	}
+	fmt.Printf("integrity: %s\n", result.Integrity)
+	fmt.Println("before:")
+	printDBStats(result.Before)
+	fmt.Println("after:")
+	printDBStats(result.After)
+	if result.Before != nil && result.After != nil {
+		saved := result.Before.FileBytes + result.Before.WALBytes - result.After.FileBytes - result.After.WALBytes
+		fmt.Printf("reclaimed %s in %s\n", formatBytes(saved), time.Duration(result.DurationMs)*time.Millisecond)
+	}
+	return 0
+}
+
+func openStoreDirect(configPath string) (*store.SQLiteStore, error) {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	return openSQLiteStore(cfg)
+}
+
+// openSQLiteStore opens the database in cfg.DataDir with the store
+// section's connection settings.
+func openSQLiteStore(cfg *config.Config) (*store.SQLiteStore, error) {
+	busyTimeout, err := cfg.Store.ParseBusyTimeout()
+	if err != nil {
+		return nil, fmt.Errorf("invalid store.busy_timeout %q: %w", cfg.Store.BusyTimeout, err)
+	}
+	return store.OpenSQLiteStore(filepath.Join(cfg.DataDir, "cronbat.db"), store.Options{
+		BusyTimeout:  busyTimeout,
+		MaxOpenConns: cfg.Store.MaxOpenConns,
+		BusyRetries:  cfg.Store.BusyRetries,
+	})
+}
+
+func storeAPIRequest(method, apiURL, path string, out any) error {
+	req, err := http.NewRequest(method, strings.TrimRight(apiURL, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func printJSON(v any) int {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func printDBStats(s *store.DBStats) {
+	if s == nil {
+		return
+	}
+	fmt.Printf("  path:        %s\n", s.Path)
+	fmt.Printf("  file size:   %s (wal %s)\n", formatBytes(s.FileBytes), formatBytes(s.WALBytes))
+	fmt.Printf("  free pages:  %d of %d (%s reclaimable)\n", s.FreePages, s.PageCount, formatBytes(s.FreePages*s.PageSize))
+	fmt.Printf("  runs:        %d across %d jobs\n", s.RunCount, s.JobCount)
+	fmt.Printf("  tail bytes:  %s\n", formatBytes(s.TailBytes))
+	if s.OldestRun != nil {
+		fmt.Printf("  oldest run:  %s\n", s.OldestRun.Format(time.RFC3339))
+	}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit && n > -unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit || v <= -unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
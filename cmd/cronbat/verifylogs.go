@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/runlog"
+	"github.com/patrickspencer/cronbat/internal/store"
+)
+
+// logVerifyResult is one run's entry in `cronbat verify-logs --json`.
+type logVerifyResult struct {
+	RunID   string            `json:"run_id"`
+	JobName string            `json:"job_name"`
+	Checks  []runlog.LogCheck `json:"checks"`
+}
+
+// runVerifyLogs re-hashes persisted run logs against the checksums recorded
+// when they were written. It exits 1 if any file is missing or changed.
+func runVerifyLogs(args []string) int {
+	fs := flag.NewFlagSet("verify-logs", flag.ExitOnError)
+	configPath := fs.String("config", "cronbat.yaml", "path to config file")
+	jobName := fs.String("job", "", "only verify runs of this job")
+	runID := fs.String("run", "", "only verify this run")
+	limit := fs.Int("limit", 100, "number of most recent runs to verify")
+	asJSON := fs.Bool("json", false, "print JSON instead of a summary")
+	fs.Parse(args)
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading config: %v\n", err)
+		return 1
+	}
+	st, err := openSQLiteStore(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error opening store: %v\n", err)
+		return 1
+	}
+	defer st.Close()
+
+	m := runlog.NewManager(cfg.RunLogs.Dir, cfg.RunLogs.MaxBytesPerStream, cfg.RunLogs.RetentionDays, cfg.RunLogs.MaxTotalMB*1024*1024)
+	if ac := cfg.RunLogs.Archive; ac.Enabled {
+		archive, err := newRunLogArchive(ac)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: invalid run_logs.archive config: %v\n", err)
+			return 1
+		}
+		m.SetArchive(archive, ac.AfterDays)
+	}
+
+	ctx := context.Background()
+	var runs []*store.Run
+	if *runID != "" {
+		run, err := st.GetRun(ctx, *runID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading run: %v\n", err)
+			return 1
+		}
+		if run == nil {
+			fmt.Fprintf(os.Stderr, "error: run %s not found\n", *runID)
+			return 1
+		}
+		runs = append(runs, run)
+	} else {
+		runs, err = st.ListRuns(ctx, store.ListOpts{JobName: *jobName, Limit: *limit})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error listing runs: %v\n", err)
+			return 1
+		}
+	}
+
+	results := make([]logVerifyResult, 0, len(runs))
+	var verified, unverified, failed int
+	for _, run := range runs {
+		checks := m.Verify(run.JobName, run.ID, run.StdoutSHA256, run.StderrSHA256)
+		if checks == nil {
+			checks = []runlog.LogCheck{}
+		}
+		results = append(results, logVerifyResult{RunID: run.ID, JobName: run.JobName, Checks: checks})
+		if len(checks) == 0 {
+			unverified++
+			continue
+		}
+		verified++
+		for _, c := range checks {
+			if c.Status == runlog.CheckOK {
+				continue
+			}
+			failed++
+			if !*asJSON {
+				fmt.Printf("%s  %-24s %s %s: %s\n", run.ID, run.JobName, c.Stream, c.Status, c.Path)
+				if c.Error != "" {
+					fmt.Printf("    %s\n", c.Error)
+				}
+			}
+		}
+	}
+
+	if *asJSON {
+		if code := printJSON(results); code != 0 {
+			return code
+		}
+	} else {
+		fmt.Printf("verified %d runs (%d without checksums): %d problems\n", verified, unverified, failed)
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// newRunLogArchive builds the archive configured in run_logs.archive.
+func newRunLogArchive(ac config.RunLogArchiveConfig) (*runlog.S3Archive, error) {
+	return runlog.NewS3Archive(runlog.S3Config{
+		Endpoint:        ac.Endpoint,
+		Region:          ac.Region,
+		Bucket:          ac.Bucket,
+		Prefix:          ac.Prefix,
+		PathStyle:       ac.PathStyle,
+		AccessKeyID:     ac.AccessKeyID,
+		SecretAccessKey: ac.SecretAccessKey,
+		SessionToken:    ac.SessionToken,
+	})
+}
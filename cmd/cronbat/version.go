@@ -0,0 +1,54 @@
+package main
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"github.com/patrickspencer/cronbat/internal/web/api"
+)
+
+// version, commit, and buildDate are set at build time with
+// -ldflags "-X main.version=v1.2.3 -X main.commit=abc123 -X main.buildDate=2024-01-02T15:04:05Z".
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+// cronbatVersion returns the build version, falling back to the module
+// version recorded by go install.
+func cronbatVersion() string {
+	if version != "dev" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return version
+}
+
+// buildInfo describes this binary. The commit and build date fall back to
+// the VCS stamp the go command records when building from a checkout.
+func buildInfo() api.BuildInfo {
+	b := api.BuildInfo{
+		Version:   cronbatVersion(),
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch {
+			case s.Key == "vcs.revision" && b.Commit == "":
+				b.Commit = s.Value
+			case s.Key == "vcs.time" && b.BuildDate == "":
+				b.BuildDate = s.Value
+			case s.Key == "vcs.modified" && s.Value == "true":
+				b.Modified = true
+			}
+		}
+	}
+	return b
+}
@@ -1,55 +1,291 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/notify"
 )
 
+// maxRestartBackoff caps the wait between restarts that do not bring
+// cronbat back.
+const maxRestartBackoff = 30 * time.Minute
+
+// watchdogMessage is the notification text for notify_urls entries without
+// a message of their own.
+const watchdogMessage = `cronbat watchdog: {{.Status}}{{if .Error}}: {{.Error}}{{end}}`
+
 func runWatchdog(args []string) int {
 	fs := flag.NewFlagSet("watchdog", flag.ExitOnError)
 	apiURL := fs.String("api", "http://localhost:8080", "cronbat API URL")
 	restartCmd := fs.String("restart-cmd", "", "command to run if unhealthy")
 	timeoutSec := fs.Int("timeout", 5, "health check timeout in seconds")
+	checkReady := fs.Bool("ready", false, "check readiness (/api/v1/ready) instead of liveness")
+	deep := fs.Bool("deep", false, "run the deep health check: database writable, scheduler running")
+	interval := fs.Duration("interval", 0, "keep running and check this often (default: check once and exit)")
+	failures := fs.Int("failures", 1, "consecutive failed checks before restarting")
+	backoff := fs.Duration("restart-backoff", 30*time.Second, "wait after a restart before the next one; doubles while restarts do not help")
+	configPath := fs.String("config", "", "cronbat config file with a watchdog block (notify_urls, state_file)")
+	stateFile := fs.String("state-file", "", "file keeping failure counts between checks (default: watchdog.state_file with --config)")
 	fs.Parse(args)
 
-	url := strings.TrimRight(*apiURL, "/") + "/api/v1/health"
+	if *failures < 1 {
+		fmt.Fprintln(os.Stderr, "error: --failures must be at least 1")
+		return 1
+	}
 
-	client := &http.Client{
-		Timeout: time.Duration(*timeoutSec) * time.Second,
+	endpoint := "/api/v1/health"
+	if *checkReady {
+		endpoint = "/api/v1/ready"
+	} else if *deep {
+		endpoint += "?deep=1"
 	}
 
-	resp, err := client.Get(url)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "health check failed: %v\n", err)
-		return handleUnhealthy(*restartCmd)
+	w := &watchdog{
+		url:        strings.TrimRight(*apiURL, "/") + endpoint,
+		client:     &http.Client{Timeout: time.Duration(*timeoutSec) * time.Second},
+		restartCmd: *restartCmd,
+		failures:   *failures,
+		backoff:    *backoff,
+		stateFile:  *stateFile,
+	}
+	if *configPath != "" {
+		cfg, err := config.LoadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error loading config: %v\n", err)
+			return 1
+		}
+		for i, n := range cfg.Watchdog.NotifyURLs {
+			if err := n.Validate(); err != nil {
+				fmt.Fprintf(os.Stderr, "error: watchdog.notify_urls[%d]: %v\n", i, err)
+				return 1
+			}
+		}
+		w.notifyURLs = cfg.Watchdog.NotifyURLs
+		if w.stateFile == "" {
+			w.stateFile = cfg.Watchdog.StateFile
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		fmt.Fprintf(os.Stderr, "health check returned status %d\n", resp.StatusCode)
-		return handleUnhealthy(*restartCmd)
+	if *interval <= 0 {
+		return w.check()
 	}
 
-	return 0
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		w.check()
+		select {
+		case <-sigCh:
+			return 0
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchdogState is what the watchdog remembers between checks.
+type watchdogState struct {
+	ConsecutiveFailures int `json:"consecutive_failures"`
+	// Restarts counts restarts since cronbat was last healthy; it sets
+	// the backoff before the next one.
+	Restarts    int       `json:"restarts"`
+	LastRestart time.Time `json:"last_restart"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+type watchdog struct {
+	url        string
+	client     *http.Client
+	restartCmd string
+	failures   int
+	backoff    time.Duration
+	notifyURLs []config.NotifyConfig
+	// stateFile persists state; empty keeps it in memory only.
+	stateFile string
+	state     watchdogState
 }
 
-func handleUnhealthy(restartCmd string) int {
-	if restartCmd == "" {
+// check runs one health check, restarting cronbat if it has failed often
+// enough and the restart backoff allows. It returns the exit code of a
+// one-shot watchdog.
+func (w *watchdog) check() int {
+	w.loadState()
+	defer w.saveState()
+
+	err := w.probe()
+	if err == nil {
+		if w.state.Restarts > 0 {
+			w.notify("recovered", "")
+		}
+		w.state = watchdogState{}
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "health check failed: %v\n", err)
+	w.state.ConsecutiveFailures++
+	w.state.LastError = err.Error()
+
+	if w.restartCmd == "" || w.state.ConsecutiveFailures < w.failures {
 		return 1
 	}
+	now := time.Now().UTC()
+	if w.state.Restarts > 0 {
+		next := w.state.LastRestart.Add(restartBackoff(w.backoff, w.state.Restarts))
+		if now.Before(next) {
+			fmt.Fprintf(os.Stderr, "restart backoff: next restart attempt after %s\n", next.Format(time.RFC3339))
+			return 1
+		}
+	}
 
-	fmt.Fprintf(os.Stderr, "attempting restart: %s\n", restartCmd)
-	cmd := exec.Command("sh", "-c", restartCmd)
+	fmt.Fprintf(os.Stderr, "attempting restart: %s\n", w.restartCmd)
+	cmd := exec.Command("sh", "-c", w.restartCmd)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	err = cmd.Run()
+	w.state.Restarts++
+	w.state.LastRestart = now
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "restart command failed: %v\n", err)
+		w.notify("restart_failed", fmt.Sprintf("%s; restart command failed: %v", w.state.LastError, err))
 		return 1
 	}
+	w.notify("restarted", w.state.LastError)
 	return 0
 }
+
+// probe returns why cronbat is unhealthy, or nil.
+func (w *watchdog) probe() error {
+	resp, err := w.client.Get(w.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	// A failed deep check names the components that failed.
+	var body struct {
+		Checks []struct {
+			Name  string `json:"name"`
+			OK    bool   `json:"ok"`
+			Error string `json:"error"`
+		} `json:"checks"`
+	}
+	var failed []string
+	if json.NewDecoder(resp.Body).Decode(&body) == nil {
+		for _, c := range body.Checks {
+			if !c.OK {
+				failed = append(failed, c.Name+": "+c.Error)
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("status %d (%s)", resp.StatusCode, strings.Join(failed, "; "))
+	}
+	return fmt.Errorf("status %d", resp.StatusCode)
+}
+
+// restartBackoff is the wait after the given number of restarts: base,
+// doubled for each restart after the first, capped at maxRestartBackoff.
+func restartBackoff(base time.Duration, restarts int) time.Duration {
+	d := base
+	for i := 1; i < restarts && d < maxRestartBackoff; i++ {
+		d *= 2
+	}
+	if d > maxRestartBackoff {
+		d = maxRestartBackoff
+	}
+	return d
+}
+
+// notify tells the watchdog's notify_urls about a restart or recovery.
+// Incident entries open an incident on restarts and resolve it on
+// recovery.
+func (w *watchdog) notify(status, errMsg string) {
+	if len(w.notifyURLs) == 0 {
+		return
+	}
+	now := time.Now().UTC()
+	p := notify.Payload{
+		Job:        "cronbat",
+		Status:     status,
+		Trigger:    "watchdog",
+		Error:      errMsg,
+		StartedAt:  now,
+		FinishedAt: now,
+	}
+	// on: filters see restarts as failures and recoveries as successes.
+	kind := "failure"
+	if status == "recovered" {
+		kind = "success"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), notify.Timeout*time.Duration(len(w.notifyURLs)))
+	defer cancel()
+	client := &http.Client{Timeout: notify.Timeout}
+	for i, n := range w.notifyURLs {
+		if n.Message == "" {
+			n.Message = watchdogMessage
+		}
+		var err error
+		switch {
+		case n.IsIncident() && kind == "failure":
+			err = notify.Trigger(ctx, client, n, "cronbat/watchdog", p)
+		case n.IsIncident():
+			err = notify.Resolve(ctx, client, n, "cronbat/watchdog", p)
+		case n.Matches(kind):
+			err = notify.Send(ctx, client, n, p)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "watchdog.notify_urls[%d]: %v\n", i, err)
+		}
+	}
+}
+
+func (w *watchdog) loadState() {
+	if w.stateFile == "" {
+		return
+	}
+	data, err := os.ReadFile(w.stateFile)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "warning: failed to read watchdog state: %v\n", err)
+		}
+		return
+	}
+	var st watchdogState
+	if err := json.Unmarshal(data, &st); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: ignoring unreadable watchdog state: %v\n", err)
+		return
+	}
+	w.state = st
+}
+
+func (w *watchdog) saveState() {
+	if w.stateFile == "" {
+		return
+	}
+	data, err := json.MarshalIndent(w.state, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(w.stateFile), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(w.stateFile, append(data, '\n'), 0644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to save watchdog state: %v\n", err)
+	}
+}
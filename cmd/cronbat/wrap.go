@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -15,8 +16,10 @@ import (
 	"time"
 
 	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/gitrev"
 	"github.com/patrickspencer/cronbat/internal/runlog"
 	"github.com/patrickspencer/cronbat/internal/runner"
+	"github.com/patrickspencer/cronbat/internal/spool"
 	"github.com/patrickspencer/cronbat/internal/store"
 	"github.com/patrickspencer/cronbat/pkg/plugin"
 )
@@ -27,6 +30,8 @@ func runWrap(args []string) int {
 	configPath := fs.String("config", "cronbat.yaml", "path to config file")
 	apiURL := fs.String("api", "", "if set, record via API instead of direct DB access")
 	timeout := fs.Duration("timeout", 0, "optional command timeout")
+	noOverlap := fs.Bool("no-overlap", false, "skip the run, recording it as skipped:overlap, while the previous one is still running")
+	spoolDir := fs.String("spool-dir", "", "where to keep run reports that could not be recorded (default: data_dir/spool, or the user cache dir with --api)")
 
 	// Find "--" separator for the wrapped command.
 	var wrapArgs, cmdArgs []string
@@ -57,12 +62,23 @@ func runWrap(args []string) int {
 	command := strings.Join(cmdArgs, " ")
 
 	if *apiURL != "" {
-		return wrapViaAPI(*apiURL, *name, command, *timeout)
+		if *noOverlap {
+			fmt.Fprintln(os.Stderr, "error: --no-overlap cannot be used with --api")
+			return 1
+		}
+		if *spoolDir == "" {
+			cacheDir, err := os.UserCacheDir()
+			if err != nil {
+				cacheDir = os.TempDir()
+			}
+			*spoolDir = filepath.Join(cacheDir, "cronbat", spool.DirName)
+		}
+		return wrapViaAPI(*apiURL, *name, command, *timeout, *spoolDir)
 	}
-	return wrapDirect(*configPath, *name, command, *timeout)
+	return wrapDirect(*configPath, *name, command, *timeout, *noOverlap, *spoolDir)
 }
 
-func wrapDirect(configPath, jobName, command string, timeout time.Duration) int {
+func wrapDirect(configPath, jobName, command string, timeout time.Duration, noOverlap bool, spoolDir string) int {
 	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error loading config: %v\n", err)
@@ -74,14 +90,52 @@ func wrapDirect(configPath, jobName, command string, timeout time.Duration) int
 		return 1
 	}
 
-	dbPath := filepath.Join(cfg.DataDir, "cronbat.db")
-	st, err := store.NewSQLiteStore(dbPath)
+	st, err := openSQLiteStore(cfg)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error opening store: %v\n", err)
 		return 1
 	}
 	defer st.Close()
 
+	// Record what earlier invocations spooled while the database was locked.
+	if spoolDir == "" {
+		spoolDir = filepath.Join(cfg.DataDir, spool.DirName)
+	}
+	if n, err := spool.Drain(spoolDir, func(rep *spool.Report) error {
+		return st.RecordRun(context.Background(), rep.Run())
+	}); err != nil {
+		log.Printf("WARN: recorded %d spooled runs, then: %v", n, err)
+	}
+
+	if noOverlap {
+		lockDir := filepath.Join(cfg.DataDir, "locks")
+		if err := os.MkdirAll(lockDir, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "error creating lock dir: %v\n", err)
+			return 1
+		}
+		unlock, locked, err := tryLockFile(filepath.Join(lockDir, url.PathEscape(jobName)+".lock"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error taking lock: %v\n", err)
+			return 1
+		}
+		if !locked {
+			now := time.Now().UTC()
+			recordOrSpool(st, spoolDir, &store.Run{
+				ID:         store.NewRunID(),
+				JobName:    jobName,
+				Status:     "skipped:overlap",
+				StartedAt:  now,
+				FinishedAt: &now,
+				Trigger:    "cron",
+				ErrorMsg:   "previous run still running",
+				Host:       cfg.Host.Name,
+			})
+			log.Printf("job %q skipped: previous run still running", jobName)
+			return 0
+		}
+		defer unlock()
+	}
+
 	runLogManager := runlog.NewManager(
 		cfg.RunLogs.Dir,
 		cfg.RunLogs.MaxBytesPerStream,
@@ -98,10 +152,19 @@ func wrapDirect(configPath, jobName, command string, timeout time.Duration) int
 		Status:    "running",
 		StartedAt: startedAt,
 		Trigger:   "cron",
+		Host:      cfg.Host.Name,
+	}
+	if commit, err := gitrev.Head(cfg.JobsDir); err == nil {
+		run.JobsCommit = commit
 	}
 	if err := st.RecordRun(context.Background(), run); err != nil {
 		log.Printf("WARN: failed to record run start: %v", err)
 	}
+	if interval, err := cfg.Store.ParseHeartbeatInterval(); err != nil {
+		log.Printf("WARN: invalid store.heartbeat_interval %q: %v", cfg.Store.HeartbeatInterval, err)
+	} else if interval > 0 {
+		defer startHeartbeat(st, runID, interval)()
+	}
 
 	r := runner.NewRunner()
 	jctx := plugin.JobContext{
@@ -109,7 +172,10 @@ func wrapDirect(configPath, jobName, command string, timeout time.Duration) int
 		Trigger: "cron",
 	}
 
-	var runOpts runner.RunOptions
+	runOpts := runner.RunOptions{TailBytes: cfg.Defaults.TailBytes}
+	if runOpts.TailBytes > cfg.Store.MaxTailBytes {
+		runOpts.TailBytes = cfg.Store.MaxTailBytes
+	}
 	var fileWriters *runlog.RunWriters
 	if cfg.RunLogs.IsEnabled() {
 		if err := os.MkdirAll(runLogManager.BaseDir(), 0755); err == nil {
@@ -124,10 +190,33 @@ func wrapDirect(configPath, jobName, command string, timeout time.Duration) int
 		}
 	}
 
+	rc := buildRunContext(runID, &config.Job{Name: jobName, Command: command}, jctx, timeout, "")
+	rc.Definition = "" // wrapped commands have no job definition
+	rc.Hostname = cfg.Host.Name
+	if err := st.SaveRunContext(context.Background(), rc); err != nil {
+		log.Printf("WARN: failed to record run context: %v", err)
+	}
+
+	outputPath, err := runner.NewOutputFile("", "")
+	if err != nil {
+		log.Printf("WARN: failed to create output file: %v", err)
+	} else {
+		jctx.Env = map[string]string{runner.OutputEnv: outputPath}
+	}
+
 	result := r.Run(context.Background(), command, jctx, timeout, &runOpts)
+	if outputPath != "" {
+		outputs, err := runner.ReadOutputs(outputPath)
+		if err != nil {
+			log.Printf("WARN: failed to read outputs: %v", err)
+		}
+		run.Outputs = outputs
+	}
 
 	if fileWriters != nil {
 		_ = fileWriters.Close()
+		run.StdoutSHA256 = fileWriters.Stdout.Checksum()
+		run.StderrSHA256 = fileWriters.Stderr.Checksum()
 	}
 
 	// Also write to real stdout/stderr so cron can capture output for MAILTO.
@@ -152,16 +241,35 @@ func wrapDirect(configPath, jobName, command string, timeout time.Duration) int
 	run.StderrTail = result.Stderr
 	run.ErrorMsg = result.Error
 
-	if err := st.RecordRun(context.Background(), run); err != nil {
-		log.Printf("WARN: failed to record run result: %v", err)
-	}
+	recordOrSpool(st, spoolDir, run)
 
 	return result.ExitCode
 }
 
-func wrapViaAPI(apiURL, jobName, command string, timeout time.Duration) int {
+// recordOrSpool records a finished run, spooling it for a later wrap or
+// the daemon to record if the database is unavailable.
+func recordOrSpool(st *store.SQLiteStore, spoolDir string, run *store.Run) {
+	err := st.RecordRun(context.Background(), run)
+	if err == nil {
+		return
+	}
+	if spoolErr := spool.Write(spoolDir, spool.FromRun(run)); spoolErr != nil {
+		log.Printf("WARN: failed to record run %s (%v) or spool it: %v", run.ID, err, spoolErr)
+		return
+	}
+	log.Printf("WARN: failed to record run %s, spooled it: %v", run.ID, err)
+}
+
+func wrapViaAPI(apiURL, jobName, command string, timeout time.Duration, spoolDir string) int {
 	apiURL = strings.TrimRight(apiURL, "/")
 
+	// Send what earlier invocations spooled while the API was unreachable.
+	if n, err := spool.Drain(spoolDir, func(rep *spool.Report) error {
+		return postRunReport(apiURL, rep)
+	}); err != nil {
+		log.Printf("WARN: sent %d spooled runs, then: %v", n, err)
+	}
+
 	// Execute command locally.
 	ctx := context.Background()
 	if timeout > 0 {
@@ -170,14 +278,14 @@ func wrapViaAPI(apiURL, jobName, command string, timeout time.Duration) int {
 		defer cancel()
 	}
 
-	start := time.Now()
+	startedAt := time.Now().UTC()
 	cmd := exec.CommandContext(ctx, "sh", "-c", command)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
 	cmdErr := cmd.Run()
-	durationMs := time.Since(start).Milliseconds()
+	finishedAt := time.Now().UTC()
 
 	exitCode := 0
 	errMsg := ""
@@ -214,24 +322,52 @@ func wrapViaAPI(apiURL, jobName, command string, timeout time.Duration) int {
 		status = "failure"
 	}
 
-	payload := map[string]any{
-		"job_name":    jobName,
-		"status":      status,
-		"exit_code":   exitCode,
-		"duration_ms": durationMs,
-		"stdout_tail": stdoutStr,
-		"stderr_tail": stderrStr,
-		"error_msg":   errMsg,
-		"trigger":     "cron",
+	rep := &spool.Report{
+		ID:         store.NewRunID(),
+		JobName:    jobName,
+		Status:     status,
+		ExitCode:   exitCode,
+		StartedAt:  startedAt,
+		FinishedAt: &finishedAt,
+		DurationMs: finishedAt.Sub(startedAt).Milliseconds(),
+		StdoutTail: stdoutStr,
+		StderrTail: stderrStr,
+		ErrorMsg:   errMsg,
+		Trigger:    "cron",
 	}
-
-	body, _ := json.Marshal(payload)
-	resp, err := http.Post(apiURL+"/api/v1/jobs/"+jobName+"/run", "application/json", bytes.NewReader(body))
-	if err != nil {
-		log.Printf("WARN: failed to POST run result to API: %v", err)
-	} else {
-		resp.Body.Close()
+	rep.Host, _ = os.Hostname()
+	if err := postRunReport(apiURL, rep); err != nil {
+		if spoolErr := spool.Write(spoolDir, rep); spoolErr != nil {
+			log.Printf("WARN: failed to report run to API (%v) or spool it: %v", err, spoolErr)
+		} else {
+			log.Printf("WARN: failed to report run to API, spooled it: %v", err)
+		}
 	}
 
 	return exitCode
 }
+
+// postRunReport sends rep to the daemon. A report the daemon rejects as
+// invalid, or as clashing with a run it did not report, is dropped rather
+// than retried forever.
+func postRunReport(apiURL string, rep *spool.Report) error {
+	body, err := json.Marshal(rep)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Transport: apiKeyTransport{}, Timeout: 30 * time.Second}
+	resp, err := client.Post(apiURL+"/api/v1/runs", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusBadRequest, resp.StatusCode == http.StatusConflict:
+		log.Printf("WARN: API rejected report of run %s; dropping it", rep.ID)
+		return nil
+	default:
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+}
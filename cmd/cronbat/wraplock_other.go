@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "errors"
+
+// tryLockFile is unsupported where flock is unavailable.
+func tryLockFile(path string) (unlock func(), locked bool, err error) {
+	return nil, false, errors.New("--no-overlap is not supported on this platform")
+}
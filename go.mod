@@ -3,6 +3,7 @@ module github.com/patrickspencer/cronbat
 go 1.20
 
 require (
+	github.com/BurntSushi/toml v1.4.0
 	github.com/oklog/ulid/v2 v2.1.1
 	github.com/robfig/cron/v3 v3.0.1
 	gopkg.in/yaml.v3 v3.0.1
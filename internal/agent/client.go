@@ -0,0 +1,194 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/runner"
+)
+
+// Agent connects to a cronbat server and runs the processes it sends.
+type Agent struct {
+	// Server is the server's base URL, e.g. https://cronbat.example.com.
+	Server   string
+	Token    string
+	Hostname string
+	Labels   map[string]string
+	Version  string
+	// Executor runs the processes; nil means runner.OSExecutor.
+	Executor runner.Executor
+}
+
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+)
+
+// Run keeps a connection to the server until ctx is done, reconnecting
+// with backoff. Processes still running when a connection drops are
+// stopped, since their results could no longer be reported.
+func (a *Agent) Run(ctx context.Context) error {
+	delay := minReconnectDelay
+	for {
+		started := time.Now()
+		err := a.serve(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if time.Since(started) > maxReconnectDelay {
+			delay = minReconnectDelay
+		}
+		log.Printf("WARN: agent connection to %s lost: %v; reconnecting in %s", a.Server, err, delay)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+func (a *Agent) serve(ctx context.Context) error {
+	header := http.Header{}
+	if a.Token != "" {
+		header.Set("Authorization", "Bearer "+a.Token)
+	}
+	conn, err := dial(ctx, strings.TrimRight(a.Server, "/")+ConnectPath, header)
+	if err != nil {
+		return err
+	}
+	conn.readTimeout = 3 * pingInterval
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stop:
+		}
+		conn.close()
+	}()
+
+	if err := conn.writeJSON(message{Type: msgHello, Hostname: a.Hostname, Labels: a.Labels, Version: a.Version}); err != nil {
+		return err
+	}
+	log.Printf("agent connected to %s as %s", a.Server, a.Hostname)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	running := make(map[string]context.CancelFunc)
+	defer func() {
+		mu.Lock()
+		for _, cancel := range running {
+			cancel()
+		}
+		mu.Unlock()
+		wg.Wait()
+	}()
+
+	for {
+		data, err := conn.readMessage()
+		if err != nil {
+			return err
+		}
+		var m message
+		if err := json.Unmarshal(data, &m); err != nil {
+			log.Printf("WARN: agent got an invalid message: %v", err)
+			continue
+		}
+		switch m.Type {
+		case msgRun:
+			runCtx, cancel := context.WithCancel(ctx)
+			mu.Lock()
+			running[m.RunID] = cancel
+			mu.Unlock()
+			wg.Add(1)
+			go func(m message) {
+				defer wg.Done()
+				a.execute(runCtx, conn, m)
+				cancel()
+				mu.Lock()
+				delete(running, m.RunID)
+				mu.Unlock()
+			}(m)
+		case msgCancel:
+			mu.Lock()
+			if cancel := running[m.RunID]; cancel != nil {
+				cancel()
+			}
+			mu.Unlock()
+		}
+	}
+}
+
+func (a *Agent) execute(ctx context.Context, conn *wsConn, m message) {
+	reply := message{Type: msgExit, RunID: m.RunID}
+	if len(m.Args) == 0 {
+		reply.Error = "empty command"
+		_ = conn.writeJSON(reply)
+		return
+	}
+	log.Printf("agent running %q", strings.Join(m.Args, " "))
+
+	spec := &runner.Spec{
+		Args:    m.Args,
+		Env:     overlayEnv(os.Environ(), m.Env),
+		Dir:     m.Dir,
+		Stdout:  &outputWriter{conn: conn, runID: m.RunID, stream: "stdout"},
+		Stderr:  &outputWriter{conn: conn, runID: m.RunID, stream: "stderr"},
+		User:    m.User,
+		Group:   m.Group,
+		Sandbox: m.Sandbox,
+	}
+	executor := a.Executor
+	if executor == nil {
+		executor = runner.OSExecutor{}
+	}
+	if err := executor.Run(ctx, spec); err != nil {
+		reply.Error = err.Error()
+		var exitErr interface{ ExitCode() int }
+		if errors.As(err, &exitErr) {
+			reply.ExitCode = exitErr.ExitCode()
+		}
+	}
+	if err := conn.writeJSON(reply); err != nil {
+		log.Printf("WARN: agent failed to report exit of %q: %v", strings.Join(m.Args, " "), err)
+	}
+}
+
+// outputWriter sends one stream of a process to the server.
+type outputWriter struct {
+	conn   *wsConn
+	runID  string
+	stream string
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	if err := w.conn.writeJSON(message{Type: msgOutput, RunID: w.runID, Stream: w.stream, Data: p}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// overlayEnv returns base with the variables in env added or replaced.
+func overlayEnv(base []string, env map[string]string) []string {
+	out := make([]string, 0, len(base)+len(env))
+	for _, e := range base {
+		k, _, _ := strings.Cut(e, "=")
+		if _, ok := env[k]; !ok {
+			out = append(out, e)
+		}
+	}
+	for k, v := range env {
+		out = append(out, k+"="+v)
+	}
+	return out
+}
@@ -0,0 +1,297 @@
+package agent
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/placement"
+	"github.com/patrickspencer/cronbat/internal/runner"
+)
+
+// cancelGrace is how long a cancelled run waits for the agent to report
+// that the process stopped.
+const cancelGrace = 10 * time.Second
+
+// Info describes a connected agent.
+type Info struct {
+	ID          string
+	Hostname    string
+	Labels      map[string]string
+	Version     string
+	Addr        string
+	ConnectedAt time.Time
+	Running     int
+}
+
+// Hub accepts agent connections and runs processes on them.
+type Hub struct {
+	token string
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	nextID   uint64
+}
+
+// NewHub returns a Hub that accepts agents presenting token as a bearer
+// token.
+func NewHub(token string) *Hub {
+	return &Hub{token: token, sessions: make(map[string]*session)}
+}
+
+type session struct {
+	info Info
+	conn *wsConn
+
+	mu      sync.Mutex
+	runs    map[string]*remoteRun
+	nextRun uint64
+	closed  bool
+}
+
+type remoteRun struct {
+	stdout io.Writer
+	stderr io.Writer
+	done   chan message
+}
+
+// ServeHTTP upgrades an agent's connection and serves it until it drops.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if h.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		http.Error(w, "invalid agent token", http.StatusUnauthorized)
+		return
+	}
+	conn, err := upgrade(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer conn.close()
+	conn.readTimeout = 3 * pingInterval
+
+	data, err := conn.readMessage()
+	if err != nil {
+		log.Printf("WARN: agent at %s did not say hello: %v", r.RemoteAddr, err)
+		return
+	}
+	var hello message
+	if err := json.Unmarshal(data, &hello); err != nil || hello.Type != msgHello || hello.Hostname == "" {
+		log.Printf("WARN: agent at %s sent an invalid hello", r.RemoteAddr)
+		return
+	}
+
+	s := &session{
+		info: Info{
+			Hostname:    hello.Hostname,
+			Labels:      hello.Labels,
+			Version:     hello.Version,
+			Addr:        r.RemoteAddr,
+			ConnectedAt: time.Now().UTC(),
+		},
+		conn: conn,
+		runs: make(map[string]*remoteRun),
+	}
+	h.mu.Lock()
+	h.nextID++
+	s.info.ID = "agent-" + strconv.FormatUint(h.nextID, 10)
+	h.sessions[s.info.ID] = s
+	h.mu.Unlock()
+	log.Printf("agent %s connected: hostname=%s labels=%s addr=%s", s.info.ID, s.info.Hostname, placement.FormatSelector(s.info.Labels), r.RemoteAddr)
+
+	done := make(chan struct{})
+	go s.pingLoop(done)
+	err = s.readLoop()
+	close(done)
+
+	h.mu.Lock()
+	delete(h.sessions, s.info.ID)
+	h.mu.Unlock()
+	s.failAll("agent " + s.info.Hostname + " disconnected")
+	log.Printf("agent %s (%s) disconnected: %v", s.info.ID, s.info.Hostname, err)
+}
+
+func (s *session) pingLoop(done <-chan struct{}) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := s.conn.ping(); err != nil {
+				s.conn.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+func (s *session) readLoop() error {
+	for {
+		data, err := s.conn.readMessage()
+		if err != nil {
+			return err
+		}
+		var m message
+		if err := json.Unmarshal(data, &m); err != nil {
+			continue
+		}
+		switch m.Type {
+		case msgOutput:
+			s.mu.Lock()
+			rr := s.runs[m.RunID]
+			s.mu.Unlock()
+			if rr == nil {
+				continue
+			}
+			w := rr.stdout
+			if m.Stream == "stderr" {
+				w = rr.stderr
+			}
+			if w != nil {
+				_, _ = w.Write(m.Data)
+			}
+		case msgExit:
+			s.mu.Lock()
+			rr := s.runs[m.RunID]
+			delete(s.runs, m.RunID)
+			s.mu.Unlock()
+			if rr != nil {
+				rr.done <- m
+			}
+		}
+	}
+}
+
+// failAll ends every run still waiting on the session.
+func (s *session) failAll(reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for id, rr := range s.runs {
+		rr.done <- message{Type: msgExit, RunID: id, Error: reason}
+		delete(s.runs, id)
+	}
+}
+
+func (s *session) running() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.runs)
+}
+
+// List returns the connected agents sorted by hostname.
+func (h *Hub) List() []Info {
+	h.mu.Lock()
+	sessions := make([]*session, 0, len(h.sessions))
+	for _, s := range h.sessions {
+		sessions = append(sessions, s)
+	}
+	h.mu.Unlock()
+
+	out := make([]Info, 0, len(sessions))
+	for _, s := range sessions {
+		info := s.info
+		info.Running = s.running()
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Hostname != out[j].Hostname {
+			return out[i].Hostname < out[j].Hostname
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// Remote returns an executor that runs processes on the agent with the
+// given ID.
+func (h *Hub) Remote(id string) (*Remote, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.sessions[id]
+	if s == nil {
+		return nil, fmt.Errorf("agent %s is not connected", id)
+	}
+	return &Remote{s: s}, nil
+}
+
+// Close disconnects every agent.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, s := range h.sessions {
+		s.conn.close()
+	}
+}
+
+// Remote is a runner.Executor that runs processes on one agent.
+type Remote struct {
+	s *session
+	// Env is the job's own environment. It replaces the spec's, which
+	// holds the server's environment; the agent overlays it on its own.
+	Env map[string]string
+}
+
+// Info returns the agent the executor runs on.
+func (r *Remote) Info() Info { return r.s.info }
+
+// Run implements runner.Executor.
+func (r *Remote) Run(ctx context.Context, spec *runner.Spec) error {
+	s := r.s
+	rr := &remoteRun{stdout: spec.Stdout, stderr: spec.Stderr, done: make(chan message, 1)}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return fmt.Errorf("agent %s disconnected", s.info.Hostname)
+	}
+	s.nextRun++
+	id := strconv.FormatUint(s.nextRun, 10)
+	s.runs[id] = rr
+	s.mu.Unlock()
+
+	err := s.conn.writeJSON(message{
+		Type:    msgRun,
+		RunID:   id,
+		Args:    spec.Args,
+		Env:     r.Env,
+		Dir:     spec.Dir,
+		User:    spec.User,
+		Group:   spec.Group,
+		Sandbox: spec.Sandbox,
+	})
+	if err != nil {
+		s.forget(id)
+		return fmt.Errorf("agent %s: %w", s.info.Hostname, err)
+	}
+
+	var m message
+	select {
+	case m = <-rr.done:
+	case <-ctx.Done():
+		_ = s.conn.writeJSON(message{Type: msgCancel, RunID: id})
+		select {
+		case m = <-rr.done:
+		case <-time.After(cancelGrace):
+			s.forget(id)
+			return ctx.Err()
+		}
+	}
+	return m.err()
+}
+
+func (s *session) forget(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.runs, id)
+}
@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/runner"
+)
+
+// fakeExecutor writes its argv and the TEST_VAR variable to stdout and
+// exits with the code in its last argument.
+type fakeExecutor struct{}
+
+func (fakeExecutor) Run(ctx context.Context, spec *runner.Spec) error {
+	var testVar string
+	for _, e := range spec.Env {
+		if v, ok := strings.CutPrefix(e, "TEST_VAR="); ok {
+			testVar = v
+		}
+	}
+	spec.Stdout.Write([]byte(strings.Join(spec.Args, " ") + " " + testVar))
+	spec.Stderr.Write(bytes.Repeat([]byte("x"), 100000))
+	if code := spec.Args[len(spec.Args)-1]; code != "0" {
+		return &ExitError{Code: 3}
+	}
+	return nil
+}
+
+func startAgent(t *testing.T, token string) *Hub {
+	t.Helper()
+	hub := NewHub("secret")
+	srv := httptest.NewServer(hub)
+	t.Cleanup(srv.Close)
+	t.Cleanup(hub.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	t.Cleanup(func() { cancel(); <-done })
+	a := &Agent{
+		Server:   srv.URL,
+		Token:    token,
+		Hostname: "worker1",
+		Labels:   map[string]string{"role": "db"},
+		Executor: fakeExecutor{},
+	}
+	go func() {
+		defer close(done)
+		a.Run(ctx)
+	}()
+	return hub
+}
+
+func waitForAgents(t *testing.T, hub *Hub, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(hub.List()) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("want %d agents connected, have %d", n, len(hub.List()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRemoteRun(t *testing.T) {
+	hub := startAgent(t, "secret")
+	waitForAgents(t, hub, 1)
+
+	agents := hub.List()
+	if agents[0].Hostname != "worker1" || agents[0].Labels["role"] != "db" {
+		t.Fatalf("agent registered as %+v", agents[0])
+	}
+	remote, err := hub.Remote(agents[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote.Env = map[string]string{"TEST_VAR": "hello"}
+
+	var stdout, stderr bytes.Buffer
+	err = remote.Run(context.Background(), &runner.Spec{Args: []string{"job", "0"}, Stdout: &stdout, Stderr: &stderr})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := stdout.String(); got != "job 0 hello" {
+		t.Errorf("stdout = %q", got)
+	}
+	if stderr.Len() != 100000 {
+		t.Errorf("stderr has %d bytes, want 100000", stderr.Len())
+	}
+
+	err = remote.Run(context.Background(), &runner.Spec{Args: []string{"job", "3"}, Stdout: &stdout})
+	var exitErr interface{ ExitCode() int }
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Fatalf("Run error = %v, want exit code 3", err)
+	}
+}
+
+func TestHubRejectsWrongToken(t *testing.T) {
+	hub := startAgent(t, "wrong")
+	time.Sleep(200 * time.Millisecond)
+	if n := len(hub.List()); n != 0 {
+		t.Fatalf("%d agents connected with a wrong token", n)
+	}
+}
@@ -0,0 +1,81 @@
+// Package agent runs jobs on remote hosts. An agent keeps an outbound
+// WebSocket connection to the cronbat server, announces its hostname and
+// labels, and runs the processes the server sends it, streaming their
+// output back. On the server, a Hub tracks connected agents and hands out
+// runner.Executors bound to one of them; package placement decides which.
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/runner"
+)
+
+// ConnectPath is where agents open their connection.
+const ConnectPath = "/api/v1/agents/connect"
+
+// pingInterval is how often the server pings each agent. Either side
+// drops the connection after three intervals without a frame.
+const pingInterval = 30 * time.Second
+
+// Message types.
+const (
+	msgHello  = "hello"  // agent: hostname, labels, version
+	msgRun    = "run"    // server: start a process
+	msgCancel = "cancel" // server: stop a process
+	msgOutput = "output" // agent: a chunk of stdout or stderr
+	msgExit   = "exit"   // agent: a process finished
+)
+
+// message is the JSON envelope of every message; Type says which fields
+// are set.
+type message struct {
+	Type string `json:"type"`
+
+	Hostname string            `json:"hostname,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Version  string            `json:"version,omitempty"`
+
+	RunID   string                 `json:"run_id,omitempty"`
+	Args    []string               `json:"args,omitempty"`
+	Env     map[string]string      `json:"env,omitempty"`
+	Dir     string                 `json:"dir,omitempty"`
+	User    string                 `json:"user,omitempty"`
+	Group   string                 `json:"group,omitempty"`
+	Sandbox *runner.SandboxOptions `json:"sandbox,omitempty"`
+
+	Stream string `json:"stream,omitempty"`
+	Data   []byte `json:"data,omitempty"`
+
+	ExitCode int    `json:"exit_code,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// err turns an exit message into the error runner.Executor.Run returns.
+func (m *message) err() error {
+	if m.ExitCode != 0 {
+		return &ExitError{Code: m.ExitCode, Msg: m.Error}
+	}
+	if m.Error != "" {
+		return errors.New(m.Error)
+	}
+	return nil
+}
+
+// ExitError is a non-zero exit of a remote process.
+type ExitError struct {
+	Code int
+	Msg  string
+}
+
+func (e *ExitError) Error() string {
+	if e.Msg != "" {
+		return e.Msg
+	}
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode returns the process's exit code.
+func (e *ExitError) ExitCode() int { return e.Code }
@@ -0,0 +1,329 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The subset of RFC 6455 that agents need: text messages, ping/pong, and
+// close, with fragmented messages reassembled on read.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxMessageSize bounds one message; output is sent in much smaller chunks.
+const maxMessageSize = 16 << 20
+
+const (
+	handshakeTimeout = 10 * time.Second
+	writeTimeout     = 10 * time.Second
+)
+
+// wsConn is one end of a WebSocket connection. Writes may come from any
+// goroutine; reads must come from one.
+type wsConn struct {
+	conn   net.Conn
+	rd     *bufio.Reader
+	client bool // clients mask their frames
+	// readTimeout, if set, is how long a read waits for the next frame.
+	readTimeout time.Duration
+
+	wmu sync.Mutex
+}
+
+func wsAccept(key string) string {
+	sum := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// upgrade completes the server side of the opening handshake.
+func upgrade(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	if r.Method != http.MethodGet ||
+		!headerHasToken(r.Header, "Connection", "upgrade") ||
+		!headerHasToken(r.Header, "Upgrade", "websocket") {
+		return nil, errors.New("not a websocket upgrade request")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, errors.New("unsupported websocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, errors.New("missing Sec-WebSocket-Key")
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("connection cannot be upgraded")
+	}
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	// Clear the deadlines the HTTP server set for the request.
+	conn.SetDeadline(time.Time{})
+	resp := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + wsAccept(key) + "\r\n\r\n"
+	if _, err := conn.Write([]byte(resp)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, rd: brw.Reader}, nil
+}
+
+// dial opens a client connection to a ws://, wss://, http://, or https://
+// URL.
+func dial(ctx context.Context, rawURL string, header http.Header) (*wsConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	secure := false
+	switch u.Scheme {
+	case "ws", "http":
+		u.Scheme = "http"
+	case "wss", "https":
+		u.Scheme = "https"
+		secure = true
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		if secure {
+			addr = net.JoinHostPort(u.Hostname(), "443")
+		} else {
+			addr = net.JoinHostPort(u.Hostname(), "80")
+		}
+	}
+
+	d := net.Dialer{Timeout: handshakeTimeout}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	if secure {
+		tc := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err := tc.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tc
+	}
+
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	rd := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(rd, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		conn.Close()
+		return nil, fmt.Errorf("server refused connection: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != wsAccept(key) {
+		conn.Close()
+		return nil, errors.New("server sent an invalid Sec-WebSocket-Accept")
+	}
+	conn.SetDeadline(time.Time{})
+	return &wsConn{conn: conn, rd: rd, client: true}, nil
+}
+
+func (c *wsConn) writeFrame(op byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, 0x80|op)
+	var mask byte
+	if c.client {
+		mask = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, mask|byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, mask|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, mask|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	if c.client {
+		var key [4]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		frame = append(frame, key[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		for i := start; i < len(frame); i++ {
+			frame[i] ^= key[(i-start)%4]
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+func (c *wsConn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	if c.readTimeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	}
+	var hdr [2]byte
+	if _, err = io.ReadFull(c.rd, hdr[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = hdr[0]&0x80 != 0
+	op = hdr[0] & 0x0F
+	masked := hdr[1]&0x80 != 0
+	n := uint64(hdr[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.rd, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.rd, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > maxMessageSize {
+		return false, 0, nil, fmt.Errorf("websocket frame of %d bytes is too large", n)
+	}
+	var key [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.rd, key[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(c.rd, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= key[i%4]
+		}
+	}
+	return fin, op, payload, nil
+}
+
+// readMessage returns the next data message, answering pings on the way.
+// A close from the peer is returned as io.EOF.
+func (c *wsConn) readMessage() ([]byte, error) {
+	var msg []byte
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			_ = c.writeFrame(opClose, nil)
+			return nil, io.EOF
+		case opText, opBinary:
+			msg = payload
+		case opContinuation:
+			msg = append(msg, payload...)
+			if len(msg) > maxMessageSize {
+				return nil, errors.New("websocket message is too large")
+			}
+		default:
+			return nil, fmt.Errorf("unknown websocket opcode %d", op)
+		}
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+func (c *wsConn) writeJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.writeFrame(opText, data)
+}
+
+func (c *wsConn) ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// close sends a normal-closure frame and closes the connection.
+func (c *wsConn) close() error {
+	_ = c.writeFrame(opClose, []byte{0x03, 0xE8})
+	return c.conn.Close()
+}
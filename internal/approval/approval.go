@@ -0,0 +1,186 @@
+// Package approval holds manual runs of approval-gated jobs until a second
+// person approves them.
+package approval
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/store"
+)
+
+// maxRequests is how many requests are kept for queries; the oldest decided
+// or expired ones are dropped first.
+const maxRequests = 200
+
+// Request statuses.
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+	StatusExpired  = "expired"
+)
+
+var (
+	ErrNotFound   = errors.New("approval not found")
+	ErrNotPending = errors.New("approval is no longer pending")
+	// ErrSelfApproval is returned when the requester tries to decide their
+	// own request.
+	ErrSelfApproval = errors.New("approval must come from someone other than the requester")
+)
+
+// Request is a manual run waiting for approval.
+type Request struct {
+	ID      string
+	JobName string
+	// Requester identifies who asked for the run, for the self-approval
+	// check; RequestedBy describes them (see the API's triggered_by).
+	Requester   string
+	RequestedBy string
+	Status      string
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+	DecidedBy   string
+	DecidedAt   *time.Time
+}
+
+// RunFunc starts the run of an approved request.
+type RunFunc func(r *Request)
+
+// Manager tracks approval requests in memory. Pending requests do not
+// survive a restart.
+type Manager struct {
+	run RunFunc
+	now func() time.Time
+
+	mu       sync.Mutex
+	requests map[string]*Request
+	order    []string
+}
+
+// NewManager creates a Manager that starts approved runs with run.
+func NewManager(run RunFunc) *Manager {
+	return &Manager{
+		run:      run,
+		now:      time.Now,
+		requests: make(map[string]*Request),
+	}
+}
+
+// Create records a pending request that expires after ttl.
+func (m *Manager) Create(jobName, requester, requestedBy string, ttl time.Duration) *Request {
+	now := m.now().UTC()
+	r := &Request{
+		ID:          store.NewRunID(),
+		JobName:     jobName,
+		Requester:   requester,
+		RequestedBy: requestedBy,
+		Status:      StatusPending,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[r.ID] = r
+	m.order = append(m.order, r.ID)
+	m.pruneLocked()
+	cp := *r
+	return &cp
+}
+
+// Get returns a snapshot of a request, or nil if it is unknown.
+func (m *Manager) Get(id string) *Request {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.requests[id]
+	if !ok {
+		return nil
+	}
+	m.expireLocked(r)
+	cp := *r
+	return &cp
+}
+
+// List returns snapshots of requests with the given status (all if empty),
+// newest first.
+func (m *Manager) List(status string) []*Request {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []*Request
+	for _, id := range m.order {
+		r := m.requests[id]
+		m.expireLocked(r)
+		if status != "" && r.Status != status {
+			continue
+		}
+		cp := *r
+		out = append(out, &cp)
+	}
+	sort.SliceStable(out, func(i, k int) bool { return out[i].CreatedAt.After(out[k].CreatedAt) })
+	return out
+}
+
+// Decide approves or rejects a pending request on behalf of decider
+// (identity) and decidedBy (description). Approval starts the run.
+func (m *Manager) Decide(id string, approve bool, decider, decidedBy string) (*Request, error) {
+	m.mu.Lock()
+	r, ok := m.requests[id]
+	if !ok {
+		m.mu.Unlock()
+		return nil, ErrNotFound
+	}
+	m.expireLocked(r)
+	if r.Status != StatusPending {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrNotPending, r.Status)
+	}
+	if decider == r.Requester {
+		m.mu.Unlock()
+		return nil, ErrSelfApproval
+	}
+	now := m.now().UTC()
+	r.DecidedBy = decidedBy
+	r.DecidedAt = &now
+	r.Status = StatusRejected
+	if approve {
+		r.Status = StatusApproved
+	}
+	cp := *r
+	m.mu.Unlock()
+
+	if approve {
+		m.run(&cp)
+	}
+	return &cp, nil
+}
+
+func (m *Manager) expireLocked(r *Request) {
+	if r.Status == StatusPending && !m.now().Before(r.ExpiresAt) {
+		r.Status = StatusExpired
+	}
+}
+
+// pruneLocked drops the oldest requests that are no longer pending beyond
+// maxRequests.
+func (m *Manager) pruneLocked() {
+	excess := len(m.order) - maxRequests
+	if excess <= 0 {
+		return
+	}
+	kept := m.order[:0]
+	for _, id := range m.order {
+		r := m.requests[id]
+		m.expireLocked(r)
+		if excess > 0 && r.Status != StatusPending {
+			delete(m.requests, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	m.order = kept
+}
@@ -0,0 +1,40 @@
+package approval
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDecide(t *testing.T) {
+	t.Parallel()
+
+	var ran []string
+	m := NewManager(func(r *Request) { ran = append(ran, r.JobName) })
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	r := m.Create("fix-data", "key:alice", "key=alice", time.Hour)
+	if _, err := m.Decide(r.ID, true, "key:alice", "key=alice"); !errors.Is(err, ErrSelfApproval) {
+		t.Fatalf("expected self-approval error, got %v", err)
+	}
+	got, err := m.Decide(r.ID, true, "key:bob", "key=bob")
+	if err != nil || got.Status != StatusApproved || len(ran) != 1 {
+		t.Fatalf("approve: %+v, %v, ran=%v", got, err, ran)
+	}
+	if _, err := m.Decide(r.ID, false, "key:carol", "key=carol"); !errors.Is(err, ErrNotPending) {
+		t.Fatalf("expected not pending error, got %v", err)
+	}
+
+	late := m.Create("fix-data", "key:alice", "key=alice", time.Hour)
+	now = now.Add(2 * time.Hour)
+	if got := m.Get(late.ID); got.Status != StatusExpired {
+		t.Fatalf("expected expired, got %s", got.Status)
+	}
+	if _, err := m.Decide(late.ID, true, "key:bob", "key=bob"); !errors.Is(err, ErrNotPending) {
+		t.Fatalf("expected expired request to be refused, got %v", err)
+	}
+	if len(m.List(StatusPending)) != 0 || len(ran) != 1 {
+		t.Fatal("expired request must not run or be listed as pending")
+	}
+}
@@ -0,0 +1,291 @@
+// Package backfill runs a job once per time window over a past range, with
+// bounded parallelism, and resumes unfinished backfills after a restart.
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/store"
+)
+
+const (
+	// MaxWindows caps how many windows one backfill may have.
+	MaxWindows = 10000
+	// MaxParallelism caps how many windows of one backfill run at once.
+	MaxParallelism = 32
+)
+
+// Backfill and window statuses.
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+
+	WindowPending   = "pending"
+	WindowRunning   = "running"
+	WindowCancelled = "cancelled"
+)
+
+// Store persists backfills; *store.SQLiteStore implements it.
+type Store interface {
+	CreateBackfill(ctx context.Context, b *store.Backfill, windows []store.BackfillWindow) error
+	UpdateBackfillStatus(ctx context.Context, id, status string, finishedAt *time.Time) error
+	UpdateBackfillWindow(ctx context.Context, w store.BackfillWindow) error
+	GetBackfill(ctx context.Context, id string) (*store.Backfill, error)
+	ListBackfills(ctx context.Context, jobName, status string) ([]*store.Backfill, error)
+	ListBackfillWindows(ctx context.Context, backfillID string) ([]store.BackfillWindow, error)
+}
+
+// StartFunc submits one run of a job with extra environment variables and
+// calls done with the run ID and final status once it has finished.
+type StartFunc func(jobName string, env map[string]string, done func(runID, status string))
+
+// Manager schedules backfill windows onto runs.
+type Manager struct {
+	store Store
+	start StartFunc
+	// OnProgress, if set, is called after each window finishes and when a
+	// backfill reaches a final status (w is nil then). It is called with the
+	// manager's lock held and must not call back into the manager.
+	OnProgress func(b store.Backfill, w *store.BackfillWindow)
+
+	mu     sync.Mutex
+	active map[string]*activeBackfill
+}
+
+type activeBackfill struct {
+	b         store.Backfill
+	pending   []store.BackfillWindow
+	running   int
+	failed    int
+	cancelled bool
+}
+
+// NewManager creates a Manager that starts runs with start.
+func NewManager(s Store, start StartFunc) *Manager {
+	return &Manager{
+		store:  s,
+		start:  start,
+		active: make(map[string]*activeBackfill),
+	}
+}
+
+// Windows splits [from, to) into consecutive windows of the given length;
+// the last window ends at to.
+func Windows(from, to time.Time, interval time.Duration) ([]store.BackfillWindow, error) {
+	if interval <= 0 {
+		return nil, errors.New("invalid interval: must be positive")
+	}
+	if !from.Before(to) {
+		return nil, errors.New("invalid range: from must be before to")
+	}
+	if n := to.Sub(from) / interval; n >= MaxWindows {
+		return nil, fmt.Errorf("invalid range: more than %d windows", MaxWindows)
+	}
+
+	var windows []store.BackfillWindow
+	for start := from; start.Before(to); start = start.Add(interval) {
+		end := start.Add(interval)
+		if end.After(to) {
+			end = to
+		}
+		windows = append(windows, store.BackfillWindow{
+			Seq:    len(windows),
+			Start:  start.UTC(),
+			End:    end.UTC(),
+			Status: WindowPending,
+		})
+	}
+	return windows, nil
+}
+
+// Create stores a new backfill for jobName and starts its first windows.
+func (m *Manager) Create(ctx context.Context, jobName string, from, to time.Time, interval string, parallelism int) (*store.Backfill, error) {
+	d, err := config.ParseDays(strings.TrimSpace(interval))
+	if err != nil {
+		return nil, fmt.Errorf("invalid interval %q: %v", interval, err)
+	}
+	windows, err := Windows(from, to, d)
+	if err != nil {
+		return nil, err
+	}
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+	if parallelism > MaxParallelism {
+		parallelism = MaxParallelism
+	}
+
+	b := &store.Backfill{
+		ID:          store.NewRunID(),
+		JobName:     jobName,
+		From:        from.UTC(),
+		To:          to.UTC(),
+		Interval:    strings.TrimSpace(interval),
+		Parallelism: parallelism,
+		Status:      StatusRunning,
+		CreatedAt:   time.Now().UTC(),
+	}
+	for i := range windows {
+		windows[i].BackfillID = b.ID
+	}
+	if err := m.store.CreateBackfill(ctx, b, windows); err != nil {
+		return nil, err
+	}
+
+	m.launch(&activeBackfill{b: *b, pending: windows})
+	return b, nil
+}
+
+// Resume restarts every backfill left running by a previous process.
+// Windows that were running when it stopped are run again.
+func (m *Manager) Resume(ctx context.Context) error {
+	backfills, err := m.store.ListBackfills(ctx, "", StatusRunning)
+	if err != nil {
+		return err
+	}
+	for _, b := range backfills {
+		windows, err := m.store.ListBackfillWindows(ctx, b.ID)
+		if err != nil {
+			return err
+		}
+		ab := &activeBackfill{b: *b}
+		for _, w := range windows {
+			switch w.Status {
+			case WindowPending, WindowRunning:
+				w.Status = WindowPending
+				w.RunID = ""
+				ab.pending = append(ab.pending, w)
+			case "success", "warning":
+			default:
+				ab.failed++
+			}
+		}
+		log.Printf("resuming backfill %s for job %q: %d window(s) left", b.ID, b.JobName, len(ab.pending))
+		m.launch(ab)
+	}
+	return nil
+}
+
+// Cancel stops a running backfill. Windows already running finish; pending
+// windows are marked cancelled.
+func (m *Manager) Cancel(ctx context.Context, id string) error {
+	m.mu.Lock()
+	ab, ok := m.active[id]
+	if !ok {
+		m.mu.Unlock()
+		b, err := m.store.GetBackfill(ctx, id)
+		if err != nil {
+			return err
+		}
+		if b == nil {
+			return fmt.Errorf("backfill not found: %s", id)
+		}
+		return fmt.Errorf("backfill is already %s", b.Status)
+	}
+	ab.cancelled = true
+	pending := ab.pending
+	ab.pending = nil
+	m.mu.Unlock()
+
+	for _, w := range pending {
+		w.Status = WindowCancelled
+		if err := m.store.UpdateBackfillWindow(ctx, w); err != nil {
+			log.Printf("ERROR: backfill %s: failed to cancel window %d: %v", id, w.Seq, err)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.finishLocked(ab)
+	return nil
+}
+
+func (m *Manager) launch(ab *activeBackfill) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.active[ab.b.ID] = ab
+	m.dispatchLocked(ab)
+}
+
+// dispatchLocked starts pending windows up to the parallelism limit and
+// finishes the backfill when nothing is left.
+func (m *Manager) dispatchLocked(ab *activeBackfill) {
+	for len(ab.pending) > 0 && ab.running < ab.b.Parallelism && !ab.cancelled {
+		w := ab.pending[0]
+		ab.pending = ab.pending[1:]
+		ab.running++
+
+		w.Status = WindowRunning
+		if err := m.store.UpdateBackfillWindow(context.Background(), w); err != nil {
+			log.Printf("ERROR: backfill %s: failed to record window %d start: %v", ab.b.ID, w.Seq, err)
+		}
+		env := map[string]string{
+			"CRONBAT_BACKFILL_ID":  ab.b.ID,
+			"CRONBAT_WINDOW_START": w.Start.Format(time.RFC3339),
+			"CRONBAT_WINDOW_END":   w.End.Format(time.RFC3339),
+		}
+		// start may call back synchronously; run it outside the lock.
+		go m.start(ab.b.JobName, env, func(runID, status string) {
+			m.windowDone(ab, w, runID, status)
+		})
+	}
+	m.finishLocked(ab)
+}
+
+func (m *Manager) windowDone(ab *activeBackfill, w store.BackfillWindow, runID, status string) {
+	w.RunID = runID
+	w.Status = status
+	if err := m.store.UpdateBackfillWindow(context.Background(), w); err != nil {
+		log.Printf("ERROR: backfill %s: failed to record window %d result: %v", ab.b.ID, w.Seq, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.OnProgress != nil {
+		m.OnProgress(ab.b, &w)
+	}
+	ab.running--
+	if status != "success" && status != "warning" {
+		ab.failed++
+	}
+	m.dispatchLocked(ab)
+}
+
+// finishLocked records the final status once no window is pending or
+// running.
+func (m *Manager) finishLocked(ab *activeBackfill) {
+	if ab.running > 0 || len(ab.pending) > 0 {
+		return
+	}
+	if _, ok := m.active[ab.b.ID]; !ok {
+		return
+	}
+	delete(m.active, ab.b.ID)
+
+	status := StatusCompleted
+	switch {
+	case ab.cancelled:
+		status = StatusCancelled
+	case ab.failed > 0:
+		status = StatusFailed
+	}
+	now := time.Now().UTC()
+	ab.b.Status = status
+	ab.b.FinishedAt = &now
+	if err := m.store.UpdateBackfillStatus(context.Background(), ab.b.ID, status, &now); err != nil {
+		log.Printf("ERROR: backfill %s: failed to record status %s: %v", ab.b.ID, status, err)
+	}
+	log.Printf("backfill %s for job %q %s", ab.b.ID, ab.b.JobName, status)
+	if m.OnProgress != nil {
+		m.OnProgress(ab.b, nil)
+	}
+}
@@ -0,0 +1,32 @@
+package backfill
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindows(t *testing.T) {
+	t.Parallel()
+
+	d := 24 * time.Hour
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(60 * time.Hour)
+	windows, err := Windows(from, to, d)
+	if err != nil {
+		t.Fatalf("Windows: %v", err)
+	}
+	if len(windows) != 3 {
+		t.Fatalf("expected 3 windows, got %d", len(windows))
+	}
+	if !windows[1].Start.Equal(from.Add(24*time.Hour)) || !windows[2].End.Equal(to) {
+		t.Fatalf("unexpected window bounds: %+v", windows)
+	}
+
+	if _, err := Windows(to, from, d); err == nil {
+		t.Fatal("expected error for reversed range")
+	}
+	if _, err := Windows(from, from.Add(MaxWindows*time.Minute), time.Minute); err == nil {
+		t.Fatal("expected error for too many windows")
+	}
+}
@@ -0,0 +1,208 @@
+// Package batch triggers a group of jobs together, in parallel or one after
+// another, and tracks each job's outcome.
+package batch
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/store"
+)
+
+// maxBatches is how many batches are kept for status queries; the oldest
+// finished ones are dropped first.
+const maxBatches = 200
+
+// Batch and item statuses. Finished items take their run's status.
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+
+	ItemPending   = "pending"
+	ItemRunning   = "running"
+	ItemCancelled = "cancelled"
+)
+
+// Item is one job of a batch.
+type Item struct {
+	JobName string
+	Status  string
+	RunID   string
+}
+
+// Batch is a group of jobs triggered by one request.
+type Batch struct {
+	ID            string
+	Sequential    bool
+	StopOnFailure bool
+	Status        string
+	CreatedAt     time.Time
+	FinishedAt    *time.Time
+	Items         []Item
+
+	// outputs collects the outputs of a sequential batch's finished runs;
+	// each run gets those of the runs before it in its environment.
+	outputs map[string]string
+}
+
+// StartFunc submits one run of a job with env added to its environment,
+// and calls done with the run ID, final status, and the run's outputs once
+// it has finished.
+type StartFunc func(jobName string, env map[string]string, done func(runID, status string, outputs map[string]string))
+
+// Manager runs batches and keeps recent ones in memory.
+type Manager struct {
+	start StartFunc
+
+	mu      sync.Mutex
+	batches map[string]*Batch
+	order   []string
+}
+
+// NewManager creates a Manager that starts runs with start.
+func NewManager(start StartFunc) *Manager {
+	return &Manager{
+		start:   start,
+		batches: make(map[string]*Batch),
+	}
+}
+
+// Create starts a batch for jobNames and returns a snapshot of it. With
+// sequential set, each job starts after the previous one finishes and
+// receives the outputs of the runs before it; with stopOnFailure also set,
+// the remaining jobs are cancelled after the first run that does not
+// succeed.
+func (m *Manager) Create(jobNames []string, sequential, stopOnFailure bool) (*Batch, error) {
+	if len(jobNames) == 0 {
+		return nil, fmt.Errorf("at least one job is required")
+	}
+
+	b := &Batch{
+		ID:            store.NewRunID(),
+		Sequential:    sequential,
+		StopOnFailure: stopOnFailure && sequential,
+		Status:        StatusRunning,
+		CreatedAt:     time.Now().UTC(),
+		Items:         make([]Item, len(jobNames)),
+	}
+	for i, name := range jobNames {
+		b.Items[i] = Item{JobName: name, Status: ItemPending}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.batches[b.ID] = b
+	m.order = append(m.order, b.ID)
+	m.pruneLocked()
+
+	if sequential {
+		m.startLocked(b, 0)
+	} else {
+		for i := range b.Items {
+			m.startLocked(b, i)
+		}
+	}
+	return cloneBatch(b), nil
+}
+
+// Get returns a snapshot of a batch, or nil if it is unknown.
+func (m *Manager) Get(id string) *Batch {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.batches[id]
+	if !ok {
+		return nil
+	}
+	return cloneBatch(b)
+}
+
+func (m *Manager) startLocked(b *Batch, i int) {
+	b.Items[i].Status = ItemRunning
+	name := b.Items[i].JobName
+	var env map[string]string
+	if len(b.outputs) > 0 {
+		env = make(map[string]string, len(b.outputs))
+		for k, v := range b.outputs {
+			env[k] = v
+		}
+	}
+	// start may call back synchronously; run it outside the lock.
+	go m.start(name, env, func(runID, status string, outputs map[string]string) {
+		m.itemDone(b, i, runID, status, outputs)
+	})
+}
+
+func (m *Manager) itemDone(b *Batch, i int, runID, status string, outputs map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b.Items[i].RunID = runID
+	b.Items[i].Status = status
+	if b.Sequential && len(outputs) > 0 {
+		if b.outputs == nil {
+			b.outputs = make(map[string]string, len(outputs))
+		}
+		for k, v := range outputs {
+			b.outputs[k] = v
+		}
+	}
+
+	if b.Sequential && i+1 < len(b.Items) {
+		if status != "success" && status != "warning" && b.StopOnFailure {
+			for j := i + 1; j < len(b.Items); j++ {
+				b.Items[j].Status = ItemCancelled
+			}
+		} else {
+			m.startLocked(b, i+1)
+			return
+		}
+	}
+
+	failed := false
+	for _, it := range b.Items {
+		switch it.Status {
+		case ItemPending, ItemRunning:
+			return
+		case "success", "warning":
+		default:
+			failed = true
+		}
+	}
+	now := time.Now().UTC()
+	b.FinishedAt = &now
+	b.Status = StatusCompleted
+	if failed {
+		b.Status = StatusFailed
+	}
+}
+
+// pruneLocked drops the oldest finished batches beyond maxBatches.
+func (m *Manager) pruneLocked() {
+	excess := len(m.order) - maxBatches
+	if excess <= 0 {
+		return
+	}
+	kept := m.order[:0]
+	for _, id := range m.order {
+		if excess > 0 && m.batches[id].Status != StatusRunning {
+			delete(m.batches, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	m.order = kept
+}
+
+func cloneBatch(b *Batch) *Batch {
+	cp := *b
+	cp.outputs = nil
+	cp.Items = append([]Item(nil), b.Items...)
+	if b.FinishedAt != nil {
+		t := *b.FinishedAt
+		cp.FinishedAt = &t
+	}
+	return &cp
+}
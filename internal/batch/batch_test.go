@@ -0,0 +1,56 @@
+package batch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSequentialStopOnFailure(t *testing.T) {
+	t.Parallel()
+
+	var started []string
+	var envOfB map[string]string
+	m := NewManager(func(jobName string, env map[string]string, done func(runID, status string, outputs map[string]string)) {
+		started = append(started, jobName)
+		status := "success"
+		if jobName == "b" {
+			status = "failure"
+			envOfB = env
+		}
+		done("run-"+jobName, status, map[string]string{"FROM": jobName})
+	})
+
+	b, err := m.Create([]string{"a", "b", "c"}, true, true)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		got := m.Get(b.ID)
+		if got.Status != StatusRunning {
+			b = got
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("batch did not finish")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if b.Status != StatusFailed {
+		t.Fatalf("expected failed batch, got %s", b.Status)
+	}
+	want := []string{"success", "failure", ItemCancelled}
+	for i, it := range b.Items {
+		if it.Status != want[i] {
+			t.Fatalf("item %d (%s): expected %s, got %s", i, it.JobName, want[i], it.Status)
+		}
+	}
+	if len(started) != 2 {
+		t.Fatalf("expected 2 jobs started, got %v", started)
+	}
+	if envOfB["FROM"] != "a" {
+		t.Fatalf("expected b to get a's outputs, got %v", envOfB)
+	}
+}
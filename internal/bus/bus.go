@@ -0,0 +1,123 @@
+// Package bus connects cronbat to Redis pub/sub or NATS, speaking each
+// protocol directly over TCP without a client library.
+package bus
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// dialTimeout bounds connecting and each publish round trip.
+const dialTimeout = 5 * time.Second
+
+// Reconnect backoff for subscriptions.
+const (
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// Bus publishes messages to and subscribes to messages from a message bus.
+type Bus interface {
+	// Publish sends data to subject, reconnecting if the connection was
+	// lost.
+	Publish(ctx context.Context, subject string, data []byte) error
+	// Subscribe calls handle with every message on subject until ctx is
+	// done, reconnecting with backoff when the connection drops. Errors
+	// after the first successful subscription are reported to onError.
+	Subscribe(ctx context.Context, subject string, handle func(data []byte), onError func(error)) error
+	// Close closes the publishing connection.
+	Close() error
+}
+
+// TriggerMessage asks cronbat to run a job. TriggeredBy is recorded on the
+// run.
+type TriggerMessage struct {
+	Job         string `json:"job"`
+	TriggeredBy string `json:"triggered_by,omitempty"`
+	// CorrelationID is stored on the run so the sender can look it up
+	// with GET /api/v1/runs?correlation_id=.
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+// Open returns the Bus for a redis:// or nats:// URL. It does not connect
+// until the bus is first used.
+func Open(rawURL string) (Bus, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bus url: %w", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid bus url %q: missing host", rawURL)
+	}
+	switch u.Scheme {
+	case "redis":
+		b := &redisBus{addr: hostPort(u, "6379")}
+		if u.User != nil {
+			b.password, _ = u.User.Password()
+			b.username = u.User.Username()
+		}
+		return b, nil
+	case "nats":
+		b := &natsBus{addr: hostPort(u, "4222")}
+		if u.User != nil {
+			if pass, ok := u.User.Password(); ok {
+				b.user, b.pass = u.User.Username(), pass
+			} else {
+				b.token = u.User.Username()
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("invalid bus url %q: scheme must be redis or nats", rawURL)
+}
+
+func hostPort(u *url.URL, defaultPort string) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	return net.JoinHostPort(u.Hostname(), defaultPort)
+}
+
+// subscribeLoop runs subscribe until ctx is done, retrying with backoff.
+// subscribe returns after its connection fails; it reports whether it got
+// as far as subscribing, which resets the backoff.
+func subscribeLoop(ctx context.Context, subscribe func(ctx context.Context) (bool, error), onError func(error)) error {
+	backoff := minBackoff
+	for {
+		subscribed, err := subscribe(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if subscribed {
+			backoff = minBackoff
+		}
+		if err != nil && onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// closeOnDone closes conn when ctx is done, unblocking reads. The returned
+// func stops watching.
+func closeOnDone(ctx context.Context, conn net.Conn) func() {
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+	return func() { close(stop) }
+}
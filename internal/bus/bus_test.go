@@ -0,0 +1,186 @@
+package bus
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer is a tiny pub/sub server that routes published messages to
+// subscribers of the same subject.
+type fakeServer struct {
+	ln   net.Listener
+	mu   sync.Mutex
+	subs map[string][]func(subject string, data []byte)
+}
+
+func newFakeServer(t *testing.T, serve func(s *fakeServer, conn net.Conn)) *fakeServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{ln: ln, subs: make(map[string][]func(string, []byte))}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serve(s, conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) subscribe(subject string, deliver func(string, []byte)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subs[subject] = append(s.subs[subject], deliver)
+}
+
+func (s *fakeServer) publish(subject string, data []byte) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, deliver := range s.subs[subject] {
+		deliver(subject, data)
+	}
+	return len(s.subs[subject])
+}
+
+func serveRedis(s *fakeServer, conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	var wmu sync.Mutex
+	write := func(b []byte) {
+		wmu.Lock()
+		defer wmu.Unlock()
+		conn.Write(b)
+	}
+	for {
+		reply, err := readRedisReply(rd)
+		if err != nil {
+			return
+		}
+		args, _ := reply.([]any)
+		if len(args) == 0 {
+			return
+		}
+		cmd, _ := args[0].(string)
+		switch strings.ToUpper(cmd) {
+		case "PUBLISH":
+			data, _ := args[2].(string)
+			n := s.publish(args[1].(string), []byte(data))
+			write([]byte(":" + strconv.Itoa(n) + "\r\n"))
+		case "SUBSCRIBE":
+			ch := args[1].(string)
+			s.subscribe(ch, func(subject string, data []byte) {
+				var buf strings.Builder
+				writeRedisCommand(&buf, "message", subject, string(data))
+				write([]byte(buf.String()))
+			})
+			var buf strings.Builder
+			writeRedisCommand(&buf, "subscribe", ch, "1")
+			write([]byte(buf.String()))
+		default:
+			write([]byte("-ERR unknown command\r\n"))
+		}
+	}
+}
+
+func serveNATS(s *fakeServer, conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	var wmu sync.Mutex
+	write := func(b string) {
+		wmu.Lock()
+		defer wmu.Unlock()
+		conn.Write([]byte(b))
+	}
+	write("INFO {\"server_id\":\"fake\"}\r\n")
+	for {
+		line, err := readLine(rd)
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "PING":
+			write("PONG\r\n")
+		case "SUB":
+			sid := fields[2]
+			s.subscribe(fields[1], func(subject string, data []byte) {
+				write("MSG " + subject + " " + sid + " " + strconv.Itoa(len(data)) + "\r\n" + string(data) + "\r\n")
+			})
+		case "PUB":
+			n, _ := strconv.Atoi(fields[len(fields)-1])
+			buf := make([]byte, n+2)
+			if _, err := io.ReadFull(rd, buf); err != nil {
+				return
+			}
+			s.publish(fields[1], buf[:n])
+		}
+	}
+}
+
+func testRoundTrip(t *testing.T, scheme string, serve func(*fakeServer, net.Conn)) {
+	srv := newFakeServer(t, serve)
+	b, err := Open(scheme + "://" + srv.ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer b.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	got := make(chan string, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.Subscribe(ctx, "cronbat.trigger", func(data []byte) {
+			select {
+			case got <- string(data):
+			default:
+			}
+		}, nil)
+	}()
+
+	payload := `{"job":"backup"}`
+	deadline := time.After(5 * time.Second)
+	for {
+		if err := b.Publish(context.Background(), "cronbat.trigger", []byte(payload)); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+		select {
+		case msg := <-got:
+			if msg != payload {
+				t.Fatalf("got %q, want %q", msg, payload)
+			}
+			cancel()
+			<-done
+			return
+		case <-time.After(50 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("subscriber never received the message")
+		}
+	}
+}
+
+func TestRedisRoundTrip(t *testing.T) { testRoundTrip(t, "redis", serveRedis) }
+
+func TestNATSRoundTrip(t *testing.T) { testRoundTrip(t, "nats", serveNATS) }
+
+func TestOpenRejectsUnknownScheme(t *testing.T) {
+	if _, err := Open("amqp://localhost"); err == nil {
+		t.Fatal("expected an error for amqp://")
+	}
+}
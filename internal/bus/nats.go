@@ -0,0 +1,181 @@
+package bus
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// natsBus speaks the NATS client protocol: CONNECT, PUB, SUB, and
+// PING/PONG.
+type natsBus struct {
+	addr  string
+	user  string
+	pass  string
+	token string
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+func (b *natsBus) dial(ctx context.Context) (net.Conn, *bufio.Reader, error) {
+	d := net.Dialer{Timeout: dialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", b.addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+	rd := bufio.NewReader(conn)
+
+	line, err := readLine(rd)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return nil, nil, fmt.Errorf("nats: unexpected greeting %q", line)
+	}
+	opts, _ := json.Marshal(map[string]any{
+		"verbose":    false,
+		"pedantic":   false,
+		"name":       "cronbat",
+		"lang":       "go",
+		"version":    "1",
+		"user":       b.user,
+		"pass":       b.pass,
+		"auth_token": b.token,
+	})
+	if _, err := conn.Write([]byte("CONNECT " + string(opts) + "\r\nPING\r\n")); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if err := natsAwaitPong(conn, rd); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, rd, nil
+}
+
+// natsAwaitPong reads until the server answers a PING, so a successful
+// return means everything written before it was processed.
+func natsAwaitPong(w io.Writer, rd *bufio.Reader) error {
+	for {
+		line, err := readLine(rd)
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := w.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return natsError(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+// natsError is an -ERR from the server.
+type natsError string
+
+func (e natsError) Error() string { return "nats: " + string(e) }
+
+func (b *natsBus) Publish(ctx context.Context, subject string, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	msg := make([]byte, 0, len(subject)+len(data)+32)
+	msg = append(msg, "PUB "+subject+" "+strconv.Itoa(len(data))+"\r\n"...)
+	msg = append(msg, data...)
+	msg = append(msg, "\r\nPING\r\n"...)
+
+	// A pooled connection may have been closed by the server; retry once
+	// on a fresh one.
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if b.conn == nil {
+			if b.conn, b.rd, err = b.dial(ctx); err != nil {
+				b.conn = nil
+				return err
+			}
+		}
+		b.conn.SetDeadline(time.Now().Add(dialTimeout))
+		if _, err = b.conn.Write(msg); err == nil {
+			if err = natsAwaitPong(b.conn, b.rd); err == nil {
+				return nil
+			}
+		}
+		b.conn.Close()
+		b.conn = nil
+		var srvErr natsError
+		if errors.As(err, &srvErr) {
+			return err
+		}
+	}
+	return err
+}
+
+func (b *natsBus) Subscribe(ctx context.Context, subject string, handle func([]byte), onError func(error)) error {
+	return subscribeLoop(ctx, func(ctx context.Context) (bool, error) {
+		conn, rd, err := b.dial(ctx)
+		if err != nil {
+			return false, err
+		}
+		defer conn.Close()
+		defer closeOnDone(ctx, conn)()
+
+		if _, err := conn.Write([]byte("SUB " + subject + " 1\r\n")); err != nil {
+			return false, err
+		}
+		for {
+			line, err := readLine(rd)
+			if err != nil {
+				return true, err
+			}
+			switch {
+			case line == "PING":
+				if _, err := conn.Write([]byte("PONG\r\n")); err != nil {
+					return true, err
+				}
+			case strings.HasPrefix(line, "-ERR"):
+				return true, natsError(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+			case strings.HasPrefix(line, "MSG "):
+				// MSG <subject> <sid> [reply-to] <#bytes>
+				fields := strings.Fields(line)
+				n, err := strconv.Atoi(fields[len(fields)-1])
+				if err != nil || n < 0 {
+					return true, fmt.Errorf("nats: bad message header %q", line)
+				}
+				buf := make([]byte, n+2)
+				if _, err := io.ReadFull(rd, buf); err != nil {
+					return true, err
+				}
+				handle(buf[:n])
+			}
+		}
+	}, onError)
+}
+
+func (b *natsBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn == nil {
+		return nil
+	}
+	err := b.conn.Close()
+	b.conn = nil
+	return err
+}
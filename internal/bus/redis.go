@@ -0,0 +1,205 @@
+package bus
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// redisBus publishes with PUBLISH and subscribes with SUBSCRIBE using the
+// RESP protocol.
+type redisBus struct {
+	addr     string
+	username string
+	password string
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+func (b *redisBus) dial(ctx context.Context) (net.Conn, *bufio.Reader, error) {
+	d := net.Dialer{Timeout: dialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", b.addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	rd := bufio.NewReader(conn)
+	if b.password != "" {
+		args := []string{"AUTH", b.password}
+		if b.username != "" {
+			args = []string{"AUTH", b.username, b.password}
+		}
+		conn.SetDeadline(time.Now().Add(dialTimeout))
+		if _, err := redisCall(conn, rd, args...); err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("redis auth: %w", err)
+		}
+		conn.SetDeadline(time.Time{})
+	}
+	return conn, rd, nil
+}
+
+func (b *redisBus) Publish(ctx context.Context, subject string, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// A pooled connection may have been closed by the server; retry once
+	// on a fresh one.
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if b.conn == nil {
+			if b.conn, b.rd, err = b.dial(ctx); err != nil {
+				b.conn = nil
+				return err
+			}
+		}
+		b.conn.SetDeadline(time.Now().Add(dialTimeout))
+		if _, err = redisCall(b.conn, b.rd, "PUBLISH", subject, string(data)); err == nil {
+			return nil
+		}
+		var replyErr redisError
+		if errors.As(err, &replyErr) {
+			return err
+		}
+		b.conn.Close()
+		b.conn = nil
+	}
+	return err
+}
+
+func (b *redisBus) Subscribe(ctx context.Context, subject string, handle func([]byte), onError func(error)) error {
+	return subscribeLoop(ctx, func(ctx context.Context) (bool, error) {
+		conn, rd, err := b.dial(ctx)
+		if err != nil {
+			return false, err
+		}
+		defer conn.Close()
+		defer closeOnDone(ctx, conn)()
+
+		if err := writeRedisCommand(conn, "SUBSCRIBE", subject); err != nil {
+			return false, err
+		}
+		subscribed := false
+		for {
+			reply, err := readRedisReply(rd)
+			if err != nil {
+				return subscribed, err
+			}
+			msg, ok := reply.([]any)
+			if !ok || len(msg) < 3 {
+				continue
+			}
+			switch kind, _ := msg[0].(string); kind {
+			case "subscribe":
+				subscribed = true
+			case "message":
+				if data, ok := msg[2].(string); ok {
+					handle([]byte(data))
+				}
+			}
+		}
+	}, onError)
+}
+
+func (b *redisBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn == nil {
+		return nil
+	}
+	err := b.conn.Close()
+	b.conn = nil
+	return err
+}
+
+// redisError is an error reply from the server.
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+func redisCall(w io.Writer, rd *bufio.Reader, args ...string) (any, error) {
+	if err := writeRedisCommand(w, args...); err != nil {
+		return nil, err
+	}
+	return readRedisReply(rd)
+}
+
+func writeRedisCommand(w io.Writer, args ...string) error {
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, a := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(a))+"\r\n"...)
+		buf = append(buf, a...)
+		buf = append(buf, "\r\n"...)
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// readRedisReply reads one RESP reply: a string for simple and bulk
+// strings, an int64, nil, or a []any for arrays. Error replies are
+// returned as redisError.
+func readRedisReply(rd *bufio.Reader) (any, error) {
+	line, err := readLine(rd)
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad bulk length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad array length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		out := make([]any, n)
+		for i := range out {
+			if out[i], err = readRedisReply(rd); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
+
+// readLine reads a CRLF-terminated line without the line ending.
+func readLine(rd *bufio.Reader) (string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) >= 2 && line[len(line)-2] == '\r' {
+		return line[:len(line)-2], nil
+	}
+	return line[:len(line)-1], nil
+}
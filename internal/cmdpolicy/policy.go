@@ -0,0 +1,163 @@
+// Package cmdpolicy restricts the commands that jobs created or edited
+// through the API may run. Without a policy, anyone who can reach the API
+// can run arbitrary commands on the host.
+package cmdpolicy
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+)
+
+// shellMetacharacters chain, substitute, or redirect commands, which would
+// let a command escape the allowlist.
+const shellMetacharacters = ";&|`$()<>\n\\"
+
+// Policy checks job commands against a command_policy block.
+type Policy struct {
+	allow       []string
+	deny        []*regexp.Regexp
+	denySources []string
+	forbidMeta  bool
+}
+
+// New compiles a command_policy block. It returns nil when the block
+// restricts nothing.
+func New(cfg config.CommandPolicyConfig) (*Policy, error) {
+	if len(cfg.Allow) == 0 && len(cfg.Deny) == 0 && !cfg.ForbidShellMetacharacters {
+		return nil, nil
+	}
+	p := &Policy{forbidMeta: cfg.ForbidShellMetacharacters}
+	for _, a := range cfg.Allow {
+		a = strings.TrimSpace(a)
+		if a == "" {
+			return nil, fmt.Errorf("allow: empty entry")
+		}
+		p.allow = append(p.allow, a)
+	}
+	for _, d := range cfg.Deny {
+		re, err := regexp.Compile(d)
+		if err != nil {
+			return nil, fmt.Errorf("deny %q: %w", d, err)
+		}
+		p.deny = append(p.deny, re)
+		p.denySources = append(p.denySources, d)
+	}
+	return p, nil
+}
+
+// Check returns an error naming the rule a command breaks. A nil Policy
+// allows every command.
+func (p *Policy) Check(command string) error {
+	if p == nil {
+		return nil
+	}
+	command = strings.TrimSpace(command)
+	for i, re := range p.deny {
+		if re.MatchString(command) {
+			return fmt.Errorf("command_policy: command matches deny pattern %q", p.denySources[i])
+		}
+	}
+	if p.forbidMeta {
+		if i := strings.IndexAny(command, shellMetacharacters); i >= 0 {
+			return fmt.Errorf("command_policy: command contains shell metacharacter %q", command[i])
+		}
+	}
+	if len(p.allow) > 0 {
+		fields := strings.Fields(command)
+		if len(fields) == 0 || !p.allowed(fields[0]) {
+			return fmt.Errorf("command_policy: %q is not an allowed program", firstWord(fields))
+		}
+	}
+	return nil
+}
+
+// CheckJob applies the policy to everything in j that decides what runs
+// and how: the command and pre_check, plus the shell, environment, and
+// user that could otherwise carry an arbitrary program past Check. A nil
+// Policy allows every job.
+func (p *Policy) CheckJob(j *config.Job) error {
+	if p == nil {
+		return nil
+	}
+	if err := p.Check(j.Command); err != nil {
+		return err
+	}
+	// pre_check runs as the job too.
+	if j.PreCheck != "" {
+		if err := p.Check(j.PreCheck); err != nil {
+			return fmt.Errorf("pre_check: %w", err)
+		}
+	}
+	// The rules above read the command as sh syntax; an interpreter such
+	// as "python3 -c" would run it as something else entirely.
+	if shell := strings.TrimSpace(j.Shell); shell != "" {
+		if fields := strings.Fields(shell); len(fields) != 1 || !posixShells[path.Base(fields[0])] {
+			return fmt.Errorf("command_policy: shell %q is not a plain sh-compatible shell", shell)
+		}
+	}
+	for name := range j.Env {
+		if startupEnv(name) {
+			return fmt.Errorf("command_policy: env %s is not allowed", name)
+		}
+	}
+	for _, id := range []string{strings.TrimSpace(j.User), strings.TrimSpace(j.Group)} {
+		if id == "root" || id == "0" {
+			return fmt.Errorf("command_policy: jobs may not run as %s", id)
+		}
+	}
+	return nil
+}
+
+// posixShells are the shells whose syntax the metacharacter rule covers.
+var posixShells = map[string]bool{
+	"sh":   true,
+	"bash": true,
+	"dash": true,
+	"ksh":  true,
+	"zsh":  true,
+}
+
+// startupEnv reports whether an environment variable makes the shell or
+// the dynamic loader run code of its own before the command starts.
+func startupEnv(name string) bool {
+	switch name {
+	case "BASH_ENV", "ENV", "ZDOTDIR", "SHELLOPTS", "BASHOPTS", "PS4", "PROMPT_COMMAND", "IFS":
+		return true
+	}
+	for _, prefix := range []string{"LD_", "DYLD_", "BASH_FUNC_"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed reports whether a program matches an allow entry: a directory
+// ending in "/" allows the programs under it, any other entry one program
+// by exact name or path.
+func (p *Policy) allowed(program string) bool {
+	for _, a := range p.allow {
+		if strings.HasSuffix(a, "/") {
+			// Reject "/usr/local/bin/../../bin/sh" and similar escapes.
+			if strings.HasPrefix(program, a) && path.Clean(program) == program {
+				return true
+			}
+			continue
+		}
+		if program == a {
+			return true
+		}
+	}
+	return false
+}
+
+func firstWord(fields []string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
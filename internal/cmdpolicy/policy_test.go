@@ -0,0 +1,80 @@
+package cmdpolicy
+
+import (
+	"testing"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+)
+
+func TestCheck(t *testing.T) {
+	p, err := New(config.CommandPolicyConfig{
+		Allow:                     []string{"/opt/jobs/", "backup"},
+		Deny:                      []string{`rm\s+-rf\s+/`},
+		ForbidShellMetacharacters: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for command, ok := range map[string]bool{
+		"backup --full":             true,
+		"/opt/jobs/report.sh daily": true,
+		"/opt/jobs/../../bin/sh":    false,
+		"/opt/jobsx/report.sh":      false,
+		"curl http://example.com":   false,
+		"backup; curl evil.example": false,
+		"backup $(cat /etc/passwd)": false,
+		"/opt/jobs/clean rm -rf /":  false,
+		"backup > /etc/cron.d/evil": false,
+		"":                          false,
+	} {
+		if err := p.Check(command); (err == nil) != ok {
+			t.Errorf("Check(%q) = %v, want allowed=%v", command, err, ok)
+		}
+	}
+
+	if p, err := New(config.CommandPolicyConfig{}); err != nil || p != nil {
+		t.Fatalf("empty policy = %v, %v; want nil", p, err)
+	}
+	var none *Policy
+	if err := none.Check("rm -rf /"); err != nil {
+		t.Fatalf("nil policy rejected a command: %v", err)
+	}
+	if _, err := New(config.CommandPolicyConfig{Deny: []string{"("}}); err == nil {
+		t.Fatal("invalid deny pattern accepted")
+	}
+}
+
+func TestCheckJob(t *testing.T) {
+	p, err := New(config.CommandPolicyConfig{Allow: []string{"/opt/jobs/"}, ForbidShellMetacharacters: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := config.Job{Command: "/opt/jobs/report.sh", Shell: "/bin/bash", User: "nobody", Env: map[string]string{"MODE": "daily"}}
+	if err := p.CheckJob(&ok); err != nil {
+		t.Fatalf("CheckJob(allowed job) = %v", err)
+	}
+
+	for name, j := range map[string]config.Job{
+		"command":           {Command: "curl http://example.com"},
+		"pre_check":         {Command: "/opt/jobs/report.sh", PreCheck: "curl http://example.com"},
+		"interpreter shell": {Command: "/opt/jobs/x", Shell: "python3 -c"},
+		"non-posix shell":   {Command: "/opt/jobs/x", Shell: "/usr/bin/python3"},
+		"BASH_ENV":          {Command: "/opt/jobs/x", Env: map[string]string{"BASH_ENV": "/tmp/evil"}},
+		"ENV":               {Command: "/opt/jobs/x", Env: map[string]string{"ENV": "/tmp/evil"}},
+		"LD_PRELOAD":        {Command: "/opt/jobs/x", Env: map[string]string{"LD_PRELOAD": "/tmp/evil.so"}},
+		"LD_LIBRARY_PATH":   {Command: "/opt/jobs/x", Env: map[string]string{"LD_LIBRARY_PATH": "/tmp"}},
+		"exported function": {Command: "/opt/jobs/x", Env: map[string]string{"BASH_FUNC_x%%": "() { id; }"}},
+		"root user":         {Command: "/opt/jobs/x", User: "root"},
+		"uid 0":             {Command: "/opt/jobs/x", User: "0"},
+		"root group":        {Command: "/opt/jobs/x", Group: "root"},
+	} {
+		if err := p.CheckJob(&j); err == nil {
+			t.Errorf("%s: CheckJob accepted %+v", name, j)
+		}
+	}
+
+	var none *Policy
+	if err := none.CheckJob(&config.Job{Command: "rm -rf /", User: "root", Env: map[string]string{"LD_PRELOAD": "x"}}); err != nil {
+		t.Fatalf("nil policy rejected a job: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic writes data to path so that readers, and the file after a
+// crash, see either the old or the new contents, never a partial write. The
+// data goes to a temp file in the same directory, which is fsynced and
+// renamed over path; the directory is then fsynced so the rename is durable.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	// The temp name does not end in .yaml, so LoadJobs never picks it up.
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	cleanup := func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		cleanup()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		cleanup()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		cleanup()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return syncDir(dir)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	// Some platforms and filesystems do not support syncing directories;
+	// the rename has already happened, so that is not worth failing over.
+	_ = d.Sync()
+	return nil
+}
+
+// JobFileChange is one step of SaveJobs: Job is written to Path, or the
+// file is removed when Job is nil.
+type JobFileChange struct {
+	Path string
+	Job  *Job
+}
+
+// SaveJobs applies changes in order as one unit. If a step fails, the files
+// already changed are put back as they were and the error is returned.
+// On success it returns a function that undoes all of the changes.
+func SaveJobs(changes []JobFileChange) (undo func() error, err error) {
+	type saved struct {
+		path   string
+		data   []byte
+		perm   os.FileMode
+		exists bool
+	}
+	var done []saved
+	undo = func() error {
+		var firstErr error
+		for i := len(done) - 1; i >= 0; i-- {
+			s := done[i]
+			var err error
+			if s.exists {
+				err = writeFileAtomic(s.path, s.data, s.perm)
+			} else if err = os.Remove(s.path); errors.Is(err, os.ErrNotExist) {
+				err = nil
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+
+	for _, c := range changes {
+		s := saved{path: c.Path, perm: 0644}
+		if info, err := os.Stat(c.Path); err == nil {
+			if s.data, err = os.ReadFile(c.Path); err != nil {
+				_ = undo()
+				return nil, err
+			}
+			s.perm, s.exists = info.Mode().Perm(), true
+		} else if !errors.Is(err, os.ErrNotExist) {
+			_ = undo()
+			return nil, err
+		}
+
+		if c.Job != nil {
+			err = SaveJob(c.Path, c.Job)
+		} else if err = os.Remove(c.Path); errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+		// A failed atomic write leaves the file untouched, but restore it
+		// anyway in case the step got partway.
+		done = append(done, s)
+		if err != nil {
+			if undoErr := undo(); undoErr != nil {
+				return nil, fmt.Errorf("%w (rollback failed: %v)", err, undoErr)
+			}
+			return nil, err
+		}
+	}
+	return undo, nil
+}
@@ -1,9 +1,13 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -23,6 +27,25 @@ type RunLogConfig struct {
 	RetentionDays     int    `yaml:"retention_days"`
 	MaxTotalMB        int64  `yaml:"max_total_mb"`
 	CleanupInterval   string `yaml:"cleanup_interval"`
+	// Archive moves old log files to object storage.
+	Archive RunLogArchiveConfig `yaml:"archive"`
+}
+
+// RunLogArchiveConfig uploads run log files older than AfterDays to an
+// S3-compatible bucket and leaves a small stub file behind. Archived logs
+// are fetched back when read. Empty credentials fall back to the
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN variables.
+type RunLogArchiveConfig struct {
+	Enabled         bool   `yaml:"enabled"`
+	AfterDays       int    `yaml:"after_days"`
+	Endpoint        string `yaml:"endpoint"`
+	Region          string `yaml:"region"`
+	Bucket          string `yaml:"bucket"`
+	Prefix          string `yaml:"prefix"`
+	PathStyle       bool   `yaml:"path_style"`
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+	SessionToken    string `yaml:"session_token"`
 }
 
 // IsEnabled returns whether persistent run log files are enabled.
@@ -34,6 +57,91 @@ func (c RunLogConfig) IsEnabled() bool {
 	return *c.Enabled
 }
 
+// LoadGuardConfig holds host pressure thresholds checked before a run starts.
+// Zero thresholds are not checked. It is used both globally and per job; job
+// values override global ones field by field.
+type LoadGuardConfig struct {
+	MaxLoad1        float64 `yaml:"max_load1,omitempty" json:"max_load1,omitempty"`
+	MinFreeMemoryMB int64   `yaml:"min_free_memory_mb,omitempty" json:"min_free_memory_mb,omitempty"`
+	MinFreeDiskMB   int64   `yaml:"min_free_disk_mb,omitempty" json:"min_free_disk_mb,omitempty"`
+	DiskPath        string  `yaml:"disk_path,omitempty" json:"disk_path,omitempty"`
+	// Action is "defer" (retry after a backoff) or "skip". Defaults to "defer".
+	Action     string `yaml:"action,omitempty" json:"action,omitempty"`
+	RetryAfter string `yaml:"retry_after,omitempty" json:"retry_after,omitempty"`
+	MaxRetries int    `yaml:"max_retries,omitempty" json:"max_retries,omitempty"`
+}
+
+// Active reports whether any threshold is configured.
+func (g LoadGuardConfig) Active() bool {
+	return g.MaxLoad1 > 0 || g.MinFreeMemoryMB > 0 || g.MinFreeDiskMB > 0
+}
+
+// Merge returns g with every non-zero field of override applied on top.
+func (g LoadGuardConfig) Merge(override *LoadGuardConfig) LoadGuardConfig {
+	if override == nil {
+		return g
+	}
+	if override.MaxLoad1 != 0 {
+		g.MaxLoad1 = override.MaxLoad1
+	}
+	if override.MinFreeMemoryMB != 0 {
+		g.MinFreeMemoryMB = override.MinFreeMemoryMB
+	}
+	if override.MinFreeDiskMB != 0 {
+		g.MinFreeDiskMB = override.MinFreeDiskMB
+	}
+	if override.DiskPath != "" {
+		g.DiskPath = override.DiskPath
+	}
+	if override.Action != "" {
+		g.Action = override.Action
+	}
+	if override.RetryAfter != "" {
+		g.RetryAfter = override.RetryAfter
+	}
+	if override.MaxRetries != 0 {
+		g.MaxRetries = override.MaxRetries
+	}
+	return g
+}
+
+// Validate checks the action, retry delay, and thresholds. A nil config is
+// valid.
+func (g *LoadGuardConfig) Validate() error {
+	if g == nil {
+		return nil
+	}
+	switch g.Action {
+	case "", "defer", "skip":
+	default:
+		return fmt.Errorf("invalid action %q: want defer or skip", g.Action)
+	}
+	if g.RetryAfter != "" {
+		d, err := time.ParseDuration(g.RetryAfter)
+		if err != nil {
+			return fmt.Errorf("retry_after: %w", err)
+		}
+		if d <= 0 {
+			return errors.New("retry_after must be positive")
+		}
+	}
+	if g.MaxLoad1 < 0 || g.MinFreeMemoryMB < 0 || g.MinFreeDiskMB < 0 || g.MaxRetries < 0 {
+		return errors.New("values must not be negative")
+	}
+	return nil
+}
+
+// HTTPConfig holds HTTP server timeouts as Go duration strings.
+// Streaming endpoints (SSE) are exempt from the read and write timeouts.
+type HTTPConfig struct {
+	ReadHeaderTimeout string `yaml:"read_header_timeout"`
+	ReadTimeout       string `yaml:"read_timeout"`
+	WriteTimeout      string `yaml:"write_timeout"`
+	IdleTimeout       string `yaml:"idle_timeout"`
+	// ShutdownTimeout bounds how long shutdown waits for in-flight requests.
+	ShutdownTimeout string `yaml:"shutdown_timeout"`
+}
+
 // Config is the top-level daemon configuration parsed from cronbat.yaml.
 type Config struct {
 	Listen   string         `yaml:"listen"`
@@ -42,6 +150,438 @@ type Config struct {
 	LogLevel string         `yaml:"log_level"`
 	Plugins  []PluginConfig `yaml:"plugins"`
 	RunLogs  RunLogConfig   `yaml:"run_logs"`
+	// MaxConcurrentRuns caps how many runs execute at once; waiting runs
+	// are dispatched by job priority. Zero means unlimited.
+	MaxConcurrentRuns int `yaml:"max_concurrent_runs"`
+	// LoadGuard defers or skips runs while the host is under pressure.
+	LoadGuard LoadGuardConfig `yaml:"load_guard"`
+	HTTP      HTTPConfig      `yaml:"http"`
+	// QuarantineInvalidJobs moves job files that fail to load into
+	// jobs_dir/quarantine/ at startup instead of leaving them in place.
+	QuarantineInvalidJobs bool `yaml:"quarantine_invalid_jobs"`
+	// Defaults apply to jobs that do not set the field themselves.
+	Defaults JobDefaultsConfig `yaml:"defaults"`
+	Store    StoreConfig       `yaml:"store"`
+	// APIKeys name API callers. A request that sends one of these keys is
+	// attributed to its name in run and audit records.
+	APIKeys []APIKeyConfig `yaml:"api_keys"`
+	// Bus publishes run events to, and takes trigger messages from, Redis
+	// pub/sub or NATS.
+	Bus BusConfig `yaml:"bus"`
+	// Agents run jobs with runs_on on remote hosts.
+	Agents AgentsConfig `yaml:"agents"`
+	// Host names this daemon and labels it for runs_on placement.
+	Host HostConfig `yaml:"host"`
+	// EventWebhooks receive every realtime event as a JSON POST.
+	EventWebhooks []EventWebhookConfig `yaml:"event_webhooks"`
+	// CommandPolicy restricts the commands of jobs created, edited, or
+	// imported through the API.
+	CommandPolicy CommandPolicyConfig `yaml:"command_policy"`
+	// Watchdog configures "cronbat watchdog --config".
+	Watchdog WatchdogConfig `yaml:"watchdog"`
+	// Tracing exports each run as OpenTelemetry spans.
+	Tracing TracingConfig `yaml:"tracing"`
+	// LogShipping forwards finished runs' logs to Grafana Loki or a remote
+	// syslog server.
+	LogShipping LogShippingConfig `yaml:"log_shipping"`
+	// UpdateCheck looks for newer cronbat releases once a day.
+	UpdateCheck UpdateCheckConfig `yaml:"update_check"`
+	// Lint holds house rules job definitions are checked against.
+	Lint LintConfig `yaml:"lint"`
+	// Secrets holds the key that decrypts "enc:v1:" job env values.
+	Secrets SecretsConfig `yaml:"secrets"`
+	// Auth requires an API key or a UI session on API requests.
+	Auth AuthConfig `yaml:"auth"`
+}
+
+// AuthConfig turns on authentication of the API. Programmatic clients send
+// one of api_keys as a bearer token; the UI signs in with one and gets a
+// session cookie.
+type AuthConfig struct {
+	// Required rejects API requests without a valid key or session.
+	// Health and readiness probes stay open.
+	Required bool `yaml:"required"`
+	// SessionTTL is how long a UI session lasts. Default 12h.
+	SessionTTL string `yaml:"session_ttl"`
+	// SecureCookie marks the session cookie Secure; set it when the UI is
+	// served over HTTPS.
+	SecureCookie bool `yaml:"secure_cookie"`
+	// OIDC signs UI users in with an OpenID Connect provider.
+	OIDC OIDCConfig `yaml:"oidc"`
+}
+
+// OIDCConfig configures single sign-on with an OpenID Connect provider
+// such as Google, Okta, or Keycloak.
+type OIDCConfig struct {
+	// Issuer is the provider's issuer URL; its
+	// /.well-known/openid-configuration is read at first sign-in. Empty
+	// disables single sign-on.
+	Issuer   string `yaml:"issuer"`
+	ClientID string `yaml:"client_id"`
+	// ClientSecret may be an "enc:v1:" value (see cronbat encrypt).
+	ClientSecret string `yaml:"client_secret"`
+	// RedirectURL is this daemon's callback as registered with the
+	// provider, e.g. https://cronbat.example.com/api/v1/auth/oidc/callback.
+	RedirectURL string `yaml:"redirect_url"`
+	// Scopes requested. Default openid, email, profile.
+	Scopes []string `yaml:"scopes"`
+	// GroupsClaim is the ID token claim listing the user's groups.
+	// Default "groups".
+	GroupsClaim string `yaml:"groups_claim"`
+	// Roles maps provider groups to cronbat roles (admin, operator, or
+	// viewer); a user in several groups gets the strongest role.
+	Roles map[string]string `yaml:"roles"`
+	// DefaultRole is given to users in none of the Roles groups. Empty
+	// refuses them.
+	DefaultRole string `yaml:"default_role"`
+}
+
+// IsEnabled reports whether single sign-on is configured.
+func (o OIDCConfig) IsEnabled() bool {
+	return o.Issuer != ""
+}
+
+// SecretsConfig locates the master key for encrypted job env values: a
+// base64-encoded 32-byte key given inline or read from KeyFile. Without
+// either, the CRONBAT_SECRET_KEY environment variable is used.
+type SecretsConfig struct {
+	Key     string `yaml:"key"`
+	KeyFile string `yaml:"key_file"`
+}
+
+// LintConfig configures the lint rules checked when jobs are loaded,
+// created, updated, or imported. Each rule is off unless set.
+type LintConfig struct {
+	// MaxTimeout caps a job's effective timeout (Max); no timeout at all
+	// exceeds it. Service jobs are exempt.
+	MaxTimeout *LintRuleConfig `yaml:"max_timeout"`
+	// RequiredTags requires each of Tags, or any tag when Tags is empty.
+	RequiredTags *LintRuleConfig `yaml:"required_tags"`
+	// RequiredMetadata requires non-empty metadata for each of Keys, e.g.
+	// owner.
+	RequiredMetadata *LintRuleConfig `yaml:"required_metadata"`
+	// ForbiddenSchedules rejects Schedules, compared as cron expressions
+	// so "every minute" matches "* * * * *".
+	ForbiddenSchedules *LintRuleConfig `yaml:"forbidden_schedules"`
+	// NamePattern requires job names to match the regular expression
+	// Pattern.
+	NamePattern *LintRuleConfig `yaml:"name_pattern"`
+}
+
+// LintRuleConfig is one lint rule. Which of its settings apply depends on
+// the rule.
+type LintRuleConfig struct {
+	// Level is "warning" (default), reported but allowed, or "error",
+	// which rejects the job.
+	Level     string   `yaml:"level"`
+	Max       string   `yaml:"max"`
+	Tags      []string `yaml:"tags"`
+	Keys      []string `yaml:"keys"`
+	Schedules []string `yaml:"schedules"`
+	Pattern   string   `yaml:"pattern"`
+}
+
+// TracingConfig configures OpenTelemetry span export over OTLP/HTTP.
+type TracingConfig struct {
+	// Endpoint is the OTLP/HTTP collector URL, e.g.
+	// http://localhost:4318; spans are posted to its /v1/traces. Empty
+	// disables tracing.
+	Endpoint string            `yaml:"endpoint"`
+	Headers  map[string]string `yaml:"headers"`
+	// ServiceName is the service.name resource attribute. Default
+	// "cronbat".
+	ServiceName string `yaml:"service_name"`
+}
+
+// IsEnabled reports whether spans are exported.
+func (t TracingConfig) IsEnabled() bool {
+	return t.Endpoint != ""
+}
+
+// LogShippingConfig forwards the logs of finished runs to log
+// aggregation. Loki and syslog may both be set.
+type LogShippingConfig struct {
+	// Loki is pushed to at its /loki/api/v1/push.
+	Loki LokiConfig `yaml:"loki"`
+	// Syslog is a remote syslog server that takes RFC 5424 messages.
+	Syslog RemoteSyslogConfig `yaml:"syslog"`
+	// Labels are added to every shipped stream, next to job, run_id,
+	// status, and stream.
+	Labels map[string]string `yaml:"labels"`
+	// MaxBytes caps how much of each of a run's streams is shipped.
+	// Default 1 MiB.
+	MaxBytes int64 `yaml:"max_bytes"`
+}
+
+// LokiConfig locates a Grafana Loki server.
+type LokiConfig struct {
+	// URL is Loki's base URL, e.g. http://loki:3100. Empty disables Loki.
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+// RemoteSyslogConfig locates a remote syslog server.
+type RemoteSyslogConfig struct {
+	// Address is "udp://host:port" or "tcp://host:port". Empty disables
+	// syslog.
+	Address string `yaml:"address"`
+}
+
+// IsEnabled reports whether run logs are shipped anywhere.
+func (l LogShippingConfig) IsEnabled() bool {
+	return l.Loki.URL != "" || l.Syslog.Address != ""
+}
+
+// UpdateCheckConfig configures the daily check for newer releases.
+type UpdateCheckConfig struct {
+	// Enabled turns the check on. It is off by default, since it calls
+	// out to GitHub.
+	Enabled bool `yaml:"enabled"`
+	// Repo is the GitHub repository whose latest release is compared with
+	// the running version. Default "patrickspencer/cronbat".
+	Repo string `yaml:"repo"`
+}
+
+// WatchdogConfig configures the watchdog that health-checks and restarts
+// the daemon.
+type WatchdogConfig struct {
+	// NotifyURLs, in the format of a job's notify_urls, hear about
+	// restarts and recoveries.
+	NotifyURLs []NotifyConfig `yaml:"notify_urls"`
+	// StateFile keeps failure counts and restart backoff across watchdog
+	// invocations. Default data_dir/watchdog.state.json.
+	StateFile string `yaml:"state_file"`
+}
+
+// CommandPolicyConfig limits what API-managed jobs may run. Job files
+// written to jobs_dir directly are trusted and not checked.
+type CommandPolicyConfig struct {
+	// Allow lists the programs a command may start with: a name or path
+	// matched exactly, or a directory ending in "/" for the programs
+	// under it. Empty allows any program.
+	Allow []string `yaml:"allow"`
+	// Deny rejects commands matching any of these regular expressions.
+	Deny []string `yaml:"deny"`
+	// ForbidShellMetacharacters rejects ; & | ` $ ( ) < > and backslashes,
+	// so a command cannot chain or substitute its way past Allow.
+	ForbidShellMetacharacters bool `yaml:"forbid_shell_metacharacters"`
+}
+
+// EventWebhookConfig is an outbound webhook for realtime events.
+type EventWebhookConfig struct {
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	// Secret, if set, signs each body with HMAC-SHA256 in the
+	// X-Cronbat-Signature header.
+	Secret string `yaml:"secret"`
+	// Events limits the event types sent; empty sends all.
+	Events []string `yaml:"events"`
+	// MaxRetries is how often a failed delivery is retried. Default 5.
+	MaxRetries int `yaml:"max_retries"`
+	// DeadLetter is the file undeliverable events are appended to, one
+	// JSON object per line. Default data_dir/event_webhooks.deadletter.jsonl.
+	DeadLetter string `yaml:"dead_letter"`
+}
+
+// HostConfig describes the daemon's host to runs_on selectors.
+type HostConfig struct {
+	// Name is matched by the "hostname" selector key and recorded on
+	// runs. Defaults to the system hostname.
+	Name   string            `yaml:"name"`
+	Labels map[string]string `yaml:"labels"`
+}
+
+// AgentsConfig lets cronbat agents connect. An empty token turns agent
+// connections off.
+type AgentsConfig struct {
+	// Token is the shared secret agents present as a bearer token.
+	Token string `yaml:"token"`
+}
+
+// BusConfig connects cronbat to a message bus. An empty URL turns it off.
+type BusConfig struct {
+	// URL is redis://[user:password@]host:port or
+	// nats://[user:password@|token@]host:port.
+	URL string `yaml:"url"`
+	// EventsSubject prefixes the channel or subject events are published
+	// to: a run.completed event goes to "<events_subject>.run.completed".
+	EventsSubject string `yaml:"events_subject"`
+	// Events lists the event types to publish.
+	Events []string `yaml:"events"`
+	// TriggerSubject is the channel or subject trigger messages are read
+	// from. Empty means the bus cannot trigger runs.
+	TriggerSubject string `yaml:"trigger_subject"`
+}
+
+// APIKeyConfig is a named API key.
+type APIKeyConfig struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+	// Role limits what the key may do: admin (default), operator, or
+	// viewer.
+	Role string `yaml:"role"`
+}
+
+// StoreConfig tunes the run database.
+type StoreConfig struct {
+	// FlushInterval, when set, buffers run writes and commits them in one
+	// transaction per interval. Empty writes every run immediately.
+	FlushInterval string `yaml:"flush_interval"`
+	// FlushMaxBatch flushes early once this many runs are buffered.
+	FlushMaxBatch int `yaml:"flush_max_batch"`
+	// MaxTailBytes caps the stdout and stderr tails kept on each run row,
+	// whatever the jobs ask for. Default 1MB.
+	MaxTailBytes int `yaml:"max_tail_bytes"`
+	// TailFlushInterval is how often the output tails of running jobs are
+	// saved to their run records. Default "10s"; "0" turns it off.
+	TailFlushInterval string `yaml:"tail_flush_interval"`
+	// HeartbeatInterval is how often running runs, in the daemon and in
+	// "cronbat wrap", report they are alive. A run whose heartbeat is three
+	// intervals old is flagged as possibly hung. Default "30s"; "0" turns
+	// it off.
+	HeartbeatInterval string `yaml:"heartbeat_interval"`
+	// BusyTimeout is how long a statement waits for a lock held by another
+	// process, such as "cronbat wrap", before failing with "database is
+	// locked". Default "5s".
+	BusyTimeout string `yaml:"busy_timeout"`
+	// BusyRetries is how often run writes that still find the database
+	// locked are retried, with backoff. Default 3.
+	BusyRetries int `yaml:"busy_retries"`
+	// MaxOpenConns caps the daemon's database connections. Zero means no
+	// limit.
+	MaxOpenConns int `yaml:"max_open_conns"`
+	// CheckpointInterval is how often the daemon checkpoints the WAL into
+	// the database and truncates it. Default "5m"; "0" turns it off.
+	CheckpointInterval string `yaml:"checkpoint_interval"`
+}
+
+// ParseFlushInterval parses flush_interval; it returns 0 when buffering is
+// off.
+func (s StoreConfig) ParseFlushInterval() (time.Duration, error) {
+	if s.FlushInterval == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s.FlushInterval)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, errors.New("must not be negative")
+	}
+	return d, nil
+}
+
+// ParseTailFlushInterval parses tail_flush_interval; it returns 0 when
+// in-progress tails are not saved.
+func (s StoreConfig) ParseTailFlushInterval() (time.Duration, error) {
+	return parseOptionalInterval(s.TailFlushInterval)
+}
+
+// ParseHeartbeatInterval parses heartbeat_interval; it returns 0 when
+// running runs do not heartbeat.
+func (s StoreConfig) ParseHeartbeatInterval() (time.Duration, error) {
+	return parseOptionalInterval(s.HeartbeatInterval)
+}
+
+// ParseBusyTimeout parses busy_timeout.
+func (s StoreConfig) ParseBusyTimeout() (time.Duration, error) {
+	d, err := time.ParseDuration(s.BusyTimeout)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, errors.New("must be positive")
+	}
+	return d, nil
+}
+
+// ParseCheckpointInterval parses checkpoint_interval; it returns 0 when
+// the WAL is not checkpointed periodically.
+func (s StoreConfig) ParseCheckpointInterval() (time.Duration, error) {
+	return parseOptionalInterval(s.CheckpointInterval)
+}
+
+// parseOptionalInterval parses a duration where "" and "0" mean off.
+func parseOptionalInterval(v string) (time.Duration, error) {
+	if v == "" || v == "0" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, errors.New("must not be negative")
+	}
+	return d, nil
+}
+
+// JobDefaultsConfig holds fallback values for job settings.
+type JobDefaultsConfig struct {
+	// Timeout limits runs of jobs without their own timeout. Empty means
+	// no limit.
+	Timeout string `yaml:"timeout"`
+	// AutoDisable applies to jobs without their own auto_disable block.
+	AutoDisable *AutoDisableConfig `yaml:"auto_disable"`
+	// TailBytes is how much of the end of each output stream is kept on
+	// the run record for jobs without output.tail_bytes. Default 64KB.
+	TailBytes int `yaml:"tail_bytes"`
+}
+
+// ParseTimeout parses the default timeout; it returns 0 when none is set.
+func (d JobDefaultsConfig) ParseTimeout() (time.Duration, error) {
+	if d.Timeout == "" {
+		return 0, nil
+	}
+	t, err := time.ParseDuration(d.Timeout)
+	if err != nil {
+		return 0, err
+	}
+	if t < 0 {
+		return 0, errors.New("must not be negative")
+	}
+	return t, nil
+}
+
+// AutoDisableConfig disables a job once it has failed Failures times within
+// the Within window. Zero failures turns the policy off.
+type AutoDisableConfig struct {
+	Failures int    `yaml:"failures" json:"failures"`
+	Within   string `yaml:"within" json:"within"`
+}
+
+// Active reports whether the policy is turned on.
+func (a *AutoDisableConfig) Active() bool {
+	return a != nil && a.Failures > 0
+}
+
+// ParseWithin parses the failure window.
+func (a *AutoDisableConfig) ParseWithin() (time.Duration, error) {
+	d, err := time.ParseDuration(a.Within)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, errors.New("must be positive")
+	}
+	return d, nil
+}
+
+// Validate checks an auto_disable block.
+func (a *AutoDisableConfig) Validate() error {
+	if a == nil {
+		return nil
+	}
+	if a.Failures < 0 {
+		return errors.New("failures must not be negative")
+	}
+	if a.Failures == 0 {
+		return nil
+	}
+	if _, err := a.ParseWithin(); err != nil {
+		return fmt.Errorf("within: %w", err)
+	}
+	return nil
 }
 
 func applyDefaults(c *Config) {
@@ -80,6 +620,99 @@ func applyDefaults(c *Config) {
 		t := true
 		c.RunLogs.Enabled = &t
 	}
+	if c.RunLogs.Archive.AfterDays <= 0 {
+		c.RunLogs.Archive.AfterDays = 3
+	}
+	if c.RunLogs.Archive.Prefix == "" {
+		c.RunLogs.Archive.Prefix = "cronbat/logs"
+	}
+	if c.LoadGuard.DiskPath == "" {
+		c.LoadGuard.DiskPath = c.DataDir
+	} else {
+		c.LoadGuard.DiskPath = expandPath(c.LoadGuard.DiskPath)
+	}
+	if c.LoadGuard.Action == "" {
+		c.LoadGuard.Action = "defer"
+	}
+	if c.LoadGuard.RetryAfter == "" {
+		c.LoadGuard.RetryAfter = "1m"
+	}
+	if c.LoadGuard.MaxRetries <= 0 {
+		c.LoadGuard.MaxRetries = 5
+	}
+	if c.HTTP.ReadHeaderTimeout == "" {
+		c.HTTP.ReadHeaderTimeout = "10s"
+	}
+	if c.HTTP.ReadTimeout == "" {
+		c.HTTP.ReadTimeout = "1m"
+	}
+	if c.HTTP.WriteTimeout == "" {
+		c.HTTP.WriteTimeout = "1m"
+	}
+	if c.HTTP.IdleTimeout == "" {
+		c.HTTP.IdleTimeout = "2m"
+	}
+	if c.HTTP.ShutdownTimeout == "" {
+		c.HTTP.ShutdownTimeout = "10s"
+	}
+	if c.Store.MaxTailBytes <= 0 {
+		c.Store.MaxTailBytes = 1024 * 1024 // 1MB
+	}
+	if c.Store.TailFlushInterval == "" {
+		c.Store.TailFlushInterval = "10s"
+	}
+	if c.Store.HeartbeatInterval == "" {
+		c.Store.HeartbeatInterval = "30s"
+	}
+	if c.Store.BusyTimeout == "" {
+		c.Store.BusyTimeout = "5s"
+	}
+	if c.Store.BusyRetries <= 0 {
+		c.Store.BusyRetries = 3
+	}
+	if c.Store.CheckpointInterval == "" {
+		c.Store.CheckpointInterval = "5m"
+	}
+	if c.Defaults.TailBytes <= 0 {
+		c.Defaults.TailBytes = 64 * 1024 // 64KB
+	}
+	if c.Bus.EventsSubject == "" {
+		c.Bus.EventsSubject = "cronbat.events"
+	}
+	if len(c.Bus.Events) == 0 {
+		c.Bus.Events = []string{"run.started", "run.completed"}
+	}
+	for i := range c.EventWebhooks {
+		wh := &c.EventWebhooks[i]
+		if wh.MaxRetries == 0 {
+			wh.MaxRetries = 5
+		}
+		if wh.DeadLetter == "" {
+			wh.DeadLetter = filepath.Join(c.DataDir, "event_webhooks.deadletter.jsonl")
+		} else {
+			wh.DeadLetter = expandPath(wh.DeadLetter)
+		}
+	}
+	if c.Host.Name == "" {
+		c.Host.Name, _ = os.Hostname()
+	}
+	if c.Tracing.ServiceName == "" {
+		c.Tracing.ServiceName = "cronbat"
+	}
+	if c.UpdateCheck.Repo == "" {
+		c.UpdateCheck.Repo = "patrickspencer/cronbat"
+	}
+	if len(c.Auth.OIDC.Scopes) == 0 {
+		c.Auth.OIDC.Scopes = []string{"openid", "email", "profile"}
+	}
+	if c.Auth.OIDC.GroupsClaim == "" {
+		c.Auth.OIDC.GroupsClaim = "groups"
+	}
+	if c.Watchdog.StateFile == "" {
+		c.Watchdog.StateFile = filepath.Join(c.DataDir, "watchdog.state.json")
+	} else {
+		c.Watchdog.StateFile = expandPath(c.Watchdog.StateFile)
+	}
 }
 
 func defaultJobsDir() string {
@@ -131,3 +764,63 @@ func LoadConfig(path string) (*Config, error) {
 	applyDefaults(&cfg)
 	return &cfg, nil
 }
+
+// Redacted returns a copy of c for the config endpoint, with secrets
+// replaced by "REDACTED".
+func (c *Config) Redacted() *Config {
+	cp := *c
+	if c.RunLogs.Enabled != nil {
+		v := *c.RunLogs.Enabled
+		cp.RunLogs.Enabled = &v
+	}
+	if cp.RunLogs.Archive.SecretAccessKey != "" {
+		cp.RunLogs.Archive.SecretAccessKey = "REDACTED"
+	}
+	if cp.RunLogs.Archive.SessionToken != "" {
+		cp.RunLogs.Archive.SessionToken = "REDACTED"
+	}
+	if len(c.APIKeys) > 0 {
+		cp.APIKeys = make([]APIKeyConfig, len(c.APIKeys))
+		for i, k := range c.APIKeys {
+			cp.APIKeys[i] = APIKeyConfig{Name: k.Name, Key: "REDACTED", Role: k.Role}
+		}
+	}
+	if cp.Auth.OIDC.ClientSecret != "" {
+		cp.Auth.OIDC.ClientSecret = "REDACTED"
+	}
+	if cp.Secrets.Key != "" {
+		cp.Secrets.Key = "REDACTED"
+	}
+	if cp.Agents.Token != "" {
+		cp.Agents.Token = "REDACTED"
+	}
+	if len(c.EventWebhooks) > 0 {
+		cp.EventWebhooks = make([]EventWebhookConfig, len(c.EventWebhooks))
+		for i, h := range c.EventWebhooks {
+			if h.Secret != "" {
+				h.Secret = "REDACTED"
+			}
+			h.Headers = redactedValues(h.Headers)
+			cp.EventWebhooks[i] = h
+		}
+	}
+	if u, err := url.Parse(c.Bus.URL); err == nil && u.User != nil {
+		cp.Bus.URL = u.Redacted()
+	}
+	cp.Tracing.Headers = redactedValues(c.Tracing.Headers)
+	cp.LogShipping.Loki.Headers = redactedValues(c.LogShipping.Loki.Headers)
+	return &cp
+}
+
+// redactedValues returns a copy of m with every value replaced, for maps
+// of headers that usually carry credentials.
+func redactedValues(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return m
+	}
+	out := make(map[string]string, len(m))
+	for k := range m {
+		out[k] = "REDACTED"
+	}
+	return out
+}
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -72,3 +74,31 @@ run_logs:
 		t.Fatalf("expected expanded run_logs.dir %q, got %q", want, got)
 	}
 }
+
+func TestRedactedHidesSecrets(t *testing.T) {
+	t.Parallel()
+
+	const secret = "s3cr3t-value"
+	cfg := &Config{}
+	cfg.RunLogs.Archive.SecretAccessKey = secret
+	cfg.RunLogs.Archive.SessionToken = secret
+	cfg.APIKeys = []APIKeyConfig{{Name: "ci", Key: secret}}
+	cfg.Auth.OIDC.ClientSecret = secret
+	cfg.Secrets.Key = secret
+	cfg.Agents.Token = secret
+	cfg.EventWebhooks = []EventWebhookConfig{{URL: "https://hooks.example.com", Secret: secret, Headers: map[string]string{"Authorization": secret}}}
+	cfg.Bus.URL = "redis://cronbat:" + secret + "@localhost:6379/0"
+	cfg.Tracing.Headers = map[string]string{"x-honeycomb-team": secret}
+	cfg.LogShipping.Loki.Headers = map[string]string{"Authorization": "Bearer " + secret}
+
+	data, err := json.Marshal(cfg.Redacted())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), secret) {
+		t.Fatalf("redacted config leaks a secret: %s", data)
+	}
+	if cfg.Secrets.Key != secret || cfg.APIKeys[0].Key != secret {
+		t.Fatal("Redacted changed the original config")
+	}
+}
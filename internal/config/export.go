@@ -0,0 +1,120 @@
+package config
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExportTar is the export format holding one job file per job, laid out as
+// in a jobs directory. FormatYAML and FormatJSON are the other formats.
+const ExportTar = "tar"
+
+// ExportContentType returns the HTTP content type of an export format, or
+// "" if the format is not supported.
+func ExportContentType(format string) string {
+	switch format {
+	case FormatYAML:
+		return "application/x-yaml; charset=utf-8"
+	case FormatJSON:
+		return "application/json"
+	case ExportTar:
+		return "application/x-tar"
+	}
+	return ""
+}
+
+// FilterJobs returns the jobs named in names (all jobs when names is empty)
+// that are tagged with tag (any tag when empty), sorted by name.
+func FilterJobs(jobs []*Job, names []string, tag string) []*Job {
+	wanted := make(map[string]bool, len(names))
+	for _, n := range names {
+		wanted[n] = true
+	}
+	out := make([]*Job, 0, len(jobs))
+	for _, j := range jobs {
+		if len(wanted) > 0 && !wanted[j.Name] {
+			continue
+		}
+		if tag != "" && !j.HasTag(tag) {
+			continue
+		}
+		out = append(out, j)
+	}
+	sort.Slice(out, func(i, k int) bool { return out[i].Name < out[k].Name })
+	return out
+}
+
+// WriteExport writes jobs in format: a multi-document YAML stream, a JSON
+// array, or a tar archive of job files. The YAML and JSON forms are accepted
+// by the jobs import endpoint; the tar archive unpacks into a jobs directory.
+func WriteExport(w io.Writer, jobs []*Job, format string, now time.Time) error {
+	switch format {
+	case FormatYAML:
+		fmt.Fprintf(w, "# cronbat jobs export\n# generated_at: %s\n# count: %d\n", now.UTC().Format(time.RFC3339), len(jobs))
+		for i, job := range jobs {
+			if i > 0 {
+				io.WriteString(w, "\n---\n")
+			}
+			data, err := MarshalJobYAML(job)
+			if err != nil {
+				return fmt.Errorf("marshal job %q: %w", job.Name, err)
+			}
+			if len(data) == 0 || data[len(data)-1] != '\n' {
+				data = append(data, '\n')
+			}
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case FormatJSON:
+		if jobs == nil {
+			jobs = []*Job{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(jobs)
+
+	case ExportTar:
+		tw := tar.NewWriter(w)
+		for _, job := range jobs {
+			name := exportFileName(job)
+			data, err := MarshalJob(job, JobFileFormat(name))
+			if err != nil {
+				return fmt.Errorf("marshal job %q: %w", job.Name, err)
+			}
+			if err := tw.WriteHeader(&tar.Header{
+				Name:    name,
+				Mode:    0644,
+				Size:    int64(len(data)),
+				ModTime: now,
+				Format:  tar.FormatPAX,
+			}); err != nil {
+				return err
+			}
+			if _, err := tw.Write(data); err != nil {
+				return err
+			}
+		}
+		return tw.Close()
+	}
+	return fmt.Errorf("unsupported export format %q (want yaml, json, or tar)", format)
+}
+
+// exportFileName is the job's file name in a jobs directory, keeping the
+// format of the file it was loaded from.
+func exportFileName(job *Job) string {
+	ext := strings.ToLower(filepath.Ext(job.FilePath))
+	if JobFileFormat(ext) == "" {
+		ext = ".yaml"
+	}
+	return job.Name + ext
+}
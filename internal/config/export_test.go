@@ -0,0 +1,60 @@
+package config
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestWriteExportTarKeepsOneFilePerJob(t *testing.T) {
+	jobs := []*Job{
+		{Name: "backup", Schedule: "@daily", Command: "backup.sh", FilePath: "/jobs/backup.json"},
+		{Name: "report", Schedule: "@hourly", Command: "report.sh"},
+	}
+	var buf bytes.Buffer
+	if err := WriteExport(&buf, jobs, ExportTar, time.Now()); err != nil {
+		t.Fatalf("WriteExport: %v", err)
+	}
+
+	tr := tar.NewReader(&buf)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read tar: %v", err)
+		}
+		names = append(names, hdr.Name)
+		data, _ := io.ReadAll(tr)
+		if hdr.Name == "backup.json" {
+			var j Job
+			if err := json.Unmarshal(data, &j); err != nil || j.Command != "backup.sh" {
+				t.Fatalf("backup.json = %q (%v)", data, err)
+			}
+		}
+	}
+	if len(names) != 2 || names[0] != "backup.json" || names[1] != "report.yaml" {
+		t.Fatalf("entries = %v, want [backup.json report.yaml]", names)
+	}
+}
+
+func TestFilterJobs(t *testing.T) {
+	jobs := []*Job{
+		{Name: "c", Tags: []string{"nightly"}},
+		{Name: "a", Tags: []string{"nightly"}},
+		{Name: "b"},
+	}
+	got := FilterJobs(jobs, nil, "nightly")
+	if len(got) != 2 || got[0].Name != "a" || got[1].Name != "c" {
+		t.Fatalf("by tag = %v", got)
+	}
+	got = FilterJobs(jobs, []string{"b", "c"}, "")
+	if len(got) != 2 || got[0].Name != "b" || got[1].Name != "c" {
+		t.Fatalf("by name = %v", got)
+	}
+}
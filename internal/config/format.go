@@ -0,0 +1,130 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Job file formats. All of them decode into the same Job struct using the
+// field names of the YAML format.
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+	FormatTOML = "toml"
+)
+
+// JobFileFormat returns the format of a job file from its extension, or ""
+// if the file is not a job file.
+func JobFileFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml":
+		return FormatYAML
+	case ".json":
+		return FormatJSON
+	case ".toml":
+		return FormatTOML
+	}
+	return ""
+}
+
+// ParseJob parses a single job definition in the given format and applies
+// defaults. An empty format is treated as YAML.
+func ParseJob(data []byte, format string) (*Job, error) {
+	switch format {
+	case "", FormatYAML:
+		return ParseJobYAML(data)
+	case FormatJSON:
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil {
+			return nil, err
+		}
+		applyJobDefaults(&job)
+		return &job, nil
+	case FormatTOML:
+		var job Job
+		if err := UnmarshalTOML(data, &job); err != nil {
+			return nil, err
+		}
+		applyJobDefaults(&job)
+		return &job, nil
+	}
+	return nil, fmt.Errorf("unsupported job format %q", format)
+}
+
+// MarshalJob serializes a job in the given format. An empty format is
+// treated as YAML.
+func MarshalJob(job *Job, format string) ([]byte, error) {
+	switch format {
+	case "", FormatYAML:
+		return MarshalJobYAML(job)
+	case FormatJSON:
+		data, err := json.MarshalIndent(job, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case FormatTOML:
+		return MarshalTOML(job)
+	}
+	return nil, fmt.Errorf("unsupported job format %q", format)
+}
+
+// UnmarshalTOML decodes a TOML document into v. The document is converted
+// to YAML first so that v's yaml tags and decoding rules apply unchanged.
+func UnmarshalTOML(data []byte, v any) error {
+	var doc map[string]any
+	if _, err := toml.Decode(string(data), &doc); err != nil {
+		return err
+	}
+	yamlData, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(yamlData, v)
+}
+
+// MarshalTOML encodes v as TOML using its yaml field names. Null and empty
+// values are left out, since TOML has no null.
+func MarshalTOML(v any) ([]byte, error) {
+	yamlData, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(yamlData, &doc); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(pruneEmpty(doc)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func pruneEmpty(m map[string]any) map[string]any {
+	for k, v := range m {
+		switch val := v.(type) {
+		case nil:
+			delete(m, k)
+		case string:
+			if val == "" {
+				delete(m, k)
+			}
+		case []any:
+			if len(val) == 0 {
+				delete(m, k)
+			}
+		case map[string]any:
+			if len(pruneEmpty(val)) == 0 {
+				delete(m, k)
+			}
+		}
+	}
+	return m
+}
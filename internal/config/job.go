@@ -1,10 +1,18 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -18,21 +26,459 @@ type AnalyzeConfig struct {
 	OnResult []string `yaml:"on_result" json:"on_result"`
 }
 
+// OutputConfig holds per-stream output capture options for a job.
+type OutputConfig struct {
+	Stdout *bool `yaml:"stdout,omitempty" json:"stdout,omitempty"`
+	Stderr *bool `yaml:"stderr,omitempty" json:"stderr,omitempty"`
+	// MergeStderr writes stderr into the stdout stream and log file.
+	MergeStderr bool `yaml:"merge_stderr,omitempty" json:"merge_stderr,omitempty"`
+	// StripANSI removes terminal escape codes before storage.
+	StripANSI bool `yaml:"strip_ansi,omitempty" json:"strip_ansi,omitempty"`
+	// NormalizeCRLF rewrites CRLF line endings to LF before storage.
+	NormalizeCRLF bool `yaml:"normalize_crlf,omitempty" json:"normalize_crlf,omitempty"`
+	// TailBytes is how much of the end of each stream is kept on the run
+	// record. Zero uses defaults.tail_bytes; store.max_tail_bytes caps it.
+	TailBytes int `yaml:"tail_bytes,omitempty" json:"tail_bytes,omitempty"`
+	// SystemLog also forwards the captured streams, line by line, to
+	// "syslog" or "journald", tagged with the job name.
+	SystemLog string `yaml:"system_log,omitempty" json:"system_log,omitempty"`
+}
+
+// SandboxConfig restricts a job's process to reduce its blast radius.
+type SandboxConfig struct {
+	// ReadOnly mounts the root filesystem and every submount (/home,
+	// /tmp, ...) read-only for the job; WritablePaths are bind-mounted
+	// back writable.
+	ReadOnly      bool     `yaml:"read_only,omitempty" json:"read_only,omitempty"`
+	WritablePaths []string `yaml:"writable_paths,omitempty" json:"writable_paths,omitempty"`
+	// NoNetwork runs the job without network access.
+	NoNetwork bool `yaml:"no_network,omitempty" json:"no_network,omitempty"`
+}
+
+// LogRetentionConfig overrides run_logs retention for one job's log files.
+type LogRetentionConfig struct {
+	MaxRuns       int   `yaml:"max_runs,omitempty" json:"max_runs,omitempty"`
+	MaxMB         int64 `yaml:"max_mb,omitempty" json:"max_mb,omitempty"`
+	RetentionDays int   `yaml:"retention_days,omitempty" json:"retention_days,omitempty"`
+}
+
+// Notifier types for notify_urls entries.
+const (
+	NotifyWebhook  = "webhook"
+	NotifyPushover = "pushover"
+	NotifyTelegram = "telegram"
+	NotifyDiscord  = "discord"
+	// Incident notifiers open an incident after failures and resolve it
+	// once the job succeeds again.
+	NotifyPagerDuty = "pagerduty"
+	NotifyOpsgenie  = "opsgenie"
+)
+
+// DefaultNotifyMessage is the notification text when Message is empty.
+const DefaultNotifyMessage = `cronbat: {{.Job}} {{.Status}}{{if .ExitCode}} (exit {{.ExitCode}}){{end}} in {{.DurationMs}}ms{{if .Error}}: {{.Error}}{{end}}`
+
+// NotifyConfig sends a notification after the job's runs. The default
+// type, webhook, posts a JSON payload to URL; pushover, telegram, and
+// discord send a text message to a chat service; pagerduty and opsgenie
+// manage an incident.
+type NotifyConfig struct {
+	// Type is webhook (the default), pushover, telegram, discord,
+	// pagerduty, or opsgenie.
+	Type string `yaml:"type,omitempty" json:"type,omitempty"`
+	// URL is the webhook or Discord webhook URL.
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+	// Template is a text/template rendering a webhook's request body from
+	// the run; empty sends the default payload.
+	Template string `yaml:"template,omitempty" json:"template,omitempty"`
+	// Message is a text/template rendering the notification text from the
+	// run; empty uses DefaultNotifyMessage. Webhook templates see it as
+	// .Message.
+	Message string `yaml:"message,omitempty" json:"message,omitempty"`
+	// Token and User are a Pushover application token and user key.
+	Token string `yaml:"token,omitempty" json:"token,omitempty"`
+	User  string `yaml:"user,omitempty" json:"user,omitempty"`
+	// BotToken and ChatID address a Telegram chat.
+	BotToken string `yaml:"bot_token,omitempty" json:"bot_token,omitempty"`
+	ChatID   string `yaml:"chat_id,omitempty" json:"chat_id,omitempty"`
+	// RoutingKey is a PagerDuty Events API v2 integration key.
+	RoutingKey string `yaml:"routing_key,omitempty" json:"routing_key,omitempty"`
+	// APIKey is an Opsgenie API integration key; Region is us (the
+	// default) or eu.
+	APIKey string `yaml:"api_key,omitempty" json:"api_key,omitempty"`
+	Region string `yaml:"region,omitempty" json:"region,omitempty"`
+	// Failures is how many failed runs in a row open an incident (default
+	// 1).
+	Failures int `yaml:"failures,omitempty" json:"failures,omitempty"`
+	// On lists the run statuses to notify for; empty means every run.
+	On []string `yaml:"on,omitempty" json:"on,omitempty"`
+}
+
+// notifyStatuses are the final run statuses a notify_urls entry can select.
+var notifyStatuses = map[string]bool{"success": true, "warning": true, "failure": true, "preempted": true, "stopped": true}
+
+// IsIncident reports whether n manages incidents rather than sending a
+// message after each run.
+func (n NotifyConfig) IsIncident() bool {
+	return n.Type == NotifyPagerDuty || n.Type == NotifyOpsgenie
+}
+
+// FailureThreshold returns Failures, defaulting to 1.
+func (n NotifyConfig) FailureThreshold() int {
+	if n.Failures <= 0 {
+		return 1
+	}
+	return n.Failures
+}
+
+// Matches reports whether a run that finished with status is notified.
+func (n NotifyConfig) Matches(status string) bool {
+	if len(n.On) == 0 {
+		return true
+	}
+	for _, s := range n.On {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTemplate parses Template. Besides the builtins, templates can use
+// json, which encodes a value as JSON (e.g. {{json .StdoutTail}}).
+func (n NotifyConfig) ParseTemplate() (*template.Template, error) {
+	return parseNotifyTemplate("notify", n.Template)
+}
+
+// ParseMessage parses Message, or DefaultNotifyMessage when it is empty,
+// with the same functions as ParseTemplate.
+func (n NotifyConfig) ParseMessage() (*template.Template, error) {
+	if n.Message == "" {
+		return parseNotifyTemplate("message", DefaultNotifyMessage)
+	}
+	return parseNotifyTemplate("message", n.Message)
+}
+
+func parseNotifyTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(text)
+}
+
+// Validate checks the type's settings, statuses, and templates.
+func (n NotifyConfig) Validate() error {
+	switch n.Type {
+	case "", NotifyWebhook, NotifyDiscord:
+		u, err := url.Parse(n.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url must be an http or https URL")
+		}
+	case NotifyPushover:
+		if n.Token == "" || n.User == "" {
+			return fmt.Errorf("pushover needs token and user")
+		}
+	case NotifyTelegram:
+		if n.BotToken == "" || n.ChatID == "" {
+			return fmt.Errorf("telegram needs bot_token and chat_id")
+		}
+	case NotifyPagerDuty:
+		if n.RoutingKey == "" {
+			return fmt.Errorf("pagerduty needs routing_key")
+		}
+	case NotifyOpsgenie:
+		if n.APIKey == "" {
+			return fmt.Errorf("opsgenie needs api_key")
+		}
+		if n.Region != "" && n.Region != "us" && n.Region != "eu" {
+			return fmt.Errorf("region must be us or eu")
+		}
+	default:
+		return fmt.Errorf("unknown type %q (want webhook, pushover, telegram, discord, pagerduty, or opsgenie)", n.Type)
+	}
+	if n.Failures < 0 {
+		return fmt.Errorf("failures must not be negative")
+	}
+	if n.IsIncident() && len(n.On) > 0 {
+		return fmt.Errorf("on does not apply to %s; use failures", n.Type)
+	}
+	for _, s := range n.On {
+		if !notifyStatuses[s] {
+			return fmt.Errorf("on: unknown status %q (want success, warning, failure, preempted, or stopped)", s)
+		}
+	}
+	if n.Template != "" && n.Type != "" && n.Type != NotifyWebhook {
+		return fmt.Errorf("template only applies to webhooks; use message")
+	}
+	if _, err := n.ParseTemplate(); err != nil {
+		return fmt.Errorf("template: %w", err)
+	}
+	if _, err := n.ParseMessage(); err != nil {
+		return fmt.Errorf("message: %w", err)
+	}
+	return nil
+}
+
+// ServiceConfig tunes how a mode: service job is restarted when it exits.
+type ServiceConfig struct {
+	// RestartBackoff is the first delay before a restart (default 1s); it
+	// doubles after each quick exit up to MaxBackoff (default 5m).
+	RestartBackoff string `yaml:"restart_backoff,omitempty" json:"restart_backoff,omitempty"`
+	MaxBackoff     string `yaml:"max_backoff,omitempty" json:"max_backoff,omitempty"`
+}
+
+// SLOConfig declares a job's service level objective: a target success
+// rate over a rolling window and/or a longest allowed gap between
+// successful runs.
+type SLOConfig struct {
+	// SuccessRate is the percentage of finished runs that must succeed
+	// within Window, e.g. 99.
+	SuccessRate float64 `yaml:"success_rate,omitempty" json:"success_rate,omitempty"`
+	// Window is a duration or a number of days ("30d", the default).
+	Window string `yaml:"window,omitempty" json:"window,omitempty"`
+	// MaxInterval is the longest time allowed since the last successful
+	// run, e.g. "24h" or "1d".
+	MaxInterval string `yaml:"max_interval,omitempty" json:"max_interval,omitempty"`
+}
+
+// DefaultSLOWindow is the SLO window when none is set.
+const DefaultSLOWindow = 30 * 24 * time.Hour
+
+// ParseWindow parses Window, defaulting to DefaultSLOWindow.
+func (s *SLOConfig) ParseWindow() (time.Duration, error) {
+	if s.Window == "" {
+		return DefaultSLOWindow, nil
+	}
+	return ParseDays(s.Window)
+}
+
+// ParseMaxInterval parses MaxInterval; zero means it is not tracked.
+func (s *SLOConfig) ParseMaxInterval() (time.Duration, error) {
+	if s.MaxInterval == "" {
+		return 0, nil
+	}
+	return ParseDays(s.MaxInterval)
+}
+
+// Validate checks an slo block.
+func (s *SLOConfig) Validate() error {
+	if s == nil {
+		return nil
+	}
+	if s.SuccessRate < 0 || s.SuccessRate > 100 {
+		return errors.New("success_rate must be between 0 and 100")
+	}
+	if s.SuccessRate == 0 && s.MaxInterval == "" {
+		return errors.New("set success_rate, max_interval, or both")
+	}
+	if _, err := s.ParseWindow(); err != nil {
+		return fmt.Errorf("window: %w", err)
+	}
+	if _, err := s.ParseMaxInterval(); err != nil {
+		return fmt.Errorf("max_interval: %w", err)
+	}
+	return nil
+}
+
+// ExitCodes lists the exit codes that end a run with a status other than
+// the default: "success" for zero and "failure" for anything else.
+type ExitCodes struct {
+	Success []int `yaml:"success,omitempty" json:"success,omitempty"`
+	// Warning codes finish the run as "warning": counted apart from
+	// successes and failures, and neither opening incidents nor counting
+	// toward auto_disable.
+	Warning []int `yaml:"warning,omitempty" json:"warning,omitempty"`
+}
+
+// Validate checks an exit_codes block.
+func (e *ExitCodes) Validate() error {
+	if e == nil {
+		return nil
+	}
+	seen := make(map[int]string)
+	for _, list := range []struct {
+		name  string
+		codes []int
+	}{{"success", e.Success}, {"warning", e.Warning}} {
+		for _, c := range list.codes {
+			if c < 0 || c > 255 {
+				return fmt.Errorf("%s: exit code %d is not between 0 and 255", list.name, c)
+			}
+			if prev, ok := seen[c]; ok && prev != list.name {
+				return fmt.Errorf("exit code %d is listed under both %s and %s", c, prev, list.name)
+			}
+			seen[c] = list.name
+		}
+	}
+	return nil
+}
+
+// ExitStatus returns the status of a run that exited with code:
+// "success", "warning", or "failure".
+func (j *Job) ExitStatus(code int) string {
+	if e := j.ExitCodes; e != nil {
+		for _, c := range e.Warning {
+			if c == code {
+				return "warning"
+			}
+		}
+		for _, c := range e.Success {
+			if c == code {
+				return "success"
+			}
+		}
+	}
+	if code == 0 {
+		return "success"
+	}
+	return "failure"
+}
+
+// ParseDays parses a positive Go duration or a whole number of days ("7d").
+func ParseDays(s string) (time.Duration, error) {
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, err
+		}
+	}
+	if d <= 0 {
+		return 0, errors.New("must be positive")
+	}
+	return d, nil
+}
+
+// Job modes. Cron jobs (the default) run on their schedule; service jobs
+// are kept running continuously and restarted when they exit.
+const (
+	ModeCron    = "cron"
+	ModeService = "service"
+)
+
 // Job is the definition of a single cron job parsed from a YAML file.
 type Job struct {
-	Name       string            `yaml:"name" json:"name"`
-	Schedule   string            `yaml:"schedule" json:"schedule"`
-	Command    string            `yaml:"command" json:"command"`
-	WorkingDir string            `yaml:"working_dir" json:"working_dir,omitempty"`
-	Executor   string            `yaml:"executor" json:"executor,omitempty"`
-	Timeout    string            `yaml:"timeout" json:"timeout,omitempty"`
-	Env        map[string]string `yaml:"env" json:"env,omitempty"`
-	Enabled    *bool             `yaml:"enabled" json:"enabled,omitempty"`
-	OnSuccess  []string          `yaml:"on_success" json:"on_success,omitempty"`
-	OnFailure  []string          `yaml:"on_failure" json:"on_failure,omitempty"`
-	Analyze    *AnalyzeConfig    `yaml:"analyze" json:"analyze,omitempty"`
-	Metadata   map[string]any    `yaml:"metadata" json:"metadata,omitempty"`
-	FilePath   string            `yaml:"-" json:"-"`
+	Name     string `yaml:"name" json:"name"`
+	Mode     string `yaml:"mode,omitempty" json:"mode,omitempty"`
+	Schedule string `yaml:"schedule" json:"schedule"`
+	// DSTPolicy is run_once (default) or skip: what a fixed-time schedule
+	// does when a spring-forward transition skips its time.
+	DSTPolicy  string `yaml:"dst_policy,omitempty" json:"dst_policy,omitempty"`
+	Command    string `yaml:"command" json:"command"`
+	WorkingDir string `yaml:"working_dir" json:"working_dir,omitempty"`
+	Executor   string `yaml:"executor" json:"executor,omitempty"`
+	Timeout    string `yaml:"timeout" json:"timeout,omitempty"`
+	WarnAfter  string `yaml:"warn_after,omitempty" json:"warn_after,omitempty"`
+	// PreCheck is a command run before each run, with the job's shell,
+	// env, and working_dir; a non-zero exit skips the run, recorded as
+	// skipped:condition. PreCheckTimeout defaults to 1m.
+	PreCheck        string `yaml:"pre_check,omitempty" json:"pre_check,omitempty"`
+	PreCheckTimeout string `yaml:"pre_check_timeout,omitempty" json:"pre_check_timeout,omitempty"`
+	// DedupeWindow collapses manual and bus triggers that arrive within
+	// this long of the previous one into that run.
+	DedupeWindow  string              `yaml:"dedupe_window,omitempty" json:"dedupe_window,omitempty"`
+	Env           map[string]string   `yaml:"env" json:"env,omitempty"`
+	Enabled       *bool               `yaml:"enabled" json:"enabled,omitempty"`
+	OnSuccess     []string            `yaml:"on_success" json:"on_success,omitempty"`
+	OnFailure     []string            `yaml:"on_failure" json:"on_failure,omitempty"`
+	Analyze       *AnalyzeConfig      `yaml:"analyze" json:"analyze,omitempty"`
+	Metadata      map[string]any      `yaml:"metadata" json:"metadata,omitempty"`
+	Tags          []string            `yaml:"tags,omitempty" json:"tags,omitempty"`
+	CaptureOutput *bool               `yaml:"capture_output,omitempty" json:"capture_output,omitempty"`
+	Output        *OutputConfig       `yaml:"output,omitempty" json:"output,omitempty"`
+	Priority      int                 `yaml:"priority,omitempty" json:"priority,omitempty"`
+	Preempt       bool                `yaml:"preempt,omitempty" json:"preempt,omitempty"`
+	LoadGuard     *LoadGuardConfig    `yaml:"load_guard,omitempty" json:"load_guard,omitempty"`
+	User          string              `yaml:"user,omitempty" json:"user,omitempty"`
+	Group         string              `yaml:"group,omitempty" json:"group,omitempty"`
+	Sandbox       *SandboxConfig      `yaml:"sandbox,omitempty" json:"sandbox,omitempty"`
+	Shell         string              `yaml:"shell,omitempty" json:"shell,omitempty"`
+	LoginShell    bool                `yaml:"login_shell,omitempty" json:"login_shell,omitempty"`
+	LogRetention  *LogRetentionConfig `yaml:"log_retention,omitempty" json:"log_retention,omitempty"`
+	AutoDisable   *AutoDisableConfig  `yaml:"auto_disable,omitempty" json:"auto_disable,omitempty"`
+	// RequireApproval holds manual runs until someone other than the
+	// requester approves them; ApprovalTimeout (default 1h) is how long a
+	// request stays pending.
+	RequireApproval bool           `yaml:"require_approval,omitempty" json:"require_approval,omitempty"`
+	ApprovalTimeout string         `yaml:"approval_timeout,omitempty" json:"approval_timeout,omitempty"`
+	NotifyURLs      []NotifyConfig `yaml:"notify_urls,omitempty" json:"notify_urls,omitempty"`
+	Service         *ServiceConfig `yaml:"service,omitempty" json:"service,omitempty"`
+	SLO             *SLOConfig     `yaml:"slo,omitempty" json:"slo,omitempty"`
+	// RunsOn sends runs to a connected agent whose labels (and hostname,
+	// under the "hostname" key) match every entry, instead of running them
+	// on the server.
+	RunsOn map[string]string `yaml:"runs_on,omitempty" json:"runs_on,omitempty"`
+	// AutoArchive archives a one-shot ("@at") job once its scheduled run
+	// has finished.
+	AutoArchive bool `yaml:"auto_archive,omitempty" json:"auto_archive,omitempty"`
+	// RunIDPrefix is prepended, with a dash, to the IDs of the job's runs
+	// so they are recognizable outside cronbat.
+	RunIDPrefix string `yaml:"run_id_prefix,omitempty" json:"run_id_prefix,omitempty"`
+	// Matrix fans each run out into one parallel instance per combination
+	// of its values, e.g. region: [us, eu] and env: [staging, prod] make
+	// four. Each instance gets its values as MATRIX_<KEY> env vars.
+	Matrix map[string][]string `yaml:"matrix,omitempty" json:"matrix,omitempty"`
+	// MaxOutputBytes caps the combined stdout and stderr a run may write.
+	// Output past the cap is dropped; with KillOnOutputLimit the run is
+	// also killed and fails with "output limit exceeded".
+	MaxOutputBytes    int64 `yaml:"max_output_bytes,omitempty" json:"max_output_bytes,omitempty"`
+	KillOnOutputLimit bool  `yaml:"kill_on_output_limit,omitempty" json:"kill_on_output_limit,omitempty"`
+	// ExitCodes maps exit codes to run statuses, e.g. warning: [24] for
+	// rsync's vanished source files. Codes it does not list succeed when
+	// zero and fail otherwise.
+	ExitCodes *ExitCodes `yaml:"exit_codes,omitempty" json:"exit_codes,omitempty"`
+	// DisabledReason, DisabledBy, and DisabledAt record why, by whom, and
+	// when the job was disabled or paused. They are cleared when the job is
+	// enabled again.
+	DisabledReason string     `yaml:"disabled_reason,omitempty" json:"disabled_reason,omitempty"`
+	DisabledBy     string     `yaml:"disabled_by,omitempty" json:"disabled_by,omitempty"`
+	DisabledAt     *time.Time `yaml:"disabled_at,omitempty" json:"disabled_at,omitempty"`
+	FilePath       string     `yaml:"-" json:"-"`
+}
+
+// HasTag reports whether the job is tagged with tag.
+func (j *Job) HasTag(tag string) bool {
+	for _, t := range j.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// IsService reports whether the job runs in service mode.
+func (j *Job) IsService() bool {
+	return j.Mode == ModeService
+}
+
+// ParseServiceBackoff returns the initial and maximum restart delays of a
+// service job.
+func (j *Job) ParseServiceBackoff() (initial, maxDelay time.Duration, err error) {
+	initial, maxDelay = time.Second, 5*time.Minute
+	if j.Service == nil {
+		return initial, maxDelay, nil
+	}
+	if j.Service.RestartBackoff != "" {
+		if initial, err = time.ParseDuration(j.Service.RestartBackoff); err != nil || initial <= 0 {
+			return 0, 0, fmt.Errorf("restart_backoff must be a positive duration")
+		}
+	}
+	if j.Service.MaxBackoff != "" {
+		if maxDelay, err = time.ParseDuration(j.Service.MaxBackoff); err != nil || maxDelay <= 0 {
+			return 0, 0, fmt.Errorf("max_backoff must be a positive duration")
+		}
+	}
+	if maxDelay < initial {
+		maxDelay = initial
+	}
+	return initial, maxDelay, nil
 }
 
 // IsEnabled returns whether the job is enabled. Defaults to true if not set.
@@ -43,6 +489,48 @@ func (j *Job) IsEnabled() bool {
 	return *j.Enabled
 }
 
+// CapturesStdout reports whether stdout should be kept (tail and log file).
+// Defaults to true unless capture_output or output.stdout is false.
+func (j *Job) CapturesStdout() bool {
+	if j.CaptureOutput != nil && !*j.CaptureOutput {
+		return false
+	}
+	if j.Output != nil && j.Output.Stdout != nil {
+		return *j.Output.Stdout
+	}
+	return true
+}
+
+// CapturesStderr reports whether stderr should be kept (tail and log file).
+// Defaults to true unless capture_output or output.stderr is false.
+func (j *Job) CapturesStderr() bool {
+	if j.CaptureOutput != nil && !*j.CaptureOutput {
+		return false
+	}
+	if j.Output != nil && j.Output.Stderr != nil {
+		return *j.Output.Stderr
+	}
+	return true
+}
+
+// MergesStderr reports whether stderr is written into the stdout stream.
+func (j *Job) MergesStderr() bool {
+	return j.Output != nil && j.Output.MergeStderr
+}
+
+// EffectiveTailBytes returns the size of the job's output tails: its own
+// output.tail_bytes or def, capped at max.
+func (j *Job) EffectiveTailBytes(def, max int) int {
+	n := def
+	if j.Output != nil && j.Output.TailBytes > 0 {
+		n = j.Output.TailBytes
+	}
+	if max > 0 && n > max {
+		n = max
+	}
+	return n
+}
+
 // ParseTimeout parses the Timeout string into a time.Duration.
 // Returns 0 if the timeout is empty.
 func (j *Job) ParseTimeout() (time.Duration, error) {
@@ -52,6 +540,185 @@ func (j *Job) ParseTimeout() (time.Duration, error) {
 	return time.ParseDuration(j.Timeout)
 }
 
+// MaxRunIDPrefixLen bounds run_id_prefix.
+const MaxRunIDPrefixLen = 32
+
+// ValidateRunIDPrefix checks that a run_id_prefix is short and made of
+// letters, digits, underscores, and dashes, so run IDs stay safe in URLs
+// and log file names.
+func ValidateRunIDPrefix(prefix string) error {
+	if len(prefix) > MaxRunIDPrefixLen {
+		return fmt.Errorf("longer than %d characters", MaxRunIDPrefixLen)
+	}
+	for _, c := range prefix {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_', c == '-':
+		default:
+			return fmt.Errorf("%q may only contain letters, digits, '_' and '-'", prefix)
+		}
+	}
+	return nil
+}
+
+// ParseDedupeWindow parses dedupe_window. Zero means every trigger starts
+// a run.
+func (j *Job) ParseDedupeWindow() (time.Duration, error) {
+	if j.DedupeWindow == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(j.DedupeWindow)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return d, nil
+}
+
+// ParseWarnAfter parses warn_after: how long a run may take before a
+// run.slow warning is raised. Unset means never; set values must be
+// positive.
+func (j *Job) ParseWarnAfter() (time.Duration, error) {
+	if j.WarnAfter == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(j.WarnAfter)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("must be positive")
+	}
+	return d, nil
+}
+
+// DefaultPreCheckTimeout bounds pre_check when pre_check_timeout is not
+// set.
+const DefaultPreCheckTimeout = time.Minute
+
+// ParsePreCheckTimeout parses pre_check_timeout, defaulting to
+// DefaultPreCheckTimeout.
+func (j *Job) ParsePreCheckTimeout() (time.Duration, error) {
+	if j.PreCheckTimeout == "" {
+		return DefaultPreCheckTimeout, nil
+	}
+	d, err := time.ParseDuration(j.PreCheckTimeout)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("must be positive")
+	}
+	return d, nil
+}
+
+// DefaultApprovalTimeout is how long a manual run of an approval-gated job
+// waits for approval when approval_timeout is not set.
+const DefaultApprovalTimeout = time.Hour
+
+// ParseApprovalTimeout parses approval_timeout, defaulting to
+// DefaultApprovalTimeout.
+func (j *Job) ParseApprovalTimeout() (time.Duration, error) {
+	if j.ApprovalTimeout == "" {
+		return DefaultApprovalTimeout, nil
+	}
+	d, err := time.ParseDuration(j.ApprovalTimeout)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("must be positive")
+	}
+	return d, nil
+}
+
+// EffectiveTimeout returns the job's own timeout, or def when it sets none.
+// An explicit zero timeout disables the limit.
+func (j *Job) EffectiveTimeout(def time.Duration) (time.Duration, error) {
+	if j.Timeout == "" {
+		return def, nil
+	}
+	return j.ParseTimeout()
+}
+
+// MergeSettings returns a copy of j with the settings in updated applied,
+// as PUT /api/v1/jobs/{name} and imports of existing jobs do. updated is
+// the whole definition: a setting it leaves unset is cleared, except
+// enabled, which keeps its current value.
+func (j *Job) MergeSettings(updated Job) *Job {
+	candidate := *j
+	if j.Enabled != nil {
+		v := *j.Enabled
+		candidate.Enabled = &v
+	}
+	candidate.Mode = strings.TrimSpace(updated.Mode)
+	candidate.Service = updated.Service
+	candidate.Schedule = strings.TrimSpace(updated.Schedule)
+	candidate.Command = strings.TrimSpace(updated.Command)
+	candidate.WorkingDir = strings.TrimSpace(updated.WorkingDir)
+	candidate.Executor = strings.TrimSpace(updated.Executor)
+	if candidate.Executor == "" {
+		candidate.Executor = "shell"
+	}
+	candidate.Timeout = strings.TrimSpace(updated.Timeout)
+	candidate.WarnAfter = strings.TrimSpace(updated.WarnAfter)
+	candidate.DedupeWindow = strings.TrimSpace(updated.DedupeWindow)
+	candidate.PreCheck = strings.TrimSpace(updated.PreCheck)
+	candidate.PreCheckTimeout = strings.TrimSpace(updated.PreCheckTimeout)
+	candidate.RunIDPrefix = strings.TrimSpace(updated.RunIDPrefix)
+	candidate.User = strings.TrimSpace(updated.User)
+	candidate.Group = strings.TrimSpace(updated.Group)
+	candidate.Env = updated.Env
+	candidate.OnSuccess = updated.OnSuccess
+	candidate.OnFailure = updated.OnFailure
+	candidate.Metadata = updated.Metadata
+	candidate.Tags = updated.Tags
+	candidate.Analyze = updated.Analyze
+	candidate.Matrix = updated.Matrix
+	candidate.MaxOutputBytes = updated.MaxOutputBytes
+	candidate.KillOnOutputLimit = updated.KillOnOutputLimit
+	if updated.Enabled != nil {
+		v := *updated.Enabled
+		candidate.Enabled = &v
+		if v {
+			candidate.DisabledReason, candidate.DisabledBy, candidate.DisabledAt = "", "", nil
+		}
+	}
+	candidate.AutoDisable = updated.AutoDisable
+	candidate.CaptureOutput = updated.CaptureOutput
+	candidate.Output = updated.Output
+	candidate.Priority = updated.Priority
+	candidate.Preempt = updated.Preempt
+	candidate.LoadGuard = updated.LoadGuard
+	candidate.Sandbox = updated.Sandbox
+	candidate.LogRetention = updated.LogRetention
+	candidate.Shell = strings.TrimSpace(updated.Shell)
+	candidate.LoginShell = updated.LoginShell
+	candidate.RequireApproval = updated.RequireApproval
+	candidate.ApprovalTimeout = strings.TrimSpace(updated.ApprovalTimeout)
+	candidate.DSTPolicy = strings.TrimSpace(updated.DSTPolicy)
+	candidate.NotifyURLs = updated.NotifyURLs
+	candidate.SLO = updated.SLO
+	candidate.ExitCodes = updated.ExitCodes
+	candidate.RunsOn = updated.RunsOn
+	candidate.AutoArchive = updated.AutoArchive
+	if !candidate.IsOneShot() {
+		candidate.AutoArchive = false
+	}
+	return &candidate
+}
+
+// Lint returns warnings about settings that are valid but probably not
+// intended.
+func (j *Job) Lint() []string {
+	var warnings []string
+	if d, err := j.ParseTimeout(); err == nil && j.Timeout != "" && d == 0 {
+		warnings = append(warnings, "timeout: 0 disables the time limit, so runs may never finish")
+	}
+	return warnings
+}
+
 func applyJobDefaults(j *Job) {
 	if j.Executor == "" {
 		j.Executor = "shell"
@@ -73,54 +740,355 @@ func MarshalJobYAML(job *Job) ([]byte, error) {
 	return yaml.Marshal(job)
 }
 
-// SaveJob writes a single job definition file.
+// EffectiveAutoDisable returns the job's auto_disable policy, falling back
+// to def. The result may be inactive or nil.
+func (j *Job) EffectiveAutoDisable(def *AutoDisableConfig) *AutoDisableConfig {
+	if j.AutoDisable != nil {
+		return j.AutoDisable
+	}
+	return def
+}
+
+// Version returns a short hash identifying the job's definition. Enabling or
+// disabling the job does not change it.
+func (j *Job) Version() string {
+	cp := *j
+	cp.Enabled = nil
+	cp.DisabledReason, cp.DisabledBy, cp.DisabledAt = "", "", nil
+	data, err := MarshalJobYAML(&cp)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// ApplyPinned replaces how the job runs (command, environment, working
+// directory, and shell) with pinned's, keeping its name and schedule.
+func (j *Job) ApplyPinned(pinned *Job) {
+	j.Command = pinned.Command
+	j.Env = pinned.Env
+	j.WorkingDir = pinned.WorkingDir
+	j.Executor = pinned.Executor
+	j.Shell = pinned.Shell
+	j.LoginShell = pinned.LoginShell
+	j.Timeout = pinned.Timeout
+}
+
+// SaveJob writes a single job definition file atomically, in the format
+// given by the file's extension (YAML unless it is .json or .toml).
 func SaveJob(path string, job *Job) error {
-	data, err := MarshalJobYAML(job)
+	data, err := MarshalJob(job, JobFileFormat(path))
 	if err != nil {
 		return err
 	}
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return err
 	}
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	if err := writeFileAtomic(path, data, 0644); err != nil {
 		return err
 	}
 	job.FilePath = path
 	return nil
 }
 
-// LoadJobs reads all *.yaml files from dir, parses each into a Job,
-// and returns the collected jobs.
+// LoadError describes a job file that could not be loaded.
+type LoadError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+	// QuarantinedPath is set when the file was moved out of the jobs dir.
+	QuarantinedPath string `json:"quarantined_path,omitempty"`
+}
+
+// Validate checks the fields a job file must define to be loaded.
+func (j *Job) Validate() error {
+	if strings.TrimSpace(j.Name) == "" {
+		return fmt.Errorf("job name is required")
+	}
+	if err := j.ValidateMode(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(j.Schedule) == "" && !j.IsService() {
+		return fmt.Errorf("job schedule is required")
+	}
+	if strings.TrimSpace(j.Command) == "" {
+		return fmt.Errorf("job command is required")
+	}
+	if _, err := j.ParseTimeout(); err != nil {
+		return fmt.Errorf("invalid timeout: %w", err)
+	}
+	if _, err := j.ParseWarnAfter(); err != nil {
+		return fmt.Errorf("invalid warn_after: %w", err)
+	}
+	if _, err := j.ParseDedupeWindow(); err != nil {
+		return fmt.Errorf("invalid dedupe_window: %w", err)
+	}
+	if _, err := j.ParsePreCheckTimeout(); err != nil {
+		return fmt.Errorf("invalid pre_check_timeout: %w", err)
+	}
+	if j.PreCheck != "" && j.IsService() {
+		return fmt.Errorf("invalid pre_check: service jobs run continuously")
+	}
+	if err := ValidateRunIDPrefix(j.RunIDPrefix); err != nil {
+		return fmt.Errorf("invalid run_id_prefix: %w", err)
+	}
+	if err := j.AutoDisable.Validate(); err != nil {
+		return fmt.Errorf("invalid auto_disable: %w", err)
+	}
+	if err := j.LoadGuard.Validate(); err != nil {
+		return fmt.Errorf("invalid load_guard: %w", err)
+	}
+	if _, err := j.ParseApprovalTimeout(); err != nil {
+		return fmt.Errorf("invalid approval_timeout: %w", err)
+	}
+	if err := j.ValidateNotifyURLs(); err != nil {
+		return err
+	}
+	if err := j.SLO.Validate(); err != nil {
+		return fmt.Errorf("invalid slo: %w", err)
+	}
+	if j.MaxOutputBytes < 0 {
+		return fmt.Errorf("max_output_bytes must not be negative")
+	}
+	if j.KillOnOutputLimit && j.MaxOutputBytes == 0 {
+		return fmt.Errorf("kill_on_output_limit requires max_output_bytes")
+	}
+	if err := j.ExitCodes.Validate(); err != nil {
+		return fmt.Errorf("invalid exit_codes: %w", err)
+	}
+	if j.Output != nil && j.Output.TailBytes < 0 {
+		return fmt.Errorf("output.tail_bytes must not be negative")
+	}
+	if j.Output != nil {
+		switch j.Output.SystemLog {
+		case "", "syslog", "journald":
+		default:
+			return fmt.Errorf("invalid output.system_log %q: want syslog or journald", j.Output.SystemLog)
+		}
+	}
+	if err := j.ValidateAutoArchive(); err != nil {
+		return err
+	}
+	if err := j.ValidateMatrix(); err != nil {
+		return err
+	}
+	return j.ValidateRunsOn()
+}
+
+// MaxMatrixInstances bounds the instances a matrix may expand to.
+const MaxMatrixInstances = 64
+
+// ValidateMatrix checks that matrix keys can name env vars, every key has
+// values, and the expansion stays under MaxMatrixInstances.
+func (j *Job) ValidateMatrix() error {
+	if len(j.Matrix) == 0 {
+		return nil
+	}
+	if j.IsService() {
+		return fmt.Errorf("invalid matrix: service jobs run a single process")
+	}
+	total := 1
+	for k, values := range j.Matrix {
+		if !isMatrixKey(k) {
+			return fmt.Errorf("invalid matrix: key %q must be letters, digits, and '_', not starting with a digit", k)
+		}
+		if len(values) == 0 {
+			return fmt.Errorf("invalid matrix: %s has no values", k)
+		}
+		total *= len(values)
+		if total > MaxMatrixInstances {
+			return fmt.Errorf("invalid matrix: more than %d instances", MaxMatrixInstances)
+		}
+	}
+	return nil
+}
+
+func isMatrixKey(k string) bool {
+	for i, c := range k {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return k != ""
+}
+
+// MatrixInstances returns the value combinations of the job's matrix, in a
+// stable order with the first key (sorted by name) varying slowest, or
+// nil for a job without one.
+func (j *Job) MatrixInstances() []map[string]string {
+	if len(j.Matrix) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(j.Matrix))
+	for k := range j.Matrix {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	instances := []map[string]string{{}}
+	for _, k := range keys {
+		next := make([]map[string]string, 0, len(instances)*len(j.Matrix[k]))
+		for _, inst := range instances {
+			for _, v := range j.Matrix[k] {
+				combo := make(map[string]string, len(inst)+1)
+				for ik, iv := range inst {
+					combo[ik] = iv
+				}
+				combo[k] = v
+				next = append(next, combo)
+			}
+		}
+		instances = next
+	}
+	return instances
+}
+
+// MatrixEnv returns the env vars that carry an instance's matrix values:
+// MATRIX_ and the upper-cased key.
+func MatrixEnv(values map[string]string) map[string]string {
+	env := make(map[string]string, len(values))
+	for k, v := range values {
+		env["MATRIX_"+strings.ToUpper(k)] = v
+	}
+	return env
+}
+
+// ValidateAutoArchive checks that auto_archive is only set on one-shot jobs.
+func (j *Job) ValidateAutoArchive() error {
+	if j.AutoArchive && !j.IsOneShot() {
+		return fmt.Errorf("auto_archive needs a one-shot \"@at <time>\" schedule")
+	}
+	return nil
+}
+
+// IsOneShot reports whether the job runs once, at the time in an
+// "@at <time>" schedule.
+func (j *Job) IsOneShot() bool {
+	return strings.HasPrefix(strings.TrimSpace(j.Schedule), "@at ")
+}
+
+// ValidateRunsOn checks the runs_on selector.
+func (j *Job) ValidateRunsOn() error {
+	if len(j.RunsOn) == 0 {
+		return nil
+	}
+	if j.IsService() {
+		return fmt.Errorf("invalid runs_on: service jobs run on the server")
+	}
+	for k, v := range j.RunsOn {
+		if strings.TrimSpace(k) == "" || strings.TrimSpace(v) == "" {
+			return fmt.Errorf("invalid runs_on: labels and values must not be empty")
+		}
+	}
+	return nil
+}
+
+// ValidateMode checks mode and, for services, the restart backoff.
+func (j *Job) ValidateMode() error {
+	switch j.Mode {
+	case "", ModeCron:
+	case ModeService:
+		if _, _, err := j.ParseServiceBackoff(); err != nil {
+			return fmt.Errorf("invalid service: %w", err)
+		}
+	default:
+		return fmt.Errorf("invalid mode %q: want cron or service", j.Mode)
+	}
+	return nil
+}
+
+// ValidateNotifyURLs checks each notify_urls entry.
+func (j *Job) ValidateNotifyURLs() error {
+	for i, n := range j.NotifyURLs {
+		if err := n.Validate(); err != nil {
+			return fmt.Errorf("invalid notify_urls[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// LoadJobs reads all *.yaml, *.json and *.toml files from dir, parses each into a Job,
+// and returns the collected jobs. It fails on the first unreadable or
+// unparsable file; see LoadJobsReport for a lenient variant.
 func LoadJobs(dir string) ([]*Job, error) {
-	entries, err := os.ReadDir(dir)
+	jobs, loadErrors, err := LoadJobsReport(dir)
 	if err != nil {
 		return nil, err
 	}
+	if len(loadErrors) > 0 {
+		return nil, fmt.Errorf("%s: %s", loadErrors[0].Path, loadErrors[0].Error)
+	}
+	return jobs, nil
+}
+
+// LoadJobsReport reads all job files from dir like LoadJobs, but skips
+// files that cannot be read, parsed, or validated, or that repeat an
+// earlier job's name, and reports them as load errors.
+// An error is returned only if dir itself cannot be read.
+func LoadJobsReport(dir string) ([]*Job, []LoadError, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	var jobs []*Job
+	var loadErrors []LoadError
+	seen := make(map[string]string)
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
 		}
 		name := entry.Name()
-		if !strings.HasSuffix(name, ".yaml") {
+		format := JobFileFormat(name)
+		if format == "" {
 			continue
 		}
 
 		path := filepath.Join(dir, name)
 		data, err := os.ReadFile(path)
 		if err != nil {
-			return nil, fmt.Errorf("reading %s: %w", path, err)
+			loadErrors = append(loadErrors, LoadError{Path: path, Error: fmt.Sprintf("reading: %v", err)})
+			continue
 		}
 
-		job, err := ParseJobYAML(data)
+		job, err := ParseJob(data, format)
 		if err != nil {
-			return nil, fmt.Errorf("parsing %s: %w", path, err)
+			loadErrors = append(loadErrors, LoadError{Path: path, Error: fmt.Sprintf("parsing: %v", err)})
+			continue
 		}
+		if err := job.Validate(); err != nil {
+			loadErrors = append(loadErrors, LoadError{Path: path, Error: err.Error()})
+			continue
+		}
+		if first, dup := seen[job.Name]; dup {
+			loadErrors = append(loadErrors, LoadError{Path: path, Error: fmt.Sprintf("duplicate job name %q (already defined in %s)", job.Name, filepath.Base(first))})
+			continue
+		}
+		seen[job.Name] = path
 
 		job.FilePath = path
 		jobs = append(jobs, job)
 	}
 
-	return jobs, nil
+	return jobs, loadErrors, nil
+}
+
+// QuarantineJobFile moves an invalid job file into the quarantine/
+// subdirectory next to it, so it is no longer picked up at load. A timestamp
+// is added to the name to avoid overwriting earlier quarantined copies.
+// It returns the new path.
+func QuarantineJobFile(path string) (string, error) {
+	dir := filepath.Join(filepath.Dir(path), "quarantine")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(filepath.Base(path), ext)
+	dst := filepath.Join(dir, fmt.Sprintf("%s-%s%s", base, time.Now().UTC().Format("20060102T150405Z"), ext))
+	if err := os.Rename(path, dst); err != nil {
+		return "", err
+	}
+	return dst, nil
 }
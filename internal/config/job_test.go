@@ -0,0 +1,404 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadJobsReportSkipsBrokenFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	good := &Job{Name: "good", Schedule: "* * * * *", Command: "true"}
+	if err := SaveJob(filepath.Join(dir, "good.yaml"), good); err != nil {
+		t.Fatalf("SaveJob: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bad.yaml"), []byte("name: [unterminated\n"), 0644); err != nil {
+		t.Fatalf("write bad job: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected no temp files left behind, got %d entries", len(entries))
+	}
+
+	jobs, loadErrors, err := LoadJobsReport(dir)
+	if err != nil {
+		t.Fatalf("LoadJobsReport: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].Name != "good" {
+		t.Fatalf("expected only the good job, got %+v", jobs)
+	}
+	if len(loadErrors) != 1 || filepath.Base(loadErrors[0].Path) != "bad.yaml" {
+		t.Fatalf("expected one load error for bad.yaml, got %+v", loadErrors)
+	}
+
+	if _, err := LoadJobs(dir); err == nil {
+		t.Fatal("expected strict LoadJobs to fail on the broken file")
+	}
+}
+
+func TestLoadJobsReportRejectsInvalidAndDuplicateJobs(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for file, job := range map[string]*Job{
+		"a.yaml":         {Name: "dup", Schedule: "* * * * *", Command: "true"},
+		"b.yaml":         {Name: "dup", Schedule: "* * * * *", Command: "true"},
+		"nocommand.yaml": {Name: "nocommand", Schedule: "* * * * *"},
+	} {
+		if err := SaveJob(filepath.Join(dir, file), job); err != nil {
+			t.Fatalf("SaveJob: %v", err)
+		}
+	}
+
+	jobs, loadErrors, err := LoadJobsReport(dir)
+	if err != nil {
+		t.Fatalf("LoadJobsReport: %v", err)
+	}
+	if len(jobs) != 1 || filepath.Base(jobs[0].FilePath) != "a.yaml" {
+		t.Fatalf("expected only a.yaml to load, got %+v", jobs)
+	}
+	if len(loadErrors) != 2 {
+		t.Fatalf("expected two load errors, got %+v", loadErrors)
+	}
+
+	dst, err := QuarantineJobFile(loadErrors[0].Path)
+	if err != nil {
+		t.Fatalf("QuarantineJobFile: %v", err)
+	}
+	if filepath.Dir(dst) != filepath.Join(dir, "quarantine") {
+		t.Fatalf("unexpected quarantine path %s", dst)
+	}
+	if _, err := os.Stat(loadErrors[0].Path); !os.IsNotExist(err) {
+		t.Fatalf("expected original file to be moved, stat err = %v", err)
+	}
+}
+
+func TestSaveJobKeepsFileFormat(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	enabled := false
+	job := &Job{
+		Name:     "multi",
+		Schedule: "0 * * * *",
+		Command:  "true",
+		Enabled:  &enabled,
+		Priority: 2,
+		Env:      map[string]string{"A": "1"},
+		Output:   &OutputConfig{StripANSI: true},
+	}
+	for _, file := range []string{"multi.json", "multi.toml"} {
+		path := filepath.Join(dir, file)
+		if err := SaveJob(path, job); err != nil {
+			t.Fatalf("SaveJob(%s): %v", file, err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ParseJob(data, JobFileFormat(path))
+		if err != nil {
+			t.Fatalf("ParseJob(%s): %v\n%s", file, err, data)
+		}
+		if got.Name != "multi" || got.IsEnabled() || got.Priority != 2 || got.Env["A"] != "1" || got.Output == nil || !got.Output.StripANSI {
+			t.Fatalf("%s did not round-trip: %+v\n%s", file, got, data)
+		}
+	}
+}
+
+func TestSaveJobsRollsBackOnFailure(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "b.yaml")
+	original := []byte("name: b\nschedule: \"0 * * * *\"\ncommand: \"true\"\n# keep me\n")
+	if err := os.WriteFile(existing, original, 0644); err != nil {
+		t.Fatal(err)
+	}
+	removed := filepath.Join(dir, "c.yaml")
+	if err := os.WriteFile(removed, []byte("name: c\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A path below a regular file cannot be written.
+	blocked := filepath.Join(existing, "d.yaml")
+
+	job := func(name string) *Job {
+		return &Job{Name: name, Schedule: "0 * * * *", Command: "true"}
+	}
+	created := filepath.Join(dir, "a.yaml")
+	_, err := SaveJobs([]JobFileChange{
+		{Path: created, Job: job("a")},
+		{Path: existing, Job: job("b")},
+		{Path: removed},
+		{Path: blocked, Job: job("d")},
+	})
+	if err == nil {
+		t.Fatal("expected SaveJobs to fail")
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Fatalf("created file should be removed, stat err=%v", err)
+	}
+	if data, _ := os.ReadFile(existing); string(data) != string(original) {
+		t.Fatalf("updated file not restored:\n%s", data)
+	}
+	if _, err := os.Stat(removed); err != nil {
+		t.Fatalf("removed file not restored: %v", err)
+	}
+
+	undo, err := SaveJobs([]JobFileChange{{Path: created, Job: job("a")}, {Path: existing, Job: job("b")}})
+	if err != nil {
+		t.Fatalf("SaveJobs: %v", err)
+	}
+	if err := undo(); err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Fatalf("undo should remove created file, stat err=%v", err)
+	}
+	if data, _ := os.ReadFile(existing); string(data) != string(original) {
+		t.Fatalf("undo did not restore file:\n%s", data)
+	}
+}
+
+func TestEffectiveTimeout(t *testing.T) {
+	t.Parallel()
+
+	def := time.Hour
+	cases := []struct {
+		timeout string
+		want    time.Duration
+		lint    bool
+	}{
+		{"", time.Hour, false},
+		{"5m", 5 * time.Minute, false},
+		{"0", 0, true},
+	}
+	for _, tc := range cases {
+		j := &Job{Name: "j", Timeout: tc.timeout}
+		got, err := j.EffectiveTimeout(def)
+		if err != nil || got != tc.want {
+			t.Fatalf("timeout %q: got %v, %v; want %v", tc.timeout, got, err, tc.want)
+		}
+		if warned := len(j.Lint()) > 0; warned != tc.lint {
+			t.Fatalf("timeout %q: lint warning = %v, want %v", tc.timeout, warned, tc.lint)
+		}
+	}
+}
+
+func TestEffectiveAutoDisable(t *testing.T) {
+	t.Parallel()
+
+	def := &AutoDisableConfig{Failures: 3, Within: "1h"}
+	if got := (&Job{Name: "j"}).EffectiveAutoDisable(def); got != def || !got.Active() {
+		t.Fatalf("expected default policy, got %+v", got)
+	}
+	optOut := &Job{Name: "j", AutoDisable: &AutoDisableConfig{Failures: 0}}
+	if got := optOut.EffectiveAutoDisable(def); got.Active() {
+		t.Fatalf("expected failures: 0 to opt out, got %+v", got)
+	}
+	if err := optOut.AutoDisable.Validate(); err != nil {
+		t.Fatalf("opt-out should validate: %v", err)
+	}
+
+	for _, bad := range []AutoDisableConfig{
+		{Failures: 3},
+		{Failures: 3, Within: "soon"},
+		{Failures: 3, Within: "-1m"},
+		{Failures: -1, Within: "1h"},
+	} {
+		bad := bad
+		if err := bad.Validate(); err == nil {
+			t.Fatalf("expected %+v to be rejected", bad)
+		}
+	}
+}
+
+func TestMatrixInstances(t *testing.T) {
+	t.Parallel()
+
+	j := &Job{Name: "j", Matrix: map[string][]string{"region": {"us", "eu"}, "env": {"staging", "prod"}}}
+	if err := j.ValidateMatrix(); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, inst := range j.MatrixInstances() {
+		got = append(got, inst["env"]+"/"+inst["region"])
+	}
+	want := "staging/us staging/eu prod/us prod/eu"
+	if strings.Join(got, " ") != want {
+		t.Fatalf("instances = %v, want %s", got, want)
+	}
+	if env := MatrixEnv(map[string]string{"region": "us"}); env["MATRIX_REGION"] != "us" {
+		t.Fatalf("env = %v", env)
+	}
+
+	for _, bad := range []map[string][]string{
+		{"1st": {"a"}},
+		{"region-name": {"a"}},
+		{"region": {}},
+		{"a": make([]string, 8), "b": make([]string, 9)},
+	} {
+		if err := (&Job{Name: "j", Matrix: bad}).ValidateMatrix(); err == nil {
+			t.Errorf("matrix %v: expected an error", bad)
+		}
+	}
+}
+
+func TestMergeSettingsMatrix(t *testing.T) {
+	t.Parallel()
+
+	current := &Job{Name: "deploy", Schedule: "@daily", Command: "deploy.sh"}
+	added := current.MergeSettings(Job{Schedule: "@daily", Command: "deploy.sh", Matrix: map[string][]string{"region": {"us", "eu"}}})
+	if got := added.Matrix["region"]; len(got) != 2 {
+		t.Fatalf("matrix not added: %v", added.Matrix)
+	}
+	if current.Matrix != nil {
+		t.Fatalf("MergeSettings changed the original job: %v", current.Matrix)
+	}
+
+	changed := added.MergeSettings(Job{Schedule: "@daily", Command: "deploy.sh", Matrix: map[string][]string{"region": {"ap"}}})
+	if got := changed.Matrix["region"]; len(got) != 1 || got[0] != "ap" {
+		t.Fatalf("matrix not changed: %v", changed.Matrix)
+	}
+
+	removed := changed.MergeSettings(Job{Schedule: "@daily", Command: "deploy.sh"})
+	if removed.Matrix != nil {
+		t.Fatalf("matrix not removed: %v", removed.Matrix)
+	}
+}
+
+func TestMergeSettingsClearsFlags(t *testing.T) {
+	t.Parallel()
+
+	current := &Job{
+		Name: "j", Schedule: "@daily", Command: "true",
+		Priority: 5, Preempt: true, User: "app", Shell: "/bin/bash", LoginShell: true,
+		RequireApproval: true, ApprovalTimeout: "1h",
+	}
+	got := current.MergeSettings(Job{Schedule: "@daily", Command: "true"})
+	if got.Priority != 0 || got.Preempt || got.User != "" || got.Shell != "" || got.LoginShell ||
+		got.RequireApproval || got.ApprovalTimeout != "" {
+		t.Fatalf("settings not cleared: %+v", got)
+	}
+
+	no := false
+	current = &Job{
+		Name: "j", Schedule: "@daily", Command: "true",
+		RunsOn:        map[string]string{"region": "eu"},
+		Sandbox:       &SandboxConfig{ReadOnly: true},
+		LoadGuard:     &LoadGuardConfig{MaxLoad1: 4},
+		LogRetention:  &LogRetentionConfig{MaxRuns: 10},
+		NotifyURLs:    []NotifyConfig{{URL: "https://hooks.example.com"}},
+		SLO:           &SLOConfig{SuccessRate: 99},
+		ExitCodes:     &ExitCodes{Warning: []int{24}},
+		AutoDisable:   &AutoDisableConfig{Failures: 3},
+		Output:        &OutputConfig{StripANSI: true},
+		CaptureOutput: &no,
+		DSTPolicy:     "skip",
+	}
+	got = current.MergeSettings(Job{Schedule: "@daily", Command: "true"})
+	for field, set := range map[string]bool{
+		"runs_on":        got.RunsOn != nil,
+		"sandbox":        got.Sandbox != nil,
+		"load_guard":     got.LoadGuard != nil,
+		"log_retention":  got.LogRetention != nil,
+		"notify_urls":    got.NotifyURLs != nil,
+		"slo":            got.SLO != nil,
+		"exit_codes":     got.ExitCodes != nil,
+		"auto_disable":   got.AutoDisable != nil,
+		"output":         got.Output != nil,
+		"capture_output": got.CaptureOutput != nil,
+		"dst_policy":     got.DSTPolicy != "",
+	} {
+		if set {
+			t.Errorf("%s not cleared", field)
+		}
+	}
+}
+
+func TestPreCheckValidation(t *testing.T) {
+	t.Parallel()
+
+	j := &Job{Name: "j", Schedule: "@daily", Command: "true", PreCheck: "test -f /tmp/ready"}
+	if err := j.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := j.ParsePreCheckTimeout(); d != DefaultPreCheckTimeout {
+		t.Fatalf("default pre_check_timeout = %v", d)
+	}
+	j.PreCheckTimeout = "-1s"
+	if err := j.Validate(); err == nil {
+		t.Fatal("expected an error for a negative pre_check_timeout")
+	}
+	j.PreCheckTimeout, j.Mode = "", ModeService
+	if err := j.Validate(); err == nil {
+		t.Fatal("expected an error for a service job with pre_check")
+	}
+}
+
+func TestParseDays(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]time.Duration{"1d": 24 * time.Hour, "30d": 720 * time.Hour, "90m": 90 * time.Minute} {
+		if got, err := ParseDays(in); err != nil || got != want {
+			t.Errorf("ParseDays(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "d", "1.5d", "0d", "-1h", "soon"} {
+		if _, err := ParseDays(bad); err == nil {
+			t.Errorf("ParseDays(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestWarnAfterValidation(t *testing.T) {
+	t.Parallel()
+
+	j := &Job{Name: "j", Schedule: "@daily", Command: "true", WarnAfter: "5m"}
+	if err := j.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{"-5m", "0s", "soon"} {
+		j.WarnAfter = bad
+		if err := j.Validate(); err == nil {
+			t.Errorf("warn_after %q: expected an error", bad)
+		}
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	t.Parallel()
+
+	j := &Job{Name: "j", Schedule: "@daily", Command: "rsync -a src dst"}
+	if j.ExitStatus(0) != "success" || j.ExitStatus(24) != "failure" {
+		t.Fatal("default exit statuses changed")
+	}
+	j.ExitCodes = &ExitCodes{Success: []int{3}, Warning: []int{24, 1}}
+	if err := j.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for code, want := range map[int]string{0: "success", 1: "warning", 3: "success", 24: "warning", 2: "failure"} {
+		if got := j.ExitStatus(code); got != want {
+			t.Errorf("exit %d = %s, want %s", code, got, want)
+		}
+	}
+
+	for _, bad := range []*ExitCodes{
+		{Warning: []int{256}},
+		{Success: []int{-1}},
+		{Success: []int{1}, Warning: []int{1}},
+	} {
+		j.ExitCodes = bad
+		if err := j.Validate(); err == nil {
+			t.Errorf("exit_codes %+v: expected an error", *bad)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Package crontab builds the command field of crontab entries and parses it
+// back. cron hands the field to /bin/sh after turning every unescaped "%"
+// into a newline, so arguments are shell-quoted and their "%" escaped.
+package crontab
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Quote returns s as a single shell word for a crontab command field.
+// Words made only of safe characters are left bare; anything else is
+// single-quoted. "%" is escaped either way.
+func Quote(s string) string {
+	if s != "" && strings.Trim(s, safeChars) == "" {
+		return strings.ReplaceAll(s, "%", `\%`)
+	}
+	q := "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	return strings.ReplaceAll(q, "%", `\%`)
+}
+
+const safeChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_@%+=:,./-"
+
+// Command quotes args and joins them into a crontab command field. It
+// fails for arguments cron cannot represent: an entry is a single line, so
+// newlines and carriage returns are impossible, and a NUL byte ends it.
+func Command(args ...string) (string, error) {
+	words := make([]string, len(args))
+	for i, arg := range args {
+		if j := strings.IndexAny(arg, "\n\r\x00"); j >= 0 {
+			return "", fmt.Errorf("argument %q: cron cannot represent %q", arg, arg[j:j+1])
+		}
+		words[i] = Quote(arg)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// Unescape returns the command cron passes to the shell for a command
+// field: "\%" becomes "%", and an unescaped "%" ends the command, with
+// the rest going to its standard input. input reports whether there was
+// such a rest.
+func Unescape(field string) (command string, input bool) {
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		switch {
+		case field[i] == '\\' && i+1 < len(field) && field[i+1] == '%':
+			b.WriteByte('%')
+			i++
+		case field[i] == '%':
+			return b.String(), true
+		default:
+			b.WriteByte(field[i])
+		}
+	}
+	return b.String(), false
+}
+
+// Split unescapes a command field and splits it into shell words,
+// honoring single quotes, double quotes and backslashes. It undoes
+// Command, but does not expand variables or globs.
+func Split(field string) ([]string, error) {
+	command, input := Unescape(field)
+	if input {
+		return nil, errors.New("command has an unescaped %: standard input is not supported")
+	}
+
+	var words []string
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+			continue
+		case c == '\'':
+			end := strings.IndexByte(command[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			word.WriteString(command[i+1 : i+1+end])
+			i += end + 1
+		case c == '"':
+			for i++; i < len(command) && command[i] != '"'; i++ {
+				// Inside double quotes a backslash only escapes these.
+				if command[i] == '\\' && i+1 < len(command) && strings.IndexByte("$`\"\\", command[i+1]) >= 0 {
+					i++
+				}
+				word.WriteByte(command[i])
+			}
+			if i >= len(command) {
+				return nil, errors.New("unterminated double quote")
+			}
+		case c == '\\':
+			if i+1 < len(command) {
+				i++
+				word.WriteByte(command[i])
+			}
+		default:
+			word.WriteByte(c)
+		}
+		inWord = true
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
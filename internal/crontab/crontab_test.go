@@ -0,0 +1,66 @@
+package crontab
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCommandRoundTrip(t *testing.T) {
+	args := []string{
+		"/usr/local/bin/cronbat", "wrap", "--name", "backup", "--",
+		`pg_dump "my db" > /backups/$(date +%F).dump && echo 'done'`,
+		"",
+		"50%",
+	}
+	field, err := Command(args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `/usr/local/bin/cronbat wrap --name backup -- 'pg_dump "my db" > /backups/$(date +\%F).dump && echo '\''done'\''' '' 50\%`
+	if field != want {
+		t.Fatalf("Command = %s\nwant      %s", field, want)
+	}
+
+	got, err := Split(field)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, args) {
+		t.Fatalf("Split = %q, want %q", got, args)
+	}
+}
+
+func TestCommandRejectsNewlines(t *testing.T) {
+	for _, arg := range []string{"echo a\necho b", "echo a\r", "a\x00b"} {
+		if _, err := Command("sh", "-c", arg); err == nil {
+			t.Errorf("Command accepted %q", arg)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		field string
+		want  []string
+	}{
+		{`a  b	c`, []string{"a", "b", "c"}},
+		{`"a \"b\" \$c" d\ e`, []string{`a "b" $c`, "d e"}},
+		{`x'y'"z"`, []string{"xyz"}},
+	}
+	for _, tt := range tests {
+		got, err := Split(tt.field)
+		if err != nil {
+			t.Errorf("Split(%s): %v", tt.field, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Split(%s) = %q, want %q", tt.field, got, tt.want)
+		}
+	}
+
+	for _, field := range []string{`echo 'a`, `echo "a`, `mail -s hi root%body`} {
+		if _, err := Split(field); err == nil {
+			t.Errorf("Split(%s) succeeded", field)
+		}
+	}
+}
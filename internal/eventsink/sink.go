@@ -0,0 +1,230 @@
+// Package eventsink posts realtime events to outbound webhooks, so other
+// systems can follow run lifecycles without holding an SSE connection.
+// Deliveries are retried with backoff; events that still cannot be
+// delivered are appended to a dead-letter file.
+package eventsink
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/realtime"
+)
+
+// Request headers. SignatureHeader carries "sha256=" and the hex
+// HMAC-SHA256 of the body, keyed with the sink's secret.
+const (
+	SignatureHeader = "X-Cronbat-Signature"
+	EventHeader     = "X-Cronbat-Event"
+)
+
+// Timeout bounds each delivery attempt.
+const Timeout = 10 * time.Second
+
+// queueSize is how many events may wait for delivery. Events arriving at a
+// full queue go straight to the dead-letter file.
+const queueSize = 4096
+
+// retryBase is the delay before the first retry; it doubles after each
+// attempt. A variable so tests can shorten it.
+var retryBase = time.Second
+
+const maxRetryDelay = time.Minute
+
+// Sink delivers events to one webhook.
+type Sink struct {
+	name   string
+	cfg    config.EventWebhookConfig
+	types  map[string]bool
+	client *http.Client
+	queue  chan realtime.Event
+
+	deadMu sync.Mutex
+}
+
+// New checks a webhook's configuration and returns its sink. name
+// identifies the sink in logs, which never show the URL since it may embed
+// a token.
+func New(name string, cfg config.EventWebhookConfig) (*Sink, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("url must be an http or https URL")
+	}
+	if cfg.MaxRetries < 0 {
+		return nil, errors.New("max_retries must not be negative")
+	}
+	if cfg.DeadLetter == "" {
+		return nil, errors.New("dead_letter is required")
+	}
+	s := &Sink{
+		name:   name,
+		cfg:    cfg,
+		client: &http.Client{Timeout: Timeout},
+		queue:  make(chan realtime.Event, queueSize),
+	}
+	if len(cfg.Events) > 0 {
+		s.types = make(map[string]bool, len(cfg.Events))
+		for _, t := range cfg.Events {
+			s.types[t] = true
+		}
+	}
+	return s, nil
+}
+
+// Run delivers the events read from events, in order, until ctx is done or
+// events is closed.
+func (s *Sink) Run(ctx context.Context, events <-chan realtime.Event) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case evt, ok := <-s.queue:
+				if !ok {
+					return
+				}
+				s.deliver(ctx, evt)
+			}
+		}
+	}()
+
+	// Events are read off the broker promptly, which drops events for
+	// subscribers that fall behind, and wait here for delivery.
+	for evt := range events {
+		if s.types != nil && !s.types[evt.Type] {
+			continue
+		}
+		select {
+		case s.queue <- evt:
+		default:
+			s.deadLetter(evt, 0, errors.New("delivery queue full"))
+		}
+	}
+	close(s.queue)
+	<-done
+}
+
+// deliver posts evt, retrying failed attempts, and dead-letters it once the
+// retries are used up or the endpoint rejects it.
+func (s *Sink) deliver(ctx context.Context, evt realtime.Event) {
+	body, err := json.Marshal(evt)
+	if err != nil {
+		s.deadLetter(evt, 0, err)
+		return
+	}
+	delay := retryBase
+	for attempt := 1; ; attempt++ {
+		retry, err := s.post(ctx, evt.Type, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt > s.cfg.MaxRetries || ctx.Err() != nil {
+			s.deadLetter(evt, attempt, err)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			s.deadLetter(evt, attempt, err)
+			return
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+// post makes one delivery attempt. retry reports whether a failure may be
+// temporary: a network error, 408, 429, or a 5xx status.
+func (s *Sink) post(ctx context.Context, eventType string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, errors.New("invalid webhook URL")
+	}
+	for k, v := range s.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, eventType)
+	if s.cfg.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(s.cfg.Secret, body))
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// Drop the URL from the error; it may embed a token.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return true, urlErr.Err
+		}
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout
+	return retry, fmt.Errorf("unexpected status %s", resp.Status)
+}
+
+// Sign returns the SignatureHeader value for body.
+func Sign(secret string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write(body)
+	return "sha256=" + hex.EncodeToString(h.Sum(nil))
+}
+
+// deadLetterEntry is one line of the dead-letter file.
+type deadLetterEntry struct {
+	At       time.Time      `json:"at"`
+	Sink     string         `json:"sink"`
+	Attempts int            `json:"attempts"`
+	Error    string         `json:"error"`
+	Event    realtime.Event `json:"event"`
+}
+
+func (s *Sink) deadLetter(evt realtime.Event, attempts int, cause error) {
+	log.Printf("WARN: %s gave up on %s event %d after %d attempts: %v", s.name, evt.Type, evt.ID, attempts, cause)
+	line, err := json.Marshal(deadLetterEntry{
+		At:       time.Now().UTC(),
+		Sink:     s.name,
+		Attempts: attempts,
+		Error:    cause.Error(),
+		Event:    evt,
+	})
+	if err != nil {
+		return
+	}
+	s.deadMu.Lock()
+	defer s.deadMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(s.cfg.DeadLetter), 0755); err != nil {
+		log.Printf("ERROR: %s failed to write dead letter: %v", s.name, err)
+		return
+	}
+	f, err := os.OpenFile(s.cfg.DeadLetter, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Printf("ERROR: %s failed to write dead letter: %v", s.name, err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		log.Printf("ERROR: %s failed to write dead letter: %v", s.name, err)
+	}
+}
@@ -0,0 +1,99 @@
+package eventsink
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/realtime"
+)
+
+func TestSinkDelivery(t *testing.T) {
+	retryBase = time.Millisecond
+
+	var mu sync.Mutex
+	var got []realtime.Event
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(SignatureHeader) != Sign("s3cret", body) {
+			t.Errorf("bad signature %q", r.Header.Get(SignatureHeader))
+		}
+		if r.Header.Get("X-Team") != "ops" {
+			t.Errorf("configured header missing")
+		}
+		var evt realtime.Event
+		json.Unmarshal(body, &evt)
+		mu.Lock()
+		defer mu.Unlock()
+		switch evt.JobName {
+		case "flaky":
+			if attempts++; attempts == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		case "rejected":
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		got = append(got, evt)
+	}))
+	defer srv.Close()
+
+	deadLetter := filepath.Join(t.TempDir(), "dead.jsonl")
+	sink, err := New("test", config.EventWebhookConfig{
+		URL:        srv.URL,
+		Headers:    map[string]string{"X-Team": "ops"},
+		Secret:     "s3cret",
+		Events:     []string{"run.completed"},
+		MaxRetries: 2,
+		DeadLetter: deadLetter,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan realtime.Event, 4)
+	events <- realtime.Event{ID: 1, Type: "run.started", JobName: "flaky"}
+	events <- realtime.Event{ID: 2, Type: "run.completed", JobName: "flaky"}
+	events <- realtime.Event{ID: 3, Type: "run.completed", JobName: "rejected"}
+	events <- realtime.Event{ID: 4, Type: "run.completed", JobName: "ok"}
+	close(events)
+	sink.Run(context.Background(), events)
+
+	if len(got) != 2 || got[0].ID != 2 || got[1].ID != 4 {
+		t.Fatalf("delivered %+v, want events 2 and 4", got)
+	}
+	if attempts != 2 {
+		t.Fatalf("flaky event took %d attempts, want 2", attempts)
+	}
+	data, err := os.ReadFile(deadLetter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var entry deadLetterEntry
+	if len(lines) != 1 || json.Unmarshal([]byte(lines[0]), &entry) != nil || entry.Event.ID != 3 || entry.Attempts != 1 {
+		t.Fatalf("dead letters = %q, want only event 3 after 1 attempt", data)
+	}
+}
+
+func TestNewRejectsBadConfig(t *testing.T) {
+	for _, cfg := range []config.EventWebhookConfig{
+		{URL: "ftp://example.com", DeadLetter: "x"},
+		{URL: "https://example.com", DeadLetter: "x", MaxRetries: -1},
+		{URL: "https://example.com"},
+	} {
+		if _, err := New("test", cfg); err == nil {
+			t.Errorf("New(%+v) accepted", cfg)
+		}
+	}
+}
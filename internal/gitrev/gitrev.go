@@ -0,0 +1,113 @@
+// Package gitrev resolves the HEAD commit of a git working tree without
+// shelling out to git.
+package gitrev
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotRepo is returned when dir is not inside a git working tree.
+var ErrNotRepo = errors.New("not a git repository")
+
+// Head returns the full commit hash checked out in the working tree that
+// contains dir. It walks up parent directories to find .git, and follows
+// symbolic refs, loose refs, and packed-refs.
+func Head(dir string) (string, error) {
+	gitDir, err := findGitDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	head, err := readTrimmed(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(head, "ref: ") {
+		// Detached HEAD.
+		return head, nil
+	}
+	ref := strings.TrimSpace(strings.TrimPrefix(head, "ref: "))
+
+	// Linked worktrees keep shared refs in the common dir.
+	dirs := []string{gitDir}
+	if common, err := readTrimmed(filepath.Join(gitDir, "commondir")); err == nil {
+		if !filepath.IsAbs(common) {
+			common = filepath.Join(gitDir, common)
+		}
+		dirs = append(dirs, common)
+	}
+
+	for _, d := range dirs {
+		if hash, err := readTrimmed(filepath.Join(d, filepath.FromSlash(ref))); err == nil {
+			return hash, nil
+		}
+		if hash, ok := lookupPackedRef(filepath.Join(d, "packed-refs"), ref); ok {
+			return hash, nil
+		}
+	}
+	return "", errors.New("unresolved ref " + ref)
+}
+
+func findGitDir(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		candidate := filepath.Join(abs, ".git")
+		fi, err := os.Stat(candidate)
+		if err == nil {
+			if fi.IsDir() {
+				return candidate, nil
+			}
+			// Worktrees and submodules use a ".git" file pointing elsewhere.
+			content, err := readTrimmed(candidate)
+			if err != nil {
+				return "", err
+			}
+			gitDir := strings.TrimSpace(strings.TrimPrefix(content, "gitdir:"))
+			if !filepath.IsAbs(gitDir) {
+				gitDir = filepath.Join(abs, gitDir)
+			}
+			return gitDir, nil
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return "", ErrNotRepo
+		}
+		abs = parent
+	}
+}
+
+func lookupPackedRef(path, ref string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' || line[0] == '^' {
+			continue
+		}
+		hash, name, ok := strings.Cut(line, " ")
+		if ok && name == ref {
+			return hash, true
+		}
+	}
+	return "", false
+}
+
+func readTrimmed(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,47 @@
+package gitrev
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHeadResolvesLooseAndPackedRefs(t *testing.T) {
+	root := t.TempDir()
+	jobs := filepath.Join(root, "jobs")
+	if err := os.MkdirAll(jobs, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(root, ".git", "HEAD"), "ref: refs/heads/main\n")
+	writeFile(t, filepath.Join(root, ".git", "packed-refs"),
+		"# pack-refs with: peeled\naaaa111 refs/heads/main\n^bbbb222\n")
+
+	got, err := Head(jobs)
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+	if got != "aaaa111" {
+		t.Fatalf("packed ref: got %q", got)
+	}
+
+	writeFile(t, filepath.Join(root, ".git", "refs", "heads", "main"), "cccc333\n")
+	if got, _ := Head(jobs); got != "cccc333" {
+		t.Fatalf("loose ref should win over packed-refs: got %q", got)
+	}
+}
+
+func TestHeadNotRepo(t *testing.T) {
+	if _, err := Head(t.TempDir()); err == nil {
+		t.Fatal("expected error outside a repository")
+	}
+}
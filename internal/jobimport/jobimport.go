@@ -0,0 +1,246 @@
+// Package jobimport converts the job definitions of other schedulers into
+// cronbat jobs: Kubernetes CronJob manifests and docker-compose services
+// labeled with a cronbat schedule. cronbat has no container executor, so
+// the imported jobs use the shell executor and run their container
+// through the docker CLI.
+package jobimport
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+type k8sCronJob struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Schedule          string `yaml:"schedule"`
+		TimeZone          string `yaml:"timeZone"`
+		Suspend           bool   `yaml:"suspend"`
+		ConcurrencyPolicy string `yaml:"concurrencyPolicy"`
+		JobTemplate       struct {
+			Spec struct {
+				ActiveDeadlineSeconds int64 `yaml:"activeDeadlineSeconds"`
+				Template              struct {
+					Spec struct {
+						Containers []k8sContainer `yaml:"containers"`
+					} `yaml:"spec"`
+				} `yaml:"template"`
+			} `yaml:"spec"`
+		} `yaml:"jobTemplate"`
+	} `yaml:"spec"`
+}
+
+type k8sContainer struct {
+	Image      string   `yaml:"image"`
+	Command    []string `yaml:"command"`
+	Args       []string `yaml:"args"`
+	WorkingDir string   `yaml:"workingDir"`
+	Env        []struct {
+		Name      string     `yaml:"name"`
+		Value     string     `yaml:"value"`
+		ValueFrom *yaml.Node `yaml:"valueFrom"`
+	} `yaml:"env"`
+}
+
+// Kubernetes returns the jobs of the CronJobs in a multi-document manifest.
+// Other kinds are skipped. Each job runs the first container of its pod
+// with "docker run"; env values become job env, passed through by name.
+// Settings with no cronbat equivalent are reported as warnings.
+func Kubernetes(data []byte) (jobs []*config.Job, warnings []string, err error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var cj k8sCronJob
+		if err := dec.Decode(&cj); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, nil, fmt.Errorf("parse manifest: %w", err)
+		}
+		if cj.Kind != "CronJob" {
+			continue
+		}
+		name := cj.Metadata.Name
+		warn := func(format string, args ...any) {
+			warnings = append(warnings, "cronjob "+name+": "+fmt.Sprintf(format, args...))
+		}
+
+		containers := cj.Spec.JobTemplate.Spec.Template.Spec.Containers
+		if name == "" || cj.Spec.Schedule == "" || len(containers) == 0 || containers[0].Image == "" {
+			warn("skipped: name, schedule, or container image missing")
+			continue
+		}
+		if len(containers) > 1 {
+			warn("only the first of %d containers is imported", len(containers))
+		}
+		if cj.Spec.TimeZone != "" {
+			warn("timeZone %s is not imported; the schedule runs in the daemon's time zone", cj.Spec.TimeZone)
+		}
+		if p := cj.Spec.ConcurrencyPolicy; p != "" && p != "Allow" {
+			warn("concurrencyPolicy %s is not imported", p)
+		}
+
+		c := containers[0]
+		job := &config.Job{
+			Name:     name,
+			Schedule: cj.Spec.Schedule,
+			Executor: "shell",
+			Metadata: map[string]any{"imported_from": "kubernetes"},
+		}
+		if cj.Metadata.Namespace != "" {
+			job.Metadata["namespace"] = cj.Metadata.Namespace
+		}
+		if cj.Spec.Suspend {
+			enabled := false
+			job.Enabled = &enabled
+		}
+		if secs := cj.Spec.JobTemplate.Spec.ActiveDeadlineSeconds; secs > 0 {
+			job.Timeout = strconv.FormatInt(secs, 10) + "s"
+		}
+
+		argv := []string{"docker", "run", "--rm"}
+		for _, e := range c.Env {
+			if e.ValueFrom != nil {
+				warn("env %s uses valueFrom and is not imported", e.Name)
+				continue
+			}
+			if job.Env == nil {
+				job.Env = make(map[string]string)
+			}
+			job.Env[e.Name] = e.Value
+			argv = append(argv, "-e", e.Name)
+		}
+		if c.WorkingDir != "" {
+			argv = append(argv, "-w", c.WorkingDir)
+		}
+		// A container command replaces the image entrypoint; docker takes
+		// only its first element as --entrypoint.
+		if len(c.Command) > 0 {
+			argv = append(argv, "--entrypoint", c.Command[0])
+		}
+		argv = append(argv, c.Image)
+		if len(c.Command) > 1 {
+			argv = append(argv, c.Command[1:]...)
+		}
+		argv = append(argv, c.Args...)
+		job.Command = shellJoin(argv)
+		jobs = append(jobs, job)
+	}
+	return jobs, warnings, nil
+}
+
+// Compose label keys. Services without a cronbat.schedule label are not
+// imported.
+const (
+	LabelSchedule = "cronbat.schedule"
+	LabelName     = "cronbat.name"
+	LabelCommand  = "cronbat.command"
+	LabelTimeout  = "cronbat.timeout"
+	LabelEnabled  = "cronbat.enabled"
+)
+
+type composeProject struct {
+	Services map[string]struct {
+		Labels yaml.Node `yaml:"labels"`
+	} `yaml:"services"`
+}
+
+// Compose returns the jobs of the services in a docker-compose file that
+// carry a cronbat.schedule label. Each job runs its service with
+// "docker compose run"; composePath is the file the commands refer to.
+func Compose(data []byte, composePath string) (jobs []*config.Job, warnings []string, err error) {
+	var project composeProject
+	if err := yaml.Unmarshal(data, &project); err != nil {
+		return nil, nil, fmt.Errorf("parse compose file: %w", err)
+	}
+	services := make([]string, 0, len(project.Services))
+	for name := range project.Services {
+		services = append(services, name)
+	}
+	sort.Strings(services)
+
+	for _, service := range services {
+		labels, err := composeLabels(project.Services[service].Labels)
+		if err != nil {
+			return nil, nil, fmt.Errorf("service %s: %w", service, err)
+		}
+		schedule := labels[LabelSchedule]
+		if schedule == "" {
+			continue
+		}
+		job := &config.Job{
+			Name:     service,
+			Schedule: schedule,
+			Executor: "shell",
+			Timeout:  labels[LabelTimeout],
+			Metadata: map[string]any{"imported_from": "compose", "service": service},
+		}
+		if name := labels[LabelName]; name != "" {
+			job.Name = name
+		}
+		if v := labels[LabelEnabled]; v != "" {
+			enabled, err := strconv.ParseBool(v)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("service %s: invalid %s %q ignored", service, LabelEnabled, v))
+			} else {
+				job.Enabled = &enabled
+			}
+		}
+		job.Command = shellJoin([]string{"docker", "compose", "-f", composePath, "run", "--rm", service})
+		// The command label is shell text, appended as written.
+		if cmd := labels[LabelCommand]; cmd != "" {
+			job.Command += " " + cmd
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, warnings, nil
+}
+
+// composeLabels decodes service labels, given either as a map or as a list
+// of key=value strings.
+func composeLabels(node yaml.Node) (map[string]string, error) {
+	labels := make(map[string]string)
+	switch node.Kind {
+	case 0:
+	case yaml.MappingNode:
+		if err := node.Decode(&labels); err != nil {
+			return nil, fmt.Errorf("labels: %w", err)
+		}
+	case yaml.SequenceNode:
+		var list []string
+		if err := node.Decode(&list); err != nil {
+			return nil, fmt.Errorf("labels: %w", err)
+		}
+		for _, item := range list {
+			key, value, _ := strings.Cut(item, "=")
+			labels[key] = value
+		}
+	default:
+		return nil, errors.New("labels must be a map or a list")
+	}
+	return labels, nil
+}
+
+// shellJoin quotes argv for sh, leaving words of safe characters bare.
+func shellJoin(argv []string) string {
+	words := make([]string, len(argv))
+	for i, arg := range argv {
+		if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_@%+=:,./-") == "" {
+			words[i] = arg
+		} else {
+			words[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,95 @@
+package jobimport
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKubernetes(t *testing.T) {
+	manifest := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: ignored
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: report
+  namespace: analytics
+spec:
+  schedule: "30 2 * * *"
+  suspend: true
+  concurrencyPolicy: Forbid
+  jobTemplate:
+    spec:
+      activeDeadlineSeconds: 600
+      template:
+        spec:
+          containers:
+            - name: main
+              image: example/report:1.2
+              command: ["python", "-m", "report"]
+              args: ["--since", "last week"]
+              env:
+                - name: MODE
+                  value: full
+                - name: TOKEN
+                  valueFrom:
+                    secretKeyRef: {name: s, key: token}
+`
+	jobs, warnings, err := Kubernetes([]byte(manifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("got %d jobs, want 1", len(jobs))
+	}
+	j := jobs[0]
+	want := `docker run --rm -e MODE --entrypoint python example/report:1.2 -m report --since 'last week'`
+	if j.Name != "report" || j.Schedule != "30 2 * * *" || j.Command != want {
+		t.Fatalf("unexpected job: %+v", j)
+	}
+	if j.IsEnabled() || j.Timeout != "600s" || j.Env["MODE"] != "full" || j.Metadata["namespace"] != "analytics" {
+		t.Fatalf("unexpected job settings: %+v", j)
+	}
+	if len(warnings) != 2 {
+		t.Fatalf("want warnings for concurrencyPolicy and valueFrom, got %q", warnings)
+	}
+}
+
+func TestCompose(t *testing.T) {
+	compose := `
+services:
+  web:
+    image: nginx
+  cleanup:
+    image: example/tools
+    labels:
+      cronbat.schedule: "0 * * * *"
+      cronbat.command: prune --older-than 7d
+  backup:
+    image: example/backup
+    labels:
+      - cronbat.schedule=every day at 3am
+      - cronbat.name=nightly-backup
+      - cronbat.timeout=1h
+`
+	jobs, _, err := Compose([]byte(compose), "/srv/app/docker-compose.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("got %d jobs, want 2", len(jobs))
+	}
+	backup, cleanup := jobs[0], jobs[1]
+	if backup.Name != "nightly-backup" || backup.Schedule != "every day at 3am" || backup.Timeout != "1h" {
+		t.Fatalf("unexpected backup job: %+v", backup)
+	}
+	if backup.Command != "docker compose -f /srv/app/docker-compose.yml run --rm backup" {
+		t.Fatalf("backup command = %s", backup.Command)
+	}
+	if !strings.HasSuffix(cleanup.Command, "run --rm cleanup prune --older-than 7d") {
+		t.Fatalf("cleanup command = %s", cleanup.Command)
+	}
+}
@@ -0,0 +1,231 @@
+// Package lint checks job definitions against the house rules in the lint
+// config block: timeout caps, required tags and metadata, forbidden
+// schedules, and naming conventions. Each rule reports at its own level, so
+// a team can warn about a rule before enforcing it.
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/scheduler"
+)
+
+// Violation levels.
+const (
+	LevelWarning = "warning"
+	LevelError   = "error"
+)
+
+// Rule names, as in the lint config block.
+const (
+	RuleMaxTimeout         = "max_timeout"
+	RuleRequiredTags       = "required_tags"
+	RuleRequiredMetadata   = "required_metadata"
+	RuleForbiddenSchedules = "forbidden_schedules"
+	RuleNamePattern        = "name_pattern"
+)
+
+// Violation is a rule a job breaks.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	return v.Rule + ": " + v.Message
+}
+
+// Linter checks jobs against the configured rules.
+type Linter struct {
+	defaultTimeout time.Duration
+
+	maxTimeout      time.Duration
+	maxTimeoutLevel string
+
+	requiredTags      []string
+	requiredTagsLevel string
+
+	requiredKeys      []string
+	requiredKeysLevel string
+
+	forbidden      map[string]string // normalized schedule -> as configured
+	forbiddenLevel string
+
+	namePattern      *regexp.Regexp
+	namePatternLevel string
+}
+
+// New compiles a lint block. defaultTimeout is the defaults.timeout jobs
+// without their own fall back to. It returns nil when no rule is set.
+func New(cfg config.LintConfig, defaultTimeout time.Duration) (*Linter, error) {
+	l := &Linter{defaultTimeout: defaultTimeout}
+	set := false
+	if r := cfg.MaxTimeout; r != nil {
+		level, err := ruleLevel(RuleMaxTimeout, r)
+		if err != nil {
+			return nil, err
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(r.Max))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%s: max must be a positive duration", RuleMaxTimeout)
+		}
+		l.maxTimeout, l.maxTimeoutLevel, set = d, level, true
+	}
+	if r := cfg.RequiredTags; r != nil {
+		level, err := ruleLevel(RuleRequiredTags, r)
+		if err != nil {
+			return nil, err
+		}
+		l.requiredTags, l.requiredTagsLevel, set = r.Tags, level, true
+	}
+	if r := cfg.RequiredMetadata; r != nil {
+		level, err := ruleLevel(RuleRequiredMetadata, r)
+		if err != nil {
+			return nil, err
+		}
+		if len(r.Keys) == 0 {
+			return nil, fmt.Errorf("%s: keys is required", RuleRequiredMetadata)
+		}
+		l.requiredKeys, l.requiredKeysLevel, set = r.Keys, level, true
+	}
+	if r := cfg.ForbiddenSchedules; r != nil {
+		level, err := ruleLevel(RuleForbiddenSchedules, r)
+		if err != nil {
+			return nil, err
+		}
+		if len(r.Schedules) == 0 {
+			return nil, fmt.Errorf("%s: schedules is required", RuleForbiddenSchedules)
+		}
+		l.forbidden = make(map[string]string, len(r.Schedules))
+		for _, s := range r.Schedules {
+			expr, err := scheduler.Normalize(strings.TrimSpace(s))
+			if err != nil {
+				return nil, fmt.Errorf("%s: schedule %q: %w", RuleForbiddenSchedules, s, err)
+			}
+			l.forbidden[canonicalCron(expr)] = s
+		}
+		l.forbiddenLevel, set = level, true
+	}
+	if r := cfg.NamePattern; r != nil {
+		level, err := ruleLevel(RuleNamePattern, r)
+		if err != nil {
+			return nil, err
+		}
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil || r.Pattern == "" {
+			return nil, fmt.Errorf("%s: pattern must be a regular expression", RuleNamePattern)
+		}
+		l.namePattern, l.namePatternLevel, set = re, level, true
+	}
+	if !set {
+		return nil, nil
+	}
+	return l, nil
+}
+
+func ruleLevel(rule string, r *config.LintRuleConfig) (string, error) {
+	switch strings.TrimSpace(r.Level) {
+	case "", LevelWarning:
+		return LevelWarning, nil
+	case LevelError:
+		return LevelError, nil
+	default:
+		return "", fmt.Errorf("%s: level must be %q or %q", rule, LevelWarning, LevelError)
+	}
+}
+
+// Check returns the rules j breaks. A nil Linter checks nothing.
+func (l *Linter) Check(j *config.Job) []Violation {
+	if l == nil {
+		return nil
+	}
+	var vs []Violation
+	add := func(rule, level, format string, args ...any) {
+		vs = append(vs, Violation{Rule: rule, Level: level, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if l.maxTimeoutLevel != "" && !j.IsService() {
+		if timeout, err := j.EffectiveTimeout(l.defaultTimeout); err == nil {
+			switch {
+			case timeout == 0:
+				add(RuleMaxTimeout, l.maxTimeoutLevel, "job has no timeout; at most %s is allowed", l.maxTimeout)
+			case timeout > l.maxTimeout:
+				add(RuleMaxTimeout, l.maxTimeoutLevel, "timeout %s exceeds the maximum of %s", timeout, l.maxTimeout)
+			}
+		}
+	}
+	if l.requiredTagsLevel != "" {
+		if len(l.requiredTags) == 0 && len(j.Tags) == 0 {
+			add(RuleRequiredTags, l.requiredTagsLevel, "job has no tags")
+		}
+		for _, tag := range l.requiredTags {
+			if !hasTag(j, tag) {
+				add(RuleRequiredTags, l.requiredTagsLevel, "tag %q is required", tag)
+			}
+		}
+	}
+	for _, key := range l.requiredKeys {
+		if v, ok := j.Metadata[key]; !ok || v == nil || fmt.Sprint(v) == "" {
+			add(RuleRequiredMetadata, l.requiredKeysLevel, "metadata %q is required", key)
+		}
+	}
+	if l.forbidden != nil && j.Schedule != "" && !j.IsOneShot() {
+		if expr, err := scheduler.Normalize(j.Schedule); err == nil {
+			if s, ok := l.forbidden[canonicalCron(expr)]; ok && s == j.Schedule {
+				add(RuleForbiddenSchedules, l.forbiddenLevel, "schedule %q is not allowed", j.Schedule)
+			} else if ok {
+				add(RuleForbiddenSchedules, l.forbiddenLevel, "schedule %q is not allowed (%s)", j.Schedule, s)
+			}
+		}
+	}
+	if l.namePattern != nil && !l.namePattern.MatchString(j.Name) {
+		add(RuleNamePattern, l.namePatternLevel, "name %q does not match %s", j.Name, l.namePattern)
+	}
+	return vs
+}
+
+// Err returns the error-level violations as one error, or nil when there
+// are none.
+func Err(vs []Violation) error {
+	var msgs []string
+	for _, v := range vs {
+		if v.Level == LevelError {
+			msgs = append(msgs, v.String())
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return errors.New("lint: " + strings.Join(msgs, "; "))
+}
+
+// Warnings returns the warning-level violations as strings.
+func Warnings(vs []Violation) []string {
+	var out []string
+	for _, v := range vs {
+		if v.Level == LevelWarning {
+			out = append(out, v.String())
+		}
+	}
+	return out
+}
+
+func hasTag(j *config.Job, tag string) bool {
+	for _, t := range j.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// canonicalCron collapses whitespace so equivalent spellings compare equal.
+func canonicalCron(expr string) string {
+	return strings.Join(strings.Fields(expr), " ")
+}
@@ -0,0 +1,78 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+)
+
+func TestCheck(t *testing.T) {
+	l, err := New(config.LintConfig{
+		MaxTimeout:         &config.LintRuleConfig{Max: "1h", Level: LevelError},
+		RequiredTags:       &config.LintRuleConfig{Tags: []string{"team"}},
+		RequiredMetadata:   &config.LintRuleConfig{Keys: []string{"owner"}, Level: LevelError},
+		ForbiddenSchedules: &config.LintRuleConfig{Schedules: []string{"* * * * *"}, Level: LevelError},
+		NamePattern:        &config.LintRuleConfig{Pattern: `^[a-z][a-z0-9-]*$`},
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	good := &config.Job{
+		Name:     "nightly-report",
+		Schedule: "0 2 * * *",
+		Timeout:  "30m",
+		Tags:     []string{"team"},
+		Metadata: map[string]any{"owner": "data"},
+	}
+	if vs := l.Check(good); len(vs) != 0 {
+		t.Fatalf("good job has violations: %v", vs)
+	}
+
+	bad := &config.Job{Name: "Report_1", Schedule: "every minute"}
+	vs := l.Check(bad)
+	rules := make(map[string]string)
+	for _, v := range vs {
+		rules[v.Rule] = v.Level
+	}
+	want := map[string]string{
+		RuleMaxTimeout:         LevelError,
+		RuleRequiredTags:       LevelWarning,
+		RuleRequiredMetadata:   LevelError,
+		RuleForbiddenSchedules: LevelError,
+		RuleNamePattern:        LevelWarning,
+	}
+	for rule, level := range want {
+		if rules[rule] != level {
+			t.Errorf("rule %s: level %q, want %q (violations %v)", rule, rules[rule], level, vs)
+		}
+	}
+	if err := Err(vs); err == nil || !strings.HasPrefix(err.Error(), "lint: ") {
+		t.Fatalf("Err = %v", err)
+	}
+	if w := Warnings(vs); len(w) != 2 {
+		t.Fatalf("Warnings = %q", w)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if l, err := New(config.LintConfig{}, time.Hour); err != nil || l != nil {
+		t.Fatalf("empty lint block = %v, %v; want nil", l, err)
+	}
+	var none *Linter
+	if vs := none.Check(&config.Job{Name: "x"}); vs != nil {
+		t.Fatalf("nil linter reported %v", vs)
+	}
+	for _, cfg := range []config.LintConfig{
+		{MaxTimeout: &config.LintRuleConfig{Max: "soon"}},
+		{RequiredMetadata: &config.LintRuleConfig{}},
+		{NamePattern: &config.LintRuleConfig{Pattern: "("}},
+		{RequiredTags: &config.LintRuleConfig{Level: "fatal"}},
+	} {
+		if _, err := New(cfg, 0); err == nil {
+			t.Errorf("New(%+v) succeeded", cfg)
+		}
+	}
+}
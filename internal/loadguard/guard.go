@@ -0,0 +1,61 @@
+package loadguard
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+)
+
+// Sample is a point-in-time view of host pressure. Fields that could not be
+// read on this platform are left negative.
+type Sample struct {
+	Load1        float64
+	FreeMemoryMB int64
+	FreeDiskMB   int64
+}
+
+// Check samples the host and returns a non-empty reason when any configured
+// threshold is exceeded. Thresholds left at zero are not checked, and metrics
+// that are unavailable on this platform never block a run.
+func Check(g config.LoadGuardConfig) (string, error) {
+	if !g.Active() {
+		return "", nil
+	}
+	sample, err := Read(g.DiskPath)
+	if err != nil {
+		return "", err
+	}
+	return Evaluate(g, sample), nil
+}
+
+// Evaluate compares a sample against thresholds and returns the first
+// violation, or "" when the host is within limits.
+func Evaluate(g config.LoadGuardConfig, s Sample) string {
+	if g.MaxLoad1 > 0 && s.Load1 >= 0 && s.Load1 > g.MaxLoad1 {
+		return fmt.Sprintf("load average %.2f exceeds %.2f", s.Load1, g.MaxLoad1)
+	}
+	if g.MinFreeMemoryMB > 0 && s.FreeMemoryMB >= 0 && s.FreeMemoryMB < g.MinFreeMemoryMB {
+		return fmt.Sprintf("free memory %dMB below %dMB", s.FreeMemoryMB, g.MinFreeMemoryMB)
+	}
+	if g.MinFreeDiskMB > 0 && s.FreeDiskMB >= 0 && s.FreeDiskMB < g.MinFreeDiskMB {
+		return fmt.Sprintf("free disk %dMB below %dMB", s.FreeDiskMB, g.MinFreeDiskMB)
+	}
+	return ""
+}
+
+// Backoff returns the delay before the given retry attempt (1-based),
+// doubling from base and capped at one hour.
+func Backoff(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		base = time.Minute
+	}
+	d := base
+	for i := 1; i < attempt && d < time.Hour; i++ {
+		d *= 2
+	}
+	if d > time.Hour {
+		d = time.Hour
+	}
+	return d
+}
@@ -0,0 +1,122 @@
+package loadguard
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+)
+
+func TestEvaluate(t *testing.T) {
+	t.Parallel()
+
+	guard := config.LoadGuardConfig{MaxLoad1: 4, MinFreeMemoryMB: 512, MinFreeDiskMB: 1024}
+
+	tests := []struct {
+		name   string
+		guard  config.LoadGuardConfig
+		sample Sample
+		want   string // substring of the reason; "" means no violation
+	}{
+		{"within limits", guard, Sample{Load1: 1, FreeMemoryMB: 2048, FreeDiskMB: 4096}, ""},
+		{"load too high", guard, Sample{Load1: 4.5, FreeMemoryMB: 2048, FreeDiskMB: 4096}, "load average 4.50"},
+		{"memory too low", guard, Sample{Load1: 1, FreeMemoryMB: 100, FreeDiskMB: 4096}, "free memory 100MB"},
+		{"disk too low", guard, Sample{Load1: 1, FreeMemoryMB: 2048, FreeDiskMB: 10}, "free disk 10MB"},
+		{"load checked first", guard, Sample{Load1: 9, FreeMemoryMB: 1, FreeDiskMB: 1}, "load average"},
+		{"unavailable metrics never block", guard, Sample{Load1: -1, FreeMemoryMB: -1, FreeDiskMB: -1}, ""},
+		{"zero thresholds not checked", config.LoadGuardConfig{}, Sample{Load1: 100}, ""},
+	}
+	for _, tt := range tests {
+		got := Evaluate(tt.guard, tt.sample)
+		if tt.want == "" && got != "" {
+			t.Errorf("%s: Evaluate = %q, want no violation", tt.name, got)
+		}
+		if tt.want != "" && !strings.Contains(got, tt.want) {
+			t.Errorf("%s: Evaluate = %q, want it to contain %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		base    time.Duration
+		attempt int
+		want    time.Duration
+	}{
+		{time.Minute, 1, time.Minute},
+		{time.Minute, 2, 2 * time.Minute},
+		{time.Minute, 4, 8 * time.Minute},
+		{time.Minute, 10, time.Hour},
+		{0, 1, time.Minute},
+		{-time.Second, 2, 2 * time.Minute},
+		{2 * time.Hour, 1, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := Backoff(tt.base, tt.attempt); got != tt.want {
+			t.Errorf("Backoff(%s, %d) = %s, want %s", tt.base, tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	global := config.LoadGuardConfig{
+		MaxLoad1:   4,
+		DiskPath:   "/var/lib/cronbat",
+		Action:     "defer",
+		RetryAfter: "1m",
+		MaxRetries: 5,
+	}
+
+	tests := []struct {
+		name     string
+		override *config.LoadGuardConfig
+		want     config.LoadGuardConfig
+	}{
+		{"nil override", nil, global},
+		{"empty override", &config.LoadGuardConfig{}, global},
+		{
+			"fields applied",
+			&config.LoadGuardConfig{MaxLoad1: 8, MinFreeDiskMB: 100, Action: "skip"},
+			config.LoadGuardConfig{MaxLoad1: 8, MinFreeDiskMB: 100, DiskPath: "/var/lib/cronbat", Action: "skip", RetryAfter: "1m", MaxRetries: 5},
+		},
+		{
+			"retry settings applied",
+			&config.LoadGuardConfig{RetryAfter: "30s", MaxRetries: 2, DiskPath: "/data"},
+			config.LoadGuardConfig{MaxLoad1: 4, DiskPath: "/data", Action: "defer", RetryAfter: "30s", MaxRetries: 2},
+		},
+	}
+	for _, tt := range tests {
+		if got := global.Merge(tt.override); got != tt.want {
+			t.Errorf("%s: Merge = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		guard   *config.LoadGuardConfig
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"empty", &config.LoadGuardConfig{}, false},
+		{"defer", &config.LoadGuardConfig{Action: "defer", RetryAfter: "30s"}, false},
+		{"skip", &config.LoadGuardConfig{Action: "skip"}, false},
+		{"typo in action", &config.LoadGuardConfig{Action: "skipp"}, true},
+		{"bad retry_after", &config.LoadGuardConfig{RetryAfter: "soon"}, true},
+		{"zero retry_after", &config.LoadGuardConfig{RetryAfter: "0s"}, true},
+		{"negative max_retries", &config.LoadGuardConfig{MaxRetries: -1}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.guard.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
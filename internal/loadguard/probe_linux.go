@@ -0,0 +1,48 @@
+package loadguard
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Read samples load average and available memory from /proc and free disk
+// space for diskPath. An empty diskPath skips the disk check.
+func Read(diskPath string) (Sample, error) {
+	s := Sample{Load1: -1, FreeMemoryMB: -1, FreeDiskMB: -1}
+
+	if data, err := os.ReadFile("/proc/loadavg"); err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) > 0 {
+			if v, err := strconv.ParseFloat(fields[0], 64); err == nil {
+				s.Load1 = v
+			}
+		}
+	}
+
+	if f, err := os.Open("/proc/meminfo"); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 2 && fields[0] == "MemAvailable:" {
+				if kb, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+					s.FreeMemoryMB = kb / 1024
+				}
+				break
+			}
+		}
+		f.Close()
+	}
+
+	if diskPath != "" {
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(diskPath, &st); err != nil {
+			return s, err
+		}
+		s.FreeDiskMB = int64(st.Bavail) * int64(st.Bsize) / (1024 * 1024)
+	}
+
+	return s, nil
+}
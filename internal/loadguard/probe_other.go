@@ -0,0 +1,9 @@
+//go:build !linux
+
+package loadguard
+
+// Read is not implemented on this platform; every metric is reported as
+// unavailable so the guard never blocks runs.
+func Read(diskPath string) (Sample, error) {
+	return Sample{Load1: -1, FreeMemoryMB: -1, FreeDiskMB: -1}, nil
+}
@@ -0,0 +1,367 @@
+// Package logship forwards the logs of finished runs to Grafana Loki and
+// to remote syslog servers, labelled with the job, run ID, and status, so
+// run output lands in existing log aggregation. Shipping is best effort:
+// runs are queued, and logs that fail to ship are dropped with a warning.
+package logship
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+)
+
+// Timeout bounds shipping one run's logs to each destination.
+const Timeout = 30 * time.Second
+
+// DefaultMaxBytes is how much of each stream is shipped unless max_bytes
+// says otherwise.
+const DefaultMaxBytes = 1 << 20
+
+// maxLine bounds one shipped line; longer lines are cut.
+const maxLine = 8 * 1024
+
+// queueSize is how many finished runs may wait to be shipped. Runs
+// finishing while it is full are not shipped.
+const queueSize = 1024
+
+// Run is a finished run whose logs are shipped.
+type Run struct {
+	JobName   string
+	RunID     string
+	Status    string
+	StartedAt time.Time
+	// StdoutPath and StderrPath are the persisted log files; a stream
+	// without one ships its tail instead.
+	StdoutPath string
+	StderrPath string
+	StdoutTail string
+	StderrTail string
+}
+
+// stream is one of a run's output streams, split into lines.
+type stream struct {
+	name   string
+	labels map[string]string
+	lines  []string
+}
+
+// Shipper ships run logs in the background. A nil Shipper is valid and
+// ships nothing.
+type Shipper struct {
+	lokiURL     string
+	lokiHeaders map[string]string
+	syslogNet   string
+	syslogAddr  string
+	labels      map[string]string
+	maxBytes    int64
+	hostname    string
+	client      *http.Client
+	queue       chan Run
+}
+
+// New checks the log shipping configuration and returns its shipper.
+func New(cfg config.LogShippingConfig) (*Shipper, error) {
+	s := &Shipper{
+		lokiHeaders: cfg.Loki.Headers,
+		labels:      cfg.Labels,
+		maxBytes:    cfg.MaxBytes,
+		client:      &http.Client{Timeout: Timeout},
+		queue:       make(chan Run, queueSize),
+	}
+	if cfg.Loki.URL != "" {
+		u, err := url.Parse(cfg.Loki.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, errors.New("loki.url must be an http or https URL")
+		}
+		s.lokiURL = strings.TrimSuffix(cfg.Loki.URL, "/") + "/loki/api/v1/push"
+	}
+	if cfg.Syslog.Address != "" {
+		network, addr, ok := strings.Cut(cfg.Syslog.Address, "://")
+		if !ok || (network != "udp" && network != "tcp") || addr == "" {
+			return nil, errors.New(`syslog.address must be "udp://host:port" or "tcp://host:port"`)
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return nil, fmt.Errorf("syslog.address: %v", err)
+		}
+		s.syslogNet, s.syslogAddr = network, addr
+	}
+	for k := range cfg.Labels {
+		if !validLabel(k) || reservedLabels[k] {
+			return nil, fmt.Errorf("invalid label name %q", k)
+		}
+	}
+	if s.maxBytes < 0 {
+		return nil, errors.New("max_bytes must not be negative")
+	}
+	if s.maxBytes == 0 {
+		s.maxBytes = DefaultMaxBytes
+	}
+	s.hostname, _ = os.Hostname()
+	if s.hostname == "" {
+		s.hostname = "-"
+	}
+	return s, nil
+}
+
+// reservedLabels are set on every stream and cannot be configured.
+var reservedLabels = map[string]bool{"job": true, "run_id": true, "status": true, "stream": true}
+
+// validLabel reports whether name is a valid Loki label name, which also
+// makes it a valid syslog structured data parameter name.
+func validLabel(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Ship queues a finished run's logs without waiting for them to be sent.
+func (s *Shipper) Ship(r Run) {
+	if s == nil {
+		return
+	}
+	select {
+	case s.queue <- r:
+	default:
+		log.Printf("WARN: not shipping logs of run %s; the queue is full", r.RunID)
+	}
+}
+
+// Run ships queued runs until ctx is done.
+func (s *Shipper) Run(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case r := <-s.queue:
+			s.ship(ctx, r)
+		}
+	}
+}
+
+func (s *Shipper) ship(ctx context.Context, r Run) {
+	var streams []stream
+	for _, src := range []struct{ name, path, tail string }{
+		{"stdout", r.StdoutPath, r.StdoutTail},
+		{"stderr", r.StderrPath, r.StderrTail},
+	} {
+		lines, err := s.readLines(src.path, src.tail)
+		if err != nil {
+			log.Printf("WARN: failed to read %s of run %s for shipping: %v", src.name, r.RunID, err)
+			continue
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		labels := make(map[string]string, len(s.labels)+4)
+		for k, v := range s.labels {
+			labels[k] = v
+		}
+		labels["job"] = r.JobName
+		labels["run_id"] = r.RunID
+		labels["status"] = r.Status
+		labels["stream"] = src.name
+		streams = append(streams, stream{name: src.name, labels: labels, lines: lines})
+	}
+	if len(streams) == 0 {
+		return
+	}
+	if s.lokiURL != "" {
+		if err := s.pushLoki(ctx, r, streams); err != nil && ctx.Err() == nil {
+			log.Printf("WARN: failed to ship logs of run %s to Loki: %v", r.RunID, err)
+		}
+	}
+	if s.syslogAddr != "" {
+		if err := s.sendSyslog(ctx, r, streams); err != nil && ctx.Err() == nil {
+			log.Printf("WARN: failed to ship logs of run %s to syslog: %v", r.RunID, err)
+		}
+	}
+}
+
+// readLines returns the first maxBytes of a log file, or of tail when
+// there is no file, as lines.
+func (s *Shipper) readLines(path, tail string) ([]string, error) {
+	var src io.Reader = strings.NewReader(tail)
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		src = f
+	}
+	sc := bufio.NewScanner(io.LimitReader(src, s.maxBytes))
+	sc.Buffer(make([]byte, 0, 64*1024), int(s.maxBytes)+1)
+	var lines []string
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if len(line) > maxLine {
+			line = line[:maxLine]
+		}
+		lines = append(lines, line)
+	}
+	return lines, sc.Err()
+}
+
+// Loki push API encoding. Timestamps are Unix nanoseconds as strings.
+type (
+	lokiPush struct {
+		Streams []lokiStream `json:"streams"`
+	}
+	lokiStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+)
+
+// lineTime spaces a run's lines a nanosecond apart from its start, which
+// keeps them in order; log files do not record when each line was written.
+func lineTime(r Run, i int) time.Time {
+	return r.StartedAt.Add(time.Duration(i))
+}
+
+func (s *Shipper) pushLoki(ctx context.Context, r Run, streams []stream) error {
+	var push lokiPush
+	for _, st := range streams {
+		ls := lokiStream{Stream: st.labels, Values: make([][2]string, len(st.lines))}
+		for i, line := range st.lines {
+			ls.Values[i] = [2]string{strconv.FormatInt(lineTime(r, i).UnixNano(), 10), line}
+		}
+		push.Streams = append(push.Streams, ls)
+	}
+	body, err := json.Marshal(push)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.lokiURL, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("invalid loki url")
+	}
+	for k, v := range s.lokiHeaders {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// Drop the URL from the error; it may embed a token.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *Shipper) sendSyslog(ctx context.Context, r Run, streams []stream) error {
+	dialer := net.Dialer{Timeout: Timeout}
+	conn, err := dialer.DialContext(ctx, s.syslogNet, s.syslogAddr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(Timeout))
+	w := bufio.NewWriter(conn)
+	for _, st := range streams {
+		for i, line := range st.lines {
+			msg := syslogMessage(s.hostname, r, st, lineTime(r, i), line)
+			if s.syslogNet == "tcp" {
+				// Octet-counting framing, RFC 6587.
+				fmt.Fprintf(w, "%d %s", len(msg), msg)
+			} else {
+				// One message per datagram.
+				if _, err := conn.Write(msg); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return w.Flush()
+}
+
+// syslogMessage formats an RFC 5424 message from the cron facility: info
+// for stdout, error for stderr. The labels travel as structured data.
+func syslogMessage(hostname string, r Run, st stream, at time.Time, line string) []byte {
+	const facilityCron = 9
+	severity := 6
+	if st.name == "stderr" {
+		severity = 3
+	}
+	keys := make([]string, 0, len(st.labels))
+	for k := range st.labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sd strings.Builder
+	sd.WriteString("[cronbat@32473")
+	for _, k := range keys {
+		sd.WriteString(" " + k + `="` + sdEscape(st.labels[k]) + `"`)
+	}
+	sd.WriteString("]")
+	return []byte(fmt.Sprintf("<%d>1 %s %s %s - %s %s %s",
+		facilityCron*8+severity,
+		at.UTC().Format("2006-01-02T15:04:05.000000Z"),
+		hostname,
+		appName(r.JobName),
+		st.name,
+		sd.String(),
+		line,
+	))
+}
+
+// appName makes a job name a valid RFC 5424 APP-NAME: at most 48 printable
+// ASCII characters without spaces.
+func appName(name string) string {
+	b := []byte(name)
+	if len(b) > 48 {
+		b = b[:48]
+	}
+	for i, c := range b {
+		if c <= ' ' || c > '~' {
+			b[i] = '_'
+		}
+	}
+	if len(b) == 0 {
+		return "-"
+	}
+	return string(b)
+}
+
+// sdEscape escapes a structured data parameter value.
+func sdEscape(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(v)
+}
@@ -0,0 +1,143 @@
+package logship
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+)
+
+func TestShipToLokiAndSyslog(t *testing.T) {
+	pushed := make(chan lokiPush, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/loki/api/v1/push" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if got := r.Header.Get("X-Scope-OrgID"); got != "team-a" {
+			t.Errorf("X-Scope-OrgID = %q", got)
+		}
+		var p lokiPush
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Error(err)
+		}
+		pushed <- p
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		data, _ := io.ReadAll(conn)
+		received <- string(data)
+	}()
+
+	s, err := New(config.LogShippingConfig{
+		Loki:   config.LokiConfig{URL: srv.URL, Headers: map[string]string{"X-Scope-OrgID": "team-a"}},
+		Syslog: config.RemoteSyslogConfig{Address: "tcp://" + ln.Addr().String()},
+		Labels: map[string]string{"env": "prod"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := filepath.Join(t.TempDir(), "stdout.log")
+	if err := os.WriteFile(stdout, []byte("copied 3 files\r\n\ndone\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run := Run{
+		JobName:    "backup",
+		RunID:      "run-1",
+		Status:     "failure",
+		StartedAt:  time.Unix(1700000000, 0),
+		StdoutPath: stdout,
+		StderrTail: "disk full",
+	}
+	s.ship(context.Background(), run)
+
+	p := <-pushed
+	if len(p.Streams) != 2 {
+		t.Fatalf("streams = %+v", p.Streams)
+	}
+	out := p.Streams[0]
+	if out.Stream["job"] != "backup" || out.Stream["run_id"] != "run-1" || out.Stream["status"] != "failure" ||
+		out.Stream["stream"] != "stdout" || out.Stream["env"] != "prod" {
+		t.Fatalf("labels = %v", out.Stream)
+	}
+	if len(out.Values) != 2 || out.Values[0][1] != "copied 3 files" || out.Values[1][1] != "done" {
+		t.Fatalf("values = %v", out.Values)
+	}
+	if out.Values[0][0] != strconv.FormatInt(run.StartedAt.UnixNano(), 10) {
+		t.Fatalf("timestamp = %s", out.Values[0][0])
+	}
+	if p.Streams[1].Stream["stream"] != "stderr" || p.Streams[1].Values[0][1] != "disk full" {
+		t.Fatalf("stderr stream = %+v", p.Streams[1])
+	}
+
+	var msgs []string
+	r := bufio.NewReader(strings.NewReader(<-received))
+	for {
+		n, err := readFrameLength(r)
+		if err != nil {
+			break
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, string(buf))
+	}
+	if len(msgs) != 3 {
+		t.Fatalf("syslog messages = %q", msgs)
+	}
+	want := `<75>1 2023-11-14T22:13:20.000000Z `
+	if !strings.HasPrefix(msgs[2], want) {
+		t.Fatalf("message = %q, want prefix %q", msgs[2], want)
+	}
+	if !strings.HasSuffix(msgs[2], ` backup - stderr [cronbat@32473 env="prod" job="backup" run_id="run-1" status="failure" stream="stderr"] disk full`) {
+		t.Fatalf("message = %q", msgs[2])
+	}
+}
+
+// readFrameLength reads the octet count that frames a syslog message over
+// TCP.
+func readFrameLength(r *bufio.Reader) (int, error) {
+	s, err := r.ReadString(' ')
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSuffix(s, " "))
+}
+
+func TestNewRejectsBadConfig(t *testing.T) {
+	for _, cfg := range []config.LogShippingConfig{
+		{Loki: config.LokiConfig{URL: "loki:3100"}},
+		{Syslog: config.RemoteSyslogConfig{Address: "logs.example.com:514"}},
+		{Syslog: config.RemoteSyslogConfig{Address: "udp://logs.example.com"}},
+		{Loki: config.LokiConfig{URL: "http://loki:3100"}, Labels: map[string]string{"job": "x"}},
+		{Loki: config.LokiConfig{URL: "http://loki:3100"}, Labels: map[string]string{"bad-name": "x"}},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("New(%+v) succeeded", cfg)
+		}
+	}
+}
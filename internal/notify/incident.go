@@ -0,0 +1,123 @@
+package notify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+)
+
+// Incident service endpoints; variables so tests can point them elsewhere.
+var (
+	pagerDutyURL  = "https://events.pagerduty.com/v2/enqueue"
+	opsgenieURL   = "https://api.opsgenie.com"
+	opsgenieEUURL = "https://api.eu.opsgenie.com"
+)
+
+// Length limits of incident summaries, in characters.
+const (
+	pagerDutyMaxLen = 1024
+	opsgenieMaxLen  = 130
+)
+
+// IncidentTarget identifies the service account an incident entry opens
+// incidents with, so entries are told apart without storing their keys.
+func IncidentTarget(n config.NotifyConfig) string {
+	key := n.RoutingKey
+	if n.Type == config.NotifyOpsgenie {
+		key = n.Region + ":" + n.APIKey
+	}
+	sum := sha256.Sum256([]byte(key))
+	return n.Type + ":" + hex.EncodeToString(sum[:6])
+}
+
+// DedupKey returns the key of the incident a job's failed run opens.
+func DedupKey(p Payload) string {
+	return "cronbat/" + p.Job + "/" + p.RunID
+}
+
+// Trigger opens an incident for the failed run in p under dedupKey.
+func Trigger(ctx context.Context, client *http.Client, n config.NotifyConfig, dedupKey string, p Payload) error {
+	msg, err := Message(n, p)
+	if err != nil {
+		return fmt.Errorf("message: %w", err)
+	}
+	switch n.Type {
+	case config.NotifyPagerDuty:
+		return pagerDutyEvent(ctx, client, n, map[string]any{
+			"event_action": "trigger",
+			"dedup_key":    dedupKey,
+			"payload": map[string]any{
+				"summary":        truncate(msg, pagerDutyMaxLen),
+				"source":         "cronbat",
+				"severity":       "error",
+				"component":      p.Job,
+				"custom_details": p,
+			},
+		})
+	case config.NotifyOpsgenie:
+		body, err := json.Marshal(map[string]any{
+			"message":     truncate(msg, opsgenieMaxLen),
+			"alias":       dedupKey,
+			"description": truncate(p.Error+"\n\n"+p.StderrTail, 15000),
+			"source":      "cronbat",
+			"entity":      p.Job,
+			"details":     map[string]string{"job": p.Job, "run_id": p.RunID, "status": p.Status, "exit_code": fmt.Sprint(p.ExitCode)},
+		})
+		if err != nil {
+			return err
+		}
+		return post(ctx, client, opsgenieBase(n)+"/v2/alerts", opsgenieHeader(n), body)
+	}
+	return fmt.Errorf("%s does not manage incidents", n.Type)
+}
+
+// Resolve resolves the incident opened under dedupKey now that the run in
+// p succeeded.
+func Resolve(ctx context.Context, client *http.Client, n config.NotifyConfig, dedupKey string, p Payload) error {
+	switch n.Type {
+	case config.NotifyPagerDuty:
+		return pagerDutyEvent(ctx, client, n, map[string]any{
+			"event_action": "resolve",
+			"dedup_key":    dedupKey,
+		})
+	case config.NotifyOpsgenie:
+		body, err := json.Marshal(map[string]string{
+			"source": "cronbat",
+			"note":   "job " + p.Job + " succeeded again in run " + p.RunID,
+		})
+		if err != nil {
+			return err
+		}
+		target := opsgenieBase(n) + "/v2/alerts/" + url.PathEscape(dedupKey) + "/close?identifierType=alias"
+		return post(ctx, client, target, opsgenieHeader(n), body)
+	}
+	return fmt.Errorf("%s does not manage incidents", n.Type)
+}
+
+func pagerDutyEvent(ctx context.Context, client *http.Client, n config.NotifyConfig, event map[string]any) error {
+	event["routing_key"] = n.RoutingKey
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return post(ctx, client, pagerDutyURL, jsonHeader(), body)
+}
+
+func opsgenieBase(n config.NotifyConfig) string {
+	if n.Region == "eu" {
+		return opsgenieEUURL
+	}
+	return opsgenieURL
+}
+
+func opsgenieHeader(n config.NotifyConfig) http.Header {
+	h := jsonHeader()
+	h.Set("Authorization", "GenieKey "+n.APIKey)
+	return h
+}
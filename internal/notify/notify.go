@@ -0,0 +1,174 @@
+// Package notify sends run results to the notify_urls a job defines:
+// webhooks, Pushover, Telegram, and Discord.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/store"
+)
+
+// Timeout bounds each notification request.
+const Timeout = 10 * time.Second
+
+// Service endpoints; variables so tests can point them elsewhere.
+var (
+	pushoverURL = "https://api.pushover.net/1/messages.json"
+	telegramURL = "https://api.telegram.org"
+)
+
+// Message length limits of the chat services, in characters.
+const (
+	pushoverMaxLen = 1024
+	telegramMaxLen = 4096
+	discordMaxLen  = 2000
+)
+
+// Payload describes a finished run. It is the default request body and the
+// data notify_urls templates render from.
+type Payload struct {
+	Job         string    `json:"job"`
+	RunID       string    `json:"run_id"`
+	Status      string    `json:"status"`
+	Trigger     string    `json:"trigger"`
+	TriggeredBy string    `json:"triggered_by,omitempty"`
+	ExitCode    int       `json:"exit_code"`
+	DurationMs  int64     `json:"duration_ms"`
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+	Error       string    `json:"error,omitempty"`
+	StdoutTail  string    `json:"stdout_tail,omitempty"`
+	StderrTail  string    `json:"stderr_tail,omitempty"`
+	// TriggerContext is the request or message that triggered a manual
+	// or bus run; nil for other triggers.
+	TriggerContext *store.TriggerContext `json:"trigger_context,omitempty"`
+	// CorrelationID is the ID the trigger supplied to find the run again.
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// Message is the entry's rendered message; Send sets it.
+	Message string `json:"message,omitempty"`
+}
+
+// Message renders the notification text for n.
+func Message(n config.NotifyConfig, p Payload) (string, error) {
+	tmpl, err := n.ParseMessage()
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, p); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// Body renders the request body for n: its template, or the payload as
+// JSON when it has none. Rendered templates must be valid JSON.
+func Body(n config.NotifyConfig, p Payload) ([]byte, error) {
+	if n.Template == "" {
+		return json.Marshal(p)
+	}
+	tmpl, err := n.ParseTemplate()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, p); err != nil {
+		return nil, err
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("template did not render valid JSON")
+	}
+	return buf.Bytes(), nil
+}
+
+// Send delivers the notification for n.
+func Send(ctx context.Context, client *http.Client, n config.NotifyConfig, p Payload) error {
+	msg, err := Message(n, p)
+	if err != nil {
+		return fmt.Errorf("message: %w", err)
+	}
+	p.Message = msg
+
+	switch n.Type {
+	case config.NotifyPushover:
+		form := url.Values{
+			"token":   {n.Token},
+			"user":    {n.User},
+			"message": {truncate(msg, pushoverMaxLen)},
+		}
+		return post(ctx, client, pushoverURL, formHeader(), []byte(form.Encode()))
+	case config.NotifyTelegram:
+		body, err := json.Marshal(map[string]string{"chat_id": n.ChatID, "text": truncate(msg, telegramMaxLen)})
+		if err != nil {
+			return err
+		}
+		return post(ctx, client, telegramURL+"/bot"+n.BotToken+"/sendMessage", jsonHeader(), body)
+	case config.NotifyPagerDuty, config.NotifyOpsgenie:
+		return fmt.Errorf("%s opens incidents; use Trigger and Resolve", n.Type)
+	case config.NotifyDiscord:
+		body, err := json.Marshal(map[string]string{"content": truncate(msg, discordMaxLen)})
+		if err != nil {
+			return err
+		}
+		return post(ctx, client, n.URL, jsonHeader(), body)
+	default:
+		body, err := Body(n, p)
+		if err != nil {
+			return err
+		}
+		return post(ctx, client, n.URL, jsonHeader(), body)
+	}
+}
+
+func jsonHeader() http.Header {
+	return http.Header{"Content-Type": {"application/json"}}
+}
+
+func formHeader() http.Header {
+	return http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}
+}
+
+func post(ctx context.Context, client *http.Client, target string, header http.Header, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		// Not err itself: it quotes the URL.
+		return errors.New("invalid notification URL")
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// Drop the URL from the error; it often embeds a token.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// truncate shortens s to at most max characters, marking the cut.
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-1]) + "…"
+}
@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+)
+
+func TestSendTemplate(t *testing.T) {
+	t.Parallel()
+
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Errorf("body is not JSON: %s", data)
+		}
+	}))
+	defer srv.Close()
+
+	n := config.NotifyConfig{
+		URL:      srv.URL,
+		Template: `{"text": {{json (printf "%s %s" .Job .Status)}}, "tail": {{json .StdoutTail}}}`,
+	}
+	p := Payload{Job: "backup", Status: "failure", StdoutTail: "line \"1\"\n"}
+	if err := Send(context.Background(), srv.Client(), n, p); err != nil {
+		t.Fatal(err)
+	}
+	if got["text"] != "backup failure" || got["tail"] != "line \"1\"\n" {
+		t.Fatalf("unexpected body: %v", got)
+	}
+
+	n.Template = `{"text": {{.Job}}}`
+	if _, err := Body(n, p); err == nil {
+		t.Fatal("expected an error for a template that renders invalid JSON")
+	}
+}
+
+func TestSendChatServices(t *testing.T) {
+	var paths, bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		paths = append(paths, r.URL.Path)
+		bodies = append(bodies, string(data))
+	}))
+	defer srv.Close()
+	pushoverURL = srv.URL + "/pushover"
+	telegramURL = srv.URL
+
+	p := Payload{Job: "backup", Status: "failure", ExitCode: 2, DurationMs: 1500}
+	entries := []config.NotifyConfig{
+		{Type: config.NotifyPushover, Token: "app", User: "me"},
+		{Type: config.NotifyTelegram, BotToken: "123:abc", ChatID: "-42", Message: "{{.Job}} is {{.Status}}"},
+		{Type: config.NotifyDiscord, URL: srv.URL + "/discord"},
+	}
+	for _, n := range entries {
+		if err := n.Validate(); err != nil {
+			t.Fatalf("Validate(%s): %v", n.Type, err)
+		}
+		if err := Send(context.Background(), srv.Client(), n, p); err != nil {
+			t.Fatalf("Send(%s): %v", n.Type, err)
+		}
+	}
+
+	want := []struct{ path, body string }{
+		{"/pushover", "message=cronbat%3A+backup+failure+%28exit+2%29+in+1500ms&token=app&user=me"},
+		{"/bot123:abc/sendMessage", `{"chat_id":"-42","text":"backup is failure"}`},
+		{"/discord", `{"content":"cronbat: backup failure (exit 2) in 1500ms"}`},
+	}
+	for i, w := range want {
+		if paths[i] != w.path || bodies[i] != w.body {
+			t.Errorf("request %d: %s %s, want %s %s", i, paths[i], bodies[i], w.path, w.body)
+		}
+	}
+}
+
+func TestIncidents(t *testing.T) {
+	type request struct {
+		path, auth string
+		body       map[string]any
+	}
+	var got []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		got = append(got, request{r.URL.RequestURI(), r.Header.Get("Authorization"), body})
+	}))
+	defer srv.Close()
+	pagerDutyURL = srv.URL + "/v2/enqueue"
+	opsgenieURL = srv.URL
+
+	p := Payload{Job: "backup", RunID: "r1", Status: "failure"}
+	key := DedupKey(p)
+	pd := config.NotifyConfig{Type: config.NotifyPagerDuty, RoutingKey: "rk"}
+	og := config.NotifyConfig{Type: config.NotifyOpsgenie, APIKey: "gk"}
+	if IncidentTarget(pd) == IncidentTarget(config.NotifyConfig{Type: config.NotifyPagerDuty, RoutingKey: "other"}) {
+		t.Fatal("different routing keys share a target")
+	}
+	for _, n := range []config.NotifyConfig{pd, og} {
+		if err := Trigger(context.Background(), srv.Client(), n, key, p); err != nil {
+			t.Fatalf("Trigger(%s): %v", n.Type, err)
+		}
+		if err := Resolve(context.Background(), srv.Client(), n, key, p); err != nil {
+			t.Fatalf("Resolve(%s): %v", n.Type, err)
+		}
+	}
+
+	if len(got) != 4 {
+		t.Fatalf("got %d requests, want 4", len(got))
+	}
+	if b := got[0].body; b["event_action"] != "trigger" || b["dedup_key"] != key || b["routing_key"] != "rk" {
+		t.Errorf("pagerduty trigger = %v", b)
+	}
+	if b := got[1].body; b["event_action"] != "resolve" || b["dedup_key"] != key {
+		t.Errorf("pagerduty resolve = %v", b)
+	}
+	if r := got[2]; r.path != "/v2/alerts" || r.auth != "GenieKey gk" || r.body["alias"] != key {
+		t.Errorf("opsgenie create = %+v", r)
+	}
+	if r := got[3]; r.path != "/v2/alerts/cronbat%2Fbackup%2Fr1/close?identifierType=alias" {
+		t.Errorf("opsgenie close = %+v", r)
+	}
+}
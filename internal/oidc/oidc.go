@@ -0,0 +1,450 @@
+// Package oidc signs users in with an OpenID Connect provider such as
+// Google, Okta, or Keycloak, using the authorization code flow with PKCE.
+// The provider's endpoints come from its discovery document, and ID tokens
+// are verified against its published keys, without a third-party client
+// library.
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+)
+
+// Timeout bounds each request to the provider.
+const Timeout = 10 * time.Second
+
+// clockSkew is how far the provider's clock may be ahead of or behind
+// ours when checking token expiry.
+const clockSkew = time.Minute
+
+// keyRefreshInterval is how often an unknown key ID may trigger a
+// refetch of the provider's keys, which rotate.
+const keyRefreshInterval = time.Minute
+
+// Identity is a signed-in user.
+type Identity struct {
+	Subject string
+	Email   string
+	// Name is how the user is shown in run and audit records: the email
+	// address, else the preferred username, else the subject.
+	Name   string
+	Groups []string
+}
+
+// Provider talks to one OpenID Connect provider.
+type Provider struct {
+	cfg    config.OIDCConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	meta        *metadata
+	keys        map[string]crypto.PublicKey
+	keysFetched time.Time
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// New checks the single sign-on configuration and returns its provider.
+// The provider is not contacted until the first sign-in.
+func New(cfg config.OIDCConfig) (*Provider, error) {
+	if !isHTTPURL(cfg.Issuer) {
+		return nil, errors.New("issuer must be an http or https URL")
+	}
+	if cfg.ClientID == "" {
+		return nil, errors.New("client_id is required")
+	}
+	if !isHTTPURL(cfg.RedirectURL) {
+		return nil, errors.New("redirect_url must be an http or https URL")
+	}
+	return &Provider{cfg: cfg, client: &http.Client{Timeout: Timeout}}, nil
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// AuthURL returns the provider URL to send the browser to. The provider
+// redirects back to the redirect URL with state, and the ID token it
+// issues carries nonce; verifier is later passed to Exchange.
+func (p *Provider) AuthURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange redeems the authorization code the provider redirected back
+// with and returns the user its verified ID token names.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request: %w", err)
+	}
+	defer resp.Body.Close()
+	var tok struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tok); err != nil {
+		return nil, fmt.Errorf("token response: status %d", resp.StatusCode)
+	}
+	if tok.Error != "" {
+		return nil, fmt.Errorf("token request: %s %s", tok.Error, tok.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK || tok.IDToken == "" {
+		return nil, fmt.Errorf("token response: status %d without an id_token", resp.StatusCode)
+	}
+	return p.Verify(ctx, tok.IDToken, nonce)
+}
+
+// Verify checks an ID token's signature, issuer, audience, expiry, and
+// nonce, and returns the user it names.
+func (p *Provider) Verify(ctx context.Context, rawToken, nonce string) (*Identity, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("id_token is not a JWT")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("id_token header: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("id_token signature is not base64url")
+	}
+	key, err := p.key(ctx, meta, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("id_token claims: %w", err)
+	}
+	if iss, _ := claims["iss"].(string); iss != meta.Issuer {
+		return nil, fmt.Errorf("id_token issuer %q, want %q", iss, meta.Issuer)
+	}
+	if !audienceHas(claims["aud"], p.cfg.ClientID) {
+		return nil, errors.New("id_token is not for this client_id")
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok || time.Now().Add(-clockSkew).After(time.Unix(int64(exp), 0)) {
+		return nil, errors.New("id_token has expired")
+	}
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return nil, errors.New("id_token nonce does not match this sign-in")
+	}
+
+	id := &Identity{Groups: stringList(claims[p.cfg.GroupsClaim])}
+	id.Subject, _ = claims["sub"].(string)
+	id.Email, _ = claims["email"].(string)
+	if id.Subject == "" {
+		return nil, errors.New("id_token has no subject")
+	}
+	username, _ := claims["preferred_username"].(string)
+	switch {
+	case id.Email != "":
+		id.Name = id.Email
+	case username != "":
+		id.Name = username
+	default:
+		id.Name = id.Subject
+	}
+	return id, nil
+}
+
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return errors.New("not base64url")
+	}
+	return json.Unmarshal(data, v)
+}
+
+func audienceHas(aud any, clientID string) bool {
+	for _, a := range stringList(aud) {
+		if a == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// stringList reads a claim that is a string or a list of strings.
+func stringList(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	var h crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		h = crypto.SHA256
+	case "RS384", "ES384":
+		h = crypto.SHA384
+	case "RS512", "ES512":
+		h = crypto.SHA512
+	default:
+		return fmt.Errorf("id_token algorithm %q is not supported", alg)
+	}
+	digest := hashOf(h, signed)
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") || rsa.VerifyPKCS1v15(k, h, digest, sig) != nil {
+			return errors.New("id_token signature is invalid")
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(alg, "ES") || len(sig) != 2*size {
+			return errors.New("id_token signature is invalid")
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return errors.New("id_token signature is invalid")
+		}
+	default:
+		return errors.New("id_token signing key is not supported")
+	}
+	return nil
+}
+
+func hashOf(h crypto.Hash, s string) []byte {
+	switch h {
+	case crypto.SHA384:
+		sum := sha512.Sum384([]byte(s))
+		return sum[:]
+	case crypto.SHA512:
+		sum := sha512.Sum512([]byte(s))
+		return sum[:]
+	}
+	sum := sha256.Sum256([]byte(s))
+	return sum[:]
+}
+
+// metadata returns the provider's discovery document, fetched once.
+func (p *Provider) metadata(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	meta := p.meta
+	p.mu.Unlock()
+	if meta != nil {
+		return meta, nil
+	}
+
+	meta = &metadata{}
+	if err := p.getJSON(ctx, strings.TrimSuffix(p.cfg.Issuer, "/")+"/.well-known/openid-configuration", meta); err != nil {
+		return nil, fmt.Errorf("discovery: %w", err)
+	}
+	if meta.Issuer != strings.TrimSuffix(p.cfg.Issuer, "/") && meta.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("discovery: provider issuer %q does not match %q", meta.Issuer, p.cfg.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, errors.New("discovery: document is missing endpoints")
+	}
+	p.mu.Lock()
+	p.meta = meta
+	p.mu.Unlock()
+	return meta, nil
+}
+
+// key returns the provider's signing key with ID kid, refetching the key
+// set when kid is unknown.
+func (p *Provider) key(ctx context.Context, meta *metadata, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	k := pickKey(p.keys, kid)
+	stale := time.Since(p.keysFetched) > keyRefreshInterval
+	p.mu.Unlock()
+	if k != nil {
+		return k, nil
+	}
+	if !stale {
+		return nil, fmt.Errorf("id_token signing key %q is unknown", kid)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := p.getJSON(ctx, meta.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("signing keys: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, j := range set.Keys {
+		if j.Use != "" && j.Use != "sig" {
+			continue
+		}
+		if pub, err := j.publicKey(); err == nil {
+			keys[j.Kid] = pub
+		}
+	}
+	p.mu.Lock()
+	p.keys, p.keysFetched = keys, time.Now()
+	p.mu.Unlock()
+
+	if k := pickKey(keys, kid); k != nil {
+		return k, nil
+	}
+	return nil, fmt.Errorf("id_token signing key %q is unknown", kid)
+}
+
+// pickKey returns the key with ID kid, or nil. A token without a key ID
+// is fine when the provider has one key.
+func pickKey(keys map[string]crypto.PublicKey, kid string) crypto.PublicKey {
+	if k, ok := keys[kid]; ok {
+		return k
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, k := range keys {
+			return k
+		}
+	}
+	return nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// jwk is one key of a JSON Web Key Set.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (j jwk) publicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("curve %q is not supported", j.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("key type %q is not supported", j.Kty)
+}
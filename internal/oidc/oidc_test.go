@@ -0,0 +1,139 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+)
+
+func TestSignIn(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issuer string
+	var claims map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer,
+			"authorization_endpoint": issuer + "/authorize",
+			"token_endpoint":         issuer + "/token",
+			"jwks_uri":               issuer + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, _ := r.BasicAuth(); id != "cronbat" || secret != "shh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		if r.FormValue("code") != "the-code" || r.FormValue("code_verifier") != "verifier" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signJWT(t, key, claims)})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	issuer = srv.URL
+
+	p, err := New(config.OIDCConfig{
+		Issuer:       issuer,
+		ClientID:     "cronbat",
+		ClientSecret: "shh",
+		RedirectURL:  "http://localhost:8080/api/v1/auth/oidc/callback",
+		Scopes:       []string{"openid", "email"},
+		GroupsClaim:  "groups",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	authURL, err := p.AuthURL(ctx, "st", "n1", "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(authURL)
+	sum := sha256.Sum256([]byte("verifier"))
+	if q := u.Query(); u.Path != "/authorize" || q.Get("state") != "st" || q.Get("nonce") != "n1" ||
+		q.Get("code_challenge") != base64.RawURLEncoding.EncodeToString(sum[:]) || q.Get("scope") != "openid email" {
+		t.Fatalf("AuthURL = %s", authURL)
+	}
+
+	claims = map[string]any{
+		"iss":    issuer,
+		"aud":    []string{"cronbat"},
+		"sub":    "u-1",
+		"email":  "ada@example.com",
+		"groups": []string{"sre", "devs"},
+		"nonce":  "n1",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}
+	id, err := p.Exchange(ctx, "the-code", "verifier", "n1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Name != "ada@example.com" || id.Subject != "u-1" || strings.Join(id.Groups, ",") != "sre,devs" {
+		t.Fatalf("identity = %+v", id)
+	}
+
+	if _, err := p.Exchange(ctx, "the-code", "verifier", "other"); err == nil {
+		t.Fatal("token with another sign-in's nonce accepted")
+	}
+	if _, err := p.Exchange(ctx, "wrong", "verifier", "n1"); err == nil {
+		t.Fatal("invalid code accepted")
+	}
+	claims["exp"] = time.Now().Add(-time.Hour).Unix()
+	if _, err := p.Exchange(ctx, "the-code", "verifier", "n1"); err == nil {
+		t.Fatal("expired token accepted")
+	}
+	claims["exp"] = time.Now().Add(time.Hour).Unix()
+	claims["aud"] = "someone-else"
+	if _, err := p.Exchange(ctx, "the-code", "verifier", "n1"); err == nil {
+		t.Fatal("token for another client accepted")
+	}
+
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	claims["aud"] = "cronbat"
+	if _, err := p.Verify(ctx, signJWT(t, other, claims), "n1"); err == nil {
+		t.Fatal("token signed with another key accepted")
+	}
+}
+
+func signJWT(t *testing.T, key *rsa.PrivateKey, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "k1", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
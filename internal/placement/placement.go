@@ -0,0 +1,75 @@
+// Package placement decides which host runs a job with a runs_on
+// selector: the local daemon or one of the connected agents.
+package placement
+
+import (
+	"sort"
+	"strings"
+)
+
+// HostnameLabel matches a host's hostname in a selector.
+const HostnameLabel = "hostname"
+
+// Host is a machine a run can be placed on.
+type Host struct {
+	// ID identifies the host to the caller, e.g. an agent connection.
+	ID       string
+	Hostname string
+	Labels   map[string]string
+	// Running is how many runs the host has in progress.
+	Running int
+	// Local marks the daemon's own host.
+	Local bool
+}
+
+// Matches reports whether a host with the given hostname and labels
+// satisfies every entry of selector. An empty selector matches any host.
+func Matches(selector map[string]string, hostname string, labels map[string]string) bool {
+	for k, want := range selector {
+		got, ok := labels[k]
+		if k == HostnameLabel {
+			got, ok = hostname, true
+		}
+		if !ok || got != want {
+			return false
+		}
+	}
+	return true
+}
+
+// Place picks the eligible host with the fewest running runs. Ties go to
+// the local host, then to the lowest ID. It returns false if no host
+// matches.
+func Place(selector map[string]string, hosts []Host) (Host, bool) {
+	var best Host
+	found := false
+	for _, h := range hosts {
+		if !Matches(selector, h.Hostname, h.Labels) {
+			continue
+		}
+		if !found || better(h, best) {
+			best, found = h, true
+		}
+	}
+	return best, found
+}
+
+func better(a, b Host) bool {
+	if a.Running != b.Running {
+		return a.Running < b.Running
+	}
+	if a.Local != b.Local {
+		return a.Local
+	}
+	return a.ID < b.ID
+}
+
+// FormatSelector renders a selector as sorted key=value pairs.
+func FormatSelector(selector map[string]string) string {
+	pairs := make([]string, 0, len(selector))
+	for k, v := range selector {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,28 @@
+package placement
+
+import "testing"
+
+func TestPlace(t *testing.T) {
+	hosts := []Host{
+		{ID: "agent-2", Hostname: "db2", Labels: map[string]string{"role": "db"}, Running: 1},
+		{ID: "agent-1", Hostname: "db1", Labels: map[string]string{"role": "db"}, Running: 1},
+		{ID: "local", Hostname: "web1", Labels: map[string]string{"role": "web"}, Local: true},
+	}
+	tests := []struct {
+		selector map[string]string
+		want     string
+		ok       bool
+	}{
+		{map[string]string{"role": "db"}, "agent-1", true},
+		{map[string]string{"role": "db", "hostname": "db2"}, "agent-2", true},
+		{map[string]string{"role": "web"}, "local", true},
+		{map[string]string{"role": "cache"}, "", false},
+		{nil, "local", true},
+	}
+	for _, tt := range tests {
+		got, ok := Place(tt.selector, hosts)
+		if ok != tt.ok || got.ID != tt.want {
+			t.Errorf("Place(%v) = %q, %v; want %q, %v", tt.selector, got.ID, ok, tt.want, tt.ok)
+		}
+	}
+}
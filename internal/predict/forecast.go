@@ -0,0 +1,213 @@
+package predict
+
+import (
+	"sort"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// MaxFiresPerJob bounds how many fires of one job Forecast enumerates, so
+// a sub-second schedule cannot make a forecast arbitrarily slow.
+const MaxFiresPerJob = 100000
+
+// ForecastJob is a scheduled job to forecast.
+type ForecastJob struct {
+	Name     string
+	Schedule cron.Schedule
+	// Typical is the job's usual duration; zero if unknown, in which case
+	// each run is counted in the minute it starts.
+	Typical time.Duration
+	// Heavy marks jobs whose overlaps are reported.
+	Heavy bool
+}
+
+// ForecastMinute is one minute in which runs are expected.
+type ForecastMinute struct {
+	At time.Time
+	// Jobs are the jobs expected to be running at some point in the
+	// minute, sorted; a job counts once however often it fires.
+	Jobs []string
+	// Starting are the jobs that fire in the minute, sorted.
+	Starting []string
+}
+
+// Overlap is a stretch of minutes in which the same heavy jobs are
+// expected to run at once.
+type Overlap struct {
+	From time.Time
+	To   time.Time
+	Jobs []string
+}
+
+// Forecast is the expected load of the schedules over a window.
+type Forecast struct {
+	// Fires counts each job's fires in the window.
+	Fires map[string]int
+	// Truncated lists jobs that fire more than MaxFiresPerJob times; only
+	// their first fires are counted.
+	Truncated []string
+	// Minutes are the minutes with at least one expected run, in order.
+	Minutes  []ForecastMinute
+	Overlaps []Overlap
+}
+
+// ForecastRuns enumerates the fires of jobs after from and up to to, and
+// the minutes their runs are expected to occupy.
+func ForecastRuns(jobs []ForecastJob, from, to time.Time) *Forecast {
+	start := from.Truncate(time.Minute)
+	n := int(to.Sub(start)/time.Minute) + 1
+	running := make([]map[string]bool, n)
+	starting := make([]map[string]bool, n)
+	mark := func(slots []map[string]bool, i int, job string) {
+		if slots[i] == nil {
+			slots[i] = make(map[string]bool)
+		}
+		slots[i][job] = true
+	}
+
+	f := &Forecast{Fires: make(map[string]int, len(jobs))}
+	heavy := make(map[string]bool)
+	for _, j := range jobs {
+		if j.Heavy {
+			heavy[j.Name] = true
+		}
+		f.Fires[j.Name] = 0
+		// lastMarked avoids re-marking minutes of runs that fire more often
+		// than they last.
+		lastMarked := -1
+		for at := j.Schedule.Next(from); !at.IsZero() && !at.After(to); at = j.Schedule.Next(at) {
+			if f.Fires[j.Name] == MaxFiresPerJob {
+				f.Truncated = append(f.Truncated, j.Name)
+				break
+			}
+			f.Fires[j.Name]++
+			first := int(at.Sub(start) / time.Minute)
+			mark(starting, first, j.Name)
+			last := first
+			if j.Typical > 0 {
+				last = int(at.Add(j.Typical-1).Sub(start) / time.Minute)
+			}
+			if last >= n {
+				last = n - 1
+			}
+			if first <= lastMarked {
+				first = lastMarked + 1
+			}
+			for i := first; i <= last; i++ {
+				mark(running, i, j.Name)
+			}
+			if last > lastMarked {
+				lastMarked = last
+			}
+		}
+	}
+
+	var open *Overlap
+	for i := 0; i < n; i++ {
+		at := start.Add(time.Duration(i) * time.Minute)
+		var heavyJobs []string
+		if len(running[i]) > 0 {
+			m := ForecastMinute{At: at, Jobs: sortedKeys(running[i]), Starting: sortedKeys(starting[i])}
+			f.Minutes = append(f.Minutes, m)
+			for _, name := range m.Jobs {
+				if heavy[name] {
+					heavyJobs = append(heavyJobs, name)
+				}
+			}
+		}
+		if len(heavyJobs) < 2 {
+			open = nil
+			continue
+		}
+		if open != nil && sameStrings(open.Jobs, heavyJobs) {
+			open.To = at.Add(time.Minute)
+			continue
+		}
+		f.Overlaps = append(f.Overlaps, Overlap{From: at, To: at.Add(time.Minute), Jobs: heavyJobs})
+		open = &f.Overlaps[len(f.Overlaps)-1]
+	}
+	sort.Strings(f.Truncated)
+	return f
+}
+
+func sortedKeys(set map[string]bool) []string {
+	out := make([]string, 0, len(set))
+	for k := range set {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Collision is a minute in which a job fires together with other jobs.
+type Collision struct {
+	At time.Time
+	// Jobs are the other jobs firing in the minute, sorted.
+	Jobs []string
+}
+
+// WorstCollision finds the minute after from and up to to in which
+// schedule fires together with the most of others. It returns false if
+// schedule never shares a minute with another job.
+func WorstCollision(schedule cron.Schedule, others map[string]cron.Schedule, from, to time.Time) (Collision, bool) {
+	fires := minuteSet(schedule, from, to)
+	counts := make(map[time.Time][]string)
+	for name, other := range others {
+		for minute := range minuteSet(other, from, to) {
+			if fires[minute] {
+				counts[minute] = append(counts[minute], name)
+			}
+		}
+	}
+	var worst Collision
+	for minute, jobs := range counts {
+		if len(jobs) > len(worst.Jobs) || (len(jobs) == len(worst.Jobs) && minute.Before(worst.At)) {
+			worst = Collision{At: minute, Jobs: jobs}
+		}
+	}
+	sort.Strings(worst.Jobs)
+	return worst, len(worst.Jobs) > 0
+}
+
+// minuteSet returns the minutes schedule fires in after from and up to to,
+// stopping after MaxFiresPerJob fires.
+func minuteSet(schedule cron.Schedule, from, to time.Time) map[time.Time]bool {
+	out := make(map[time.Time]bool)
+	fires := 0
+	for at := schedule.Next(from); !at.IsZero() && !at.After(to) && fires < MaxFiresPerJob; at = schedule.Next(at) {
+		out[at.UTC().Truncate(time.Minute)] = true
+		fires++
+	}
+	return out
+}
+
+// MinInterval returns the shortest gap between consecutive fires of
+// schedule after from and up to to, or 0 if it fires fewer than twice.
+func MinInterval(schedule cron.Schedule, from, to time.Time) time.Duration {
+	var shortest time.Duration
+	prev := schedule.Next(from)
+	for fires := 0; !prev.IsZero() && fires < MaxFiresPerJob; fires++ {
+		next := schedule.Next(prev)
+		if next.IsZero() || next.After(to) {
+			break
+		}
+		if gap := next.Sub(prev); shortest == 0 || gap < shortest {
+			shortest = gap
+		}
+		prev = next
+	}
+	return shortest
+}
@@ -0,0 +1,69 @@
+// Package predict estimates run completion from past run durations and
+// compares it with a job's schedule.
+package predict
+
+import (
+	"sort"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	// Samples is how many recent successful runs estimates are based on.
+	Samples = 20
+	// MinSamples is how many past durations are needed before estimating.
+	MinSamples = 3
+)
+
+// Percentile returns the p-th percentile (0-100) of durations using the
+// nearest-rank method, or 0 for an empty slice. durations is not modified.
+func Percentile(durations []int64, p float64) int64 {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]int64(nil), durations...)
+	sort.Slice(sorted, func(i, k int) bool { return sorted[i] < sorted[k] })
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// Estimate is the expected outcome of one run.
+type Estimate struct {
+	ExpectedFinish time.Time
+	// WindowEnd is the job's next fire time after the run started; zero if
+	// the schedule has none.
+	WindowEnd time.Time
+	// Overrun is true when the run is expected to still be going at
+	// WindowEnd.
+	Overrun bool
+}
+
+// Run estimates a run that started at startedAt, given the typical
+// duration and the job's schedule.
+func Run(schedule cron.Schedule, startedAt time.Time, typical time.Duration) Estimate {
+	e := Estimate{ExpectedFinish: startedAt.Add(typical)}
+	if schedule == nil {
+		return e
+	}
+	e.WindowEnd = schedule.Next(startedAt)
+	e.Overrun = !e.WindowEnd.IsZero() && e.ExpectedFinish.After(e.WindowEnd)
+	return e
+}
+
+// Overlaps reports whether a typical run started at the next fire time
+// would still be going at the fire after it.
+func Overlaps(schedule cron.Schedule, now time.Time, typical time.Duration) bool {
+	next := schedule.Next(now)
+	if next.IsZero() {
+		return false
+	}
+	following := schedule.Next(next)
+	return !following.IsZero() && typical > following.Sub(next)
+}
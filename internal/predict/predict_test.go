@@ -0,0 +1,134 @@
+package predict
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+func TestPercentile(t *testing.T) {
+	t.Parallel()
+
+	ds := []int64{50, 10, 40, 20, 30}
+	if got := Percentile(ds, 50); got != 30 {
+		t.Fatalf("p50 = %d, want 30", got)
+	}
+	if got := Percentile(ds, 100); got != 50 {
+		t.Fatalf("p100 = %d, want 50", got)
+	}
+	if got := Percentile(nil, 50); got != 0 {
+		t.Fatalf("empty p50 = %d, want 0", got)
+	}
+	if ds[0] != 50 {
+		t.Fatal("input was reordered")
+	}
+}
+
+func TestRunAndOverlaps(t *testing.T) {
+	t.Parallel()
+
+	every10, err := cron.ParseStandard("*/10 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	e := Run(every10, start, 15*time.Minute)
+	if !e.Overrun || !e.WindowEnd.Equal(start.Add(10*time.Minute)) {
+		t.Fatalf("expected overrun of the 12:10 fire, got %+v", e)
+	}
+	if e := Run(every10, start, 5*time.Minute); e.Overrun {
+		t.Fatalf("expected no overrun, got %+v", e)
+	}
+	if !Overlaps(every10, start, 11*time.Minute) || Overlaps(every10, start, 9*time.Minute) {
+		t.Fatal("unexpected overlap prediction")
+	}
+}
+
+func TestForecastRuns(t *testing.T) {
+	t.Parallel()
+
+	parse := func(spec string) cron.Schedule {
+		s, err := cron.ParseStandard(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	from := time.Date(2026, 1, 1, 0, 0, 30, 0, time.UTC)
+	to := from.Add(time.Hour)
+	f := ForecastRuns([]ForecastJob{
+		{Name: "backup", Schedule: parse("0 * * * *"), Typical: 20 * time.Minute, Heavy: true},
+		{Name: "report", Schedule: parse("10 * * * *"), Typical: 15 * time.Minute, Heavy: true},
+		{Name: "ping", Schedule: parse("*/5 * * * *")},
+	}, from, to)
+
+	if f.Fires["backup"] != 1 || f.Fires["report"] != 1 || f.Fires["ping"] != 12 {
+		t.Fatalf("fires = %v", f.Fires)
+	}
+	// backup at 01:00 runs through 01:19 and report 00:10-00:24; ping
+	// fires every five minutes.
+	byMinute := map[int][]string{}
+	for _, m := range f.Minutes {
+		byMinute[int(m.At.Sub(from.Truncate(time.Minute))/time.Minute)] = m.Jobs
+	}
+	if got := byMinute[10]; len(got) != 2 || got[0] != "ping" || got[1] != "report" {
+		t.Fatalf("minute 10 = %v", got)
+	}
+	if got := byMinute[11]; len(got) != 1 || got[0] != "report" {
+		t.Fatalf("minute 11 = %v", got)
+	}
+	if _, ok := byMinute[31]; ok {
+		t.Fatalf("minute 31 = %v, want no runs", byMinute[31])
+	}
+	if len(f.Overlaps) != 0 {
+		t.Fatalf("overlaps = %+v", f.Overlaps)
+	}
+
+	f = ForecastRuns([]ForecastJob{
+		{Name: "backup", Schedule: parse("0 * * * *"), Typical: 20 * time.Minute, Heavy: true},
+		{Name: "report", Schedule: parse("10 * * * *"), Typical: 15 * time.Minute, Heavy: true},
+	}, from.Add(-time.Hour), to)
+	// 00:00 backup runs through 00:19, overlapping report from 00:10.
+	if len(f.Overlaps) != 1 || f.Overlaps[0].From.Minute() != 10 || f.Overlaps[0].To.Minute() != 20 {
+		t.Fatalf("overlaps = %+v", f.Overlaps)
+	}
+}
+
+func TestWorstCollisionAndMinInterval(t *testing.T) {
+	t.Parallel()
+
+	parse := func(spec string) cron.Schedule {
+		s, err := cron.ParseStandard(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(7 * 24 * time.Hour)
+	others := map[string]cron.Schedule{
+		"hourly":  parse("0 * * * *"),
+		"nightly": parse("0 2 * * *"),
+		"weekly":  parse("0 2 * * 0"),
+		"offset":  parse("30 * * * *"),
+	}
+	c, ok := WorstCollision(parse("0 2 * * *"), others, from, to)
+	if !ok || len(c.Jobs) != 3 || c.Jobs[0] != "hourly" || c.Jobs[2] != "weekly" {
+		t.Fatalf("collision = %+v, %v", c, ok)
+	}
+	if c.At.Weekday() != time.Sunday || c.At.Hour() != 2 {
+		t.Fatalf("collision at %s, want Sunday 02:00", c.At)
+	}
+	if _, ok := WorstCollision(parse("15 * * * *"), others, from, to); ok {
+		t.Fatal("found a collision for minute 15")
+	}
+
+	if got := MinInterval(parse("0 9,17 * * *"), from, to); got != 8*time.Hour {
+		t.Fatalf("interval = %s, want 8h", got)
+	}
+	if got := MinInterval(parse("0 0 1 1 *"), from, to); got != 0 {
+		t.Fatalf("yearly interval = %s, want 0", got)
+	}
+}
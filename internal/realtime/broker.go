@@ -1,6 +1,7 @@
 package realtime
 
 import (
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -8,41 +9,74 @@ import (
 
 // Event is a server-side realtime event pushed to UI clients.
 type Event struct {
-	ID      int64     `json:"id"`
-	Type    string    `json:"type"`
-	JobName string    `json:"job_name,omitempty"`
-	RunID   string    `json:"run_id,omitempty"`
-	Action  string    `json:"action,omitempty"`
-	Status  string    `json:"status,omitempty"`
-	Trigger string    `json:"trigger,omitempty"`
-	At      time.Time `json:"at"`
+	ID         int64     `json:"id"`
+	Type       string    `json:"type"`
+	JobName    string    `json:"job_name,omitempty"`
+	RunID      string    `json:"run_id,omitempty"`
+	Action     string    `json:"action,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	Trigger    string    `json:"trigger,omitempty"`
+	BackfillID string    `json:"backfill_id,omitempty"`
+	At         time.Time `json:"at"`
 }
 
-// Broker is an in-memory fan-out event bus for SSE subscribers.
+// historySize is how many recent events are kept for cursor-based reads.
+const historySize = 1024
+
+// Broker is an in-memory fan-out event bus for SSE subscribers. It also
+// keeps the most recent events so polling clients can catch up by event ID.
 type Broker struct {
-	mu     sync.RWMutex
-	nextID atomic.Int64
-	nextCh int64
-	subs   map[int64]chan Event
+	mu        sync.RWMutex
+	nextID    atomic.Int64
+	nextCh    int64
+	subs      map[int64]chan Event
+	history   []Event
+	epoch     string
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
 // NewBroker creates a Broker.
 func NewBroker() *Broker {
 	return &Broker{
-		subs: make(map[int64]chan Event),
+		subs:  make(map[int64]chan Event),
+		epoch: strconv.FormatInt(time.Now().UnixNano(), 36),
+		done:  make(chan struct{}),
 	}
 }
 
+// Epoch identifies this broker instance. Event IDs restart from 1 with a
+// new epoch, so clients holding a cursor from another epoch must resync.
+func (b *Broker) Epoch() string {
+	return b.epoch
+}
+
+// Close signals subscribers that the server is shutting down.
+// It is safe to call more than once.
+func (b *Broker) Close() {
+	b.closeOnce.Do(func() { close(b.done) })
+}
+
+// Done is closed when the broker is shutting down.
+func (b *Broker) Done() <-chan struct{} {
+	return b.done
+}
+
 // Publish broadcasts an event to all active subscribers.
 // Slow subscribers drop events instead of blocking producers.
 func (b *Broker) Publish(evt Event) {
-	evt.ID = b.nextID.Add(1)
 	if evt.At.IsZero() {
 		evt.At = time.Now().UTC()
 	}
 
-	b.mu.RLock()
-	defer b.mu.RUnlock()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	evt.ID = b.nextID.Add(1)
+	if len(b.history) == historySize {
+		copy(b.history, b.history[1:])
+		b.history = b.history[:historySize-1]
+	}
+	b.history = append(b.history, evt)
 	for _, ch := range b.subs {
 		select {
 		case ch <- evt:
@@ -51,6 +85,42 @@ func (b *Broker) Publish(evt Event) {
 	}
 }
 
+// LatestID returns the ID of the most recent event, 0 if none.
+func (b *Broker) LatestID() int64 {
+	return b.nextID.Load()
+}
+
+// Since returns buffered events with IDs greater than afterID, oldest first,
+// along with the latest event ID. complete is false when events after
+// afterID have already been evicted from the buffer, or afterID is ahead of
+// the latest ID (as after a restart).
+func (b *Broker) Since(afterID int64) (events []Event, latestID int64, complete bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	latestID = b.nextID.Load()
+	if afterID > latestID {
+		return nil, latestID, false
+	}
+	complete = true
+	if len(b.history) > 0 && b.history[0].ID > afterID+1 {
+		complete = false
+	}
+	for _, evt := range b.history {
+		if evt.ID > afterID {
+			events = append(events, evt)
+		}
+	}
+	return events, latestID, complete
+}
+
+// Subscribers returns how many subscribers are connected.
+func (b *Broker) Subscribers() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs)
+}
+
 // Subscribe registers a subscriber and returns an event channel and cancel func.
 func (b *Broker) Subscribe() (<-chan Event, func()) {
 	id := atomic.AddInt64(&b.nextCh, 1)
@@ -0,0 +1,291 @@
+// Package replica keeps a read-only cronbat's view of another instance
+// current. The scheduling instance owns the jobs directory and database; a
+// replica re-reads both on an interval and turns the run changes it finds
+// into realtime events, so its dashboard and SSE stream follow along.
+package replica
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/realtime"
+	"github.com/patrickspencer/cronbat/internal/scheduler"
+	"github.com/patrickspencer/cronbat/internal/store"
+	"github.com/robfig/cron/v3"
+)
+
+// DefaultPollInterval is how often a replica re-reads jobs and runs.
+const DefaultPollInterval = 5 * time.Second
+
+// Jobs is the replica's copy of the job definitions and their states.
+type Jobs struct {
+	dir    string
+	states func(ctx context.Context) (map[string]*store.JobState, error)
+
+	mu         sync.RWMutex
+	jobs       map[string]*config.Job
+	loadErrors []config.LoadError
+	state      map[string]string
+	snooze     map[string]time.Time
+	version    atomic.Uint64
+}
+
+// NewJobs returns an empty set of jobs read from dir, with runtime states
+// from states. Call Reload to fill it.
+func NewJobs(dir string, states func(ctx context.Context) (map[string]*store.JobState, error)) *Jobs {
+	return &Jobs{dir: dir, states: states}
+}
+
+// Reload re-reads the jobs directory and the saved job states. The
+// version changes only if something did.
+func (js *Jobs) Reload(ctx context.Context) error {
+	loaded, loadErrors, err := config.LoadJobsReport(js.dir)
+	if err != nil {
+		return err
+	}
+	saved, err := js.states(ctx)
+	if err != nil {
+		return err
+	}
+	jobs := make(map[string]*config.Job, len(loaded))
+	state := make(map[string]string, len(loaded))
+	snooze := make(map[string]time.Time)
+	for _, j := range loaded {
+		// FilePath differs per host and is not shown.
+		j.FilePath = ""
+		jobs[j.Name] = j
+		if j.IsEnabled() {
+			state[j.Name] = "started"
+			continue
+		}
+		// The same rules as the daemon's startup: a paused job stays
+		// paused, and its saved reason fills in a missing one.
+		state[j.Name] = "stopped"
+		if s := saved[j.Name]; s != nil {
+			if s.State == "paused" {
+				state[j.Name] = "paused"
+				if s.SnoozeUntil != nil {
+					snooze[j.Name] = *s.SnoozeUntil
+				}
+			}
+			if j.DisabledReason == "" {
+				j.DisabledReason = s.DisabledReason
+			}
+		}
+	}
+
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	if !reflect.DeepEqual(jobs, js.jobs) || !reflect.DeepEqual(state, js.state) || !reflect.DeepEqual(snooze, js.snooze) {
+		js.version.Add(1)
+	}
+	js.jobs, js.loadErrors, js.state, js.snooze = jobs, loadErrors, state, snooze
+	return nil
+}
+
+// Version changes whenever a reload finds different jobs or states.
+func (js *Jobs) Version() uint64 {
+	return js.version.Load()
+}
+
+// List returns copies of the jobs.
+func (js *Jobs) List() []*config.Job {
+	js.mu.RLock()
+	defer js.mu.RUnlock()
+	out := make([]*config.Job, 0, len(js.jobs))
+	for _, j := range js.jobs {
+		cp := *j
+		if j.Enabled != nil {
+			v := *j.Enabled
+			cp.Enabled = &v
+		}
+		out = append(out, &cp)
+	}
+	return out
+}
+
+// LoadErrors returns the job files that failed to load.
+func (js *Jobs) LoadErrors() []config.LoadError {
+	js.mu.RLock()
+	defer js.mu.RUnlock()
+	out := make([]config.LoadError, len(js.loadErrors))
+	copy(out, js.loadErrors)
+	return out
+}
+
+// State returns "started", "stopped", or "paused", or "" for an unknown
+// job.
+func (js *Jobs) State(name string) string {
+	js.mu.RLock()
+	defer js.mu.RUnlock()
+	return js.state[name]
+}
+
+// SnoozeUntil returns when a snoozed job resumes.
+func (js *Jobs) SnoozeUntil(name string) (time.Time, bool) {
+	js.mu.RLock()
+	defer js.mu.RUnlock()
+	t, ok := js.snooze[name]
+	return t, ok
+}
+
+// Schedules returns the parsed schedule of each started, scheduled job,
+// like the daemon's scheduler holds them.
+func (js *Jobs) Schedules() map[string]cron.Schedule {
+	js.mu.RLock()
+	defer js.mu.RUnlock()
+	now := time.Now()
+	out := make(map[string]cron.Schedule)
+	for name, j := range js.jobs {
+		if js.state[name] != "started" || j.IsService() {
+			continue
+		}
+		policy, err := scheduler.ParseDSTPolicy(j.DSTPolicy)
+		if err != nil {
+			continue
+		}
+		schedule, err := scheduler.ParseScheduleWithDST(j.Schedule, policy)
+		if err != nil {
+			continue
+		}
+		if at, ok := schedule.(scheduler.AtSchedule); ok && !at.At.After(now) {
+			continue
+		}
+		out[name] = schedule
+	}
+	return out
+}
+
+// NextRunTime returns when a started job fires next.
+func (js *Jobs) NextRunTime(name string) (time.Time, bool) {
+	schedule, ok := js.Schedules()[name]
+	if !ok {
+		return time.Time{}, false
+	}
+	next := scheduler.NextTime(schedule, time.Now())
+	return next, !next.IsZero()
+}
+
+// RunSource is the part of the store the Poller reads.
+type RunSource interface {
+	ListRuns(ctx context.Context, opts store.ListOpts) ([]*store.Run, error)
+	GetRun(ctx context.Context, id string) (*store.Run, error)
+}
+
+const (
+	// pollSlack widens each poll's window, so runs recorded a little after
+	// they started, such as those reported by "cronbat wrap", are found.
+	pollSlack = time.Minute
+	// maxPollRuns bounds the runs read per poll.
+	maxPollRuns = 1000
+)
+
+// Poller finds runs that started or finished since its last poll and
+// publishes run.started and run.completed events for them.
+type Poller struct {
+	src     RunSource
+	publish func(realtime.Event)
+
+	since time.Time
+	// running holds the job of each run last seen running; done holds the
+	// start of each finished run already reported, until it leaves the
+	// window.
+	running map[string]string
+	done    map[string]time.Time
+	primed  bool
+}
+
+// NewPoller returns a Poller reading runs from src.
+func NewPoller(src RunSource, publish func(realtime.Event)) *Poller {
+	return &Poller{
+		src:     src,
+		publish: publish,
+		running: make(map[string]string),
+		done:    make(map[string]time.Time),
+	}
+}
+
+// Poll reads the runs that changed since the last poll. The first poll
+// reads the latest runs to note what is running and publishes nothing.
+func (p *Poller) Poll(ctx context.Context, now time.Time) error {
+	opts := store.ListOpts{Limit: maxPollRuns}
+	if p.primed {
+		opts.Since = p.since.Add(-pollSlack)
+	}
+	runs, err := p.src.ListRuns(ctx, opts)
+	if err != nil {
+		return err
+	}
+	listed := make(map[string]bool, len(runs))
+	// Oldest first, so events come in the order runs started.
+	for i := len(runs) - 1; i >= 0; i-- {
+		listed[runs[i].ID] = true
+		p.observe(runs[i])
+	}
+	// Runs that started before the window are looked up one by one.
+	for id := range p.running {
+		if listed[id] {
+			continue
+		}
+		run, err := p.src.GetRun(ctx, id)
+		if err != nil {
+			return err
+		}
+		if run == nil {
+			delete(p.running, id)
+			continue
+		}
+		p.observe(run)
+	}
+
+	// Runs that started before the next window cannot be listed again.
+	p.since = now
+	for id, started := range p.done {
+		if started.Before(now.Add(-pollSlack)) {
+			delete(p.done, id)
+		}
+	}
+	p.primed = true
+	return nil
+}
+
+func (p *Poller) observe(run *store.Run) {
+	if _, ok := p.done[run.ID]; ok {
+		return
+	}
+	_, wasRunning := p.running[run.ID]
+	if run.Status == "running" {
+		if !wasRunning {
+			p.running[run.ID] = run.JobName
+			p.emit("run.started", run)
+		}
+		return
+	}
+	delete(p.running, run.ID)
+	p.done[run.ID] = run.StartedAt
+	if !wasRunning {
+		p.emit("run.started", run)
+	}
+	p.emit("run.completed", run)
+}
+
+func (p *Poller) emit(typ string, run *store.Run) {
+	if !p.primed {
+		return
+	}
+	status := run.Status
+	if typ == "run.started" {
+		status = "running"
+	}
+	p.publish(realtime.Event{
+		Type:    typ,
+		JobName: run.JobName,
+		RunID:   run.ID,
+		Status:  status,
+		Trigger: run.Trigger,
+	})
+}
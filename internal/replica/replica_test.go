@@ -0,0 +1,136 @@
+package replica
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/realtime"
+	"github.com/patrickspencer/cronbat/internal/store"
+)
+
+// fakeRuns serves runs newest first, like the store.
+type fakeRuns struct {
+	runs []*store.Run
+}
+
+func (f *fakeRuns) ListRuns(_ context.Context, opts store.ListOpts) ([]*store.Run, error) {
+	var out []*store.Run
+	for i := len(f.runs) - 1; i >= 0; i-- {
+		if r := f.runs[i]; !r.StartedAt.Before(opts.Since) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeRuns) GetRun(_ context.Context, id string) (*store.Run, error) {
+	for _, r := range f.runs {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestPoller(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	src := &fakeRuns{runs: []*store.Run{
+		{ID: "old", JobName: "a", Status: "success", StartedAt: now.Add(-time.Hour)},
+		{ID: "long", JobName: "b", Status: "running", StartedAt: now.Add(-2 * time.Hour)},
+	}}
+	var got []string
+	p := NewPoller(src, func(evt realtime.Event) {
+		got = append(got, evt.Type+" "+evt.RunID+" "+evt.Status)
+	})
+	ctx := context.Background()
+	if err := p.Poll(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("first poll published %v", got)
+	}
+
+	// A run starts, another starts and finishes between polls, and a run
+	// that started long before the window finishes.
+	src.runs = append(src.runs,
+		&store.Run{ID: "new", JobName: "a", Status: "running", StartedAt: now.Add(time.Second)},
+		&store.Run{ID: "quick", JobName: "c", Status: "failure", StartedAt: now.Add(2 * time.Second)},
+	)
+	src.runs[1].Status = "success"
+	now = now.Add(5 * time.Second)
+	if err := p.Poll(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"run.started new running",
+		"run.started quick running",
+		"run.completed quick failure",
+		"run.completed long success",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("events:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	got = nil
+	src.runs[2].Status = "success"
+	if err := p.Poll(ctx, now.Add(5*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != "run.completed new success" {
+		t.Fatalf("third poll published %v", got)
+	}
+}
+
+func TestJobsReload(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	write := func(name, body string) {
+		if err := os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a", "name: a\nschedule: \"*/5 * * * *\"\ncommand: \"true\"\n")
+	write("b", "name: b\nschedule: \"0 3 * * *\"\ncommand: \"true\"\nenabled: false\n")
+	snooze := time.Now().Add(time.Hour).UTC()
+	states := map[string]*store.JobState{"b": {JobName: "b", State: "paused", SnoozeUntil: &snooze, DisabledReason: "maintenance"}}
+	js := NewJobs(dir, func(context.Context) (map[string]*store.JobState, error) { return states, nil })
+
+	ctx := context.Background()
+	if err := js.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if js.State("a") != "started" || js.State("b") != "paused" {
+		t.Fatalf("states a=%q b=%q", js.State("a"), js.State("b"))
+	}
+	if until, ok := js.SnoozeUntil("b"); !ok || !until.Equal(snooze) {
+		t.Fatalf("snooze of b = %v, %v", until, ok)
+	}
+	if _, ok := js.Schedules()["b"]; ok {
+		t.Fatal("paused job has a schedule")
+	}
+	if _, ok := js.NextRunTime("a"); !ok {
+		t.Fatal("started job has no next run")
+	}
+
+	v := js.Version()
+	if err := js.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if js.Version() != v {
+		t.Fatal("version changed without changes")
+	}
+	write("a", "name: a\nschedule: \"*/10 * * * *\"\ncommand: \"true\"\n")
+	if err := js.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if js.Version() == v {
+		t.Fatal("version unchanged after an edit")
+	}
+}
@@ -0,0 +1,177 @@
+package runlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// archivedSuffix marks the stub file left in place of an archived log file,
+// e.g. "<run>.stdout.log.archived".
+const archivedSuffix = ".archived"
+
+// archiveTimeout bounds one upload or download.
+const archiveTimeout = 2 * time.Minute
+
+// Archive stores run log files outside the local log directory.
+type Archive interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Location describes where key is stored, for display.
+	Location(key string) string
+}
+
+// archiveStub is the content of a stub file.
+type archiveStub struct {
+	Key        string    `json:"key"`
+	Location   string    `json:"location"`
+	Size       int64     `json:"size"`
+	ArchivedAt time.Time `json:"archived_at"`
+}
+
+// SetArchive enables archival: on each Cleanup pass, log files older than
+// afterDays are uploaded to a and replaced locally by small stub files.
+// Reads of archived logs fetch them back from a on demand.
+func (m *Manager) SetArchive(a Archive, afterDays int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.archive = a
+	m.archiveAfterDays = afterDays
+}
+
+func (m *Manager) currentArchive() (Archive, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.archive, m.archiveAfterDays
+}
+
+// archiveOld archives one job's files older than the archive cutoff and
+// returns the files still stored locally. Files that fail to upload are kept
+// and the first error is returned.
+func (m *Manager) archiveOld(files []logFile) ([]logFile, error) {
+	a, days := m.currentArchive()
+	if a == nil {
+		return files, nil
+	}
+	cutoff := time.Now().AddDate(0, 0, -days)
+
+	var firstErr error
+	kept := files[:0]
+	for _, f := range files {
+		if !f.modTime.Before(cutoff) {
+			kept = append(kept, f)
+			continue
+		}
+		if err := m.archiveFile(a, f.path); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("archiving %s: %w", f.path, err)
+			}
+			kept = append(kept, f)
+		}
+	}
+	return kept, firstErr
+}
+
+func (m *Manager) archiveFile(a Archive, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	key := m.archiveKey(path)
+
+	ctx, cancel := context.WithTimeout(context.Background(), archiveTimeout)
+	defer cancel()
+	if err := a.Put(ctx, key, data); err != nil {
+		return err
+	}
+
+	stub, err := json.Marshal(archiveStub{
+		Key:        key,
+		Location:   a.Location(key),
+		Size:       int64(len(data)),
+		ArchivedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	tmp := path + archivedSuffix + ".tmp"
+	if err := os.WriteFile(tmp, stub, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path+archivedSuffix); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Remove(path)
+}
+
+// archiveKey maps a local log path to its object key: "<job>/<file>".
+func (m *Manager) archiveKey(path string) string {
+	return filepath.Base(filepath.Dir(path)) + "/" + filepath.Base(path)
+}
+
+// readLogFile reads a local log file, falling back to its archived copy if
+// the file was replaced by a stub. The returned location is the local path,
+// or the archive location for archived logs.
+func (m *Manager) readLogFile(path string) ([]byte, string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil || !errors.Is(err, os.ErrNotExist) {
+		return data, path, err
+	}
+
+	stubData, stubErr := os.ReadFile(path + archivedSuffix)
+	if stubErr != nil {
+		return nil, path, err
+	}
+	var stub archiveStub
+	if err := json.Unmarshal(stubData, &stub); err != nil {
+		return nil, path, fmt.Errorf("reading archive stub: %w", err)
+	}
+
+	a, _ := m.currentArchive()
+	if a == nil {
+		return nil, stub.Location, errors.New("log is archived but no archive is configured")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), archiveTimeout)
+	defer cancel()
+	data, err = a.Get(ctx, stub.Key)
+	if err != nil {
+		return nil, stub.Location, fmt.Errorf("fetching archived log: %w", err)
+	}
+	return data, stub.Location, nil
+}
+
+// openLogFile opens a log file for ranged reads, fetching its archived copy
+// into memory if the file was replaced by a stub.
+func (m *Manager) openLogFile(path string) (io.ReaderAt, int64, string, func(), error) {
+	f, err := os.Open(path)
+	if err == nil {
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, 0, path, nil, err
+		}
+		return f, info.Size(), path, func() { f.Close() }, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, 0, path, nil, err
+	}
+
+	data, location, err := m.readLogFile(path)
+	if err != nil {
+		return nil, 0, location, nil, err
+	}
+	return bytes.NewReader(data), int64(len(data)), location, func() {}, nil
+}
+
+func isArchiveStub(name string) bool {
+	return strings.HasSuffix(name, stdoutSuffix+archivedSuffix) ||
+		strings.HasSuffix(name, stderrSuffix+archivedSuffix)
+}
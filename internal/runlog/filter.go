@@ -0,0 +1,171 @@
+package runlog
+
+import "io"
+
+// FilterOptions selects output normalization applied before storage.
+type FilterOptions struct {
+	StripANSI     bool
+	NormalizeCRLF bool
+}
+
+// Active reports whether any filter is enabled.
+func (o FilterOptions) Active() bool {
+	return o.StripANSI || o.NormalizeCRLF
+}
+
+// Wrap returns w wrapped with the selected filters. The result has a Flush
+// method that must be called once the stream ends.
+func (o FilterOptions) Wrap(w io.Writer) io.Writer {
+	if o.NormalizeCRLF {
+		w = NewCRLFWriter(w)
+	}
+	if o.StripANSI {
+		w = NewANSIStripWriter(w)
+	}
+	return w
+}
+
+type flusher interface {
+	Flush() error
+}
+
+func flushNext(w io.Writer) error {
+	if f, ok := w.(flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+type ansiState int
+
+const (
+	ansiText   ansiState = iota
+	ansiEsc              // after ESC
+	ansiCSI              // inside ESC [ ... final byte
+	ansiOSC              // inside ESC ] ... BEL or ESC \
+	ansiOSCEsc           // ESC seen inside OSC
+)
+
+// ANSIStripWriter removes ANSI escape sequences (colors, cursor movement,
+// terminal titles) from the stream. Sequences split across writes are
+// handled.
+type ANSIStripWriter struct {
+	next  io.Writer
+	state ansiState
+	buf   []byte
+}
+
+// NewANSIStripWriter wraps next with ANSI escape stripping.
+func NewANSIStripWriter(next io.Writer) *ANSIStripWriter {
+	return &ANSIStripWriter{next: next}
+}
+
+// Write implements io.Writer.
+func (a *ANSIStripWriter) Write(p []byte) (int, error) {
+	out := a.buf[:0]
+	for _, c := range p {
+		switch a.state {
+		case ansiText:
+			if c == 0x1b {
+				a.state = ansiEsc
+				continue
+			}
+			out = append(out, c)
+		case ansiEsc:
+			switch c {
+			case '[':
+				a.state = ansiCSI
+			case ']':
+				a.state = ansiOSC
+			default:
+				// Two-byte sequence such as ESC c or ESC =.
+				a.state = ansiText
+			}
+		case ansiCSI:
+			if c >= 0x40 && c <= 0x7e {
+				a.state = ansiText
+			}
+		case ansiOSC:
+			switch c {
+			case 0x07:
+				a.state = ansiText
+			case 0x1b:
+				a.state = ansiOSCEsc
+			}
+		case ansiOSCEsc:
+			if c == '\\' {
+				a.state = ansiText
+			} else {
+				a.state = ansiOSC
+			}
+		}
+	}
+	a.buf = out
+	if len(out) > 0 {
+		if _, err := a.next.Write(out); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush flushes the wrapped writer. An unterminated escape sequence at the
+// end of the stream is dropped.
+func (a *ANSIStripWriter) Flush() error {
+	return flushNext(a.next)
+}
+
+// CRLFWriter rewrites CRLF line endings to LF. A CR at the end of one write
+// is held until the next write shows whether it starts a CRLF pair.
+type CRLFWriter struct {
+	next      io.Writer
+	pendingCR bool
+	buf       []byte
+}
+
+// NewCRLFWriter wraps next with CRLF normalization.
+func NewCRLFWriter(next io.Writer) *CRLFWriter {
+	return &CRLFWriter{next: next}
+}
+
+// Write implements io.Writer.
+func (c *CRLFWriter) Write(p []byte) (int, error) {
+	out := c.buf[:0]
+	for i, b := range p {
+		if c.pendingCR {
+			c.pendingCR = false
+			if b != '\n' {
+				out = append(out, '\r')
+			}
+		}
+		if b == '\r' {
+			if i+1 < len(p) {
+				if p[i+1] != '\n' {
+					out = append(out, '\r')
+				}
+			} else {
+				c.pendingCR = true
+			}
+			continue
+		}
+		out = append(out, b)
+	}
+	c.buf = out
+	if len(out) > 0 {
+		if _, err := c.next.Write(out); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes a held trailing CR and flushes the wrapped writer.
+func (c *CRLFWriter) Flush() error {
+	if c.pendingCR {
+		c.pendingCR = false
+		if _, err := c.next.Write([]byte{'\r'}); err != nil {
+			return err
+		}
+	}
+	return flushNext(c.next)
+}
@@ -0,0 +1,37 @@
+package runlog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func writeChunks(t *testing.T, opts FilterOptions, chunks ...string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w := opts.Wrap(&buf)
+	for _, c := range chunks {
+		if n, err := w.Write([]byte(c)); err != nil || n != len(c) {
+			t.Fatalf("write %q: n=%d err=%v", c, n, err)
+		}
+	}
+	if err := w.(flusher).Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestFilterStripsANSIAcrossWrites(t *testing.T) {
+	got := writeChunks(t, FilterOptions{StripANSI: true},
+		"\x1b[1;3", "1mred\x1b[0m ", "\x1b]0;title\x07ok\x1b]2;t\x1b\\!")
+	if got != "red ok!" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestFilterNormalizesCRLFAcrossWrites(t *testing.T) {
+	got := writeChunks(t, FilterOptions{NormalizeCRLF: true},
+		"a\r\nb\r", "\nc\rd\r")
+	if got != "a\nb\nc\rd\r" {
+		t.Fatalf("got %q", got)
+	}
+}
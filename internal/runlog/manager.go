@@ -1,11 +1,16 @@
 package runlog
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"hash"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -20,6 +25,12 @@ type Manager struct {
 	maxBytesPerStream int64
 	retentionDays     int
 	maxTotalBytes     int64
+
+	mu               sync.Mutex
+	retention        func() map[string]JobRetention
+	pinned           func() (map[string]bool, error)
+	archive          Archive
+	archiveAfterDays int
 }
 
 // NewManager creates a new run log manager.
@@ -37,6 +48,73 @@ func (m *Manager) BaseDir() string {
 	return m.baseDir
 }
 
+// MaxTotalBytes returns the cap on the total size of all log files; zero
+// means no cap.
+func (m *Manager) MaxTotalBytes() int64 {
+	return m.maxTotalBytes
+}
+
+// Usage returns the total size of the log files, pinned and archive stubs
+// included.
+func (m *Manager) Usage() (int64, error) {
+	var total int64
+	err := filepath.WalkDir(m.baseDir, func(path string, d os.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	return total, err
+}
+
+// JobUsage returns the total size of one job's log files, archive stubs
+// included.
+func (m *Manager) JobUsage(jobName string) (int64, error) {
+	entries, err := os.ReadDir(filepath.Join(m.baseDir, sanitizeSegment(jobName)))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	var total int64
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		total += info.Size()
+	}
+	return total, nil
+}
+
+// CheckWritable creates and removes a file in the log directory.
+func (m *Manager) CheckWritable() error {
+	if err := os.MkdirAll(m.baseDir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(m.baseDir, ".health-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
 // Paths returns stdout/stderr log file paths for a run.
 func (m *Manager) Paths(jobName, runID string) (string, string) {
 	safeJob := sanitizeSegment(jobName)
@@ -46,36 +124,45 @@ func (m *Manager) Paths(jobName, runID string) (string, string) {
 
 // OpenRunWriters opens capped stdout/stderr writers for the run.
 func (m *Manager) OpenRunWriters(jobName, runID string) (*RunWriters, error) {
+	return m.OpenStreamWriters(jobName, runID, true, true)
+}
+
+// OpenStreamWriters opens capped writers for the selected streams only.
+// Writers for unselected streams are left nil and no file is created.
+func (m *Manager) OpenStreamWriters(jobName, runID string, stdout, stderr bool) (*RunWriters, error) {
 	stdoutPath, stderrPath := m.Paths(jobName, runID)
 	if err := os.MkdirAll(filepath.Dir(stdoutPath), 0755); err != nil {
 		return nil, err
 	}
 
-	stdoutFile, err := os.Create(stdoutPath)
-	if err != nil {
-		return nil, err
+	writers := &RunWriters{}
+	if stdout {
+		stdoutFile, err := os.Create(stdoutPath)
+		if err != nil {
+			return nil, err
+		}
+		writers.Stdout = NewCappedFileWriter(stdoutFile, m.maxBytesPerStream)
+		writers.StdoutPath = stdoutPath
 	}
-	stderrFile, err := os.Create(stderrPath)
-	if err != nil {
-		_ = stdoutFile.Close()
-		return nil, err
+	if stderr {
+		stderrFile, err := os.Create(stderrPath)
+		if err != nil {
+			_ = writers.Close()
+			return nil, err
+		}
+		writers.Stderr = NewCappedFileWriter(stderrFile, m.maxBytesPerStream)
+		writers.StderrPath = stderrPath
 	}
-
-	return &RunWriters{
-		Stdout:     NewCappedFileWriter(stdoutFile, m.maxBytesPerStream),
-		Stderr:     NewCappedFileWriter(stderrFile, m.maxBytesPerStream),
-		StdoutPath: stdoutPath,
-		StderrPath: stderrPath,
-	}, nil
+	return writers, nil
 }
 
-// ReadRunLogs reads persisted logs for the run.
-// If neither file exists, os.ErrNotExist is returned.
+// ReadRunLogs reads persisted logs for the run, fetching archived logs back
+// from the archive. If neither file exists, os.ErrNotExist is returned.
 func (m *Manager) ReadRunLogs(jobName, runID string) (stdout string, stderr string, stdoutPath string, stderrPath string, err error) {
 	stdoutPath, stderrPath = m.Paths(jobName, runID)
 
-	stdoutData, stdoutErr := os.ReadFile(stdoutPath)
-	stderrData, stderrErr := os.ReadFile(stderrPath)
+	stdoutData, stdoutPath, stdoutErr := m.readLogFile(stdoutPath)
+	stderrData, stderrPath, stderrErr := m.readLogFile(stderrPath)
 
 	switch {
 	case stdoutErr == nil && stderrErr == nil:
@@ -93,18 +180,66 @@ func (m *Manager) ReadRunLogs(jobName, runID string) (stdout string, stderr stri
 	}
 }
 
-// Cleanup removes old logs and enforces a maximum total log size.
-func (m *Manager) Cleanup() error {
-	cutoff := time.Now().AddDate(0, 0, -m.retentionDays)
+// JobRetention overrides the global retention policy for one job's logs.
+// Zero fields fall back to the global setting (or no limit).
+type JobRetention struct {
+	// MaxRuns keeps only the newest N runs' logs.
+	MaxRuns int
+	// MaxBytes caps the total size of the job's logs; oldest runs go first.
+	MaxBytes int64
+	// RetentionDays replaces the global retention_days for this job.
+	RetentionDays int
+}
+
+// SetRetentionProvider registers a function that returns per-job retention
+// overrides keyed by job name. It is consulted on every Cleanup pass.
+func (m *Manager) SetRetentionProvider(fn func() map[string]JobRetention) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retention = fn
+}
+
+// SetPinnedProvider registers a function that returns the IDs of runs whose
+// logs are pinned. Cleanup neither archives nor removes their files, and
+// they do not count toward max_total_mb.
+func (m *Manager) SetPinnedProvider(fn func() (map[string]bool, error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pinned = fn
+}
+
+type logFile struct {
+	path    string
+	runID   string
+	size    int64
+	modTime time.Time
+}
 
-	type fileInfo struct {
-		path    string
-		size    int64
-		modTime time.Time
+// Cleanup archives old logs when an archive is set, removes old logs,
+// applies per-job retention overrides, and enforces a maximum total log size.
+// Archive stubs and pinned runs' logs are not subject to retention.
+func (m *Manager) Cleanup() error {
+	m.mu.Lock()
+	provider, pinnedProvider := m.retention, m.pinned
+	m.mu.Unlock()
+
+	var pinned map[string]bool
+	if pinnedProvider != nil {
+		var err error
+		// Without the pinned set nothing can be removed safely.
+		if pinned, err = pinnedProvider(); err != nil {
+			return fmt.Errorf("list pinned runs: %w", err)
+		}
 	}
 
-	var files []fileInfo
+	policies := make(map[string]JobRetention)
+	if provider != nil {
+		for name, p := range provider() {
+			policies[sanitizeSegment(name)] = p
+		}
+	}
 
+	byJob := make(map[string][]logFile)
 	err := filepath.WalkDir(m.baseDir, func(path string, d os.DirEntry, walkErr error) error {
 		if walkErr != nil {
 			return walkErr
@@ -112,7 +247,8 @@ func (m *Manager) Cleanup() error {
 		if d.IsDir() {
 			return nil
 		}
-		if !strings.HasSuffix(path, stdoutSuffix) && !strings.HasSuffix(path, stderrSuffix) {
+		runID, ok := runIDFromLogName(d.Name())
+		if !ok || pinned[runID] {
 			return nil
 		}
 
@@ -120,14 +256,10 @@ func (m *Manager) Cleanup() error {
 		if err != nil {
 			return err
 		}
-
-		if info.ModTime().Before(cutoff) {
-			_ = os.Remove(path)
-			return nil
-		}
-
-		files = append(files, fileInfo{
+		jobDir := filepath.Base(filepath.Dir(path))
+		byJob[jobDir] = append(byJob[jobDir], logFile{
 			path:    path,
+			runID:   runID,
 			size:    info.Size(),
 			modTime: info.ModTime(),
 		})
@@ -140,8 +272,18 @@ func (m *Manager) Cleanup() error {
 		return err
 	}
 
+	var files []logFile
+	var archiveErr error
+	for jobDir, jobFiles := range byJob {
+		jobFiles, err := m.archiveOld(jobFiles)
+		if err != nil && archiveErr == nil {
+			archiveErr = err
+		}
+		files = append(files, m.applyJobRetention(jobFiles, policies[jobDir])...)
+	}
+
 	if m.maxTotalBytes <= 0 {
-		return nil
+		return archiveErr
 	}
 
 	var total int64
@@ -149,7 +291,7 @@ func (m *Manager) Cleanup() error {
 		total += f.size
 	}
 	if total <= m.maxTotalBytes {
-		return nil
+		return archiveErr
 	}
 
 	sort.Slice(files, func(i, j int) bool {
@@ -166,7 +308,117 @@ func (m *Manager) Cleanup() error {
 		total -= f.size
 	}
 
-	return nil
+	return archiveErr
+}
+
+// applyJobRetention removes one job's expired or excess log files and
+// returns the files that remain.
+func (m *Manager) applyJobRetention(files []logFile, policy JobRetention) []logFile {
+	days := m.retentionDays
+	if policy.RetentionDays > 0 {
+		days = policy.RetentionDays
+	}
+	cutoff := time.Now().AddDate(0, 0, -days)
+
+	// Run IDs are ULIDs, so sorting by ID orders runs oldest first.
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].runID != files[j].runID {
+			return files[i].runID < files[j].runID
+		}
+		return files[i].path < files[j].path
+	})
+
+	var runs []string
+	runSize := make(map[string]int64)
+	for _, f := range files {
+		if _, seen := runSize[f.runID]; !seen {
+			runs = append(runs, f.runID)
+		}
+		runSize[f.runID] += f.size
+	}
+
+	drop := make(map[string]bool)
+	if policy.MaxRuns > 0 && len(runs) > policy.MaxRuns {
+		for _, id := range runs[:len(runs)-policy.MaxRuns] {
+			drop[id] = true
+		}
+	}
+	if policy.MaxBytes > 0 {
+		var total int64
+		for _, id := range runs {
+			if !drop[id] {
+				total += runSize[id]
+			}
+		}
+		for _, id := range runs {
+			if total <= policy.MaxBytes {
+				break
+			}
+			if !drop[id] {
+				drop[id] = true
+				total -= runSize[id]
+			}
+		}
+	}
+
+	kept := files[:0]
+	for _, f := range files {
+		if drop[f.runID] || f.modTime.Before(cutoff) {
+			_ = os.Remove(f.path)
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
+
+// PurgeJob removes all persisted logs and archive stubs for a job and
+// reports how many files and bytes were removed. Archived copies are left in
+// the archive.
+func (m *Manager) PurgeJob(jobName string) (int, int64, error) {
+	dir := filepath.Join(m.baseDir, sanitizeSegment(jobName))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+
+	var removed int
+	var bytes int64
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if _, ok := runIDFromLogName(entry.Name()); !ok && !isArchiveStub(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return removed, bytes, err
+		}
+		removed++
+		bytes += info.Size()
+	}
+	_ = os.Remove(dir) // only succeeds if now empty
+	return removed, bytes, nil
+}
+
+func runIDFromLogName(name string) (string, bool) {
+	switch {
+	case strings.HasSuffix(name, stdoutSuffix):
+		return strings.TrimSuffix(name, stdoutSuffix), true
+	case strings.HasSuffix(name, stderrSuffix):
+		return strings.TrimSuffix(name, stderrSuffix), true
+	}
+	return "", false
 }
 
 // RunWriters holds stdout/stderr writers for one run.
@@ -194,11 +446,13 @@ func (r *RunWriters) Close() error {
 }
 
 // CappedFileWriter writes to a file up to maxBytes, then discards new bytes.
+// It keeps a SHA-256 of the bytes persisted.
 type CappedFileWriter struct {
 	file      *os.File
 	maxBytes  int64
 	written   int64
 	truncated bool
+	sum       hash.Hash
 }
 
 // NewCappedFileWriter creates a capped writer.
@@ -206,6 +460,7 @@ func NewCappedFileWriter(file *os.File, maxBytes int64) *CappedFileWriter {
 	return &CappedFileWriter{
 		file:     file,
 		maxBytes: maxBytes,
+		sum:      sha256.New(),
 	}
 }
 
@@ -229,6 +484,7 @@ func (w *CappedFileWriter) Write(p []byte) (int, error) {
 	}
 
 	n, err := w.file.Write(toWrite)
+	w.sum.Write(toWrite[:n])
 	if err != nil {
 		// Ignore file write errors so job execution does not fail on log storage issues.
 		return len(p), nil
@@ -247,6 +503,11 @@ func (w *CappedFileWriter) WrittenBytes() int64 {
 	return w.written
 }
 
+// Checksum returns the hex SHA-256 of the bytes persisted so far.
+func (w *CappedFileWriter) Checksum() string {
+	return hex.EncodeToString(w.sum.Sum(nil))
+}
+
 // Truncated reports whether content exceeded maxBytes.
 func (w *CappedFileWriter) Truncated() bool {
 	return w.truncated
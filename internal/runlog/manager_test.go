@@ -0,0 +1,103 @@
+package runlog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCleanupAppliesJobRetention(t *testing.T) {
+	base := t.TempDir()
+	m := NewManager(base, 1024, 30, 0)
+	m.SetRetentionProvider(func() map[string]JobRetention {
+		return map[string]JobRetention{"capped": {MaxRuns: 2}}
+	})
+
+	for _, job := range []string{"capped", "other"} {
+		for _, id := range []string{"01A", "01B", "01C"} {
+			w, err := m.OpenRunWriters(job, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = w.Stdout.Write([]byte("x"))
+			_ = w.Close()
+		}
+	}
+
+	if err := m.Cleanup(); err != nil {
+		t.Fatalf("Cleanup: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(base, "capped", "01A"+stdoutSuffix)); !os.IsNotExist(err) {
+		t.Fatalf("oldest capped run should be removed, stat err=%v", err)
+	}
+	for _, p := range []string{
+		filepath.Join(base, "capped", "01C"+stdoutSuffix),
+		filepath.Join(base, "other", "01A"+stdoutSuffix),
+	} {
+		if _, err := os.Stat(p); err != nil {
+			t.Fatalf("%s should be kept: %v", p, err)
+		}
+	}
+
+	files, _, err := m.PurgeJob("other")
+	if err != nil || files != 6 {
+		t.Fatalf("PurgeJob: files=%d err=%v", files, err)
+	}
+}
+
+func TestCleanupKeepsPinnedRuns(t *testing.T) {
+	base := t.TempDir()
+	m := NewManager(base, 1024, 30, 0)
+	m.SetRetentionProvider(func() map[string]JobRetention {
+		return map[string]JobRetention{"capped": {MaxRuns: 1}}
+	})
+	m.SetPinnedProvider(func() (map[string]bool, error) {
+		return map[string]bool{"01A": true}, nil
+	})
+
+	for _, id := range []string{"01A", "01B", "01C"} {
+		w, err := m.OpenRunWriters("capped", id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Stdout.Write([]byte("x"))
+		_ = w.Close()
+	}
+
+	if err := m.Cleanup(); err != nil {
+		t.Fatalf("Cleanup: %v", err)
+	}
+	for id, want := range map[string]bool{"01A": true, "01B": false, "01C": true} {
+		_, err := os.Stat(filepath.Join(base, "capped", id+stdoutSuffix))
+		if kept := err == nil; kept != want {
+			t.Fatalf("run %s: kept=%v, want %v", id, kept, want)
+		}
+	}
+}
+
+func TestUsageAndCheckWritable(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "logs")
+	m := NewManager(base, 1024, 30, 0)
+	if used, err := m.Usage(); err != nil || used != 0 {
+		t.Fatalf("missing dir: used=%d err=%v", used, err)
+	}
+	if err := m.CheckWritable(); err != nil {
+		t.Fatal(err)
+	}
+	w, err := m.OpenRunWriters("job", "01A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Stdout.Write([]byte("hello"))
+	_ = w.Close()
+	if used, err := m.Usage(); err != nil || used != 5 {
+		t.Fatalf("used=%d err=%v, want 5", used, err)
+	}
+	if used, err := m.JobUsage("job"); err != nil || used != 5 {
+		t.Fatalf("job used=%d err=%v, want 5", used, err)
+	}
+	if used, err := m.JobUsage("other"); err != nil || used != 0 {
+		t.Fatalf("other job used=%d err=%v, want 0", used, err)
+	}
+}
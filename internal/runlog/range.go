@@ -0,0 +1,107 @@
+package runlog
+
+import (
+	"errors"
+	"io"
+)
+
+const (
+	// DefaultRangeLimit is the number of bytes returned when no limit is given.
+	DefaultRangeLimit = 64 * 1024
+	// MaxRangeLimit caps a single range read.
+	MaxRangeLimit = 1024 * 1024
+)
+
+// LogRange is a window into one persisted log stream.
+type LogRange struct {
+	Stream     string `json:"stream"`
+	Path       string `json:"path"`
+	Offset     int64  `json:"offset"`
+	NextOffset int64  `json:"next_offset"`
+	Size       int64  `json:"size"`
+	Data       string `json:"data"`
+	EOF        bool   `json:"eof"`
+}
+
+// ReadRange reads up to limit bytes of a run's stdout or stderr log starting
+// at offset. A negative offset counts back from the end of the file, so
+// offset=-65536 returns the last 64KB. Only the requested window is read.
+// Archived logs are fetched back from the archive. If the file does not
+// exist, os.ErrNotExist is returned.
+func (m *Manager) ReadRange(jobName, runID, stream string, offset, limit int64) (*LogRange, error) {
+	stdoutPath, stderrPath := m.Paths(jobName, runID)
+	var path string
+	switch stream {
+	case "stdout":
+		path = stdoutPath
+	case "stderr":
+		path = stderrPath
+	default:
+		return nil, errors.New("stream must be stdout or stderr")
+	}
+
+	f, size, path, closeFn, err := m.openLogFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFn()
+
+	offset, limit = clampRange(offset, limit, size)
+
+	buf := make([]byte, limit)
+	n, err := f.ReadAt(buf, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return &LogRange{
+		Stream:     stream,
+		Path:       path,
+		Offset:     offset,
+		NextOffset: offset + int64(n),
+		Size:       size,
+		Data:       string(buf[:n]),
+		EOF:        offset+int64(n) >= size,
+	}, nil
+}
+
+// SliceRange applies the same offset/limit rules as ReadRange to in-memory
+// content, such as the run tail stored in the database.
+func SliceRange(stream, content string, offset, limit int64) *LogRange {
+	size := int64(len(content))
+	offset, limit = clampRange(offset, limit, size)
+	end := offset + limit
+	if end > size {
+		end = size
+	}
+	return &LogRange{
+		Stream:     stream,
+		Offset:     offset,
+		NextOffset: end,
+		Size:       size,
+		Data:       content[offset:end],
+		EOF:        end >= size,
+	}
+}
+
+func clampRange(offset, limit, size int64) (int64, int64) {
+	if limit <= 0 {
+		limit = DefaultRangeLimit
+	}
+	if limit > MaxRangeLimit {
+		limit = MaxRangeLimit
+	}
+	if offset < 0 {
+		offset += size
+		if offset < 0 {
+			offset = 0
+		}
+	}
+	if offset > size {
+		offset = size
+	}
+	if offset+limit > size {
+		limit = size - offset
+	}
+	return offset, limit
+}
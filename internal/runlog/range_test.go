@@ -0,0 +1,23 @@
+package runlog
+
+import "testing"
+
+func TestSliceRange(t *testing.T) {
+	cases := []struct {
+		offset, limit int64
+		want          string
+		eof           bool
+	}{
+		{0, 4, "0123", false},
+		{8, 100, "89", true},
+		{-3, 0, "789", true},
+		{-100, 2, "01", false},
+		{50, 5, "", true},
+	}
+	for _, c := range cases {
+		got := SliceRange("stdout", "0123456789", c.offset, c.limit)
+		if got.Data != c.want || got.EOF != c.eof || got.Size != 10 {
+			t.Errorf("offset=%d limit=%d: got %+v", c.offset, c.limit, got)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package runlog
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config describes an S3-compatible bucket used as a log archive.
+type S3Config struct {
+	// Endpoint is the service URL, e.g. "https://s3.eu-west-1.amazonaws.com"
+	// or "http://minio:9000". Defaults to AWS for Region.
+	Endpoint string
+	Region   string
+	Bucket   string
+	// Prefix is prepended to every object key.
+	Prefix string
+	// PathStyle addresses the bucket as Endpoint/Bucket/Key instead of
+	// Bucket.Endpoint/Key; most self-hosted services need it.
+	PathStyle       bool
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// S3Archive stores logs in an S3-compatible bucket using SigV4-signed
+// requests from net/http, without an SDK.
+type S3Archive struct {
+	cfg    S3Config
+	base   *url.URL
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewS3Archive validates cfg and returns an archive for it. Empty
+// credentials are read from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN.
+func NewS3Archive(cfg S3Config) (*S3Archive, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("s3 bucket is required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	if cfg.AccessKeyID == "" {
+		cfg.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	if cfg.SecretAccessKey == "" {
+		cfg.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	if cfg.SessionToken == "" {
+		cfg.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("s3 credentials are required")
+	}
+	cfg.Prefix = strings.Trim(cfg.Prefix, "/")
+
+	base, err := url.Parse(cfg.Endpoint)
+	if err != nil || base.Host == "" || (base.Scheme != "http" && base.Scheme != "https") {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.PathStyle {
+		base.Path = "/" + cfg.Bucket
+	} else {
+		base.Host = cfg.Bucket + "." + base.Host
+		base.Path = ""
+	}
+
+	return &S3Archive{
+		cfg:    cfg,
+		base:   base,
+		client: &http.Client{Timeout: archiveTimeout},
+		now:    time.Now,
+	}, nil
+}
+
+// Put uploads data under key.
+func (s *S3Archive) Put(ctx context.Context, key string, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	_, err = s.do(req, data)
+	return err
+}
+
+// Get downloads the object stored under key.
+func (s *S3Archive) Get(ctx context.Context, key string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.do(req, nil)
+}
+
+// Location returns the s3:// URL of key.
+func (s *S3Archive) Location(key string) string {
+	return "s3://" + s.cfg.Bucket + "/" + s.fullKey(key)
+}
+
+func (s *S3Archive) fullKey(key string) string {
+	if s.cfg.Prefix == "" {
+		return key
+	}
+	return s.cfg.Prefix + "/" + key
+}
+
+func (s *S3Archive) objectURL(key string) string {
+	u := *s.base
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.fullKey(key)
+	return u.String()
+}
+
+func (s *S3Archive) do(req *http.Request, payload []byte) ([]byte, error) {
+	signV4(req, payload, s.cfg, s.now().UTC())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg := strings.TrimSpace(string(body))
+		if len(msg) > 512 {
+			msg = msg[:512]
+		}
+		return nil, fmt.Errorf("s3 %s: %s: %s", req.Method, resp.Status, msg)
+	}
+	return body, nil
+}
+
+// signV4 adds AWS Signature Version 4 headers to req. Every header already
+// set on req is signed, along with Host.
+func signV4(req *http.Request, payload []byte, cfg S3Config, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", cfg.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	// Send the path exactly as it was signed.
+	canonicalPath := uriEncodePath(req.URL.Path)
+	req.URL.RawPath = canonicalPath
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalPath,
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + cfg.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+cfg.SecretAccessKey), date)
+	key = hmacSHA256(key, cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		cfg.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+// uriEncodePath encodes each path segment as SigV4 requires: everything
+// except unreserved characters is percent-encoded and '/' is kept.
+func uriEncodePath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		isAlnum := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+		if isAlnum || c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
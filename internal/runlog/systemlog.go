@@ -0,0 +1,116 @@
+package runlog
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"strings"
+	"sync"
+)
+
+// System loggers output.system_log can forward a run's output to.
+const (
+	SystemLogSyslog   = "syslog"
+	SystemLogJournald = "journald"
+)
+
+// maxSystemLogLine bounds one forwarded message; longer lines are split.
+const maxSystemLogLine = 8 * 1024
+
+// SystemLog forwards a run's output to the system logger, one message per
+// line. Forwarding never fails a run: the first error is logged and later
+// lines are dropped.
+type SystemLog struct {
+	name   string
+	send   func(stderr bool, line string) error
+	close  func() error
+	mu     sync.Mutex
+	failed bool
+	stdout *lineWriter
+	stderr *lineWriter
+}
+
+func newSystemLog(name string, send func(stderr bool, line string) error, closeFn func() error) *SystemLog {
+	l := &SystemLog{name: name, send: send, close: closeFn}
+	l.stdout = &lineWriter{log: l}
+	l.stderr = &lineWriter{log: l, stderr: true}
+	return l
+}
+
+// Stdout returns the writer for the run's stdout, logged at info priority.
+func (l *SystemLog) Stdout() io.Writer { return l.stdout }
+
+// Stderr returns the writer for the run's stderr, logged at error
+// priority.
+func (l *SystemLog) Stderr() io.Writer { return l.stderr }
+
+// Close forwards any unterminated last lines and closes the connection.
+func (l *SystemLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stdout.flushLocked()
+	l.stderr.flushLocked()
+	return l.close()
+}
+
+// emitLocked sends one line; l.mu is held.
+func (l *SystemLog) emitLocked(stderr bool, line []byte) {
+	text := strings.TrimRight(string(line), "\r")
+	if l.failed || text == "" {
+		return
+	}
+	if err := l.send(stderr, text); err != nil {
+		l.failed = true
+		log.Printf("WARN: forwarding output to %s stopped: %v", l.name, err)
+	}
+}
+
+// lineWriter splits one stream into lines for its SystemLog.
+type lineWriter struct {
+	log    *SystemLog
+	stderr bool
+	buf    []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.log.mu.Lock()
+	defer w.log.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		switch {
+		case i >= 0 && i <= maxSystemLogLine:
+			w.log.emitLocked(w.stderr, w.buf[:i])
+			w.buf = append(w.buf[:0], w.buf[i+1:]...)
+		case len(w.buf) >= maxSystemLogLine:
+			w.log.emitLocked(w.stderr, w.buf[:maxSystemLogLine])
+			w.buf = append(w.buf[:0], w.buf[maxSystemLogLine:]...)
+		default:
+			return len(p), nil
+		}
+	}
+}
+
+func (w *lineWriter) flushLocked() {
+	if len(w.buf) > 0 {
+		w.log.emitLocked(w.stderr, w.buf)
+		w.buf = w.buf[:0]
+	}
+}
+
+// journalEntry formats a line for journald's native protocol. Values must
+// not contain newlines, which lines never do.
+func journalEntry(stderr bool, tag, runID, line string) []byte {
+	priority := "6"
+	if stderr {
+		priority = "3"
+	}
+	var b bytes.Buffer
+	b.WriteString("MESSAGE=" + line + "\n")
+	b.WriteString("PRIORITY=" + priority + "\n")
+	b.WriteString("SYSLOG_IDENTIFIER=" + tag + "\n")
+	if runID != "" {
+		b.WriteString("CRONBAT_RUN_ID=" + runID + "\n")
+	}
+	return b.Bytes()
+}
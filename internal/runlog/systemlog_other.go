@@ -0,0 +1,11 @@
+//go:build !unix
+
+package runlog
+
+import "fmt"
+
+// OpenSystemLog reports that there is no system logger to forward to on
+// this platform.
+func OpenSystemLog(target, jobName, runID string) (*SystemLog, error) {
+	return nil, fmt.Errorf("%s is not available on this platform", target)
+}
@@ -0,0 +1,67 @@
+package runlog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSystemLogSplitsLines(t *testing.T) {
+	var got []string
+	closed := false
+	l := newSystemLog("test", func(stderr bool, line string) error {
+		got = append(got, fmt.Sprintf("%v:%s", stderr, line))
+		return nil
+	}, func() error {
+		closed = true
+		return nil
+	})
+
+	fmt.Fprint(l.Stdout(), "one\r\ntw")
+	fmt.Fprint(l.Stdout(), "o\n\n")
+	fmt.Fprint(l.Stderr(), "oops\n")
+	fmt.Fprint(l.Stdout(), strings.Repeat("x", maxSystemLogLine+1)+"\nthree")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"false:one",
+		"false:two",
+		"true:oops",
+		"false:" + strings.Repeat("x", maxSystemLogLine),
+		"false:x",
+		"false:three",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %d lines, want %d: %.200q", len(got), len(want), got)
+	}
+	if !closed {
+		t.Fatal("Close did not close the connection")
+	}
+}
+
+func TestSystemLogStopsAfterError(t *testing.T) {
+	sends := 0
+	l := newSystemLog("test", func(bool, string) error {
+		sends++
+		return errors.New("connection refused")
+	}, func() error { return nil })
+
+	n, err := fmt.Fprint(l.Stdout(), "a\nb\nc\n")
+	if err != nil || n != 6 {
+		t.Fatalf("Write = %d, %v; want 6, nil", n, err)
+	}
+	if sends != 1 {
+		t.Fatalf("sends = %d, want 1", sends)
+	}
+}
+
+func TestJournalEntry(t *testing.T) {
+	got := string(journalEntry(true, "backup", "run-1", "disk full"))
+	want := "MESSAGE=disk full\nPRIORITY=3\nSYSLOG_IDENTIFIER=backup\nCRONBAT_RUN_ID=run-1\n"
+	if got != want {
+		t.Fatalf("entry = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,41 @@
+//go:build unix
+
+package runlog
+
+import (
+	"fmt"
+	"log/syslog"
+	"net"
+)
+
+// journaldSocket is where journald accepts native protocol datagrams.
+var journaldSocket = "/run/systemd/journal/socket"
+
+// OpenSystemLog connects to the local syslog or journald to forward one
+// run's output, tagged with the job name. Journal entries also carry the
+// run ID as CRONBAT_RUN_ID.
+func OpenSystemLog(target, jobName, runID string) (*SystemLog, error) {
+	switch target {
+	case SystemLogSyslog:
+		w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_CRON, jobName)
+		if err != nil {
+			return nil, err
+		}
+		return newSystemLog(target, func(stderr bool, line string) error {
+			if stderr {
+				return w.Err(line)
+			}
+			return w.Info(line)
+		}, w.Close), nil
+	case SystemLogJournald:
+		conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journaldSocket, Net: "unixgram"})
+		if err != nil {
+			return nil, err
+		}
+		return newSystemLog(target, func(stderr bool, line string) error {
+			_, err := conn.Write(journalEntry(stderr, jobName, runID, line))
+			return err
+		}, conn.Close), nil
+	}
+	return nil, fmt.Errorf("unknown system log %q", target)
+}
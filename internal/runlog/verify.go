@@ -0,0 +1,71 @@
+package runlog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+)
+
+// Log check statuses.
+const (
+	CheckOK       = "ok"
+	CheckMismatch = "mismatch"
+	CheckMissing  = "missing"
+	CheckError    = "error"
+)
+
+// LogCheck is the result of re-hashing one persisted log stream.
+type LogCheck struct {
+	Stream   string `json:"stream"`
+	Path     string `json:"path"`
+	Status   string `json:"status"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Verify re-hashes a run's log files, fetching archived ones back, and
+// compares them with the SHA-256 sums recorded when the files were closed.
+// Streams with no recorded sum were not persisted and are not checked.
+func (m *Manager) Verify(jobName, runID, stdoutSum, stderrSum string) []LogCheck {
+	stdoutPath, stderrPath := m.Paths(jobName, runID)
+	var checks []LogCheck
+	for _, s := range []struct{ stream, path, want string }{
+		{"stdout", stdoutPath, stdoutSum},
+		{"stderr", stderrPath, stderrSum},
+	} {
+		if s.want != "" {
+			checks = append(checks, m.verifyFile(s.stream, s.path, s.want))
+		}
+	}
+	return checks
+}
+
+func (m *Manager) verifyFile(stream, path, want string) LogCheck {
+	c := LogCheck{Stream: stream, Path: path, Expected: want}
+	r, size, location, closeFn, err := m.openLogFile(path)
+	c.Path = location
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			c.Status = CheckMissing
+		} else {
+			c.Status, c.Error = CheckError, err.Error()
+		}
+		return c
+	}
+	defer closeFn()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(r, 0, size)); err != nil {
+		c.Status, c.Error = CheckError, err.Error()
+		return c
+	}
+	c.Actual = hex.EncodeToString(h.Sum(nil))
+	c.Status = CheckOK
+	if c.Actual != want {
+		c.Status = CheckMismatch
+	}
+	return c
+}
@@ -0,0 +1,43 @@
+package runlog
+
+import (
+	"os"
+	"testing"
+)
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	m := NewManager(t.TempDir(), 1024, 30, 0)
+	w, err := m.OpenRunWriters("job", "01A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Stdout.Write([]byte("hello\n"))
+	_ = w.Close()
+	stdoutSum, stderrSum := w.Stdout.Checksum(), w.Stderr.Checksum()
+
+	statuses := func() map[string]string {
+		got := make(map[string]string)
+		for _, c := range m.Verify("job", "01A", stdoutSum, stderrSum) {
+			got[c.Stream] = c.Status
+		}
+		return got
+	}
+	if got := statuses(); got["stdout"] != CheckOK || got["stderr"] != CheckOK {
+		t.Fatalf("fresh logs: %v", got)
+	}
+
+	stdoutPath, stderrPath := m.Paths("job", "01A")
+	if err := os.WriteFile(stdoutPath, []byte("hellO\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(stderrPath); err != nil {
+		t.Fatal(err)
+	}
+	if got := statuses(); got["stdout"] != CheckMismatch || got["stderr"] != CheckMissing {
+		t.Fatalf("tampered logs: %v", got)
+	}
+
+	if checks := m.Verify("job", "01A", "", ""); len(checks) != 0 {
+		t.Fatalf("expected no checks without recorded sums, got %v", checks)
+	}
+}
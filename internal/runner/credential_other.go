@@ -0,0 +1,27 @@
+//go:build !unix
+
+package runner
+
+import (
+	"errors"
+	"os/exec"
+)
+
+var errRunAsUnsupported = errors.New("user/group switching is not supported on this platform")
+
+// ValidateRunAs reports an error when a user or group is requested, since
+// switching credentials is not supported on this platform.
+func ValidateRunAs(username, groupname string) error {
+	if username == "" && groupname == "" {
+		return nil
+	}
+	return errRunAsUnsupported
+}
+
+func applyCredential(cmd *exec.Cmd, username, groupname string) error {
+	return ValidateRunAs(username, groupname)
+}
+
+func chownRunAs(path, username, groupname string) error {
+	return ValidateRunAs(username, groupname)
+}
@@ -0,0 +1,108 @@
+//go:build unix
+
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// geteuid is os.Geteuid, replaced in tests to act as an unprivileged daemon.
+var geteuid = os.Geteuid
+
+// ValidateRunAs checks that the user (and optional group) a job should run
+// as exist and that the daemon is able to switch to them.
+func ValidateRunAs(username, groupname string) error {
+	_, _, err := resolveCredential(username, groupname)
+	return err
+}
+
+// resolveCredential looks up uid/gid and supplementary groups for a job's
+// user/group settings. It returns nil when no switch is requested.
+func resolveCredential(username, groupname string) (*syscall.Credential, *user.User, error) {
+	if username == "" && groupname == "" {
+		return nil, nil, nil
+	}
+
+	var u *user.User
+	var err error
+	if username != "" {
+		u, err = user.Lookup(username)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid user %q: %w", username, err)
+		}
+	} else {
+		u, err = user.Current()
+		if err != nil {
+			return nil, nil, fmt.Errorf("lookup current user: %w", err)
+		}
+	}
+
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid uid for user %q: %w", u.Username, err)
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid gid for user %q: %w", u.Username, err)
+	}
+
+	if groupname != "" {
+		g, err := user.LookupGroup(groupname)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid group %q: %w", groupname, err)
+		}
+		gid, err = strconv.ParseUint(g.Gid, 10, 32)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid gid for group %q: %w", groupname, err)
+		}
+	}
+
+	if geteuid() != 0 && (uint32(uid) != uint32(os.Getuid()) || uint32(gid) != uint32(os.Getgid())) {
+		return nil, nil, errors.New("switching user/group requires cronbat to run as root")
+	}
+
+	cred := &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}
+	if username != "" {
+		if ids, err := u.GroupIds(); err == nil {
+			for _, id := range ids {
+				if v, err := strconv.ParseUint(id, 10, 32); err == nil {
+					cred.Groups = append(cred.Groups, uint32(v))
+				}
+			}
+		}
+	}
+	return cred, u, nil
+}
+
+// applyCredential configures cmd to run as the requested user/group and
+// points HOME/USER/LOGNAME at that user, as cron does.
+func applyCredential(cmd *exec.Cmd, username, groupname string) error {
+	cred, u, err := resolveCredential(username, groupname)
+	if err != nil || cred == nil {
+		return err
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = cred
+	if username != "" {
+		cmd.Env = append(cmd.Env, "HOME="+u.HomeDir, "USER="+u.Username, "LOGNAME="+u.Username)
+	}
+	return nil
+}
+
+// chownRunAs gives path to the user/group a job runs as, so the job can
+// write to a file the daemon created for it.
+func chownRunAs(path, username, groupname string) error {
+	cred, _, err := resolveCredential(username, groupname)
+	if err != nil || cred == nil {
+		return err
+	}
+	return os.Chown(path, int(cred.Uid), int(cred.Gid))
+}
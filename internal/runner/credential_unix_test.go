@@ -0,0 +1,63 @@
+//go:build unix
+
+package runner
+
+import (
+	"os"
+	"os/exec"
+	"os/user"
+	"strings"
+	"testing"
+)
+
+func TestResolveCredential(t *testing.T) {
+	cred, _, err := resolveCredential("", "")
+	if err != nil || cred != nil {
+		t.Fatalf("no user or group = %+v, %v; want no switch", cred, err)
+	}
+	if _, _, err := resolveCredential("no-such-user-cronbat", ""); err == nil || !strings.Contains(err.Error(), "invalid user") {
+		t.Fatalf("unknown user: %v", err)
+	}
+	if _, _, err := resolveCredential("", "no-such-group-cronbat"); err == nil || !strings.Contains(err.Error(), "invalid group") {
+		t.Fatalf("unknown group: %v", err)
+	}
+
+	me, err := user.Current()
+	if err != nil {
+		t.Skipf("current user: %v", err)
+	}
+	other := "root"
+	if me.Uid == "0" {
+		other = "nobody"
+	}
+	if _, err := user.Lookup(other); err != nil {
+		t.Skipf("no %s user: %v", other, err)
+	}
+
+	geteuid = func() int { return 1 }
+	defer func() { geteuid = os.Geteuid }()
+	if _, _, err := resolveCredential(other, ""); err == nil || !strings.Contains(err.Error(), "requires cronbat to run as root") {
+		t.Fatalf("switch to %s without root: %v", other, err)
+	}
+}
+
+func TestApplyCredentialSetsUserEnv(t *testing.T) {
+	me, err := user.Current()
+	if err != nil {
+		t.Skipf("current user: %v", err)
+	}
+	// Switching to the daemon's own user needs no privileges.
+	cmd := exec.Command("true")
+	if err := applyCredential(cmd, me.Username, ""); err != nil {
+		t.Fatal(err)
+	}
+	if cred := cmd.SysProcAttr.Credential; cred == nil || cred.Uid != uint32(os.Getuid()) {
+		t.Fatalf("credential = %+v", cred)
+	}
+	env := strings.Join(cmd.Env, "\n")
+	for _, want := range []string{"HOME=" + me.HomeDir, "USER=" + me.Username, "LOGNAME=" + me.Username} {
+		if !strings.Contains(env, want) {
+			t.Errorf("env missing %q: %q", want, cmd.Env)
+		}
+	}
+}
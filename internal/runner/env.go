@@ -2,6 +2,7 @@ package runner
 
 import (
 	"os"
+	"strings"
 
 	"github.com/patrickspencer/cronbat/pkg/plugin"
 )
@@ -26,15 +27,11 @@ func BuildEnv(base map[string]string, job plugin.JobContext) []string {
 		envMap[k] = v
 	}
 
-	// Overlay job-specific env.
-	for k, v := range job.Env {
+	// Overlay job-specific env and cronbat metadata.
+	for k, v := range JobEnv(job) {
 		envMap[k] = v
 	}
 
-	// Add cronbat metadata.
-	envMap["CRONBAT_JOB_NAME"] = job.JobName
-	envMap["CRONBAT_TRIGGER"] = job.Trigger
-
 	// Convert to slice.
 	result := make([]string, 0, len(envMap))
 	for k, v := range envMap {
@@ -42,3 +39,45 @@ func BuildEnv(base map[string]string, job plugin.JobContext) []string {
 	}
 	return result
 }
+
+// JobEnv returns the variables a job adds to the environment it runs in:
+// its own env plus CRONBAT_JOB_NAME and CRONBAT_TRIGGER.
+func JobEnv(job plugin.JobContext) map[string]string {
+	env := make(map[string]string, len(job.Env)+2)
+	for k, v := range job.Env {
+		env[k] = v
+	}
+	env["CRONBAT_JOB_NAME"] = job.JobName
+	env["CRONBAT_TRIGGER"] = job.Trigger
+	return env
+}
+
+// secretEnvMarkers flag variables whose values RedactEnv hides.
+var secretEnvMarkers = []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "KEY", "CREDENTIAL", "AUTH", "PRIVATE", "COOKIE", "SESSION"}
+
+// RedactedValue replaces the values of secret-looking variables.
+const RedactedValue = "REDACTED"
+
+// RedactEnv turns a KEY=value slice into a map, replacing the values of
+// variables whose names look like they hold secrets.
+func RedactEnv(env []string) map[string]string {
+	out := make(map[string]string, len(env))
+	for _, e := range env {
+		k, v, _ := strings.Cut(e, "=")
+		if isSecretEnvKey(k) {
+			v = RedactedValue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func isSecretEnvKey(k string) bool {
+	upper := strings.ToUpper(k)
+	for _, m := range secretEnvMarkers {
+		if strings.Contains(upper, m) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	"time"
+)
+
+// Spec describes one process for an Executor to run.
+type Spec struct {
+	// Args is the argv; Args[0] is resolved on PATH.
+	Args []string
+	Env  []string
+	Dir  string
+	// Stdout and Stderr may be nil to discard the stream, and may be the
+	// same writer.
+	Stdout io.Writer
+	Stderr io.Writer
+	// User, Group, and Sandbox are applied by executors that support them.
+	User    string
+	Group   string
+	Sandbox *SandboxOptions
+	// OnStart, if set, is called with the process ID once the process
+	// has started, by executors that run a local process.
+	OnStart func(pid int)
+}
+
+// Executor runs a process to completion. Run returns nil on a zero exit
+// status. A non-zero exit is reported with an error that has an
+// ExitCode() int method, as *exec.ExitError does; any other error means the
+// process could not be run. The process must be stopped when ctx is done.
+type Executor interface {
+	Run(ctx context.Context, spec *Spec) error
+}
+
+// cancelWaitDelay is how long a cancelled run waits for its output pipes
+// to close.
+const cancelWaitDelay = 5 * time.Second
+
+// OSExecutor runs processes on the local host with os/exec.
+type OSExecutor struct{}
+
+// Run implements Executor.
+func (OSExecutor) Run(ctx context.Context, spec *Spec) error {
+	cmd := exec.CommandContext(ctx, spec.Args[0], spec.Args[1:]...)
+	cmd.Env = spec.Env
+	cmd.Dir = spec.Dir
+	if err := applyCredential(cmd, spec.User, spec.Group); err != nil {
+		return err
+	}
+	if err := applySandbox(cmd, spec.Sandbox, spec.Args); err != nil {
+		return err
+	}
+	applyProcessGroup(cmd)
+	// Don't wait forever on output pipes held open by processes that left
+	// the group.
+	cmd.WaitDelay = cancelWaitDelay
+	cmd.Stdout = spec.Stdout
+	cmd.Stderr = spec.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if spec.OnStart != nil {
+		spec.OnStart(cmd.Process.Pid)
+	}
+	return cmd.Wait()
+}
@@ -0,0 +1,85 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+)
+
+// OutputEnv names the file a run appends KEY=value lines to. The pairs are
+// recorded on the run and passed to the jobs that follow it.
+const OutputEnv = "CRONBAT_OUTPUT"
+
+// maxOutputBytes bounds how much of an output file is read.
+const maxOutputBytes = 64 * 1024
+
+// NewOutputFile creates an empty output file for one run, writable by the
+// user/group the run switches to.
+func NewOutputFile(username, groupname string) (string, error) {
+	f, err := os.CreateTemp("", "cronbat-output-*")
+	if err != nil {
+		return "", err
+	}
+	path := f.Name()
+	f.Close()
+	if err := chownRunAs(path, username, groupname); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// ReadOutputs parses the output file at path and removes it.
+func ReadOutputs(path string) (map[string]string, error) {
+	defer os.Remove(path)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxOutputBytes))
+	if err != nil {
+		return nil, err
+	}
+	return ParseOutputs(data), nil
+}
+
+// ParseOutputs parses KEY=value lines. Blank lines, comments, and lines
+// whose key is not a valid variable name are skipped; a repeated key keeps
+// its last value.
+func ParseOutputs(data []byte) map[string]string {
+	var out map[string]string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 4096), maxOutputBytes)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !isEnvName(key) {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]string)
+		}
+		out[key] = value
+	}
+	return out
+}
+
+func isEnvName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		letter := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !letter && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
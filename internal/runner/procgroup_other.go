@@ -0,0 +1,9 @@
+//go:build !unix
+
+package runner
+
+import "os/exec"
+
+// applyProcessGroup is a no-op where process groups are unavailable; only
+// the job's own process is killed on cancellation.
+func applyProcessGroup(cmd *exec.Cmd) {}
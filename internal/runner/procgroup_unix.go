@@ -0,0 +1,21 @@
+//go:build unix
+
+package runner
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// applyProcessGroup starts the job in its own process group and makes
+// cancellation kill the whole group, so commands the shell started (e.g.
+// "sh -c 'sleep 30'", which dash does not exec) stop with it.
+func applyProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -2,18 +2,28 @@ package runner
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
-	"os/exec"
+	"sync"
 	"time"
 
 	"github.com/patrickspencer/cronbat/pkg/plugin"
 )
 
-const ringBufSize = 64 * 1024 // 64KB
+// ErrOutputLimit is the error of a run killed for writing more than
+// RunOptions.MaxOutputBytes.
+var ErrOutputLimit = errors.New("output limit exceeded")
+
+// DefaultTailBytes is the size of each stream's tail buffer when
+// RunOptions.TailBytes is unset.
+const DefaultTailBytes = 64 * 1024 // 64KB
 
 // RingBuffer is a fixed-size circular buffer that implements io.Writer.
-// It retains only the most recent bytes written, up to its capacity.
+// It retains only the most recent bytes written, up to its capacity, and
+// is safe to read while a command is still writing to it.
 type RingBuffer struct {
+	mu   sync.Mutex
 	buf  []byte
 	size int
 	pos  int
@@ -28,6 +38,8 @@ func NewRingBuffer(size int) *RingBuffer {
 // Write implements io.Writer. It writes p into the ring buffer,
 // overwriting the oldest data if capacity is exceeded.
 func (rb *RingBuffer) Write(p []byte) (int, error) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
 	n := len(p)
 	if n >= rb.size {
 		// Data larger than buffer; keep only the tail.
@@ -56,6 +68,8 @@ func (rb *RingBuffer) Write(p []byte) (int, error) {
 
 // String returns the buffered contents in chronological order.
 func (rb *RingBuffer) String() string {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
 	if !rb.full {
 		return string(rb.buf[:rb.pos])
 	}
@@ -66,19 +80,59 @@ func (rb *RingBuffer) String() string {
 	return string(out)
 }
 
-// Runner executes shell commands for jobs.
-type Runner struct{}
+// Runner executes shell commands for jobs. It builds the command line and
+// environment, applies the timeout, and captures output; Executor starts
+// the process.
+type Runner struct {
+	Executor Executor
+}
 
 // RunOptions controls optional output destinations for a command run.
 type RunOptions struct {
 	ExtraStdout io.Writer
 	ExtraStderr io.Writer
 	WorkDir     string
+	// DiscardStdout and DiscardStderr drop the stream entirely: no tail
+	// buffer is kept and nothing is written to the extra writers.
+	DiscardStdout bool
+	DiscardStderr bool
+	// User and Group switch the process credentials (requires root).
+	User  string
+	Group string
+	// Sandbox restricts filesystem and network access (linux only).
+	Sandbox *SandboxOptions
+	// Shell selects the interpreter (default "sh"); LoginShell runs it as a
+	// login shell so profile files are sourced. See ShellCommand.
+	Shell      string
+	LoginShell bool
+	// MergeStderr sends stderr into the stdout stream (tail and log file),
+	// preserving interleaving. Stderr then follows stdout's capture setting.
+	MergeStderr bool
+	// WrapOutput, if set, wraps each captured stream ahead of its tail
+	// buffer and extra writer (e.g. to strip ANSI codes). A returned writer
+	// with a Flush method is flushed after the command exits.
+	WrapOutput func(io.Writer) io.Writer
+	// TailBytes sizes the tail buffer of each captured stream; zero means
+	// DefaultTailBytes.
+	TailBytes int
+	// OnTail, if set, is called every TailInterval while the command runs
+	// with the current output tails, so partial output can be saved.
+	TailInterval time.Duration
+	OnTail       func(stdout, stderr string)
+	// OnStart, if set, is called with the process ID once a local process
+	// has started.
+	OnStart func(pid int)
+	// MaxOutputBytes caps the combined bytes of both streams passed to the
+	// tails and extra writers; the rest is dropped after a notice line.
+	// With KillOnOutputLimit, reaching the cap also kills the command and
+	// the run fails with ErrOutputLimit.
+	MaxOutputBytes    int64
+	KillOnOutputLimit bool
 }
 
-// NewRunner creates a new Runner.
+// NewRunner creates a Runner that runs processes on the local host.
 func NewRunner() *Runner {
-	return &Runner{}
+	return &Runner{Executor: OSExecutor{}}
 }
 
 // Run executes the given shell command with the provided job context and timeout.
@@ -89,32 +143,121 @@ func (r *Runner) Run(ctx context.Context, command string, job plugin.JobContext,
 		defer cancel()
 	}
 
-	cmd := exec.CommandContext(ctx, "sh", "-c", command)
-	cmd.Env = BuildEnv(nil, job)
-	if opts != nil && opts.WorkDir != "" {
-		cmd.Dir = opts.WorkDir
+	var shell string
+	var login bool
+	if opts != nil {
+		shell, login = opts.Shell, opts.LoginShell
+	}
+	args, err := ShellCommand(shell, login, command)
+	if err != nil {
+		return &plugin.RunResult{ExitCode: -1, Error: err.Error()}
 	}
 
-	stdoutBuf := NewRingBuffer(ringBufSize)
-	stderrBuf := NewRingBuffer(ringBufSize)
+	if opts == nil {
+		opts = &RunOptions{}
+	}
+	spec := &Spec{
+		Args:    args,
+		Env:     BuildEnv(nil, job),
+		Dir:     opts.WorkDir,
+		User:    opts.User,
+		Group:   opts.Group,
+		Sandbox: opts.Sandbox,
+		OnStart: opts.OnStart,
+	}
+	var flushers []interface{ Flush() error }
+	wrap := func(w io.Writer) io.Writer {
+		if opts.WrapOutput == nil {
+			return w
+		}
+		w = opts.WrapOutput(w)
+		if f, ok := w.(interface{ Flush() error }); ok {
+			flushers = append(flushers, f)
+		}
+		return w
+	}
 
-	if opts != nil {
-		cmd.Stdout = newTeeWriter(stdoutBuf, opts.ExtraStdout)
-		cmd.Stderr = newTeeWriter(stderrBuf, opts.ExtraStderr)
-	} else {
-		cmd.Stdout = stdoutBuf
-		cmd.Stderr = stderrBuf
+	var limit *outputLimit
+	if opts.MaxOutputBytes > 0 {
+		limit = &outputLimit{max: opts.MaxOutputBytes}
+		if opts.KillOnOutputLimit {
+			var cancel context.CancelCauseFunc
+			ctx, cancel = context.WithCancelCause(ctx)
+			defer cancel(nil)
+			limit.onExceed = func() { cancel(ErrOutputLimit) }
+		}
+	}
+
+	tailBytes := opts.TailBytes
+	if tailBytes <= 0 {
+		tailBytes = DefaultTailBytes
+	}
+	var stdoutBuf, stderrBuf *RingBuffer
+	if !opts.DiscardStdout {
+		stdoutBuf = NewRingBuffer(tailBytes)
+		spec.Stdout = limit.wrap(wrap(newTeeWriter(stdoutBuf, opts.ExtraStdout)))
+	}
+	if opts.MergeStderr {
+		// Same writer for both: exec copies them through a single pipe.
+		spec.Stderr = spec.Stdout
+	} else if !opts.DiscardStderr {
+		stderrBuf = NewRingBuffer(tailBytes)
+		spec.Stderr = limit.wrap(wrap(newTeeWriter(stderrBuf, opts.ExtraStderr)))
 	}
 
+	executor := r.Executor
+	if executor == nil {
+		executor = OSExecutor{}
+	}
+	tails := func() (stdout, stderr string) {
+		if stdoutBuf != nil {
+			stdout = stdoutBuf.String()
+		}
+		if stderrBuf != nil {
+			stderr = stderrBuf.String()
+		}
+		return stdout, stderr
+	}
+	var tailDone chan struct{}
+	var tailWG sync.WaitGroup
+	if opts.OnTail != nil && opts.TailInterval > 0 && (stdoutBuf != nil || stderrBuf != nil) {
+		tailDone = make(chan struct{})
+		tailWG.Add(1)
+		go func() {
+			defer tailWG.Done()
+			ticker := time.NewTicker(opts.TailInterval)
+			defer ticker.Stop()
+			var lastOut, lastErr string
+			for {
+				select {
+				case <-tailDone:
+					return
+				case <-ticker.C:
+					stdout, stderr := tails()
+					if stdout != lastOut || stderr != lastErr {
+						opts.OnTail(stdout, stderr)
+						lastOut, lastErr = stdout, stderr
+					}
+				}
+			}
+		}()
+	}
 	start := time.Now()
-	err := cmd.Run()
+	err = executor.Run(ctx, spec)
 	durationMs := time.Since(start).Milliseconds()
+	if tailDone != nil {
+		close(tailDone)
+		tailWG.Wait()
+	}
+	for _, f := range flushers {
+		_ = f.Flush()
+	}
 
 	result := &plugin.RunResult{
-		Stdout:     stdoutBuf.String(),
-		Stderr:     stderrBuf.String(),
 		DurationMs: durationMs,
+		Exited:     err == nil,
 	}
+	result.Stdout, result.Stderr = tails()
 
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
@@ -122,12 +265,28 @@ func (r *Runner) Run(ctx context.Context, command string, job plugin.JobContext,
 		} else {
 			result.Error = err.Error()
 		}
-		if exitErr, ok := err.(*exec.ExitError); ok {
+		var exitErr interface{ ExitCode() int }
+		if errors.As(err, &exitErr) {
 			result.ExitCode = exitErr.ExitCode()
+			// A command killed at its timeout or by cancellation did not
+			// exit on its own, whatever its code.
+			result.Exited = ctx.Err() == nil && result.ExitCode >= 0
 		} else {
 			result.ExitCode = -1
 		}
 	}
+	if limit.exceeded() {
+		result.OutputLimited = true
+		if opts.KillOnOutputLimit {
+			// The command may have finished before the kill landed; it
+			// still fails.
+			result.Error = ErrOutputLimit.Error()
+			result.Exited = false
+			if result.ExitCode == 0 {
+				result.ExitCode = -1
+			}
+		}
+	}
 
 	return result
 }
@@ -154,3 +313,64 @@ func (t *teeWriter) Write(p []byte) (int, error) {
 	}
 	return n, err
 }
+
+// outputLimit counts the output of a run across both streams.
+type outputLimit struct {
+	max      int64
+	onExceed func()
+
+	mu      sync.Mutex
+	written int64
+	hit     bool
+}
+
+// wrap returns w limited by l; a nil limit returns w unchanged.
+func (l *outputLimit) wrap(w io.Writer) io.Writer {
+	if l == nil {
+		return w
+	}
+	return &limitWriter{limit: l, w: w}
+}
+
+func (l *outputLimit) exceeded() bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.hit
+}
+
+// limitWriter passes writes through until its limit is reached, then
+// writes one notice and drops the rest. It never fails, so the command is
+// not killed by a broken pipe.
+type limitWriter struct {
+	limit *outputLimit
+	w     io.Writer
+}
+
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	l := lw.limit
+	l.mu.Lock()
+	allowed := l.max - l.written
+	if allowed > int64(len(p)) {
+		allowed = int64(len(p))
+	}
+	l.written += allowed
+	first := !l.hit && allowed < int64(len(p))
+	if first {
+		l.hit = true
+	}
+	l.mu.Unlock()
+
+	if allowed > 0 {
+		_, _ = lw.w.Write(p[:allowed])
+	}
+	if first {
+		_, _ = fmt.Fprintf(lw.w, "\n[output limit of %d bytes exceeded; further output dropped]\n", l.max)
+		if l.onExceed != nil {
+			l.onExceed()
+		}
+	}
+	return len(p), nil
+}
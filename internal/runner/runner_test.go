@@ -0,0 +1,327 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrickspencer/cronbat/pkg/plugin"
+)
+
+type exitError int
+
+func (e exitError) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+func (e exitError) ExitCode() int { return int(e) }
+
+// fakeExecutor records the spec it was given and runs fn instead of a process.
+type fakeExecutor struct {
+	spec *Spec
+	fn   func(ctx context.Context, spec *Spec) error
+}
+
+func (f *fakeExecutor) Run(ctx context.Context, spec *Spec) error {
+	f.spec = spec
+	return f.fn(ctx, spec)
+}
+
+func TestRunBuildsSpecAndCapturesOutput(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeExecutor{fn: func(_ context.Context, spec *Spec) error {
+		io.WriteString(spec.Stdout, strings.Repeat("x", DefaultTailBytes)+"tail")
+		io.WriteString(spec.Stderr, "oops")
+		return exitError(3)
+	}}
+	r := &Runner{Executor: fake}
+
+	job := plugin.JobContext{JobName: "report", Trigger: "manual", Env: map[string]string{"REGION": "eu"}}
+	result := r.Run(context.Background(), "make report", job, 0, &RunOptions{WorkDir: "/srv", Shell: "bash"})
+
+	if got := strings.Join(fake.spec.Args, " "); got != "bash -c make report" {
+		t.Fatalf("unexpected args %q", got)
+	}
+	if fake.spec.Dir != "/srv" {
+		t.Fatalf("unexpected dir %q", fake.spec.Dir)
+	}
+	env := strings.Join(fake.spec.Env, "\n")
+	for _, want := range []string{"REGION=eu", "CRONBAT_JOB_NAME=report", "CRONBAT_TRIGGER=manual"} {
+		if !strings.Contains(env, want) {
+			t.Fatalf("env missing %s", want)
+		}
+	}
+
+	if result.ExitCode != 3 || result.Error != "exit status 3" {
+		t.Fatalf("unexpected result: exit=%d error=%q", result.ExitCode, result.Error)
+	}
+	if len(result.Stdout) != DefaultTailBytes || !strings.HasSuffix(result.Stdout, "tail") {
+		t.Fatalf("expected stdout capped to the last %d bytes, got %d", DefaultTailBytes, len(result.Stdout))
+	}
+	if result.Stderr != "oops" {
+		t.Fatalf("unexpected stderr %q", result.Stderr)
+	}
+
+	result = r.Run(context.Background(), "make report", job, 0, &RunOptions{TailBytes: 10})
+	if result.Stdout != "xxxxxxtail" {
+		t.Fatalf("expected stdout capped to TailBytes, got %d bytes", len(result.Stdout))
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeExecutor{fn: func(ctx context.Context, _ *Spec) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	r := &Runner{Executor: fake}
+
+	result := r.Run(context.Background(), "sleep 60", plugin.JobContext{JobName: "slow"}, 10*time.Millisecond, nil)
+	if result.Error != "timeout" || result.ExitCode != -1 {
+		t.Fatalf("expected timeout with exit -1, got exit=%d error=%q", result.ExitCode, result.Error)
+	}
+}
+
+func TestRunMergeAndDiscard(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeExecutor{fn: func(_ context.Context, spec *Spec) error {
+		if spec.Stdout != spec.Stderr {
+			return fmt.Errorf("expected merged streams")
+		}
+		return nil
+	}}
+	r := &Runner{Executor: fake}
+
+	result := r.Run(context.Background(), "true", plugin.JobContext{}, 0, &RunOptions{MergeStderr: true})
+	if result.Error != "" {
+		t.Fatal(result.Error)
+	}
+
+	fake.fn = func(_ context.Context, spec *Spec) error {
+		if spec.Stdout != nil || spec.Stderr != nil {
+			return fmt.Errorf("expected discarded streams")
+		}
+		return nil
+	}
+	result = r.Run(context.Background(), "true", plugin.JobContext{}, 0, &RunOptions{DiscardStdout: true, DiscardStderr: true})
+	if result.Error != "" {
+		t.Fatal(result.Error)
+	}
+}
+
+func TestRunOutputLimit(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeExecutor{fn: func(_ context.Context, spec *Spec) error {
+		io.WriteString(spec.Stdout, "12345")
+		io.WriteString(spec.Stderr, "67890")
+		io.WriteString(spec.Stdout, "more")
+		return nil
+	}}
+	r := &Runner{Executor: fake}
+
+	var extra strings.Builder
+	result := r.Run(context.Background(), "spam", plugin.JobContext{}, 0, &RunOptions{MaxOutputBytes: 8, ExtraStdout: &extra})
+	if result.Error != "" || result.ExitCode != 0 || !result.OutputLimited || !result.Exited {
+		t.Fatalf("expected a successful limited run, got exit=%d error=%q limited=%v", result.ExitCode, result.Error, result.OutputLimited)
+	}
+	if result.Stdout != "12345" || extra.String() != "12345" {
+		t.Fatalf("unexpected stdout %q / %q", result.Stdout, extra.String())
+	}
+	if !strings.HasPrefix(result.Stderr, "678\n[output limit of 8 bytes exceeded") {
+		t.Fatalf("unexpected stderr %q", result.Stderr)
+	}
+
+	fake.fn = func(ctx context.Context, spec *Spec) error {
+		io.WriteString(spec.Stdout, strings.Repeat("y", 100))
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	result = r.Run(context.Background(), "yes", plugin.JobContext{}, time.Minute, &RunOptions{MaxOutputBytes: 10, KillOnOutputLimit: true})
+	if result.Error != ErrOutputLimit.Error() || result.ExitCode != -1 || result.Exited {
+		t.Fatalf("expected output limit failure, got exit=%d error=%q", result.ExitCode, result.Error)
+	}
+}
+
+func TestCheckSyntax(t *testing.T) {
+	t.Parallel()
+
+	ok, _, err := CheckSyntax(context.Background(), "", "for f in a b; do echo $f; done")
+	if err != nil || !ok {
+		t.Fatalf("expected valid syntax, got ok=%v err=%v", ok, err)
+	}
+	ok, out, err := CheckSyntax(context.Background(), "sh", "if true; then echo")
+	if err != nil || ok || out == "" {
+		t.Fatalf("expected a syntax error with diagnostics, got ok=%v out=%q err=%v", ok, out, err)
+	}
+	if _, _, err := CheckSyntax(context.Background(), "python3 -c", "print(1)"); err != ErrSyntaxCheckUnsupported {
+		t.Fatalf("expected unsupported shell error, got %v", err)
+	}
+}
+
+func TestShellCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		shell string
+		login bool
+		want  []string // nil means an error
+	}{
+		{"default", "", false, []string{"sh", "-c", "echo hi"}},
+		{"plain shell", "bash", false, []string{"bash", "-c", "echo hi"}},
+		{"plain login shell", "bash", true, []string{"bash", "-l", "-c", "echo hi"}},
+		{"absolute path", "/bin/sh", false, []string{"/bin/sh", "-c", "echo hi"}},
+		{"absolute login shell", "/usr/bin/zsh", true, []string{"/usr/bin/zsh", "-l", "-c", "echo hi"}},
+		{"interpreter prefix", "python3 -c", false, []string{"python3", "-c", "echo hi"}},
+		{"login on unknown shell", "python3", true, nil},
+		{"login on shell with arguments", "bash -e", true, nil},
+	}
+	for _, tt := range tests {
+		got, err := ShellCommand(tt.shell, tt.login, "echo hi")
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("%s: ShellCommand = %q, want an error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil || fmt.Sprintf("%q", got) != fmt.Sprintf("%q", tt.want) {
+			t.Errorf("%s: ShellCommand = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestValidateShell(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		shell   string
+		login   bool
+		wantErr bool
+	}{
+		{"", false, false},
+		{"sh", true, false},
+		{"/bin/sh", false, false},
+		{"sh -e", false, false},
+		{"sh -e", true, true},
+		{"python3", true, true},
+		{"/no/such/shell", false, true},
+		{"no-such-interpreter -c", false, true},
+	} {
+		if err := ValidateShell(tt.shell, tt.login); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateShell(%q, %v) = %v, wantErr %v", tt.shell, tt.login, err, tt.wantErr)
+		}
+	}
+}
+
+func TestOSExecutorCancelStopsChildren(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	var out strings.Builder
+	start := time.Now()
+	// dash forks for "sleep" here, so only killing the group ends the run.
+	err := OSExecutor{}.Run(ctx, &Spec{Args: []string{"sh", "-c", "sleep 30; echo done"}, Stdout: &out})
+	if err == nil {
+		t.Fatal("expected the cancelled run to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("run took %s to stop after cancellation", elapsed)
+	}
+}
+
+func TestOSExecutorReportsPID(t *testing.T) {
+	t.Parallel()
+
+	var out strings.Builder
+	var pid int
+	err := OSExecutor{}.Run(context.Background(), &Spec{
+		Args:    []string{"sh", "-c", "echo $$"},
+		Stdout:  &out,
+		OnStart: func(p int) { pid = p },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(out.String()); pid == 0 || got != fmt.Sprint(pid) {
+		t.Fatalf("OnStart pid %d, process printed %q", pid, got)
+	}
+}
+
+func TestRedactEnv(t *testing.T) {
+	got := RedactEnv([]string{"PATH=/bin", "DB_PASSWORD=hunter2", "github_token=abc", "EMPTY="})
+	want := map[string]string{"PATH": "/bin", "DB_PASSWORD": RedactedValue, "github_token": RedactedValue, "EMPTY": ""}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestParseOutputs(t *testing.T) {
+	got := ParseOutputs([]byte("# comment\nROWS=42\n\nfile = /tmp/a=b\n1BAD=x\nno-dash=y\nnovalue\nROWS=43\n"))
+	want := map[string]string{"ROWS": "43", "file": " /tmp/a=b"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestRunReportsTailsWhileRunning(t *testing.T) {
+	t.Parallel()
+
+	seen := make(chan string, 16)
+	fake := &fakeExecutor{fn: func(_ context.Context, spec *Spec) error {
+		io.WriteString(spec.Stdout, "partial")
+		select {
+		case <-seen:
+		case <-time.After(5 * time.Second):
+			return fmt.Errorf("tail never reported")
+		}
+		return nil
+	}}
+	r := &Runner{Executor: fake}
+
+	var reported string
+	result := r.Run(context.Background(), "long", plugin.JobContext{}, 0, &RunOptions{
+		TailInterval: time.Millisecond,
+		OnTail: func(stdout, _ string) {
+			reported = stdout
+			seen <- stdout
+		},
+	})
+	if result.Error != "" {
+		t.Fatal(result.Error)
+	}
+	if reported != "partial" {
+		t.Fatalf("reported tail %q, want %q", reported, "partial")
+	}
+}
+
+func TestSandboxScriptRemountsEveryMount(t *testing.T) {
+	t.Parallel()
+
+	script := sandboxScript(&SandboxOptions{ReadOnly: true, WritablePaths: []string{"/var/cache/app", "/tmp/it's"}}, 1000, 1000)
+	for _, want := range []string{
+		"mount --bind '/var/cache/app' '/var/cache/app'\n",
+		"/proc/self/mountinfo",
+		`case "$mnt" in /proc|'/var/cache/app'|'/tmp/it'\''s') continue ;; esac`,
+		`mount -o "remount,bind,ro${opts#r[ow]}" "$mnt"`,
+		`exec unshare --user --map-user=1000 --map-group=1000 -- "$@"`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script missing %q:\n%s", want, script)
+		}
+	}
+	if strings.Contains(script, "mount -o remount,bind,ro /\n") {
+		t.Errorf("script remounts only the root mount:\n%s", script)
+	}
+}
@@ -0,0 +1,74 @@
+package runner
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SandboxOptions restricts what a job's process can touch.
+type SandboxOptions struct {
+	// ReadOnly remounts every mount read-only in a private mount
+	// namespace; WritablePaths stay writable.
+	ReadOnly      bool
+	WritablePaths []string
+	// NoNetwork runs the process in an empty network namespace.
+	NoNetwork bool
+}
+
+// Active reports whether any restriction is requested.
+func (o *SandboxOptions) Active() bool {
+	return o != nil && (o.ReadOnly || o.NoNetwork)
+}
+
+// shellQuote single-quotes value for safe use in an sh script.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// procReadOnlyPaths are the parts of /proc that change the host rather
+// than the caller's own processes.
+var procReadOnlyPaths = []string{"/proc/bus", "/proc/fs", "/proc/irq", "/proc/sys", "/proc/sysrq-trigger"}
+
+// sandboxScript returns an sh script that sets up the read-only mount
+// namespace and then execs the job's shell argv passed as "$@".
+//
+// Every mount in the namespace is remounted read-only, not just /, so
+// submounts such as /home, /var, or /tmp cannot be written either. Each
+// remount keeps the mount's other flags (nosuid, nodev, ...), which the
+// kernel refuses to clear inside a user namespace. WritablePaths are bind
+// mounts of their own and are skipped.
+//
+// The script holds CAP_SYS_ADMIN over the mount namespace, and so would
+// the job, free to remount / read-write again. The job is therefore
+// started in a nested user namespace that maps uid and gid, the daemon's
+// own ids, back to themselves: it keeps no capabilities over the mounts,
+// and the kernel locks their read-only flags.
+func sandboxScript(o *SandboxOptions, uid, gid int) string {
+	var b strings.Builder
+	b.WriteString("set -e\n")
+	if o.ReadOnly {
+		b.WriteString("mount --make-rprivate /\n")
+		// unshare writes the nested namespace's id maps under /proc/self,
+		// so /proc stays writable; the parts of it that reach beyond the
+		// job's own processes get read-only bind mounts instead.
+		skip := []string{"/proc"}
+		b.WriteString("for p in " + strings.Join(procReadOnlyPaths, " ") + "; do\n")
+		b.WriteString("\tif [ -e \"$p\" ]; then mount --bind \"$p\" \"$p\"; fi\n")
+		b.WriteString("done\n")
+		for _, p := range o.WritablePaths {
+			q := shellQuote(p)
+			b.WriteString("mount --bind " + q + " " + q + "\n")
+			skip = append(skip, q)
+		}
+		b.WriteString("mounts=$(cat /proc/self/mountinfo)\n")
+		b.WriteString("printf '%s\\n' \"$mounts\" | while read -r _ _ _ _ mnt opts _; do\n")
+		b.WriteString("\tmnt=$(printf '%b' \"$mnt\")\n")
+		b.WriteString("\tcase \"$mnt\" in " + strings.Join(skip, "|") + ") continue ;; esac\n")
+		b.WriteString("\tmount -o \"remount,bind,ro${opts#r[ow]}\" \"$mnt\"\n")
+		b.WriteString("done\n")
+		b.WriteString("exec unshare --user --map-user=" + strconv.Itoa(uid) + " --map-group=" + strconv.Itoa(gid) + " -- \"$@\"\n")
+		return b.String()
+	}
+	b.WriteString("exec \"$@\"\n")
+	return b.String()
+}
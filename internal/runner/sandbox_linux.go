@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// ValidateSandbox checks that the sandbox options can be applied on this host.
+func ValidateSandbox(o *SandboxOptions, username, groupname string) error {
+	if !o.Active() {
+		return nil
+	}
+	if o.ReadOnly && (username != "" || groupname != "") {
+		return errors.New("invalid sandbox: read_only cannot be combined with user/group switching")
+	}
+	for _, p := range o.WritablePaths {
+		if p == "" || p[0] != '/' {
+			return errors.New("invalid sandbox: writable_paths must be absolute")
+		}
+	}
+	if o.ReadOnly {
+		if _, err := exec.LookPath("unshare"); err != nil {
+			return fmt.Errorf("invalid sandbox: read_only needs unshare: %w", err)
+		}
+	}
+	return nil
+}
+
+// applySandbox configures namespaces on cmd and, for read-only sandboxes,
+// rewrites it to run the mount setup script before exec'ing args.
+func applySandbox(cmd *exec.Cmd, o *SandboxOptions, args []string) error {
+	return sandboxAs(cmd, o, args, os.Geteuid(), os.Getegid())
+}
+
+// sandboxAs is applySandbox for a daemon running as uid and gid.
+func sandboxAs(cmd *exec.Cmd, o *SandboxOptions, args []string, uid, gid int) error {
+	if !o.Active() {
+		return nil
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	var flags uintptr
+	if o.NoNetwork {
+		flags |= syscall.CLONE_NEWNET
+	}
+	if o.ReadOnly {
+		flags |= syscall.CLONE_NEWNS
+		sh, err := exec.LookPath("sh")
+		if err != nil {
+			return err
+		}
+		cmd.Path = sh
+		cmd.Args = append([]string{"sh", "-c", sandboxScript(o, uid, gid), "cronbat-sandbox"}, args...)
+	}
+	if uid != 0 {
+		// Unprivileged users need a user namespace to create the others.
+		flags |= syscall.CLONE_NEWUSER
+		cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: uid, Size: 1}}
+		cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: gid, Size: 1}}
+	}
+	cmd.SysProcAttr.Cloneflags |= flags
+	return nil
+}
@@ -0,0 +1,47 @@
+package runner
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestSandboxDropsPrivileges(t *testing.T) {
+	t.Parallel()
+
+	for _, tool := range []string{"mount", "unshare"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not installed", tool)
+		}
+	}
+	args := []string{"sh", "-c", "id -u; mount -o remount,rw / 2>/dev/null && echo remounted || true"}
+	cmd := exec.Command(args[0])
+	cmd.Dir = "/"
+	// Run as an unprivileged daemon would. Root stands in for one by
+	// mapping nobody and becoming the namespace's root before exec.
+	uid, gid := os.Getuid(), os.Getgid()
+	if uid == 0 {
+		uid, gid = 65534, 65534
+		cmd.SysProcAttr = &syscall.SysProcAttr{Credential: &syscall.Credential{NoSetGroups: true}}
+	}
+	if err := sandboxAs(cmd, &SandboxOptions{ReadOnly: true}, args, uid, gid); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err := cmd.Start(); err != nil {
+		t.Skipf("user namespaces unavailable: %v", err)
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("sandboxed job failed: %v: %s", err, out.String())
+	}
+	lines := strings.Fields(out.String())
+	if len(lines) == 0 || lines[0] == "0" {
+		t.Fatalf("sandboxed job runs as uid 0: %q", out.String())
+	}
+	if strings.Contains(out.String(), "remounted") {
+		t.Fatalf("sandboxed job remounted / read-write: %q", out.String())
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+
+package runner
+
+import (
+	"errors"
+	"os/exec"
+)
+
+var errSandboxUnsupported = errors.New("invalid sandbox: namespaces are only supported on linux")
+
+// ValidateSandbox rejects sandbox options on platforms without namespaces.
+func ValidateSandbox(o *SandboxOptions, username, groupname string) error {
+	if !o.Active() {
+		return nil
+	}
+	return errSandboxUnsupported
+}
+
+func applySandbox(cmd *exec.Cmd, o *SandboxOptions, args []string) error {
+	return ValidateSandbox(o, "", "")
+}
@@ -0,0 +1,87 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultShell is used when a job does not select one.
+const DefaultShell = "sh"
+
+// loginShells lists shells known to accept -l for login mode.
+var loginShells = map[string]bool{
+	"sh":   true,
+	"bash": true,
+	"dash": true,
+	"ksh":  true,
+	"zsh":  true,
+	"fish": true,
+}
+
+// ShellCommand returns the argv used to run command with the given shell.
+//
+// A single-word shell (e.g. "bash", "/usr/bin/zsh") is invoked as
+// "<shell> -c <command>", or "<shell> -l -c <command>" in login mode. A
+// shell with arguments (e.g. "python3 -c", "node -e") is used as a literal
+// prefix and the command is appended as the last argument.
+func ShellCommand(shell string, login bool, command string) ([]string, error) {
+	fields := strings.Fields(shell)
+	if len(fields) == 0 {
+		fields = []string{DefaultShell}
+	}
+	if len(fields) > 1 {
+		if login {
+			return nil, errors.New("invalid shell: login_shell requires a plain shell without arguments")
+		}
+		return append(fields, command), nil
+	}
+	if login {
+		if !loginShells[filepath.Base(fields[0])] {
+			return nil, fmt.Errorf("invalid shell: login_shell is not supported for %q", fields[0])
+		}
+		return []string{fields[0], "-l", "-c", command}, nil
+	}
+	return []string{fields[0], "-c", command}, nil
+}
+
+// ValidateShell checks that the shell can be resolved on this host.
+func ValidateShell(shell string, login bool) error {
+	args, err := ShellCommand(shell, login, "")
+	if err != nil {
+		return err
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return fmt.Errorf("invalid shell: %w", err)
+	}
+	return nil
+}
+
+// ErrSyntaxCheckUnsupported is returned by CheckSyntax for shells that are
+// not POSIX-style, such as "python3 -c".
+var ErrSyntaxCheckUnsupported = errors.New("syntax check is only supported for sh-compatible shells")
+
+// CheckSyntax parses command with shell in no-exec mode ("sh -n") without
+// running it. It returns the shell's diagnostics and false when the command
+// does not parse.
+func CheckSyntax(ctx context.Context, shell, command string) (bool, string, error) {
+	fields := strings.Fields(shell)
+	if len(fields) == 0 {
+		fields = []string{DefaultShell}
+	}
+	if len(fields) > 1 || !loginShells[filepath.Base(fields[0])] {
+		return false, "", ErrSyntaxCheckUnsupported
+	}
+	out, err := exec.CommandContext(ctx, fields[0], "-n", "-c", command).CombinedOutput()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return false, strings.TrimSpace(string(out)), nil
+		}
+		return false, "", err
+	}
+	return true, "", nil
+}
@@ -0,0 +1,47 @@
+package runqueue
+
+import (
+	"sync"
+	"time"
+)
+
+// Deduper folds repeated manual or bus triggers of a job into the run the
+// first of them queued, for the job's dedupe_window.
+type Deduper struct {
+	// Now returns the current time; nil means time.Now.
+	Now func() time.Time
+
+	mu   sync.Mutex
+	last map[string]recentTrigger
+}
+
+// recentTrigger is the run a trigger queued and when.
+type recentTrigger struct {
+	runID string
+	at    time.Time
+}
+
+// Submit passes item to submit and returns its RunID, unless a trigger of
+// the same job queued a run less than window ago: then it queues nothing
+// and returns that run's ID with deduped set. A window of zero or less
+// turns deduplication off.
+func (d *Deduper) Submit(item Item, window time.Duration, submit func(Item)) (runID string, deduped bool) {
+	if window > 0 {
+		now := time.Now()
+		if d.Now != nil {
+			now = d.Now()
+		}
+		d.mu.Lock()
+		if last, ok := d.last[item.JobName]; ok && now.Sub(last.at) < window {
+			d.mu.Unlock()
+			return last.runID, true
+		}
+		if d.last == nil {
+			d.last = make(map[string]recentTrigger)
+		}
+		d.last[item.JobName] = recentTrigger{runID: item.RunID, at: now}
+		d.mu.Unlock()
+	}
+	submit(item)
+	return item.RunID, false
+}
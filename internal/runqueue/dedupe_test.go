@@ -0,0 +1,36 @@
+package runqueue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeduperSubmit(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	d := &Deduper{Now: func() time.Time { return now }}
+	var queued []string
+	submit := func(it Item) { queued = append(queued, it.RunID) }
+
+	if id, deduped := d.Submit(Item{JobName: "a", RunID: "r1"}, time.Minute, submit); id != "r1" || deduped {
+		t.Fatalf("first trigger = %q, %v", id, deduped)
+	}
+	now = now.Add(30 * time.Second)
+	if id, deduped := d.Submit(Item{JobName: "a", RunID: "r2"}, time.Minute, submit); id != "r1" || !deduped {
+		t.Fatalf("trigger inside the window = %q, %v; want r1, deduped", id, deduped)
+	}
+	if id, deduped := d.Submit(Item{JobName: "b", RunID: "r3"}, time.Minute, submit); id != "r3" || deduped {
+		t.Fatalf("other job's trigger = %q, %v", id, deduped)
+	}
+	now = now.Add(time.Minute)
+	if id, deduped := d.Submit(Item{JobName: "a", RunID: "r4"}, time.Minute, submit); id != "r4" || deduped {
+		t.Fatalf("trigger outside the window = %q, %v; want r4", id, deduped)
+	}
+	if id, deduped := d.Submit(Item{JobName: "a", RunID: "r5"}, 0, submit); id != "r5" || deduped {
+		t.Fatalf("trigger without a window = %q, %v; want r5", id, deduped)
+	}
+	if got := len(queued); got != 4 || queued[0] != "r1" || queued[1] != "r3" || queued[2] != "r4" || queued[3] != "r5" {
+		t.Fatalf("queued %v, want [r1 r3 r4 r5]", queued)
+	}
+}
@@ -0,0 +1,201 @@
+package runqueue
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/store"
+)
+
+// ErrPreempted is the cancellation cause set on a run's context when it is
+// cancelled to make room for a higher-priority run.
+var ErrPreempted = errors.New("preempted by higher-priority run")
+
+// Item is a unit of work waiting for (or holding) an execution slot.
+type Item struct {
+	JobName  string
+	Trigger  string
+	Priority int
+	// Preempt allows this item to cancel and requeue a lower-priority
+	// running item when no slot is free.
+	Preempt    bool
+	EnqueuedAt time.Time
+	// ScheduledAt is the time a scheduled run was due; zero for other
+	// triggers.
+	ScheduledAt time.Time
+	// Env is added to the job's environment for this run only.
+	Env map[string]string
+	// TriggeredBy identifies who requested a manual run.
+	TriggeredBy string
+	// TriggerContext is the request or message that triggered a manual
+	// or bus run.
+	TriggerContext *store.TriggerContext
+	// CorrelationID is the ID the trigger supplied to find the run again.
+	CorrelationID string
+	// ParentRunID and Matrix mark an instance of a matrix run: the run it
+	// belongs to and the instance's values.
+	ParentRunID string
+	Matrix      map[string]string
+	// RunID, if set, is the ID the run is recorded under; it lets a
+	// trigger report the run before it starts. A preempted item is
+	// requeued without it.
+	RunID string
+	// Done, if set, is called once the item is finished with: the run ID
+	// (empty if no run was recorded) and the final status. It is not called
+	// when the run is deferred or preempted and will be retried.
+	Done func(runID, status string)
+
+	seq uint64
+}
+
+// itemHeap orders items by priority (highest first), then FIFO.
+type itemHeap []*Item
+
+func (h itemHeap) Len() int { return len(h) }
+func (h itemHeap) Less(i, j int) bool {
+	if h[i].Priority != h[j].Priority {
+		return h[i].Priority > h[j].Priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h itemHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *itemHeap) Push(x any)   { *h = append(*h, x.(*Item)) }
+func (h *itemHeap) Pop() any {
+	old := *h
+	n := len(old)
+	it := old[n-1]
+	*h = old[:n-1]
+	return it
+}
+
+type slot struct {
+	item      *Item
+	cancel    context.CancelCauseFunc
+	preempted bool
+}
+
+// Queue limits concurrent runs and dispatches waiting items by priority.
+type Queue struct {
+	mu            sync.Mutex
+	maxConcurrent int
+	pending       itemHeap
+	running       map[uint64]*slot
+	nextSeq       uint64
+	stopped       bool
+	run           func(ctx context.Context, item Item)
+}
+
+// New creates a Queue that calls run for each dispatched item.
+// maxConcurrent <= 0 means unlimited: items are started immediately.
+func New(maxConcurrent int, run func(ctx context.Context, item Item)) *Queue {
+	return &Queue{
+		maxConcurrent: maxConcurrent,
+		running:       make(map[uint64]*slot),
+		run:           run,
+	}
+}
+
+// Submit enqueues an item and starts it as soon as a slot is available.
+func (q *Queue) Submit(item Item) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.stopped {
+		return
+	}
+
+	q.nextSeq++
+	it := item
+	it.seq = q.nextSeq
+	if it.EnqueuedAt.IsZero() {
+		it.EnqueuedAt = time.Now().UTC()
+	}
+	heap.Push(&q.pending, &it)
+
+	if !q.hasFreeSlotLocked() && it.Preempt {
+		q.preemptLocked(it.Priority)
+	}
+	q.dispatchLocked()
+}
+
+// Pending returns a snapshot of waiting items in dispatch order.
+func (q *Queue) Pending() []Item {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	cp := make(itemHeap, len(q.pending))
+	copy(cp, q.pending)
+	out := make([]Item, 0, len(cp))
+	for cp.Len() > 0 {
+		out = append(out, *heap.Pop(&cp).(*Item))
+	}
+	return out
+}
+
+// Running returns the number of items currently holding a slot.
+func (q *Queue) Running() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.running)
+}
+
+// Stop drops pending items and rejects new submissions. Running items are
+// left to finish on their own.
+func (q *Queue) Stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.stopped = true
+	q.pending = nil
+}
+
+func (q *Queue) hasFreeSlotLocked() bool {
+	return q.maxConcurrent <= 0 || len(q.running) < q.maxConcurrent
+}
+
+// preemptLocked cancels the lowest-priority running item whose priority is
+// below the given one. The cancelled item is requeued when it returns.
+func (q *Queue) preemptLocked(priority int) {
+	var victim *slot
+	for _, s := range q.running {
+		if s.preempted || s.item.Priority >= priority {
+			continue
+		}
+		if victim == nil || s.item.Priority < victim.item.Priority ||
+			(s.item.Priority == victim.item.Priority && s.item.seq > victim.item.seq) {
+			victim = s
+		}
+	}
+	if victim == nil {
+		return
+	}
+	victim.preempted = true
+	victim.cancel(ErrPreempted)
+}
+
+func (q *Queue) dispatchLocked() {
+	for q.pending.Len() > 0 && q.hasFreeSlotLocked() && !q.stopped {
+		it := heap.Pop(&q.pending).(*Item)
+		ctx, cancel := context.WithCancelCause(context.Background())
+		s := &slot{item: it, cancel: cancel}
+		q.running[it.seq] = s
+		go q.execute(ctx, s)
+	}
+}
+
+func (q *Queue) execute(ctx context.Context, s *slot) {
+	q.run(ctx, *s.item)
+	s.cancel(nil)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.running, s.item.seq)
+	if s.preempted && !q.stopped {
+		// Requeue with its original sequence so it keeps its place among
+		// equal-priority items.
+		s.item.RunID = ""
+		heap.Push(&q.pending, s.item)
+	}
+	q.dispatchLocked()
+}
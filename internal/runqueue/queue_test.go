@@ -0,0 +1,96 @@
+package runqueue
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestQueueDispatchesByPriority(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var order []string
+	done := make(chan struct{}, 4)
+
+	q := New(1, func(ctx context.Context, item Item) {
+		if item.JobName == "blocker" {
+			<-release
+		}
+		mu.Lock()
+		order = append(order, item.JobName)
+		mu.Unlock()
+		done <- struct{}{}
+	})
+
+	q.Submit(Item{JobName: "blocker"})
+	q.Submit(Item{JobName: "low", Priority: -1})
+	q.Submit(Item{JobName: "normal"})
+	q.Submit(Item{JobName: "high", Priority: 10})
+
+	if got := len(q.Pending()); got != 3 {
+		t.Fatalf("expected 3 pending items, got %d", got)
+	}
+	close(release)
+	for i := 0; i < 4; i++ {
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for runs")
+		}
+	}
+
+	want := []string{"blocker", "high", "normal", "low"}
+	mu.Lock()
+	defer mu.Unlock()
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("expected order %v, got %v", want, order)
+		}
+	}
+}
+
+func TestQueuePreemptsLowerPriority(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan string, 4)
+	var mu sync.Mutex
+	runs := make(map[string]int)
+	done := make(chan struct{}, 4)
+
+	q := New(1, func(ctx context.Context, item Item) {
+		mu.Lock()
+		runs[item.JobName]++
+		attempt := runs[item.JobName]
+		mu.Unlock()
+		started <- item.JobName
+
+		if item.JobName == "batch" && attempt == 1 {
+			<-ctx.Done()
+			if context.Cause(ctx) != ErrPreempted {
+				t.Errorf("expected ErrPreempted cause, got %v", context.Cause(ctx))
+			}
+		}
+		done <- struct{}{}
+	})
+
+	q.Submit(Item{JobName: "batch"})
+	<-started
+	q.Submit(Item{JobName: "backup", Priority: 5, Preempt: true})
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for runs")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if runs["batch"] != 2 || runs["backup"] != 1 {
+		t.Fatalf("expected batch requeued once and backup run once, got %v", runs)
+	}
+}
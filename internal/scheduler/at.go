@@ -0,0 +1,48 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// atPrefix starts a one-shot schedule: "@at 2024-07-01T03:00:00Z".
+const atPrefix = "@at "
+
+// atLayouts are the accepted one-shot times; the zone-less forms are in
+// local time.
+var atLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+// AtSchedule fires once, at At.
+type AtSchedule struct {
+	At time.Time
+}
+
+// Next implements cron.Schedule. It returns the zero time once At has
+// passed, so the scheduler marks the job dormant after its run.
+func (s AtSchedule) Next(after time.Time) time.Time {
+	if after.Before(s.At) {
+		return s.At
+	}
+	return time.Time{}
+}
+
+// IsOneShot reports whether expr is an "@at" schedule.
+func IsOneShot(expr string) bool {
+	return strings.HasPrefix(strings.TrimSpace(expr), atPrefix)
+}
+
+// ParseAt parses an "@at <time>" schedule.
+func ParseAt(expr string) (AtSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, atPrefix) {
+		return AtSchedule{}, fmt.Errorf("not an @at schedule: %q", expr)
+	}
+	value := strings.TrimSpace(strings.TrimPrefix(expr, atPrefix))
+	for _, layout := range atLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return AtSchedule{At: t}, nil
+		}
+	}
+	return AtSchedule{}, fmt.Errorf("invalid @at time %q: want RFC 3339, e.g. 2024-07-01T03:00:00Z", value)
+}
@@ -11,12 +11,45 @@ var cronParser = cron.NewParser(
 	cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
 )
 
-// ParseSchedule parses a cron expression and returns a Schedule.
+// ParseSchedule parses a cron expression, or a natural-language schedule
+// (see Normalize), and returns a Schedule using the default DST policy.
 func ParseSchedule(expr string) (cron.Schedule, error) {
-	return cronParser.Parse(expr)
+	return ParseScheduleWithDST(expr, DefaultDSTPolicy)
+}
+
+// ParseScheduleWithDST is ParseSchedule with an explicit policy for fixed
+// times that daylight saving transitions skip or repeat.
+func ParseScheduleWithDST(expr string, policy DSTPolicy) (cron.Schedule, error) {
+	if IsOneShot(expr) {
+		return ParseAt(expr)
+	}
+	normalized, err := Normalize(expr)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := cronParser.Parse(normalized)
+	if err != nil {
+		return nil, err
+	}
+	return withDSTPolicy(schedule, policy), nil
 }
 
 // NextTime returns the next fire time after the given time for the schedule.
 func NextTime(schedule cron.Schedule, after time.Time) time.Time {
 	return schedule.Next(after)
 }
+
+// Upcoming returns up to n fire times of schedule after the given time. It
+// returns fewer when the schedule has no further occurrences.
+func Upcoming(schedule cron.Schedule, after time.Time, n int) []time.Time {
+	times := make([]time.Time, 0, n)
+	for len(times) < n {
+		next := NextTime(schedule, after)
+		if next.IsZero() {
+			break
+		}
+		times = append(times, next)
+		after = next
+	}
+	return times
+}
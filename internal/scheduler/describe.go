@@ -0,0 +1,305 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Description explains a schedule in words.
+type Description struct {
+	// Expression is the cron expression the schedule runs on: natural
+	// language is translated, and a CRON_TZ= prefix moves to Timezone.
+	Expression string `json:"expression"`
+	Timezone   string `json:"timezone,omitempty"`
+	// Summary reads like "At 02:00 on Sunday".
+	Summary string `json:"summary"`
+	// Fields break a five-field expression down; empty for @every and
+	// @at schedules.
+	Fields []FieldDescription `json:"fields,omitempty"`
+}
+
+// FieldDescription is one field of a cron expression and what it matches.
+type FieldDescription struct {
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+	Description string `json:"description"`
+}
+
+// cronField describes how to read one of the five fields.
+type cronField struct {
+	name         string
+	unit, plural string
+	min, max     int
+	// names spell out values, e.g. months; nil prints numbers.
+	names []string
+}
+
+var (
+	monthNames   = []string{"", "January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+	weekdayNames = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+
+	cronFields = [5]cronField{
+		{name: "minute", unit: "minute", plural: "minutes", min: 0, max: 59},
+		{name: "hour", unit: "hour", plural: "hours", min: 0, max: 23},
+		{name: "day_of_month", unit: "day", plural: "days", min: 1, max: 31},
+		{name: "month", unit: "month", plural: "months", min: 1, max: 12, names: monthNames},
+		{name: "day_of_week", unit: "day of the week", plural: "days of the week", min: 0, max: 6, names: weekdayNames},
+	}
+
+	// descriptors are the cron forms of the @ shorthands.
+	descriptors = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// Describe explains expr, a cron expression, descriptor, natural-language
+// schedule, or "@at" time, in words. It returns an error for schedules
+// ParseSchedule rejects.
+func Describe(expr string) (*Description, error) {
+	expr = strings.TrimSpace(expr)
+	if IsOneShot(expr) {
+		at, err := ParseAt(expr)
+		if err != nil {
+			return nil, err
+		}
+		return &Description{Expression: expr, Summary: "Once, at " + at.At.Format(time.RFC3339)}, nil
+	}
+	if _, err := ParseSchedule(expr); err != nil {
+		return nil, err
+	}
+	normalized, err := Normalize(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Description{}
+	spec := normalized
+	for _, prefix := range []string{"CRON_TZ=", "TZ="} {
+		if rest, ok := strings.CutPrefix(spec, prefix); ok {
+			d.Timezone, spec, _ = strings.Cut(rest, " ")
+			spec = strings.TrimSpace(spec)
+		}
+	}
+	d.Expression = spec
+	if every, ok := strings.CutPrefix(spec, "@every "); ok {
+		dur, err := time.ParseDuration(strings.TrimSpace(every))
+		if err != nil {
+			return nil, err
+		}
+		d.Summary = "Every " + dur.String()
+		return d.inZone(), nil
+	}
+	if cron, ok := descriptors[spec]; ok {
+		spec = cron
+	}
+
+	values := strings.Fields(spec)
+	if len(values) != len(cronFields) {
+		return nil, fmt.Errorf("expected %d fields, found %d", len(cronFields), len(values))
+	}
+	phrases := make([]string, len(values))
+	for i, f := range cronFields {
+		phrase, err := f.describe(values[i])
+		if err != nil {
+			return nil, err
+		}
+		phrases[i] = phrase
+		d.Fields = append(d.Fields, FieldDescription{Name: f.name, Value: values[i], Description: phrase})
+	}
+	d.Summary = summarize(values, phrases)
+	return d.inZone(), nil
+}
+
+// inZone notes the timezone in the summary.
+func (d *Description) inZone() *Description {
+	if d.Timezone != "" {
+		d.Summary += " (" + d.Timezone + ")"
+	}
+	return d
+}
+
+// summarize joins the field phrases into a sentence.
+func summarize(values, phrases []string) string {
+	minute, hour, dom, month, dow := values[0], values[1], values[2], values[3], values[4]
+	var parts []string
+	switch {
+	case isNumber(minute) && allNumbers(hour):
+		m, _ := strconv.Atoi(minute)
+		var times []string
+		for _, h := range strings.Split(hour, ",") {
+			n, _ := strconv.Atoi(h)
+			times = append(times, fmt.Sprintf("%02d:%02d", n, m))
+		}
+		parts = append(parts, "At "+joinList(times))
+	case isStar(hour) && strings.HasPrefix(phrases[0], "every"):
+		parts = append(parts, capitalize(phrases[0]))
+	case strings.HasPrefix(phrases[0], "every"):
+		parts = append(parts, capitalize(phrases[0])+" during "+phrases[1])
+	default:
+		parts = append(parts, "At "+phrases[0]+" past "+phrases[1])
+	}
+
+	// Cron matches either day field when both are restricted.
+	var days []string
+	if !isStar(dom) {
+		if strings.HasPrefix(phrases[2], "every") {
+			days = append(days, phrases[2])
+		} else {
+			days = append(days, "on "+phrases[2]+" of the month")
+		}
+	}
+	if !isStar(dow) {
+		days = append(days, "on "+phrases[4])
+	}
+	if len(days) > 0 {
+		parts = append(parts, strings.Join(days, " or "))
+	}
+	if !isStar(month) {
+		if strings.HasPrefix(phrases[3], "every") {
+			parts = append(parts, phrases[3])
+		} else {
+			parts = append(parts, "in "+phrases[3])
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// describe explains one field value, e.g. "minutes 0 and 30" or "Monday
+// through Friday".
+func (f cronField) describe(value string) (string, error) {
+	if isStar(value) {
+		return "every " + f.unit, nil
+	}
+	var items []string
+	labelled := true
+	for _, part := range strings.Split(value, ",") {
+		item, step, err := f.describePart(part)
+		if err != nil {
+			return "", err
+		}
+		if step {
+			labelled = false
+		}
+		items = append(items, item)
+	}
+	phrase := joinList(items)
+	if !labelled || f.names != nil {
+		return phrase, nil
+	}
+	if len(items) == 1 && !strings.Contains(value, "-") {
+		return f.unit + " " + phrase, nil
+	}
+	return f.plural + " " + phrase, nil
+}
+
+// describePart explains one comma-separated part of a field. step reports
+// a part with a step, which reads as "every ...".
+func (f cronField) describePart(part string) (s string, step bool, err error) {
+	rng, stepStr, hasStep := strings.Cut(part, "/")
+	if hasStep {
+		n, err := strconv.Atoi(stepStr)
+		if err != nil || n < 1 {
+			return "", false, fmt.Errorf("invalid step %q in %s", stepStr, f.name)
+		}
+		every := "every " + f.unit
+		if n > 1 {
+			every = fmt.Sprintf("every %d %s", n, f.plural)
+		}
+		if isStar(rng) {
+			return every, true, nil
+		}
+		lo, hi, isRange := strings.Cut(rng, "-")
+		from, err := f.value(lo)
+		if err != nil {
+			return "", false, err
+		}
+		if !isRange {
+			return every + " from " + from, true, nil
+		}
+		to, err := f.value(hi)
+		if err != nil {
+			return "", false, err
+		}
+		return every + " from " + from + " through " + to, true, nil
+	}
+	if lo, hi, isRange := strings.Cut(rng, "-"); isRange {
+		from, err := f.value(lo)
+		if err != nil {
+			return "", false, err
+		}
+		to, err := f.value(hi)
+		if err != nil {
+			return "", false, err
+		}
+		return from + " through " + to, false, nil
+	}
+	v, err := f.value(rng)
+	return v, false, err
+}
+
+// value spells out a single field value, accepting the three-letter names
+// cron takes for months and weekdays.
+func (f cronField) value(s string) (string, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil && f.names != nil {
+		for _, name := range f.names {
+			if len(name) >= 3 && strings.EqualFold(name[:3], s) {
+				return name, nil
+			}
+		}
+	}
+	if err != nil || n < f.min || n > f.max {
+		return "", fmt.Errorf("invalid %s value %q", f.name, s)
+	}
+	if f.names != nil {
+		return f.names[n], nil
+	}
+	return strconv.Itoa(n), nil
+}
+
+func isStar(s string) bool {
+	return s == "*" || s == "?"
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// allNumbers reports whether s is a comma-separated list of numbers.
+func allNumbers(s string) bool {
+	for _, part := range strings.Split(s, ",") {
+		if !isNumber(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// joinList joins items as "a", "a and b", or "a, b, and c".
+func joinList(items []string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	case 2:
+		return items[0] + " and " + items[1]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + ", and " + items[len(items)-1]
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,43 @@
+package scheduler
+
+import "testing"
+
+func TestDescribe(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		expr, want string
+	}{
+		{"0 2 * * 0", "At 02:00 on Sunday"},
+		{"*/15 * * * *", "Every 15 minutes"},
+		{"5 * * * *", "At minute 5 past every hour"},
+		{"0 9,17 * * 1-5", "At 09:00 and 17:00 on Monday through Friday"},
+		{"*/10 9-17 * * MON-FRI", "Every 10 minutes during hours 9 through 17 on Monday through Friday"},
+		{"30 6 1,15 * *", "At 06:30 on days 1 and 15 of the month"},
+		{"0 0 13 * 5", "At 00:00 on day 13 of the month or on Friday"},
+		{"0 12 * JAN-MAR *", "At 12:00 in January through March"},
+		{"@daily", "At 00:00"},
+		{"CRON_TZ=Europe/Berlin 0 2 * * *", "At 02:00 (Europe/Berlin)"},
+		{"every weekday at 9am", "At 09:00 on Monday through Friday"},
+	} {
+		d, err := Describe(tc.expr)
+		if err != nil {
+			t.Errorf("Describe(%q): %v", tc.expr, err)
+			continue
+		}
+		if d.Summary != tc.want {
+			t.Errorf("Describe(%q) = %q, want %q", tc.expr, d.Summary, tc.want)
+		}
+	}
+
+	d, err := Describe("0 2 * * 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Fields) != 5 || d.Fields[1].Name != "hour" || d.Fields[1].Description != "hour 2" {
+		t.Fatalf("fields = %+v", d.Fields)
+	}
+	if _, err := Describe("61 * * * *"); err == nil {
+		t.Fatal("expected an error for an out-of-range minute")
+	}
+}
@@ -0,0 +1,113 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// DSTPolicy decides how a fixed-time schedule treats daylight saving time
+// transitions in its time zone.
+type DSTPolicy string
+
+const (
+	// DSTRunOnce runs a time that a spring-forward transition skips once,
+	// shifted forward by the gap (02:30 becomes 03:30).
+	DSTRunOnce DSTPolicy = "run_once"
+	// DSTSkip drops a time that a spring-forward transition skips.
+	DSTSkip DSTPolicy = "skip"
+)
+
+// DefaultDSTPolicy applies when a job sets no dst_policy.
+const DefaultDSTPolicy = DSTRunOnce
+
+// ParseDSTPolicy validates a dst_policy value; empty selects the default.
+func ParseDSTPolicy(s string) (DSTPolicy, error) {
+	switch DSTPolicy(s) {
+	case "":
+		return DefaultDSTPolicy, nil
+	case DSTRunOnce, DSTSkip:
+		return DSTPolicy(s), nil
+	}
+	return "", fmt.Errorf("invalid dst_policy %q: want run_once or skip", s)
+}
+
+// hourStar marks a cron field parsed from "*" (robfig/cron's starBit).
+const hourStar = 1 << 63
+
+// allHours has a bit set for every hour of the day.
+const allHours = 1<<24 - 1
+
+// wallSchedule evaluates a fixed-time cron spec against wall-clock time in
+// its location, so each matching wall time fires at most once: a time
+// repeated when clocks fall back runs only at its first occurrence, and a
+// time skipped when clocks spring forward follows the policy.
+type wallSchedule struct {
+	// wall is the spec evaluated in UTC, which has no transitions.
+	wall   *cron.SpecSchedule
+	loc    *time.Location
+	policy DSTPolicy
+}
+
+// withDSTPolicy wraps fixed-time specs (those not firing every hour) in a
+// wallSchedule. Schedules that run every hour keep elapsed-time semantics:
+// "0 * * * *" runs in both repeated hours and has no skipped-hour catch-up.
+func withDSTPolicy(schedule cron.Schedule, policy DSTPolicy) cron.Schedule {
+	spec, ok := schedule.(*cron.SpecSchedule)
+	if !ok || spec.Hour&hourStar != 0 || spec.Hour&allHours == allHours {
+		return schedule
+	}
+	loc := spec.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	wall := *spec
+	wall.Location = time.UTC
+	return &wallSchedule{wall: &wall, loc: loc, policy: policy}
+}
+
+// Next implements cron.Schedule.
+func (s *wallSchedule) Next(t time.Time) time.Time {
+	t = t.In(s.loc)
+	cursor := asUTCWall(t)
+	for {
+		nw := s.wall.Next(cursor)
+		if nw.IsZero() {
+			return nw
+		}
+		cursor = nw
+
+		next := time.Date(nw.Year(), nw.Month(), nw.Day(), nw.Hour(), nw.Minute(), nw.Second(), 0, s.loc)
+		if !asUTCWall(next).Equal(nw) {
+			// nw does not exist on this day's clock.
+			if s.policy == DSTSkip {
+				continue
+			}
+			next = shiftPastGap(nw, next)
+		}
+		if !next.After(t) {
+			// A repeated wall time whose first occurrence already passed.
+			continue
+		}
+		return next
+	}
+}
+
+// asUTCWall returns the wall-clock reading of t as a UTC time.
+func asUTCWall(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// shiftPastGap returns the instant for a wall time nw that falls in a
+// spring-forward gap, moved forward by the gap: nw read with the offset in
+// effect before the transition. near is any instant close to the gap.
+func shiftPastGap(nw, near time.Time) time.Time {
+	_, before := near.Add(-3 * time.Hour).Zone()
+	_, after := near.Add(3 * time.Hour).Zone()
+	off := before
+	if after < off {
+		off = after
+	}
+	return nw.Add(-time.Duration(off) * time.Second).In(near.Location())
+}
@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDSTTransitions(t *testing.T) {
+	t.Parallel()
+
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	utc := func(s string) time.Time {
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		name   string
+		expr   string
+		policy DSTPolicy
+		from   time.Time
+		want   []string // UTC
+	}{
+		{
+			// 2025-03-09 02:30 does not exist in New York.
+			name:   "spring forward run_once",
+			expr:   "CRON_TZ=America/New_York 30 2 * * *",
+			policy: DSTRunOnce,
+			from:   time.Date(2025, 3, 8, 12, 0, 0, 0, ny),
+			want:   []string{"2025-03-09T07:30:00Z", "2025-03-10T06:30:00Z"},
+		},
+		{
+			name:   "spring forward skip",
+			expr:   "CRON_TZ=America/New_York 30 2 * * *",
+			policy: DSTSkip,
+			from:   time.Date(2025, 3, 8, 12, 0, 0, 0, ny),
+			want:   []string{"2025-03-10T06:30:00Z", "2025-03-11T06:30:00Z"},
+		},
+		{
+			// 2025-11-02 01:30 happens twice; only the first (EDT) runs.
+			name:   "fall back runs once",
+			expr:   "CRON_TZ=America/New_York 30 1 * * *",
+			policy: DSTSkip,
+			from:   time.Date(2025, 11, 1, 12, 0, 0, 0, ny),
+			want:   []string{"2025-11-02T05:30:00Z", "2025-11-03T06:30:00Z"},
+		},
+		{
+			name:   "fall back from inside the repeated hour",
+			expr:   "CRON_TZ=America/New_York 30 1 * * *",
+			policy: DSTRunOnce,
+			from:   utc("2025-11-02T06:10:00Z"), // 01:10 EST, second pass
+			want:   []string{"2025-11-03T06:30:00Z"},
+		},
+		{
+			// Hourly schedules keep elapsed-time semantics.
+			name:   "hourly runs in both repeated hours",
+			expr:   "CRON_TZ=America/New_York 0 * * * *",
+			policy: DSTRunOnce,
+			from:   time.Date(2025, 11, 2, 0, 10, 0, 0, ny),
+			want:   []string{"2025-11-02T05:00:00Z", "2025-11-02T06:00:00Z", "2025-11-02T07:00:00Z"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			schedule, err := ParseScheduleWithDST(tt.expr, tt.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := Upcoming(schedule, tt.from, len(tt.want))
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i, w := range tt.want {
+				if !got[i].Equal(utc(w)) {
+					t.Fatalf("occurrence %d: got %s, want %s (all: %v)", i, got[i].UTC().Format(time.RFC3339), w, got)
+				}
+			}
+		})
+	}
+}
+
+func TestParseDSTPolicy(t *testing.T) {
+	t.Parallel()
+
+	if p, err := ParseDSTPolicy(""); err != nil || p != DefaultDSTPolicy {
+		t.Fatalf("empty policy: %v, %v", p, err)
+	}
+	if _, err := ParseDSTPolicy("twice"); err == nil {
+		t.Fatal("expected an error for an unknown policy")
+	}
+}
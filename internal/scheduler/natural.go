@@ -0,0 +1,186 @@
+package scheduler
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Natural-language schedules are matched against these patterns after
+// lowercasing and collapsing whitespace. timePattern is "9am", "9:30 pm",
+// "14:30", "noon", or "midnight".
+const timePattern = `(\d{1,2}(?::\d{2})?\s*(?:am|pm)?|noon|midnight)`
+
+var (
+	nlEveryMinutes = regexp.MustCompile(`^every (?:(\d+) )?minutes?$`)
+	nlEveryHours   = regexp.MustCompile(`^(?:hourly|every (?:(\d+) )?hours?)$`)
+	nlDaily        = regexp.MustCompile(`^(?:daily|every day)(?: at ` + timePattern + `)?$`)
+	nlWeekdays     = regexp.MustCompile(`^(?:on |every )?([a-z, ]+?)(?: at ` + timePattern + `)?$`)
+	nlMonthly      = regexp.MustCompile(`^(?:monthly|every month)(?: on the (\d{1,2})(?:st|nd|rd|th)?)?(?: at ` + timePattern + `)?$`)
+)
+
+var weekdayNumbers = map[string]string{
+	"sunday": "0", "sun": "0",
+	"monday": "1", "mon": "1",
+	"tuesday": "2", "tue": "2", "tues": "2",
+	"wednesday": "3", "wed": "3",
+	"thursday": "4", "thu": "4", "thurs": "4",
+	"friday": "5", "fri": "5",
+	"saturday": "6", "sat": "6",
+}
+
+// IsNatural reports whether expr is a natural-language schedule rather than
+// a cron expression or descriptor. Cron expressions start with a digit, '*',
+// '@', or a CRON_TZ=/TZ= prefix.
+func IsNatural(expr string) bool {
+	expr = strings.TrimSpace(expr)
+	if expr == "" || strings.HasPrefix(expr, "CRON_TZ=") || strings.HasPrefix(expr, "TZ=") {
+		return false
+	}
+	c := expr[0]
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// Normalize returns the cron expression for expr. Cron expressions are
+// returned unchanged; natural-language schedules such as "every 15 minutes",
+// "every weekday at 9am", "every monday and friday at 17:30", or
+// "every month on the 1st at 6am" are translated.
+func Normalize(expr string) (string, error) {
+	expr = strings.TrimSpace(expr)
+	if !IsNatural(expr) {
+		return expr, nil
+	}
+	s := strings.Join(strings.Fields(strings.ToLower(expr)), " ")
+
+	if m := nlEveryMinutes.FindStringSubmatch(s); m != nil {
+		n, err := stepValue(m[1], 59, "minutes")
+		if err != nil {
+			return "", err
+		}
+		if n == 1 {
+			return "* * * * *", nil
+		}
+		return fmt.Sprintf("*/%d * * * *", n), nil
+	}
+	if m := nlEveryHours.FindStringSubmatch(s); m != nil {
+		n, err := stepValue(m[1], 23, "hours")
+		if err != nil {
+			return "", err
+		}
+		if n == 1 {
+			return "0 * * * *", nil
+		}
+		return fmt.Sprintf("0 */%d * * *", n), nil
+	}
+	if m := nlDaily.FindStringSubmatch(s); m != nil {
+		hour, minute, err := parseClock(m[1])
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d %d * * *", minute, hour), nil
+	}
+	if m := nlMonthly.FindStringSubmatch(s); m != nil {
+		day := 1
+		if m[1] != "" {
+			day, _ = strconv.Atoi(m[1])
+			if day < 1 || day > 31 {
+				return "", fmt.Errorf("invalid schedule %q: day of month must be 1-31", expr)
+			}
+		}
+		hour, minute, err := parseClock(m[2])
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d %d %d * *", minute, hour, day), nil
+	}
+	if m := nlWeekdays.FindStringSubmatch(s); m != nil {
+		if days, ok := parseWeekdays(m[1]); ok {
+			hour, minute, err := parseClock(m[2])
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%d %d * * %s", minute, hour, days), nil
+		}
+	}
+	return "", fmt.Errorf("invalid schedule %q: not a cron expression or a recognized phrase", expr)
+}
+
+// stepValue parses the optional count of "every N units"; empty means 1.
+func stepValue(raw string, max int, unit string) (int, error) {
+	if raw == "" {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 || n > max {
+		return 0, fmt.Errorf("invalid schedule: every N %s needs N between 1 and %d", unit, max)
+	}
+	return n, nil
+}
+
+// parseClock parses a time of day; empty means midnight.
+func parseClock(raw string) (hour, minute int, err error) {
+	raw = strings.ReplaceAll(raw, " ", "")
+	switch raw {
+	case "", "midnight":
+		return 0, 0, nil
+	case "noon":
+		return 12, 0, nil
+	}
+
+	suffix := ""
+	if strings.HasSuffix(raw, "am") || strings.HasSuffix(raw, "pm") {
+		suffix = raw[len(raw)-2:]
+		raw = raw[:len(raw)-2]
+	}
+	h, m, hasMinute := strings.Cut(raw, ":")
+	hour, err = strconv.Atoi(h)
+	if err == nil && hasMinute {
+		minute, err = strconv.Atoi(m)
+	}
+	if err != nil || minute > 59 {
+		return 0, 0, fmt.Errorf("invalid schedule: bad time of day %q", raw+suffix)
+	}
+	switch suffix {
+	case "":
+		if hour > 23 {
+			return 0, 0, fmt.Errorf("invalid schedule: bad time of day %q", raw)
+		}
+	default:
+		if hour < 1 || hour > 12 {
+			return 0, 0, fmt.Errorf("invalid schedule: bad time of day %q", raw+suffix)
+		}
+		hour %= 12
+		if suffix == "pm" {
+			hour += 12
+		}
+	}
+	return hour, minute, nil
+}
+
+// parseWeekdays turns "weekday", "weekend", or a list such as
+// "monday, wednesday and friday" into a cron day-of-week field.
+func parseWeekdays(s string) (string, bool) {
+	switch s {
+	case "weekday", "weekdays":
+		return "1-5", true
+	case "weekend", "weekends":
+		return "0,6", true
+	}
+	s = strings.ReplaceAll(s, ",", " ")
+	var days []string
+	for _, word := range strings.Fields(s) {
+		if word == "and" {
+			continue
+		}
+		n, ok := weekdayNumbers[strings.TrimSuffix(word, "s")]
+		if !ok {
+			n, ok = weekdayNumbers[word]
+		}
+		if !ok {
+			return "", false
+		}
+		days = append(days, n)
+	}
+	return strings.Join(days, ","), len(days) > 0
+}
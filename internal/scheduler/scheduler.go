@@ -37,35 +37,101 @@ type Scheduler struct {
 	timer *time.Timer
 	done  chan struct{}
 	wg    sync.WaitGroup
-	fire  func(jobName string)
+	fire  func(jobName string, scheduledAt time.Time)
 	reset chan struct{} // signals the goroutine to re-read the timer
+
+	// dormant holds jobs whose schedule has no future occurrence.
+	dormant   map[string]struct{}
+	onDormant func(jobName string)
+
+	onClockJump func(delta time.Duration)
+
+	// paused keeps schedules advancing without firing them.
+	paused bool
+
+	// lastWake is when the run loop last woke. It wakes at least every
+	// clockCheckInterval, so an old lastWake means it is stuck.
+	lastWake time.Time
 }
 
-// NewScheduler creates a Scheduler that calls fire when a job is due.
-func NewScheduler(fire func(jobName string)) *Scheduler {
+// clockCheckInterval is how often the scheduler re-evaluates its timer
+// against wall-clock time. Timers run on the monotonic clock, so without it
+// a wall-clock step (NTP correction, suspend/resume, manual change) would
+// delay due jobs until the old timer expires.
+const clockCheckInterval = 30 * time.Second
+
+// clockJumpThreshold is the smallest wall/monotonic disagreement reported
+// as a clock jump.
+const clockJumpThreshold = 2 * time.Second
+
+// NewScheduler creates a Scheduler that calls fire when a job is due, with
+// the time the job was scheduled for. The gap between scheduledAt and the
+// call is the scheduler's drift.
+func NewScheduler(fire func(jobName string, scheduledAt time.Time)) *Scheduler {
 	return &Scheduler{
-		fire:  fire,
-		done:  make(chan struct{}),
-		reset: make(chan struct{}, 1),
+		fire:    fire,
+		done:    make(chan struct{}),
+		reset:   make(chan struct{}, 1),
+		dormant: make(map[string]struct{}),
 	}
 }
 
+// OnDormant registers a callback invoked (without the scheduler lock held)
+// when a job's schedule has no future occurrence and it is dropped from the
+// heap. Must be called before Start.
+func (s *Scheduler) OnDormant(fn func(jobName string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onDormant = fn
+}
+
+// OnClockJump registers a callback invoked (without the scheduler lock held)
+// when the wall clock moved by delta relative to the monotonic clock since
+// the previous check. Jobs are never double-fired: a backward jump waits for
+// the next occurrence after the last run, and a forward jump runs each
+// overdue job once. Must be called before Start.
+func (s *Scheduler) OnClockJump(fn func(delta time.Duration)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onClockJump = fn
+}
+
+// IsDormant reports whether the named job was dropped because its schedule
+// never fires again.
+func (s *Scheduler) IsDormant(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.dormant[name]
+	return ok
+}
+
 // AddJob adds a job with the given schedule. If the job already exists it is
 // replaced. The timer is reset if the new job is the earliest.
 func (s *Scheduler) AddJob(name string, schedule cron.Schedule) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 
 	// Remove existing entry with the same name.
 	s.removeLockedByName(name)
+	delete(s.dormant, name)
 
 	e := entry{
 		jobName:  name,
 		schedule: schedule,
 		nextRun:  NextTime(schedule, time.Now()),
 	}
+	if e.nextRun.IsZero() {
+		s.dormant[name] = struct{}{}
+		onDormant := s.onDormant
+		s.resetTimerLocked()
+		s.mu.Unlock()
+		if onDormant != nil {
+			onDormant(name)
+		}
+		return
+	}
 	heap.Push(&s.heap, e)
 	s.resetTimerLocked()
+	s.mu.Unlock()
 }
 
 // RemoveJob removes a job by name.
@@ -73,6 +139,7 @@ func (s *Scheduler) RemoveJob(name string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.removeLockedByName(name)
+	delete(s.dormant, name)
 	s.resetTimerLocked()
 }
 
@@ -98,6 +165,40 @@ func (s *Scheduler) NextRunTime(name string) (time.Time, bool) {
 	return time.Time{}, false
 }
 
+// Schedules returns the schedule of each job waiting to fire. Dormant jobs
+// are left out.
+func (s *Scheduler) Schedules() map[string]cron.Schedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]cron.Schedule, len(s.heap))
+	for _, e := range s.heap {
+		out[e.jobName] = e.schedule
+	}
+	return out
+}
+
+// Pause stops firing jobs. Schedules keep advancing, so occurrences that
+// fall in the pause are skipped rather than run on Resume.
+func (s *Scheduler) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = true
+}
+
+// Resume fires jobs again from their next occurrence.
+func (s *Scheduler) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = false
+}
+
+// Paused reports whether the scheduler is paused.
+func (s *Scheduler) Paused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
 // Start launches the scheduler goroutine.
 func (s *Scheduler) Start() {
 	s.mu.Lock()
@@ -107,12 +208,39 @@ func (s *Scheduler) Start() {
 		<-s.timer.C
 	}
 	s.resetTimerLocked()
+	s.lastWake = time.Now()
 	s.mu.Unlock()
 
 	s.wg.Add(1)
 	go s.run()
 }
 
+// Len returns how many jobs are scheduled, not counting dormant ones.
+func (s *Scheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.heap.Len()
+}
+
+// Next returns the job that fires next and when; ok is false when no job
+// is scheduled.
+func (s *Scheduler) Next() (jobName string, at time.Time, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.heap.Len() == 0 {
+		return "", time.Time{}, false
+	}
+	return s.heap[0].jobName, s.heap[0].nextRun, true
+}
+
+// Alive reports whether the run loop has woken within two clock check
+// intervals. It is false before Start and while a fire callback blocks.
+func (s *Scheduler) Alive() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.lastWake.IsZero() && time.Since(s.lastWake) < 2*clockCheckInterval
+}
+
 // Stop signals the scheduler goroutine to exit and waits for it.
 func (s *Scheduler) Stop() {
 	close(s.done)
@@ -122,6 +250,9 @@ func (s *Scheduler) Stop() {
 // run is the main scheduler loop.
 func (s *Scheduler) run() {
 	defer s.wg.Done()
+	clock := time.NewTicker(clockCheckInterval)
+	defer clock.Stop()
+	lastCheck := time.Now()
 	for {
 		select {
 		case <-s.done:
@@ -129,12 +260,25 @@ func (s *Scheduler) run() {
 			s.timer.Stop()
 			s.mu.Unlock()
 			return
+		case <-clock.C:
+			now := time.Now()
+			delta := clockSkew(lastCheck, now)
+			lastCheck = now
+			s.mu.Lock()
+			s.lastWake = now
+			s.resetTimerLocked()
+			onClockJump := s.onClockJump
+			s.mu.Unlock()
+			if onClockJump != nil && (delta > clockJumpThreshold || delta < -clockJumpThreshold) {
+				onClockJump(delta)
+			}
 		case <-s.reset:
 			// Timer was reset externally (AddJob/RemoveJob); loop back to
 			// wait on the updated timer.
 			continue
 		case <-s.timer.C:
 			s.mu.Lock()
+			s.lastWake = time.Now()
 			if s.heap.Len() == 0 {
 				s.mu.Unlock()
 				continue
@@ -151,18 +295,38 @@ func (s *Scheduler) run() {
 			}
 
 			// Pop the entry, fire the callback, recalculate, and re-push.
+			// Entries with no future occurrence are dropped as dormant.
 			heap.Pop(&s.heap)
 			jobName := e.jobName
+			scheduledAt := e.nextRun
 			e.nextRun = NextTime(e.schedule, now)
-			heap.Push(&s.heap, e)
+			var onDormant func(string)
+			if e.nextRun.IsZero() {
+				s.dormant[jobName] = struct{}{}
+				onDormant = s.onDormant
+			} else {
+				heap.Push(&s.heap, e)
+			}
 			s.resetTimerLocked()
+			paused := s.paused
 			s.mu.Unlock()
 
-			s.fire(jobName)
+			if !paused {
+				s.fire(jobName, scheduledAt)
+			}
+			if onDormant != nil {
+				onDormant(jobName)
+			}
 		}
 	}
 }
 
+// clockSkew returns how far the wall clock moved beyond the monotonic clock
+// between two time.Now readings.
+func clockSkew(last, now time.Time) time.Duration {
+	return now.Round(0).Sub(last.Round(0)) - now.Sub(last)
+}
+
 // resetTimerLocked resets the timer to fire at the earliest entry's nextRun.
 // Caller must hold s.mu. Safe to call before Start (timer may be nil).
 func (s *Scheduler) resetTimerLocked() {
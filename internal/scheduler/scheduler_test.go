@@ -0,0 +1,181 @@
+package scheduler
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAddJobWithNoFutureOccurrenceIsDormant(t *testing.T) {
+	t.Parallel()
+
+	// February 30th never exists.
+	schedule, err := ParseSchedule("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("ParseSchedule: %v", err)
+	}
+
+	var notified []string
+	s := NewScheduler(func(string, time.Time) {
+		t.Fatal("dormant job must not fire")
+	})
+	s.OnDormant(func(name string) {
+		notified = append(notified, name)
+	})
+
+	s.AddJob("never", schedule)
+
+	if !s.IsDormant("never") {
+		t.Fatal("expected job to be dormant")
+	}
+	if _, ok := s.NextRunTime("never"); ok {
+		t.Fatal("dormant job must not report a next run time")
+	}
+	if len(notified) != 1 || notified[0] != "never" {
+		t.Fatalf("expected one dormant notification, got %v", notified)
+	}
+
+	s.RemoveJob("never")
+	if s.IsDormant("never") {
+		t.Fatal("expected dormant flag cleared after RemoveJob")
+	}
+}
+
+func TestUpcoming(t *testing.T) {
+	t.Parallel()
+
+	schedule, err := ParseSchedule("0 12 * * *")
+	if err != nil {
+		t.Fatalf("ParseSchedule: %v", err)
+	}
+	after := time.Date(2026, 1, 1, 13, 0, 0, 0, time.UTC)
+	times := Upcoming(schedule, after, 3)
+	if len(times) != 3 {
+		t.Fatalf("expected 3 times, got %v", times)
+	}
+	if want := time.Date(2026, 1, 4, 12, 0, 0, 0, time.UTC); !times[2].Equal(want) {
+		t.Fatalf("expected last time %s, got %s", want, times[2])
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"*/5 * * * *":                      "*/5 * * * *",
+		"@daily":                           "@daily",
+		"every minute":                     "* * * * *",
+		"Every 15 minutes":                 "*/15 * * * *",
+		"hourly":                           "0 * * * *",
+		"every 6 hours":                    "0 */6 * * *",
+		"daily":                            "0 0 * * *",
+		"every day at 2:30pm":              "30 14 * * *",
+		"every weekday at 9am":             "0 9 * * 1-5",
+		"every weekend at noon":            "0 12 * * 0,6",
+		"every monday and friday at 17:30": "30 17 * * 1,5",
+		"every month on the 1st at 6am":    "0 6 1 * *",
+	}
+	for in, want := range cases {
+		got, err := Normalize(in)
+		if err != nil || got != want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+
+	for _, bad := range []string{"every 90 minutes", "every day at 25:00", "every blursday", "sometimes"} {
+		if _, err := Normalize(bad); err == nil {
+			t.Errorf("Normalize(%q): expected error", bad)
+		}
+	}
+}
+
+func TestAtSchedule(t *testing.T) {
+	t.Parallel()
+
+	schedule, err := ParseSchedule("@at 2024-07-01T03:00:00Z")
+	if err != nil {
+		t.Fatalf("ParseSchedule: %v", err)
+	}
+	at := time.Date(2024, 7, 1, 3, 0, 0, 0, time.UTC)
+	if got := schedule.Next(at.Add(-time.Hour)); !got.Equal(at) {
+		t.Fatalf("Next before the time = %s, want %s", got, at)
+	}
+	if got := schedule.Next(at); !got.IsZero() {
+		t.Fatalf("Next at the time = %s, want zero", got)
+	}
+
+	local, err := ParseAt("@at 2024-07-01 03:00")
+	if err != nil {
+		t.Fatalf("ParseAt: %v", err)
+	}
+	if want := time.Date(2024, 7, 1, 3, 0, 0, 0, time.Local); !local.At.Equal(want) {
+		t.Fatalf("zone-less time = %s, want %s", local.At, want)
+	}
+
+	for _, expr := range []string{"@at", "@at tomorrow", "@at 2024-13-01T00:00:00Z"} {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Errorf("ParseSchedule(%q) succeeded, want error", expr)
+		}
+	}
+}
+
+// everySchedule fires at a fixed interval.
+type everySchedule time.Duration
+
+func (e everySchedule) Next(t time.Time) time.Time { return t.Add(time.Duration(e)) }
+
+func TestPauseSkipsFires(t *testing.T) {
+	t.Parallel()
+
+	var fired atomic.Int64
+	s := NewScheduler(func(string, time.Time) { fired.Add(1) })
+	s.Pause()
+	s.AddJob("often", everySchedule(10*time.Millisecond))
+	s.Start()
+	defer s.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+	if n := fired.Load(); n != 0 {
+		t.Fatalf("paused scheduler fired %d times", n)
+	}
+	if _, ok := s.NextRunTime("often"); !ok {
+		t.Fatal("paused schedule stopped advancing")
+	}
+	s.Resume()
+	deadline := time.Now().Add(2 * time.Second)
+	for fired.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("resumed scheduler never fired")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAlive(t *testing.T) {
+	t.Parallel()
+
+	s := NewScheduler(func(string, time.Time) {})
+	if s.Alive() {
+		t.Fatal("scheduler alive before Start")
+	}
+	s.Start()
+	defer s.Stop()
+	if !s.Alive() {
+		t.Fatal("started scheduler not alive")
+	}
+}
+
+func TestNext(t *testing.T) {
+	t.Parallel()
+
+	s := NewScheduler(func(string, time.Time) {})
+	if _, _, ok := s.Next(); ok || s.Len() != 0 {
+		t.Fatal("empty scheduler has a next job")
+	}
+	s.AddJob("slow", everySchedule(time.Hour))
+	s.AddJob("fast", everySchedule(time.Minute))
+	name, at, ok := s.Next()
+	if !ok || name != "fast" || s.Len() != 2 {
+		t.Fatalf("Next = %q, %s, %v; Len = %d", name, at, ok, s.Len())
+	}
+}
@@ -0,0 +1,154 @@
+// Package secrets encrypts job env values so job files can be committed
+// without exposing them. An encrypted value is "enc:v1:" followed by the
+// base64 of a random nonce and the AES-256-GCM ciphertext; it is decrypted
+// only when a run starts.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+)
+
+// Prefix marks an encrypted value.
+const Prefix = "enc:v1:"
+
+// KeyEnv is the environment variable holding the master key when the
+// config sets none.
+const KeyEnv = "CRONBAT_SECRET_KEY"
+
+// KeySize is the master key length in bytes.
+const KeySize = 32
+
+// ErrNoKey is returned when a value needs decrypting and no master key is
+// configured.
+var ErrNoKey = errors.New("no secrets key configured (secrets.key, secrets.key_file, or " + KeyEnv + ")")
+
+// IsEncrypted reports whether v is an encrypted value.
+func IsEncrypted(v string) bool {
+	return strings.HasPrefix(v, Prefix)
+}
+
+// GenerateKey returns a new random master key, base64-encoded.
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ParseKey decodes a base64-encoded master key.
+func ParseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, errors.New("secrets key is not valid base64")
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("secrets key must be %d bytes, got %d", KeySize, len(key))
+	}
+	return key, nil
+}
+
+// LoadKey returns the master key from cfg, its key file, or KeyEnv, in
+// that order. It returns nil without an error when none is set.
+func LoadKey(cfg config.SecretsConfig) ([]byte, error) {
+	switch {
+	case cfg.Key != "":
+		return ParseKey(cfg.Key)
+	case cfg.KeyFile != "":
+		data, err := os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read secrets key file: %w", err)
+		}
+		return ParseKey(string(data))
+	case os.Getenv(KeyEnv) != "":
+		return ParseKey(os.Getenv(KeyEnv))
+	}
+	return nil, nil
+}
+
+// Encrypt returns plaintext as an encrypted value.
+func Encrypt(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return Prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the plaintext of an encrypted value. Other values are
+// returned unchanged.
+func Decrypt(key []byte, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if key == nil {
+		return "", ErrNoKey
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, Prefix))
+	if err != nil {
+		return "", errors.New("encrypted value is not valid base64")
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("encrypted value is truncated")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("encrypted value does not decrypt with this key")
+	}
+	return string(plaintext), nil
+}
+
+// DecryptEnv returns env with its encrypted values decrypted, and the
+// sorted names of those values. env itself is returned when nothing in it
+// is encrypted.
+func DecryptEnv(key []byte, env map[string]string) (map[string]string, []string, error) {
+	var names []string
+	for name, v := range env {
+		if IsEncrypted(v) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return env, nil, nil
+	}
+	sort.Strings(names)
+	out := make(map[string]string, len(env))
+	for k, v := range env {
+		out[k] = v
+	}
+	for _, name := range names {
+		plaintext, err := Decrypt(key, env[name])
+		if err != nil {
+			return nil, nil, fmt.Errorf("env %s: %w", name, err)
+		}
+		out[name] = plaintext
+	}
+	return out, names, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
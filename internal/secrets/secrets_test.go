@@ -0,0 +1,55 @@
+package secrets
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	encoded, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParseKey(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := Encrypt(key, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(value) {
+		t.Fatalf("Encrypt = %q, missing prefix", value)
+	}
+	env, names, err := DecryptEnv(key, map[string]string{"TOKEN": value, "MODE": "full"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env["TOKEN"] != "hunter2" || env["MODE"] != "full" || len(names) != 1 || names[0] != "TOKEN" {
+		t.Fatalf("DecryptEnv = %v, %v", env, names)
+	}
+
+	other, _ := GenerateKey()
+	otherKey, _ := ParseKey(other)
+	if _, err := Decrypt(otherKey, value); err == nil {
+		t.Fatal("value decrypted with the wrong key")
+	}
+	if _, err := Decrypt(nil, value); !errors.Is(err, ErrNoKey) {
+		t.Fatalf("Decrypt without key = %v, want ErrNoKey", err)
+	}
+	if _, err := Decrypt(key, Prefix+"AAAA"); err == nil {
+		t.Fatal("truncated value decrypted")
+	}
+	if got, err := Decrypt(nil, "plain"); err != nil || got != "plain" {
+		t.Fatalf("Decrypt(plain) = %q, %v", got, err)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	for _, s := range []string{"", "not base64!", "c2hvcnQ="} {
+		if _, err := ParseKey(s); err == nil {
+			t.Errorf("ParseKey(%q) succeeded", s)
+		}
+	}
+}
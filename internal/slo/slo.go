@@ -0,0 +1,135 @@
+// Package slo evaluates jobs' service level objectives against their run
+// history.
+package slo
+
+import (
+	"math"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+)
+
+// Violations a Report can raise.
+const (
+	BudgetBurned     = "budget_burned"
+	IntervalBreached = "interval_breached"
+)
+
+// CheckInterval is how often the daemon re-evaluates SLOs between runs.
+const CheckInterval = time.Minute
+
+// Objective is a parsed SLO.
+type Objective struct {
+	// SuccessRate is the target percentage of successful runs; zero
+	// means the success rate is not tracked.
+	SuccessRate float64
+	Window      time.Duration
+	// MaxInterval is the longest allowed time since the last success;
+	// zero means it is not tracked.
+	MaxInterval time.Duration
+}
+
+// FromConfig parses a job's slo block. It returns false when the job has
+// no SLO.
+func FromConfig(c *config.SLOConfig) (Objective, bool, error) {
+	if c == nil {
+		return Objective{}, false, nil
+	}
+	window, err := c.ParseWindow()
+	if err != nil {
+		return Objective{}, false, err
+	}
+	maxInterval, err := c.ParseMaxInterval()
+	if err != nil {
+		return Objective{}, false, err
+	}
+	return Objective{SuccessRate: c.SuccessRate, Window: window, MaxInterval: maxInterval}, true, nil
+}
+
+// History summarizes a job's runs for evaluation.
+type History struct {
+	// Successes and Failures count runs that finished within the window.
+	Successes int
+	Failures  int
+	// LastSuccess is the job's most recent successful run, if any.
+	LastSuccess *time.Time
+	// Since is when tracking started: the later of the window start and
+	// the job's first run. A job is not in breach of MaxInterval before
+	// MaxInterval has passed since then.
+	Since time.Time
+}
+
+// Report is the compliance of one job with its SLO.
+type Report struct {
+	JobName string `json:"job_name"`
+	// Compliant is false while any violation is active.
+	Compliant  bool     `json:"compliant"`
+	Violations []string `json:"violations"`
+
+	TargetSuccessRate float64 `json:"target_success_rate,omitempty"`
+	// SuccessRate is the observed percentage; nil without finished runs.
+	SuccessRate *float64 `json:"success_rate,omitempty"`
+	Window      string   `json:"window"`
+	Successes   int      `json:"successes"`
+	Failures    int      `json:"failures"`
+	// ErrorBudgetRemaining is the fraction of allowed failures not yet
+	// used: 1 with no failures, 0 when exhausted, negative once overspent.
+	ErrorBudgetRemaining *float64 `json:"error_budget_remaining,omitempty"`
+
+	MaxInterval string     `json:"max_interval,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	// IntervalDeadline is when the job breaches MaxInterval without
+	// another success.
+	IntervalDeadline *time.Time `json:"interval_deadline,omitempty"`
+}
+
+// Evaluate computes a job's compliance with obj at now.
+func Evaluate(jobName string, obj Objective, h History, now time.Time) Report {
+	r := Report{
+		JobName:    jobName,
+		Violations: []string{},
+		Window:     obj.Window.String(),
+		Successes:  h.Successes,
+		Failures:   h.Failures,
+	}
+
+	if obj.SuccessRate > 0 {
+		r.TargetSuccessRate = obj.SuccessRate
+		total := h.Successes + h.Failures
+		if total > 0 {
+			rate := 100 * float64(h.Successes) / float64(total)
+			r.SuccessRate = &rate
+		}
+		allowed := (1 - obj.SuccessRate/100) * float64(total)
+		remaining := 1.0
+		switch {
+		case allowed > 0:
+			remaining = 1 - float64(h.Failures)/allowed
+		case h.Failures > 0:
+			// A 100% target allows no failures at all.
+			remaining = -1
+		}
+		remaining = math.Round(remaining*1e4) / 1e4
+		r.ErrorBudgetRemaining = &remaining
+		if remaining < 0 {
+			r.Violations = append(r.Violations, BudgetBurned)
+		}
+	}
+
+	if obj.MaxInterval > 0 {
+		r.MaxInterval = obj.MaxInterval.String()
+		r.LastSuccess = h.LastSuccess
+		from := h.Since
+		if h.LastSuccess != nil && h.LastSuccess.After(from) {
+			from = *h.LastSuccess
+		}
+		deadline := from.Add(obj.MaxInterval)
+		r.IntervalDeadline = &deadline
+		if now.After(deadline) {
+			r.Violations = append(r.Violations, IntervalBreached)
+		}
+	}
+
+	r.Compliant = len(r.Violations) == 0
+	return r
+}
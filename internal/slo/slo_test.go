@@ -0,0 +1,89 @@
+package slo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+)
+
+func TestEvaluateErrorBudget(t *testing.T) {
+	t.Parallel()
+
+	obj := Objective{SuccessRate: 90, Window: 30 * 24 * time.Hour}
+	now := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                string
+		successes, failures int
+		wantRemaining       float64
+		wantCompliant       bool
+	}{
+		{"no runs", 0, 0, 1, true},
+		{"half the budget", 19, 1, 0.5, true},
+		{"budget exhausted", 18, 2, 0, true},
+		{"budget burned", 17, 3, -0.5, false},
+	}
+	strict := Objective{SuccessRate: 100, Window: obj.Window}
+	if r := Evaluate("job", strict, History{Successes: 99, Failures: 1}, now); r.Compliant {
+		t.Error("a 100% target must be burned by one failure")
+	}
+	for _, tt := range tests {
+		r := Evaluate("job", obj, History{Successes: tt.successes, Failures: tt.failures}, now)
+		if r.ErrorBudgetRemaining == nil || *r.ErrorBudgetRemaining != tt.wantRemaining {
+			t.Errorf("%s: remaining=%v, want %v", tt.name, r.ErrorBudgetRemaining, tt.wantRemaining)
+		}
+		if r.Compliant != tt.wantCompliant {
+			t.Errorf("%s: compliant=%v, want %v (violations %v)", tt.name, r.Compliant, tt.wantCompliant, r.Violations)
+		}
+	}
+}
+
+func TestEvaluateMaxInterval(t *testing.T) {
+	t.Parallel()
+
+	obj := Objective{Window: 30 * 24 * time.Hour, MaxInterval: 24 * time.Hour}
+	now := time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC)
+	recent := now.Add(-23 * time.Hour)
+	stale := now.Add(-25 * time.Hour)
+
+	if r := Evaluate("job", obj, History{LastSuccess: &recent, Since: now.Add(-48 * time.Hour)}, now); !r.Compliant {
+		t.Fatalf("recent success: %v", r.Violations)
+	}
+	r := Evaluate("job", obj, History{LastSuccess: &stale, Since: now.Add(-48 * time.Hour)}, now)
+	if r.Compliant || len(r.Violations) != 1 || r.Violations[0] != IntervalBreached {
+		t.Fatalf("stale success: %v", r.Violations)
+	}
+	if r.ErrorBudgetRemaining != nil {
+		t.Fatal("success rate is not tracked without success_rate")
+	}
+	// Never succeeded, but tracking started less than max_interval ago.
+	if r := Evaluate("job", obj, History{Since: now.Add(-time.Hour)}, now); !r.Compliant {
+		t.Fatalf("new job: %v", r.Violations)
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	t.Parallel()
+
+	obj, ok, err := FromConfig(&config.SLOConfig{SuccessRate: 99, MaxInterval: "1d"})
+	if err != nil || !ok {
+		t.Fatalf("FromConfig: ok=%v err=%v", ok, err)
+	}
+	if obj.Window != config.DefaultSLOWindow || obj.MaxInterval != 24*time.Hour {
+		t.Fatalf("got %+v", obj)
+	}
+	if _, ok, _ := FromConfig(nil); ok {
+		t.Fatal("nil slo must not be tracked")
+	}
+	for _, bad := range []*config.SLOConfig{
+		{SuccessRate: 101},
+		{Window: "30d"},
+		{SuccessRate: 99, Window: "-1h"},
+		{MaxInterval: "soon"},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", bad)
+		}
+	}
+}
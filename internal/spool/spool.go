@@ -0,0 +1,172 @@
+// Package spool keeps reports of runs that could not be recorded, because
+// the API was unreachable or the database was locked, until they can be.
+// "cronbat wrap" writes and drains spools; the daemon also drains the one in
+// its data directory.
+package spool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/store"
+)
+
+// DirName is the spool directory under data_dir.
+const DirName = "spool"
+
+// Report is a run executed outside the daemon, as spooled and as sent to
+// POST /api/v1/runs. Reports carry their run ID, so recording one twice
+// updates the same run.
+type Report struct {
+	ID           string            `json:"id"`
+	JobName      string            `json:"job_name"`
+	Status       string            `json:"status"`
+	ExitCode     int               `json:"exit_code"`
+	StartedAt    time.Time         `json:"started_at"`
+	FinishedAt   *time.Time        `json:"finished_at,omitempty"`
+	DurationMs   int64             `json:"duration_ms"`
+	StdoutTail   string            `json:"stdout_tail,omitempty"`
+	StderrTail   string            `json:"stderr_tail,omitempty"`
+	ErrorMsg     string            `json:"error_msg,omitempty"`
+	Trigger      string            `json:"trigger"`
+	JobsCommit   string            `json:"jobs_commit,omitempty"`
+	StdoutSHA256 string            `json:"stdout_sha256,omitempty"`
+	StderrSHA256 string            `json:"stderr_sha256,omitempty"`
+	Host         string            `json:"host,omitempty"`
+	Outputs      map[string]string `json:"outputs,omitempty"`
+}
+
+// FromRun returns the report of run.
+func FromRun(run *store.Run) *Report {
+	return &Report{
+		ID:           run.ID,
+		JobName:      run.JobName,
+		Status:       run.Status,
+		ExitCode:     run.ExitCode,
+		StartedAt:    run.StartedAt,
+		FinishedAt:   run.FinishedAt,
+		DurationMs:   run.DurationMs,
+		StdoutTail:   run.StdoutTail,
+		StderrTail:   run.StderrTail,
+		ErrorMsg:     run.ErrorMsg,
+		Trigger:      run.Trigger,
+		JobsCommit:   run.JobsCommit,
+		StdoutSHA256: run.StdoutSHA256,
+		StderrSHA256: run.StderrSHA256,
+		Host:         run.Host,
+		Outputs:      run.Outputs,
+	}
+}
+
+// Run returns the run record of r.
+func (r *Report) Run() *store.Run {
+	return &store.Run{
+		ID:           r.ID,
+		JobName:      r.JobName,
+		Status:       r.Status,
+		ExitCode:     r.ExitCode,
+		StartedAt:    r.StartedAt,
+		FinishedAt:   r.FinishedAt,
+		DurationMs:   r.DurationMs,
+		StdoutTail:   r.StdoutTail,
+		StderrTail:   r.StderrTail,
+		ErrorMsg:     r.ErrorMsg,
+		Trigger:      r.Trigger,
+		JobsCommit:   r.JobsCommit,
+		StdoutSHA256: r.StdoutSHA256,
+		StderrSHA256: r.StderrSHA256,
+		Host:         r.Host,
+		Outputs:      r.Outputs,
+	}
+}
+
+// Validate checks the fields a report must have to be recorded.
+func (r *Report) Validate() error {
+	switch {
+	case r.ID == "":
+		return errors.New("id is required")
+	case strings.ContainsAny(r.ID, `/\`) || r.ID == "." || r.ID == "..":
+		return errors.New("invalid id")
+	case r.JobName == "":
+		return errors.New("job_name is required")
+	case r.Status == "":
+		return errors.New("status is required")
+	case r.StartedAt.IsZero():
+		return errors.New("started_at is required")
+	}
+	return nil
+}
+
+// Write adds r to the spool in dir.
+func Write(dir string, r *Report) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	// Write then rename, so a drain never reads half a report.
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, r.ID+".json"))
+}
+
+// Drain passes the spooled reports in dir to record, oldest first,
+// removing each one record accepts. It stops at the first error, leaving
+// that report and the rest for the next drain. Unreadable reports are
+// moved aside with a ".bad" suffix. A missing dir holds no reports.
+func Drain(dir string, record func(*Report) error) (n int, err error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+	reports := make([]*Report, 0, len(paths))
+	files := make(map[*Report]string, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return n, err
+		}
+		var r Report
+		if err := json.Unmarshal(data, &r); err != nil || r.Validate() != nil {
+			os.Rename(path, path+".bad")
+			continue
+		}
+		reports = append(reports, &r)
+		files[&r] = path
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		return reports[i].StartedAt.Before(reports[j].StartedAt)
+	})
+	for _, r := range reports {
+		if err := record(r); err != nil {
+			return n, fmt.Errorf("run %s: %w", r.ID, err)
+		}
+		if err := os.Remove(files[r]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
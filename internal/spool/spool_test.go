@@ -0,0 +1,50 @@
+package spool
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteAndDrain(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, id := range []string{"b", "a"} {
+		r := &Report{ID: id, JobName: "backup", Status: "success", StartedAt: start.Add(time.Duration(i) * time.Minute), Trigger: "cron"}
+		if err := Write(dir, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, "junk.json"), []byte("{"), 0600)
+	if err := Write(dir, &Report{ID: "../x", JobName: "backup", Status: "success", StartedAt: start}); err == nil {
+		t.Fatal("wrote a report with a path in its id")
+	}
+
+	// The first drain fails on the second report and keeps it.
+	var got []string
+	n, err := Drain(dir, func(r *Report) error {
+		if len(got) == 1 {
+			return errors.New("db locked")
+		}
+		got = append(got, r.ID)
+		return nil
+	})
+	if n != 1 || err == nil || len(got) != 1 || got[0] != "b" {
+		t.Fatalf("first drain: n=%d err=%v got=%v; want b recorded, then an error", n, err, got)
+	}
+	n, err = Drain(dir, func(r *Report) error {
+		got = append(got, r.ID)
+		return nil
+	})
+	if n != 1 || err != nil || len(got) != 2 || got[1] != "a" {
+		t.Fatalf("second drain: n=%d err=%v got=%v", n, err, got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "junk.json.bad")); err != nil {
+		t.Fatalf("unreadable report not moved aside: %v", err)
+	}
+	if n, err := Drain(filepath.Join(dir, "missing"), nil); n != 0 || err != nil {
+		t.Fatalf("missing dir: n=%d err=%v", n, err)
+	}
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Annotation is a timestamped note attached to a job, such as why it was
+// disabled or what it is waiting on.
+type Annotation struct {
+	ID      int64
+	JobName string
+	At      time.Time
+	Author  string
+	Note    string
+}
+
+// AddAnnotation attaches a note to a job.
+func (s *SQLiteStore) AddAnnotation(ctx context.Context, a *Annotation) error {
+	if a.At.IsZero() {
+		a.At = time.Now().UTC()
+	}
+	res, err := s.db.ExecContext(ctx,
+		"INSERT INTO job_annotations (job_name, at, author, note) VALUES (?, ?, ?, ?)",
+		a.JobName, formatTime(a.At), a.Author, a.Note)
+	if err != nil {
+		return err
+	}
+	a.ID, err = res.LastInsertId()
+	return err
+}
+
+// ListAnnotations returns a job's newest annotations first.
+func (s *SQLiteStore) ListAnnotations(ctx context.Context, jobName string, limit int) ([]*Annotation, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, job_name, at, author, note FROM job_annotations
+		WHERE job_name = ?
+		ORDER BY id DESC
+		LIMIT ?`, jobName, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*Annotation
+	for rows.Next() {
+		var a Annotation
+		var at string
+		if err := rows.Scan(&a.ID, &a.JobName, &at, &a.Author, &a.Note); err != nil {
+			return nil, err
+		}
+		if a.At, err = parseTime(at); err != nil {
+			return nil, err
+		}
+		out = append(out, &a)
+	}
+	return out, rows.Err()
+}
+
+// DeleteAnnotation removes one of a job's annotations. It returns false if
+// the job has no annotation with that ID.
+func (s *SQLiteStore) DeleteAnnotation(ctx context.Context, jobName string, id int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM job_annotations WHERE id = ? AND job_name = ?", id, jobName)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
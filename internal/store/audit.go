@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// AuditEntry records an action taken on a job through the API or by
+// cronbat itself.
+type AuditEntry struct {
+	ID int64
+	At time.Time
+	// Actor identifies who acted: an API caller description, or "cronbat"
+	// for automatic actions.
+	Actor   string
+	Action  string
+	JobName string
+	Detail  string
+}
+
+// RecordAudit appends an entry to the audit log.
+func (s *SQLiteStore) RecordAudit(ctx context.Context, e *AuditEntry) error {
+	if e.At.IsZero() {
+		e.At = time.Now().UTC()
+	}
+	res, err := s.db.ExecContext(ctx,
+		"INSERT INTO audit_log (at, actor, action, job_name, detail) VALUES (?, ?, ?, ?, ?)",
+		formatTime(e.At), e.Actor, e.Action, nullString(e.JobName), nullString(e.Detail))
+	if err != nil {
+		return err
+	}
+	e.ID, err = res.LastInsertId()
+	return err
+}
+
+// ListAudit returns the newest audit entries, optionally for one job.
+func (s *SQLiteStore) ListAudit(ctx context.Context, jobName string, limit int) ([]*AuditEntry, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, at, actor, action, job_name, detail FROM audit_log
+		WHERE (? = '' OR job_name = ?)
+		ORDER BY id DESC
+		LIMIT ?`, jobName, jobName, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		var at string
+		var job, detail sql.NullString
+		if err := rows.Scan(&e.ID, &at, &e.Actor, &e.Action, &job, &detail); err != nil {
+			return nil, err
+		}
+		if e.At, err = parseTime(at); err != nil {
+			return nil, err
+		}
+		e.JobName, e.Detail = job.String, detail.String
+		out = append(out, &e)
+	}
+	return out, rows.Err()
+}
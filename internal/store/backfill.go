@@ -0,0 +1,163 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Backfill is a request to run a job once per time window over a past range.
+type Backfill struct {
+	ID          string
+	JobName     string
+	From        time.Time
+	To          time.Time
+	Interval    string
+	Parallelism int
+	Status      string // "running", "completed", "failed", "cancelled"
+	CreatedAt   time.Time
+	FinishedAt  *time.Time
+}
+
+// BackfillWindow is one [Start, End) window of a backfill and the run that
+// processed it.
+type BackfillWindow struct {
+	BackfillID string
+	Seq        int
+	Start      time.Time
+	End        time.Time
+	Status     string // "pending", "running", or the run's final status
+	RunID      string
+}
+
+// CreateBackfill inserts a backfill and all of its windows in one transaction.
+func (s *SQLiteStore) CreateBackfill(ctx context.Context, b *Backfill, windows []BackfillWindow) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO backfills (id, job_name, range_from, range_to, interval, parallelism, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		b.ID, b.JobName, formatTime(b.From), formatTime(b.To), b.Interval, b.Parallelism, b.Status, formatTime(b.CreatedAt),
+	); err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO backfill_windows (backfill_id, seq, window_start, window_end, status)
+		VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, w := range windows {
+		if _, err := stmt.ExecContext(ctx, b.ID, w.Seq, formatTime(w.Start), formatTime(w.End), w.Status); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UpdateBackfillStatus sets a backfill's status and finish time.
+func (s *SQLiteStore) UpdateBackfillStatus(ctx context.Context, id, status string, finishedAt *time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE backfills SET status = ?, finished_at = ? WHERE id = ?",
+		status, formatTimePtr(finishedAt), id)
+	return err
+}
+
+// UpdateBackfillWindow records a window's status and run ID.
+func (s *SQLiteStore) UpdateBackfillWindow(ctx context.Context, w BackfillWindow) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE backfill_windows SET status = ?, run_id = ? WHERE backfill_id = ? AND seq = ?",
+		w.Status, nullString(w.RunID), w.BackfillID, w.Seq)
+	return err
+}
+
+const selectBackfillCols = "id, job_name, range_from, range_to, interval, parallelism, status, created_at, finished_at"
+
+func scanBackfill(row interface{ Scan(...any) error }) (*Backfill, error) {
+	var b Backfill
+	var from, to, created string
+	var finished sql.NullString
+	if err := row.Scan(&b.ID, &b.JobName, &from, &to, &b.Interval, &b.Parallelism, &b.Status, &created, &finished); err != nil {
+		return nil, err
+	}
+	var err error
+	if b.From, err = parseTime(from); err != nil {
+		return nil, err
+	}
+	if b.To, err = parseTime(to); err != nil {
+		return nil, err
+	}
+	if b.CreatedAt, err = parseTime(created); err != nil {
+		return nil, err
+	}
+	if b.FinishedAt, err = parseTimePtr(finished); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// GetBackfill returns a backfill by ID, or nil if it does not exist.
+func (s *SQLiteStore) GetBackfill(ctx context.Context, id string) (*Backfill, error) {
+	b, err := scanBackfill(s.db.QueryRowContext(ctx,
+		"SELECT "+selectBackfillCols+" FROM backfills WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return b, err
+}
+
+// ListBackfills returns backfills, newest first, optionally filtered by job
+// name and status.
+func (s *SQLiteStore) ListBackfills(ctx context.Context, jobName, status string) ([]*Backfill, error) {
+	query := "SELECT " + selectBackfillCols + " FROM backfills WHERE (? = '' OR job_name = ?) AND (? = '' OR status = ?) ORDER BY created_at DESC"
+	rows, err := s.db.QueryContext(ctx, query, jobName, jobName, status, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*Backfill
+	for rows.Next() {
+		b, err := scanBackfill(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+// ListBackfillWindows returns a backfill's windows in order.
+func (s *SQLiteStore) ListBackfillWindows(ctx context.Context, backfillID string) ([]BackfillWindow, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT seq, window_start, window_end, status, run_id
+		FROM backfill_windows WHERE backfill_id = ? ORDER BY seq`, backfillID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []BackfillWindow
+	for rows.Next() {
+		w := BackfillWindow{BackfillID: backfillID}
+		var start, end string
+		var runID sql.NullString
+		if err := rows.Scan(&w.Seq, &start, &end, &w.Status, &runID); err != nil {
+			return nil, err
+		}
+		if w.Start, err = parseTime(start); err != nil {
+			return nil, err
+		}
+		if w.End, err = parseTime(end); err != nil {
+			return nil, err
+		}
+		w.RunID = runID.String
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
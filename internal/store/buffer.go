@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultFlushBatch is the number of queued runs that triggers an immediate
+// flush when no limit is given to StartWriteBuffer.
+const DefaultFlushBatch = 100
+
+// writeBuffer queues run writes and commits them in one transaction per
+// flush. Writes to the same run are coalesced, so a run that starts and
+// finishes between flushes costs one insert.
+type writeBuffer struct {
+	mu       sync.Mutex
+	pending  map[string]*Run
+	order    []string
+	maxBatch int
+
+	// flushMu keeps flushes in order so an older state of a run never
+	// overwrites a newer one.
+	flushMu sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// StartWriteBuffer makes RecordRun queue writes and commit them every
+// interval, or as soon as maxBatch runs are queued. Reads flush first, so
+// they always see queued runs. Queued writes are lost if the process dies
+// before a flush; Close flushes them. It must be called before the store is
+// used concurrently.
+func (s *SQLiteStore) StartWriteBuffer(interval time.Duration, maxBatch int) {
+	if maxBatch <= 0 {
+		maxBatch = DefaultFlushBatch
+	}
+	b := &writeBuffer{
+		pending:  make(map[string]*Run),
+		maxBatch: maxBatch,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	s.buf = b
+
+	go func() {
+		defer close(b.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-b.stop:
+				return
+			case <-ticker.C:
+				// A failed flush keeps its runs queued for the next tick;
+				// the error also surfaces on the next read or Close.
+				_ = s.Flush(context.Background())
+			}
+		}
+	}()
+}
+
+// Flush commits queued run writes. It does nothing without a write buffer.
+func (s *SQLiteStore) Flush(ctx context.Context) error {
+	b := s.buf
+	if b == nil {
+		return nil
+	}
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	runs := b.take()
+	if err := s.recordRunsTx(ctx, runs); err != nil {
+		b.requeue(runs)
+		return err
+	}
+	return nil
+}
+
+// add queues a copy of run and reports whether the queue is full.
+func (b *writeBuffer) add(run *Run) bool {
+	cp := *run
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.pending[cp.ID]; !ok {
+		b.order = append(b.order, cp.ID)
+	}
+	b.pending[cp.ID] = &cp
+	return len(b.order) >= b.maxBatch
+}
+
+// take removes and returns all queued runs in first-write order.
+func (b *writeBuffer) take() []*Run {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	runs := make([]*Run, 0, len(b.order))
+	for _, id := range b.order {
+		runs = append(runs, b.pending[id])
+	}
+	b.pending = make(map[string]*Run)
+	b.order = nil
+	return runs
+}
+
+// requeue puts back runs from a failed flush unless a newer write for the
+// same run was queued meanwhile.
+func (b *writeBuffer) requeue(runs []*Run) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var order []string
+	for _, run := range runs {
+		if _, ok := b.pending[run.ID]; ok {
+			continue
+		}
+		b.pending[run.ID] = run
+		order = append(order, run.ID)
+	}
+	b.order = append(order, b.order...)
+}
+
+// close stops the flush loop and commits what is left.
+func (b *writeBuffer) close(s *SQLiteStore) error {
+	close(b.stop)
+	<-b.done
+	return s.Flush(context.Background())
+}
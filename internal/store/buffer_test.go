@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteBufferCoalescesAndFlushesOnRead(t *testing.T) {
+	t.Parallel()
+
+	st, err := NewSQLiteStore(filepath.Join(t.TempDir(), "cronbat.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	st.StartWriteBuffer(time.Hour, 10)
+	ctx := context.Background()
+
+	run := &Run{JobName: "fast", Status: "running", StartedAt: time.Now().UTC(), Trigger: "schedule"}
+	if err := st.RecordRun(ctx, run); err != nil {
+		t.Fatal(err)
+	}
+	run.Status = "success"
+	if err := st.RecordRun(ctx, run); err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	if err := st.db.QueryRow("SELECT COUNT(*) FROM runs").Scan(&n); err != nil || n != 0 {
+		t.Fatalf("expected writes to be buffered, found %d rows (err %v)", n, err)
+	}
+	got, err := st.GetRun(ctx, run.ID)
+	if err != nil || got == nil || got.Status != "success" {
+		t.Fatalf("expected read to flush the latest state, got %+v, %v", got, err)
+	}
+
+	// Reaching the batch limit flushes without waiting for the interval.
+	for i := 0; i < 10; i++ {
+		r := &Run{JobName: "fast", Status: "success", StartedAt: time.Now().UTC(), Trigger: "schedule"}
+		if err := st.RecordRun(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := st.db.QueryRow("SELECT COUNT(*) FROM runs").Scan(&n); err != nil || n != 11 {
+		t.Fatalf("expected a full batch to flush, found %d rows (err %v)", n, err)
+	}
+}
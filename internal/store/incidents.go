@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Incident is an incident a job's failures opened with an incident
+// management service (Target), kept so it can be resolved after a restart.
+type Incident struct {
+	JobName  string
+	Target   string
+	DedupKey string
+	// RunID is the run whose failure opened the incident.
+	RunID    string
+	OpenedAt time.Time
+}
+
+// OpenIncident records an open incident, replacing any earlier one for the
+// same job and target.
+func (s *SQLiteStore) OpenIncident(ctx context.Context, inc *Incident) error {
+	if inc.OpenedAt.IsZero() {
+		inc.OpenedAt = time.Now().UTC()
+	}
+	_, err := s.db.ExecContext(ctx,
+		"INSERT OR REPLACE INTO incidents (job_name, target, dedup_key, run_id, opened_at) VALUES (?, ?, ?, ?, ?)",
+		inc.JobName, inc.Target, inc.DedupKey, inc.RunID, formatTime(inc.OpenedAt))
+	return err
+}
+
+// GetIncident returns the open incident of a job with a target, or nil if
+// there is none.
+func (s *SQLiteStore) GetIncident(ctx context.Context, jobName, target string) (*Incident, error) {
+	inc := Incident{JobName: jobName, Target: target}
+	var openedAt string
+	err := s.db.QueryRowContext(ctx,
+		"SELECT dedup_key, run_id, opened_at FROM incidents WHERE job_name = ? AND target = ?",
+		jobName, target).Scan(&inc.DedupKey, &inc.RunID, &openedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if inc.OpenedAt, err = parseTime(openedAt); err != nil {
+		return nil, err
+	}
+	return &inc, nil
+}
+
+// CloseIncident forgets the open incident of a job with a target.
+func (s *SQLiteStore) CloseIncident(ctx context.Context, jobName, target string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM incidents WHERE job_name = ? AND target = ?", jobName, target)
+	return err
+}
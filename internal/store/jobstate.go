@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// JobState is a job's runtime state, kept across restarts: "started",
+// "stopped", or "paused", when a snoozed (paused) job resumes, and why it
+// was disabled.
+type JobState struct {
+	JobName        string
+	State          string
+	SnoozeUntil    *time.Time
+	DisabledReason string
+	UpdatedAt      time.Time
+}
+
+// SaveJobState creates or replaces a job's runtime state.
+func (s *SQLiteStore) SaveJobState(ctx context.Context, js *JobState) error {
+	if js.UpdatedAt.IsZero() {
+		js.UpdatedAt = time.Now().UTC()
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO job_state (job_name, state, snooze_until, disabled_reason, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(job_name) DO UPDATE SET
+			state = excluded.state,
+			snooze_until = excluded.snooze_until,
+			disabled_reason = excluded.disabled_reason,
+			updated_at = excluded.updated_at`,
+		js.JobName, js.State, formatTimePtr(js.SnoozeUntil), nullString(js.DisabledReason), formatTime(js.UpdatedAt))
+	return err
+}
+
+// ListJobStates returns the saved runtime state of every job, by name.
+func (s *SQLiteStore) ListJobStates(ctx context.Context) (map[string]*JobState, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT job_name, state, snooze_until, disabled_reason, updated_at FROM job_state")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]*JobState)
+	for rows.Next() {
+		var js JobState
+		var snooze, reason sql.NullString
+		var updated string
+		if err := rows.Scan(&js.JobName, &js.State, &snooze, &reason, &updated); err != nil {
+			return nil, err
+		}
+		if js.SnoozeUntil, err = parseTimePtr(snooze); err != nil {
+			return nil, err
+		}
+		if js.UpdatedAt, err = parseTime(updated); err != nil {
+			return nil, err
+		}
+		js.DisabledReason = reason.String
+		out[js.JobName] = &js
+	}
+	return out, rows.Err()
+}
+
+// DeleteJobState forgets a job's runtime state.
+func (s *SQLiteStore) DeleteJobState(ctx context.Context, name string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM job_state WHERE job_name = ?", name)
+	return err
+}
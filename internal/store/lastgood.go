@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// JobSnapshot is the most recent job definition that produced a successful
+// run ("last known good").
+type JobSnapshot struct {
+	JobName string
+	// Version identifies the definition (see config.Job.Version).
+	Version string
+	// Definition is the job as YAML.
+	Definition string
+	RunID      string
+	RecordedAt time.Time
+	// Pinned makes runs use Definition instead of the current job file.
+	Pinned bool
+}
+
+// SaveLastGoodJob records snap as the job's last known good definition. The
+// pinned flag is left unchanged.
+func (s *SQLiteStore) SaveLastGoodJob(ctx context.Context, snap *JobSnapshot) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO job_last_good (job_name, version, definition, run_id, recorded_at, pinned)
+		VALUES (?, ?, ?, ?, ?, 0)
+		ON CONFLICT(job_name) DO UPDATE SET
+			version = excluded.version,
+			definition = excluded.definition,
+			run_id = excluded.run_id,
+			recorded_at = excluded.recorded_at`,
+		snap.JobName, snap.Version, snap.Definition, snap.RunID, formatTime(snap.RecordedAt))
+	return err
+}
+
+// SetJobPinned pins or unpins a job to its last known good definition. It
+// returns false if the job has no last known good definition.
+func (s *SQLiteStore) SetJobPinned(ctx context.Context, jobName string, pinned bool) (bool, error) {
+	v := 0
+	if pinned {
+		v = 1
+	}
+	res, err := s.db.ExecContext(ctx, "UPDATE job_last_good SET pinned = ? WHERE job_name = ?", v, jobName)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetLastGoodJob returns a job's last known good definition, or nil if it
+// has none.
+func (s *SQLiteStore) GetLastGoodJob(ctx context.Context, jobName string) (*JobSnapshot, error) {
+	var snap JobSnapshot
+	var recorded string
+	var pinned int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT job_name, version, definition, run_id, recorded_at, pinned
+		FROM job_last_good WHERE job_name = ?`, jobName).Scan(
+		&snap.JobName, &snap.Version, &snap.Definition, &snap.RunID, &recorded, &pinned)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if snap.RecordedAt, err = parseTime(recorded); err != nil {
+		return nil, err
+	}
+	snap.Pinned = pinned != 0
+	return &snap, nil
+}
+
+// DeleteLastGoodJob forgets a job's last known good definition.
+func (s *SQLiteStore) DeleteLastGoodJob(ctx context.Context, jobName string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM job_last_good WHERE job_name = ?", jobName)
+	return err
+}
@@ -0,0 +1,43 @@
+package store
+
+import "context"
+
+// SetRunLogsPinned pins or unpins a run's log files, exempting them from
+// retention cleanup. It returns false if the run does not exist.
+func (s *SQLiteStore) SetRunLogsPinned(ctx context.Context, id string, pinned bool) (bool, error) {
+	if err := s.Flush(ctx); err != nil {
+		return false, err
+	}
+	v := 0
+	if pinned {
+		v = 1
+	}
+	res, err := s.db.ExecContext(ctx, "UPDATE runs SET logs_pinned = ? WHERE id = ?", v, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// PinnedRunIDs returns the IDs of runs whose logs are pinned.
+func (s *SQLiteStore) PinnedRunIDs(ctx context.Context) (map[string]bool, error) {
+	if err := s.Flush(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, "SELECT id FROM runs WHERE logs_pinned = 1")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,201 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// maintenanceBusyTimeoutMs is how long maintenance waits for locks held by
+// another process (e.g. the daemon) before giving up.
+const maintenanceBusyTimeoutMs = 30000
+
+// DBStats describes the on-disk size and contents of the database.
+type DBStats struct {
+	Path      string     `json:"path"`
+	FileBytes int64      `json:"file_bytes"`
+	WALBytes  int64      `json:"wal_bytes"`
+	PageSize  int64      `json:"page_size"`
+	PageCount int64      `json:"page_count"`
+	FreePages int64      `json:"free_pages"`
+	RunCount  int64      `json:"run_count"`
+	JobCount  int64      `json:"job_count"`
+	TailBytes int64      `json:"tail_bytes"`
+	OldestRun *time.Time `json:"oldest_run,omitempty"`
+}
+
+// CompactResult reports the outcome of Compact.
+type CompactResult struct {
+	Before     *DBStats `json:"before"`
+	After      *DBStats `json:"after"`
+	Integrity  string   `json:"integrity"`
+	DurationMs int64    `json:"duration_ms"`
+}
+
+// Stats returns size and content statistics for the database.
+func (s *SQLiteStore) Stats(ctx context.Context) (*DBStats, error) {
+	if err := s.Flush(ctx); err != nil {
+		return nil, err
+	}
+	stats := &DBStats{Path: s.path}
+	if fi, err := os.Stat(s.path); err == nil {
+		stats.FileBytes = fi.Size()
+	}
+	if fi, err := os.Stat(s.path + "-wal"); err == nil {
+		stats.WALBytes = fi.Size()
+	}
+
+	pragmas := []struct {
+		name string
+		dst  *int64
+	}{
+		{"page_size", &stats.PageSize},
+		{"page_count", &stats.PageCount},
+		{"freelist_count", &stats.FreePages},
+	}
+	for _, p := range pragmas {
+		if err := s.db.QueryRowContext(ctx, "PRAGMA "+p.name).Scan(p.dst); err != nil {
+			return nil, fmt.Errorf("pragma %s: %w", p.name, err)
+		}
+	}
+
+	var oldest sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(DISTINCT job_name),
+			COALESCE(SUM(LENGTH(stdout_tail) + LENGTH(stderr_tail)), 0),
+			MIN(started_at)
+		FROM runs`).Scan(&stats.RunCount, &stats.JobCount, &stats.TailBytes, &oldest)
+	if err != nil {
+		return nil, fmt.Errorf("count runs: %w", err)
+	}
+	if stats.OldestRun, err = parseTimePtr(oldest); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// RunCountsByJob returns the number of recorded runs of each job, deleted
+// jobs included.
+func (s *SQLiteStore) RunCountsByJob(ctx context.Context) (map[string]int64, error) {
+	if err := s.Flush(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT job_name, COUNT(*) FROM runs GROUP BY job_name`)
+	if err != nil {
+		return nil, fmt.Errorf("count runs by job: %w", err)
+	}
+	defer rows.Close()
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var name string
+		var n int64
+		if err := rows.Scan(&name, &n); err != nil {
+			return nil, err
+		}
+		counts[name] = n
+	}
+	return counts, rows.Err()
+}
+
+// WALBytes returns the size of the write-ahead log file, or 0 if there is
+// none.
+func (s *SQLiteStore) WALBytes() int64 {
+	fi, err := os.Stat(s.path + "-wal")
+	if err != nil {
+		return 0
+	}
+	return fi.Size()
+}
+
+// CheckWritable takes the database's write lock and releases it without
+// changing anything, failing if the file is read-only or another process
+// holds the lock too long.
+func (s *SQLiteStore) CheckWritable(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, "DELETE FROM runs WHERE 0")
+	return err
+}
+
+// Compact checks database integrity, then checkpoints the WAL, rebuilds the
+// file with VACUUM and refreshes planner statistics with ANALYZE. It is safe
+// to run while another process has the database open; it waits for locks
+// and fails if they are not released in time.
+func (s *SQLiteStore) Compact(ctx context.Context) (*CompactResult, error) {
+	if err := s.Flush(ctx); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	before, err := s.Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("PRAGMA busy_timeout = %d", maintenanceBusyTimeoutMs)); err != nil {
+		return nil, fmt.Errorf("set busy timeout: %w", err)
+	}
+
+	integrity, err := integrityCheck(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	if integrity != "ok" {
+		return &CompactResult{Before: before, Integrity: integrity}, errors.New("integrity check failed, not compacting")
+	}
+
+	for _, stmt := range []string{
+		"PRAGMA wal_checkpoint(TRUNCATE)",
+		"VACUUM",
+		"ANALYZE",
+		"PRAGMA wal_checkpoint(TRUNCATE)",
+	} {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("%s: %w", stmt, err)
+		}
+	}
+
+	after, err := s.Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &CompactResult{
+		Before:     before,
+		After:      after,
+		Integrity:  integrity,
+		DurationMs: time.Since(start).Milliseconds(),
+	}, nil
+}
+
+func integrityCheck(ctx context.Context, conn *sql.Conn) (string, error) {
+	rows, err := conn.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return "", fmt.Errorf("integrity check: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", err
+		}
+		problems = append(problems, line)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(problems, "; "), nil
+}
@@ -1,30 +1,323 @@
 package store
 
-import "database/sql"
-
-const migrationSQL = `
-CREATE TABLE IF NOT EXISTS runs (
-    id TEXT PRIMARY KEY,
-    job_name TEXT NOT NULL,
-    status TEXT NOT NULL,
-    exit_code INTEGER,
-    started_at TEXT NOT NULL,
-    finished_at TEXT,
-    duration_ms INTEGER,
-    stdout_tail TEXT,
-    stderr_tail TEXT,
-    error_msg TEXT,
-    trigger_type TEXT NOT NULL DEFAULT 'schedule',
-    llm_analysis TEXT,
-    llm_tokens_used INTEGER,
-    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Migrations live in migrations/ as NNNN_name.up.sql and NNNN_name.down.sql.
+// They are applied in version order, each in its own transaction, and
+// recorded in schema_migrations. Add new schema changes as a new file pair;
+// never edit a migration that has been released.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+const schemaMigrationsSQL = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TEXT NOT NULL
 );
-CREATE INDEX IF NOT EXISTS idx_runs_job_name ON runs(job_name);
-CREATE INDEX IF NOT EXISTS idx_runs_started_at ON runs(started_at);
 `
 
-// RunMigrations applies the database schema migrations.
+// Migration is one versioned schema change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	// Down reverts Up; empty if the migration cannot be rolled back.
+	Down string
+}
+
+// MigrationState is a migration and whether it has been applied.
+type MigrationState struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// legacyColumns were added to runs before migrations were versioned. A
+// database created back then has no schema_migrations table; it is brought up
+// to the shape of migration 1 before that migration is recorded.
+var legacyColumns = []struct {
+	table, column, definition string
+}{
+	{"runs", "jobs_commit", "TEXT"},
+	{"runs", "scheduled_at", "TEXT"},
+	{"runs", "drift_ms", "INTEGER"},
+	{"runs", "job_version", "TEXT"},
+	{"runs", "pinned", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+// Migrations returns the embedded migrations in version order.
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		m := migrationFileName.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", e.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		data, err := migrationFiles.ReadFile("migrations/" + e.Name())
+		if err != nil {
+			return nil, err
+		}
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(data)
+		} else {
+			mig.Down = string(data)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, k int) bool { return out[i].Version < out[k].Version })
+	return out, nil
+}
+
+// RunMigrations applies all pending migrations.
 func RunMigrations(db *sql.DB) error {
-	_, err := db.Exec(migrationSQL)
+	_, err := MigrateUp(db, 0)
 	return err
 }
+
+// MigrateUp applies pending migrations up to and including version target
+// (0 means all) and returns the ones it applied.
+func MigrateUp(db *sql.DB, target int) ([]Migration, error) {
+	migrations, applied, err := loadMigrationState(db)
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) == 0 {
+		if err := adoptLegacySchema(db); err != nil {
+			return nil, err
+		}
+	}
+
+	var done []Migration
+	for _, m := range migrations {
+		if target > 0 && m.Version > target {
+			break
+		}
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		err := inTx(db, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(m.Up); err != nil {
+				return err
+			}
+			_, err := tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+				m.Version, m.Name, formatTime(time.Now()))
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// MigrateDown reverts applied migrations newer than version target, newest
+// first, and returns the ones it reverted.
+func MigrateDown(db *sql.DB, target int) ([]Migration, error) {
+	migrations, applied, err := loadMigrationState(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version <= target {
+			break
+		}
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if m.Down == "" {
+			return done, fmt.Errorf("migration %d_%s cannot be reverted: no down file", m.Version, m.Name)
+		}
+		err := inTx(db, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(m.Down); err != nil {
+				return err
+			}
+			_, err := tx.Exec("DELETE FROM schema_migrations WHERE version = ?", m.Version)
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("revert migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// checkMigrated fails unless every known migration is applied, for
+// databases that cannot be migrated because they are opened read-only.
+func checkMigrated(db *sql.DB) error {
+	states, err := MigrationStatus(db)
+	if err != nil {
+		return err
+	}
+	for _, s := range states {
+		if !s.Applied {
+			return fmt.Errorf("database lacks migration %d_%s; run cronbat migrate up or start the daemon first", s.Version, s.Name)
+		}
+	}
+	return nil
+}
+
+// MigrationStatus lists every known migration and whether it is applied.
+func MigrationStatus(db *sql.DB) ([]MigrationState, error) {
+	migrations, applied, err := loadMigrationState(db)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		s := MigrationState{Version: m.Version, Name: m.Name}
+		if at, ok := applied[m.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = &at
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// loadMigrationState returns the known migrations and the applied versions
+// with their times. It fails if the database has a migration this binary
+// does not know, which means it was written by a newer cronbat.
+func loadMigrationState(db *sql.DB) ([]Migration, map[int]time.Time, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := db.Exec(schemaMigrationsSQL); err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at string
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, nil, err
+		}
+		t, err := parseTime(at)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse applied_at of migration %d: %w", version, err)
+		}
+		applied[version] = t
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	known := make(map[int]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = true
+	}
+	for v := range applied {
+		if !known[v] {
+			return nil, nil, fmt.Errorf("database has migration %d, which this version of cronbat does not know; upgrade cronbat", v)
+		}
+	}
+	return migrations, applied, nil
+}
+
+// adoptLegacySchema adds legacyColumns to a database created before
+// migrations were versioned. It does nothing on a new database.
+func adoptLegacySchema(db *sql.DB) error {
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'runs'").Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+	for _, c := range legacyColumns {
+		if err := addColumnIfMissing(db, c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func inTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func addColumnIfMissing(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query("PRAGMA table_info(" + table + ")")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("add column %s.%s: %w", table, column, err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrateUpDown(t *testing.T) {
+	t.Parallel()
+
+	db, err := OpenDB(filepath.Join(t.TempDir(), "cronbat.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("RunMigrations: %v", err)
+	}
+	states, err := MigrationStatus(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range states {
+		if !s.Applied || s.AppliedAt == nil {
+			t.Fatalf("expected migration %d to be applied: %+v", s.Version, s)
+		}
+	}
+
+	reverted, err := MigrateDown(db, 0)
+	if err != nil || len(reverted) != len(states) {
+		t.Fatalf("MigrateDown: %d reverted, %v", len(reverted), err)
+	}
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'runs'").Scan(&n); err != nil || n != 0 {
+		t.Fatalf("expected runs table to be dropped, count=%d err=%v", n, err)
+	}
+
+	// A database written by a newer cronbat is refused.
+	if _, err := db.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (9999, 'future', '2026-01-01T00:00:00Z')"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunMigrations(db); err == nil || !strings.Contains(err.Error(), "upgrade cronbat") {
+		t.Fatalf("expected unknown migration error, got %v", err)
+	}
+}
+
+func TestMigrateAdoptsLegacySchema(t *testing.T) {
+	t.Parallel()
+
+	db, err := OpenDB(filepath.Join(t.TempDir(), "cronbat.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// The runs table as created before versioned migrations.
+	if _, err := db.Exec(`CREATE TABLE runs (
+		id TEXT PRIMARY KEY, job_name TEXT NOT NULL, status TEXT NOT NULL,
+		exit_code INTEGER, started_at TEXT NOT NULL, finished_at TEXT,
+		duration_ms INTEGER, stdout_tail TEXT, stderr_tail TEXT, error_msg TEXT,
+		trigger_type TEXT NOT NULL DEFAULT 'schedule', llm_analysis TEXT,
+		llm_tokens_used INTEGER, created_at TEXT NOT NULL DEFAULT ''
+	)`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("RunMigrations: %v", err)
+	}
+	if _, err := db.Exec("SELECT jobs_commit, scheduled_at, drift_ms, job_version, pinned FROM runs"); err != nil {
+		t.Fatalf("expected legacy columns to be added: %v", err)
+	}
+	states, err := MigrationStatus(db)
+	if err != nil || len(states) == 0 || !states[0].Applied {
+		t.Fatalf("expected baseline migration to be recorded: %+v, %v", states, err)
+	}
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS job_last_good;
+DROP TABLE IF EXISTS backfill_windows;
+DROP TABLE IF EXISTS backfills;
+DROP TABLE IF EXISTS runs;
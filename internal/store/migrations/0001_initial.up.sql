@@ -0,0 +1,56 @@
+CREATE TABLE IF NOT EXISTS runs (
+    id TEXT PRIMARY KEY,
+    job_name TEXT NOT NULL,
+    status TEXT NOT NULL,
+    exit_code INTEGER,
+    started_at TEXT NOT NULL,
+    finished_at TEXT,
+    duration_ms INTEGER,
+    stdout_tail TEXT,
+    stderr_tail TEXT,
+    error_msg TEXT,
+    trigger_type TEXT NOT NULL DEFAULT 'schedule',
+    llm_analysis TEXT,
+    llm_tokens_used INTEGER,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+    jobs_commit TEXT,
+    scheduled_at TEXT,
+    drift_ms INTEGER,
+    job_version TEXT,
+    pinned INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_runs_job_name ON runs(job_name);
+CREATE INDEX IF NOT EXISTS idx_runs_started_at ON runs(started_at);
+CREATE INDEX IF NOT EXISTS idx_runs_jobs_commit ON runs(jobs_commit);
+
+CREATE TABLE IF NOT EXISTS backfills (
+    id TEXT PRIMARY KEY,
+    job_name TEXT NOT NULL,
+    range_from TEXT NOT NULL,
+    range_to TEXT NOT NULL,
+    interval TEXT NOT NULL,
+    parallelism INTEGER NOT NULL,
+    status TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    finished_at TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_backfills_job_name ON backfills(job_name);
+
+CREATE TABLE IF NOT EXISTS backfill_windows (
+    backfill_id TEXT NOT NULL,
+    seq INTEGER NOT NULL,
+    window_start TEXT NOT NULL,
+    window_end TEXT NOT NULL,
+    status TEXT NOT NULL,
+    run_id TEXT,
+    PRIMARY KEY (backfill_id, seq)
+);
+
+CREATE TABLE IF NOT EXISTS job_last_good (
+    job_name TEXT PRIMARY KEY,
+    version TEXT NOT NULL,
+    definition TEXT NOT NULL,
+    run_id TEXT NOT NULL,
+    recorded_at TEXT NOT NULL,
+    pinned INTEGER NOT NULL DEFAULT 0
+);
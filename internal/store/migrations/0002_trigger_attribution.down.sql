@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS audit_log;
+ALTER TABLE runs DROP COLUMN triggered_by;
@@ -0,0 +1,11 @@
+ALTER TABLE runs ADD COLUMN triggered_by TEXT;
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    at TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    action TEXT NOT NULL,
+    job_name TEXT,
+    detail TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_job_name ON audit_log(job_name);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_runs_logs_pinned;
+ALTER TABLE runs DROP COLUMN logs_pinned;
@@ -0,0 +1,3 @@
+ALTER TABLE runs ADD COLUMN logs_pinned INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_runs_logs_pinned ON runs(logs_pinned) WHERE logs_pinned = 1;
@@ -0,0 +1,2 @@
+ALTER TABLE runs DROP COLUMN stderr_sha256;
+ALTER TABLE runs DROP COLUMN stdout_sha256;
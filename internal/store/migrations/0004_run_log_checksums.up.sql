@@ -0,0 +1,2 @@
+ALTER TABLE runs ADD COLUMN stdout_sha256 TEXT;
+ALTER TABLE runs ADD COLUMN stderr_sha256 TEXT;
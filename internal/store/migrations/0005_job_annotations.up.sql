@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS job_annotations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    job_name TEXT NOT NULL,
+    at TEXT NOT NULL,
+    author TEXT NOT NULL,
+    note TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_job_annotations_job_name ON job_annotations(job_name);
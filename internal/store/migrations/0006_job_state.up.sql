@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS job_state (
+    job_name TEXT PRIMARY KEY,
+    state TEXT NOT NULL,
+    snooze_until TEXT,
+    disabled_reason TEXT,
+    updated_at TEXT NOT NULL
+);
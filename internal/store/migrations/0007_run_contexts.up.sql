@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS run_contexts (
+    run_id TEXT PRIMARY KEY,
+    context TEXT NOT NULL
+);
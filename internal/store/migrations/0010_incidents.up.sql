@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS incidents (
+    job_name TEXT NOT NULL,
+    target TEXT NOT NULL,
+    dedup_key TEXT NOT NULL,
+    run_id TEXT NOT NULL,
+    opened_at TEXT NOT NULL,
+    PRIMARY KEY (job_name, target)
+);
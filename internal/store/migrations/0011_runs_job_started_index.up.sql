@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_runs_job_started ON runs(job_name, started_at);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS scheduler_state (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    paused INTEGER NOT NULL DEFAULT 0,
+    reason TEXT,
+    updated_by TEXT,
+    updated_at TEXT NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE runs DROP COLUMN last_heartbeat;
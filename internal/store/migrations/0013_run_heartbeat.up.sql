@@ -0,0 +1 @@
+ALTER TABLE runs ADD COLUMN last_heartbeat TEXT;
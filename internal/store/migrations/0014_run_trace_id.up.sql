@@ -0,0 +1 @@
+ALTER TABLE runs ADD COLUMN trace_id TEXT;
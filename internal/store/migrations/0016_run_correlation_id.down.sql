@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_runs_correlation_id;
+ALTER TABLE runs DROP COLUMN correlation_id;
@@ -0,0 +1,2 @@
+ALTER TABLE runs ADD COLUMN correlation_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_runs_correlation_id ON runs(correlation_id);
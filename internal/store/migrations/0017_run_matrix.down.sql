@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_runs_parent_run_id;
+ALTER TABLE runs DROP COLUMN matrix;
+ALTER TABLE runs DROP COLUMN parent_run_id;
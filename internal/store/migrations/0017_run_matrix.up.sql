@@ -0,0 +1,3 @@
+ALTER TABLE runs ADD COLUMN parent_run_id TEXT;
+ALTER TABLE runs ADD COLUMN matrix TEXT;
+CREATE INDEX IF NOT EXISTS idx_runs_parent_run_id ON runs(parent_run_id);
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+// RunContext is what a run actually executed, recorded when it starts so
+// the run can be reproduced after the job definition changes. Env is the
+// full process environment with secret-looking values redacted, and
+// Definition is the job as YAML.
+type RunContext struct {
+	RunID          string
+	Command        string
+	Argv           []string
+	Shell          string
+	LoginShell     bool
+	WorkingDir     string
+	Executor       string
+	User           string
+	Group          string
+	Timeout        string
+	Env            map[string]string
+	Hostname       string
+	CronbatVersion string
+	JobVersion     string
+	Definition     string
+}
+
+// SaveRunContext records the context of a run.
+func (s *SQLiteStore) SaveRunContext(ctx context.Context, rc *RunContext) error {
+	data, err := json.Marshal(rc)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx,
+		"INSERT OR REPLACE INTO run_contexts (run_id, context) VALUES (?, ?)", rc.RunID, string(data))
+	return err
+}
+
+// GetRunContext returns the recorded context of a run, or nil if none was
+// recorded.
+func (s *SQLiteStore) GetRunContext(ctx context.Context, runID string) (*RunContext, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, "SELECT context FROM run_contexts WHERE run_id = ?", runID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rc RunContext
+	if err := json.Unmarshal([]byte(data), &rc); err != nil {
+		return nil, err
+	}
+	return &rc, nil
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// SchedulerState is the global scheduler switch, kept across restarts.
+// While Paused, scheduled runs do not fire; manual runs still do.
+type SchedulerState struct {
+	Paused    bool
+	Reason    string
+	UpdatedBy string
+	UpdatedAt time.Time
+}
+
+// SaveSchedulerState replaces the scheduler state.
+func (s *SQLiteStore) SaveSchedulerState(ctx context.Context, ss *SchedulerState) error {
+	if ss.UpdatedAt.IsZero() {
+		ss.UpdatedAt = time.Now().UTC()
+	}
+	_, err := s.db.ExecContext(ctx,
+		"INSERT OR REPLACE INTO scheduler_state (id, paused, reason, updated_by, updated_at) VALUES (1, ?, ?, ?, ?)",
+		ss.Paused, nullString(ss.Reason), nullString(ss.UpdatedBy), formatTime(ss.UpdatedAt))
+	return err
+}
+
+// GetSchedulerState returns the saved scheduler state; a scheduler that was
+// never paused is running.
+func (s *SQLiteStore) GetSchedulerState(ctx context.Context) (*SchedulerState, error) {
+	var ss SchedulerState
+	var reason, by sql.NullString
+	var updated string
+	err := s.db.QueryRowContext(ctx,
+		"SELECT paused, reason, updated_by, updated_at FROM scheduler_state WHERE id = 1").
+		Scan(&ss.Paused, &reason, &by, &updated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &ss, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ss.Reason, ss.UpdatedBy = reason.String, by.String
+	if ss.UpdatedAt, err = parseTime(updated); err != nil {
+		return nil, err
+	}
+	return &ss, nil
+}
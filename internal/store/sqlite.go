@@ -4,11 +4,18 @@ import (
 	"context"
 	"crypto/rand"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/oklog/ulid/v2"
-	_ "modernc.org/sqlite"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
 )
 
 // NewRunID generates a new ULID-based run identifier.
@@ -16,35 +23,182 @@ func NewRunID() string {
 	return ulid.MustNew(ulid.Timestamp(time.Now()), rand.Reader).String()
 }
 
+// NewPrefixedRunID generates a run identifier that starts with prefix and
+// a dash, or a plain one when prefix is empty.
+func NewPrefixedRunID(prefix string) string {
+	if prefix == "" {
+		return NewRunID()
+	}
+	return prefix + "-" + NewRunID()
+}
+
 // SQLiteStore implements RunStore backed by SQLite.
 type SQLiteStore struct {
-	db *sql.DB
+	db   *sql.DB
+	path string
+	// buf queues run writes when StartWriteBuffer was called.
+	buf *writeBuffer
+	// busyRetries is how often a run write that fails with a locked
+	// database is retried.
+	busyRetries int
+}
+
+const (
+	// DefaultBusyTimeout is how long a statement waits for a lock held by
+	// another connection or process before failing.
+	DefaultBusyTimeout = 5 * time.Second
+	// DefaultBusyRetries is how often run writes are retried when the
+	// database is still locked after the busy timeout.
+	DefaultBusyRetries = 3
+)
+
+// Options tunes how the database is opened. Zero fields use the defaults.
+type Options struct {
+	BusyTimeout time.Duration
+	// MaxOpenConns caps the connection pool; zero means no limit.
+	MaxOpenConns int
+	BusyRetries  int
+	// ReadOnly opens the database for reading only, for a replica serving
+	// another process's data. Migrations are not applied; the database must
+	// already be at this version's schema.
+	ReadOnly bool
 }
 
-// NewSQLiteStore opens the SQLite database at dbPath and runs migrations.
+// NewSQLiteStore opens the SQLite database at dbPath with default options
+// and runs migrations.
 func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
-	db, err := sql.Open("sqlite", dbPath)
+	return OpenSQLiteStore(dbPath, Options{})
+}
+
+// OpenSQLiteStore opens the SQLite database at dbPath and runs migrations.
+func OpenSQLiteStore(dbPath string, opts Options) (*SQLiteStore, error) {
+	db, err := OpenDBWithOptions(dbPath, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.ReadOnly {
+		err = checkMigrated(db)
+	} else if err = RunMigrations(db); err != nil {
+		err = fmt.Errorf("run migrations: %w", err)
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	retries := opts.BusyRetries
+	if retries <= 0 {
+		retries = DefaultBusyRetries
+	}
+	return &SQLiteStore{db: db, path: dbPath, busyRetries: retries}, nil
+}
+
+// OpenDB opens the SQLite database at dbPath with default options, without
+// running migrations.
+func OpenDB(dbPath string) (*sql.DB, error) {
+	return OpenDBWithOptions(dbPath, Options{})
+}
+
+// OpenDBWithOptions opens the SQLite database at dbPath without running
+// migrations. Every pooled connection gets the busy timeout, and
+// transactions take the write lock when they begin, so a transaction
+// never fails halfway when another writer got in first.
+func OpenDBWithOptions(dbPath string, opts Options) (*sql.DB, error) {
+	busyTimeout := opts.BusyTimeout
+	if busyTimeout <= 0 {
+		busyTimeout = DefaultBusyTimeout
+	}
+	dsn := fmt.Sprintf("%s?_pragma=busy_timeout(%d)&_txlock=immediate", dbPath, busyTimeout.Milliseconds())
+	if opts.ReadOnly {
+		// SQLite reports a missing file as a bare "unable to open".
+		if _, err := os.Stat(dbPath); err != nil {
+			return nil, fmt.Errorf("open sqlite: %w", err)
+		}
+		abs, err := filepath.Abs(dbPath)
+		if err != nil {
+			return nil, err
+		}
+		u := url.URL{Scheme: "file", Path: abs, RawQuery: fmt.Sprintf("mode=ro&_pragma=busy_timeout(%d)", busyTimeout.Milliseconds())}
+		dsn = u.String()
+	}
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("open sqlite: %w", err)
 	}
+	if opts.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+		db.SetMaxIdleConns(opts.MaxOpenConns)
+	}
+	if opts.ReadOnly {
+		// The journal mode belongs to the writer; a read-only connection
+		// cannot change it.
+		if err := db.Ping(); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("open sqlite: %w", err)
+		}
+		return db, nil
+	}
 
 	// Enable WAL mode for better concurrent read performance.
 	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("set WAL mode: %w", err)
 	}
+	return db, nil
+}
 
-	if err := RunMigrations(db); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("run migrations: %w", err)
+// Checkpoint copies the WAL into the database file and truncates it, so the
+// WAL does not grow while readers keep it busy. A checkpoint that readers
+// block is left for the next call.
+func (s *SQLiteStore) Checkpoint(ctx context.Context) error {
+	var busy, logFrames, checkpointed int
+	return s.db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &logFrames, &checkpointed)
+}
+
+// IsBusy reports whether err is SQLite failing because the database is
+// locked by another connection or process.
+func IsBusy(err error) bool {
+	var se *sqlite.Error
+	if !errors.As(err, &se) {
+		return false
+	}
+	switch se.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+		return true
 	}
+	return false
+}
 
-	return &SQLiteStore{db: db}, nil
+// retryBusy runs write, retrying with backoff while it fails because the
+// database is locked.
+func (s *SQLiteStore) retryBusy(ctx context.Context, write func() error) error {
+	delay := 100 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		err := write()
+		if err == nil || attempt >= s.busyRetries || !IsBusy(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
 }
 
-// Close closes the underlying database connection.
+// Close flushes buffered writes and closes the underlying database
+// connection.
 func (s *SQLiteStore) Close() error {
-	return s.db.Close()
+	var flushErr error
+	if s.buf != nil {
+		flushErr = s.buf.close(s)
+	}
+	if err := s.db.Close(); err != nil {
+		return err
+	}
+	return flushErr
 }
 
 // DB returns the underlying *sql.DB for use by other packages.
@@ -87,6 +241,32 @@ func nullString(s string) sql.NullString {
 	return sql.NullString{String: s, Valid: true}
 }
 
+// nullOutputs encodes run outputs, or matrix values, as a JSON object, or
+// NULL when there are none.
+func nullOutputs(outputs map[string]string) sql.NullString {
+	if len(outputs) == 0 {
+		return sql.NullString{}
+	}
+	data, err := json.Marshal(outputs)
+	if err != nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(data), Valid: true}
+}
+
+// nullTriggerContext encodes a trigger context as JSON, or NULL when
+// there is none.
+func nullTriggerContext(tc *TriggerContext) sql.NullString {
+	if tc == nil {
+		return sql.NullString{}
+	}
+	data, err := json.Marshal(tc)
+	if err != nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(data), Valid: true}
+}
+
 func nullInt64(v int) sql.NullInt64 {
 	if v == 0 {
 		return sql.NullInt64{}
@@ -94,21 +274,139 @@ func nullInt64(v int) sql.NullInt64 {
 	return sql.NullInt64{Int64: int64(v), Valid: true}
 }
 
-// RecordRun inserts or updates a run record.
+// RecordRun inserts or updates a run record. With a write buffer (see
+// StartWriteBuffer) the write is queued and committed by the next flush.
 func (s *SQLiteStore) RecordRun(ctx context.Context, run *Run) error {
+	prepareRun(run)
+	if s.buf != nil {
+		if full := s.buf.add(run); full {
+			return s.Flush(ctx)
+		}
+		return nil
+	}
+	return s.retryBusy(ctx, func() error { return recordRun(ctx, s.db, run) })
+}
+
+// UpdateRunTails stores the output tails of a run still in progress, so
+// partial output is visible and survives a daemon crash. It leaves
+// finished runs alone.
+func (s *SQLiteStore) UpdateRunTails(ctx context.Context, id, stdout, stderr string) error {
+	if err := s.Flush(ctx); err != nil {
+		return err
+	}
+	return s.retryBusy(ctx, func() error {
+		_, err := s.db.ExecContext(ctx,
+			"UPDATE runs SET stdout_tail = ?, stderr_tail = ? WHERE id = ? AND status = 'running'",
+			nullString(stdout), nullString(stderr), id)
+		return err
+	})
+}
+
+// Heartbeat records that a running run is still alive.
+func (s *SQLiteStore) Heartbeat(ctx context.Context, id string, at time.Time) error {
+	if err := s.Flush(ctx); err != nil {
+		return err
+	}
+	return s.retryBusy(ctx, func() error {
+		_, err := s.db.ExecContext(ctx,
+			"UPDATE runs SET last_heartbeat = ? WHERE id = ? AND status = 'running'",
+			formatTime(at), id)
+		return err
+	})
+}
+
+// RecordRuns inserts or updates several run records in one transaction.
+func (s *SQLiteStore) RecordRuns(ctx context.Context, runs []*Run) error {
+	for _, run := range runs {
+		prepareRun(run)
+	}
+	return s.recordRunsTx(ctx, runs)
+}
+
+// ReportedRunTrigger is the trigger of runs executed outside the daemon by
+// "cronbat wrap" and reported through RecordReportedRun.
+const ReportedRunTrigger = "cron"
+
+// ErrRunConflict is returned by RecordReportedRun when the run ID belongs
+// to a run that the report may not overwrite.
+var ErrRunConflict = errors.New("run id belongs to another run")
+
+// RecordReportedRun records a run reported from outside the daemon. A new
+// ID is inserted; an existing run is updated only when it was itself
+// reported (trigger "cron") for the same job, so a report cannot rewrite
+// runs the daemon recorded. Otherwise it returns ErrRunConflict.
+func (s *SQLiteStore) RecordReportedRun(ctx context.Context, run *Run) error {
+	prepareRun(run)
+	if err := s.Flush(ctx); err != nil {
+		return err
+	}
+	return s.retryBusy(ctx, func() error {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		var jobName, trigger string
+		err = tx.QueryRowContext(ctx, "SELECT job_name, trigger_type FROM runs WHERE id = ?", run.ID).Scan(&jobName, &trigger)
+		switch {
+		case err == sql.ErrNoRows:
+		case err != nil:
+			return err
+		case jobName != run.JobName || trigger != ReportedRunTrigger:
+			return ErrRunConflict
+		}
+		if err := recordRun(ctx, tx, run); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+func (s *SQLiteStore) recordRunsTx(ctx context.Context, runs []*Run) error {
+	if len(runs) == 0 {
+		return nil
+	}
+	return s.retryBusy(ctx, func() error { return s.recordRunsOnce(ctx, runs) })
+}
+
+func (s *SQLiteStore) recordRunsOnce(ctx context.Context, runs []*Run) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, run := range runs {
+		if err := recordRun(ctx, tx, run); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("record run %s: %w", run.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func prepareRun(run *Run) {
 	if run.ID == "" {
 		run.ID = NewRunID()
 	}
 	if run.CreatedAt.IsZero() {
 		run.CreatedAt = time.Now().UTC()
 	}
+}
 
-	_, err := s.db.ExecContext(ctx, `
+// execer is satisfied by *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func recordRun(ctx context.Context, db execer, run *Run) error {
+	_, err := db.ExecContext(ctx, `
 		INSERT INTO runs (
 			id, job_name, status, exit_code, started_at, finished_at,
 			duration_ms, stdout_tail, stderr_tail, error_msg, trigger_type,
-			llm_analysis, llm_tokens_used, created_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			llm_analysis, llm_tokens_used, jobs_commit, scheduled_at, drift_ms,
+			job_version, pinned, triggered_by, stdout_sha256, stderr_sha256,
+			host, outputs, trace_id, trigger_context, correlation_id, parent_run_id,
+			matrix, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			status = excluded.status,
 			exit_code = excluded.exit_code,
@@ -118,7 +416,17 @@ func (s *SQLiteStore) RecordRun(ctx context.Context, run *Run) error {
 			stderr_tail = excluded.stderr_tail,
 			error_msg = excluded.error_msg,
 			llm_analysis = excluded.llm_analysis,
-			llm_tokens_used = excluded.llm_tokens_used`,
+			llm_tokens_used = excluded.llm_tokens_used,
+			stdout_sha256 = excluded.stdout_sha256,
+			stderr_sha256 = excluded.stderr_sha256,
+			host = COALESCE(excluded.host, runs.host),
+			outputs = COALESCE(excluded.outputs, runs.outputs),
+			trace_id = COALESCE(excluded.trace_id, runs.trace_id),
+			trigger_context = COALESCE(excluded.trigger_context, runs.trigger_context),
+			correlation_id = COALESCE(excluded.correlation_id, runs.correlation_id),
+			parent_run_id = COALESCE(excluded.parent_run_id, runs.parent_run_id),
+			matrix = COALESCE(excluded.matrix, runs.matrix),
+			jobs_commit = COALESCE(excluded.jobs_commit, runs.jobs_commit)`,
 		run.ID,
 		run.JobName,
 		run.Status,
@@ -132,6 +440,21 @@ func (s *SQLiteStore) RecordRun(ctx context.Context, run *Run) error {
 		run.Trigger,
 		nullString(run.LLMAnalysis),
 		nullInt64(run.LLMTokensUsed),
+		nullString(run.JobsCommit),
+		formatTimePtr(run.ScheduledAt),
+		scheduledDrift(run),
+		nullString(run.JobVersion),
+		run.Pinned,
+		nullString(run.TriggeredBy),
+		nullString(run.StdoutSHA256),
+		nullString(run.StderrSHA256),
+		nullString(run.Host),
+		nullOutputs(run.Outputs),
+		nullString(run.TraceID),
+		nullTriggerContext(run.TriggerContext),
+		nullString(run.CorrelationID),
+		nullString(run.ParentRunID),
+		nullOutputs(run.Matrix),
 		formatTime(run.CreatedAt),
 	)
 	return err
@@ -140,8 +463,8 @@ func (s *SQLiteStore) RecordRun(ctx context.Context, run *Run) error {
 func (s *SQLiteStore) scanRun(row interface{ Scan(...any) error }) (*Run, error) {
 	var r Run
 	var startedAt, createdAt string
-	var finishedAt, stdoutTail, stderrTail, errorMsg, llmAnalysis sql.NullString
-	var exitCode, durationMs, llmTokensUsed sql.NullInt64
+	var finishedAt, stdoutTail, stderrTail, errorMsg, llmAnalysis, jobsCommit, scheduledAt, jobVersion, triggeredBy, stdoutSHA256, stderrSHA256, host, outputs, traceID, triggerContext, correlationID, parentRunID, matrix, lastHeartbeat sql.NullString
+	var exitCode, durationMs, llmTokensUsed, driftMs sql.NullInt64
 
 	err := row.Scan(
 		&r.ID,
@@ -157,6 +480,23 @@ func (s *SQLiteStore) scanRun(row interface{ Scan(...any) error }) (*Run, error)
 		&r.Trigger,
 		&llmAnalysis,
 		&llmTokensUsed,
+		&jobsCommit,
+		&scheduledAt,
+		&driftMs,
+		&jobVersion,
+		&r.Pinned,
+		&triggeredBy,
+		&r.LogsPinned,
+		&stdoutSHA256,
+		&stderrSHA256,
+		&host,
+		&outputs,
+		&traceID,
+		&triggerContext,
+		&correlationID,
+		&parentRunID,
+		&matrix,
+		&lastHeartbeat,
 		&createdAt,
 	)
 	if err != nil {
@@ -175,6 +515,14 @@ func (s *SQLiteStore) scanRun(row interface{ Scan(...any) error }) (*Run, error)
 	if err != nil {
 		return nil, fmt.Errorf("parse finished_at: %w", err)
 	}
+	r.ScheduledAt, err = parseTimePtr(scheduledAt)
+	if err != nil {
+		return nil, fmt.Errorf("parse scheduled_at: %w", err)
+	}
+	r.LastHeartbeat, err = parseTimePtr(lastHeartbeat)
+	if err != nil {
+		return nil, fmt.Errorf("parse last_heartbeat: %w", err)
+	}
 
 	if exitCode.Valid {
 		r.ExitCode = int(exitCode.Int64)
@@ -197,16 +545,53 @@ func (s *SQLiteStore) scanRun(row interface{ Scan(...any) error }) (*Run, error)
 	if llmTokensUsed.Valid {
 		r.LLMTokensUsed = int(llmTokensUsed.Int64)
 	}
+	if jobsCommit.Valid {
+		r.JobsCommit = jobsCommit.String
+	}
+	if driftMs.Valid {
+		r.DriftMs = driftMs.Int64
+	}
+	if jobVersion.Valid {
+		r.JobVersion = jobVersion.String
+	}
+	r.TriggeredBy = triggeredBy.String
+	r.StdoutSHA256 = stdoutSHA256.String
+	r.StderrSHA256 = stderrSHA256.String
+	r.Host = host.String
+	r.TraceID = traceID.String
+	r.CorrelationID = correlationID.String
+	r.ParentRunID = parentRunID.String
+	if outputs.Valid {
+		if err := json.Unmarshal([]byte(outputs.String), &r.Outputs); err != nil {
+			return nil, fmt.Errorf("parse outputs: %w", err)
+		}
+	}
+	if matrix.Valid {
+		if err := json.Unmarshal([]byte(matrix.String), &r.Matrix); err != nil {
+			return nil, fmt.Errorf("parse matrix: %w", err)
+		}
+	}
+	if triggerContext.Valid {
+		if err := json.Unmarshal([]byte(triggerContext.String), &r.TriggerContext); err != nil {
+			return nil, fmt.Errorf("parse trigger_context: %w", err)
+		}
+	}
 
 	return &r, nil
 }
 
 const selectRunCols = `id, job_name, status, exit_code, started_at, finished_at,
 	duration_ms, stdout_tail, stderr_tail, error_msg, trigger_type,
-	llm_analysis, llm_tokens_used, created_at`
+	llm_analysis, llm_tokens_used, jobs_commit, scheduled_at, drift_ms,
+	job_version, pinned, triggered_by, logs_pinned, stdout_sha256,
+	stderr_sha256, host, outputs, trace_id, trigger_context, correlation_id,
+	parent_run_id, matrix, last_heartbeat, created_at`
 
 // GetRun retrieves a single run by ID.
 func (s *SQLiteStore) GetRun(ctx context.Context, id string) (*Run, error) {
+	if err := s.Flush(ctx); err != nil {
+		return nil, err
+	}
 	row := s.db.QueryRowContext(ctx,
 		"SELECT "+selectRunCols+" FROM runs WHERE id = ?", id)
 	run, err := s.scanRun(row)
@@ -218,13 +603,44 @@ func (s *SQLiteStore) GetRun(ctx context.Context, id string) (*Run, error) {
 
 // ListRuns returns runs matching the given options, ordered by started_at descending.
 func (s *SQLiteStore) ListRuns(ctx context.Context, opts ListOpts) ([]*Run, error) {
+	if err := s.Flush(ctx); err != nil {
+		return nil, err
+	}
 	query := "SELECT " + selectRunCols + " FROM runs"
 	var args []any
 
+	var where []string
 	if opts.JobName != "" {
-		query += " WHERE job_name = ?"
+		where = append(where, "job_name = ?")
 		args = append(args, opts.JobName)
 	}
+	if opts.JobsCommit != "" {
+		where = append(where, "jobs_commit LIKE ? || '%'")
+		args = append(args, opts.JobsCommit)
+	}
+	if opts.Host != "" {
+		where = append(where, "host = ?")
+		args = append(args, opts.Host)
+	}
+	if opts.CorrelationID != "" {
+		where = append(where, "correlation_id = ?")
+		args = append(args, opts.CorrelationID)
+	}
+	if opts.ParentRunID != "" {
+		where = append(where, "parent_run_id = ?")
+		args = append(args, opts.ParentRunID)
+	}
+	if !opts.Since.IsZero() {
+		where = append(where, "started_at >= ?")
+		args = append(args, formatTime(opts.Since))
+	}
+	if !opts.Until.IsZero() {
+		where = append(where, "started_at < ?")
+		args = append(args, formatTime(opts.Until))
+	}
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
 	query += " ORDER BY started_at DESC"
 
 	if opts.Limit > 0 {
@@ -253,18 +669,57 @@ func (s *SQLiteStore) ListRuns(ctx context.Context, opts ListOpts) ([]*Run, erro
 	return runs, rows.Err()
 }
 
-// GetJobStats returns aggregate statistics for a given job.
-func (s *SQLiteStore) GetJobStats(ctx context.Context, jobName string) (*JobStats, error) {
+// GetLatestRuns returns the most recent run of each named job that has
+// one, keyed by job name, in a single query.
+func (s *SQLiteStore) GetLatestRuns(ctx context.Context, jobNames []string) (map[string]*Run, error) {
+	out := make(map[string]*Run, len(jobNames))
+	if len(jobNames) == 0 {
+		return out, nil
+	}
+	if err := s.Flush(ctx); err != nil {
+		return nil, err
+	}
+	args := make([]any, len(jobNames))
+	for i, name := range jobNames {
+		args[i] = name
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(jobNames)), ", ")
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+selectRunCols+` FROM (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY job_name ORDER BY started_at DESC) AS rn
+			FROM runs WHERE job_name IN (`+placeholders+`)
+		) WHERE rn = 1`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		r, err := s.scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		out[r.JobName] = r
+	}
+	return out, rows.Err()
+}
+
+// GetJobStats returns aggregate statistics for a given job, with
+// statistics over each of windows.
+func (s *SQLiteStore) GetJobStats(ctx context.Context, jobName string, windows ...time.Duration) (*JobStats, error) {
+	if err := s.Flush(ctx); err != nil {
+		return nil, err
+	}
 	var stats JobStats
 	var lastRun sql.NullString
 	var avgDuration sql.NullFloat64
-	var successes, failures sql.NullInt64
+	var successes, failures, warnings sql.NullInt64
 
 	err := s.db.QueryRowContext(ctx, `
 		SELECT
 			COUNT(*) AS total_runs,
 			SUM(CASE WHEN status = 'success' THEN 1 ELSE 0 END) AS successes,
 			SUM(CASE WHEN status = 'failure' THEN 1 ELSE 0 END) AS failures,
+			SUM(CASE WHEN status = 'warning' THEN 1 ELSE 0 END) AS warnings,
 			MAX(started_at) AS last_run,
 			AVG(duration_ms) AS avg_duration_ms
 		FROM runs
@@ -272,6 +727,7 @@ func (s *SQLiteStore) GetJobStats(ctx context.Context, jobName string) (*JobStat
 		&stats.TotalRuns,
 		&successes,
 		&failures,
+		&warnings,
 		&lastRun,
 		&avgDuration,
 	)
@@ -281,6 +737,9 @@ func (s *SQLiteStore) GetJobStats(ctx context.Context, jobName string) (*JobStat
 	if failures.Valid {
 		stats.Failures = int(failures.Int64)
 	}
+	if warnings.Valid {
+		stats.Warnings = int(warnings.Int64)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -296,5 +755,268 @@ func (s *SQLiteStore) GetJobStats(ctx context.Context, jobName string) (*JobStat
 		stats.AvgDurationMs = avgDuration.Float64
 	}
 
+	now := time.Now()
+	for _, window := range windows {
+		ws, err := s.windowStats(ctx, jobName, now.Add(-window))
+		if err != nil {
+			return nil, err
+		}
+		ws.Window = window
+		stats.Windows = append(stats.Windows, *ws)
+	}
+
 	return &stats, nil
 }
+
+// windowStats computes WindowStats over a job's finished runs started at
+// or after since. Runs are ranked by duration so the percentiles are
+// picked in SQL rather than by loading every duration.
+func (s *SQLiteStore) windowStats(ctx context.Context, jobName string, since time.Time) (*WindowStats, error) {
+	var ws WindowStats
+	var p50, p95, maxDuration sql.NullInt64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*),
+			COALESCE(SUM(CASE WHEN status = 'success' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN status = 'warning' THEN 1 ELSE 0 END), 0),
+			MAX(CASE WHEN rn = MAX(1, CAST(n * 0.50 + 0.5 AS INTEGER)) THEN duration_ms END),
+			MAX(CASE WHEN rn = MAX(1, CAST(n * 0.95 + 0.5 AS INTEGER)) THEN duration_ms END),
+			MAX(duration_ms)
+		FROM (
+			SELECT
+				status,
+				duration_ms,
+				ROW_NUMBER() OVER (ORDER BY duration_ms) AS rn,
+				COUNT(*) OVER () AS n
+			FROM runs
+			WHERE job_name = ? AND started_at >= ?
+				AND finished_at IS NOT NULL AND status NOT LIKE 'skipped%'
+		)`, jobName, formatTime(since)).Scan(&ws.Runs, &ws.Successes, &ws.Warnings, &p50, &p95, &maxDuration)
+	if err != nil {
+		return nil, err
+	}
+	if ws.Runs > 0 {
+		ws.SuccessRate = float64(ws.Successes) / float64(ws.Runs)
+	}
+	ws.P50DurationMs = p50.Int64
+	ws.P95DurationMs = p95.Int64
+	ws.MaxDurationMs = maxDuration.Int64
+	return &ws, nil
+}
+
+// scheduledDrift returns the drift column value: NULL unless the run was
+// scheduled.
+func scheduledDrift(run *Run) sql.NullInt64 {
+	if run.ScheduledAt == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: run.DriftMs, Valid: true}
+}
+
+// GetDriftStats aggregates drift and start delay over scheduled runs that
+// started at or after since.
+func (s *SQLiteStore) GetDriftStats(ctx context.Context, since time.Time) (*DriftStats, error) {
+	if err := s.Flush(ctx); err != nil {
+		return nil, err
+	}
+	var stats DriftStats
+	var avgDrift, avgDelay sql.NullFloat64
+	var maxDrift, maxDelay sql.NullInt64
+
+	// started_at and scheduled_at share one format, so julianday handles both.
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*),
+			AVG(drift_ms),
+			MAX(drift_ms),
+			AVG(delay_ms),
+			MAX(delay_ms)
+		FROM (
+			SELECT
+				drift_ms,
+				CAST(ROUND((julianday(started_at) - julianday(scheduled_at)) * 86400000) AS INTEGER) AS delay_ms
+			FROM runs
+			WHERE scheduled_at IS NOT NULL AND started_at >= ?
+		)`, formatTime(since)).Scan(
+		&stats.Samples,
+		&avgDrift,
+		&maxDrift,
+		&avgDelay,
+		&maxDelay,
+	)
+	if err != nil {
+		return nil, err
+	}
+	stats.AvgDriftMs = avgDrift.Float64
+	stats.MaxDriftMs = maxDrift.Int64
+	stats.AvgStartDelayMs = avgDelay.Float64
+	stats.MaxStartDelayMs = maxDelay.Int64
+	return &stats, nil
+}
+
+// DailyRunCount is the number of runs with a given status started on one
+// UTC day.
+type DailyRunCount struct {
+	Day    string // YYYY-MM-DD
+	Status string
+	Count  int
+}
+
+// DailyRunCounts counts runs per UTC day and status for runs started at or
+// after since, ordered by day.
+func (s *SQLiteStore) DailyRunCounts(ctx context.Context, since time.Time) ([]DailyRunCount, error) {
+	if err := s.Flush(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT substr(started_at, 1, 10) AS day, status, COUNT(*)
+		FROM runs
+		WHERE started_at >= ?
+		GROUP BY day, status
+		ORDER BY day, status`, formatTime(since))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []DailyRunCount
+	for rows.Next() {
+		var c DailyRunCount
+		if err := rows.Scan(&c.Day, &c.Status, &c.Count); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// CountRunsSince counts a job's runs with the given status that started at or
+// after since.
+func (s *SQLiteStore) CountRunsSince(ctx context.Context, jobName, status string, since time.Time) (int, error) {
+	if err := s.Flush(ctx); err != nil {
+		return 0, err
+	}
+	var n int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM runs
+		WHERE job_name = ? AND status = ? AND started_at >= ?`,
+		jobName, status, formatTime(since)).Scan(&n)
+	return n, err
+}
+
+// GetJobOutcomes counts a job's successful and failed runs that started at
+// or after since, and finds its latest success. Warning runs finished as
+// expected, so they count as successes here.
+func (s *SQLiteStore) GetJobOutcomes(ctx context.Context, jobName string, since time.Time) (*JobOutcomes, error) {
+	if err := s.Flush(ctx); err != nil {
+		return nil, err
+	}
+	var out JobOutcomes
+	var successes, failures sql.NullInt64
+	var lastSuccess sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			SUM(CASE WHEN status IN ('success', 'warning') AND started_at >= ? THEN 1 ELSE 0 END),
+			SUM(CASE WHEN status = 'failure' AND started_at >= ? THEN 1 ELSE 0 END),
+			MAX(CASE WHEN status IN ('success', 'warning') THEN COALESCE(finished_at, started_at) END)
+		FROM runs
+		WHERE job_name = ?`,
+		formatTime(since), formatTime(since), jobName).Scan(&successes, &failures, &lastSuccess)
+	if err != nil {
+		return nil, err
+	}
+	out.Successes = int(successes.Int64)
+	out.Failures = int(failures.Int64)
+	if out.LastSuccess, err = parseTimePtr(lastSuccess); err != nil {
+		return nil, fmt.Errorf("parse last success: %w", err)
+	}
+	return &out, nil
+}
+
+// GetGlobalStats aggregates all runs in one pass over the runs table, plus
+// one query for the slowest jobs among runs started at or after since.
+func (s *SQLiteStore) GetGlobalStats(ctx context.Context, since time.Time, slowest int) (*GlobalStats, error) {
+	if err := s.Flush(ctx); err != nil {
+		return nil, err
+	}
+	stats := GlobalStats{StatusCounts: make(map[string]int)}
+	sinceStr := formatTime(since)
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT status, COUNT(*), SUM(CASE WHEN started_at >= ? THEN 1 ELSE 0 END)
+		FROM runs
+		GROUP BY status`, sinceStr)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var total, recent int
+		if err := rows.Scan(&status, &total, &recent); err != nil {
+			return nil, err
+		}
+		stats.StatusCounts[status] = total
+		stats.TotalRuns += total
+		stats.RecentRuns += recent
+		switch status {
+		case "failure":
+			stats.RecentFailures = recent
+		case "warning":
+			stats.RecentWarnings = recent
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if slowest <= 0 {
+		return &stats, nil
+	}
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT job_name, COUNT(*), AVG(duration_ms), MAX(duration_ms)
+		FROM runs
+		WHERE started_at >= ? AND finished_at IS NOT NULL
+		GROUP BY job_name
+		ORDER BY AVG(duration_ms) DESC
+		LIMIT ?`, sinceStr, slowest)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d JobDuration
+		if err := rows.Scan(&d.JobName, &d.Runs, &d.AvgDurationMs, &d.MaxDurationMs); err != nil {
+			return nil, err
+		}
+		stats.SlowestJobs = append(stats.SlowestJobs, d)
+	}
+	return &stats, rows.Err()
+}
+
+// RecentDurations returns the durations in milliseconds of a job's latest
+// successful runs, newest first.
+func (s *SQLiteStore) RecentDurations(ctx context.Context, jobName string, limit int) ([]int64, error) {
+	if err := s.Flush(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT duration_ms FROM runs
+		WHERE job_name = ? AND status = 'success' AND duration_ms IS NOT NULL
+		ORDER BY started_at DESC
+		LIMIT ?`, jobName, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []int64
+	for rows.Next() {
+		var d int64
+		if err := rows.Scan(&d); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,393 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetLatestRuns(t *testing.T) {
+	t.Parallel()
+
+	st, err := NewSQLiteStore(filepath.Join(t.TempDir(), "cronbat.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	ctx := context.Background()
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, r := range []struct{ job, status string }{
+		{"a", "failure"}, {"b", "success"}, {"a", "success"}, {"c", "failure"},
+	} {
+		run := &Run{JobName: r.job, Status: r.status, StartedAt: start.Add(time.Duration(i) * time.Minute), Trigger: "schedule"}
+		if err := st.RecordRun(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	latest, err := st.GetLatestRuns(ctx, []string{"a", "b", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(latest) != 2 || latest["a"].Status != "success" || latest["b"].Status != "success" {
+		t.Fatalf("unexpected latest runs: %+v", latest)
+	}
+	if !latest["a"].StartedAt.Equal(start.Add(2 * time.Minute)) {
+		t.Fatalf("latest run of a started at %s", latest["a"].StartedAt)
+	}
+}
+
+func TestRunCountsByJob(t *testing.T) {
+	t.Parallel()
+
+	st, err := NewSQLiteStore(filepath.Join(t.TempDir(), "cronbat.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	ctx := context.Background()
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, job := range []string{"a", "b", "a"} {
+		run := &Run{JobName: job, Status: "success", StartedAt: start.Add(time.Duration(i) * time.Minute), Trigger: "schedule"}
+		if err := st.RecordRun(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	counts, err := st.RunCountsByJob(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 2 || counts["a"] != 2 || counts["b"] != 1 {
+		t.Fatalf("unexpected run counts: %v", counts)
+	}
+}
+
+func TestJobStatsWindows(t *testing.T) {
+	t.Parallel()
+
+	st, err := NewSQLiteStore(filepath.Join(t.TempDir(), "cronbat.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	for _, r := range []struct {
+		age      time.Duration
+		status   string
+		duration int64
+	}{
+		{time.Hour, "success", 100},
+		{2 * time.Hour, "success", 300},
+		{3 * time.Hour, "failure", 200},
+		{4 * time.Hour, "skipped:overlap", 0},
+		{5 * 24 * time.Hour, "success", 900},
+		{40 * 24 * time.Hour, "success", 5000},
+	} {
+		finished := now.Add(-r.age)
+		run := &Run{JobName: "a", Status: r.status, StartedAt: now.Add(-r.age), FinishedAt: &finished, DurationMs: r.duration, Trigger: "schedule"}
+		if err := st.RecordRun(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := st.GetJobStats(ctx, "a", 24*time.Hour, 7*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Windows) != 2 {
+		t.Fatalf("windows = %+v", stats.Windows)
+	}
+	day := stats.Windows[0]
+	if day.Window != 24*time.Hour || day.Runs != 3 || day.Successes != 2 ||
+		day.P50DurationMs != 200 || day.P95DurationMs != 300 || day.MaxDurationMs != 300 {
+		t.Fatalf("24h window = %+v", day)
+	}
+	week := stats.Windows[1]
+	if week.Runs != 4 || week.SuccessRate != 0.75 || week.P50DurationMs != 200 || week.MaxDurationMs != 900 {
+		t.Fatalf("7d window = %+v", week)
+	}
+}
+
+func TestRecordReportedRun(t *testing.T) {
+	t.Parallel()
+
+	st, err := NewSQLiteStore(filepath.Join(t.TempDir(), "cronbat.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	ctx := context.Background()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	daemonRun := &Run{ID: "daemon-1", JobName: "a", Status: "success", StartedAt: start, Trigger: "schedule"}
+	if err := st.RecordRun(ctx, daemonRun); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new ID is created, and resending it updates the report.
+	rep := &Run{ID: "wrap-1", JobName: "a", Status: "running", StartedAt: start, Trigger: ReportedRunTrigger}
+	if err := st.RecordReportedRun(ctx, rep); err != nil {
+		t.Fatal(err)
+	}
+	rep.Status = "success"
+	if err := st.RecordReportedRun(ctx, rep); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := st.GetRun(ctx, "wrap-1"); got == nil || got.Status != "success" {
+		t.Fatalf("reported run not updated: %+v", got)
+	}
+
+	// A report may not overwrite a daemon run or another job's report.
+	for _, bad := range []*Run{
+		{ID: "daemon-1", JobName: "a", Status: "failure", StartedAt: start, Trigger: ReportedRunTrigger},
+		{ID: "wrap-1", JobName: "b", Status: "failure", StartedAt: start, Trigger: ReportedRunTrigger},
+	} {
+		if err := st.RecordReportedRun(ctx, bad); !errors.Is(err, ErrRunConflict) {
+			t.Errorf("report of %s as job %s: err = %v, want ErrRunConflict", bad.ID, bad.JobName, err)
+		}
+	}
+	if got, _ := st.GetRun(ctx, "daemon-1"); got.Status != "success" || got.Trigger != "schedule" {
+		t.Fatalf("daemon run overwritten: %+v", got)
+	}
+	if got, _ := st.GetRun(ctx, "wrap-1"); got.JobName != "a" || got.Status != "success" {
+		t.Fatalf("reported run overwritten: %+v", got)
+	}
+}
+
+func TestJobOutcomesCountWarnings(t *testing.T) {
+	t.Parallel()
+
+	st, err := NewSQLiteStore(filepath.Join(t.TempDir(), "cronbat.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	ctx := context.Background()
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, status := range []string{"success", "failure", "warning"} {
+		at := start.Add(time.Duration(i) * time.Hour)
+		run := &Run{JobName: "rsync", Status: status, StartedAt: at, FinishedAt: &at, Trigger: "schedule"}
+		if err := st.RecordRun(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err := st.GetJobOutcomes(ctx, "rsync", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Successes != 2 || out.Failures != 1 {
+		t.Fatalf("successes=%d failures=%d, want 2 and 1", out.Successes, out.Failures)
+	}
+	if want := start.Add(2 * time.Hour); out.LastSuccess == nil || !out.LastSuccess.Equal(want) {
+		t.Fatalf("last success = %v, want the warning run at %s", out.LastSuccess, want)
+	}
+}
+
+func TestUpdateRunTails(t *testing.T) {
+	t.Parallel()
+
+	st, err := NewSQLiteStore(filepath.Join(t.TempDir(), "cronbat.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	ctx := context.Background()
+
+	run := &Run{ID: "r1", JobName: "a", Status: "running", StartedAt: time.Now().UTC(), Trigger: "manual"}
+	if err := st.RecordRun(ctx, run); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.UpdateRunTails(ctx, "r1", "partial", ""); err != nil {
+		t.Fatal(err)
+	}
+	got, err := st.GetRun(ctx, "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.StdoutTail != "partial" {
+		t.Fatalf("stdout tail = %q, want partial", got.StdoutTail)
+	}
+
+	// A late update must not clobber the final record.
+	run.Status, run.StdoutTail = "success", "partial\ndone"
+	if err := st.RecordRun(ctx, run); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.UpdateRunTails(ctx, "r1", "stale", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := st.GetRun(ctx, "r1"); got.StdoutTail != "partial\ndone" {
+		t.Fatalf("finished run tail overwritten: %q", got.StdoutTail)
+	}
+}
+
+func TestTriggerContext(t *testing.T) {
+	t.Parallel()
+
+	st, err := NewSQLiteStore(filepath.Join(t.TempDir(), "cronbat.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	ctx := context.Background()
+
+	tc := &TriggerContext{Headers: map[string]string{"X-Github-Event": "push"}, Body: `{"ref":"main"}`}
+	run := &Run{ID: "r1", JobName: "a", Status: "running", StartedAt: time.Now().UTC(), Trigger: "manual", TriggerContext: tc}
+	if err := st.RecordRun(ctx, run); err != nil {
+		t.Fatal(err)
+	}
+	// The final record keeps the context recorded at start.
+	run.Status, run.TriggerContext = "success", nil
+	if err := st.RecordRun(ctx, run); err != nil {
+		t.Fatal(err)
+	}
+	got, err := st.GetRun(ctx, "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.TriggerContext == nil || got.TriggerContext.Headers["X-Github-Event"] != "push" || got.TriggerContext.Body != tc.Body {
+		t.Fatalf("trigger context = %+v", got.TriggerContext)
+	}
+}
+
+func TestListRunsByCorrelationID(t *testing.T) {
+	t.Parallel()
+
+	st, err := NewSQLiteStore(filepath.Join(t.TempDir(), "cronbat.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	for i, id := range []string{"deploy-42", "", "deploy-42"} {
+		run := &Run{ID: NewPrefixedRunID("etl"), JobName: "a", Status: "running", StartedAt: now.Add(time.Duration(i) * time.Second), Trigger: "manual", CorrelationID: id}
+		if err := st.RecordRun(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+		// The final record keeps the correlation ID recorded at start.
+		run.Status, run.CorrelationID = "success", ""
+		if err := st.RecordRun(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runs, err := st.ListRuns(ctx, ListOpts{CorrelationID: "deploy-42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("got %d runs, want 2", len(runs))
+	}
+	for _, r := range runs {
+		if r.CorrelationID != "deploy-42" || !strings.HasPrefix(r.ID, "etl-") {
+			t.Fatalf("run %s has correlation ID %q", r.ID, r.CorrelationID)
+		}
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	t.Parallel()
+
+	st, err := NewSQLiteStore(filepath.Join(t.TempDir(), "cronbat.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	ctx := context.Background()
+
+	if err := st.RecordRun(ctx, &Run{ID: "r1", JobName: "a", Status: "running", StartedAt: time.Now().UTC(), Trigger: "cron"}); err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := st.Heartbeat(ctx, "r1", at); err != nil {
+		t.Fatal(err)
+	}
+	got, err := st.GetRun(ctx, "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LastHeartbeat == nil || !got.LastHeartbeat.Equal(at) {
+		t.Fatalf("last heartbeat = %v, want %s", got.LastHeartbeat, at)
+	}
+}
+
+func TestRecordRunRetriesWhileLocked(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "cronbat.db")
+	st, err := OpenSQLiteStore(path, Options{BusyTimeout: 10 * time.Millisecond, BusyRetries: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	ctx := context.Background()
+
+	other, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	tx, err := other.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("DELETE FROM runs"); err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(150*time.Millisecond, func() { tx.Rollback() })
+
+	run := &Run{JobName: "a", Status: "success", StartedAt: time.Now(), Trigger: "manual"}
+	if err := st.RecordRun(ctx, run); err != nil {
+		t.Fatalf("run write was not retried: %v", err)
+	}
+	if got, err := st.GetRun(ctx, run.ID); err != nil || got == nil {
+		t.Fatalf("run not recorded: %v", err)
+	}
+	if err := st.Checkpoint(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckWritable(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "cronbat.db")
+	st, err := OpenSQLiteStore(path, Options{BusyTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	if err := st.CheckWritable(context.Background()); err != nil {
+		t.Fatalf("fresh database not writable: %v", err)
+	}
+
+	// Another process holding the write lock makes it unwritable.
+	other, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	tx, err := other.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM runs"); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.CheckWritable(context.Background()); err == nil {
+		t.Fatal("database reported writable while another connection holds the write lock")
+	}
+}
@@ -20,14 +20,91 @@ type Run struct {
 	Trigger       string
 	LLMAnalysis   string
 	LLMTokensUsed int
+	// JobsCommit is the git HEAD of the jobs directory when the run
+	// started, if the directory is in a git repository.
+	JobsCommit string
+	// ScheduledAt is when a scheduled run was due, and DriftMs how late
+	// the scheduler fired it. Both are unset for other triggers.
+	ScheduledAt *time.Time
+	DriftMs     int64
+	// JobVersion identifies the job definition the run executed; Pinned
+	// marks runs that used the pinned last known good definition instead
+	// of the current one.
+	JobVersion string
+	Pinned     bool
+	// TriggeredBy identifies who started a manual run (API key name,
+	// client IP, user agent); empty for other triggers.
+	TriggeredBy string
+	// LogsPinned exempts the run's log files from retention cleanup.
+	LogsPinned bool
+	// StdoutSHA256 and StderrSHA256 are hex SHA-256 sums of the persisted
+	// log files, recorded when they were closed; empty if not persisted.
+	StdoutSHA256 string
+	StderrSHA256 string
+	// Host is the hostname of the machine the run was placed on.
+	Host string
+	// Outputs are the key=value pairs the run wrote to $CRONBAT_OUTPUT.
+	Outputs map[string]string
+	// TraceID is the OpenTelemetry trace of the run, when tracing is on.
+	TraceID string
+	// TriggerContext is the request or message that triggered a manual or
+	// bus run; nil for other triggers.
+	TriggerContext *TriggerContext
+	// CorrelationID is an ID the system that triggered the run supplied
+	// to find it again; empty if none was given.
+	CorrelationID string
+	// ParentRunID is the run of a matrix job that this run is an instance
+	// of, and Matrix the instance's values; both are unset for other runs.
+	ParentRunID string
+	Matrix      map[string]string
+	// LastHeartbeat is when whoever executes a running run last reported
+	// it alive; nil before the first heartbeat.
+	LastHeartbeat *time.Time
 	CreatedAt     time.Time
 }
 
+// TriggerContext records why a run was triggered from outside: the
+// headers and the start of the body of the API request, or the bus
+// message, that fired it.
+type TriggerContext struct {
+	// Subject is the bus subject a triggering message arrived on.
+	Subject string            `json:"subject,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	// BodyTruncated reports that Body holds only the first
+	// MaxTriggerBodyBytes of the body.
+	BodyTruncated bool `json:"body_truncated,omitempty"`
+}
+
+// MaxTriggerBodyBytes bounds the body kept in a TriggerContext.
+const MaxTriggerBodyBytes = 4096
+
+// SetBody keeps the first MaxTriggerBodyBytes of body.
+func (tc *TriggerContext) SetBody(body []byte) {
+	if len(body) > MaxTriggerBodyBytes {
+		body = body[:MaxTriggerBodyBytes]
+		tc.BodyTruncated = true
+	}
+	tc.Body = string(body)
+}
+
 // ListOpts controls filtering and pagination for run queries.
 type ListOpts struct {
 	JobName string
-	Limit   int
-	Offset  int
+	// JobsCommit filters by jobs directory commit; a prefix (short hash)
+	// matches.
+	JobsCommit string
+	// Host filters by the host a run was placed on.
+	Host string
+	// CorrelationID filters by the correlation ID the trigger supplied.
+	CorrelationID string
+	// ParentRunID lists the instances of a matrix run.
+	ParentRunID string
+	// Since and Until, when set, bound started_at to [Since, Until).
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+	Offset int
 }
 
 // JobStats holds aggregate statistics for a job.
@@ -35,14 +112,81 @@ type JobStats struct {
 	TotalRuns     int
 	Successes     int
 	Failures      int
+	Warnings      int
 	LastRun       *time.Time
 	AvgDurationMs float64
+	// Windows hold statistics over recent periods, one per window asked
+	// for, in the same order.
+	Windows []WindowStats
+}
+
+// WindowStats describes a job's finished runs that started within Window
+// of now. Skipped and still running runs are not counted. Percentiles use
+// the nearest-rank method, like predict.Percentile.
+type WindowStats struct {
+	Window        time.Duration
+	Runs          int
+	Successes     int
+	Warnings      int
+	SuccessRate   float64
+	P50DurationMs int64
+	P95DurationMs int64
+	MaxDurationMs int64
+}
+
+// JobOutcomes counts a job's finished runs since a point in time. Warning
+// runs count as successes.
+type JobOutcomes struct {
+	Successes int
+	Failures  int
+	// LastSuccess is the job's most recent successful or warning run at any
+	// time.
+	LastSuccess *time.Time
+}
+
+// DriftStats aggregates scheduler drift and start delay over scheduled runs.
+// Drift is how late the scheduler fired; start delay also includes time
+// spent waiting in the run queue.
+type DriftStats struct {
+	Samples         int
+	AvgDriftMs      float64
+	MaxDriftMs      int64
+	AvgStartDelayMs float64
+	MaxStartDelayMs int64
+}
+
+// GlobalStats aggregates runs across all jobs.
+type GlobalStats struct {
+	TotalRuns int
+	// StatusCounts counts all runs by status.
+	StatusCounts map[string]int
+	// RecentRuns, RecentFailures, and RecentWarnings count runs started in
+	// the window.
+	RecentRuns     int
+	RecentFailures int
+	RecentWarnings int
+	// SlowestJobs are the jobs with the highest average duration of
+	// finished runs in the window, slowest first.
+	SlowestJobs []JobDuration
+}
+
+// JobDuration is a job's average run duration.
+type JobDuration struct {
+	JobName       string
+	Runs          int
+	AvgDurationMs float64
+	MaxDurationMs int64
 }
 
 // RunStore is the interface for persisting and querying job runs.
 type RunStore interface {
 	RecordRun(ctx context.Context, run *Run) error
+	RecordRuns(ctx context.Context, runs []*Run) error
 	GetRun(ctx context.Context, id string) (*Run, error)
 	ListRuns(ctx context.Context, opts ListOpts) ([]*Run, error)
-	GetJobStats(ctx context.Context, jobName string) (*JobStats, error)
+	GetLatestRuns(ctx context.Context, jobNames []string) (map[string]*Run, error)
+	GetJobStats(ctx context.Context, jobName string, windows ...time.Duration) (*JobStats, error)
+	GetDriftStats(ctx context.Context, since time.Time) (*DriftStats, error)
+	GetGlobalStats(ctx context.Context, since time.Time, slowest int) (*GlobalStats, error)
+	RecentDurations(ctx context.Context, jobName string, limit int) ([]int64, error)
 }
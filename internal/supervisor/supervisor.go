@@ -0,0 +1,158 @@
+// Package supervisor keeps service-mode jobs running, restarting them with
+// backoff whenever they exit.
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrStopped is the cancellation cause set on a service run's context when
+// the service is stopped, replaced, or cronbat shuts down.
+var ErrStopped = errors.New("service stopped")
+
+// Triggers recorded on service runs: the first start, and every later
+// restart after the process exited.
+const (
+	TriggerStart   = "service"
+	TriggerRestart = "restart"
+)
+
+// RunFunc runs one instance of a service until it exits or ctx is
+// cancelled.
+type RunFunc func(ctx context.Context, name, trigger string)
+
+// Backoff controls the delay between restarts. The delay starts at Initial
+// and doubles after each quick exit up to Max; a run that lasted at least
+// ResetAfter starts the sequence over.
+type Backoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	ResetAfter time.Duration
+}
+
+// Status describes a supervised service.
+type Status struct {
+	Restarts    int
+	LastStarted time.Time
+}
+
+type service struct {
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+
+	mu     sync.Mutex
+	status Status
+}
+
+// Supervisor runs services until they are stopped.
+type Supervisor struct {
+	run RunFunc
+
+	mu       sync.Mutex
+	services map[string]*service
+	wg       sync.WaitGroup
+}
+
+// New creates a Supervisor that runs services with run.
+func New(run RunFunc) *Supervisor {
+	return &Supervisor{run: run, services: make(map[string]*service)}
+}
+
+// Start begins supervising name, replacing (and stopping) any instance
+// already supervised under that name. The new instance starts once the old
+// one has exited.
+func (s *Supervisor) Start(name string, b Backoff) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	svc := &service{cancel: cancel, done: make(chan struct{})}
+
+	s.mu.Lock()
+	prev := s.services[name]
+	s.services[name] = svc
+	s.wg.Add(1)
+	s.mu.Unlock()
+	if prev != nil {
+		prev.cancel(ErrStopped)
+	}
+
+	go func() {
+		defer s.wg.Done()
+		defer close(svc.done)
+		if prev != nil {
+			<-prev.done
+		}
+		s.loop(ctx, name, svc, b)
+	}()
+}
+
+func (s *Supervisor) loop(ctx context.Context, name string, svc *service, b Backoff) {
+	delay := b.Initial
+	trigger := TriggerStart
+	for ctx.Err() == nil {
+		started := time.Now()
+		svc.mu.Lock()
+		svc.status.LastStarted = started
+		if trigger == TriggerRestart {
+			svc.status.Restarts++
+		}
+		svc.mu.Unlock()
+
+		s.run(ctx, name, trigger)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) >= b.ResetAfter {
+			delay = b.Initial
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		delay *= 2
+		if delay > b.Max {
+			delay = b.Max
+		}
+		trigger = TriggerRestart
+	}
+}
+
+// Stop stops supervising name and cancels its running instance. It does
+// not wait for the instance to exit.
+func (s *Supervisor) Stop(name string) {
+	s.mu.Lock()
+	svc := s.services[name]
+	delete(s.services, name)
+	s.mu.Unlock()
+	if svc != nil {
+		svc.cancel(ErrStopped)
+	}
+}
+
+// Status reports on a supervised service.
+func (s *Supervisor) Status(name string) (Status, bool) {
+	s.mu.Lock()
+	svc := s.services[name]
+	s.mu.Unlock()
+	if svc == nil {
+		return Status{}, false
+	}
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	return svc.status, true
+}
+
+// StopAll stops every service and waits for their instances to exit.
+func (s *Supervisor) StopAll() {
+	s.mu.Lock()
+	for name, svc := range s.services {
+		svc.cancel(ErrStopped)
+		delete(s.services, name)
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
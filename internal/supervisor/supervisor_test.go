@@ -0,0 +1,67 @@
+package supervisor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRestartsUntilStopped(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var triggers []string
+	s := New(func(ctx context.Context, name, trigger string) {
+		mu.Lock()
+		triggers = append(triggers, trigger)
+		mu.Unlock()
+	})
+	s.Start("consumer", Backoff{Initial: time.Millisecond, Max: 4 * time.Millisecond, ResetAfter: time.Hour})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if st, _ := s.Status("consumer"); st.Restarts >= 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("service was not restarted")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	s.StopAll()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if triggers[0] != TriggerStart || triggers[1] != TriggerRestart {
+		t.Fatalf("unexpected triggers %v", triggers[:2])
+	}
+	if _, ok := s.Status("consumer"); ok {
+		t.Fatal("expected service to be gone after StopAll")
+	}
+}
+
+func TestStopCancelsRun(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	stopped := make(chan error, 1)
+	s := New(func(ctx context.Context, name, trigger string) {
+		close(started)
+		<-ctx.Done()
+		stopped <- context.Cause(ctx)
+	})
+	s.Start("tunnel", Backoff{Initial: time.Second, Max: time.Second})
+	<-started
+	s.Stop("tunnel")
+
+	select {
+	case err := <-stopped:
+		if err != ErrStopped {
+			t.Fatalf("expected ErrStopped cause, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("run was not cancelled")
+	}
+	s.StopAll()
+}
@@ -0,0 +1,328 @@
+// Package tracing records runs as OpenTelemetry spans and exports them to a
+// collector over OTLP/HTTP with the JSON encoding, without pulling in the
+// OpenTelemetry SDK. Ended spans are batched and posted periodically.
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+)
+
+// TraceparentEnv is the environment variable that carries a run's W3C
+// trace context to its job.
+const TraceparentEnv = "TRACEPARENT"
+
+// Timeout bounds each export.
+const Timeout = 10 * time.Second
+
+// FlushInterval is how often ended spans are exported.
+const FlushInterval = 5 * time.Second
+
+// maxPending is how many ended spans may wait for export. Spans ended while
+// it is full are dropped.
+const maxPending = 8192
+
+// Tracer starts spans and exports them once ended. A nil Tracer is valid
+// and records nothing.
+type Tracer struct {
+	url     string
+	headers map[string]string
+	service string
+	client  *http.Client
+
+	mu      sync.Mutex
+	pending []*Span
+	dropped int
+}
+
+// New checks the tracing configuration and returns its tracer.
+func New(cfg config.TracingConfig) (*Tracer, error) {
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("endpoint must be an http or https URL")
+	}
+	return &Tracer{
+		url:     strings.TrimSuffix(cfg.Endpoint, "/") + "/v1/traces",
+		headers: cfg.Headers,
+		service: cfg.ServiceName,
+		client:  &http.Client{Timeout: Timeout},
+	}, nil
+}
+
+// Start begins the root span of a new trace.
+func (t *Tracer) Start(name string, start time.Time) *Span {
+	if t == nil {
+		return nil
+	}
+	s := &Span{tracer: t, name: name, start: start}
+	rand.Read(s.traceID[:])
+	rand.Read(s.spanID[:])
+	return s
+}
+
+// Run exports ended spans every FlushInterval until ctx is done. Spans
+// still pending then are left for Flush.
+func (t *Tracer) Run(ctx context.Context) {
+	if t == nil {
+		return
+	}
+	ticker := time.NewTicker(FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := t.Flush(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("WARN: failed to export spans: %v", err)
+			}
+		}
+	}
+}
+
+// Flush exports the spans ended so far. Spans that fail to export are
+// dropped; traces are best effort.
+func (t *Tracer) Flush(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	spans, dropped := t.pending, t.dropped
+	t.pending, t.dropped = nil, 0
+	t.mu.Unlock()
+	if dropped > 0 {
+		log.Printf("WARN: dropped %d spans; the export queue was full", dropped)
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(t.encode(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("invalid endpoint")
+	}
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		// Drop the URL from the error; it may embed a token.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s exporting %d spans", resp.Status, len(spans))
+	}
+	return nil
+}
+
+func (t *Tracer) add(s *Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.pending) >= maxPending {
+		t.dropped++
+		return
+	}
+	t.pending = append(t.pending, s)
+}
+
+// Span is one timed step of a run. A nil Span is valid and records nothing,
+// so callers need not check whether tracing is on.
+type Span struct {
+	tracer  *Tracer
+	traceID [16]byte
+	spanID  [8]byte
+	parent  [8]byte
+	name    string
+	start   time.Time
+	end     time.Time
+	attrs   []attribute
+	errMsg  string
+	ended   bool
+}
+
+type attribute struct {
+	key   string
+	value any
+}
+
+// Child begins a span within s.
+func (s *Span) Child(name string, start time.Time) *Span {
+	if s == nil {
+		return nil
+	}
+	c := &Span{tracer: s.tracer, traceID: s.traceID, parent: s.spanID, name: name, start: start}
+	rand.Read(c.spanID[:])
+	return c
+}
+
+// SetAttr sets an attribute. Values are strings, ints, int64s, float64s or
+// bools; anything else is recorded as its fmt.Sprint string.
+func (s *Span) SetAttr(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.attrs = append(s.attrs, attribute{key: key, value: value})
+}
+
+// SetError marks the span as failed with msg.
+func (s *Span) SetError(msg string) {
+	if s == nil {
+		return
+	}
+	s.errMsg = msg
+}
+
+// End ends the span now.
+func (s *Span) End() {
+	s.EndAt(time.Now())
+}
+
+// EndAt ends the span at t and queues it for export. Only the first call
+// counts.
+func (s *Span) EndAt(t time.Time) {
+	if s == nil || s.ended {
+		return
+	}
+	s.ended = true
+	s.end = t
+	s.tracer.add(s)
+}
+
+// TraceID returns the hex trace ID, or "" for a nil span.
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
+
+// Traceparent returns the W3C traceparent header value that makes s the
+// parent of another process's spans, or "" for a nil span.
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	return "00-" + hex.EncodeToString(s.traceID[:]) + "-" + hex.EncodeToString(s.spanID[:]) + "-01"
+}
+
+// OTLP JSON encoding; see opentelemetry-proto's trace.proto. IDs are hex
+// and 64-bit integers are strings.
+type (
+	exportRequest struct {
+		ResourceSpans []resourceSpans `json:"resourceSpans"`
+	}
+	resourceSpans struct {
+		Resource   resource     `json:"resource"`
+		ScopeSpans []scopeSpans `json:"scopeSpans"`
+	}
+	resource struct {
+		Attributes []keyValue `json:"attributes"`
+	}
+	scopeSpans struct {
+		Scope scope      `json:"scope"`
+		Spans []spanJSON `json:"spans"`
+	}
+	scope struct {
+		Name string `json:"name"`
+	}
+	spanJSON struct {
+		TraceID           string     `json:"traceId"`
+		SpanID            string     `json:"spanId"`
+		ParentSpanID      string     `json:"parentSpanId,omitempty"`
+		Name              string     `json:"name"`
+		Kind              int        `json:"kind"`
+		StartTimeUnixNano string     `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+		Attributes        []keyValue `json:"attributes,omitempty"`
+		Status            *status    `json:"status,omitempty"`
+	}
+	status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	keyValue struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	}
+)
+
+const (
+	spanKindInternal = 1
+	statusCodeError  = 2
+)
+
+func (t *Tracer) encode(spans []*Span) exportRequest {
+	out := make([]spanJSON, len(spans))
+	for i, s := range spans {
+		js := spanJSON{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		}
+		if s.parent != [8]byte{} {
+			js.ParentSpanID = hex.EncodeToString(s.parent[:])
+		}
+		for _, a := range s.attrs {
+			js.Attributes = append(js.Attributes, keyValue{Key: a.key, Value: anyValue(a.value)})
+		}
+		if s.errMsg != "" {
+			js.Status = &status{Code: statusCodeError, Message: s.errMsg}
+		}
+		out[i] = js
+	}
+	return exportRequest{ResourceSpans: []resourceSpans{{
+		Resource: resource{Attributes: []keyValue{
+			{Key: "service.name", Value: anyValue(t.service)},
+		}},
+		ScopeSpans: []scopeSpans{{
+			Scope: scope{Name: "cronbat"},
+			Spans: out,
+		}},
+	}}}
+}
+
+func anyValue(v any) map[string]any {
+	switch v := v.(type) {
+	case string:
+		return map[string]any{"stringValue": v}
+	case int:
+		return map[string]any{"intValue": strconv.Itoa(v)}
+	case int64:
+		return map[string]any{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		return map[string]any{"doubleValue": v}
+	case bool:
+		return map[string]any{"boolValue": v}
+	default:
+		return map[string]any{"stringValue": fmt.Sprint(v)}
+	}
+}
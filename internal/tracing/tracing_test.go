@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+)
+
+func TestExport(t *testing.T) {
+	var got exportRequest
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	tr, err := New(config.TracingConfig{
+		Endpoint:    srv.URL + "/",
+		Headers:     map[string]string{"Authorization": "Bearer t"},
+		ServiceName: "cronbat-test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Unix(1700000000, 0)
+	root := tr.Start("run backup", start)
+	root.SetAttr("cronbat.job", "backup")
+	child := root.Child("process", start.Add(time.Second))
+	child.SetAttr("process.exit_code", 2)
+	child.SetError("exit status 2")
+	child.EndAt(start.Add(3 * time.Second))
+	child.EndAt(start.Add(4 * time.Second))
+	root.EndAt(start.Add(3 * time.Second))
+
+	parts := strings.Split(child.Traceparent(), "-")
+	if len(parts) != 4 || parts[0] != "00" || parts[1] != root.TraceID() || parts[3] != "01" {
+		t.Fatalf("traceparent = %s", child.Traceparent())
+	}
+
+	if err := tr.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bearer t" {
+		t.Fatalf("Authorization = %q", auth)
+	}
+	if len(got.ResourceSpans) != 1 || got.ResourceSpans[0].Resource.Attributes[0].Value["stringValue"] != "cronbat-test" {
+		t.Fatalf("unexpected resource: %+v", got.ResourceSpans)
+	}
+	spans := got.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	c, r := spans[0], spans[1]
+	if c.TraceID != r.TraceID || c.ParentSpanID != r.SpanID || c.SpanID != parts[2] || r.ParentSpanID != "" {
+		t.Fatalf("unexpected span IDs: %+v %+v", c, r)
+	}
+	if c.StartTimeUnixNano != "1700000001000000000" || c.EndTimeUnixNano != "1700000003000000000" {
+		t.Fatalf("unexpected child times: %s..%s", c.StartTimeUnixNano, c.EndTimeUnixNano)
+	}
+	if c.Status == nil || c.Status.Code != statusCodeError || c.Attributes[0].Value["intValue"] != "2" {
+		t.Fatalf("unexpected child: %+v", c)
+	}
+
+	// Nothing is left to export.
+	got = exportRequest{}
+	if err := tr.Flush(context.Background()); err != nil || got.ResourceSpans != nil {
+		t.Fatalf("second flush exported %+v, err %v", got, err)
+	}
+}
+
+func TestNilTracer(t *testing.T) {
+	var tr *Tracer
+	s := tr.Start("run", time.Now())
+	s.Child("process", time.Now()).End()
+	s.SetAttr("k", "v")
+	s.End()
+	if s.TraceID() != "" || s.Traceparent() != "" {
+		t.Fatal("nil span has trace context")
+	}
+	if err := tr.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
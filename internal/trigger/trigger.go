@@ -0,0 +1,124 @@
+// Package trigger keeps the registry of the sources that fire jobs from
+// outside the schedule, such as the message bus, so they can be listed and
+// switched off at runtime.
+package trigger
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned for an unknown trigger name.
+var ErrNotFound = errors.New("trigger not found")
+
+// Status describes one trigger instance.
+type Status struct {
+	Name string `json:"name"`
+	// Type is the kind of trigger, such as "bus".
+	Type string `json:"type"`
+	// Source is what the trigger listens to, such as a bus subject.
+	Source  string `json:"source,omitempty"`
+	Enabled bool   `json:"enabled"`
+	// Fired counts the runs the trigger has started since the daemon
+	// started; Jobs lists the jobs it has fired.
+	Fired       int64      `json:"fired"`
+	LastFiredAt *time.Time `json:"last_fired_at,omitempty"`
+	LastJob     string     `json:"last_job,omitempty"`
+	Jobs        []string   `json:"jobs"`
+}
+
+// Registry holds the triggers of one daemon. The enabled state lives in
+// memory; a restart enables every trigger again.
+type Registry struct {
+	mu       sync.Mutex
+	triggers map[string]*Trigger
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{triggers: make(map[string]*Trigger)}
+}
+
+// Trigger is a registered trigger instance. Its source asks Enabled before
+// firing a job and reports each run it starts with Fired.
+type Trigger struct {
+	reg    *Registry
+	status Status
+	jobs   map[string]bool
+}
+
+// Register adds an enabled trigger. Registering a name twice returns the
+// existing trigger.
+func (r *Registry) Register(name, typ, source string) *Trigger {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.triggers[name]; ok {
+		return t
+	}
+	t := &Trigger{
+		reg:    r,
+		status: Status{Name: name, Type: typ, Source: source, Enabled: true},
+		jobs:   make(map[string]bool),
+	}
+	r.triggers[name] = t
+	return t
+}
+
+// List returns the status of every trigger, by name.
+func (r *Registry) List() []Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Status, 0, len(r.triggers))
+	for _, t := range r.triggers {
+		out = append(out, t.snapshot())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// SetEnabled turns a trigger on or off and returns its new status.
+func (r *Registry) SetEnabled(name string, enabled bool) (Status, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.triggers[name]
+	if !ok {
+		return Status{}, ErrNotFound
+	}
+	t.status.Enabled = enabled
+	return t.snapshot(), nil
+}
+
+// Enabled reports whether the trigger may fire jobs.
+func (t *Trigger) Enabled() bool {
+	t.reg.mu.Lock()
+	defer t.reg.mu.Unlock()
+	return t.status.Enabled
+}
+
+// Fired records that the trigger started a run of jobName at at.
+func (t *Trigger) Fired(jobName string, at time.Time) {
+	t.reg.mu.Lock()
+	defer t.reg.mu.Unlock()
+	at = at.UTC()
+	t.status.Fired++
+	t.status.LastFiredAt = &at
+	t.status.LastJob = jobName
+	t.jobs[jobName] = true
+}
+
+// snapshot copies the status; the registry lock is held.
+func (t *Trigger) snapshot() Status {
+	s := t.status
+	if s.LastFiredAt != nil {
+		at := *s.LastFiredAt
+		s.LastFiredAt = &at
+	}
+	s.Jobs = make([]string, 0, len(t.jobs))
+	for name := range t.jobs {
+		s.Jobs = append(s.Jobs, name)
+	}
+	sort.Strings(s.Jobs)
+	return s
+}
@@ -0,0 +1,46 @@
+package trigger
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+	bus := r.Register("bus", "bus", "cronbat.trigger")
+	if r.Register("bus", "bus", "other") != bus {
+		t.Fatal("registering a name twice made a second trigger")
+	}
+	if !bus.Enabled() {
+		t.Fatal("new trigger is disabled")
+	}
+
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	bus.Fired("b", at)
+	bus.Fired("a", at.Add(time.Minute))
+	bus.Fired("b", at.Add(2*time.Minute))
+
+	list := r.List()
+	if len(list) != 1 {
+		t.Fatalf("list = %+v", list)
+	}
+	s := list[0]
+	if s.Fired != 3 || s.LastJob != "b" || !s.LastFiredAt.Equal(at.Add(2*time.Minute)) {
+		t.Fatalf("status = %+v", s)
+	}
+	if !reflect.DeepEqual(s.Jobs, []string{"a", "b"}) {
+		t.Fatalf("jobs = %v", s.Jobs)
+	}
+
+	s, err := r.SetEnabled("bus", false)
+	if err != nil || s.Enabled || bus.Enabled() {
+		t.Fatalf("disable: %+v, %v", s, err)
+	}
+	if _, err := r.SetEnabled("nope", true); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("unknown trigger: %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+// Package updatecheck compares the running version with the latest GitHub
+// release of cronbat, once a day, so operators learn about updates from
+// the stats endpoint.
+package updatecheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Interval is how often releases are checked.
+const Interval = 24 * time.Hour
+
+// Timeout bounds one check.
+const Timeout = 15 * time.Second
+
+// apiBase is the GitHub API root; a variable so tests can point it at a
+// local server.
+var apiBase = "https://api.github.com"
+
+// Status is the outcome of the latest check.
+type Status struct {
+	Current string `json:"current"`
+	// Latest is the tag of the newest release; empty until a check
+	// succeeds.
+	Latest string `json:"latest,omitempty"`
+	URL    string `json:"url,omitempty"`
+	// UpdateAvailable is true when Latest is a newer version than
+	// Current. Development builds are never reported out of date.
+	UpdateAvailable bool      `json:"update_available"`
+	CheckedAt       time.Time `json:"checked_at"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// Checker checks one repository's releases. A nil Checker is valid and
+// never has a status.
+type Checker struct {
+	repo    string
+	current string
+	client  *http.Client
+
+	mu     sync.Mutex
+	status *Status
+}
+
+// New returns a checker comparing current with the releases of repo,
+// given as "owner/name".
+func New(repo, current string) (*Checker, error) {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("repo must be owner/name, got %q", repo)
+	}
+	return &Checker{repo: repo, current: current, client: &http.Client{Timeout: Timeout}}, nil
+}
+
+// Run checks now and then every Interval until ctx is done.
+func (c *Checker) Run(ctx context.Context) {
+	if c == nil {
+		return
+	}
+	ticker := time.NewTicker(Interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Check(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("WARN: update check failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Status returns the outcome of the latest check, or nil before the first.
+func (c *Checker) Status() *Status {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.status == nil {
+		return nil
+	}
+	s := *c.status
+	return &s
+}
+
+// release is the part of GitHub's release object that is used.
+type release struct {
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+}
+
+// Check fetches the latest release and records the outcome. A failed check
+// keeps the last known release.
+func (c *Checker) Check(ctx context.Context) error {
+	rel, err := c.latest(ctx)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := &Status{Current: c.current, CheckedAt: time.Now().UTC()}
+	if prev := c.status; prev != nil {
+		s.Latest, s.URL, s.UpdateAvailable = prev.Latest, prev.URL, prev.UpdateAvailable
+	}
+	if err != nil {
+		s.Error = err.Error()
+	} else {
+		s.Latest, s.URL = rel.TagName, rel.HTMLURL
+		s.UpdateAvailable = Newer(rel.TagName, c.current)
+	}
+	c.status = s
+	return err
+}
+
+func (c *Checker) latest(ctx context.Context) (*release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiBase+"/repos/"+c.repo+"/releases/latest", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "cronbat/"+c.current)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var rel release
+	if err := json.Unmarshal(body, &rel); err != nil {
+		return nil, err
+	}
+	if rel.TagName == "" {
+		return nil, errors.New("release has no tag")
+	}
+	return &rel, nil
+}
+
+// Newer reports whether latest is a higher semantic version than current.
+// Versions are compared as vMAJOR.MINOR.PATCH; anything else, such as a
+// "dev" build, is never older than a release.
+func Newer(latest, current string) bool {
+	l, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	c, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	// A prerelease of the same version is older than the release.
+	return !strings.Contains(latest, "-") && strings.Contains(current, "-")
+}
+
+// parseVersion reads the numbers of "v1.2.3", ignoring a "-rc.1" or
+// "+build" suffix.
+func parseVersion(v string) ([3]int, bool) {
+	var out [3]int
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return out, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return out, false
+		}
+		out[i] = n
+	}
+	return out, true
+}
@@ -0,0 +1,69 @@
+package updatecheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewer(t *testing.T) {
+	for _, tc := range []struct {
+		latest, current string
+		want            bool
+	}{
+		{"v1.3.0", "v1.2.9", true},
+		{"v1.2.10", "v1.2.9", true},
+		{"v1.2.9", "v1.2.9", false},
+		{"v1.2.0", "v1.10.0", false},
+		{"v1.2.0", "v1.2.0-rc.1", true},
+		{"v1.2.0-rc.2", "v1.2.0", false},
+		{"v2.0.0", "dev", false},
+		{"nightly", "v1.0.0", false},
+	} {
+		if got := Newer(tc.latest, tc.current); got != tc.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tc.latest, tc.current, got, tc.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/patrickspencer/cronbat/releases/latest" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{"tag_name":"v1.4.0","html_url":"https://github.com/patrickspencer/cronbat/releases/tag/v1.4.0"}`))
+	}))
+	defer srv.Close()
+	defer func(base string) { apiBase = base }(apiBase)
+	apiBase = srv.URL
+
+	c, err := New("patrickspencer/cronbat", "v1.3.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Status() != nil {
+		t.Fatal("status before the first check")
+	}
+	if err := c.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	s := c.Status()
+	if s.Latest != "v1.4.0" || !s.UpdateAvailable || s.Error != "" {
+		t.Fatalf("status = %+v", s)
+	}
+
+	status = http.StatusForbidden
+	if err := c.Check(context.Background()); err == nil {
+		t.Fatal("check succeeded on 403")
+	}
+	if s := c.Status(); s.Latest != "v1.4.0" || !s.UpdateAvailable || s.Error == "" {
+		t.Fatalf("status after failure = %+v", s)
+	}
+
+	if _, err := New("cronbat", "v1.0.0"); err == nil {
+		t.Fatal("accepted a repo without an owner")
+	}
+}
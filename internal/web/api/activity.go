@@ -0,0 +1,85 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ActiveRun is a run executing now.
+type ActiveRun struct {
+	RunID     string    `json:"run_id"`
+	JobName   string    `json:"job_name"`
+	Trigger   string    `json:"trigger"`
+	Priority  int       `json:"priority"`
+	Host      string    `json:"host,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	ElapsedMs int64     `json:"elapsed_ms"`
+	// PID is the process ID of a local run once its process has started;
+	// zero for runs on agents.
+	PID int `json:"pid,omitempty"`
+}
+
+// QueuedRun is a run waiting for a free slot under max_concurrent_runs.
+type QueuedRun struct {
+	// Position is 1 for the run dispatched next.
+	Position    int       `json:"position"`
+	JobName     string    `json:"job_name"`
+	Trigger     string    `json:"trigger"`
+	Priority    int       `json:"priority"`
+	RunID       string    `json:"run_id,omitempty"`
+	TriggeredBy string    `json:"triggered_by,omitempty"`
+	EnqueuedAt  time.Time `json:"enqueued_at"`
+	WaitMs      int64     `json:"wait_ms"`
+}
+
+// handleActiveRuns serves GET /api/v1/runs/active: the runs executing now,
+// longest-running first.
+func (a *API) handleActiveRuns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if a.ActiveRuns == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "active runs unavailable"})
+		return
+	}
+	now := time.Now()
+	runs := a.ActiveRuns()
+	for i := range runs {
+		runs[i].ElapsedMs = now.Sub(runs[i].StartedAt).Milliseconds()
+	}
+	sort.Slice(runs, func(i, k int) bool {
+		if !runs[i].StartedAt.Equal(runs[k].StartedAt) {
+			return runs[i].StartedAt.Before(runs[k].StartedAt)
+		}
+		return runs[i].RunID < runs[k].RunID
+	})
+	if runs == nil {
+		runs = []ActiveRun{}
+	}
+	writeJSON(w, http.StatusOK, runs)
+}
+
+// handleQueuedRuns serves GET /api/v1/runs/queued: the runs waiting for a
+// slot, in the order they will be dispatched.
+func (a *API) handleQueuedRuns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if a.QueuedRuns == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "queued runs unavailable"})
+		return
+	}
+	now := time.Now()
+	runs := a.QueuedRuns()
+	for i := range runs {
+		runs[i].Position = i + 1
+		runs[i].WaitMs = now.Sub(runs[i].EnqueuedAt).Milliseconds()
+	}
+	if runs == nil {
+		runs = []QueuedRun{}
+	}
+	writeJSON(w, http.StatusOK, runs)
+}
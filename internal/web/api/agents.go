@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"time"
+)
+
+type agentResponse struct {
+	ID          string            `json:"id"`
+	Hostname    string            `json:"hostname"`
+	Labels      map[string]string `json:"labels"`
+	Version     string            `json:"version,omitempty"`
+	Addr        string            `json:"addr"`
+	ConnectedAt time.Time         `json:"connected_at"`
+	Running     int               `json:"running"`
+}
+
+// handleListAgents serves GET /api/v1/agents: the connected agents.
+func (a *API) handleListAgents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if a.Agents == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "agent connections are not enabled"})
+		return
+	}
+	result := []agentResponse{}
+	for _, info := range a.Agents.List() {
+		labels := info.Labels
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		result = append(result, agentResponse{
+			ID:          info.ID,
+			Hostname:    info.Hostname,
+			Labels:      labels,
+			Version:     info.Version,
+			Addr:        info.Addr,
+			ConnectedAt: info.ConnectedAt,
+			Running:     info.Running,
+		})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleAgentConnect upgrades /api/v1/agents/connect to the agent
+// WebSocket protocol.
+func (a *API) handleAgentConnect(w http.ResponseWriter, r *http.Request) {
+	if a.Agents == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "agent connections are not enabled"})
+		return
+	}
+	a.Agents.ServeHTTP(w, r)
+}
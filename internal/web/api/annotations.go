@@ -0,0 +1,167 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/store"
+)
+
+// maxAnnotationLen bounds one annotation's note.
+const maxAnnotationLen = 4096
+
+// detailAnnotations is how many recent annotations the job detail includes.
+const detailAnnotations = 20
+
+// disableRequest is the optional body of PUT /api/v1/jobs/{name}/disable,
+// /stop, and /pause. Actor defaults to the caller's API key name or IP address.
+// Pause also takes Until (RFC 3339) or For (a duration such as "2h") to
+// snooze the job: it resumes on its own at that time.
+type disableRequest struct {
+	Reason string `json:"reason"`
+	Actor  string `json:"actor"`
+	Until  string `json:"until"`
+	For    string `json:"for"`
+
+	snoozeUntil time.Time
+}
+
+// readDisableRequest reads the optional reason and actor for disabling,
+// stopping, or pausing a job. An empty body is allowed.
+func (a *API) readDisableRequest(r *http.Request) (disableRequest, error) {
+	var req disableRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		return req, errors.New("invalid request body: " + err.Error())
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	req.Actor = strings.TrimSpace(req.Actor)
+	if req.Actor == "" {
+		req.Actor = a.requestIdentity(r)
+	}
+	switch {
+	case req.Until != "" && req.For != "":
+		return req, errors.New("invalid request: set until or for, not both")
+	case req.Until != "":
+		t, err := time.Parse(time.RFC3339, req.Until)
+		if err != nil {
+			return req, errors.New("invalid until: " + err.Error())
+		}
+		req.snoozeUntil = t
+	case req.For != "":
+		d, err := time.ParseDuration(req.For)
+		if err != nil || d <= 0 {
+			return req, errors.New("invalid for: must be a positive duration such as 2h")
+		}
+		req.snoozeUntil = time.Now().Add(d)
+	}
+	if !req.snoozeUntil.IsZero() && !req.snoozeUntil.After(time.Now()) {
+		return req, errors.New("invalid snooze: until must be in the future")
+	}
+	return req, nil
+}
+
+type annotationResponse struct {
+	ID      int64     `json:"id"`
+	JobName string    `json:"job_name"`
+	At      time.Time `json:"at"`
+	Author  string    `json:"author"`
+	Note    string    `json:"note"`
+}
+
+func toAnnotationResponse(n *store.Annotation) annotationResponse {
+	return annotationResponse{ID: n.ID, JobName: n.JobName, At: n.At, Author: n.Author, Note: n.Note}
+}
+
+func (a *API) jobExists(name string) bool {
+	for _, j := range a.Jobs() {
+		if j.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// handleJobAnnotations serves /api/v1/jobs/{name}/annotations[/{id}]:
+// GET lists notes newest first, POST adds {"note", "author"}, and DELETE
+// on an ID removes one.
+func (a *API) handleJobAnnotations(w http.ResponseWriter, r *http.Request, name, id string) {
+	if a.ListAnnotations == nil || a.AddAnnotation == nil || a.DeleteAnnotation == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "annotations not available"})
+		return
+	}
+
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		limit := 100
+		if v := r.URL.Query().Get("limit"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				limit = n
+			}
+		}
+		notes, err := a.ListAnnotations(r.Context(), name, limit)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list annotations"})
+			return
+		}
+		result := make([]annotationResponse, 0, len(notes))
+		for _, n := range notes {
+			result = append(result, toAnnotationResponse(n))
+		}
+		writeJSON(w, http.StatusOK, result)
+
+	case id == "" && r.Method == http.MethodPost:
+		if !a.jobExists(name) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found: " + name})
+			return
+		}
+		var req struct {
+			Note   string `json:"note"`
+			Author string `json:"author"`
+		}
+		if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body: " + err.Error()})
+			return
+		}
+		req.Note = strings.TrimSpace(req.Note)
+		if req.Note == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "note is required"})
+			return
+		}
+		if len(req.Note) > maxAnnotationLen {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "note is longer than 4096 bytes"})
+			return
+		}
+		author := strings.TrimSpace(req.Author)
+		if author == "" {
+			author = a.requestIdentity(r)
+		}
+		n := &store.Annotation{JobName: name, Author: author, Note: req.Note}
+		if err := a.AddAnnotation(r.Context(), n); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to add annotation"})
+			return
+		}
+		a.audit(r, "annotate", name, req.Note)
+		writeJSON(w, http.StatusCreated, toAnnotationResponse(n))
+
+	case id != "" && r.Method == http.MethodDelete:
+		n, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid annotation id"})
+			return
+		}
+		if err := a.DeleteAnnotation(r.Context(), name, n); err != nil {
+			writeJSON(w, statusFromError(err), map[string]string{"error": err.Error()})
+			return
+		}
+		a.audit(r, "delete_annotation", name, "annotation "+id)
+		writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
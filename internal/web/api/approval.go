@@ -0,0 +1,155 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/approval"
+	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/realtime"
+)
+
+type approvalResponse struct {
+	ID          string     `json:"id"`
+	JobName     string     `json:"job_name"`
+	Status      string     `json:"status"`
+	RequestedBy string     `json:"requested_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	DecidedBy   string     `json:"decided_by,omitempty"`
+	DecidedAt   *time.Time `json:"decided_at,omitempty"`
+}
+
+func toApprovalResponse(req *approval.Request) approvalResponse {
+	return approvalResponse{
+		ID:          req.ID,
+		JobName:     req.JobName,
+		Status:      req.Status,
+		RequestedBy: req.RequestedBy,
+		CreatedAt:   req.CreatedAt,
+		ExpiresAt:   req.ExpiresAt,
+		DecidedBy:   req.DecidedBy,
+		DecidedAt:   req.DecidedAt,
+	}
+}
+
+// requestApproval holds a manual run of an approval-gated job until someone
+// else approves it.
+func (a *API) requestApproval(w http.ResponseWriter, r *http.Request, job *config.Job) {
+	if a.RequestApproval == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "approvals not available"})
+		return
+	}
+	ttl, err := job.ParseApprovalTimeout()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("invalid approval_timeout: %v", err)})
+		return
+	}
+
+	requestedBy := a.requestActor(r)
+	req := a.RequestApproval(job.Name, a.requestIdentity(r), requestedBy, ttl)
+	log.Printf("manual run of job %s by %s is waiting for approval %s", job.Name, requestedBy, req.ID)
+	a.audit(r, "approval_request", job.Name, "approval "+req.ID)
+	a.emitEvent(realtime.Event{
+		Type:    "approval.requested",
+		JobName: job.Name,
+		Status:  req.Status,
+	})
+
+	writeJSON(w, http.StatusAccepted, map[string]any{
+		"status":      "pending_approval",
+		"approval_id": req.ID,
+		"expires_at":  req.ExpiresAt,
+	})
+}
+
+// handleListApprovals serves GET /api/v1/approvals[?status=pending].
+func (a *API) handleListApprovals(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if a.ListApprovals == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "approvals not available"})
+		return
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", approval.StatusPending, approval.StatusApproved, approval.StatusRejected, approval.StatusExpired:
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid status"})
+		return
+	}
+
+	reqs := a.ListApprovals(status)
+	out := make([]approvalResponse, 0, len(reqs))
+	for _, req := range reqs {
+		out = append(out, toApprovalResponse(req))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// routeApprovals dispatches /api/v1/approvals/{id}[/approve|/reject].
+func (a *API) routeApprovals(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/approvals/")
+	id, action, _ := strings.Cut(path, "/")
+	if id == "" {
+		a.handleListApprovals(w, r)
+		return
+	}
+	if a.GetApproval == nil || a.DecideApproval == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "approvals not available"})
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		req := a.GetApproval(id)
+		if req == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "approval not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, toApprovalResponse(req))
+	case action == "approve" && r.Method == http.MethodPost:
+		a.handleDecideApproval(w, r, id, true)
+	case action == "reject" && r.Method == http.MethodPost:
+		a.handleDecideApproval(w, r, id, false)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func (a *API) handleDecideApproval(w http.ResponseWriter, r *http.Request, id string, approve bool) {
+	decidedBy := a.requestActor(r)
+	req, err := a.DecideApproval(id, approve, a.requestIdentity(r), decidedBy)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, approval.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, approval.ErrNotPending):
+			status = http.StatusConflict
+		case errors.Is(err, approval.ErrSelfApproval):
+			status = http.StatusForbidden
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+
+	action := "reject"
+	if approve {
+		action = "approve"
+	}
+	log.Printf("approval %s for job %s %s by %s", req.ID, req.JobName, req.Status, decidedBy)
+	a.audit(r, action, req.JobName, "approval "+req.ID)
+	a.emitEvent(realtime.Event{
+		Type:    "approval.decided",
+		JobName: req.JobName,
+		Status:  req.Status,
+	})
+	writeJSON(w, http.StatusOK, toApprovalResponse(req))
+}
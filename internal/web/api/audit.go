@@ -0,0 +1,193 @@
+package api
+
+import (
+	"crypto/subtle"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/store"
+)
+
+// requestActor describes who made r: the API key or signed-in user it
+// authenticated as (see requestCaller), the client IP, and the user agent,
+// e.g. `key=deploy-bot ip=10.0.0.7 ua="curl/8.4.0"` or
+// `user=ada@example.com ip=...`. A key that matches nothing is reported as
+// key=unknown.
+func (a *API) requestActor(r *http.Request) string {
+	var parts []string
+	if c := a.requestCaller(r); c.Name != "" {
+		parts = append(parts, c.Kind+"="+c.Name)
+	}
+	if ip := requestIP(r); ip != "" {
+		parts = append(parts, "ip="+ip)
+	}
+	if ua := r.UserAgent(); ua != "" {
+		parts = append(parts, "ua="+strconv.Quote(ua))
+	}
+	return strings.Join(parts, " ")
+}
+
+// requestIdentity returns a stable identity for the caller of r, used to
+// tell two people apart: "key:<name>" for a configured API key,
+// "user:<name>" for a signed-in user, otherwise "ip:<address>".
+func (a *API) requestIdentity(r *http.Request) string {
+	if c := a.requestCaller(r); c.authenticated() {
+		return c.Kind + ":" + c.Name
+	}
+	return "ip:" + requestIP(r)
+}
+
+// Caller kinds.
+const (
+	KindKey  = "key"
+	KindUser = "user"
+)
+
+// caller is who a request authenticated as.
+type caller struct {
+	// Kind is KindKey for an API key, or a session or token started with
+	// one, and KindUser for a single sign-on user; "" when the request
+	// carried no credentials.
+	Kind string
+	// Name is the key's or the user's name, "unknown" for an
+	// unrecognized key.
+	Name string
+	// Role is empty unless the request authenticated.
+	Role string
+	// session is set when the session cookie authenticated the request,
+	// token when a minted API token did.
+	session *Session
+	token   *Session
+}
+
+func (c caller) authenticated() bool {
+	return c.Role != ""
+}
+
+// requestCaller resolves the API key, minted API token, or session cookie
+// r carries.
+func (a *API) requestCaller(r *http.Request) caller {
+	if key := requestAPIKey(r); key != "" {
+		if k := a.apiKey(key); k != nil {
+			return caller{Kind: KindKey, Name: k.Name, Role: keyRole(k)}
+		}
+		if t := a.Sessions.Token(key); t != nil {
+			return caller{Kind: t.Kind, Name: t.Name, Role: t.Role, token: t}
+		}
+		return caller{Kind: KindKey, Name: "unknown"}
+	}
+	if sess := a.requestSession(r); sess != nil {
+		return caller{Kind: sess.Kind, Name: sess.Name, Role: sess.Role, session: sess}
+	}
+	return caller{}
+}
+
+// apiKey returns the configured api_keys entry with key, or nil.
+func (a *API) apiKey(key string) *config.APIKeyConfig {
+	if key == "" {
+		return nil
+	}
+	for i, k := range a.APIKeys {
+		if k.Key != "" && subtle.ConstantTimeCompare([]byte(k.Key), []byte(key)) == 1 {
+			return &a.APIKeys[i]
+		}
+	}
+	return nil
+}
+
+// keyRole returns the role of an api_keys entry; keys without one are
+// admins.
+func keyRole(k *config.APIKeyConfig) string {
+	if k.Role == "" {
+		return RoleAdmin
+	}
+	return k.Role
+}
+
+func requestIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// audit records that the caller of r performed action on a job. Failures
+// are logged; they do not fail the request.
+func (a *API) audit(r *http.Request, action, jobName, detail string) {
+	if a.RecordAudit == nil {
+		return
+	}
+	err := a.RecordAudit(r.Context(), &store.AuditEntry{
+		Actor:   a.requestActor(r),
+		Action:  action,
+		JobName: jobName,
+		Detail:  detail,
+	})
+	if err != nil {
+		log.Printf("ERROR: failed to record audit entry: %v", err)
+	}
+}
+
+type auditResponse struct {
+	ID      int64     `json:"id"`
+	At      time.Time `json:"at"`
+	Actor   string    `json:"actor"`
+	Action  string    `json:"action"`
+	JobName string    `json:"job_name,omitempty"`
+	Detail  string    `json:"detail,omitempty"`
+}
+
+// handleListAudit serves GET /api/v1/audit?job=&limit=100.
+func (a *API) handleListAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if a.ListAudit == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "audit log unavailable"})
+		return
+	}
+
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	entries, err := a.ListAudit(r.Context(), r.URL.Query().Get("job"), limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	out := make([]auditResponse, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, auditResponse{
+			ID:      e.ID,
+			At:      e.At,
+			Actor:   e.Actor,
+			Action:  e.Action,
+			JobName: e.JobName,
+			Detail:  e.Detail,
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
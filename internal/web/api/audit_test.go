@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+)
+
+func TestRequestActor(t *testing.T) {
+	t.Parallel()
+
+	a := &API{APIKeys: []config.APIKeyConfig{{Name: "deploy-bot", Key: "s3cret"}}}
+
+	r := httptest.NewRequest("POST", "/api/v1/jobs/a/run", nil)
+	r.RemoteAddr = "10.0.0.7:51234"
+	r.Header.Set("User-Agent", "curl/8.4.0")
+	r.Header.Set("Authorization", "Bearer s3cret")
+	if got, want := a.requestActor(r), `key=deploy-bot ip=10.0.0.7 ua="curl/8.4.0"`; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	r.Header.Del("Authorization")
+	r.Header.Set("X-API-Key", "wrong")
+	if got, want := a.requestActor(r), `key=unknown ip=10.0.0.7 ua="curl/8.4.0"`; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
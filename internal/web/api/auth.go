@@ -0,0 +1,438 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SessionCookie holds the ID of a UI session.
+	SessionCookie = "cronbat_session"
+	// CSRFCookie holds the session's CSRF token where the UI's scripts can
+	// read it; they echo it in CSRFHeader.
+	CSRFCookie = "cronbat_csrf"
+	// CSRFHeader must carry the session's CSRF token on every mutating
+	// request authenticated by the session cookie.
+	CSRFHeader = "X-CSRF-Token"
+)
+
+// DefaultSessionTTL is how long a UI session lasts when auth.session_ttl
+// is unset.
+const DefaultSessionTTL = 12 * time.Hour
+
+// MaxTokenTTL caps the lifetime of API tokens minted at
+// /api/v1/auth/token.
+const MaxTokenTTL = 30 * 24 * time.Hour
+
+// Session is a signed-in UI session, or an API token minted from one.
+type Session struct {
+	ID        string
+	CSRFToken string
+	// Kind and Name say who signed in: KindKey and the api_keys entry's
+	// name, or KindUser and a single sign-on user's name. Requests made
+	// with the session are attributed to them.
+	Kind      string
+	Name      string
+	Role      string
+	ExpiresAt time.Time
+	// token marks an API token, sent as a bearer token instead of the
+	// session cookie.
+	token bool
+}
+
+// Sessions holds UI sessions and minted API tokens in memory; a restart
+// signs everyone out.
+type Sessions struct {
+	ttl time.Duration
+
+	mu   sync.Mutex
+	byID map[string]*Session
+}
+
+// NewSessions returns a session store whose sessions last ttl.
+func NewSessions(ttl time.Duration) *Sessions {
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	return &Sessions{ttl: ttl, byID: make(map[string]*Session)}
+}
+
+// Create starts a UI session.
+func (s *Sessions) Create(kind, name, role string) (*Session, error) {
+	return s.add(&Session{Kind: kind, Name: name, Role: role}, time.Now().Add(s.ttl))
+}
+
+// Mint returns a new API token for the signer-in of a session, valid for
+// ttl (the session lifetime when zero) but not past notAfter, unless that
+// is zero.
+func (s *Sessions) Mint(kind, name, role string, ttl time.Duration, notAfter time.Time) (*Session, error) {
+	if ttl <= 0 {
+		ttl = s.ttl
+	}
+	expires := time.Now().Add(ttl)
+	if !notAfter.IsZero() && expires.After(notAfter) {
+		expires = notAfter
+	}
+	return s.add(&Session{Kind: kind, Name: name, Role: role, token: true}, expires)
+}
+
+func (s *Sessions) add(sess *Session, expires time.Time) (*Session, error) {
+	var err error
+	if sess.ID, err = randomToken(); err != nil {
+		return nil, err
+	}
+	if sess.CSRFToken, err = randomToken(); err != nil {
+		return nil, err
+	}
+	sess.ExpiresAt = expires.UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for k, old := range s.byID {
+		if now.After(old.ExpiresAt) {
+			delete(s.byID, k)
+		}
+	}
+	s.byID[sess.ID] = sess
+	return sess, nil
+}
+
+// Get returns the live UI session with id, or nil.
+func (s *Sessions) Get(id string) *Session {
+	if sess := s.lookup(id); sess != nil && !sess.token {
+		return sess
+	}
+	return nil
+}
+
+// Token returns the live API token token, or nil.
+func (s *Sessions) Token(token string) *Session {
+	if sess := s.lookup(token); sess != nil && sess.token {
+		return sess
+	}
+	return nil
+}
+
+func (s *Sessions) lookup(id string) *Session {
+	if s == nil || id == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := s.byID[id]
+	if sess == nil {
+		return nil
+	}
+	if time.Now().After(sess.ExpiresAt) {
+		delete(s.byID, id)
+		return nil
+	}
+	return sess
+}
+
+// Delete ends the session or revokes the token with id.
+func (s *Sessions) Delete(id string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	delete(s.byID, id)
+	s.mu.Unlock()
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// requestSession returns the live session whose cookie r carries, or nil.
+func (a *API) requestSession(r *http.Request) *Session {
+	c, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return nil
+	}
+	return a.Sessions.Get(c.Value)
+}
+
+// publicPaths answer without authentication: probes, the sign-in flows,
+// and agent connections, which check their own token.
+var publicPaths = map[string]bool{
+	"/api/v1/health":             true,
+	"/api/v1/ready":              true,
+	"/api/v1/auth/login":         true,
+	"/api/v1/auth/logout":        true,
+	"/api/v1/auth/session":       true,
+	"/api/v1/auth/oidc/login":    true,
+	"/api/v1/auth/oidc/callback": true,
+	"/api/v1/agents/connect":     true,
+}
+
+// replicaWritablePaths accept mutating requests on a read-only replica;
+// sessions live in memory, not in the database.
+var replicaWritablePaths = map[string]bool{
+	"/api/v1/auth/login":  true,
+	"/api/v1/auth/logout": true,
+}
+
+// Protect guards the API behind next. Mutating requests from another
+// site's pages are always rejected, so a page the operator happens to
+// visit cannot drive the API. Requests authenticated by the session cookie
+// must also echo the session's CSRF token; requests sending a valid API key
+// or token carry no ambient credentials and need neither, but a key that
+// does not resolve earns no exemption: cross-site, it gets 401. With
+// RequireAuth, API requests without a valid key, token, or session get 401,
+// and authenticated callers are held to their role.
+func (a *API) Protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		unsafe := !isSafeMethod(r.Method)
+		c := a.requestCaller(r)
+		keyed := requestAPIKey(r) != ""
+		if unsafe && !(keyed && c.authenticated()) && isCrossSite(r) {
+			if keyed {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="cronbat"`)
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid API key or token"})
+				return
+			}
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "cross-site request rejected"})
+			return
+		}
+		if a.ReadOnly && unsafe && strings.HasPrefix(r.URL.Path, "/api/") && !replicaWritablePaths[r.URL.Path] {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "read-only replica"})
+			return
+		}
+
+		if c.session != nil && unsafe && !validCSRF(r, c.session) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "missing or invalid CSRF token"})
+			return
+		}
+		if a.RequireAuth && !c.authenticated() && strings.HasPrefix(r.URL.Path, "/api/") && !publicPaths[r.URL.Path] {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="cronbat"`)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "authentication required"})
+			return
+		}
+		if c.authenticated() && !roleAllows(c.Role, r) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "role " + c.Role + " may not " + r.Method + " " + r.URL.Path})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// isCrossSite reports whether a browser sent r from another site's page.
+// Browsers set Sec-Fetch-Site, and Origin on cross-origin POST, PUT, and
+// DELETE; other clients send neither.
+func isCrossSite(r *http.Request) bool {
+	if r.Header.Get("Sec-Fetch-Site") == "cross-site" {
+		return true
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		// "null" comes from sandboxed frames and file:// pages.
+		return true
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return false
+	}
+	// Behind a reverse proxy the Host header may be rewritten.
+	if fwd := r.Header.Get("X-Forwarded-Host"); fwd != "" && strings.EqualFold(u.Host, fwd) {
+		return false
+	}
+	return true
+}
+
+func validCSRF(r *http.Request, sess *Session) bool {
+	token := r.Header.Get(CSRFHeader)
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(sess.CSRFToken)) == 1
+}
+
+// sessionResponse describes the caller's session.
+type sessionResponse struct {
+	AuthRequired  bool       `json:"auth_required"`
+	Authenticated bool       `json:"authenticated"`
+	Kind          string     `json:"kind,omitempty"`
+	Name          string     `json:"name,omitempty"`
+	Role          string     `json:"role,omitempty"`
+	CSRFToken     string     `json:"csrf_token,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	// SSO reports that users can sign in at /api/v1/auth/oidc/login.
+	SSO bool `json:"sso"`
+}
+
+// handleLogin serves POST /api/v1/auth/login. A body of {"key": "..."}
+// naming one of api_keys starts a UI session: the session ID is set in an
+// HttpOnly cookie and the CSRF token is returned and set in a cookie the
+// UI can read.
+func (a *API) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if a.Sessions == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "sessions unavailable"})
+		return
+	}
+	var body struct {
+		Key string `json:"key"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	k := a.apiKey(strings.TrimSpace(body.Key))
+	if k == nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid API key"})
+		return
+	}
+	sess, err := a.Sessions.Create(KindKey, k.Name, keyRole(k))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	a.setSessionCookies(w, sess.ID, sess.CSRFToken, sess.ExpiresAt)
+	writeJSON(w, http.StatusOK, a.sessionResponse(caller{Kind: sess.Kind, Name: sess.Name, Role: sess.Role, session: sess}))
+}
+
+// handleLogout serves POST /api/v1/auth/logout, ending the caller's
+// session and clearing its cookies.
+func (a *API) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if sess := a.requestSession(r); sess != nil {
+		a.Sessions.Delete(sess.ID)
+	}
+	a.setSessionCookies(w, "", "", time.Unix(0, 0))
+	writeJSON(w, http.StatusOK, map[string]string{"status": "logged out"})
+}
+
+// handleSession serves GET /api/v1/auth/session, telling the UI whether it
+// must sign in and how.
+func (a *API) handleSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, a.sessionResponse(a.requestCaller(r)))
+}
+
+func (a *API) sessionResponse(c caller) sessionResponse {
+	resp := sessionResponse{AuthRequired: a.RequireAuth, SSO: a.OIDC != nil}
+	if !c.authenticated() {
+		return resp
+	}
+	resp.Authenticated = true
+	resp.Kind, resp.Name, resp.Role = c.Kind, c.Name, c.Role
+	if c.session != nil {
+		resp.CSRFToken = c.session.CSRFToken
+		resp.ExpiresAt = &c.session.ExpiresAt
+	}
+	return resp
+}
+
+// handleMintToken serves POST /api/v1/auth/token, returning an API token
+// with the caller's name and role, e.g. for a signed-in single sign-on
+// user to use from scripts. An optional {"ttl": "24h"} sets its lifetime,
+// at most MaxTokenTTL; the default is the session lifetime. A token minted
+// with another token expires no later than that one, so tokens cannot
+// renew themselves indefinitely.
+func (a *API) handleMintToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if a.Sessions == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "sessions unavailable"})
+		return
+	}
+	c := a.requestCaller(r)
+	if !c.authenticated() {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "sign in to mint a token"})
+		return
+	}
+	var body struct {
+		TTL string `json:"ttl"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&body); err != nil && err != io.EOF {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	var ttl time.Duration
+	if body.TTL != "" {
+		d, err := time.ParseDuration(body.TTL)
+		if err != nil || d <= 0 || d > MaxTokenTTL {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid ttl: want a duration up to " + MaxTokenTTL.String()})
+			return
+		}
+		ttl = d
+	}
+	var notAfter time.Time
+	if c.token != nil {
+		notAfter = c.token.ExpiresAt
+	}
+	tok, err := a.Sessions.Mint(c.Kind, c.Name, c.Role, ttl, notAfter)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	a.audit(r, "mint_token", "", "expires "+tok.ExpiresAt.Format(time.RFC3339))
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"token":      tok.ID,
+		"kind":       tok.Kind,
+		"name":       tok.Name,
+		"role":       tok.Role,
+		"expires_at": tok.ExpiresAt,
+	})
+}
+
+// setSessionCookies sets, or with an expiry in the past clears, the session
+// and CSRF cookies. SameSite=Strict keeps browsers from sending them with
+// requests started on other sites.
+func (a *API) setSessionCookies(w http.ResponseWriter, id, csrf string, expires time.Time) {
+	maxAge := int(time.Until(expires).Seconds())
+	if maxAge <= 0 {
+		maxAge = -1
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   a.SecureCookie,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookie,
+		Value:    csrf,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   a.SecureCookie,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
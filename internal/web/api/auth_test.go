@@ -0,0 +1,258 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+)
+
+func TestProtect(t *testing.T) {
+	t.Parallel()
+
+	a := &API{
+		APIKeys:     []config.APIKeyConfig{{Name: "ops", Key: "s3cret"}, {Name: "dash", Key: "look", Role: RoleViewer}},
+		Sessions:    NewSessions(time.Hour),
+		RequireAuth: true,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/auth/login", a.handleLogin)
+	mux.HandleFunc("/api/v1/auth/token", a.handleMintToken)
+	mux.HandleFunc("/api/v1/jobs", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"key": a.requestCaller(r).Name})
+	})
+	mux.HandleFunc("/api/v1/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	h := a.Protect(mux)
+
+	do := func(method, path, body string, setup func(r *http.Request)) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if setup != nil {
+			setup(r)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := do("GET", "/api/v1/jobs", "", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous GET = %d, want 401", w.Code)
+	}
+	if w := do("GET", "/api/v1/health", "", nil); w.Code != http.StatusOK {
+		t.Fatalf("health = %d, want 200", w.Code)
+	}
+	bearer := func(r *http.Request) { r.Header.Set("Authorization", "Bearer s3cret") }
+	if w := do("POST", "/api/v1/jobs", "", bearer); w.Code != http.StatusOK {
+		t.Fatalf("bearer POST = %d, want 200", w.Code)
+	}
+	if w := do("GET", "/api/v1/jobs", "", func(r *http.Request) { r.Header.Set("X-API-Key", "nope") }); w.Code != http.StatusUnauthorized {
+		t.Fatalf("unknown key = %d, want 401", w.Code)
+	}
+
+	// A page on another site cannot drive the API, even to sign in.
+	crossSite := func(r *http.Request) { r.Header.Set("Origin", "https://evil.example") }
+	if w := do("POST", "/api/v1/auth/login", `{"key":"s3cret"}`, crossSite); w.Code != http.StatusForbidden {
+		t.Fatalf("cross-site login = %d, want 403", w.Code)
+	}
+	// A bearer token only lifts the cross-site check when it is valid.
+	if w := do("POST", "/api/v1/jobs", "", func(r *http.Request) {
+		crossSite(r)
+		r.Header.Set("Authorization", "Bearer bogus")
+	}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("cross-site POST with bogus bearer = %d, want 401", w.Code)
+	}
+	if w := do("POST", "/api/v1/jobs", "", func(r *http.Request) {
+		crossSite(r)
+		bearer(r)
+	}); w.Code != http.StatusOK {
+		t.Fatalf("cross-site POST with valid bearer = %d, want 200", w.Code)
+	}
+	if w := do("POST", "/api/v1/auth/login", `{"key":"wrong"}`, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("login with bad key = %d, want 401", w.Code)
+	}
+
+	w := do("POST", "/api/v1/auth/login", `{"key":"s3cret"}`, func(r *http.Request) { r.Header.Set("Origin", "http://example.com") })
+	if w.Code != http.StatusOK {
+		t.Fatalf("login = %d: %s", w.Code, w.Body.String())
+	}
+	var login sessionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &login); err != nil {
+		t.Fatal(err)
+	}
+	var session *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == SessionCookie {
+			session = c
+		}
+	}
+	if session == nil || !session.HttpOnly || session.SameSite != http.SameSiteStrictMode {
+		t.Fatalf("session cookie = %+v", session)
+	}
+	withCookie := func(r *http.Request) { r.AddCookie(session) }
+
+	w = do("GET", "/api/v1/jobs", "", withCookie)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"ops"`) {
+		t.Fatalf("session GET = %d: %s", w.Code, w.Body.String())
+	}
+	if w := do("POST", "/api/v1/jobs", "", withCookie); w.Code != http.StatusForbidden {
+		t.Fatalf("session POST without CSRF token = %d, want 403", w.Code)
+	}
+	if w := do("POST", "/api/v1/jobs", "", func(r *http.Request) {
+		withCookie(r)
+		r.Header.Set(CSRFHeader, login.CSRFToken)
+	}); w.Code != http.StatusOK {
+		t.Fatalf("session POST with CSRF token = %d, want 200", w.Code)
+	}
+
+	// A token minted from the session works as a bearer token.
+	w = do("POST", "/api/v1/auth/token", `{"ttl":"1h"}`, func(r *http.Request) {
+		withCookie(r)
+		r.Header.Set(CSRFHeader, login.CSRFToken)
+	})
+	var minted struct {
+		Token string `json:"token"`
+		Role  string `json:"role"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &minted); err != nil || w.Code != http.StatusCreated || minted.Role != RoleAdmin {
+		t.Fatalf("mint = %d: %s", w.Code, w.Body.String())
+	}
+	if w := do("POST", "/api/v1/jobs", "", func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+minted.Token) }); w.Code != http.StatusOK {
+		t.Fatalf("minted token POST = %d, want 200", w.Code)
+	}
+	// A token cannot mint one that outlives it.
+	w = do("POST", "/api/v1/auth/token", `{"ttl":"720h"}`, func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+minted.Token) })
+	var chained struct {
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &chained); err != nil || w.Code != http.StatusCreated {
+		t.Fatalf("mint with token = %d: %s", w.Code, w.Body.String())
+	}
+	if limit := a.Sessions.Token(minted.Token).ExpiresAt; chained.ExpiresAt.After(limit) {
+		t.Fatalf("token minted with a token expires %s, after its parent's %s", chained.ExpiresAt, limit)
+	}
+	// A session ID is not a bearer token.
+	if w := do("GET", "/api/v1/jobs", "", func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+session.Value) }); w.Code != http.StatusUnauthorized {
+		t.Fatalf("session ID as bearer = %d, want 401", w.Code)
+	}
+
+	viewer := func(r *http.Request) { r.Header.Set("X-API-Key", "look") }
+	if w := do("GET", "/api/v1/jobs", "", viewer); w.Code != http.StatusOK {
+		t.Fatalf("viewer GET = %d, want 200", w.Code)
+	}
+	if w := do("POST", "/api/v1/jobs", "", viewer); w.Code != http.StatusForbidden {
+		t.Fatalf("viewer POST = %d, want 403", w.Code)
+	}
+
+	a.Sessions.Delete(session.Value)
+	if w := do("GET", "/api/v1/jobs", "", withCookie); w.Code != http.StatusUnauthorized {
+		t.Fatalf("GET after logout = %d, want 401", w.Code)
+	}
+}
+
+func TestProtectWithoutAuth(t *testing.T) {
+	t.Parallel()
+
+	a := &API{}
+	h := a.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for _, tc := range []struct {
+		header, value string
+		want          int
+	}{
+		{"", "", http.StatusOK},
+		{"Origin", "http://localhost:8080", http.StatusOK},
+		{"Origin", "https://evil.example", http.StatusForbidden},
+		{"Origin", "null", http.StatusForbidden},
+		{"Sec-Fetch-Site", "cross-site", http.StatusForbidden},
+		{"Authorization", "Bearer bogus", http.StatusOK},
+	} {
+		r := httptest.NewRequest("POST", "http://localhost:8080/api/v1/jobs", nil)
+		if tc.header != "" {
+			r.Header.Set(tc.header, tc.value)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tc.want {
+			t.Errorf("POST with %s %q = %d, want %d", tc.header, tc.value, w.Code, tc.want)
+		}
+	}
+
+	// Without api_keys no bearer is valid, so it cannot vouch for a
+	// request from another site.
+	r := httptest.NewRequest("POST", "http://localhost:8080/api/v1/jobs", nil)
+	r.Header.Set("Origin", "https://evil.example")
+	r.Header.Set("Authorization", "Bearer bogus")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("cross-site POST with bogus bearer = %d, want 401", w.Code)
+	}
+}
+
+func TestProtectReadOnly(t *testing.T) {
+	t.Parallel()
+
+	a := &API{ReadOnly: true}
+	h := a.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for _, tc := range []struct {
+		method, path string
+		want         int
+	}{
+		{"GET", "/api/v1/jobs", http.StatusOK},
+		{"HEAD", "/api/v1/runs", http.StatusOK},
+		{"POST", "/api/v1/jobs/backup/run", http.StatusMethodNotAllowed},
+		{"PUT", "/api/v1/jobs/backup", http.StatusMethodNotAllowed},
+		{"DELETE", "/api/v1/jobs/backup", http.StatusMethodNotAllowed},
+		{"POST", "/api/v1/auth/login", http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		if w.Code != tc.want {
+			t.Errorf("%s %s = %d, want %d", tc.method, tc.path, w.Code, tc.want)
+		}
+	}
+}
+
+func TestRoleAllows(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		role, method, path string
+		want               bool
+	}{
+		{RoleViewer, "GET", "/api/v1/jobs", true},
+		{RoleViewer, "POST", "/api/v1/lint", true},
+		{RoleViewer, "POST", "/api/v1/jobs/a/run", false},
+		{RoleOperator, "POST", "/api/v1/jobs/a/run", true},
+		{RoleOperator, "PUT", "/api/v1/jobs/a/pause", true},
+		{RoleOperator, "POST", "/api/v1/jobs/run", true},
+		{RoleOperator, "POST", "/api/v1/jobs", false},
+		{RoleOperator, "PUT", "/api/v1/jobs/a/yaml", false},
+		{RoleOperator, "PUT", "/api/v1/jobs/a/schedule", false},
+		{RoleOperator, "PATCH", "/api/v1/jobs", false},
+		{RoleOperator, "DELETE", "/api/v1/jobs/a", false},
+		{RoleOperator, "POST", "/api/v1/store/compact", false},
+		{RoleAdmin, "DELETE", "/api/v1/jobs/a", true},
+	} {
+		r := httptest.NewRequest(tc.method, tc.path, nil)
+		if got := roleAllows(tc.role, r); got != tc.want {
+			t.Errorf("roleAllows(%s, %s %s) = %v, want %v", tc.role, tc.method, tc.path, got, tc.want)
+		}
+	}
+
+	mapping := map[string]string{"sre": RoleOperator, "platform": RoleAdmin}
+	if got := roleForGroups(mapping, "", []string{"sre", "platform"}); got != RoleAdmin {
+		t.Errorf("roleForGroups = %q, want admin", got)
+	}
+	if got := roleForGroups(mapping, RoleViewer, []string{"eng"}); got != RoleViewer {
+		t.Errorf("roleForGroups without a mapped group = %q, want viewer", got)
+	}
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/store"
+)
+
+type backfillRequest struct {
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Interval    string    `json:"interval"`
+	Parallelism int       `json:"parallelism"`
+}
+
+type backfillWindowResponse struct {
+	Seq    int       `json:"seq"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Status string    `json:"status"`
+	RunID  string    `json:"run_id,omitempty"`
+}
+
+type backfillResponse struct {
+	ID          string                   `json:"id"`
+	JobName     string                   `json:"job_name"`
+	From        time.Time                `json:"from"`
+	To          time.Time                `json:"to"`
+	Interval    string                   `json:"interval"`
+	Parallelism int                      `json:"parallelism"`
+	Status      string                   `json:"status"`
+	CreatedAt   time.Time                `json:"created_at"`
+	FinishedAt  *time.Time               `json:"finished_at,omitempty"`
+	Counts      map[string]int           `json:"counts,omitempty"`
+	Windows     []backfillWindowResponse `json:"windows,omitempty"`
+}
+
+func toBackfillResponse(b *store.Backfill, windows []store.BackfillWindow) backfillResponse {
+	resp := backfillResponse{
+		ID:          b.ID,
+		JobName:     b.JobName,
+		From:        b.From,
+		To:          b.To,
+		Interval:    b.Interval,
+		Parallelism: b.Parallelism,
+		Status:      b.Status,
+		CreatedAt:   b.CreatedAt,
+		FinishedAt:  b.FinishedAt,
+	}
+	if windows != nil {
+		resp.Counts = make(map[string]int)
+		resp.Windows = make([]backfillWindowResponse, 0, len(windows))
+		for _, w := range windows {
+			resp.Counts[w.Status]++
+			resp.Windows = append(resp.Windows, backfillWindowResponse{
+				Seq:    w.Seq,
+				Start:  w.Start,
+				End:    w.End,
+				Status: w.Status,
+				RunID:  w.RunID,
+			})
+		}
+	}
+	return resp
+}
+
+// handleJobBackfill starts a backfill (POST) or lists a job's backfills (GET).
+func (a *API) handleJobBackfill(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method == http.MethodGet {
+		a.listBackfills(w, r, name)
+		return
+	}
+	if a.CreateBackfill == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "backfill not available"})
+		return
+	}
+
+	var req backfillRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body: from and to must be RFC 3339 times"})
+		return
+	}
+	if req.From.IsZero() || req.To.IsZero() || strings.TrimSpace(req.Interval) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "from, to, and interval are required"})
+		return
+	}
+
+	for _, j := range a.Jobs() {
+		if j.Name == name && j.RequireApproval {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "job requires approval and cannot be backfilled"})
+			return
+		}
+	}
+
+	b, err := a.CreateBackfill(r.Context(), name, req.From, req.To, req.Interval, req.Parallelism)
+	if err != nil {
+		writeJSON(w, statusFromError(err), map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, toBackfillResponse(b, nil))
+}
+
+// handleListBackfills serves GET /api/v1/backfills[?job=].
+func (a *API) handleListBackfills(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	a.listBackfills(w, r, r.URL.Query().Get("job"))
+}
+
+func (a *API) listBackfills(w http.ResponseWriter, r *http.Request, jobName string) {
+	if a.ListBackfills == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "backfill not available"})
+		return
+	}
+	backfills, err := a.ListBackfills(r.Context(), jobName)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	resp := make([]backfillResponse, 0, len(backfills))
+	for _, b := range backfills {
+		resp = append(resp, toBackfillResponse(b, nil))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// routeBackfills dispatches /api/v1/backfills/{id}[/cancel] requests.
+func (a *API) routeBackfills(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/backfills/")
+	id, action, _ := strings.Cut(path, "/")
+	if id == "" {
+		a.handleListBackfills(w, r)
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		a.handleGetBackfill(w, r, id)
+	case action == "cancel" && r.Method == http.MethodPost:
+		a.handleCancelBackfill(w, r, id)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func (a *API) handleGetBackfill(w http.ResponseWriter, r *http.Request, id string) {
+	if a.GetBackfill == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "backfill not available"})
+		return
+	}
+	b, windows, err := a.GetBackfill(r.Context(), id)
+	if err != nil {
+		writeJSON(w, statusFromError(err), map[string]string{"error": err.Error()})
+		return
+	}
+	if windows == nil {
+		windows = []store.BackfillWindow{}
+	}
+	writeJSON(w, http.StatusOK, toBackfillResponse(b, windows))
+}
+
+func (a *API) handleCancelBackfill(w http.ResponseWriter, r *http.Request, id string) {
+	if a.CancelBackfill == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "backfill not available"})
+		return
+	}
+	if err := a.CancelBackfill(r.Context(), id); err != nil {
+		status := statusFromError(err)
+		if strings.Contains(err.Error(), "already") {
+			status = http.StatusConflict
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "cancelled", "id": id})
+}
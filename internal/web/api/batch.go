@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/batch"
+)
+
+type bulkRunRequest struct {
+	Jobs          []string `json:"jobs"`
+	Tag           string   `json:"tag"`
+	Sequential    bool     `json:"sequential"`
+	StopOnFailure bool     `json:"stop_on_failure"`
+}
+
+type batchItemResponse struct {
+	JobName string `json:"job_name"`
+	Status  string `json:"status"`
+	RunID   string `json:"run_id,omitempty"`
+}
+
+type batchResponse struct {
+	ID            string              `json:"id"`
+	Sequential    bool                `json:"sequential"`
+	StopOnFailure bool                `json:"stop_on_failure"`
+	Status        string              `json:"status"`
+	CreatedAt     time.Time           `json:"created_at"`
+	FinishedAt    *time.Time          `json:"finished_at,omitempty"`
+	Items         []batchItemResponse `json:"items"`
+}
+
+func toBatchResponse(b *batch.Batch) batchResponse {
+	resp := batchResponse{
+		ID:            b.ID,
+		Sequential:    b.Sequential,
+		StopOnFailure: b.StopOnFailure,
+		Status:        b.Status,
+		CreatedAt:     b.CreatedAt,
+		FinishedAt:    b.FinishedAt,
+		Items:         make([]batchItemResponse, 0, len(b.Items)),
+	}
+	for _, it := range b.Items {
+		resp.Items = append(resp.Items, batchItemResponse{
+			JobName: it.JobName,
+			Status:  it.Status,
+			RunID:   it.RunID,
+		})
+	}
+	return resp
+}
+
+// handleBulkRun triggers several jobs at once: POST /api/v1/jobs/run with
+// either a list of job names or a tag. Other methods fall through to the
+// per-job routes so a job named "run" stays reachable.
+func (a *API) handleBulkRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		a.routeJobs(w, r)
+		return
+	}
+	if a.CreateBatch == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "bulk run not available"})
+		return
+	}
+
+	var req bulkRunRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1024*1024)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	req.Tag = strings.TrimSpace(req.Tag)
+	if (len(req.Jobs) == 0) == (req.Tag == "") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "exactly one of jobs or tag is required"})
+		return
+	}
+
+	var names []string
+	gated := make(map[string]bool)
+	if req.Tag != "" {
+		for _, j := range a.Jobs() {
+			if j.HasTag(req.Tag) {
+				names = append(names, j.Name)
+				gated[j.Name] = j.RequireApproval
+			}
+		}
+		if len(names) == 0 {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "no jobs found with tag " + req.Tag})
+			return
+		}
+		sort.Strings(names)
+	} else {
+		known := make(map[string]bool)
+		for _, j := range a.Jobs() {
+			known[j.Name] = true
+			gated[j.Name] = j.RequireApproval
+		}
+		seen := make(map[string]bool)
+		var missing []string
+		for _, name := range req.Jobs {
+			name = strings.TrimSpace(name)
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			if !known[name] {
+				missing = append(missing, name)
+				continue
+			}
+			names = append(names, name)
+		}
+		if len(missing) > 0 {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found: " + strings.Join(missing, ", ")})
+			return
+		}
+	}
+	// Approval-gated jobs must go through POST /api/v1/jobs/{name}/run.
+	var needApproval []string
+	for _, name := range names {
+		if gated[name] {
+			needApproval = append(needApproval, name)
+		}
+	}
+	if len(needApproval) > 0 {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "jobs require approval and cannot be bulk run: " + strings.Join(needApproval, ", ")})
+		return
+	}
+
+	b, err := a.CreateBatch(names, req.Sequential, req.StopOnFailure)
+	if err != nil {
+		writeJSON(w, statusFromError(err), map[string]string{"error": err.Error()})
+		return
+	}
+	log.Printf("bulk run %s triggered for %d job(s)", b.ID, len(names))
+	writeJSON(w, http.StatusAccepted, toBatchResponse(b))
+}
+
+// handleGetBatch serves GET /api/v1/batches/{id}.
+func (a *API) handleGetBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if a.GetBatch == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "bulk run not available"})
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/api/v1/batches/")
+	b := a.GetBatch(id)
+	if id == "" || b == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "batch not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, toBatchResponse(b))
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/predict"
+	"github.com/patrickspencer/cronbat/internal/scheduler"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	// collisionWarnAt is how many other jobs may fire in the same minute
+	// as a saved job before the save warns about it.
+	collisionWarnAt = 3
+	// conflictHorizon is how far ahead schedules are compared; a week
+	// covers weekly schedules.
+	conflictHorizon = 7 * 24 * time.Hour
+)
+
+// scheduleWarnings returns warnings about a saved job's schedule, for the
+// response to a change: the job fires in the same minute as
+// collisionWarnAt or more other jobs, or its recent runs take longer on
+// average than the time between its fires.
+func (a *API) scheduleWarnings(ctx context.Context, name string) []string {
+	var expr, dstPolicy string
+	found := false
+	for _, j := range a.Jobs() {
+		if j.Name == name {
+			expr, dstPolicy, found = j.Schedule, j.DSTPolicy, true
+			break
+		}
+	}
+	if !found || expr == "" {
+		return nil
+	}
+	policy, err := scheduler.ParseDSTPolicy(dstPolicy)
+	if err != nil {
+		return nil
+	}
+	schedule, err := scheduler.ParseScheduleWithDST(expr, policy)
+	if err != nil {
+		return nil
+	}
+
+	var warnings []string
+	now := time.Now()
+	horizon := now.Add(conflictHorizon)
+	if a.Schedules != nil {
+		others := make(map[string]cron.Schedule)
+		for other, s := range a.Schedules() {
+			if other != name {
+				others[other] = s
+			}
+		}
+		if c, ok := predict.WorstCollision(schedule, others, now, horizon); ok && len(c.Jobs) >= collisionWarnAt {
+			warnings = append(warnings, fmt.Sprintf("schedule fires at %s together with %d other jobs (%s); consider moving it to a quieter minute",
+				c.At.Format("2006-01-02 15:04 MST"), len(c.Jobs), strings.Join(c.Jobs, ", ")))
+		}
+	}
+
+	if a.Store != nil {
+		durations, err := a.Store.RecentDurations(ctx, name, predict.Samples)
+		if err != nil {
+			log.Printf("ERROR: failed to get run durations for %s: %v", name, err)
+		} else if len(durations) >= predict.MinSamples {
+			var total int64
+			for _, d := range durations {
+				total += d
+			}
+			avg := time.Duration(total/int64(len(durations))) * time.Millisecond
+			if interval := predict.MinInterval(schedule, now, horizon); interval > 0 && avg > interval {
+				warnings = append(warnings, fmt.Sprintf("average run duration %s exceeds the schedule interval of %s; runs will overlap or be skipped",
+					avg.Round(time.Second), interval))
+			}
+		}
+	}
+	return warnings
+}
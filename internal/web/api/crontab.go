@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+)
+
+// handleJobCrontab previews the crontab entry cron-sync would install for a
+// job. Jobs cron cannot run, such as one-shots or commands spanning
+// several lines, are 422.
+func (a *API) handleJobCrontab(w http.ResponseWriter, r *http.Request, name string) {
+	if a.CrontabLine == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "crontab preview unavailable"})
+		return
+	}
+	var job *config.Job
+	for _, j := range a.Jobs() {
+		if j.Name == name {
+			job = j
+			break
+		}
+	}
+	if job == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found"})
+		return
+	}
+
+	line, err := a.CrontabLine(job)
+	if err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	// Disabled jobs are left out of the crontab.
+	writeJSON(w, http.StatusOK, map[string]any{
+		"job":       name,
+		"line":      line,
+		"installed": job.IsEnabled(),
+	})
+}
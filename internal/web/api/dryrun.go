@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/runner"
+)
+
+// syntaxCheckTimeout bounds the "sh -n" check of a dry run.
+const syntaxCheckTimeout = 5 * time.Second
+
+// DryRun describes how a job's next manual run would execute.
+type DryRun struct {
+	Job     string `json:"job"`
+	Version string `json:"version"`
+	// Pinned is set when the run would use the pinned last known good
+	// definition instead of the job file.
+	Pinned     bool     `json:"pinned"`
+	Enabled    bool     `json:"enabled"`
+	Command    string   `json:"command"`
+	Argv       []string `json:"argv"`
+	Shell      string   `json:"shell"`
+	LoginShell bool     `json:"login_shell,omitempty"`
+	Executor   string   `json:"executor"`
+	WorkingDir string   `json:"working_dir,omitempty"`
+	// Env is what the job adds on top of the daemon's own environment,
+	// which the process also inherits.
+	Env         map[string]string     `json:"env"`
+	Timeout     string                `json:"timeout,omitempty"`
+	User        string                `json:"user,omitempty"`
+	Group       string                `json:"group,omitempty"`
+	Sandbox     *config.SandboxConfig `json:"sandbox,omitempty"`
+	SyntaxCheck *syntaxCheckResponse  `json:"syntax_check,omitempty"`
+}
+
+type syntaxCheckResponse struct {
+	OK     bool   `json:"ok"`
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type dryRunRequest struct {
+	SyntaxCheck bool `json:"syntax_check"`
+}
+
+// handleDryRun serves POST /api/v1/jobs/{name}/dry-run: it resolves the
+// run's execution context without running anything. With
+// {"syntax_check": true} the command is also parsed with "sh -n".
+func (a *API) handleDryRun(w http.ResponseWriter, r *http.Request, name string) {
+	if a.DryRunJob == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "dry run not available"})
+		return
+	}
+	var req dryRunRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+
+	plan, err := a.DryRunJob(r.Context(), name)
+	if err != nil {
+		writeJSON(w, statusFromError(err), map[string]string{"error": err.Error()})
+		return
+	}
+	if req.SyntaxCheck {
+		ctx, cancel := context.WithTimeout(r.Context(), syntaxCheckTimeout)
+		ok, out, err := runner.CheckSyntax(ctx, plan.Shell, plan.Command)
+		cancel()
+		plan.SyntaxCheck = &syntaxCheckResponse{OK: ok, Output: out}
+		if err != nil {
+			plan.SyntaxCheck.Error = err.Error()
+		}
+	}
+	writeJSON(w, http.StatusOK, plan)
+}
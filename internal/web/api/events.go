@@ -32,6 +32,8 @@ func (a *API) handleEvents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	disableDeadlines(w)
+
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
@@ -51,6 +53,13 @@ func (a *API) handleEvents(w http.ResponseWriter, r *http.Request) {
 		select {
 		case <-r.Context().Done():
 			return
+		case <-a.Events.Done():
+			// Tell the client to reconnect soon rather than waiting for its
+			// own backoff once the connection drops.
+			payload, _ := json.Marshal(realtime.Event{Type: "server.shutdown", At: time.Now().UTC()})
+			_, _ = fmt.Fprintf(w, "retry: 2000\nevent: server.shutdown\ndata: %s\n\n", payload)
+			flusher.Flush()
+			return
 		case evt, ok := <-events:
 			if !ok {
 				return
@@ -70,3 +79,11 @@ func (a *API) handleEvents(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 }
+
+// disableDeadlines clears the server read/write deadlines for a long-lived
+// streaming response so the configured HTTP timeouts do not cut it off.
+func disableDeadlines(w http.ResponseWriter) {
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+}
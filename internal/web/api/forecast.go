@@ -0,0 +1,151 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/predict"
+)
+
+const (
+	defaultForecastHours = 24
+	maxForecastHours     = 7 * 24
+	// defaultHeavyAfter is the typical duration from which a job counts as
+	// heavy unless ?heavy= says otherwise.
+	defaultHeavyAfter = 5 * time.Minute
+)
+
+type forecastResponse struct {
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+	Hours int       `json:"hours"`
+	// HeavyAfterMs is the typical duration from which jobs are heavy.
+	HeavyAfterMs    int64             `json:"heavy_after_ms"`
+	TotalFires      int               `json:"total_fires"`
+	PeakConcurrency int               `json:"peak_concurrency"`
+	PeakAt          *time.Time        `json:"peak_at,omitempty"`
+	Jobs            []forecastJob     `json:"jobs"`
+	Minutes         []forecastMinute  `json:"minutes"`
+	Overlaps        []forecastOverlap `json:"overlaps"`
+}
+
+type forecastJob struct {
+	Job   string `json:"job"`
+	Fires int    `json:"fires"`
+	// Samples is the number of successful runs P50DurationMs is based on;
+	// below predict.MinSamples runs are assumed to fit in their minute.
+	Samples       int   `json:"samples"`
+	P50DurationMs int64 `json:"p50_duration_ms"`
+	Heavy         bool  `json:"heavy"`
+	// Truncated reports that only the first predict.MaxFiresPerJob fires
+	// were counted.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+type forecastMinute struct {
+	At          time.Time `json:"at"`
+	Concurrency int       `json:"concurrency"`
+	Jobs        []string  `json:"jobs"`
+	Starting    []string  `json:"starting,omitempty"`
+}
+
+type forecastOverlap struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	Jobs []string  `json:"jobs"`
+}
+
+// handleForecast serves GET /api/v1/forecast?hours=24: every scheduled fire
+// in the coming hours, the jobs expected to run in each minute, and the
+// stretches where heavy jobs (typical duration of at least ?heavy=, default
+// 5m) overlap. Minutes without expected runs are left out.
+func (a *API) handleForecast(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if a.Schedules == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "forecast unavailable"})
+		return
+	}
+	hours := defaultForecastHours
+	if raw := r.URL.Query().Get("hours"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxForecastHours {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "hours must be between 1 and " + strconv.Itoa(maxForecastHours)})
+			return
+		}
+		hours = n
+	}
+	heavyAfter := defaultHeavyAfter
+	if raw := r.URL.Query().Get("heavy"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid heavy duration"})
+			return
+		}
+		heavyAfter = d
+	}
+
+	schedules := a.Schedules()
+	names := make([]string, 0, len(schedules))
+	for name := range schedules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resp := forecastResponse{
+		Hours:        hours,
+		HeavyAfterMs: heavyAfter.Milliseconds(),
+		Jobs:         []forecastJob{},
+		Minutes:      []forecastMinute{},
+		Overlaps:     []forecastOverlap{},
+	}
+	jobs := make([]predict.ForecastJob, 0, len(names))
+	for _, name := range names {
+		fj := forecastJob{Job: name}
+		if a.Store != nil {
+			durations, err := a.Store.RecentDurations(r.Context(), name, predict.Samples)
+			if err != nil {
+				log.Printf("ERROR: failed to get run durations for %s: %v", name, err)
+				writeJSON(w, statusFromError(err), map[string]string{"error": err.Error()})
+				return
+			}
+			fj.Samples = len(durations)
+			if fj.Samples >= predict.MinSamples {
+				fj.P50DurationMs = predict.Percentile(durations, 50)
+			}
+		}
+		typical := time.Duration(fj.P50DurationMs) * time.Millisecond
+		fj.Heavy = typical >= heavyAfter
+		resp.Jobs = append(resp.Jobs, fj)
+		jobs = append(jobs, predict.ForecastJob{Name: name, Schedule: schedules[name], Typical: typical, Heavy: fj.Heavy})
+	}
+
+	resp.From = time.Now().UTC()
+	resp.To = resp.From.Add(time.Duration(hours) * time.Hour)
+	f := predict.ForecastRuns(jobs, resp.From, resp.To)
+	truncated := make(map[string]bool, len(f.Truncated))
+	for _, name := range f.Truncated {
+		truncated[name] = true
+	}
+	for i := range resp.Jobs {
+		resp.Jobs[i].Fires = f.Fires[resp.Jobs[i].Job]
+		resp.Jobs[i].Truncated = truncated[resp.Jobs[i].Job]
+		resp.TotalFires += resp.Jobs[i].Fires
+	}
+	for _, m := range f.Minutes {
+		resp.Minutes = append(resp.Minutes, forecastMinute{At: m.At.UTC(), Concurrency: len(m.Jobs), Jobs: m.Jobs, Starting: m.Starting})
+		if len(m.Jobs) > resp.PeakConcurrency {
+			at := m.At.UTC()
+			resp.PeakConcurrency, resp.PeakAt = len(m.Jobs), &at
+		}
+	}
+	for _, o := range f.Overlaps {
+		resp.Overlaps = append(resp.Overlaps, forecastOverlap{From: o.From.UTC(), To: o.To.UTC(), Jobs: o.Jobs})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
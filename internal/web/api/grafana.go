@@ -0,0 +1,274 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/store"
+)
+
+// Grafana series per job, named "<job>:<metric>".
+const (
+	grafanaDuration    = "duration_ms"
+	grafanaSuccessRate = "success_rate"
+	grafanaRuns        = "runs"
+	grafanaFailures    = "failures"
+)
+
+var grafanaMetrics = []string{grafanaDuration, grafanaSuccessRate, grafanaRuns, grafanaFailures}
+
+// maxGrafanaRuns bounds how many runs one series or annotation query reads.
+const maxGrafanaRuns = 10000
+
+// minGrafanaBucket is the smallest bucket for the counted series.
+const minGrafanaBucket = time.Minute
+
+type grafanaRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+type grafanaQueryRequest struct {
+	Range         grafanaRange `json:"range"`
+	IntervalMs    int64        `json:"intervalMs"`
+	MaxDataPoints int          `json:"maxDataPoints"`
+	Targets       []struct {
+		Target string `json:"target"`
+		RefID  string `json:"refId"`
+	} `json:"targets"`
+}
+
+type grafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+type grafanaAnnotationRequest struct {
+	Range      grafanaRange `json:"range"`
+	Annotation struct {
+		Name  string `json:"name"`
+		Query string `json:"query"`
+	} `json:"annotation"`
+}
+
+type grafanaAnnotation struct {
+	Annotation any      `json:"annotation"`
+	Time       int64    `json:"time"`
+	TimeEnd    int64    `json:"timeEnd,omitempty"`
+	Title      string   `json:"title"`
+	Text       string   `json:"text"`
+	Tags       []string `json:"tags"`
+}
+
+// routeGrafana serves the Grafana simple JSON datasource protocol under
+// /api/v1/grafana: GET / checks the connection, POST /search lists series,
+// POST /query returns them, and POST /annotations returns failed runs.
+func (a *API) routeGrafana(w http.ResponseWriter, r *http.Request) {
+	action := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/grafana"), "/")
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	case action == "search" && r.Method == http.MethodPost:
+		a.handleGrafanaSearch(w, r)
+	case action == "query" && r.Method == http.MethodPost:
+		a.handleGrafanaQuery(w, r)
+	case action == "annotations" && r.Method == http.MethodPost:
+		a.handleGrafanaAnnotations(w, r)
+	case action == "search" || action == "query" || action == "annotations" || action == "":
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+func (a *API) handleGrafanaSearch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Target string `json:"target"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body: " + err.Error()})
+		return
+	}
+	filter := strings.ToLower(req.Target)
+
+	var names []string
+	for _, j := range a.Jobs() {
+		for _, m := range grafanaMetrics {
+			name := j.Name + ":" + m
+			if strings.Contains(strings.ToLower(name), filter) {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	if names == nil {
+		names = []string{}
+	}
+	writeJSON(w, http.StatusOK, names)
+}
+
+func (a *API) handleGrafanaQuery(w http.ResponseWriter, r *http.Request) {
+	var req grafanaQueryRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body: " + err.Error()})
+		return
+	}
+	if !req.Range.To.After(req.Range.From) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid range"})
+		return
+	}
+	bucket := time.Duration(req.IntervalMs) * time.Millisecond
+	if bucket <= 0 && req.MaxDataPoints > 0 {
+		bucket = req.Range.To.Sub(req.Range.From) / time.Duration(req.MaxDataPoints)
+	}
+	if bucket < minGrafanaBucket {
+		bucket = minGrafanaBucket
+	}
+
+	result := make([]grafanaSeries, 0, len(req.Targets))
+	for _, t := range req.Targets {
+		i := strings.LastIndex(t.Target, ":")
+		if i <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid target " + t.Target + ": want <job>:<metric>"})
+			return
+		}
+		job, metric := t.Target[:i], t.Target[i+1:]
+		runs, err := a.Store.ListRuns(r.Context(), store.ListOpts{
+			JobName: job,
+			Since:   req.Range.From,
+			Until:   req.Range.To,
+			Limit:   maxGrafanaRuns,
+		})
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list runs"})
+			return
+		}
+		points, ok := grafanaDatapoints(runs, metric, req.Range.From, bucket)
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid metric " + metric})
+			return
+		}
+		result = append(result, grafanaSeries{Target: t.Target, Datapoints: points})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// grafanaDatapoints turns runs (newest first) into [value, unix ms] pairs,
+// oldest first. Durations are one point per finished run; the other
+// metrics are per bucket, and success_rate skips buckets without finished
+// runs.
+func grafanaDatapoints(runs []*store.Run, metric string, from time.Time, bucket time.Duration) ([][2]float64, bool) {
+	points := [][2]float64{}
+	if metric == grafanaDuration {
+		for i := len(runs) - 1; i >= 0; i-- {
+			if runs[i].FinishedAt == nil {
+				continue
+			}
+			points = append(points, [2]float64{float64(runs[i].DurationMs), float64(runs[i].StartedAt.UnixMilli())})
+		}
+		return points, true
+	}
+	if metric != grafanaSuccessRate && metric != grafanaRuns && metric != grafanaFailures {
+		return nil, false
+	}
+
+	type counts struct{ runs, successes, failures int }
+	buckets := make(map[int64]*counts)
+	for _, run := range runs {
+		key := from.Add(run.StartedAt.Sub(from) / bucket * bucket).UnixMilli()
+		c := buckets[key]
+		if c == nil {
+			c = &counts{}
+			buckets[key] = c
+		}
+		c.runs++
+		switch run.Status {
+		case "success":
+			c.successes++
+		case "failure":
+			c.failures++
+		}
+	}
+	keys := make([]int64, 0, len(buckets))
+	for k := range buckets {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	for _, k := range keys {
+		c := buckets[k]
+		var v float64
+		switch metric {
+		case grafanaRuns:
+			v = float64(c.runs)
+		case grafanaFailures:
+			v = float64(c.failures)
+		case grafanaSuccessRate:
+			if c.successes+c.failures == 0 {
+				continue
+			}
+			v = 100 * float64(c.successes) / float64(c.successes+c.failures)
+		}
+		points = append(points, [2]float64{v, float64(k)})
+	}
+	return points, true
+}
+
+// handleGrafanaAnnotations returns failed runs in the range; the
+// annotation's query, if set, is a job name.
+func (a *API) handleGrafanaAnnotations(w http.ResponseWriter, r *http.Request) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&raw); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body: " + err.Error()})
+		return
+	}
+	var req grafanaAnnotationRequest
+	var echo struct {
+		Annotation any `json:"annotation"`
+	}
+	if err := json.Unmarshal(raw, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body: " + err.Error()})
+		return
+	}
+	_ = json.Unmarshal(raw, &echo)
+
+	runs, err := a.Store.ListRuns(r.Context(), store.ListOpts{
+		JobName: strings.TrimSpace(req.Annotation.Query),
+		Since:   req.Range.From,
+		Until:   req.Range.To,
+		Limit:   maxGrafanaRuns,
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list runs"})
+		return
+	}
+
+	result := []grafanaAnnotation{}
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+		if run.Status != "failure" {
+			continue
+		}
+		text := "exit code " + strconv.Itoa(run.ExitCode)
+		if run.ErrorMsg != "" {
+			text += ": " + run.ErrorMsg
+		}
+		ann := grafanaAnnotation{
+			Annotation: echo.Annotation,
+			Time:       run.StartedAt.UnixMilli(),
+			Title:      run.JobName + " failed",
+			Text:       text + " (run " + run.ID + ")",
+			Tags:       []string{run.JobName, run.Status, run.Trigger},
+		}
+		if run.FinishedAt != nil {
+			ann.TimeEnd = run.FinishedAt.UnixMilli()
+		}
+		result = append(result, ann)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
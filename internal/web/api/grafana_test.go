@@ -0,0 +1,47 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/store"
+)
+
+func TestGrafanaDatapointsBucketsRuns(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	finished := from
+	run := func(offset time.Duration, status string) *store.Run {
+		return &store.Run{StartedAt: from.Add(offset), FinishedAt: &finished, Status: status, DurationMs: int64(offset / time.Second)}
+	}
+	// Newest first, as ListRuns returns them.
+	runs := []*store.Run{
+		run(11*time.Minute, "running"),
+		run(10*time.Minute, "failure"),
+		run(2*time.Minute, "failure"),
+		run(1*time.Minute, "success"),
+	}
+	runs[0].FinishedAt = nil
+
+	rate, ok := grafanaDatapoints(runs, grafanaSuccessRate, from, 5*time.Minute)
+	if !ok {
+		t.Fatal("success_rate not recognized")
+	}
+	want := [][2]float64{{50, float64(from.UnixMilli())}, {0, float64(from.Add(10 * time.Minute).UnixMilli())}}
+	if len(rate) != len(want) || rate[0] != want[0] || rate[1] != want[1] {
+		t.Fatalf("success_rate = %v, want %v", rate, want)
+	}
+
+	counts, _ := grafanaDatapoints(runs, grafanaRuns, from, 5*time.Minute)
+	if len(counts) != 2 || counts[0][0] != 2 || counts[1][0] != 2 {
+		t.Fatalf("runs = %v", counts)
+	}
+
+	durations, _ := grafanaDatapoints(runs, grafanaDuration, from, 5*time.Minute)
+	if len(durations) != 3 || durations[0][0] != 60 {
+		t.Fatalf("duration_ms = %v, want 3 finished runs oldest first", durations)
+	}
+
+	if _, ok := grafanaDatapoints(runs, "bogus", from, time.Minute); ok {
+		t.Fatal("unknown metric accepted")
+	}
+}
@@ -1,52 +1,206 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/patrickspencer/cronbat/internal/agent"
+	"github.com/patrickspencer/cronbat/internal/approval"
+	"github.com/patrickspencer/cronbat/internal/batch"
 	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/lint"
+	"github.com/patrickspencer/cronbat/internal/oidc"
 	"github.com/patrickspencer/cronbat/internal/realtime"
+	"github.com/patrickspencer/cronbat/internal/runlog"
+	"github.com/patrickspencer/cronbat/internal/slo"
+	"github.com/patrickspencer/cronbat/internal/spool"
 	"github.com/patrickspencer/cronbat/internal/store"
+	"github.com/patrickspencer/cronbat/internal/supervisor"
+	"github.com/patrickspencer/cronbat/internal/trigger"
+	"github.com/patrickspencer/cronbat/internal/updatecheck"
+	"github.com/robfig/cron/v3"
 )
 
 // API holds dependencies for all API handlers.
 type API struct {
-	Store             store.RunStore
-	Events            *realtime.Broker
-	GetConfig         func() *config.Config
-	Jobs              func() []*config.Job
-	JobState          func(name string) string
-	CreateJob         func(newJob config.Job) error
-	ReadRunLogs       func(jobName string, runID string) (stdout string, stderr string, stdoutPath string, stderrPath string, err error)
-	TriggerRun        func(jobName string)
+	Store           store.RunStore
+	Events          *realtime.Broker
+	GetConfig       func() *config.Config
+	Jobs            func() []*config.Job
+	JobLoadErrors   func() []config.LoadError
+	PurgeJobLogs    func(name string) (files int, bytes int64, err error)
+	JobState        func(name string) string
+	CreateJob       func(newJob config.Job) error
+	ReadRunLogs     func(jobName string, runID string) (stdout string, stderr string, stdoutPath string, stderrPath string, err error)
+	ReadRunLogRange func(jobName, runID, stream string, offset, limit int64) (*runlog.LogRange, error)
+	// TriggerRun queues a manual run and returns its ID. deduped reports
+	// that the trigger fell in the job's dedupe_window and runID is the
+	// earlier run's. correlationID and tc are recorded on the run.
+	TriggerRun        func(jobName, triggeredBy, correlationID string, tc *store.TriggerContext) (runID string, deduped bool)
 	NextRunTime       func(name string) (time.Time, bool)
 	EnableJob         func(name string) error
-	DisableJob        func(name string) error
+	DisableJob        func(name, reason, actor string) error
 	StartJob          func(name string) error
-	StopJob           func(name string) error
-	PauseJob          func(name string) error
+	StopJob           func(name, reason, actor string) error
+	PauseJob          func(name, reason, actor string, until time.Time) error
+	SnoozeUntil       func(name string) (time.Time, bool)
 	ArchiveJob        func(name string) error
 	DeleteJob         func(name string) error
 	GetJobYAML        func(name string) (string, error)
 	UpdateJobYAML     func(name string, data string) (string, error)
 	UpdateJobSettings func(name string, updated config.Job) error
+	Readiness         *Readiness
+	StoreStats        func(ctx context.Context) (*store.DBStats, error)
+	CompactStore      func(ctx context.Context) (*store.CompactResult, error)
+	CreateBackfill    func(ctx context.Context, jobName string, from, to time.Time, interval string, parallelism int) (*store.Backfill, error)
+	ListBackfills     func(ctx context.Context, jobName string) ([]*store.Backfill, error)
+	GetBackfill       func(ctx context.Context, id string) (*store.Backfill, []store.BackfillWindow, error)
+	CancelBackfill    func(ctx context.Context, id string) error
+	LastGoodJob       func(ctx context.Context, name string) (*store.JobSnapshot, error)
+	SetJobPinned      func(ctx context.Context, name string, pinned bool) (*store.JobSnapshot, error)
+	PinRunLogs        func(ctx context.Context, id string, pinned bool) (*store.Run, error)
+	VerifyRunLogs     func(run *store.Run) []runlog.LogCheck
+	EvaluateSLO       func(ctx context.Context, j *config.Job) (*slo.Report, error)
+	ImportJobs        func(creates, updates []config.Job, deletes []string) error
+	// UpdateJobs calls update on a copy of each named job while holding the
+	// daemon's job lock, then saves the changed copies as one import, so a
+	// concurrent edit is never reverted. update must not modify the job's
+	// maps or slices in place.
+	UpdateJobs func(names []string, update func(j *config.Job) error) error
+	// CheckJob applies command_policy to imported jobs, so a dry run
+	// reports violations too; nil allows every job.
+	CheckJob         func(j *config.Job) error
+	ListAnnotations  func(ctx context.Context, jobName string, limit int) ([]*store.Annotation, error)
+	AddAnnotation    func(ctx context.Context, n *store.Annotation) error
+	DeleteAnnotation func(ctx context.Context, jobName string, id int64) error
+	GetRunContext    func(ctx context.Context, runID string) (*store.RunContext, error)
+	CreateBatch      func(jobNames []string, sequential, stopOnFailure bool) (*batch.Batch, error)
+	GetBatch         func(id string) *batch.Batch
+	// APIKeys name callers for run and audit attribution.
+	APIKeys       []config.APIKeyConfig
+	RecordAudit   func(ctx context.Context, e *store.AuditEntry) error
+	ListAudit     func(ctx context.Context, jobName string, limit int) ([]*store.AuditEntry, error)
+	DryRunJob     func(ctx context.Context, name string) (*DryRun, error)
+	ServiceStatus func(name string) (supervisor.Status, bool)
+	// Approvals hold manual runs of jobs with require_approval.
+	RequestApproval func(jobName, requester, requestedBy string, ttl time.Duration) *approval.Request
+	ListApprovals   func(status string) []*approval.Request
+	GetApproval     func(id string) *approval.Request
+	DecideApproval  func(id string, approve bool, decider, decidedBy string) (*approval.Request, error)
+	// Agents accepts agent connections and lists connected agents; nil
+	// while agent connections are off.
+	Agents *agent.Hub
+	// JobsVersion changes whenever a job is added, removed, edited, or
+	// changes state; with Events it makes the jobs list's ETag.
+	JobsVersion func() uint64
+	// SchedulerState and SetSchedulerPaused read and flip the global
+	// scheduler pause.
+	SchedulerState     func(ctx context.Context) (*store.SchedulerState, error)
+	SetSchedulerPaused func(paused bool, reason, actor string) (*store.SchedulerState, error)
+	// HungAfter is how old a running run's last heartbeat may get before
+	// the run is reported as possibly hung; zero never does.
+	HungAfter time.Duration
+	// ReportRun records a run executed outside the daemon.
+	ReportRun func(ctx context.Context, rep *spool.Report) error
+	// DeepHealth runs the component checks of GET /api/v1/health?deep=1.
+	DeepHealth func(ctx context.Context) []HealthCheck
+	// Storage reports database and run log usage; CleanupRunLogs applies
+	// run log retention now, and is nil when run logs are disabled.
+	Storage        func(ctx context.Context) (*StorageUsage, error)
+	CleanupRunLogs func() (*LogCleanupResult, error)
+	// CrontabLine returns the entry "cronbat cron-sync install" would write
+	// for a job.
+	CrontabLine func(j *config.Job) (string, error)
+	// LintJob checks a job against the lint rules.
+	LintJob func(j *config.Job) []lint.Violation
+	// Sessions holds UI sessions started at /api/v1/auth/login.
+	// RequireAuth makes Protect reject API requests without a valid key or
+	// session; SecureCookie marks the session cookies Secure.
+	Sessions     *Sessions
+	RequireAuth  bool
+	SecureCookie bool
+	// OIDC signs users in with an OpenID Connect provider; nil disables
+	// single sign-on. OIDCRoles maps the provider's groups to roles, and
+	// users in none of them get OIDCDefaultRole, or are refused if it is
+	// empty.
+	OIDC            *oidc.Provider
+	OIDCRoles       map[string]string
+	OIDCDefaultRole string
+	// ActiveRuns lists the runs executing now; QueuedRuns lists the runs
+	// waiting for a slot, in dispatch order.
+	ActiveRuns func() []ActiveRun
+	QueuedRuns func() []QueuedRun
+	// Schedules returns the scheduler's parsed schedule of each job waiting
+	// to fire, for the forecast.
+	Schedules func() map[string]cron.Schedule
+	// Build describes the running binary; UpdateStatus reports the latest
+	// release check and is nil while update_check is off.
+	Build        BuildInfo
+	UpdateStatus func() *updatecheck.Status
+	// ReadOnly serves a replica: every mutating API request except signing
+	// in and out is refused.
+	ReadOnly bool
+	// Triggers lists the registered triggers; SetTriggerEnabled turns one
+	// on or off.
+	Triggers          func() []trigger.Status
+	SetTriggerEnabled func(name string, enabled bool) (trigger.Status, error)
+
+	oidcLogins oidcLogins
 }
 
 // RegisterRoutes registers all API routes on the given ServeMux.
 func (a *API) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/v1/jobs/export", a.handleExportJobs)
 	mux.HandleFunc("/api/v1/jobs/import", a.handleImportJobs)
+	mux.HandleFunc("/api/v1/jobs/errors", a.handleJobLoadErrors)
+	mux.HandleFunc("/api/v1/jobs/run", a.handleBulkRun)
 	mux.HandleFunc("/api/v1/jobs/", a.routeJobs)
 	mux.HandleFunc("/api/v1/jobs", a.handleListJobs)
+	mux.HandleFunc("/api/v1/runs/watch", a.handleWatchRuns)
+	mux.HandleFunc("/api/v1/runs/active", a.handleActiveRuns)
+	mux.HandleFunc("/api/v1/runs/queued", a.handleQueuedRuns)
 	mux.HandleFunc("/api/v1/runs/", a.routeRuns)
 	mux.HandleFunc("/api/v1/runs", a.handleListRuns)
 	mux.HandleFunc("/api/v1/events", a.handleEvents)
 	mux.HandleFunc("/api/v1/config", a.handleConfig)
 	mux.HandleFunc("/api/v1/health", a.handleHealth)
+	mux.HandleFunc("/api/v1/version", a.handleVersion)
+	mux.HandleFunc("/api/v1/ready", a.handleReady)
 	mux.HandleFunc("/api/v1/stats", a.handleStats)
+	mux.HandleFunc("/api/v1/store/stats", a.handleStoreStats)
+	mux.HandleFunc("/api/v1/store/compact", a.handleStoreCompact)
+	mux.HandleFunc("/api/v1/storage", a.handleStorage)
+	mux.HandleFunc("/api/v1/storage/vacuum", a.handleStoreCompact)
+	mux.HandleFunc("/api/v1/storage/cleanup", a.handleCleanupRunLogs)
+	mux.HandleFunc("/api/v1/lint", a.handleLint)
+	mux.HandleFunc("/api/v1/backfills/", a.routeBackfills)
+	mux.HandleFunc("/api/v1/backfills", a.handleListBackfills)
+	mux.HandleFunc("/api/v1/batches/", a.handleGetBatch)
+	mux.HandleFunc("/api/v1/schedule/preview", a.handleSchedulePreview)
+	mux.HandleFunc("/api/v1/schedule/explain", a.handleScheduleExplain)
+	mux.HandleFunc("/api/v1/forecast", a.handleForecast)
+	mux.HandleFunc("/api/v1/audit", a.handleListAudit)
+	mux.HandleFunc("/api/v1/approvals/", a.routeApprovals)
+	mux.HandleFunc("/api/v1/approvals", a.handleListApprovals)
+	mux.HandleFunc("/api/v1/slo", a.handleListSLO)
+	mux.HandleFunc("/api/v1/grafana/", a.routeGrafana)
+	mux.HandleFunc("/api/v1/grafana", a.routeGrafana)
+	mux.HandleFunc("/api/v1/triggers/", a.routeTriggers)
+	mux.HandleFunc("/api/v1/triggers", a.routeTriggers)
+	mux.HandleFunc("/api/v1/agents/connect", a.handleAgentConnect)
+	mux.HandleFunc("/api/v1/agents", a.handleListAgents)
+	mux.HandleFunc("/api/v1/scheduler/", a.routeScheduler)
+	mux.HandleFunc("/api/v1/scheduler", a.routeScheduler)
+	mux.HandleFunc("/api/v1/auth/login", a.handleLogin)
+	mux.HandleFunc("/api/v1/auth/logout", a.handleLogout)
+	mux.HandleFunc("/api/v1/auth/session", a.handleSession)
+	mux.HandleFunc("/api/v1/auth/token", a.handleMintToken)
+	mux.HandleFunc("/api/v1/auth/oidc/login", a.handleOIDCLogin)
+	mux.HandleFunc("/api/v1/auth/oidc/callback", a.handleOIDCCallback)
 }
 
 // routeJobs dispatches /api/v1/jobs/{name}[/action] requests.
@@ -79,10 +233,32 @@ func (a *API) routeJobs(w http.ResponseWriter, r *http.Request) {
 		a.handleEnableJob(w, r, name)
 	case action == "disable" && r.Method == http.MethodPut:
 		a.handleDisableJob(w, r, name)
+	case action == "backfill" && (r.Method == http.MethodPost || r.Method == http.MethodGet):
+		a.handleJobBackfill(w, r, name)
+	case action == "pin-last-good" && r.Method == http.MethodPost:
+		a.handlePinLastGood(w, r, name, true)
+	case action == "pin-last-good" && r.Method == http.MethodDelete:
+		a.handlePinLastGood(w, r, name, false)
+	case action == "dry-run" && r.Method == http.MethodPost:
+		a.handleDryRun(w, r, name)
+	case action == "upcoming" && r.Method == http.MethodGet:
+		a.handleJobUpcoming(w, r, name)
+	case action == "prediction" && r.Method == http.MethodGet:
+		a.handleJobPrediction(w, r, name)
+	case action == "crontab" && r.Method == http.MethodGet:
+		a.handleJobCrontab(w, r, name)
+	case action == "annotations" || strings.HasPrefix(action, "annotations/"):
+		a.handleJobAnnotations(w, r, name, strings.TrimPrefix(strings.TrimPrefix(action, "annotations"), "/"))
+	case action == "logs/purge" && r.Method == http.MethodPost:
+		a.handlePurgeJobLogs(w, r, name)
 	case action == "yaml" && r.Method == http.MethodGet:
 		a.handleGetJobYAML(w, r, name)
 	case action == "yaml" && r.Method == http.MethodPut:
 		a.handleUpdateJobYAML(w, r, name)
+	case action == "schedule" && r.Method == http.MethodPut:
+		a.handleUpdateJobSchedule(w, r, name)
+	case action == "timeout" && r.Method == http.MethodPut:
+		a.handleUpdateJobTimeout(w, r, name)
 	case action == "" && r.Method == http.MethodPut:
 		a.handleUpdateJobSettings(w, r, name)
 	case action == "" && r.Method == http.MethodDelete:
@@ -109,16 +285,23 @@ func (a *API) routeRuns(w http.ResponseWriter, r *http.Request) {
 		action = parts[1]
 	}
 
-	if r.Method != http.MethodGet {
+	switch {
+	case action == "pin" && r.Method == http.MethodPost:
+		a.handlePinRunLogs(w, r, id, true)
+	case action == "pin" && r.Method == http.MethodDelete:
+		a.handlePinRunLogs(w, r, id, false)
+	case r.Method != http.MethodGet:
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
-		return
-	}
-
-	switch action {
-	case "":
+	case action == "":
 		a.handleGetRun(w, r, id)
-	case "logs":
+	case action == "logs":
 		a.handleGetRunLogs(w, r, id)
+	case action == "verify":
+		a.handleVerifyRunLogs(w, r, id)
+	case action == "context":
+		a.handleGetRunContext(w, r, id)
+	case action == "instances":
+		a.handleRunInstances(w, r, id)
 	default:
 		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
 	}
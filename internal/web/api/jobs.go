@@ -2,26 +2,45 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/lint"
 	"github.com/patrickspencer/cronbat/internal/realtime"
+	"github.com/patrickspencer/cronbat/internal/scheduler"
+	"github.com/patrickspencer/cronbat/internal/slo"
 	"github.com/patrickspencer/cronbat/internal/store"
 )
 
 type jobSummary struct {
-	Name          string         `json:"name"`
-	Schedule      string         `json:"schedule"`
-	Command       string         `json:"command"`
-	WorkingDir    string         `json:"working_dir,omitempty"`
-	Executor      string         `json:"executor"`
-	Enabled       bool           `json:"enabled"`
-	State         string         `json:"state,omitempty"`
+	Name string `json:"name"`
+	// Mode is "service" for jobs kept running by the supervisor.
+	Mode     string `json:"mode,omitempty"`
+	Schedule string `json:"schedule"`
+	// ScheduleCron is the cron form of a natural-language schedule.
+	ScheduleCron string `json:"schedule_cron,omitempty"`
+	Command      string `json:"command"`
+	WorkingDir   string `json:"working_dir,omitempty"`
+	Executor     string `json:"executor"`
+	Enabled      bool   `json:"enabled"`
+	State        string `json:"state,omitempty"`
+	// DisabledReason, DisabledBy, and DisabledAt are set while the job is
+	// disabled or paused; auto_disable records the actor "cronbat".
+	DisabledReason string     `json:"disabled_reason,omitempty"`
+	DisabledBy     string     `json:"disabled_by,omitempty"`
+	DisabledAt     *time.Time `json:"disabled_at,omitempty"`
+	// SnoozeUntil is when a snoozed job resumes on its own.
+	SnoozeUntil   *time.Time     `json:"snooze_until,omitempty"`
 	Metadata      map[string]any `json:"metadata,omitempty"`
+	Tags          []string       `json:"tags,omitempty"`
 	NextRun       *time.Time     `json:"next_run,omitempty"`
 	LastRun       *time.Time     `json:"last_run,omitempty"`
 	LastRunStatus string         `json:"last_run_status,omitempty"`
@@ -29,19 +48,105 @@ type jobSummary struct {
 
 type jobDetail struct {
 	jobSummary
-	Timeout   string            `json:"timeout,omitempty"`
-	Env       map[string]string `json:"env,omitempty"`
-	OnSuccess []string          `json:"on_success,omitempty"`
-	OnFailure []string          `json:"on_failure,omitempty"`
-	Stats     *jobStatsResp     `json:"stats,omitempty"`
+	Timeout string `json:"timeout,omitempty"`
+	// EffectiveTimeout is the limit runs get, including defaults.timeout.
+	EffectiveTimeout string   `json:"effective_timeout,omitempty"`
+	WarnAfter        string   `json:"warn_after,omitempty"`
+	DedupeWindow     string   `json:"dedupe_window,omitempty"`
+	Warnings         []string `json:"warnings,omitempty"`
+	// Lint lists the lint rules the job breaks.
+	Lint []lint.Violation `json:"lint,omitempty"`
+	// Version identifies the current definition; LastGood is the
+	// definition of the latest successful run and whether it is pinned.
+	Version     string                    `json:"version"`
+	LastGood    *lastGoodResponse         `json:"last_good,omitempty"`
+	Env         map[string]string         `json:"env,omitempty"`
+	OnSuccess   []string                  `json:"on_success,omitempty"`
+	OnFailure   []string                  `json:"on_failure,omitempty"`
+	User        string                    `json:"user,omitempty"`
+	Group       string                    `json:"group,omitempty"`
+	Sandbox     *config.SandboxConfig     `json:"sandbox,omitempty"`
+	RunsOn      map[string]string         `json:"runs_on,omitempty"`
+	Shell       string                    `json:"shell,omitempty"`
+	LoginShell  bool                      `json:"login_shell,omitempty"`
+	AutoArchive bool                      `json:"auto_archive,omitempty"`
+	AutoDisable *config.AutoDisableConfig `json:"auto_disable,omitempty"`
+	// Service reports the supervisor's view of a running service job.
+	Service     *serviceStatusResp   `json:"service,omitempty"`
+	SLO         *slo.Report          `json:"slo,omitempty"`
+	Annotations []annotationResponse `json:"annotations,omitempty"`
+	Stats       *jobStatsResp        `json:"stats,omitempty"`
+}
+
+type serviceStatusResp struct {
+	Restarts    int       `json:"restarts"`
+	LastStarted time.Time `json:"last_started"`
 }
 
 type jobStatsResp struct {
-	TotalRuns     int        `json:"total_runs"`
-	Successes     int        `json:"successes"`
-	Failures      int        `json:"failures"`
-	LastRun       *time.Time `json:"last_run,omitempty"`
-	AvgDurationMs float64    `json:"avg_duration_ms"`
+	TotalRuns     int               `json:"total_runs"`
+	Successes     int               `json:"successes"`
+	Failures      int               `json:"failures"`
+	Warnings      int               `json:"warnings"`
+	LastRun       *time.Time        `json:"last_run,omitempty"`
+	AvgDurationMs float64           `json:"avg_duration_ms"`
+	Windows       []windowStatsResp `json:"windows,omitempty"`
+}
+
+// windowStatsResp is a job's finished runs over one recent window.
+type windowStatsResp struct {
+	// Window is as requested, e.g. "24h" or "7d".
+	Window        string  `json:"window"`
+	Runs          int     `json:"runs"`
+	Successes     int     `json:"successes"`
+	Warnings      int     `json:"warnings"`
+	SuccessRate   float64 `json:"success_rate"`
+	P50DurationMs int64   `json:"p50_duration_ms"`
+	P95DurationMs int64   `json:"p95_duration_ms"`
+	MaxDurationMs int64   `json:"max_duration_ms"`
+}
+
+// defaultStatsWindows are the windows job details report when
+// ?stats_windows= is not given.
+var defaultStatsWindows = []string{"24h", "7d", "30d"}
+
+// maxStatsWindows bounds how many windows one request may ask for.
+const maxStatsWindows = 8
+
+// parseStatsWindows reads the comma-separated ?stats_windows= list of Go
+// durations or day counts ("7d").
+func parseStatsWindows(r *http.Request) ([]string, []time.Duration, error) {
+	labels := append([]string(nil), defaultStatsWindows...)
+	if v := strings.TrimSpace(r.URL.Query().Get("stats_windows")); v != "" {
+		labels = strings.Split(v, ",")
+	}
+	if len(labels) > maxStatsWindows {
+		return nil, nil, fmt.Errorf("at most %d stats_windows", maxStatsWindows)
+	}
+	windows := make([]time.Duration, len(labels))
+	for i, label := range labels {
+		label = strings.TrimSpace(label)
+		d, err := config.ParseDays(label)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid stats window %q: %v", label, err)
+		}
+		labels[i], windows[i] = label, d
+	}
+	return labels, windows, nil
+}
+
+// handleJobLoadErrors lists job files that were skipped at load because they
+// could not be read or parsed.
+func (a *API) handleJobLoadErrors(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	errs := []config.LoadError{}
+	if a.JobLoadErrors != nil {
+		errs = append(errs, a.JobLoadErrors()...)
+	}
+	writeJSON(w, http.StatusOK, errs)
 }
 
 func (a *API) handleListJobs(w http.ResponseWriter, r *http.Request) {
@@ -51,15 +156,109 @@ func (a *API) handleListJobs(w http.ResponseWriter, r *http.Request) {
 	case http.MethodPost:
 		a.handleCreateJob(w, r)
 		return
+	case http.MethodPatch:
+		a.handlePatchJobs(w, r)
+		return
 	default:
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
 		return
 	}
 
+	q := r.URL.Query()
+	sortKey := q.Get("sort")
+	switch sortKey {
+	case "":
+		sortKey = "name"
+	case "name", "last_run", "next_run", "status", "last_run_status":
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid sort: use name, last_run, next_run, status, or last_run_status"})
+		return
+	}
+	desc := false
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		desc = true
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid order: use asc or desc"})
+		return
+	}
+	limit, offset := 0, 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid limit"})
+			return
+		}
+		limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid offset"})
+			return
+		}
+		offset = n
+	}
+	search := strings.ToLower(strings.TrimSpace(q.Get("q")))
+	var enabled *bool
+	if v := q.Get("enabled"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid enabled: use true or false"})
+			return
+		}
+		enabled = &b
+	}
+	stateFilter := q.Get("state")
+	tag := q.Get("tag")
+	prefix := q.Get("prefix")
+	scheduleFilter := strings.ToLower(q.Get("schedule"))
+	var fields map[string]bool
+	if v := q.Get("fields"); v != "" {
+		fields = map[string]bool{"name": true}
+		for _, f := range strings.Split(v, ",") {
+			f = strings.TrimSpace(f)
+			if !jobSummaryFields[f] {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid fields: unknown field " + strconv.Quote(f)})
+				return
+			}
+			fields[f] = true
+		}
+	}
+	// Last runs are only looked up when they are shown or sorted on.
+	needLastRun := fields == nil || fields["last_run"] || fields["last_run_status"] ||
+		sortKey == "last_run" || sortKey == "last_run_status"
+
+	etag := a.jobsETag()
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "no-cache")
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
 	jobs := a.Jobs()
 	result := make([]jobSummary, 0, len(jobs))
 
 	for _, j := range jobs {
+		if search != "" && !jobMatches(j, search) {
+			continue
+		}
+		if enabled != nil && j.IsEnabled() != *enabled {
+			continue
+		}
+		if tag != "" && !j.HasTag(tag) {
+			continue
+		}
+		if !strings.HasPrefix(j.Name, prefix) {
+			continue
+		}
+		if scheduleFilter != "" && !strings.Contains(strings.ToLower(j.Schedule), scheduleFilter) {
+			continue
+		}
 		state := ""
 		if a.JobState != nil {
 			state = strings.TrimSpace(a.JobState(j.Name))
@@ -73,36 +272,215 @@ func (a *API) handleListJobs(w http.ResponseWriter, r *http.Request) {
 		}
 
 		s := jobSummary{
-			Name:       j.Name,
-			Schedule:   j.Schedule,
-			Command:    j.Command,
-			WorkingDir: j.WorkingDir,
-			Executor:   j.Executor,
-			Enabled:    j.IsEnabled(),
-			State:      state,
-			Metadata:   j.Metadata,
+			Name:           j.Name,
+			Mode:           j.Mode,
+			Schedule:       j.Schedule,
+			ScheduleCron:   naturalScheduleCron(j.Schedule),
+			Command:        j.Command,
+			WorkingDir:     j.WorkingDir,
+			Executor:       j.Executor,
+			Enabled:        j.IsEnabled(),
+			State:          state,
+			Metadata:       j.Metadata,
+			Tags:           j.Tags,
+			DisabledReason: j.DisabledReason,
+			DisabledBy:     j.DisabledBy,
+			DisabledAt:     j.DisabledAt,
 		}
 		if next, ok := a.NextRunTime(j.Name); ok {
 			s.NextRun = &next
 		}
-		if a.Store != nil {
-			runs, err := a.Store.ListRuns(r.Context(), store.ListOpts{
-				JobName: j.Name,
-				Limit:   1,
-			})
-			if err != nil {
-				log.Printf("ERROR: failed to get latest run for %s: %v", j.Name, err)
-			} else if len(runs) > 0 {
-				s.LastRun = &runs[0].StartedAt
-				s.LastRunStatus = runs[0].Status
+		if a.SnoozeUntil != nil {
+			if until, ok := a.SnoozeUntil(j.Name); ok {
+				s.SnoozeUntil = &until
 			}
 		}
+		if stateFilter != "" && state != stateFilter {
+			continue
+		}
 		result = append(result, s)
 	}
 
+	if needLastRun && a.Store != nil && len(result) > 0 {
+		names := make([]string, len(result))
+		for i := range result {
+			names[i] = result[i].Name
+		}
+		latest, err := a.Store.GetLatestRuns(r.Context(), names)
+		if err != nil {
+			log.Printf("ERROR: failed to get latest runs: %v", err)
+		}
+		for i := range result {
+			if run := latest[result[i].Name]; run != nil {
+				result[i].LastRun = &run.StartedAt
+				result[i].LastRunStatus = run.Status
+			}
+		}
+	}
+
+	sortJobSummaries(result, sortKey, desc)
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(result)))
+	if offset > len(result) {
+		offset = len(result)
+	}
+	total := len(result)
+	result = result[offset:]
+	if limit > 0 && limit < len(result) {
+		result = result[:limit]
+	}
+	if limit > 0 {
+		setPageLinks(w, r, offset, limit, total)
+	}
+
+	if fields != nil {
+		writeJSON(w, http.StatusOK, selectFields(result, fields))
+		return
+	}
 	writeJSON(w, http.StatusOK, result)
 }
 
+// jobsETag returns a weak ETag for the jobs list, or "" when the version
+// sources are not wired. It changes with any job change (JobsVersion) and
+// any event, which covers runs starting and finishing; the broker's epoch
+// keeps it from repeating across restarts.
+func (a *API) jobsETag() string {
+	if a.JobsVersion == nil || a.Events == nil {
+		return ""
+	}
+	return fmt.Sprintf(`W/"jobs-%s-%d-%d"`, a.Events.Epoch(), a.JobsVersion(), a.Events.LatestID())
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using
+// the weak comparison GET requests call for.
+func etagMatches(header, etag string) bool {
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.TrimPrefix(v, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// setPageLinks sets a Link header with the next and previous pages of a
+// limit/offset listing.
+func setPageLinks(w http.ResponseWriter, r *http.Request, offset, limit, total int) {
+	page := func(off int, rel string) string {
+		q := r.URL.Query()
+		q.Set("offset", strconv.Itoa(off))
+		q.Set("limit", strconv.Itoa(limit))
+		return fmt.Sprintf(`<%s?%s>; rel="%s"`, r.URL.Path, q.Encode(), rel)
+	}
+	var links []string
+	if offset+limit < total {
+		links = append(links, page(offset+limit, "next"))
+	}
+	if offset > 0 {
+		prev := offset - limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, page(prev, "prev"))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+// jobSummaryFields are the JSON field names of jobSummary, which fields=
+// selects from.
+var jobSummaryFields = func() map[string]bool {
+	out := make(map[string]bool)
+	t := reflect.TypeOf(jobSummary{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		out[name] = true
+	}
+	return out
+}()
+
+// selectFields returns the summaries as JSON objects with only the given
+// fields.
+func selectFields(jobs []jobSummary, fields map[string]bool) []map[string]json.RawMessage {
+	out := make([]map[string]json.RawMessage, 0, len(jobs))
+	for _, j := range jobs {
+		data, err := json.Marshal(j)
+		if err != nil {
+			continue
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(data, &all); err != nil {
+			continue
+		}
+		for k := range all {
+			if !fields[k] {
+				delete(all, k)
+			}
+		}
+		out = append(out, all)
+	}
+	return out
+}
+
+// jobMatches reports whether search (lowercase) is a substring of the job's
+// name, command, tags, or metadata keys and values.
+func jobMatches(j *config.Job, search string) bool {
+	if strings.Contains(strings.ToLower(j.Name), search) ||
+		strings.Contains(strings.ToLower(j.Command), search) {
+		return true
+	}
+	for _, tag := range j.Tags {
+		if strings.Contains(strings.ToLower(tag), search) {
+			return true
+		}
+	}
+	for k, v := range j.Metadata {
+		if strings.Contains(strings.ToLower(k), search) ||
+			strings.Contains(strings.ToLower(fmt.Sprint(v)), search) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortJobSummaries orders jobs by key, then by name. Jobs without a last or
+// next run (or last run status) sort last in either order.
+func sortJobSummaries(jobs []jobSummary, key string, desc bool) {
+	sort.SliceStable(jobs, func(i, k int) bool {
+		a, b := &jobs[i], &jobs[k]
+		var t1, t2 *time.Time
+		switch key {
+		case "last_run":
+			t1, t2 = a.LastRun, b.LastRun
+		case "next_run":
+			t1, t2 = a.NextRun, b.NextRun
+		case "status":
+			if a.State != b.State {
+				return (a.State < b.State) != desc
+			}
+		case "last_run_status":
+			if (a.LastRunStatus == "") != (b.LastRunStatus == "") {
+				return b.LastRunStatus == ""
+			}
+			if a.LastRunStatus != b.LastRunStatus {
+				return (a.LastRunStatus < b.LastRunStatus) != desc
+			}
+		}
+		if key == "last_run" || key == "next_run" {
+			if (t1 == nil) != (t2 == nil) {
+				return t2 == nil
+			}
+			if t1 != nil && !t1.Equal(*t2) {
+				return t1.Before(*t2) != desc
+			}
+		}
+		if a.Name != b.Name {
+			return (a.Name < b.Name) != desc
+		}
+		return false
+	})
+}
+
 func (a *API) handleCreateJob(w http.ResponseWriter, r *http.Request) {
 	if a.CreateJob == nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "create operation not available"})
@@ -125,7 +503,14 @@ func (a *API) handleCreateJob(w http.ResponseWriter, r *http.Request) {
 		JobName: strings.TrimSpace(newJob.Name),
 		Action:  "create",
 	})
-	writeJSON(w, http.StatusCreated, map[string]string{"status": "created", "name": strings.TrimSpace(newJob.Name)})
+	resp := map[string]any{"status": "created", "name": strings.TrimSpace(newJob.Name)}
+	if vs := a.jobLint(strings.TrimSpace(newJob.Name)); len(vs) > 0 {
+		resp["lint"] = vs
+	}
+	if ws := a.scheduleWarnings(r.Context(), strings.TrimSpace(newJob.Name)); len(ws) > 0 {
+		resp["warnings"] = ws
+	}
+	writeJSON(w, http.StatusCreated, resp)
 }
 
 func (a *API) handleGetJob(w http.ResponseWriter, r *http.Request, name string) {
@@ -146,33 +531,93 @@ func (a *API) handleGetJob(w http.ResponseWriter, r *http.Request, name string)
 
 			d := &jobDetail{
 				jobSummary: jobSummary{
-					Name:       j.Name,
-					Schedule:   j.Schedule,
-					Command:    j.Command,
-					WorkingDir: j.WorkingDir,
-					Executor:   j.Executor,
-					Enabled:    j.IsEnabled(),
-					State:      state,
-					Metadata:   j.Metadata,
+					Name:           j.Name,
+					Mode:           j.Mode,
+					Schedule:       j.Schedule,
+					ScheduleCron:   naturalScheduleCron(j.Schedule),
+					Command:        j.Command,
+					WorkingDir:     j.WorkingDir,
+					Executor:       j.Executor,
+					Enabled:        j.IsEnabled(),
+					State:          state,
+					Metadata:       j.Metadata,
+					Tags:           j.Tags,
+					DisabledReason: j.DisabledReason,
+					DisabledBy:     j.DisabledBy,
+					DisabledAt:     j.DisabledAt,
 				},
-				Timeout:   j.Timeout,
-				Env:       j.Env,
-				OnSuccess: j.OnSuccess,
-				OnFailure: j.OnFailure,
+				Timeout:      j.Timeout,
+				Env:          j.Env,
+				OnSuccess:    j.OnSuccess,
+				OnFailure:    j.OnFailure,
+				User:         j.User,
+				Group:        j.Group,
+				Sandbox:      j.Sandbox,
+				RunsOn:       j.RunsOn,
+				Shell:        j.Shell,
+				LoginShell:   j.LoginShell,
+				AutoArchive:  j.AutoArchive,
+				WarnAfter:    j.WarnAfter,
+				DedupeWindow: j.DedupeWindow,
+				AutoDisable:  j.AutoDisable,
+				Warnings:     j.Lint(),
+				Version:      j.Version(),
+			}
+			if a.ServiceStatus != nil && j.IsService() {
+				if st, ok := a.ServiceStatus(j.Name); ok {
+					d.Service = &serviceStatusResp{Restarts: st.Restarts, LastStarted: st.LastStarted.UTC()}
+				}
+			}
+			if a.LintJob != nil {
+				d.Lint = a.LintJob(j)
+			}
+			if a.EvaluateSLO != nil && j.SLO != nil {
+				report, err := a.EvaluateSLO(r.Context(), j)
+				if err != nil {
+					log.Printf("ERROR: failed to evaluate SLO of %s: %v", j.Name, err)
+				}
+				d.SLO = report
+			}
+			if a.ListAnnotations != nil {
+				notes, err := a.ListAnnotations(r.Context(), j.Name, detailAnnotations)
+				if err != nil {
+					log.Printf("ERROR: failed to list annotations of %s: %v", j.Name, err)
+				}
+				for _, n := range notes {
+					d.Annotations = append(d.Annotations, toAnnotationResponse(n))
+				}
+			}
+			if a.LastGoodJob != nil {
+				snap, err := a.LastGoodJob(r.Context(), j.Name)
+				if err != nil {
+					log.Printf("ERROR: failed to get last good version of %s: %v", j.Name, err)
+				}
+				d.LastGood = toLastGoodResponse(snap)
+			}
+			var defaultTimeout time.Duration
+			if a.GetConfig != nil {
+				if cfg := a.GetConfig(); cfg != nil {
+					defaultTimeout, _ = cfg.Defaults.ParseTimeout()
+				}
+			}
+			if timeout, err := j.EffectiveTimeout(defaultTimeout); err == nil && timeout > 0 {
+				d.EffectiveTimeout = timeout.String()
 			}
 			if next, ok := a.NextRunTime(j.Name); ok {
 				d.NextRun = &next
 			}
+			if a.SnoozeUntil != nil {
+				if until, ok := a.SnoozeUntil(j.Name); ok {
+					d.SnoozeUntil = &until
+				}
+			}
 			if a.Store != nil {
-				runs, err := a.Store.ListRuns(r.Context(), store.ListOpts{
-					JobName: j.Name,
-					Limit:   1,
-				})
+				latest, err := a.Store.GetLatestRuns(r.Context(), []string{j.Name})
 				if err != nil {
 					log.Printf("ERROR: failed to get latest run for %s: %v", j.Name, err)
-				} else if len(runs) > 0 {
-					d.LastRun = &runs[0].StartedAt
-					d.LastRunStatus = runs[0].Status
+				} else if run := latest[j.Name]; run != nil {
+					d.LastRun = &run.StartedAt
+					d.LastRunStatus = run.Status
 				}
 			}
 			found = d
@@ -185,7 +630,12 @@ func (a *API) handleGetJob(w http.ResponseWriter, r *http.Request, name string)
 		return
 	}
 
-	stats, err := a.Store.GetJobStats(r.Context(), name)
+	labels, windows, err := parseStatsWindows(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	stats, err := a.Store.GetJobStats(r.Context(), name, windows...)
 	if err != nil {
 		log.Printf("ERROR: failed to get job stats for %s: %v", name, err)
 	} else {
@@ -193,36 +643,135 @@ func (a *API) handleGetJob(w http.ResponseWriter, r *http.Request, name string)
 			TotalRuns:     stats.TotalRuns,
 			Successes:     stats.Successes,
 			Failures:      stats.Failures,
+			Warnings:      stats.Warnings,
 			LastRun:       stats.LastRun,
 			AvgDurationMs: stats.AvgDurationMs,
 		}
+		for i, ws := range stats.Windows {
+			found.Stats.Windows = append(found.Stats.Windows, windowStatsResp{
+				Window:        labels[i],
+				Runs:          ws.Runs,
+				Successes:     ws.Successes,
+				Warnings:      ws.Warnings,
+				SuccessRate:   ws.SuccessRate,
+				P50DurationMs: ws.P50DurationMs,
+				P95DurationMs: ws.P95DurationMs,
+				MaxDurationMs: ws.MaxDurationMs,
+			})
+		}
 	}
 
 	writeJSON(w, http.StatusOK, found)
 }
 
 func (a *API) handleTriggerRun(w http.ResponseWriter, r *http.Request, name string) {
-	var exists bool
+	var job *config.Job
 	for _, j := range a.Jobs() {
 		if j.Name == name {
-			exists = true
+			job = j
 			break
 		}
 	}
-	if !exists {
+	if job == nil {
 		writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found"})
 		return
 	}
+	if job.IsService() {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "service jobs run continuously; use start and stop instead"})
+		return
+	}
+	if job.RequireApproval {
+		a.requestApproval(w, r, job)
+		return
+	}
 
-	go a.TriggerRun(name)
-	log.Printf("manual run triggered for job %s", name)
+	tc := triggerContext(r)
+	correlationID, err := requestCorrelationID(r, tc)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	triggeredBy := a.requestActor(r)
+	runID, deduped := a.TriggerRun(name, triggeredBy, correlationID, tc)
+	if deduped {
+		log.Printf("manual run of job %s by %s folded into run %s (dedupe_window)", name, triggeredBy, runID)
+		writeJSON(w, http.StatusOK, map[string]string{"status": "deduplicated", "run_id": runID})
+		return
+	}
+	log.Printf("manual run triggered for job %s by %s", name, triggeredBy)
+	a.audit(r, "run", name, "")
 	a.emitEvent(realtime.Event{
 		Type:    "job.changed",
 		JobName: name,
 		Action:  "run",
 	})
 
-	writeJSON(w, http.StatusAccepted, map[string]string{"status": "triggered"})
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "triggered", "run_id": runID})
+}
+
+// triggerContext records the request that triggered a run: its headers,
+// without credentials, and the start of its body, so notifications can
+// tell why the job ran.
+func triggerContext(r *http.Request) *store.TriggerContext {
+	tc := &store.TriggerContext{Headers: make(map[string]string, len(r.Header))}
+	for name, values := range r.Header {
+		if isCredentialHeader(name) {
+			continue
+		}
+		tc.Headers[name] = strings.Join(values, ", ")
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, store.MaxTriggerBodyBytes+1))
+	if err != nil {
+		log.Printf("WARN: failed to read trigger request body: %v", err)
+	}
+	tc.SetBody(body)
+	return tc
+}
+
+// CorrelationIDHeader lets a trigger supply a correlation ID without a
+// body.
+const CorrelationIDHeader = "X-Correlation-ID"
+
+// maxCorrelationIDLen bounds the correlation IDs triggers may supply.
+const maxCorrelationIDLen = 128
+
+// requestCorrelationID returns the correlation ID a trigger supplied in
+// the correlation_id query parameter, the X-Correlation-ID header, or the
+// correlation_id field of a JSON body, in that order, or "" if none.
+func requestCorrelationID(r *http.Request, tc *store.TriggerContext) (string, error) {
+	id := r.URL.Query().Get("correlation_id")
+	if id == "" {
+		id = r.Header.Get(CorrelationIDHeader)
+	}
+	if id == "" && tc.Body != "" && !tc.BodyTruncated {
+		var body struct {
+			CorrelationID string `json:"correlation_id"`
+		}
+		if json.Unmarshal([]byte(tc.Body), &body) == nil {
+			id = body.CorrelationID
+		}
+	}
+	id = strings.TrimSpace(id)
+	if len(id) > maxCorrelationIDLen {
+		return "", fmt.Errorf("correlation_id is longer than %d characters", maxCorrelationIDLen)
+	}
+	for _, c := range id {
+		if c < ' ' || c == 0x7f {
+			return "", fmt.Errorf("correlation_id contains control characters")
+		}
+	}
+	return id, nil
+}
+
+// isCredentialHeader reports whether a request header may carry a
+// credential and is kept out of trigger contexts.
+func isCredentialHeader(name string) bool {
+	switch http.CanonicalHeaderKey(name) {
+	case "Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key":
+		return true
+	}
+	upper := strings.ToUpper(name)
+	return strings.Contains(upper, "TOKEN") || strings.Contains(upper, "SECRET") || strings.Contains(upper, "PASSWORD")
 }
 
 func (a *API) handleEnableJob(w http.ResponseWriter, r *http.Request, name string) {
@@ -230,6 +779,7 @@ func (a *API) handleEnableJob(w http.ResponseWriter, r *http.Request, name strin
 		writeJSON(w, statusFromError(err), map[string]string{"error": err.Error()})
 		return
 	}
+	a.audit(r, "enable", name, "")
 	a.emitEvent(realtime.Event{
 		Type:    "job.changed",
 		JobName: name,
@@ -239,10 +789,20 @@ func (a *API) handleEnableJob(w http.ResponseWriter, r *http.Request, name strin
 }
 
 func (a *API) handleDisableJob(w http.ResponseWriter, r *http.Request, name string) {
-	if err := a.DisableJob(name); err != nil {
+	req, err := a.readDisableRequest(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if !req.snoozeUntil.IsZero() {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request: only pause can snooze a job"})
+		return
+	}
+	if err := a.DisableJob(name, req.Reason, req.Actor); err != nil {
 		writeJSON(w, statusFromError(err), map[string]string{"error": err.Error()})
 		return
 	}
+	a.audit(r, "disable", name, req.Reason)
 	a.emitEvent(realtime.Event{
 		Type:    "job.changed",
 		JobName: name,
@@ -258,6 +818,10 @@ func statusFromError(err error) int {
 
 	msg := strings.ToLower(err.Error())
 	switch {
+	case strings.Contains(msg, "command_policy"):
+		return http.StatusForbidden
+	case strings.Contains(msg, "lint: "):
+		return http.StatusUnprocessableEntity
 	case strings.Contains(msg, "not found"):
 		return http.StatusNotFound
 	case strings.Contains(msg, "already exists"):
@@ -271,7 +835,7 @@ func statusFromError(err error) int {
 	}
 }
 
-func (a *API) handleStartJob(w http.ResponseWriter, _ *http.Request, name string) {
+func (a *API) handleStartJob(w http.ResponseWriter, r *http.Request, name string) {
 	fn := a.StartJob
 	if fn == nil {
 		fn = a.EnableJob
@@ -284,6 +848,7 @@ func (a *API) handleStartJob(w http.ResponseWriter, _ *http.Request, name string
 		writeJSON(w, statusFromError(err), map[string]string{"error": err.Error()})
 		return
 	}
+	a.audit(r, "start", name, "")
 	a.emitEvent(realtime.Event{
 		Type:    "job.changed",
 		JobName: name,
@@ -292,7 +857,7 @@ func (a *API) handleStartJob(w http.ResponseWriter, _ *http.Request, name string
 	writeJSON(w, http.StatusOK, map[string]string{"status": "started"})
 }
 
-func (a *API) handleStopJob(w http.ResponseWriter, _ *http.Request, name string) {
+func (a *API) handleStopJob(w http.ResponseWriter, r *http.Request, name string) {
 	fn := a.StopJob
 	if fn == nil {
 		fn = a.DisableJob
@@ -301,10 +866,20 @@ func (a *API) handleStopJob(w http.ResponseWriter, _ *http.Request, name string)
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "stop operation not available"})
 		return
 	}
-	if err := fn(name); err != nil {
+	req, err := a.readDisableRequest(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if !req.snoozeUntil.IsZero() {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request: only pause can snooze a job"})
+		return
+	}
+	if err := fn(name, req.Reason, req.Actor); err != nil {
 		writeJSON(w, statusFromError(err), map[string]string{"error": err.Error()})
 		return
 	}
+	a.audit(r, "stop", name, req.Reason)
 	a.emitEvent(realtime.Event{
 		Type:    "job.changed",
 		JobName: name,
@@ -313,19 +888,27 @@ func (a *API) handleStopJob(w http.ResponseWriter, _ *http.Request, name string)
 	writeJSON(w, http.StatusOK, map[string]string{"status": "stopped"})
 }
 
-func (a *API) handlePauseJob(w http.ResponseWriter, _ *http.Request, name string) {
+func (a *API) handlePauseJob(w http.ResponseWriter, r *http.Request, name string) {
 	fn := a.PauseJob
-	if fn == nil {
-		fn = a.DisableJob
+	if fn == nil && a.DisableJob != nil {
+		fn = func(name, reason, actor string, _ time.Time) error {
+			return a.DisableJob(name, reason, actor)
+		}
 	}
 	if fn == nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "pause operation not available"})
 		return
 	}
-	if err := fn(name); err != nil {
+	req, err := a.readDisableRequest(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if err := fn(name, req.Reason, req.Actor, req.snoozeUntil); err != nil {
 		writeJSON(w, statusFromError(err), map[string]string{"error": err.Error()})
 		return
 	}
+	a.audit(r, "pause", name, req.Reason)
 	a.emitEvent(realtime.Event{
 		Type:    "job.changed",
 		JobName: name,
@@ -351,6 +934,25 @@ func (a *API) handleDeleteJob(w http.ResponseWriter, _ *http.Request, name strin
 	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
 
+type purgeLogsResponse struct {
+	Status       string `json:"status"`
+	RemovedFiles int    `json:"removed_files"`
+	RemovedBytes int64  `json:"removed_bytes"`
+}
+
+func (a *API) handlePurgeJobLogs(w http.ResponseWriter, _ *http.Request, name string) {
+	if a.PurgeJobLogs == nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "log purge not available"})
+		return
+	}
+	files, bytes, err := a.PurgeJobLogs(name)
+	if err != nil {
+		writeJSON(w, statusFromError(err), map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, purgeLogsResponse{Status: "purged", RemovedFiles: files, RemovedBytes: bytes})
+}
+
 func (a *API) handleArchiveJob(w http.ResponseWriter, _ *http.Request, name string) {
 	if a.ArchiveJob == nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "archive operation not available"})
@@ -428,10 +1030,17 @@ func (a *API) handleUpdateJobYAML(w http.ResponseWriter, r *http.Request, name s
 		JobName: updatedName,
 		Action:  "update_yaml",
 	})
-	writeJSON(w, http.StatusOK, map[string]string{
+	resp := map[string]any{
 		"status": "updated",
 		"name":   updatedName,
-	})
+	}
+	if vs := a.jobLint(updatedName); len(vs) > 0 {
+		resp["lint"] = vs
+	}
+	if ws := a.scheduleWarnings(r.Context(), updatedName); len(ws) > 0 {
+		resp["warnings"] = ws
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 func (a *API) handleUpdateJobSettings(w http.ResponseWriter, r *http.Request, name string) {
@@ -462,5 +1071,25 @@ func (a *API) handleUpdateJobSettings(w http.ResponseWriter, r *http.Request, na
 		JobName: name,
 		Action:  "update_settings",
 	})
-	writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+	resp := map[string]any{"status": "updated"}
+	if vs := a.jobLint(name); len(vs) > 0 {
+		resp["lint"] = vs
+	}
+	if ws := a.scheduleWarnings(r.Context(), name); len(ws) > 0 {
+		resp["warnings"] = ws
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// naturalScheduleCron returns the cron form of a natural-language schedule,
+// or "" for cron expressions and unparsable schedules.
+func naturalScheduleCron(schedule string) string {
+	if !scheduler.IsNatural(schedule) {
+		return ""
+	}
+	normalized, err := scheduler.Normalize(schedule)
+	if err != nil {
+		return ""
+	}
+	return normalized
 }
@@ -0,0 +1,282 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/lint"
+	"github.com/patrickspencer/cronbat/internal/realtime"
+)
+
+// jobsPatch is a partial change applied to every selected job. Unset
+// fields leave the jobs alone; lists and env are added to, not replaced.
+type jobsPatch struct {
+	Timeout      *string           `json:"timeout"`
+	WarnAfter    *string           `json:"warn_after"`
+	Env          map[string]string `json:"env"`
+	UnsetEnv     []string          `json:"unset_env"`
+	AddTags      []string          `json:"add_tags"`
+	RemoveTags   []string          `json:"remove_tags"`
+	AddOnSuccess []string          `json:"add_on_success"`
+	AddOnFailure []string          `json:"add_on_failure"`
+}
+
+type patchJobsRequest struct {
+	Jobs   []string  `json:"jobs"`
+	Tag    string    `json:"tag"`
+	Patch  jobsPatch `json:"patch"`
+	DryRun bool      `json:"dry_run"`
+}
+
+type patchJobsResult struct {
+	Status    string    `json:"status"`
+	DryRun    bool      `json:"dry_run"`
+	Matched   int       `json:"matched"`
+	Updated   []string  `json:"updated"`
+	Unchanged []string  `json:"unchanged"`
+	Error     string    `json:"error,omitempty"`
+	Lint      []JobLint `json:"lint,omitempty"`
+}
+
+func (p *jobsPatch) empty() bool {
+	return p.Timeout == nil && p.WarnAfter == nil && len(p.Env) == 0 && len(p.UnsetEnv) == 0 &&
+		len(p.AddTags) == 0 && len(p.RemoveTags) == 0 && len(p.AddOnSuccess) == 0 && len(p.AddOnFailure) == 0
+}
+
+func (p *jobsPatch) validate() error {
+	for _, d := range []struct {
+		field string
+		value *string
+	}{{"timeout", p.Timeout}, {"warn_after", p.WarnAfter}} {
+		if d.value == nil || *d.value == "" {
+			continue
+		}
+		if v, err := time.ParseDuration(*d.value); err != nil || v <= 0 {
+			return fmt.Errorf("invalid %s %q", d.field, *d.value)
+		}
+	}
+	for k := range p.Env {
+		if strings.TrimSpace(k) == "" || strings.Contains(k, "=") {
+			return fmt.Errorf("invalid env name %q", k)
+		}
+	}
+	return nil
+}
+
+// apply returns a copy of j with the patch applied. An empty timeout or
+// warn_after clears it.
+func (p *jobsPatch) apply(j config.Job) config.Job {
+	if p.Timeout != nil {
+		j.Timeout = *p.Timeout
+	}
+	if p.WarnAfter != nil {
+		j.WarnAfter = *p.WarnAfter
+	}
+	if len(p.Env) > 0 || len(p.UnsetEnv) > 0 {
+		env := make(map[string]string, len(j.Env)+len(p.Env))
+		for k, v := range j.Env {
+			env[k] = v
+		}
+		for _, k := range p.UnsetEnv {
+			delete(env, k)
+		}
+		for k, v := range p.Env {
+			env[k] = v
+		}
+		if len(env) == 0 {
+			env = nil
+		}
+		j.Env = env
+	}
+	j.Tags = removeStrings(addStrings(j.Tags, p.AddTags), p.RemoveTags)
+	j.OnSuccess = addStrings(j.OnSuccess, p.AddOnSuccess)
+	j.OnFailure = addStrings(j.OnFailure, p.AddOnFailure)
+	return j
+}
+
+// addStrings appends the values of add not already in list, keeping the
+// original slice when nothing is added.
+func addStrings(list, add []string) []string {
+	out := list
+	for _, v := range add {
+		v = strings.TrimSpace(v)
+		if v == "" || containsString(out, v) {
+			continue
+		}
+		if len(out) == len(list) {
+			out = append(append([]string(nil), list...), v)
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+func removeStrings(list, remove []string) []string {
+	if len(remove) == 0 {
+		return list
+	}
+	var out []string
+	for _, v := range list {
+		if !containsString(remove, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func containsString(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// handlePatchJobs applies one change to many jobs: PATCH /api/v1/jobs with
+// either a list of job names or a tag, and a patch. Every matching job is
+// saved, or none is.
+func (a *API) handlePatchJobs(w http.ResponseWriter, r *http.Request) {
+	if a.UpdateJobs == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "bulk edit not available"})
+		return
+	}
+
+	var req patchJobsRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1024*1024)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	req.Tag = strings.TrimSpace(req.Tag)
+	if (len(req.Jobs) == 0) == (req.Tag == "") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "exactly one of jobs or tag is required"})
+		return
+	}
+	if req.Patch.empty() {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "patch is empty"})
+		return
+	}
+	if err := req.Patch.validate(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	byName := make(map[string]*config.Job)
+	for _, j := range a.Jobs() {
+		byName[j.Name] = j
+	}
+	var selected []*config.Job
+	if req.Tag != "" {
+		for _, j := range byName {
+			if j.HasTag(req.Tag) {
+				selected = append(selected, j)
+			}
+		}
+		if len(selected) == 0 {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "no jobs found with tag " + req.Tag})
+			return
+		}
+	} else {
+		seen := make(map[string]bool)
+		var missing []string
+		for _, name := range req.Jobs {
+			name = strings.TrimSpace(name)
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			j, ok := byName[name]
+			if !ok {
+				missing = append(missing, name)
+				continue
+			}
+			selected = append(selected, j)
+		}
+		if len(missing) > 0 {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found: " + strings.Join(missing, ", ")})
+			return
+		}
+	}
+	sort.Slice(selected, func(i, k int) bool { return selected[i].Name < selected[k].Name })
+
+	result := patchJobsResult{
+		Status:    "updated",
+		DryRun:    req.DryRun,
+		Matched:   len(selected),
+		Updated:   []string{},
+		Unchanged: []string{},
+	}
+	// apply patches one job. For a real edit it runs under the daemon's job
+	// lock on the job as currently saved, so edits made since the jobs were
+	// selected are kept.
+	var lintErr error
+	apply := func(j *config.Job) error {
+		patched := req.Patch.apply(*j)
+		if reflect.DeepEqual(patched, *j) {
+			result.Unchanged = append(result.Unchanged, j.Name)
+			return nil
+		}
+		// Patched jobs are linted like any other change; an error-level
+		// violation fails the whole edit.
+		if a.LintJob != nil {
+			if vs := a.LintJob(&patched); len(vs) > 0 {
+				result.Lint = append(result.Lint, JobLint{Job: j.Name, Violations: vs})
+				if err := lint.Err(vs); err != nil {
+					lintErr = fmt.Errorf("job %s: %v", j.Name, err)
+					return lintErr
+				}
+			}
+		}
+		result.Updated = append(result.Updated, j.Name)
+		*j = patched
+		return nil
+	}
+	fail := func(status int, err error) {
+		result.Status = "failed"
+		result.Error = err.Error()
+		result.Updated = []string{}
+		writeJSON(w, status, result)
+	}
+
+	if req.DryRun {
+		for _, j := range selected {
+			cp := *j
+			if err := apply(&cp); err != nil {
+				fail(http.StatusUnprocessableEntity, err)
+				return
+			}
+		}
+		result.Status = "dry_run"
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
+	names := make([]string, len(selected))
+	for i, j := range selected {
+		names[i] = j.Name
+	}
+	if err := a.UpdateJobs(names, apply); err != nil {
+		if lintErr != nil {
+			fail(http.StatusUnprocessableEntity, lintErr)
+		} else {
+			fail(statusFromError(err), err)
+		}
+		return
+	}
+	for _, name := range result.Updated {
+		a.audit(r, "bulk_edit", name, "")
+		a.emitEvent(realtime.Event{
+			Type:    "job.changed",
+			JobName: name,
+			Action:  "update",
+		})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
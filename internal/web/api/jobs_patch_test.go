@@ -0,0 +1,95 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+)
+
+func TestJobsPatchApply(t *testing.T) {
+	t.Parallel()
+
+	timeout := "30m"
+	p := jobsPatch{
+		Timeout:      &timeout,
+		Env:          map[string]string{"REGION": "eu", "MODE": "fast"},
+		UnsetEnv:     []string{"OLD"},
+		AddTags:      []string{"fleet", "etl"},
+		RemoveTags:   []string{"legacy"},
+		AddOnFailure: []string{"slack"},
+	}
+	orig := config.Job{
+		Name:      "a",
+		Timeout:   "5m",
+		Env:       map[string]string{"OLD": "1", "MODE": "slow"},
+		Tags:      []string{"etl", "legacy"},
+		OnFailure: []string{"email"},
+	}
+	got := p.apply(orig)
+	if got.Timeout != "30m" {
+		t.Fatalf("timeout = %q", got.Timeout)
+	}
+	if want := map[string]string{"REGION": "eu", "MODE": "fast"}; !reflect.DeepEqual(got.Env, want) {
+		t.Fatalf("env = %v, want %v", got.Env, want)
+	}
+	if want := []string{"etl", "fleet"}; !reflect.DeepEqual(got.Tags, want) {
+		t.Fatalf("tags = %v, want %v", got.Tags, want)
+	}
+	if want := []string{"email", "slack"}; !reflect.DeepEqual(got.OnFailure, want) {
+		t.Fatalf("on_failure = %v, want %v", got.OnFailure, want)
+	}
+	// The original job is not modified.
+	if orig.Env["MODE"] != "slow" || len(orig.OnFailure) != 1 || len(orig.Tags) != 2 {
+		t.Fatalf("original job changed: %+v", orig)
+	}
+
+	// Applying the same patch again changes nothing.
+	if again := p.apply(got); !reflect.DeepEqual(again, got) {
+		t.Fatalf("second apply changed the job: %+v", again)
+	}
+}
+
+func TestPatchJobsKeepsConcurrentEdits(t *testing.T) {
+	t.Parallel()
+
+	snapshot := []*config.Job{
+		{Name: "a", Schedule: "@daily", Command: "true", Tags: []string{"etl"}},
+		{Name: "b", Schedule: "@daily", Command: "true", Tags: []string{"etl"}},
+	}
+	// live is the daemon's state after b's schedule was edited elsewhere.
+	live := map[string]config.Job{
+		"a": *snapshot[0],
+		"b": {Name: "b", Schedule: "@hourly", Command: "true", Tags: []string{"etl"}},
+	}
+	saved := map[string]config.Job{}
+	a := &API{
+		Jobs: func() []*config.Job { return snapshot },
+		UpdateJobs: func(names []string, update func(j *config.Job) error) error {
+			for _, name := range names {
+				j := live[name]
+				if err := update(&j); err != nil {
+					return err
+				}
+				saved[name] = j
+			}
+			return nil
+		},
+	}
+
+	body := `{"tag": "etl", "patch": {"timeout": "30m"}}`
+	rec := httptest.NewRecorder()
+	a.handlePatchJobs(rec, httptest.NewRequest(http.MethodPatch, "/api/v1/jobs", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if got := saved["b"]; got.Timeout != "30m" || got.Schedule != "@hourly" {
+		t.Fatalf("b saved as %+v; want the patch on top of the concurrent edit", got)
+	}
+	if got := saved["a"]; got.Timeout != "30m" || got.Schedule != "@daily" {
+		t.Fatalf("a saved as %+v", got)
+	}
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/realtime"
+	"github.com/patrickspencer/cronbat/internal/scheduler"
+)
+
+// handleUpdateJobSchedule serves PUT /api/v1/jobs/{name}/schedule with
+// {"schedule": "..."}, changing only the job's schedule.
+func (a *API) handleUpdateJobSchedule(w http.ResponseWriter, r *http.Request, name string) {
+	a.updateJobField(w, r, name, "schedule", func(j *config.Job, value string) error {
+		if value == "" {
+			return fmt.Errorf("schedule is required")
+		}
+		policy, err := scheduler.ParseDSTPolicy(j.DSTPolicy)
+		if err != nil {
+			return fmt.Errorf("invalid dst_policy: %w", err)
+		}
+		if _, err := scheduler.ParseScheduleWithDST(value, policy); err != nil {
+			return fmt.Errorf("invalid schedule: %w", err)
+		}
+		j.Schedule = value
+		return nil
+	})
+}
+
+// handleUpdateJobTimeout serves PUT /api/v1/jobs/{name}/timeout with
+// {"timeout": "30m"}, changing only the job's timeout; "" removes it.
+func (a *API) handleUpdateJobTimeout(w http.ResponseWriter, r *http.Request, name string) {
+	a.updateJobField(w, r, name, "timeout", func(j *config.Job, value string) error {
+		if value != "" {
+			if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+				return fmt.Errorf("invalid timeout %q: want a positive duration such as 30m", value)
+			}
+		}
+		j.Timeout = value
+		return nil
+	})
+}
+
+// updateJobField reads {field: value} and applies it with set through
+// UpdateJobs, so the job is changed as currently saved, under the daemon's
+// job lock, and validated and scheduled like any other settings edit.
+func (a *API) updateJobField(w http.ResponseWriter, r *http.Request, name, field string, set func(j *config.Job, value string) error) {
+	if a.UpdateJobs == nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "settings operation not available"})
+		return
+	}
+	var req map[string]*string
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	value, ok := req[field]
+	if !ok || value == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": field + " is required"})
+		return
+	}
+
+	v := strings.TrimSpace(*value)
+	var setErr error
+	err := a.UpdateJobs([]string{name}, func(j *config.Job) error {
+		setErr = set(j, v)
+		return setErr
+	})
+	if setErr != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": setErr.Error()})
+		return
+	}
+	if err != nil {
+		writeJSON(w, statusFromError(err), map[string]string{"error": err.Error()})
+		return
+	}
+	a.emitEvent(realtime.Event{
+		Type:    "job.changed",
+		JobName: name,
+		Action:  "update_" + field,
+	})
+
+	resp := map[string]any{"status": "updated", field: v}
+	if field == "schedule" && a.NextRunTime != nil {
+		if next, ok := a.NextRunTime(name); ok {
+			resp["next_run"] = next
+		}
+	}
+	if vs := a.jobLint(name); len(vs) > 0 {
+		resp["lint"] = vs
+	}
+	if ws := a.scheduleWarnings(r.Context(), name); len(ws) > 0 {
+		resp["warnings"] = ws
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/realtime"
+)
+
+func TestSortJobSummaries(t *testing.T) {
+	t.Parallel()
+
+	early := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	late := early.Add(time.Hour)
+	jobs := []jobSummary{
+		{Name: "c", LastRun: &early, State: "stopped"},
+		{Name: "a"},
+		{Name: "b", LastRun: &late, State: "started"},
+	}
+	names := func() string {
+		out := ""
+		for _, j := range jobs {
+			out += j.Name
+		}
+		return out
+	}
+
+	sortJobSummaries(jobs, "last_run", true)
+	if got := names(); got != "bca" {
+		t.Fatalf("last_run desc: got %s, want bca (never-run jobs last)", got)
+	}
+	sortJobSummaries(jobs, "last_run", false)
+	if got := names(); got != "cba" {
+		t.Fatalf("last_run asc: got %s, want cba", got)
+	}
+	sortJobSummaries(jobs, "name", false)
+	if got := names(); got != "abc" {
+		t.Fatalf("name asc: got %s, want abc", got)
+	}
+}
+
+func TestJobMatches(t *testing.T) {
+	t.Parallel()
+
+	j := &config.Job{Name: "nightly-backup", Command: "pg_dump app", Metadata: map[string]any{"owner": "Data Team"}}
+	for _, q := range []string{"backup", "pg_dump", "owner", "data team"} {
+		if !jobMatches(j, q) {
+			t.Fatalf("expected %q to match", q)
+		}
+	}
+	if jobMatches(j, "billing") {
+		t.Fatal("unexpected match for billing")
+	}
+}
+
+func TestSelectFields(t *testing.T) {
+	t.Parallel()
+
+	got := selectFields([]jobSummary{{Name: "a", Schedule: "@daily", Command: "true"}}, map[string]bool{"name": true, "schedule": true})
+	if len(got) != 1 || len(got[0]) != 2 || string(got[0]["schedule"]) != `"@daily"` {
+		t.Fatalf("unexpected selection: %v", got)
+	}
+	for _, f := range []string{"name", "next_run", "last_run_status", "tags"} {
+		if !jobSummaryFields[f] {
+			t.Errorf("%s is not a selectable field", f)
+		}
+	}
+}
+
+func TestListJobsETagAndPaging(t *testing.T) {
+	t.Parallel()
+
+	var version uint64 = 1
+	a := &API{
+		Events:      realtime.NewBroker(),
+		Jobs:        func() []*config.Job { return []*config.Job{{Name: "a"}, {Name: "b"}, {Name: "c"}} },
+		NextRunTime: func(string) (time.Time, bool) { return time.Time{}, false },
+		JobsVersion: func() uint64 { return version },
+	}
+	get := func(url, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		a.handleListJobs(rec, req)
+		return rec
+	}
+
+	first := get("/api/v1/jobs?limit=1&offset=1", "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("status %d, etag %q", first.Code, etag)
+	}
+	link := first.Header().Get("Link")
+	if !strings.Contains(link, `offset=2`) || !strings.Contains(link, `rel="next"`) || !strings.Contains(link, `rel="prev"`) {
+		t.Fatalf("unexpected Link %q", link)
+	}
+	if rec := get("/api/v1/jobs", etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("unchanged list: status %d", rec.Code)
+	}
+	version++
+	if rec := get("/api/v1/jobs", etag); rec.Code != http.StatusOK {
+		t.Fatalf("after a job change: status %d", rec.Code)
+	}
+	etag = get("/api/v1/jobs", "").Header().Get("ETag")
+	a.Events.Publish(realtime.Event{Type: "run.completed", JobName: "a"})
+	if rec := get("/api/v1/jobs", etag); rec.Code != http.StatusOK {
+		t.Fatalf("after an event: status %d", rec.Code)
+	}
+}
+
+func TestTriggerContext(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Repeat("x", 5000)
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/jobs/a/run", strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set("X-Gitlab-Token", "secret")
+	r.Header.Set("X-Github-Event", "push")
+
+	tc := triggerContext(r)
+	if _, ok := tc.Headers["Authorization"]; ok {
+		t.Fatal("Authorization header recorded")
+	}
+	if _, ok := tc.Headers["X-Gitlab-Token"]; ok {
+		t.Fatal("token header recorded")
+	}
+	if tc.Headers["X-Github-Event"] != "push" {
+		t.Fatalf("headers = %v", tc.Headers)
+	}
+	if len(tc.Body) != 4096 || !tc.BodyTruncated {
+		t.Fatalf("body of %d bytes, truncated %v", len(tc.Body), tc.BodyTruncated)
+	}
+}
+
+func TestRequestCorrelationID(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		target, header, body string
+		want                 string
+		wantErr              bool
+	}{
+		{target: "/api/v1/jobs/a/run", want: ""},
+		{target: "/api/v1/jobs/a/run?correlation_id=q1", header: "h1", body: `{"correlation_id":"b1"}`, want: "q1"},
+		{target: "/api/v1/jobs/a/run", header: "h1", body: `{"correlation_id":"b1"}`, want: "h1"},
+		{target: "/api/v1/jobs/a/run", body: `{"correlation_id":" b1 "}`, want: "b1"},
+		{target: "/api/v1/jobs/a/run", body: "not json", want: ""},
+		{target: "/api/v1/jobs/a/run?correlation_id=" + strings.Repeat("x", 129), wantErr: true},
+		{target: "/api/v1/jobs/a/run?correlation_id=a%0Ab", wantErr: true},
+	} {
+		r := httptest.NewRequest(http.MethodPost, tc.target, strings.NewReader(tc.body))
+		if tc.header != "" {
+			r.Header.Set(CorrelationIDHeader, tc.header)
+		}
+		got, err := requestCorrelationID(r, triggerContext(r))
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("%s (header %q, body %q) = %q, %v", tc.target, tc.header, tc.body, got, err)
+		}
+	}
+}
+
+func TestUpdateJobSchedule(t *testing.T) {
+	t.Parallel()
+
+	// live is the job as the daemon holds it; saved is the last copy
+	// UpdateJobs stored.
+	live := config.Job{Name: "a", Schedule: "@daily", Command: "true", Timeout: "5m"}
+	var saved *config.Job
+	a := &API{
+		Jobs: func() []*config.Job { return []*config.Job{&live} },
+		UpdateJobs: func(names []string, update func(j *config.Job) error) error {
+			for _, name := range names {
+				if name != live.Name {
+					return errors.New("job not found: " + name)
+				}
+				j := live
+				if err := update(&j); err != nil {
+					return err
+				}
+				saved = &j
+			}
+			return nil
+		},
+	}
+	put := func(target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		a.routeJobs(rec, httptest.NewRequest(http.MethodPut, target, strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := put("/api/v1/jobs/a/schedule", `{"schedule": " */10 * * * * "}`); rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if saved.Schedule != "*/10 * * * *" || saved.Command != "true" || saved.Timeout != "5m" {
+		t.Fatalf("saved %+v", saved)
+	}
+	if rec := put("/api/v1/jobs/a/timeout", `{"timeout": ""}`); rec.Code != http.StatusOK || saved.Timeout != "" || saved.Schedule != "@daily" {
+		t.Fatalf("clearing the timeout: status %d, saved %+v", rec.Code, saved)
+	}
+
+	// Each edit applies to the job as the daemon holds it, so an edit made
+	// meanwhile elsewhere is kept.
+	live.Timeout = "10m"
+	if rec := put("/api/v1/jobs/a/schedule", `{"schedule": "@hourly"}`); rec.Code != http.StatusOK || saved.Timeout != "10m" || saved.Schedule != "@hourly" {
+		t.Fatalf("concurrent edit: status %d, saved %+v", rec.Code, saved)
+	}
+
+	saved = nil
+	for _, tc := range []struct {
+		target, body string
+		want         int
+	}{
+		{"/api/v1/jobs/a/schedule", `{"schedule": "not a schedule"}`, http.StatusBadRequest},
+		{"/api/v1/jobs/a/schedule", `{}`, http.StatusBadRequest},
+		{"/api/v1/jobs/a/timeout", `{"timeout": "-5m"}`, http.StatusBadRequest},
+		{"/api/v1/jobs/b/timeout", `{"timeout": "5m"}`, http.StatusNotFound},
+	} {
+		if rec := put(tc.target, tc.body); rec.Code != tc.want {
+			t.Errorf("PUT %s %s = %d, want %d", tc.target, tc.body, rec.Code, tc.want)
+		}
+	}
+	if saved != nil {
+		t.Fatalf("a rejected edit was saved: %+v", saved)
+	}
+}
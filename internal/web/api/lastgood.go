@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/realtime"
+	"github.com/patrickspencer/cronbat/internal/store"
+)
+
+type lastGoodResponse struct {
+	Version    string    `json:"version"`
+	RunID      string    `json:"run_id"`
+	RecordedAt time.Time `json:"recorded_at"`
+	Pinned     bool      `json:"pinned"`
+}
+
+func toLastGoodResponse(snap *store.JobSnapshot) *lastGoodResponse {
+	if snap == nil {
+		return nil
+	}
+	return &lastGoodResponse{
+		Version:    snap.Version,
+		RunID:      snap.RunID,
+		RecordedAt: snap.RecordedAt,
+		Pinned:     snap.Pinned,
+	}
+}
+
+// handlePinLastGood pins (POST) or unpins (DELETE) a job to the definition
+// of its most recent successful run.
+func (a *API) handlePinLastGood(w http.ResponseWriter, r *http.Request, name string, pinned bool) {
+	if a.SetJobPinned == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "pinning not available"})
+		return
+	}
+	snap, err := a.SetJobPinned(r.Context(), name, pinned)
+	if err != nil {
+		status := statusFromError(err)
+		if strings.Contains(err.Error(), "no successful run") {
+			status = http.StatusConflict
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+
+	action := "pin"
+	if !pinned {
+		action = "unpin"
+	}
+	a.emitEvent(realtime.Event{
+		Type:    "job.changed",
+		JobName: name,
+		Action:  action,
+	})
+	writeJSON(w, http.StatusOK, toLastGoodResponse(snap))
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/lint"
+)
+
+// JobLint is the lint result of one job.
+type JobLint struct {
+	Job        string           `json:"job"`
+	Violations []lint.Violation `json:"violations"`
+}
+
+// LintReport is the lint result of every loaded job. Jobs lists only the
+// jobs that break a rule.
+type LintReport struct {
+	Checked  int       `json:"checked"`
+	Errors   int       `json:"errors"`
+	Warnings int       `json:"warnings"`
+	Jobs     []JobLint `json:"jobs"`
+}
+
+// handleLint serves /api/v1/lint. GET checks every loaded job against the
+// lint rules; POST checks the job definition in the body without saving
+// it.
+func (a *API) handleLint(w http.ResponseWriter, r *http.Request) {
+	if a.LintJob == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "lint unavailable"})
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		report := LintReport{Jobs: []JobLint{}}
+		for _, j := range a.Jobs() {
+			report.Checked++
+			vs := a.LintJob(j)
+			if len(vs) == 0 {
+				continue
+			}
+			for _, v := range vs {
+				if v.Level == lint.LevelError {
+					report.Errors++
+				} else {
+					report.Warnings++
+				}
+			}
+			report.Jobs = append(report.Jobs, JobLint{Job: j.Name, Violations: vs})
+		}
+		sort.Slice(report.Jobs, func(i, k int) bool { return report.Jobs[i].Job < report.Jobs[k].Job })
+		writeJSON(w, http.StatusOK, report)
+	case http.MethodPost:
+		var job config.Job
+		if err := json.NewDecoder(io.LimitReader(r.Body, 2*1024*1024)).Decode(&job); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+			return
+		}
+		job.Name = strings.TrimSpace(job.Name)
+		job.Schedule = strings.TrimSpace(job.Schedule)
+		vs := a.LintJob(&job)
+		if vs == nil {
+			vs = []lint.Violation{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"job":        job.Name,
+			"valid":      lint.Err(vs) == nil,
+			"violations": vs,
+		})
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+// jobLint returns the lint violations of a saved job, for the response to
+// a change; error-level violations would have rejected the change.
+func (a *API) jobLint(name string) []lint.Violation {
+	if a.LintJob == nil {
+		return nil
+	}
+	for _, j := range a.Jobs() {
+		if j.Name == name {
+			return a.LintJob(j)
+		}
+	}
+	return nil
+}
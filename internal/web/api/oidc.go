@@ -0,0 +1,169 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// oidcStateCookie binds a single sign-on attempt to the browser that
+// started it.
+const oidcStateCookie = "cronbat_oidc_state"
+
+// oidcLoginTTL is how long a user has to finish signing in at the provider.
+const oidcLoginTTL = 10 * time.Minute
+
+// oidcLogin is a single sign-on attempt waiting for the provider's
+// redirect back.
+type oidcLogin struct {
+	nonce    string
+	verifier string
+	next     string
+	expires  time.Time
+}
+
+// oidcLogins holds sign-on attempts by state. The zero value is ready to
+// use.
+type oidcLogins struct {
+	mu      sync.Mutex
+	byState map[string]*oidcLogin
+}
+
+func (l *oidcLogins) put(state string, login *oidcLogin) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.byState == nil {
+		l.byState = make(map[string]*oidcLogin)
+	}
+	now := time.Now()
+	for s, old := range l.byState {
+		if now.After(old.expires) {
+			delete(l.byState, s)
+		}
+	}
+	l.byState[state] = login
+}
+
+// take removes and returns the live attempt with state, or nil.
+func (l *oidcLogins) take(state string) *oidcLogin {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	login := l.byState[state]
+	delete(l.byState, state)
+	if login == nil || time.Now().After(login.expires) {
+		return nil
+	}
+	return login
+}
+
+// handleOIDCLogin serves GET /api/v1/auth/oidc/login?next=/ui/..., sending
+// the browser to the provider to sign in.
+func (a *API) handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if a.OIDC == nil || a.Sessions == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "single sign-on is not configured"})
+		return
+	}
+	var state, nonce, verifier string
+	for _, p := range []*string{&state, &nonce, &verifier} {
+		v, err := randomToken()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		*p = v
+	}
+	authURL, err := a.OIDC.AuthURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		log.Printf("ERROR: single sign-on: %v", err)
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "identity provider unavailable"})
+		return
+	}
+	a.oidcLogins.put(state, &oidcLogin{
+		nonce:    nonce,
+		verifier: verifier,
+		next:     uiPath(r.URL.Query().Get("next")),
+		expires:  time.Now().Add(oidcLoginTTL),
+	})
+	// Lax, not Strict: the cookie must come back with the provider's
+	// cross-site redirect to the callback.
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/v1/auth/oidc/",
+		MaxAge:   int(oidcLoginTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   a.SecureCookie,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// handleOIDCCallback serves GET /api/v1/auth/oidc/callback, where the
+// provider sends the browser back. The user's groups pick their role; a
+// user without one is refused. Failures are shown on the sign-in page.
+func (a *API) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if a.OIDC == nil || a.Sessions == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "single sign-on is not configured"})
+		return
+	}
+	fail := func(msg string) {
+		http.Redirect(w, r, "/ui/login.html?error="+url.QueryEscape(msg), http.StatusFound)
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/api/v1/auth/oidc/", MaxAge: -1})
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		fail(strings.TrimSpace("sign-in failed: " + e + " " + q.Get("error_description")))
+		return
+	}
+	state := q.Get("state")
+	c, err := r.Cookie(oidcStateCookie)
+	if err != nil || state == "" || c.Value != state {
+		fail("sign-in was not started from this browser; try again")
+		return
+	}
+	login := a.oidcLogins.take(state)
+	if login == nil {
+		fail("sign-in expired; try again")
+		return
+	}
+	id, err := a.OIDC.Exchange(r.Context(), q.Get("code"), login.verifier, login.nonce)
+	if err != nil {
+		log.Printf("WARN: single sign-on: %v", err)
+		fail("sign-in failed: " + err.Error())
+		return
+	}
+	role := roleForGroups(a.OIDCRoles, a.OIDCDefaultRole, id.Groups)
+	if role == "" {
+		log.Printf("WARN: single sign-on: %s has no cronbat role (groups %v)", id.Name, id.Groups)
+		fail(id.Name + " has no cronbat role")
+		return
+	}
+	sess, err := a.Sessions.Create(KindUser, id.Name, role)
+	if err != nil {
+		fail(err.Error())
+		return
+	}
+	a.setSessionCookies(w, sess.ID, sess.CSRFToken, sess.ExpiresAt)
+	http.Redirect(w, r, login.next, http.StatusFound)
+}
+
+// uiPath returns next if it is a page of the UI, so the sign-in flow
+// cannot be used to redirect elsewhere, and the UI's home otherwise.
+func uiPath(next string) string {
+	if strings.HasPrefix(next, "/ui/") && !strings.Contains(next, "\\") {
+		return next
+	}
+	return "/ui/"
+}
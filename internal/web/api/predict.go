@@ -0,0 +1,104 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/predict"
+	"github.com/patrickspencer/cronbat/internal/scheduler"
+	"github.com/patrickspencer/cronbat/internal/store"
+)
+
+type predictionResponse struct {
+	Job string `json:"job"`
+	// Samples is the number of successful runs P50DurationMs is based on;
+	// below predict.MinSamples no estimates are made.
+	Samples       int        `json:"samples"`
+	P50DurationMs int64      `json:"p50_duration_ms"`
+	NextRun       *time.Time `json:"next_run,omitempty"`
+	// OverlapPredicted is true when a typical run started at NextRun would
+	// still be going at the fire after it.
+	OverlapPredicted bool                    `json:"overlap_predicted"`
+	Running          []runPredictionResponse `json:"running"`
+}
+
+type runPredictionResponse struct {
+	RunID            string     `json:"run_id"`
+	StartedAt        time.Time  `json:"started_at"`
+	ElapsedMs        int64      `json:"elapsed_ms"`
+	ExpectedFinishAt *time.Time `json:"expected_finish_at,omitempty"`
+	WindowEnd        *time.Time `json:"window_end,omitempty"`
+	OverrunPredicted bool       `json:"overrun_predicted"`
+}
+
+// handleJobPrediction serves GET /api/v1/jobs/{name}/prediction: expected
+// completion of running runs and overlap with upcoming fires, based on the
+// median duration of recent successful runs.
+func (a *API) handleJobPrediction(w http.ResponseWriter, r *http.Request, name string) {
+	var schedule string
+	found := false
+	for _, j := range a.Jobs() {
+		if j.Name == name {
+			schedule, found = j.Schedule, true
+			break
+		}
+	}
+	if !found {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found"})
+		return
+	}
+
+	durations, err := a.Store.RecentDurations(r.Context(), name, predict.Samples)
+	if err != nil {
+		log.Printf("ERROR: failed to get run durations for %s: %v", name, err)
+		writeJSON(w, statusFromError(err), map[string]string{"error": err.Error()})
+		return
+	}
+	recent, err := a.Store.ListRuns(r.Context(), store.ListOpts{JobName: name, Limit: 50})
+	if err != nil {
+		log.Printf("ERROR: failed to list runs for %s: %v", name, err)
+		writeJSON(w, statusFromError(err), map[string]string{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	resp := predictionResponse{
+		Job:     name,
+		Samples: len(durations),
+		Running: []runPredictionResponse{},
+	}
+	if next, ok := a.NextRunTime(name); ok {
+		resp.NextRun = &next
+	}
+	sched, _ := scheduler.ParseSchedule(schedule)
+	enough := len(durations) >= predict.MinSamples
+	if enough {
+		resp.P50DurationMs = predict.Percentile(durations, 50)
+	}
+	typical := time.Duration(resp.P50DurationMs) * time.Millisecond
+	if enough && sched != nil {
+		resp.OverlapPredicted = predict.Overlaps(sched, now, typical)
+	}
+
+	for _, run := range recent {
+		if run.Status != "running" {
+			continue
+		}
+		rp := runPredictionResponse{
+			RunID:     run.ID,
+			StartedAt: run.StartedAt,
+			ElapsedMs: now.Sub(run.StartedAt).Milliseconds(),
+		}
+		if enough {
+			e := predict.Run(sched, run.StartedAt, typical)
+			rp.ExpectedFinishAt = &e.ExpectedFinish
+			if !e.WindowEnd.IsZero() {
+				rp.WindowEnd = &e.WindowEnd
+			}
+			rp.OverrunPredicted = e.Overrun
+		}
+		resp.Running = append(resp.Running, rp)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
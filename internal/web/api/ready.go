@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+)
+
+// Readiness tracks startup steps that must complete before the daemon is
+// ready to serve traffic. Liveness (/api/v1/health) does not depend on it.
+type Readiness struct {
+	mu    sync.RWMutex
+	steps []string
+	done  map[string]bool
+}
+
+// NewReadiness creates a tracker for the given startup steps.
+func NewReadiness(steps ...string) *Readiness {
+	return &Readiness{
+		steps: steps,
+		done:  make(map[string]bool, len(steps)),
+	}
+}
+
+// MarkDone records that a startup step has completed.
+func (r *Readiness) MarkDone(step string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.done[step] = true
+}
+
+// Status reports whether all steps are done, along with per-step state.
+func (r *Readiness) Status() (bool, map[string]bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ready := true
+	steps := make(map[string]bool, len(r.steps))
+	for _, step := range r.steps {
+		steps[step] = r.done[step]
+		if !r.done[step] {
+			ready = false
+		}
+	}
+	return ready, steps
+}
+
+type readyResponse struct {
+	Status string          `json:"status"`
+	Steps  map[string]bool `json:"steps"`
+}
+
+func writeReady(w http.ResponseWriter, readiness *Readiness) {
+	if readiness == nil {
+		writeJSON(w, http.StatusOK, readyResponse{Status: "ready", Steps: map[string]bool{}})
+		return
+	}
+	ready, steps := readiness.Status()
+	if !ready {
+		writeJSON(w, http.StatusServiceUnavailable, readyResponse{Status: "starting", Steps: steps})
+		return
+	}
+	writeJSON(w, http.StatusOK, readyResponse{Status: "ready", Steps: steps})
+}
+
+func (a *API) handleReady(w http.ResponseWriter, _ *http.Request) {
+	writeReady(w, a.Readiness)
+}
+
+// StartupHandler serves liveness and readiness while the daemon is still
+// starting; every other path returns 503 until the full API is mounted.
+func StartupHandler(readiness *Readiness) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/health":
+			writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		case "/api/v1/ready":
+			writeReady(w, readiness)
+		default:
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "cronbat is starting"})
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Roles limit what an API key or signed-in user may do. Viewers may only
+// read; operators may also run, start, stop, and pause jobs and decide
+// approvals; admins may also change job definitions, purge logs, and
+// maintain the store.
+const (
+	RoleAdmin    = "admin"
+	RoleOperator = "operator"
+	RoleViewer   = "viewer"
+)
+
+// ValidRole reports whether role is a known role.
+func ValidRole(role string) bool {
+	return roleRank(role) > 0
+}
+
+func roleRank(role string) int {
+	switch role {
+	case RoleViewer:
+		return 1
+	case RoleOperator:
+		return 2
+	case RoleAdmin:
+		return 3
+	}
+	return 0
+}
+
+// roleForGroups returns the strongest role mapping gives any of groups,
+// or defaultRole when none is mapped.
+func roleForGroups(mapping map[string]string, defaultRole string, groups []string) string {
+	best := ""
+	for _, g := range groups {
+		if role := mapping[g]; roleRank(role) > roleRank(best) {
+			best = role
+		}
+	}
+	if best == "" {
+		return defaultRole
+	}
+	return best
+}
+
+// readOnlyPosts are POST endpoints that change nothing, open to viewers.
+var readOnlyPosts = map[string]bool{
+	"/api/v1/lint":                true,
+	"/api/v1/schedule/preview":    true,
+	"/api/v1/grafana/search":      true,
+	"/api/v1/grafana/query":       true,
+	"/api/v1/grafana/annotations": true,
+}
+
+// roleAllows reports whether role may make request r.
+func roleAllows(role string, r *http.Request) bool {
+	path := r.URL.Path
+	if isSafeMethod(r.Method) || strings.HasPrefix(path, "/api/v1/auth/") || readOnlyPosts[path] {
+		return true
+	}
+	if strings.HasPrefix(path, "/api/v1/jobs/") && strings.HasSuffix(path, "/dry-run") {
+		return true
+	}
+	switch role {
+	case RoleAdmin:
+		return true
+	case RoleOperator:
+		return !adminOnly(r)
+	}
+	return false
+}
+
+// adminOnly reports whether r creates, edits, archives, or deletes a job,
+// purges its logs, or maintains the store.
+func adminOnly(r *http.Request) bool {
+	path := r.URL.Path
+	switch {
+	case path == "/api/v1/jobs", path == "/api/v1/jobs/import":
+		return true
+	case strings.HasPrefix(path, "/api/v1/store/"), strings.HasPrefix(path, "/api/v1/storage/"):
+		return true
+	}
+	rest, ok := strings.CutPrefix(path, "/api/v1/jobs/")
+	if !ok {
+		return false
+	}
+	name, action, _ := strings.Cut(rest, "/")
+	if name == "" || (action == "" && name == "run") {
+		// POST /api/v1/jobs/run is the bulk run.
+		return false
+	}
+	switch action {
+	case "", "yaml", "schedule", "timeout", "archive", "pin-last-good", "logs/purge":
+		return true
+	}
+	return false
+}
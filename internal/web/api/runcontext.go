@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/patrickspencer/cronbat/internal/store"
+)
+
+type runContextResponse struct {
+	RunID          string            `json:"run_id"`
+	JobName        string            `json:"job_name"`
+	Command        string            `json:"command"`
+	Argv           []string          `json:"argv,omitempty"`
+	Shell          string            `json:"shell,omitempty"`
+	LoginShell     bool              `json:"login_shell,omitempty"`
+	WorkingDir     string            `json:"working_dir,omitempty"`
+	Executor       string            `json:"executor,omitempty"`
+	User           string            `json:"user,omitempty"`
+	Group          string            `json:"group,omitempty"`
+	Timeout        string            `json:"timeout,omitempty"`
+	Env            map[string]string `json:"env"`
+	Hostname       string            `json:"hostname,omitempty"`
+	CronbatVersion string            `json:"cronbat_version,omitempty"`
+	JobVersion     string            `json:"job_version,omitempty"`
+	Definition     string            `json:"definition,omitempty"`
+}
+
+// handleGetRunContext serves GET /api/v1/runs/{id}/context: the command,
+// environment, and job definition the run executed with. Runs recorded
+// before contexts were captured have none.
+func (a *API) handleGetRunContext(w http.ResponseWriter, r *http.Request, id string) {
+	if a.GetRunContext == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "run contexts not available"})
+		return
+	}
+	run, err := a.Store.GetRun(r.Context(), id)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to get run"})
+		return
+	}
+	if run == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "run not found"})
+		return
+	}
+	rc, err := a.GetRunContext(r.Context(), id)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to get run context"})
+		return
+	}
+	if rc == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "run context not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, runContextToResponse(run, rc))
+}
+
+func runContextToResponse(run *store.Run, rc *store.RunContext) runContextResponse {
+	env := rc.Env
+	if env == nil {
+		env = map[string]string{}
+	}
+	return runContextResponse{
+		RunID:          run.ID,
+		JobName:        run.JobName,
+		Command:        rc.Command,
+		Argv:           rc.Argv,
+		Shell:          rc.Shell,
+		LoginShell:     rc.LoginShell,
+		WorkingDir:     rc.WorkingDir,
+		Executor:       rc.Executor,
+		User:           rc.User,
+		Group:          rc.Group,
+		Timeout:        rc.Timeout,
+		Env:            env,
+		Hostname:       rc.Hostname,
+		CronbatVersion: rc.CronbatVersion,
+		JobVersion:     rc.JobVersion,
+		Definition:     rc.Definition,
+	}
+}
@@ -1,61 +1,131 @@
 package api
 
 import (
+	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"os"
 	"strconv"
 	"time"
 
+	"github.com/patrickspencer/cronbat/internal/runlog"
+	"github.com/patrickspencer/cronbat/internal/spool"
 	"github.com/patrickspencer/cronbat/internal/store"
 )
 
 type runResponse struct {
-	ID            string     `json:"id"`
-	JobName       string     `json:"job_name"`
-	Status        string     `json:"status"`
-	ExitCode      int        `json:"exit_code"`
-	StartedAt     time.Time  `json:"started_at"`
-	FinishedAt    *time.Time `json:"finished_at,omitempty"`
-	DurationMs    int64      `json:"duration_ms"`
-	StdoutTail    string     `json:"stdout_tail,omitempty"`
-	StderrTail    string     `json:"stderr_tail,omitempty"`
-	ErrorMsg      string     `json:"error_msg,omitempty"`
-	Trigger       string     `json:"trigger"`
-	LLMAnalysis   string     `json:"llm_analysis,omitempty"`
-	LLMTokensUsed int        `json:"llm_tokens_used,omitempty"`
-	CreatedAt     time.Time  `json:"created_at"`
+	ID            string            `json:"id"`
+	JobName       string            `json:"job_name"`
+	Status        string            `json:"status"`
+	ExitCode      int               `json:"exit_code"`
+	StartedAt     time.Time         `json:"started_at"`
+	FinishedAt    *time.Time        `json:"finished_at,omitempty"`
+	DurationMs    int64             `json:"duration_ms"`
+	StdoutTail    string            `json:"stdout_tail,omitempty"`
+	StderrTail    string            `json:"stderr_tail,omitempty"`
+	ErrorMsg      string            `json:"error_msg,omitempty"`
+	Trigger       string            `json:"trigger"`
+	LLMAnalysis   string            `json:"llm_analysis,omitempty"`
+	LLMTokensUsed int               `json:"llm_tokens_used,omitempty"`
+	JobsCommit    string            `json:"jobs_commit,omitempty"`
+	ScheduledAt   *time.Time        `json:"scheduled_at,omitempty"`
+	DriftMs       *int64            `json:"drift_ms,omitempty"`
+	JobVersion    string            `json:"job_version,omitempty"`
+	Pinned        bool              `json:"pinned,omitempty"`
+	TriggeredBy   string            `json:"triggered_by,omitempty"`
+	LogsPinned    bool              `json:"logs_pinned,omitempty"`
+	StdoutSHA256  string            `json:"stdout_sha256,omitempty"`
+	StderrSHA256  string            `json:"stderr_sha256,omitempty"`
+	Host          string            `json:"host,omitempty"`
+	Outputs       map[string]string `json:"outputs,omitempty"`
+	TraceID       string            `json:"trace_id,omitempty"`
+	// TriggerContext is the request or message that triggered the run.
+	TriggerContext *store.TriggerContext `json:"trigger_context,omitempty"`
+	CorrelationID  string                `json:"correlation_id,omitempty"`
+	// ParentRunID and Matrix are set on the instances of a matrix run.
+	ParentRunID   string            `json:"parent_run_id,omitempty"`
+	Matrix        map[string]string `json:"matrix,omitempty"`
+	LastHeartbeat *time.Time        `json:"last_heartbeat,omitempty"`
+	PossiblyHung  bool              `json:"possibly_hung,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
 }
 
-func runToResponse(r *store.Run) runResponse {
-	return runResponse{
-		ID:            r.ID,
-		JobName:       r.JobName,
-		Status:        r.Status,
-		ExitCode:      r.ExitCode,
-		StartedAt:     r.StartedAt,
-		FinishedAt:    r.FinishedAt,
-		DurationMs:    r.DurationMs,
-		StdoutTail:    r.StdoutTail,
-		StderrTail:    r.StderrTail,
-		ErrorMsg:      r.ErrorMsg,
-		Trigger:       r.Trigger,
-		LLMAnalysis:   r.LLMAnalysis,
-		LLMTokensUsed: r.LLMTokensUsed,
-		CreatedAt:     r.CreatedAt,
+func (a *API) runToResponse(r *store.Run) runResponse {
+	resp := runResponse{
+		ID:             r.ID,
+		JobName:        r.JobName,
+		Status:         r.Status,
+		ExitCode:       r.ExitCode,
+		StartedAt:      r.StartedAt,
+		FinishedAt:     r.FinishedAt,
+		DurationMs:     r.DurationMs,
+		StdoutTail:     r.StdoutTail,
+		StderrTail:     r.StderrTail,
+		ErrorMsg:       r.ErrorMsg,
+		Trigger:        r.Trigger,
+		LLMAnalysis:    r.LLMAnalysis,
+		LLMTokensUsed:  r.LLMTokensUsed,
+		JobsCommit:     r.JobsCommit,
+		ScheduledAt:    r.ScheduledAt,
+		JobVersion:     r.JobVersion,
+		Pinned:         r.Pinned,
+		TriggeredBy:    r.TriggeredBy,
+		LogsPinned:     r.LogsPinned,
+		StdoutSHA256:   r.StdoutSHA256,
+		StderrSHA256:   r.StderrSHA256,
+		Host:           r.Host,
+		TraceID:        r.TraceID,
+		TriggerContext: r.TriggerContext,
+		CorrelationID:  r.CorrelationID,
+		ParentRunID:    r.ParentRunID,
+		Matrix:         r.Matrix,
+		Outputs:        r.Outputs,
+		LastHeartbeat:  r.LastHeartbeat,
+		CreatedAt:      r.CreatedAt,
 	}
+	if r.ScheduledAt != nil {
+		drift := r.DriftMs
+		resp.DriftMs = &drift
+	}
+	resp.PossiblyHung = a.possiblyHung(r, time.Now())
+	return resp
+}
+
+// possiblyHung reports whether a running run has gone HungAfter without a
+// heartbeat, counting from its start if it never sent one: its process or
+// the daemon running it may have died.
+func (a *API) possiblyHung(r *store.Run, now time.Time) bool {
+	if a.HungAfter <= 0 || r.Status != "running" {
+		return false
+	}
+	last := r.StartedAt
+	if r.LastHeartbeat != nil {
+		last = *r.LastHeartbeat
+	}
+	return now.Sub(last) > a.HungAfter
 }
 
 func (a *API) handleListRuns(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+	switch r.Method {
+	case http.MethodGet:
+		// continue
+	case http.MethodPost:
+		a.handleReportRun(w, r)
+		return
+	default:
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
 		return
 	}
 
 	q := r.URL.Query()
 	opts := store.ListOpts{
-		JobName: q.Get("job"),
-		Limit:   50,
+		JobName:       q.Get("job"),
+		JobsCommit:    q.Get("commit"),
+		Host:          q.Get("host"),
+		CorrelationID: q.Get("correlation_id"),
+		ParentRunID:   q.Get("parent_run_id"),
+		Limit:         50,
 	}
 
 	if v := q.Get("limit"); v != "" {
@@ -77,7 +147,7 @@ func (a *API) handleListRuns(w http.ResponseWriter, r *http.Request) {
 
 	result := make([]runResponse, 0, len(runs))
 	for _, run := range runs {
-		result = append(result, runToResponse(run))
+		result = append(result, a.runToResponse(run))
 	}
 
 	writeJSON(w, http.StatusOK, result)
@@ -94,9 +164,113 @@ func (a *API) handleGetRun(w http.ResponseWriter, r *http.Request, id string) {
 		return
 	}
 
-	writeJSON(w, http.StatusOK, runToResponse(run))
+	writeJSON(w, http.StatusOK, a.runToResponse(run))
 }
 
+// handleRunInstances serves GET /api/v1/runs/{id}/instances: the
+// instances of a matrix run, earliest started first.
+func (a *API) handleRunInstances(w http.ResponseWriter, r *http.Request, id string) {
+	run, err := a.Store.GetRun(r.Context(), id)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to get run"})
+		return
+	}
+	if run == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "run not found"})
+		return
+	}
+	instances, err := a.Store.ListRuns(r.Context(), store.ListOpts{ParentRunID: id})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list runs"})
+		return
+	}
+	result := make([]runResponse, 0, len(instances))
+	for i := len(instances) - 1; i >= 0; i-- {
+		result = append(result, a.runToResponse(instances[i]))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handlePinRunLogs serves POST (pin) and DELETE (unpin) on
+// /api/v1/runs/{id}/pin. Pinned runs' log files survive retention cleanup.
+func (a *API) handlePinRunLogs(w http.ResponseWriter, r *http.Request, id string, pinned bool) {
+	if a.PinRunLogs == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "log pinning not available"})
+		return
+	}
+	run, err := a.PinRunLogs(r.Context(), id, pinned)
+	if err != nil {
+		writeJSON(w, statusFromError(err), map[string]string{"error": err.Error()})
+		return
+	}
+	action := "unpin_logs"
+	if pinned {
+		action = "pin_logs"
+	}
+	a.audit(r, action, run.JobName, "run "+run.ID)
+	writeJSON(w, http.StatusOK, a.runToResponse(run))
+}
+
+// Run log verification results.
+const (
+	verifyOK         = "ok"
+	verifyFailed     = "failed"
+	verifyUnverified = "unverified"
+)
+
+type runVerifyResponse struct {
+	RunID   string            `json:"run_id"`
+	JobName string            `json:"job_name"`
+	Status  string            `json:"status"`
+	Checks  []runlog.LogCheck `json:"checks"`
+}
+
+// verifyStatus summarizes log checks: ok when every recorded stream
+// matches, unverified when no checksum was recorded.
+func verifyStatus(checks []runlog.LogCheck) string {
+	if len(checks) == 0 {
+		return verifyUnverified
+	}
+	for _, c := range checks {
+		if c.Status != runlog.CheckOK {
+			return verifyFailed
+		}
+	}
+	return verifyOK
+}
+
+// handleVerifyRunLogs serves GET /api/v1/runs/{id}/verify: it re-hashes the
+// run's log files against the checksums recorded when they were written.
+func (a *API) handleVerifyRunLogs(w http.ResponseWriter, r *http.Request, id string) {
+	if a.VerifyRunLogs == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "log verification not available"})
+		return
+	}
+	run, err := a.Store.GetRun(r.Context(), id)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to get run"})
+		return
+	}
+	if run == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "run not found"})
+		return
+	}
+	checks := a.VerifyRunLogs(run)
+	if checks == nil {
+		checks = []runlog.LogCheck{}
+	}
+	writeJSON(w, http.StatusOK, runVerifyResponse{
+		RunID:   run.ID,
+		JobName: run.JobName,
+		Status:  verifyStatus(checks),
+		Checks:  checks,
+	})
+}
+
+// fullLogLimit bounds how much of each stream the non-ranged logs response
+// returns; older output is available through range reads.
+const fullLogLimit = runlog.MaxRangeLimit
+
 type runLogsResponse struct {
 	RunID        string `json:"run_id"`
 	JobName      string `json:"job_name"`
@@ -107,9 +281,20 @@ type runLogsResponse struct {
 	StderrPath   string `json:"stderr_path,omitempty"`
 	StdoutTail   string `json:"stdout_tail,omitempty"`
 	StderrTail   string `json:"stderr_tail,omitempty"`
+	StdoutSize   int64  `json:"stdout_size"`
+	StderrSize   int64  `json:"stderr_size"`
+	StdoutOffset int64  `json:"stdout_offset"`
+	StderrOffset int64  `json:"stderr_offset"`
 	StorageError string `json:"storage_error,omitempty"`
 }
 
+type runLogRangeResponse struct {
+	RunID   string `json:"run_id"`
+	JobName string `json:"job_name"`
+	Source  string `json:"source"`
+	*runlog.LogRange
+}
+
 func (a *API) handleGetRunLogs(w http.ResponseWriter, r *http.Request, id string) {
 	run, err := a.Store.GetRun(r.Context(), id)
 	if err != nil {
@@ -121,6 +306,12 @@ func (a *API) handleGetRunLogs(w http.ResponseWriter, r *http.Request, id string
 		return
 	}
 
+	q := r.URL.Query()
+	if q.Has("stream") || q.Has("offset") || q.Has("limit") {
+		a.handleGetRunLogRange(w, r, run)
+		return
+	}
+
 	resp := runLogsResponse{
 		RunID:      run.ID,
 		JobName:    run.JobName,
@@ -129,9 +320,41 @@ func (a *API) handleGetRunLogs(w http.ResponseWriter, r *http.Request, id string
 		Stderr:     run.StderrTail,
 		StdoutTail: run.StdoutTail,
 		StderrTail: run.StderrTail,
+		StdoutSize: int64(len(run.StdoutTail)),
+		StderrSize: int64(len(run.StderrTail)),
 	}
 
-	if a.ReadRunLogs != nil {
+	switch {
+	case a.ReadRunLogRange != nil:
+		// Return only the end of each file; the rest is paged by range.
+		stdout, stdoutErr := a.ReadRunLogRange(run.JobName, run.ID, "stdout", -fullLogLimit, fullLogLimit)
+		stderr, stderrErr := a.ReadRunLogRange(run.JobName, run.ID, "stderr", -fullLogLimit, fullLogLimit)
+		if stdoutErr == nil || stderrErr == nil {
+			resp.Source = "file"
+			resp.Stdout, resp.Stderr = "", ""
+			resp.StdoutSize, resp.StderrSize = 0, 0
+		}
+		for _, part := range []struct {
+			rng          *runlog.LogRange
+			err          error
+			data, path   *string
+			size, offset *int64
+		}{
+			{stdout, stdoutErr, &resp.Stdout, &resp.StdoutPath, &resp.StdoutSize, &resp.StdoutOffset},
+			{stderr, stderrErr, &resp.Stderr, &resp.StderrPath, &resp.StderrSize, &resp.StderrOffset},
+		} {
+			if part.err != nil {
+				if !errors.Is(part.err, os.ErrNotExist) {
+					resp.StorageError = part.err.Error()
+				}
+				continue
+			}
+			*part.data = part.rng.Data
+			*part.path = part.rng.Path
+			*part.size = part.rng.Size
+			*part.offset = part.rng.Offset
+		}
+	case a.ReadRunLogs != nil:
 		stdout, stderr, stdoutPath, stderrPath, err := a.ReadRunLogs(run.JobName, run.ID)
 		if err == nil {
 			resp.Source = "file"
@@ -139,6 +362,8 @@ func (a *API) handleGetRunLogs(w http.ResponseWriter, r *http.Request, id string
 			resp.Stderr = stderr
 			resp.StdoutPath = stdoutPath
 			resp.StderrPath = stderrPath
+			resp.StdoutSize = int64(len(stdout))
+			resp.StderrSize = int64(len(stderr))
 		} else if !errors.Is(err, os.ErrNotExist) {
 			resp.StorageError = err.Error()
 		}
@@ -146,3 +371,91 @@ func (a *API) handleGetRunLogs(w http.ResponseWriter, r *http.Request, id string
 
 	writeJSON(w, http.StatusOK, resp)
 }
+
+// handleGetRunLogRange serves ?stream=stdout|stderr&offset=N&limit=N reads.
+// A negative offset counts back from the end of the stream.
+func (a *API) handleGetRunLogRange(w http.ResponseWriter, r *http.Request, run *store.Run) {
+	q := r.URL.Query()
+	stream := q.Get("stream")
+	if stream == "" {
+		stream = "stdout"
+	}
+	if stream != "stdout" && stream != "stderr" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "stream must be stdout or stderr"})
+		return
+	}
+
+	var offset, limit int64
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid offset"})
+			return
+		}
+		offset = n
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	if a.ReadRunLogRange != nil {
+		rng, err := a.ReadRunLogRange(run.JobName, run.ID, stream, offset, limit)
+		if err == nil {
+			writeJSON(w, http.StatusOK, runLogRangeResponse{RunID: run.ID, JobName: run.JobName, Source: "file", LogRange: rng})
+			return
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+
+	tail := run.StdoutTail
+	if stream == "stderr" {
+		tail = run.StderrTail
+	}
+	writeJSON(w, http.StatusOK, runLogRangeResponse{
+		RunID:    run.ID,
+		JobName:  run.JobName,
+		Source:   "tail",
+		LogRange: runlog.SliceRange(stream, tail, offset, limit),
+	})
+}
+
+// handleReportRun records a run executed outside the daemon, such as one
+// from "cronbat wrap --api". Reporting the same run ID again updates it, so
+// clients may resend reports they are unsure were received; a run the
+// daemon recorded, or another job's report, is never overwritten.
+func (a *API) handleReportRun(w http.ResponseWriter, r *http.Request) {
+	if a.ReportRun == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "run reports not available"})
+		return
+	}
+	var rep spool.Report
+	if err := json.NewDecoder(io.LimitReader(r.Body, 2*1024*1024)).Decode(&rep); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	if err := rep.Validate(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if !a.jobExists(rep.JobName) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unknown job: " + rep.JobName})
+		return
+	}
+	if err := a.ReportRun(r.Context(), &rep); err != nil {
+		if errors.Is(err, store.ErrRunConflict) {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to record run"})
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]string{"status": "recorded", "id": rep.ID})
+}
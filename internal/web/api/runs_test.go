@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/config"
+	"github.com/patrickspencer/cronbat/internal/spool"
+	"github.com/patrickspencer/cronbat/internal/store"
+)
+
+func TestPossiblyHung(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	recent := now.Add(-time.Minute)
+	a := &API{HungAfter: 90 * time.Second}
+	for _, tc := range []struct {
+		run  store.Run
+		want bool
+	}{
+		{store.Run{Status: "running", StartedAt: now.Add(-time.Hour), LastHeartbeat: &recent}, false},
+		{store.Run{Status: "running", StartedAt: now.Add(-time.Hour)}, true},
+		{store.Run{Status: "running", StartedAt: now.Add(-time.Second)}, false},
+		{store.Run{Status: "success", StartedAt: now.Add(-time.Hour)}, false},
+	} {
+		if got := a.possiblyHung(&tc.run, now); got != tc.want {
+			t.Errorf("possiblyHung(%+v) = %v, want %v", tc.run, got, tc.want)
+		}
+	}
+	if (&API{}).possiblyHung(&store.Run{Status: "running"}, now) {
+		t.Error("flagged a run with heartbeats off")
+	}
+}
+
+func TestQueuedRuns(t *testing.T) {
+	t.Parallel()
+
+	enqueued := time.Now().Add(-time.Minute)
+	a := &API{QueuedRuns: func() []QueuedRun {
+		return []QueuedRun{
+			{JobName: "urgent", Trigger: "manual", Priority: 10, EnqueuedAt: enqueued},
+			{JobName: "nightly", Trigger: "schedule", EnqueuedAt: enqueued},
+		}
+	}}
+	w := httptest.NewRecorder()
+	a.handleQueuedRuns(w, httptest.NewRequest("GET", "/api/v1/runs/queued", nil))
+	var got []QueuedRun
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Position != 1 || got[1].Position != 2 || got[1].JobName != "nightly" || got[0].WaitMs < 60000 {
+		t.Fatalf("queued runs = %+v", got)
+	}
+
+	a = &API{ActiveRuns: func() []ActiveRun { return nil }}
+	w = httptest.NewRecorder()
+	a.handleActiveRuns(w, httptest.NewRequest("GET", "/api/v1/runs/active", nil))
+	if body := strings.TrimSpace(w.Body.String()); body != "[]" {
+		t.Fatalf("no active runs = %s, want []", body)
+	}
+}
+
+func TestReportRun(t *testing.T) {
+	t.Parallel()
+
+	recorded := map[string]string{"daemon-1": "a"}
+	a := &API{
+		Jobs: func() []*config.Job {
+			return []*config.Job{{Name: "a", Schedule: "@daily", Command: "true"}}
+		},
+		ReportRun: func(ctx context.Context, rep *spool.Report) error {
+			// Stands in for store.RecordReportedRun: daemon-1 was not reported.
+			if rep.ID == "daemon-1" {
+				return store.ErrRunConflict
+			}
+			recorded[rep.ID] = rep.JobName
+			return nil
+		},
+	}
+	post := func(body string) int {
+		rec := httptest.NewRecorder()
+		a.handleReportRun(rec, httptest.NewRequest(http.MethodPost, "/api/v1/runs", strings.NewReader(body)))
+		return rec.Code
+	}
+
+	if code := post(`{"id": "wrap-1", "job_name": "a", "status": "success", "started_at": "2026-01-01T00:00:00Z", "trigger": "cron"}`); code != http.StatusCreated {
+		t.Fatalf("new report: status %d", code)
+	}
+	if code := post(`{"id": "wrap-2", "job_name": "nope", "status": "success", "started_at": "2026-01-01T00:00:00Z", "trigger": "cron"}`); code != http.StatusBadRequest {
+		t.Fatalf("unknown job: status %d, want 400", code)
+	}
+	if _, ok := recorded["wrap-2"]; ok {
+		t.Fatal("a report for an unknown job was recorded")
+	}
+	if code := post(`{"id": "daemon-1", "job_name": "a", "status": "failure", "started_at": "2026-01-01T00:00:00Z", "trigger": "cron"}`); code != http.StatusConflict {
+		t.Fatalf("overwriting a daemon run: status %d, want 409", code)
+	}
+}
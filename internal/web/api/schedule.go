@@ -0,0 +1,150 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/scheduler"
+)
+
+const (
+	defaultPreviewCount = 10
+	maxPreviewCount     = 100
+)
+
+type schedulePreviewRequest struct {
+	Schedule  string `json:"schedule"`
+	Timezone  string `json:"timezone"`
+	DSTPolicy string `json:"dst_policy"`
+	Count     int    `json:"count"`
+}
+
+type schedulePreviewResponse struct {
+	Schedule string      `json:"schedule"`
+	Timezone string      `json:"timezone"`
+	Times    []time.Time `json:"times"`
+}
+
+// previewSchedule computes the next count fire times of expr. A non-empty
+// timezone applies to the expression (as a CRON_TZ= prefix would) and to
+// the returned times.
+func previewSchedule(expr, timezone, dstPolicy string, count int) (schedulePreviewResponse, error) {
+	expr, err := scheduler.Normalize(expr)
+	if err != nil {
+		return schedulePreviewResponse{}, err
+	}
+	policy, err := scheduler.ParseDSTPolicy(dstPolicy)
+	if err != nil {
+		return schedulePreviewResponse{}, err
+	}
+	loc := time.Local
+	if timezone != "" {
+		l, err := time.LoadLocation(timezone)
+		if err != nil {
+			return schedulePreviewResponse{}, errors.New("invalid timezone: " + timezone)
+		}
+		loc = l
+		if !strings.HasPrefix(expr, "CRON_TZ=") && !strings.HasPrefix(expr, "TZ=") && !scheduler.IsOneShot(expr) {
+			expr = "CRON_TZ=" + timezone + " " + expr
+		}
+	}
+	schedule, err := scheduler.ParseScheduleWithDST(expr, policy)
+	if err != nil {
+		return schedulePreviewResponse{}, errors.New("invalid schedule: " + err.Error())
+	}
+	if count <= 0 {
+		count = defaultPreviewCount
+	}
+	if count > maxPreviewCount {
+		count = maxPreviewCount
+	}
+
+	times := scheduler.Upcoming(schedule, time.Now().In(loc), count)
+	for i := range times {
+		times[i] = times[i].In(loc)
+	}
+	return schedulePreviewResponse{Schedule: expr, Timezone: loc.String(), Times: times}, nil
+}
+
+// handleSchedulePreview serves POST /api/v1/schedule/preview.
+func (a *API) handleSchedulePreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	var req schedulePreviewRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	req.Schedule = strings.TrimSpace(req.Schedule)
+	if req.Schedule == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "schedule is required"})
+		return
+	}
+
+	resp, err := previewSchedule(req.Schedule, strings.TrimSpace(req.Timezone), strings.TrimSpace(req.DSTPolicy), req.Count)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleScheduleExplain serves GET /api/v1/schedule/explain?expr=...: the
+// schedule in words and its fields broken down.
+func (a *API) handleScheduleExplain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	expr := strings.TrimSpace(r.URL.Query().Get("expr"))
+	if expr == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "expr is required"})
+		return
+	}
+	d, err := scheduler.Describe(expr)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid schedule: " + err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+// handleJobUpcoming serves GET /api/v1/jobs/{name}/upcoming?count=10.
+func (a *API) handleJobUpcoming(w http.ResponseWriter, r *http.Request, name string) {
+	var schedule, dstPolicy string
+	found := false
+	for _, j := range a.Jobs() {
+		if j.Name == name {
+			schedule, dstPolicy, found = j.Schedule, j.DSTPolicy, true
+			break
+		}
+	}
+	if !found {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found"})
+		return
+	}
+
+	count := defaultPreviewCount
+	if raw := r.URL.Query().Get("count"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid count"})
+			return
+		}
+		count = n
+	}
+
+	resp, err := previewSchedule(schedule, "", dstPolicy, count)
+	if err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/realtime"
+	"github.com/patrickspencer/cronbat/internal/store"
+)
+
+type schedulerStateResponse struct {
+	Paused    bool       `json:"paused"`
+	Reason    string     `json:"reason,omitempty"`
+	UpdatedBy string     `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+func toSchedulerStateResponse(s *store.SchedulerState) schedulerStateResponse {
+	resp := schedulerStateResponse{Paused: s.Paused, Reason: s.Reason, UpdatedBy: s.UpdatedBy}
+	if !s.UpdatedAt.IsZero() {
+		t := s.UpdatedAt.UTC()
+		resp.UpdatedAt = &t
+	}
+	return resp
+}
+
+// routeScheduler serves GET /api/v1/scheduler and PUT
+// /api/v1/scheduler/pause and /resume.
+func (a *API) routeScheduler(w http.ResponseWriter, r *http.Request) {
+	if a.SchedulerState == nil || a.SetSchedulerPaused == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "scheduler control not available"})
+		return
+	}
+	action := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/scheduler"), "/")
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		state, err := a.SchedulerState(r.Context())
+		if err != nil {
+			log.Printf("ERROR: failed to get scheduler state: %v", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to get scheduler state"})
+			return
+		}
+		writeJSON(w, http.StatusOK, toSchedulerStateResponse(state))
+	case (action == "pause" || action == "resume") && r.Method == http.MethodPut:
+		a.handleSetSchedulerPaused(w, r, action == "pause")
+	case action == "" || action == "pause" || action == "resume":
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+func (a *API) handleSetSchedulerPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body: " + err.Error()})
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	state, err := a.SetSchedulerPaused(paused, reason, a.requestActor(r))
+	if err != nil {
+		log.Printf("ERROR: failed to save scheduler state: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to save scheduler state"})
+		return
+	}
+	action := "scheduler.resume"
+	if paused {
+		action = "scheduler.pause"
+	}
+	a.audit(r, action, "", reason)
+	a.emitEvent(realtime.Event{Type: "scheduler.changed", Action: strings.TrimPrefix(action, "scheduler.")})
+	writeJSON(w, http.StatusOK, toSchedulerStateResponse(state))
+}
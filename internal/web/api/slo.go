@@ -0,0 +1,52 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/patrickspencer/cronbat/internal/slo"
+)
+
+type sloListResponse struct {
+	Jobs      []*slo.Report `json:"jobs"`
+	Violating int           `json:"violating"`
+}
+
+// handleListSLO serves GET /api/v1/slo: compliance of every job that
+// declares an slo block (?violating=true for only those in violation).
+func (a *API) handleListSLO(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if a.EvaluateSLO == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "SLO tracking not available"})
+		return
+	}
+	onlyViolating := r.URL.Query().Get("violating") == "true"
+
+	resp := sloListResponse{Jobs: []*slo.Report{}}
+	for _, j := range a.Jobs() {
+		if j.SLO == nil {
+			continue
+		}
+		report, err := a.EvaluateSLO(r.Context(), j)
+		if err != nil {
+			log.Printf("ERROR: failed to evaluate SLO of %s: %v", j.Name, err)
+			writeJSON(w, statusFromError(err), map[string]string{"error": err.Error()})
+			return
+		}
+		if report == nil {
+			continue
+		}
+		if !report.Compliant {
+			resp.Violating++
+		} else if onlyViolating {
+			continue
+		}
+		resp.Jobs = append(resp.Jobs, report)
+	}
+	sort.Slice(resp.Jobs, func(i, j int) bool { return resp.Jobs[i].JobName < resp.Jobs[j].JobName })
+	writeJSON(w, http.StatusOK, resp)
+}
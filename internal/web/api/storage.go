@@ -0,0 +1,123 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/patrickspencer/cronbat/internal/store"
+)
+
+// StorageUsage is what GET /api/v1/storage reports: the database's size and
+// contents, and how much each job takes up.
+type StorageUsage struct {
+	Database *store.DBStats `json:"database"`
+	// RunLogs is nil when run log storage is disabled.
+	RunLogs *RunLogUsage `json:"run_logs,omitempty"`
+	Jobs    []JobStorage `json:"jobs"`
+}
+
+// RunLogUsage describes the run log directory.
+type RunLogUsage struct {
+	Dir           string `json:"dir"`
+	UsedBytes     int64  `json:"used_bytes"`
+	MaxTotalBytes int64  `json:"max_total_bytes"`
+}
+
+// JobStorage is one job's share of the database and run log directory.
+// Jobs that were deleted but still have runs are included.
+type JobStorage struct {
+	Job      string `json:"job"`
+	Runs     int64  `json:"runs"`
+	LogBytes int64  `json:"log_bytes"`
+}
+
+// LogCleanupResult reports the outcome of an on-demand run log cleanup.
+type LogCleanupResult struct {
+	BeforeBytes int64 `json:"before_bytes"`
+	AfterBytes  int64 `json:"after_bytes"`
+	FreedBytes  int64 `json:"freed_bytes"`
+	DurationMs  int64 `json:"duration_ms"`
+}
+
+func (a *API) handleStoreStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if a.StoreStats == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "store stats unavailable"})
+		return
+	}
+
+	stats, err := a.StoreStats(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (a *API) handleStoreCompact(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if a.CompactStore == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "store compaction unavailable"})
+		return
+	}
+
+	// VACUUM on a large database can outlast the configured write timeout.
+	disableDeadlines(w)
+
+	result, err := a.CompactStore(r.Context())
+	if err != nil {
+		resp := map[string]any{"error": err.Error()}
+		if result != nil {
+			resp["integrity"] = result.Integrity
+		}
+		writeJSON(w, http.StatusInternalServerError, resp)
+		return
+	}
+	a.audit(r, "compact_store", "", "")
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (a *API) handleStorage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if a.Storage == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "storage usage unavailable"})
+		return
+	}
+
+	usage, err := a.Storage(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, usage)
+}
+
+func (a *API) handleCleanupRunLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if a.CleanupRunLogs == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "run log storage is disabled"})
+		return
+	}
+
+	// Archiving old logs to S3 can outlast the configured write timeout.
+	disableDeadlines(w)
+
+	result, err := a.CleanupRunLogs()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	a.audit(r, "cleanup_logs", "", "")
+	writeJSON(w, http.StatusOK, result)
+}
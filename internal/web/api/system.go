@@ -3,12 +3,38 @@ package api
 import (
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
-	"github.com/patrickspencer/cronbat/internal/store"
+	"github.com/patrickspencer/cronbat/internal/updatecheck"
 )
 
-func (a *API) handleHealth(w http.ResponseWriter, _ *http.Request) {
-	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+// HealthCheck is the result of one component check of a deep health
+// check.
+type HealthCheck struct {
+	Name string `json:"name"`
+	OK   bool   `json:"ok"`
+	// Critical checks fail the whole health check when they fail.
+	Critical bool           `json:"critical"`
+	Error    string         `json:"error,omitempty"`
+	Details  map[string]any `json:"details,omitempty"`
+}
+
+// handleHealth reports liveness. With ?deep=1 it also runs the component
+// checks and answers 503 if a critical one fails.
+func (a *API) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if deep, _ := strconv.ParseBool(r.URL.Query().Get("deep")); !deep || a.DeepHealth == nil {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		return
+	}
+	checks := a.DeepHealth(r.Context())
+	status, code := "ok", http.StatusOK
+	for _, c := range checks {
+		if !c.OK && c.Critical {
+			status, code = "fail", http.StatusServiceUnavailable
+		}
+	}
+	writeJSON(w, code, map[string]any{"status": status, "checks": checks})
 }
 
 func (a *API) handleConfig(w http.ResponseWriter, r *http.Request) {
@@ -30,11 +56,49 @@ func (a *API) handleConfig(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, cfg)
 }
 
+// statsWindow is how far back /api/v1/stats looks for recent runs, the
+// slowest jobs, and scheduler drift.
+const statsWindow = 24 * time.Hour
+
+// slowestJobsLimit is how many jobs /api/v1/stats lists as slowest.
+const slowestJobsLimit = 5
+
 type statsResponse struct {
-	TotalJobs      int `json:"total_jobs"`
-	EnabledJobs    int `json:"enabled_jobs"`
-	TotalRuns      int `json:"total_runs"`
-	RecentFailures int `json:"recent_failures"`
+	TotalJobs   int `json:"total_jobs"`
+	EnabledJobs int `json:"enabled_jobs"`
+	TotalRuns   int `json:"total_runs"`
+	// RecentFailures counts all failed runs; the *_24h fields cover only
+	// statsWindow.
+	RecentFailures int                 `json:"recent_failures"`
+	StatusCounts   map[string]int      `json:"status_counts"`
+	Runs24h        int                 `json:"runs_24h"`
+	Failures24h    int                 `json:"failures_24h"`
+	Warnings24h    int                 `json:"warnings_24h"`
+	FailureRate24h float64             `json:"failure_rate_24h"`
+	SlowestJobs    []slowJobResponse   `json:"slowest_jobs"`
+	Drift          *driftStatsResponse `json:"drift,omitempty"`
+	// Update is the latest release check, when update_check is on.
+	Update *updatecheck.Status `json:"update,omitempty"`
+	// ReadOnly is set on a read-only replica.
+	ReadOnly bool `json:"read_only,omitempty"`
+}
+
+type slowJobResponse struct {
+	JobName       string  `json:"job_name"`
+	Runs          int     `json:"runs"`
+	AvgDurationMs float64 `json:"avg_duration_ms"`
+	MaxDurationMs int64   `json:"max_duration_ms"`
+}
+
+// driftStatsResponse summarizes how late scheduled runs were over the last
+// statsWindow: drift is scheduler lateness, start delay adds queue wait.
+type driftStatsResponse struct {
+	Window          string  `json:"window"`
+	Samples         int     `json:"samples"`
+	AvgDriftMs      float64 `json:"avg_drift_ms"`
+	MaxDriftMs      int64   `json:"max_drift_ms"`
+	AvgStartDelayMs float64 `json:"avg_start_delay_ms"`
+	MaxStartDelayMs int64   `json:"max_start_delay_ms"`
 }
 
 func (a *API) handleStats(w http.ResponseWriter, r *http.Request) {
@@ -48,27 +112,50 @@ func (a *API) handleStats(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	var totalRuns, recentFailures int
-	for _, j := range jobs {
-		stats, err := a.Store.GetJobStats(r.Context(), j.Name)
-		if err != nil {
-			log.Printf("ERROR: failed to get job stats for %s: %v", j.Name, err)
-			continue
-		}
-		totalRuns += stats.TotalRuns
-		recentFailures += stats.Failures
-	}
-
-	// Cross-check with a broad query for total run count.
-	runs, err := a.Store.ListRuns(r.Context(), store.ListOpts{Limit: 0})
-	if err == nil && len(runs) > totalRuns {
-		totalRuns = len(runs)
+	since := time.Now().Add(-statsWindow)
+	global, err := a.Store.GetGlobalStats(r.Context(), since, slowestJobsLimit)
+	if err != nil {
+		log.Printf("ERROR: failed to get run stats: %v", err)
+		writeJSON(w, statusFromError(err), map[string]string{"error": err.Error()})
+		return
 	}
 
-	writeJSON(w, http.StatusOK, statsResponse{
+	resp := statsResponse{
 		TotalJobs:      totalJobs,
 		EnabledJobs:    enabledJobs,
-		TotalRuns:      totalRuns,
-		RecentFailures: recentFailures,
-	})
+		TotalRuns:      global.TotalRuns,
+		RecentFailures: global.StatusCounts["failure"],
+		StatusCounts:   global.StatusCounts,
+		Runs24h:        global.RecentRuns,
+		Failures24h:    global.RecentFailures,
+		Warnings24h:    global.RecentWarnings,
+		SlowestJobs:    make([]slowJobResponse, 0, len(global.SlowestJobs)),
+		Update:         a.updateStatus(),
+		ReadOnly:       a.ReadOnly,
+	}
+	if global.RecentRuns > 0 {
+		resp.FailureRate24h = float64(global.RecentFailures) / float64(global.RecentRuns)
+	}
+	for _, d := range global.SlowestJobs {
+		resp.SlowestJobs = append(resp.SlowestJobs, slowJobResponse{
+			JobName:       d.JobName,
+			Runs:          d.Runs,
+			AvgDurationMs: d.AvgDurationMs,
+			MaxDurationMs: d.MaxDurationMs,
+		})
+	}
+	drift, err := a.Store.GetDriftStats(r.Context(), since)
+	if err != nil {
+		log.Printf("ERROR: failed to get drift stats: %v", err)
+	} else {
+		resp.Drift = &driftStatsResponse{
+			Window:          statsWindow.String(),
+			Samples:         drift.Samples,
+			AvgDriftMs:      drift.AvgDriftMs,
+			MaxDriftMs:      drift.MaxDriftMs,
+			AvgStartDelayMs: drift.AvgStartDelayMs,
+			MaxStartDelayMs: drift.MaxStartDelayMs,
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
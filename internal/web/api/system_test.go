@@ -0,0 +1,37 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDeepHealth(t *testing.T) {
+	healthy := true
+	a := &API{DeepHealth: func(context.Context) []HealthCheck {
+		return []HealthCheck{
+			{Name: "store", OK: healthy, Critical: true},
+			{Name: "extra", OK: false},
+		}
+	}}
+	get := func(query string) (int, map[string]any) {
+		rec := httptest.NewRecorder()
+		a.handleHealth(rec, httptest.NewRequest(http.MethodGet, "/api/v1/health"+query, nil))
+		var body map[string]any
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec.Code, body
+	}
+
+	if code, body := get("?deep=1"); code != http.StatusOK || body["status"] != "ok" {
+		t.Fatalf("non-critical failure: %d %v", code, body)
+	}
+	healthy = false
+	if code, body := get("?deep=1"); code != http.StatusServiceUnavailable || body["status"] != "fail" {
+		t.Fatalf("critical failure: %d %v", code, body)
+	}
+	if code, body := get(""); code != http.StatusOK || body["checks"] != nil {
+		t.Fatalf("shallow check: %d %v", code, body)
+	}
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/patrickspencer/cronbat/internal/realtime"
+	"github.com/patrickspencer/cronbat/internal/trigger"
+)
+
+// routeTriggers serves GET /api/v1/triggers and POST
+// /api/v1/triggers/{name}/enable and /disable.
+func (a *API) routeTriggers(w http.ResponseWriter, r *http.Request) {
+	if a.Triggers == nil || a.SetTriggerEnabled == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "triggers not available"})
+		return
+	}
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/triggers"), "/")
+	if rest == "" {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, a.Triggers())
+		return
+	}
+	name, action, _ := strings.Cut(rest, "/")
+	if action != "enable" && action != "disable" {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	status, err := a.SetTriggerEnabled(name, action == "enable")
+	if errors.Is(err, trigger.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "trigger not found: " + name})
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR: failed to %s trigger %s: %v", action, name, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	a.audit(r, "trigger."+action, "", "trigger "+name)
+	a.emitEvent(realtime.Event{Type: "trigger.changed", Action: action})
+	writeJSON(w, http.StatusOK, status)
+}
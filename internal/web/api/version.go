@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/patrickspencer/cronbat/internal/updatecheck"
+)
+
+// BuildInfo describes the running binary.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	// Modified reports a build from a checkout with uncommitted changes.
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
+type versionResponse struct {
+	BuildInfo
+	// Update is the latest release check; absent while update_check is
+	// off or before the first check.
+	Update *updatecheck.Status `json:"update,omitempty"`
+}
+
+// handleVersion serves GET /api/v1/version.
+func (a *API) handleVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, versionResponse{BuildInfo: a.Build, Update: a.updateStatus()})
+}
+
+func (a *API) updateStatus() *updatecheck.Status {
+	if a.UpdateStatus == nil {
+		return nil
+	}
+	return a.UpdateStatus()
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/realtime"
+)
+
+const (
+	defaultWatchTimeout = 30 * time.Second
+	maxWatchTimeout     = 2 * time.Minute
+	defaultWatchLimit   = 100
+	maxWatchLimit       = 1000
+)
+
+type runWatchResponse struct {
+	Events []realtime.Event `json:"events"`
+	// NextID is the since_id to pass on the next call.
+	NextID int64  `json:"next_id"`
+	Epoch  string `json:"epoch"`
+	// Reset means events after since_id are no longer available (buffer
+	// overflow or a restart); resync from /api/v1/runs and continue from
+	// NextID.
+	Reset bool `json:"reset"`
+	// More means the limit was hit and further events are ready now.
+	More bool `json:"more"`
+}
+
+// handleWatchRuns long-polls for run state changes:
+// GET /api/v1/runs/watch?jobs=a,b&since_id=N&epoch=E&timeout=30s&limit=100.
+// It returns as soon as matching events after since_id exist, or an empty
+// batch when the timeout expires. Without since_id it waits for new events.
+func (a *API) handleWatchRuns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if a.Events == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "realtime events unavailable"})
+		return
+	}
+
+	q := r.URL.Query()
+	jobs := make(map[string]bool)
+	for _, name := range strings.Split(q.Get("jobs"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			jobs[name] = true
+		}
+	}
+
+	timeout := defaultWatchTimeout
+	if raw := q.Get("timeout"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid timeout"})
+			return
+		}
+		timeout = d
+	}
+	if timeout > maxWatchTimeout {
+		timeout = maxWatchTimeout
+	}
+
+	limit := defaultWatchLimit
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid limit"})
+			return
+		}
+		limit = n
+	}
+	if limit > maxWatchLimit {
+		limit = maxWatchLimit
+	}
+
+	// Subscribe before reading the buffer so nothing published in between
+	// is missed.
+	notify, cancel := a.Events.Subscribe()
+	defer cancel()
+
+	epoch := a.Events.Epoch()
+	reset := false
+	var sinceID int64
+	if raw := q.Get("since_id"); raw != "" {
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || n < 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid since_id"})
+			return
+		}
+		sinceID = n
+		if e := q.Get("epoch"); e != "" && e != epoch {
+			reset = true
+		}
+	} else {
+		_, sinceID, _ = a.Events.Since(0)
+	}
+	if reset {
+		_, sinceID, _ = a.Events.Since(0)
+	}
+
+	if timeout > 0 {
+		disableDeadlines(w)
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		events, latestID, complete := a.Events.Since(sinceID)
+		if !complete {
+			reset = true
+		}
+
+		resp := runWatchResponse{Events: []realtime.Event{}, NextID: latestID, Epoch: epoch, Reset: reset}
+		for _, evt := range events {
+			if !isRunEvent(evt, jobs) {
+				continue
+			}
+			if len(resp.Events) == limit {
+				resp.More = true
+				resp.NextID = resp.Events[len(resp.Events)-1].ID
+				break
+			}
+			resp.Events = append(resp.Events, evt)
+		}
+		if len(resp.Events) > 0 || reset {
+			writeJSON(w, http.StatusOK, resp)
+			return
+		}
+
+		// Nothing matched; skip past what was seen and wait for more.
+		sinceID = latestID
+		select {
+		case <-notify:
+			continue
+		case <-timer.C:
+		case <-r.Context().Done():
+			return
+		case <-a.Events.Done():
+		}
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+}
+
+func isRunEvent(evt realtime.Event, jobs map[string]bool) bool {
+	if !strings.HasPrefix(evt.Type, "run.") {
+		return false
+	}
+	return len(jobs) == 0 || jobs[evt.JobName]
+}
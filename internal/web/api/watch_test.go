@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/patrickspencer/cronbat/internal/realtime"
+)
+
+func TestWatchRunsLongPoll(t *testing.T) {
+	t.Parallel()
+
+	a := &API{Events: realtime.NewBroker()}
+	a.Events.Publish(realtime.Event{Type: "run.completed", JobName: "a", RunID: "r1"})
+	a.Events.Publish(realtime.Event{Type: "job.changed", JobName: "a"})
+	a.Events.Publish(realtime.Event{Type: "run.started", JobName: "b", RunID: "r2"})
+
+	watch := func(query string) runWatchResponse {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		a.handleWatchRuns(rec, httptest.NewRequest(http.MethodGet, "/api/v1/runs/watch?"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp runWatchResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// Buffered events are returned immediately, filtered by job and type.
+	resp := watch("since_id=0&jobs=a")
+	if len(resp.Events) != 1 || resp.Events[0].RunID != "r1" || resp.NextID != 3 {
+		t.Fatalf("unexpected catch-up batch: %+v", resp)
+	}
+
+	// With nothing new, the call blocks until a matching event arrives.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		a.Events.Publish(realtime.Event{Type: "run.started", JobName: "b", RunID: "r3"})
+		a.Events.Publish(realtime.Event{Type: "run.completed", JobName: "a", RunID: "r4"})
+	}()
+	resp = watch("since_id=3&jobs=a&timeout=5s")
+	if len(resp.Events) != 1 || resp.Events[0].RunID != "r4" || resp.NextID != 5 {
+		t.Fatalf("unexpected long-poll batch: %+v", resp)
+	}
+
+	// A cursor from another broker instance is reported as a reset.
+	resp = watch("since_id=5&epoch=stale&timeout=0s")
+	if !resp.Reset || resp.NextID != 5 {
+		t.Fatalf("expected reset for stale epoch: %+v", resp)
+	}
+
+	resp = watch("since_id=5&timeout=10ms")
+	if len(resp.Events) != 0 || resp.Reset || resp.NextID != 5 {
+		t.Fatalf("expected empty batch on timeout: %+v", resp)
+	}
+}
@@ -5,11 +5,9 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"sync/atomic"
 	"time"
 
-	"github.com/patrickspencer/cronbat/internal/config"
-	"github.com/patrickspencer/cronbat/internal/realtime"
-	"github.com/patrickspencer/cronbat/internal/store"
 	"github.com/patrickspencer/cronbat/internal/web/api"
 	"github.com/patrickspencer/cronbat/internal/web/ui"
 )
@@ -17,54 +15,40 @@ import (
 // Server is the HTTP server for the cronbat web interface and API.
 type Server struct {
 	httpServer *http.Server
+	handler    atomic.Pointer[http.Handler]
 }
 
-// NewServer creates a new Server with the given dependencies.
-func NewServer(
-	addr string,
-	s store.RunStore,
-	events *realtime.Broker,
-	getConfig func() *config.Config,
-	jobs func() []*config.Job,
-	jobState func(name string) string,
-	createJob func(newJob config.Job) error,
-	readRunLogs func(jobName string, runID string) (stdout string, stderr string, stdoutPath string, stderrPath string, err error),
-	triggerFunc func(jobName string),
-	nextRunTime func(name string) (time.Time, bool),
-	enableJob func(name string) error,
-	disableJob func(name string) error,
-	startJob func(name string) error,
-	stopJob func(name string) error,
-	pauseJob func(name string) error,
-	archiveJob func(name string) error,
-	deleteJob func(name string) error,
-	getJobYAML func(name string) (string, error),
-	updateJobYAML func(name string, data string) (string, error),
-	updateJobSettings func(name string, updated config.Job) error,
-) *Server {
-	mux := http.NewServeMux()
+// Timeouts configures the underlying http.Server. Zero values disable the
+// corresponding timeout.
+type Timeouts struct {
+	ReadHeader time.Duration
+	Read       time.Duration
+	Write      time.Duration
+	Idle       time.Duration
+}
 
-	a := &api.API{
-		Store:             s,
-		Events:            events,
-		GetConfig:         getConfig,
-		Jobs:              jobs,
-		JobState:          jobState,
-		CreateJob:         createJob,
-		ReadRunLogs:       readRunLogs,
-		TriggerRun:        triggerFunc,
-		NextRunTime:       nextRunTime,
-		EnableJob:         enableJob,
-		DisableJob:        disableJob,
-		StartJob:          startJob,
-		StopJob:           stopJob,
-		PauseJob:          pauseJob,
-		ArchiveJob:        archiveJob,
-		DeleteJob:         deleteJob,
-		GetJobYAML:        getJobYAML,
-		UpdateJobYAML:     updateJobYAML,
-		UpdateJobSettings: updateJobSettings,
+// NewServer creates a Server that answers liveness and readiness probes
+// right away. The full API and UI are served once Mount is called.
+func NewServer(addr string, readiness *api.Readiness, timeouts Timeouts) *Server {
+	s := &Server{}
+	startup := api.StartupHandler(readiness)
+	s.handler.Store(&startup)
+	s.httpServer = &http.Server{
+		Addr:              addr,
+		ReadHeaderTimeout: timeouts.ReadHeader,
+		ReadTimeout:       timeouts.Read,
+		WriteTimeout:      timeouts.Write,
+		IdleTimeout:       timeouts.Idle,
+		Handler: corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			(*s.handler.Load()).ServeHTTP(w, r)
+		})),
 	}
+	return s
+}
+
+// Mount installs the API routes and built-in UI, replacing the startup handler.
+func (s *Server) Mount(a *api.API) {
+	mux := http.NewServeMux()
 	a.RegisterRoutes(mux)
 
 	// Built-in minimal UI.
@@ -77,11 +61,12 @@ func NewServer(
 		http.NotFound(w, r)
 	})
 
-	return &Server{
-		httpServer: &http.Server{
-			Addr:    addr,
-			Handler: corsMiddleware(mux),
-		},
+	h := a.Protect(mux)
+	s.handler.Store(&h)
+
+	if a.Events != nil {
+		// End SSE streams on shutdown so Shutdown does not wait on them.
+		s.httpServer.RegisterOnShutdown(a.Events.Close)
 	}
 }
 
@@ -100,11 +85,13 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	return s.httpServer.Shutdown(ctx)
 }
 
-// corsMiddleware adds permissive CORS headers for development.
+// corsMiddleware adds permissive CORS headers for programmatic clients.
+// Credentials are never allowed cross-origin, so the UI's session cookie
+// only works on pages served by cronbat itself.
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
 		if r.Method == http.MethodOptions {
@@ -0,0 +1,66 @@
+// Shared by every page: sends the session's CSRF token with mutating
+// requests, sends the browser to the sign-in page when the API asks for
+// authentication, and adds a sign-out link while signed in.
+
+function readCookie(name) {
+  for (const part of document.cookie.split(";")) {
+    const [key, ...rest] = part.trim().split("=");
+    if (key === name) {
+      return decodeURIComponent(rest.join("="));
+    }
+  }
+  return "";
+}
+
+function goToLogin() {
+  const next = window.location.pathname + window.location.search;
+  window.location.href = `/ui/login.html?next=${encodeURIComponent(next)}`;
+}
+
+const originalFetch = window.fetch.bind(window);
+
+window.fetch = async (input, init = {}) => {
+  const method = (init.method || "GET").toUpperCase();
+  if (method !== "GET" && method !== "HEAD") {
+    const token = readCookie("cronbat_csrf");
+    if (token) {
+      const headers = new Headers(init.headers || {});
+      headers.set("X-CSRF-Token", token);
+      init = { ...init, headers };
+    }
+  }
+  const response = await originalFetch(input, init);
+  if (response.status === 401 && !window.location.pathname.endsWith("/login.html")) {
+    goToLogin();
+  }
+  return response;
+};
+
+async function signOut(event) {
+  event.preventDefault();
+  await fetch("/api/v1/auth/logout", { method: "POST" }).catch(() => {});
+  goToLogin();
+}
+
+async function addSignOutLink() {
+  const nav = document.querySelector(".sidebar-nav");
+  if (!nav) {
+    return;
+  }
+  try {
+    const response = await originalFetch("/api/v1/auth/session");
+    const session = await response.json();
+    if (!session.authenticated || !readCookie("cronbat_csrf")) {
+      return;
+    }
+    const link = document.createElement("a");
+    link.href = "/ui/login.html";
+    link.textContent = `Sign Out (${session.name})`;
+    link.addEventListener("click", signOut);
+    nav.appendChild(link);
+  } catch (err) {
+    // The link is a convenience; pages work without it.
+  }
+}
+
+document.addEventListener("DOMContentLoaded", addSignOutLink);
@@ -1,6 +1,8 @@
 const statusEl = document.getElementById("status");
 const jobsBodyEl = document.getElementById("jobs-body");
 const refreshBtn = document.getElementById("refresh-btn");
+const searchEl = document.getElementById("job-search");
+const loadErrorsEl = document.getElementById("load-errors");
 const deleteModalEl = document.getElementById("delete-modal");
 const deleteJobNameEl = document.getElementById("delete-job-name");
 const deleteInputEl = document.getElementById("delete-confirm-input");
@@ -133,7 +135,7 @@ function describeSchedule(expr) {
 
 function resolveState(job) {
   const raw = String(job.state || "").toLowerCase();
-  if (raw === "started" || raw === "paused" || raw === "stopped") {
+  if (raw === "started" || raw === "paused" || raw === "stopped" || raw === "dormant") {
     return raw;
   }
   return job.enabled ? "started" : "stopped";
@@ -141,7 +143,7 @@ function resolveState(job) {
 
 function resolveLastRunStatus(status) {
   const raw = String(status || "").toLowerCase();
-  if (raw === "success" || raw === "failure" || raw === "running") {
+  if (raw === "success" || raw === "warning" || raw === "failure" || raw === "running") {
     return raw;
   }
   return "none";
@@ -261,7 +263,8 @@ function openDeleteModal(jobName) {
 function renderJob(job) {
   const tr = document.createElement("tr");
   const state = resolveState(job);
-  const humanSchedule = describeSchedule(job.schedule);
+  // Natural-language schedules are already readable; show their cron form.
+  const humanSchedule = job.schedule_cron ? `cron: ${job.schedule_cron}` : describeSchedule(job.schedule);
   const nextRun = formatDate(job.next_run);
   const lastRun = formatDate(job.last_run);
   const lastRunStatus = resolveLastRunStatus(job.last_run_status);
@@ -276,12 +279,14 @@ function renderJob(job) {
   const safeLastRun = escapeHTML(lastRun);
   const safeLastRunStatus = escapeHTML(lastRunStatusLabel);
   const safeStateLabel = escapeHTML(stateLabel);
+  const disabledNote = [job.disabled_reason, job.disabled_by ? `by ${job.disabled_by}` : ""].filter(Boolean).join(" ");
+  const disabledTitle = disabledNote ? ` title="${escapeHTML(disabledNote)}"` : "";
 
   tr.innerHTML = `
     <td><strong><a class="job-title-link" href="${jobDetailURL}">${safeName}</a></strong></td>
     <td>
       <div class="status-cell-simple">
-        <span class="status-pill ${state}">${safeStateLabel}</span>
+        <span class="status-pill ${state}"${disabledTitle}>${safeStateLabel}</span>
       </div>
     </td>
     <td>
@@ -331,8 +336,12 @@ function renderJob(job) {
       if (action === "run") {
         setStatus(`Triggering ${job.name}...`);
         try {
-          await api(`/api/v1/jobs/${encodeURIComponent(job.name)}/run`, { method: "POST" });
-          setStatus(`Triggered ${job.name}`);
+          const result = await api(`/api/v1/jobs/${encodeURIComponent(job.name)}/run`, { method: "POST" });
+          if (result.status === "pending_approval") {
+            setStatus(`Run of ${job.name} is waiting for approval (${result.approval_id})`);
+          } else {
+            setStatus(`Triggered ${job.name}`);
+          }
         } catch (err) {
           setStatus(err.message, true);
         }
@@ -373,8 +382,12 @@ async function loadJobs(options = {}) {
   }
 
   try {
-    const jobs = await api("/api/v1/jobs");
-    jobs.sort((a, b) => a.name.localeCompare(b.name));
+    const params = new URLSearchParams({ sort: "name" });
+    const query = searchEl.value.trim();
+    if (query) {
+      params.set("q", query);
+    }
+    const jobs = await api(`/api/v1/jobs?${params}`);
 
     jobsBodyEl.innerHTML = "";
     if (jobs.length === 0) {
@@ -403,6 +416,31 @@ async function loadJobs(options = {}) {
   }
 }
 
+async function loadJobErrors() {
+  let errors = [];
+  try {
+    errors = await api("/api/v1/jobs/errors");
+  } catch (_) {
+    return;
+  }
+  if (!Array.isArray(errors) || errors.length === 0) {
+    loadErrorsEl.hidden = true;
+    loadErrorsEl.innerHTML = "";
+    return;
+  }
+  const items = errors.map((e) => {
+    const moved = e.quarantined_path
+      ? ` (moved to <code>${escapeHTML(e.quarantined_path)}</code>)`
+      : "";
+    return `<li><code>${escapeHTML(e.path)}</code>: ${escapeHTML(e.error)}${moved}</li>`;
+  });
+  loadErrorsEl.innerHTML = `
+    <strong>${errors.length} job file(s) could not be loaded</strong>
+    <ul>${items.join("")}</ul>
+  `;
+  loadErrorsEl.hidden = false;
+}
+
 function startPolling() {
   if (pollHandle) {
     clearInterval(pollHandle);
@@ -424,6 +462,18 @@ function onRealtimeEvent(event) {
     payload = null;
   }
 
+  if (payload && payload.type === "run.slow" && payload.job_name) {
+    setStatus(`Run still going past warn_after: ${payload.job_name}`, true);
+  }
+  if (payload && payload.type === "run.overrun_predicted" && payload.job_name) {
+    setStatus(`Run expected to overlap its next fire: ${payload.job_name}`, true);
+  }
+  if (payload && payload.type === "job.auto_disabled" && payload.job_name) {
+    setStatus(`Job auto-disabled after repeated failures: ${payload.job_name}`, true);
+  }
+  if (payload && payload.type === "approval.requested" && payload.job_name) {
+    setStatus(`Run of ${payload.job_name} is waiting for approval`);
+  }
   if (payload && payload.type === "run.completed" && payload.job_name) {
     const suffix = payload.status ? ` (${payload.status})` : "";
     setStatus(`Run finished: ${payload.job_name}${suffix}`);
@@ -446,6 +496,11 @@ function startEventStream() {
   eventStream.addEventListener("job.changed", onRealtimeEvent);
   eventStream.addEventListener("run.started", onRealtimeEvent);
   eventStream.addEventListener("run.completed", onRealtimeEvent);
+  eventStream.addEventListener("run.slow", onRealtimeEvent);
+  eventStream.addEventListener("run.overrun_predicted", onRealtimeEvent);
+  eventStream.addEventListener("job.auto_disabled", onRealtimeEvent);
+  eventStream.addEventListener("job.dormant", onRealtimeEvent);
+  eventStream.addEventListener("approval.requested", onRealtimeEvent);
   eventStream.onopen = () => {
     streamConnected = true;
     if (hasLoadedOnce) {
@@ -460,7 +515,16 @@ function startEventStream() {
   };
 }
 
-refreshBtn.addEventListener("click", () => loadJobs());
+refreshBtn.addEventListener("click", () => {
+  loadJobErrors();
+  loadJobs();
+});
+
+let searchTimer = null;
+searchEl.addEventListener("input", () => {
+  clearTimeout(searchTimer);
+  searchTimer = setTimeout(() => loadJobs(), 250);
+});
 
 if (deleteInputEl) {
   deleteInputEl.addEventListener("input", setDeleteConfirmEnabled);
@@ -507,6 +571,7 @@ document.addEventListener("keydown", (event) => {
   }
 });
 
+loadJobErrors();
 loadJobs().then(() => {
   startEventStream();
   startPolling();
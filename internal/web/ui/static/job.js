@@ -8,6 +8,7 @@ const statusEl = document.getElementById("status");
 const formEl = document.getElementById("settings-form");
 const nameEl = document.getElementById("name");
 const scheduleEl = document.getElementById("schedule");
+const scheduleExplainEl = document.getElementById("schedule-explain");
 const commandEl = document.getElementById("command");
 const workingDirEl = document.getElementById("working-dir");
 const executorEl = document.getElementById("executor");
@@ -66,6 +67,35 @@ async function api(path, options = {}) {
   return payload;
 }
 
+// explainSchedule shows the schedule being typed in words, or why it is
+// invalid, under the field.
+let explainTimer = null;
+function explainSchedule() {
+  clearTimeout(explainTimer);
+  explainTimer = setTimeout(async () => {
+    const expr = scheduleEl.value.trim();
+    if (!expr) {
+      scheduleExplainEl.textContent = "";
+      scheduleExplainEl.classList.remove("error");
+      return;
+    }
+    try {
+      const result = await api(`/api/v1/schedule/explain?expr=${encodeURIComponent(expr)}`);
+      if (scheduleEl.value.trim() !== expr) {
+        return;
+      }
+      scheduleExplainEl.textContent = result.summary;
+      scheduleExplainEl.classList.remove("error");
+    } catch (err) {
+      if (scheduleEl.value.trim() !== expr) {
+        return;
+      }
+      scheduleExplainEl.textContent = err.message;
+      scheduleExplainEl.classList.add("error");
+    }
+  }, 250);
+}
+
 function setDeleteConfirmEnabled() {
   if (!deleteConfirmBtn || !deleteInputEl) {
     return;
@@ -141,6 +171,7 @@ async function loadSettings(options = {}) {
 
   nameEl.value = job.name || "";
   scheduleEl.value = job.schedule || "";
+  explainSchedule();
   commandEl.value = job.command || "";
   workingDirEl.value = job.working_dir || "";
   executorEl.value = job.executor || "";
@@ -165,6 +196,8 @@ async function loadYAML(options = {}) {
   yamlEl.value = payload.yaml || "";
 }
 
+scheduleEl.addEventListener("input", explainSchedule);
+
 formEl.addEventListener("submit", async (event) => {
   event.preventDefault();
   setStatus("Saving settings...");
@@ -179,7 +212,11 @@ formEl.addEventListener("submit", async (event) => {
       }
     }
 
+    // Settings the form does not show are sent back unchanged; a PUT
+    // clears whatever it leaves out.
+    const [current] = await api(`/api/v1/jobs/export?format=json&name=${encodeURIComponent(jobName)}`);
     const payload = {
+      ...current,
       name: nameEl.value.trim(),
       schedule: scheduleEl.value.trim(),
       command: commandEl.value,
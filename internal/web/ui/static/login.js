@@ -0,0 +1,52 @@
+const statusEl = document.getElementById("status");
+const formEl = document.getElementById("login-form");
+const keyEl = document.getElementById("key");
+
+function setStatus(message, isError = false) {
+  statusEl.textContent = message;
+  statusEl.classList.toggle("error", isError);
+}
+
+function nextPage() {
+  const next = new URLSearchParams(window.location.search).get("next") || "";
+  // Only return to pages of this UI.
+  return next.startsWith("/ui/") ? next : "/ui/";
+}
+
+async function showSSO() {
+  const error = new URLSearchParams(window.location.search).get("error");
+  if (error) {
+    setStatus(error, true);
+  }
+  try {
+    const response = await fetch("/api/v1/auth/session");
+    const session = await response.json();
+    if (session.sso) {
+      document.getElementById("sso-link").href = `/api/v1/auth/oidc/login?next=${encodeURIComponent(nextPage())}`;
+      document.getElementById("sso").hidden = false;
+    }
+  } catch (err) {
+    // Key sign-in still works.
+  }
+}
+
+showSSO();
+
+formEl.addEventListener("submit", async (event) => {
+  event.preventDefault();
+  setStatus("Signing in...");
+  try {
+    const response = await fetch("/api/v1/auth/login", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ key: keyEl.value }),
+    });
+    const payload = await response.json().catch(() => ({}));
+    if (!response.ok) {
+      throw new Error(payload.error || `request failed (${response.status})`);
+    }
+    window.location.href = nextPage();
+  } catch (err) {
+    setStatus(err.message, true);
+  }
+});
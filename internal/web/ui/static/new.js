@@ -3,6 +3,7 @@ const statusEl = document.getElementById("status");
 
 const nameEl = document.getElementById("name");
 const scheduleEl = document.getElementById("schedule");
+const scheduleExplainEl = document.getElementById("schedule-explain");
 const commandEl = document.getElementById("command");
 const workingDirEl = document.getElementById("working-dir");
 const executorEl = document.getElementById("executor");
@@ -46,6 +47,37 @@ async function api(path, options = {}) {
   return payload;
 }
 
+// explainSchedule shows the schedule being typed in words, or why it is
+// invalid, under the field.
+let explainTimer = null;
+function explainSchedule() {
+  clearTimeout(explainTimer);
+  explainTimer = setTimeout(async () => {
+    const expr = scheduleEl.value.trim();
+    if (!expr) {
+      scheduleExplainEl.textContent = "";
+      scheduleExplainEl.classList.remove("error");
+      return;
+    }
+    try {
+      const result = await api(`/api/v1/schedule/explain?expr=${encodeURIComponent(expr)}`);
+      if (scheduleEl.value.trim() !== expr) {
+        return;
+      }
+      scheduleExplainEl.textContent = result.summary;
+      scheduleExplainEl.classList.remove("error");
+    } catch (err) {
+      if (scheduleEl.value.trim() !== expr) {
+        return;
+      }
+      scheduleExplainEl.textContent = err.message;
+      scheduleExplainEl.classList.add("error");
+    }
+  }, 250);
+}
+
+scheduleEl.addEventListener("input", explainSchedule);
+
 formEl.addEventListener("submit", async (event) => {
   event.preventDefault();
   setStatus("Creating job...");
@@ -10,8 +10,13 @@ const metaEl = document.getElementById("meta");
 const stdoutEl = document.getElementById("stdout");
 const stderrEl = document.getElementById("stderr");
 
+const pageSize = 256 * 1024;
+
 let refreshHandle = null;
 let lastRun = null;
+// Earliest byte offset loaded for each stream, for "load earlier" paging.
+const loadedFrom = { stdout: 0, stderr: 0 };
+const streamSize = { stdout: 0, stderr: 0 };
 
 function setStatus(message, isError = false) {
   statusEl.textContent = message;
@@ -40,7 +45,7 @@ async function api(path) {
 
 function renderMeta(run, logs) {
   const source = logs.source === "file" ? "persisted file" : "database tail";
-  metaEl.textContent = [
+  const lines = [
     `Run ID: ${run.id}`,
     `Job: ${run.job_name}`,
     `Status: ${run.status}`,
@@ -50,7 +55,11 @@ function renderMeta(run, logs) {
     `Duration: ${run.duration_ms} ms`,
     `Exit Code: ${run.exit_code}`,
     `Log Source: ${source}`
-  ].join("\n");
+  ];
+  if (run.jobs_commit) {
+    lines.push(`Jobs Commit: ${run.jobs_commit}`);
+  }
+  metaEl.textContent = lines.join("\n");
 }
 
 async function loadRun() {
@@ -72,12 +81,61 @@ async function loadRun() {
     renderMeta(run, logs);
     stdoutEl.textContent = logs.stdout || "";
     stderrEl.textContent = logs.stderr || "";
+    loadedFrom.stdout = logs.stdout_offset || 0;
+    loadedFrom.stderr = logs.stderr_offset || 0;
+    streamSize.stdout = logs.stdout_size || 0;
+    streamSize.stderr = logs.stderr_size || 0;
+    renderRangeInfo("stdout");
+    renderRangeInfo("stderr");
     setStatus("Run loaded");
   } catch (err) {
     setStatus(err.message, true);
   }
 }
 
+function formatBytes(n) {
+  if (n < 1024) {
+    return `${n} B`;
+  }
+  if (n < 1024 * 1024) {
+    return `${(n / 1024).toFixed(1)} KiB`;
+  }
+  return `${(n / (1024 * 1024)).toFixed(1)} MiB`;
+}
+
+function renderRangeInfo(stream) {
+  const box = document.getElementById(`${stream}-range`);
+  const info = document.getElementById(`${stream}-range-info`);
+  const from = loadedFrom[stream];
+  box.hidden = from <= 0;
+  if (from > 0) {
+    const shown = streamSize[stream] - from;
+    info.textContent = `Showing last ${formatBytes(shown)} of ${formatBytes(streamSize[stream])}.`;
+  }
+}
+
+async function loadEarlier(stream) {
+  const end = loadedFrom[stream];
+  if (end <= 0) {
+    return;
+  }
+  const start = Math.max(0, end - pageSize);
+  try {
+    const chunk = await api(
+      `/api/v1/runs/${encodeURIComponent(runID)}/logs?stream=${stream}&offset=${start}&limit=${end - start}`
+    );
+    const el = stream === "stdout" ? stdoutEl : stderrEl;
+    el.textContent = chunk.data + el.textContent;
+    loadedFrom[stream] = chunk.offset;
+    renderRangeInfo(stream);
+  } catch (err) {
+    setStatus(err.message, true);
+  }
+}
+
+document.getElementById("stdout-earlier").addEventListener("click", () => loadEarlier("stdout"));
+document.getElementById("stderr-earlier").addEventListener("click", () => loadEarlier("stderr"));
+
 function startAutoRefresh() {
   if (refreshHandle) {
     clearInterval(refreshHandle);
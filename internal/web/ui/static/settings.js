@@ -1,14 +1,16 @@
 const statusEl = document.getElementById("status");
 const statsEl = document.getElementById("stats");
 const configEl = document.getElementById("config");
+const mintTokenBtn = document.getElementById("mint-token-btn");
+const tokenEl = document.getElementById("token");
 
 function setStatus(message, isError = false) {
   statusEl.textContent = message;
   statusEl.classList.toggle("error", isError);
 }
 
-async function api(path) {
-  const response = await fetch(path);
+async function api(path, options = {}) {
+  const response = await fetch(path, options);
   const payload = await response.json().catch(() => ({}));
   if (!response.ok) {
     throw new Error(payload.error || `request failed (${response.status})`);
@@ -33,4 +35,22 @@ async function load() {
   }
 }
 
+async function mintToken() {
+  setStatus("Creating token...");
+  try {
+    const token = await api("/api/v1/auth/token", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: "{}"
+    });
+    tokenEl.textContent = `${token.token}\n\nrole ${token.role}, expires ${new Date(token.expires_at).toLocaleString()}`;
+    tokenEl.hidden = false;
+    setStatus("Created token; copy it now, it is not shown again");
+  } catch (err) {
+    setStatus(err.message, true);
+  }
+}
+
+mintTokenBtn.addEventListener("click", mintToken);
+
 load();
@@ -25,6 +25,12 @@ type RunResult struct {
 	StdoutTruncated   bool
 	StderrTruncated   bool
 	LogStorageWarning string
+	// OutputLimited reports that the run wrote more than its output limit
+	// and the rest was dropped.
+	OutputLimited bool
+	// Exited reports that the command ran and exited on its own with
+	// ExitCode, rather than failing to start or being killed.
+	Exited bool
 }
 
 // NotifyEvent holds information for notification plugins.